@@ -0,0 +1,264 @@
+package agentclient
+
+import (
+	"fmt"
+	"time"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	"github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
+	"github.com/pivotal-golang/clock"
+)
+
+// AgentClient is the client bosh-init uses to talk to the agent over its HTTP mbus.
+// It adds the agent actions bosh-init needs beyond deploying to the vendored client.
+type AgentClient interface {
+	biagentclient.AgentClient
+	GetFullState() (AgentState, error)
+	Drain(drainType string, newSpec *applyspec.ApplySpec, timeout time.Duration) (finished bool, err error)
+	ApplyErrand(spec applyspec.ApplySpec, errandJobName string) error
+	RunErrand() (ErrandResult, error)
+	FetchLogs(logType string, filters []string) (blobstoreID string, err error)
+}
+
+// AgentState is the agent's full state, including the agent id, the state of each job process
+// and the spec that was last applied
+type AgentState struct {
+	AgentID      string                               `json:"agent_id"`
+	JobState     string                               `json:"job_state"`
+	NetworkSpecs map[string]biagentclient.NetworkSpec `json:"networks"`
+	Processes    []ProcessState                       `json:"processes"`
+	Spec         applyspec.ApplySpec                  `json:"-"`
+}
+
+type ProcessState struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+type ErrandResult struct {
+	ExitStatus int
+	Stdout     string
+	Stderr     string
+}
+
+type agentClient struct {
+	biagentclient.AgentClient
+	agentRequest        agentRequest
+	getTaskDelay        time.Duration
+	toleratedErrorCount int
+	timeService         clock.Clock
+	logger              boshlog.Logger
+	logTag              string
+}
+
+func NewAgentClient(
+	endpoint string,
+	directorID string,
+	getTaskDelay time.Duration,
+	toleratedErrorCount int,
+	httpClient httpclient.HTTPClient,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) AgentClient {
+	agentRequest := agentRequest{
+		directorID: directorID,
+		endpoint:   fmt.Sprintf("%s/agent", endpoint),
+		httpClient: httpClient,
+	}
+	return &agentClient{
+		AgentClient:         bihttpagent.NewAgentClient(endpoint, directorID, getTaskDelay, toleratedErrorCount, httpClient, logger),
+		agentRequest:        agentRequest,
+		getTaskDelay:        getTaskDelay,
+		toleratedErrorCount: toleratedErrorCount,
+		timeService:         timeService,
+		logger:              logger,
+		logTag:              "agentClient",
+	}
+}
+
+func (c *agentClient) GetFullState() (AgentState, error) {
+	var response stateResponse
+	err := c.agentRequest.Send("get_state", []interface{}{"full"}, &response)
+	if err != nil {
+		return AgentState{}, bosherr.WrapError(err, "Sending get_state to the agent")
+	}
+
+	return response.Value, nil
+}
+
+// Drain runs the drain scripts of the jobs. The agent waits as long as the scripts ask for,
+// so the drain finished when its task did. When timeout is positive and the task is still
+// running after it, the task is cancelled and finished is false.
+func (c *agentClient) Drain(drainType string, newSpec *applyspec.ApplySpec, timeout time.Duration) (bool, error) {
+	arguments := []interface{}{drainType}
+	if newSpec != nil {
+		arguments = append(arguments, *newSpec)
+	}
+
+	agentTaskID, err := c.startTask("drain", arguments)
+	if err != nil {
+		return false, err
+	}
+
+	var value interface{}
+	var running bool
+	getTaskRetryable := c.newGetTaskRetryable("drain", agentTaskID, &value, &running)
+
+	if timeout <= 0 {
+		err = boshretry.NewUnlimitedRetryStrategy(c.getTaskDelay, getTaskRetryable, c.logger).Try()
+		return err == nil, err
+	}
+
+	err = boshretry.NewTimeoutRetryStrategy(timeout, c.getTaskDelay, getTaskRetryable, c.timeService, c.logger).Try()
+	if err != nil && running {
+		c.logger.Debug(c.logTag, "Cancelling drain task '%s' after %s", agentTaskID, timeout)
+		cancelErr := c.agentRequest.Send("cancel_task", []interface{}{agentTaskID}, &bihttpagent.TaskResponse{})
+		if cancelErr != nil {
+			return false, bosherr.WrapErrorf(cancelErr, "Cancelling drain task '%s'", agentTaskID)
+		}
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// errandApplySpec names the job whose 'bin/run' the agent runs for run_errand,
+// which the agent reads from the 'job.template' of the applied spec
+type errandApplySpec struct {
+	applyspec.ApplySpec
+	Job errandJob `json:"job"`
+}
+
+type errandJob struct {
+	applyspec.Job
+	Template string `json:"template"`
+}
+
+func (c *agentClient) ApplyErrand(spec applyspec.ApplySpec, errandJobName string) error {
+	errandSpec := errandApplySpec{
+		ApplySpec: spec,
+		Job: errandJob{
+			Job:      spec.Job,
+			Template: errandJobName,
+		},
+	}
+
+	_, err := c.sendAsyncTaskMessage("apply", []interface{}{errandSpec})
+	return err
+}
+
+func (c *agentClient) RunErrand() (ErrandResult, error) {
+	value, err := c.sendAsyncTaskMessage("run_errand", []interface{}{})
+	if err != nil {
+		return ErrandResult{}, err
+	}
+
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return ErrandResult{}, bosherr.Errorf("Unable to parse 'run_errand' response from the agent: %#v", value)
+	}
+
+	exitStatus, ok := result["exit_code"].(float64)
+	if !ok {
+		return ErrandResult{}, bosherr.Errorf("Unable to parse 'run_errand' response from the agent: %#v", value)
+	}
+
+	stdout, _ := result["stdout"].(string)
+	stderr, _ := result["stderr"].(string)
+
+	return ErrandResult{
+		ExitStatus: int(exitStatus),
+		Stdout:     stdout,
+		Stderr:     stderr,
+	}, nil
+}
+
+func (c *agentClient) FetchLogs(logType string, filters []string) (string, error) {
+	value, err := c.sendAsyncTaskMessage("fetch_logs", []interface{}{logType, filters})
+	if err != nil {
+		return "", err
+	}
+
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return "", bosherr.Errorf("Unable to parse 'fetch_logs' response from the agent: %#v", value)
+	}
+
+	blobstoreID, ok := result["blobstore_id"].(string)
+	if !ok {
+		return "", bosherr.Errorf("Unable to parse 'fetch_logs' response from the agent: %#v", value)
+	}
+
+	return blobstoreID, nil
+}
+
+// sendAsyncTaskMessage sends a long running action and polls its task until it finished.
+// It returns the task's result value, which is not always a map.
+func (c *agentClient) sendAsyncTaskMessage(method string, arguments []interface{}) (interface{}, error) {
+	agentTaskID, err := c.startTask(method, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	var running bool
+	getTaskRetryable := c.newGetTaskRetryable(method, agentTaskID, &value, &running)
+
+	getTaskRetryStrategy := boshretry.NewUnlimitedRetryStrategy(c.getTaskDelay, getTaskRetryable, c.logger)
+	err = getTaskRetryStrategy.Try()
+	return value, err
+}
+
+func (c *agentClient) startTask(method string, arguments []interface{}) (string, error) {
+	var response bihttpagent.TaskResponse
+	err := c.agentRequest.Send(method, arguments, &response)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Sending '%s' to the agent", method)
+	}
+
+	agentTaskID, err := response.TaskID()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Getting agent task id")
+	}
+
+	return agentTaskID, nil
+}
+
+// newGetTaskRetryable polls the task once per attempt. It stores the task's result in value once the task
+// finished, and whether the last poll found the task still running in running.
+func (c *agentClient) newGetTaskRetryable(method string, agentTaskID string, value *interface{}, running *bool) boshretry.Retryable {
+	sendErrors := 0
+	return boshretry.NewRetryable(func() (bool, error) {
+		var response bihttpagent.TaskResponse
+		err := c.agentRequest.Send("get_task", []interface{}{agentTaskID}, &response)
+		if err != nil {
+			*running = false
+			sendErrors++
+			shouldRetry := sendErrors <= c.toleratedErrorCount
+			err = bosherr.WrapError(err, "Sending 'get_task' to the agent")
+			c.logger.Debug(c.logTag, "Error occured sending get_task. Error retry %d of %d: %s", sendErrors, c.toleratedErrorCount, err)
+			return shouldRetry, err
+		}
+		sendErrors = 0
+
+		c.logger.Debug(c.logTag, "get_task response value: %#v", response.Value)
+
+		taskState, err := response.TaskState()
+		if err != nil {
+			return false, bosherr.WrapError(err, "Getting task state")
+		}
+
+		*running = taskState == "running"
+		if !*running {
+			*value = response.Value
+			return true, nil
+		}
+
+		return true, bosherr.Errorf("Task %s is still running", method)
+	})
+}
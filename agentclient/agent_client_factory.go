@@ -0,0 +1,38 @@
+package agentclient
+
+import (
+	"time"
+
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+type AgentClientFactory interface {
+	NewAgentClient(directorID, mbusURL string, client httpclient.Client) AgentClient
+}
+
+type agentClientFactory struct {
+	getTaskDelay time.Duration
+	timeService  clock.Clock
+	logger       boshlog.Logger
+}
+
+func NewAgentClientFactory(
+	getTaskDelay time.Duration,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) AgentClientFactory {
+	return &agentClientFactory{
+		getTaskDelay: getTaskDelay,
+		timeService:  timeService,
+		logger:       logger,
+	}
+}
+
+// NewAgentClient returns a client of the agent at mbusURL, sending requests with client
+// so that the mbus certificate can be verified (and the agent reached through a proxy or jump host)
+func (f *agentClientFactory) NewAgentClient(directorID, mbusURL string, client httpclient.Client) AgentClient {
+	httpClient := httpclient.NewHTTPClient(client, f.logger)
+	return NewAgentClient(mbusURL, directorID, f.getTaskDelay, 10, httpClient, f.timeService, f.logger)
+}
@@ -0,0 +1,263 @@
+package agentclient_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-init/agentclient"
+
+	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	"github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	bitestutils "github.com/cloudfoundry/bosh-init/testutils"
+	fakehttpclient "github.com/cloudfoundry/bosh-utils/httpclient/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("AgentClient", func() {
+	var (
+		fakeHTTPClient *fakehttpclient.FakeHTTPClient
+		agentClient    AgentClient
+
+		replyToAddress string
+		timeService    bitestutils.SleepingClock
+		logger         boshlog.Logger
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeHTTPClient = fakehttpclient.NewFakeHTTPClient()
+		replyToAddress = "fake-reply-to-uuid"
+		timeService = bitestutils.NewSleepingClock(time.Now())
+
+		agentClient = NewAgentClient("http://localhost:6305", replyToAddress, time.Duration(0), 2, fakeHTTPClient, timeService, logger)
+	})
+
+	sentRequest := func(i int) bihttpagent.AgentRequestMessage {
+		var request bihttpagent.AgentRequestMessage
+		err := json.Unmarshal(fakeHTTPClient.PostInputs[i].Payload, &request)
+		Expect(err).ToNot(HaveOccurred())
+		return request
+	}
+
+	Describe("GetFullState", func() {
+		It("sends a full get_state message to the agent", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_id":"fake-agent-id","deployment":"fake-deployment-name","job_state":"running","networks":{"private":{"ip":"192.0.2.10"}},"processes":[{"name":"fake-process","state":"running"}]}}`, 200, nil)
+
+			agentState, err := agentClient.GetFullState()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(agentState).To(Equal(AgentState{
+				AgentID:  "fake-agent-id",
+				JobState: "running",
+				NetworkSpecs: map[string]biagentclient.NetworkSpec{
+					"private": {IP: "192.0.2.10"},
+				},
+				Processes: []ProcessState{
+					{Name: "fake-process", State: "running"},
+				},
+				Spec: applyspec.ApplySpec{
+					Deployment: "fake-deployment-name",
+					Networks: map[string]interface{}{
+						"private": map[string]interface{}{"ip": "192.0.2.10"},
+					},
+				},
+			}))
+
+			Expect(fakeHTTPClient.PostInputs[0].Endpoint).To(Equal("http://localhost:6305/agent"))
+			Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "get_state",
+				Arguments: []interface{}{"full"},
+				ReplyTo:   replyToAddress,
+			}))
+		})
+
+		It("returns an error when the agent responds with an exception", func() {
+			fakeHTTPClient.SetPostBehavior(`{"exception":{"message":"fake-state-error"}}`, 200, nil)
+
+			_, err := agentClient.GetFullState()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-state-error"))
+		})
+	})
+
+	Describe("ApplyErrand", func() {
+		It("sends the apply spec with the errand job as the job template", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":"applied"}`, 200, nil)
+
+			spec := applyspec.ApplySpec{
+				Deployment: "fake-deployment-name",
+				Job: applyspec.Job{
+					Name: "fake-job-name",
+					Templates: []applyspec.Blob{
+						{Name: "fake-service-job"},
+						{Name: "fake-errand-job"},
+					},
+				},
+			}
+			err := agentClient.ApplyErrand(spec, "fake-errand-job")
+			Expect(err).ToNot(HaveOccurred())
+
+			request := sentRequest(0)
+			Expect(request.Method).To(Equal("apply"))
+			Expect(request.Arguments).To(HaveLen(1))
+
+			sentSpec := request.Arguments[0].(map[string]interface{})
+			Expect(sentSpec["deployment"]).To(Equal("fake-deployment-name"))
+
+			job := sentSpec["job"].(map[string]interface{})
+			Expect(job["name"]).To(Equal("fake-job-name"))
+			Expect(job["template"]).To(Equal("fake-errand-job"))
+			Expect(job["templates"]).To(HaveLen(2))
+		})
+	})
+
+	Describe("RunErrand", func() {
+		It("sends a run_errand message to the agent and returns the errand result", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":{"exit_code":3,"stdout":"fake-stdout","stderr":"fake-stderr"}}`, 200, nil)
+
+			result, err := agentClient.RunErrand()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ErrandResult{
+				ExitStatus: 3,
+				Stdout:     "fake-stdout",
+				Stderr:     "fake-stderr",
+			}))
+
+			Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "run_errand",
+				Arguments: []interface{}{},
+				ReplyTo:   replyToAddress,
+			}))
+			Expect(sentRequest(1)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "get_task",
+				Arguments: []interface{}{"fake-agent-task-id"},
+				ReplyTo:   replyToAddress,
+			}))
+		})
+
+		It("returns an error if the response has no exit code", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":{"stdout":"fake-stdout"}}`, 200, nil)
+
+			_, err := agentClient.RunErrand()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unable to parse 'run_errand' response from the agent"))
+		})
+
+		It("returns an error if sending fails", func() {
+			fakeHTTPClient.SetPostBehavior("", 0, errors.New("connection reset by peer"))
+
+			_, err := agentClient.RunErrand()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("connection reset by peer"))
+		})
+
+		It("polls the task until it finished", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior("", 0, errors.New("connection reset by peer"))
+			fakeHTTPClient.SetPostBehavior(`{"value":{"exit_code":0}}`, 200, nil)
+
+			result, err := agentClient.RunErrand()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.ExitStatus).To(Equal(0))
+			Expect(fakeHTTPClient.PostInputs).To(HaveLen(4))
+		})
+	})
+
+	Describe("FetchLogs", func() {
+		It("sends a fetch_logs message to the agent and returns the blobstore id", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":{"blobstore_id":"fake-logs-blob-id"}}`, 200, nil)
+
+			blobstoreID, err := agentClient.FetchLogs("job", []string{"fake-errand"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blobstoreID).To(Equal("fake-logs-blob-id"))
+
+			Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "fetch_logs",
+				Arguments: []interface{}{"job", []interface{}{"fake-errand"}},
+				ReplyTo:   replyToAddress,
+			}))
+		})
+	})
+
+	Describe("Drain", func() {
+		It("sends a drain message to the agent and waits for its task to finish", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":0}`, 200, nil)
+
+			finished, err := agentClient.Drain("shutdown", nil, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(finished).To(BeTrue())
+
+			Expect(sentRequest(0)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "drain",
+				Arguments: []interface{}{"shutdown"},
+				ReplyTo:   replyToAddress,
+			}))
+			Expect(sentRequest(2)).To(Equal(bihttpagent.AgentRequestMessage{
+				Method:    "get_task",
+				Arguments: []interface{}{"fake-agent-task-id"},
+				ReplyTo:   replyToAddress,
+			}))
+		})
+
+		It("sends the new apply spec with an update drain", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			fakeHTTPClient.SetPostBehavior(`{"value":0}`, 200, nil)
+
+			_, err := agentClient.Drain("update", &applyspec.ApplySpec{Deployment: "fake-deployment-name"}, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			request := sentRequest(0)
+			Expect(request.Method).To(Equal("drain"))
+			Expect(request.Arguments).To(HaveLen(2))
+			Expect(request.Arguments[0]).To(Equal("update"))
+		})
+
+		It("returns an error when the drain task fails", func() {
+			fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+			for i := 0; i < 3; i++ {
+				fakeHTTPClient.SetPostBehavior(`{"exception":{"message":"fake-drain-error"}}`, 200, nil)
+			}
+
+			finished, err := agentClient.Drain("shutdown", nil, 0)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+			Expect(finished).To(BeFalse())
+		})
+
+		Context("when the drain task is still running after the timeout", func() {
+			BeforeEach(func() {
+				agentClient = NewAgentClient("http://localhost:6305", replyToAddress, 1*time.Second, 2, fakeHTTPClient, timeService, logger)
+
+				fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				for i := 0; i < 3; i++ {
+					fakeHTTPClient.SetPostBehavior(`{"value":{"agent_task_id":"fake-agent-task-id","state":"running"}}`, 200, nil)
+				}
+				fakeHTTPClient.SetPostBehavior(`{"value":"canceled"}`, 200, nil)
+			})
+
+			It("cancels the task and returns that the drain did not finish", func() {
+				finished, err := agentClient.Drain("shutdown", nil, 2*time.Second)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(finished).To(BeFalse())
+
+				Expect(fakeHTTPClient.PostInputs).To(HaveLen(5))
+				Expect(sentRequest(4)).To(Equal(bihttpagent.AgentRequestMessage{
+					Method:    "cancel_task",
+					Arguments: []interface{}{"fake-agent-task-id"},
+					ReplyTo:   replyToAddress,
+				}))
+			})
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package agentclient
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type exception struct {
+	Message string
+}
+
+type stateResponse struct {
+	Value     AgentState
+	Exception *exception
+}
+
+func (r *stateResponse) ServerError() error {
+	if r.Exception != nil {
+		return bosherr.Errorf("Agent responded with error: %s", r.Exception.Message)
+	}
+	return nil
+}
+
+// Unmarshal reads the state, and the apply spec the state is a superset of
+func (r *stateResponse) Unmarshal(message []byte) error {
+	err := json.Unmarshal(message, r)
+	if err != nil {
+		return err
+	}
+
+	var specResponse struct {
+		Value applyspec.ApplySpec
+	}
+	err = json.Unmarshal(message, &specResponse)
+	if err != nil {
+		return err
+	}
+
+	r.Value.Spec = specResponse.Value
+	return nil
+}
@@ -0,0 +1,214 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	fakebiagentclient "github.com/cloudfoundry/bosh-agent/agentclient/fakes"
+	"github.com/cloudfoundry/bosh-init/agentclient"
+)
+
+// FakeAgentClient extends the vendored fake with the actions only bosh-init's client has
+type FakeAgentClient struct {
+	fakebiagentclient.FakeAgentClient
+
+	GetFullStateStub        func() (agentclient.AgentState, error)
+	getFullStateMutex       sync.RWMutex
+	getFullStateArgsForCall []struct{}
+	getFullStateReturns     struct {
+		result1 agentclient.AgentState
+		result2 error
+	}
+	DrainStub        func(drainType string, newSpec *applyspec.ApplySpec, timeout time.Duration) (finished bool, err error)
+	drainMutex       sync.RWMutex
+	drainArgsForCall []struct {
+		drainType string
+		newSpec   *applyspec.ApplySpec
+		timeout   time.Duration
+	}
+	drainReturns struct {
+		result1 bool
+		result2 error
+	}
+	ApplyErrandStub        func(spec applyspec.ApplySpec, errandJobName string) error
+	applyErrandMutex       sync.RWMutex
+	applyErrandArgsForCall []struct {
+		spec          applyspec.ApplySpec
+		errandJobName string
+	}
+	applyErrandReturns struct {
+		result1 error
+	}
+	RunErrandStub        func() (agentclient.ErrandResult, error)
+	runErrandMutex       sync.RWMutex
+	runErrandArgsForCall []struct{}
+	runErrandReturns     struct {
+		result1 agentclient.ErrandResult
+		result2 error
+	}
+	FetchLogsStub        func(logType string, filters []string) (blobstoreID string, err error)
+	fetchLogsMutex       sync.RWMutex
+	fetchLogsArgsForCall []struct {
+		logType string
+		filters []string
+	}
+	fetchLogsReturns struct {
+		result1 string
+		result2 error
+	}
+}
+
+func (fake *FakeAgentClient) GetFullState() (agentclient.AgentState, error) {
+	fake.getFullStateMutex.Lock()
+	fake.getFullStateArgsForCall = append(fake.getFullStateArgsForCall, struct{}{})
+	fake.getFullStateMutex.Unlock()
+	if fake.GetFullStateStub != nil {
+		return fake.GetFullStateStub()
+	} else {
+		return fake.getFullStateReturns.result1, fake.getFullStateReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) GetFullStateCallCount() int {
+	fake.getFullStateMutex.RLock()
+	defer fake.getFullStateMutex.RUnlock()
+	return len(fake.getFullStateArgsForCall)
+}
+
+func (fake *FakeAgentClient) GetFullStateReturns(result1 agentclient.AgentState, result2 error) {
+	fake.GetFullStateStub = nil
+	fake.getFullStateReturns = struct {
+		result1 agentclient.AgentState
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentClient) Drain(drainType string, newSpec *applyspec.ApplySpec, timeout time.Duration) (finished bool, err error) {
+	fake.drainMutex.Lock()
+	fake.drainArgsForCall = append(fake.drainArgsForCall, struct {
+		drainType string
+		newSpec   *applyspec.ApplySpec
+		timeout   time.Duration
+	}{drainType, newSpec, timeout})
+	fake.drainMutex.Unlock()
+	if fake.DrainStub != nil {
+		return fake.DrainStub(drainType, newSpec, timeout)
+	} else {
+		return fake.drainReturns.result1, fake.drainReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) DrainCallCount() int {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	return len(fake.drainArgsForCall)
+}
+
+func (fake *FakeAgentClient) DrainArgsForCall(i int) (string, *applyspec.ApplySpec, time.Duration) {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	return fake.drainArgsForCall[i].drainType, fake.drainArgsForCall[i].newSpec, fake.drainArgsForCall[i].timeout
+}
+
+func (fake *FakeAgentClient) DrainReturns(result1 bool, result2 error) {
+	fake.DrainStub = nil
+	fake.drainReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentClient) ApplyErrand(spec applyspec.ApplySpec, errandJobName string) error {
+	fake.applyErrandMutex.Lock()
+	fake.applyErrandArgsForCall = append(fake.applyErrandArgsForCall, struct {
+		spec          applyspec.ApplySpec
+		errandJobName string
+	}{spec, errandJobName})
+	fake.applyErrandMutex.Unlock()
+	if fake.ApplyErrandStub != nil {
+		return fake.ApplyErrandStub(spec, errandJobName)
+	} else {
+		return fake.applyErrandReturns.result1
+	}
+}
+
+func (fake *FakeAgentClient) ApplyErrandCallCount() int {
+	fake.applyErrandMutex.RLock()
+	defer fake.applyErrandMutex.RUnlock()
+	return len(fake.applyErrandArgsForCall)
+}
+
+func (fake *FakeAgentClient) ApplyErrandArgsForCall(i int) (applyspec.ApplySpec, string) {
+	fake.applyErrandMutex.RLock()
+	defer fake.applyErrandMutex.RUnlock()
+	return fake.applyErrandArgsForCall[i].spec, fake.applyErrandArgsForCall[i].errandJobName
+}
+
+func (fake *FakeAgentClient) ApplyErrandReturns(result1 error) {
+	fake.ApplyErrandStub = nil
+	fake.applyErrandReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgentClient) RunErrand() (agentclient.ErrandResult, error) {
+	fake.runErrandMutex.Lock()
+	fake.runErrandArgsForCall = append(fake.runErrandArgsForCall, struct{}{})
+	fake.runErrandMutex.Unlock()
+	if fake.RunErrandStub != nil {
+		return fake.RunErrandStub()
+	} else {
+		return fake.runErrandReturns.result1, fake.runErrandReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) RunErrandCallCount() int {
+	fake.runErrandMutex.RLock()
+	defer fake.runErrandMutex.RUnlock()
+	return len(fake.runErrandArgsForCall)
+}
+
+func (fake *FakeAgentClient) RunErrandReturns(result1 agentclient.ErrandResult, result2 error) {
+	fake.RunErrandStub = nil
+	fake.runErrandReturns = struct {
+		result1 agentclient.ErrandResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentClient) FetchLogs(logType string, filters []string) (blobstoreID string, err error) {
+	fake.fetchLogsMutex.Lock()
+	fake.fetchLogsArgsForCall = append(fake.fetchLogsArgsForCall, struct {
+		logType string
+		filters []string
+	}{logType, filters})
+	fake.fetchLogsMutex.Unlock()
+	if fake.FetchLogsStub != nil {
+		return fake.FetchLogsStub(logType, filters)
+	} else {
+		return fake.fetchLogsReturns.result1, fake.fetchLogsReturns.result2
+	}
+}
+
+func (fake *FakeAgentClient) FetchLogsCallCount() int {
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	return len(fake.fetchLogsArgsForCall)
+}
+
+func (fake *FakeAgentClient) FetchLogsArgsForCall(i int) (string, []string) {
+	fake.fetchLogsMutex.RLock()
+	defer fake.fetchLogsMutex.RUnlock()
+	return fake.fetchLogsArgsForCall[i].logType, fake.fetchLogsArgsForCall[i].filters
+}
+
+func (fake *FakeAgentClient) FetchLogsReturns(result1 string, result2 error) {
+	fake.FetchLogsStub = nil
+	fake.fetchLogsReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+var _ agentclient.AgentClient = new(FakeAgentClient)
@@ -1,5 +1,5 @@
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/cloudfoundry/bosh-agent/agentclient (interfaces: AgentClient)
+// Source: github.com/cloudfoundry/bosh-init/agentclient (interfaces: AgentClient,AgentClientFactory)
 
 package mocks
 
@@ -7,7 +7,10 @@ import (
 	agentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	applyspec "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	settings "github.com/cloudfoundry/bosh-agent/settings"
+	agentclient0 "github.com/cloudfoundry/bosh-init/agentclient"
+	httpclient "github.com/cloudfoundry/bosh-utils/httpclient"
 	gomock "github.com/golang/mock/gomock"
+	time "time"
 )
 
 // Mock of AgentClient interface
@@ -41,6 +44,16 @@ func (_mr *_MockAgentClientRecorder) Apply(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Apply", arg0)
 }
 
+func (_m *MockAgentClient) ApplyErrand(_param0 applyspec.ApplySpec, _param1 string) error {
+	ret := _m.ctrl.Call(_m, "ApplyErrand", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockAgentClientRecorder) ApplyErrand(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ApplyErrand", arg0, arg1)
+}
+
 func (_m *MockAgentClient) CompilePackage(_param0 agentclient.BlobRef, _param1 []agentclient.BlobRef) (agentclient.BlobRef, error) {
 	ret := _m.ctrl.Call(_m, "CompilePackage", _param0, _param1)
 	ret0, _ := ret[0].(agentclient.BlobRef)
@@ -62,6 +75,39 @@ func (_mr *_MockAgentClientRecorder) DeleteARPEntries(arg0 interface{}) *gomock.
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteARPEntries", arg0)
 }
 
+func (_m *MockAgentClient) Drain(_param0 string, _param1 *applyspec.ApplySpec, _param2 time.Duration) (bool, error) {
+	ret := _m.ctrl.Call(_m, "Drain", _param0, _param1, _param2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) Drain(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Drain", arg0, arg1, arg2)
+}
+
+func (_m *MockAgentClient) FetchLogs(_param0 string, _param1 []string) (string, error) {
+	ret := _m.ctrl.Call(_m, "FetchLogs", _param0, _param1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) FetchLogs(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "FetchLogs", arg0, arg1)
+}
+
+func (_m *MockAgentClient) GetFullState() (agentclient0.AgentState, error) {
+	ret := _m.ctrl.Call(_m, "GetFullState")
+	ret0, _ := ret[0].(agentclient0.AgentState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) GetFullState() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetFullState")
+}
+
 func (_m *MockAgentClient) GetState() (agentclient.AgentState, error) {
 	ret := _m.ctrl.Call(_m, "GetState")
 	ret0, _ := ret[0].(agentclient.AgentState)
@@ -115,6 +161,17 @@ func (_mr *_MockAgentClientRecorder) Ping() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Ping")
 }
 
+func (_m *MockAgentClient) RunErrand() (agentclient0.ErrandResult, error) {
+	ret := _m.ctrl.Call(_m, "RunErrand")
+	ret0, _ := ret[0].(agentclient0.ErrandResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAgentClientRecorder) RunErrand() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RunErrand")
+}
+
 func (_m *MockAgentClient) RunScript(_param0 string, _param1 map[string]interface{}) error {
 	ret := _m.ctrl.Call(_m, "RunScript", _param0, _param1)
 	ret0, _ := ret[0].(error)
@@ -175,3 +232,34 @@ func (_m *MockAgentClient) UpdateSettings(_param0 settings.Settings) error {
 func (_mr *_MockAgentClientRecorder) UpdateSettings(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateSettings", arg0)
 }
+
+// Mock of AgentClientFactory interface
+type MockAgentClientFactory struct {
+	ctrl     *gomock.Controller
+	recorder *_MockAgentClientFactoryRecorder
+}
+
+// Recorder for MockAgentClientFactory (not exported)
+type _MockAgentClientFactoryRecorder struct {
+	mock *MockAgentClientFactory
+}
+
+func NewMockAgentClientFactory(ctrl *gomock.Controller) *MockAgentClientFactory {
+	mock := &MockAgentClientFactory{ctrl: ctrl}
+	mock.recorder = &_MockAgentClientFactoryRecorder{mock}
+	return mock
+}
+
+func (_m *MockAgentClientFactory) EXPECT() *_MockAgentClientFactoryRecorder {
+	return _m.recorder
+}
+
+func (_m *MockAgentClientFactory) NewAgentClient(_param0 string, _param1 string, _param2 httpclient.Client) agentclient0.AgentClient {
+	ret := _m.ctrl.Call(_m, "NewAgentClient", _param0, _param1, _param2)
+	ret0, _ := ret[0].(agentclient0.AgentClient)
+	return ret0
+}
+
+func (_mr *_MockAgentClientFactoryRecorder) NewAgentClient(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "NewAgentClient", arg0, arg1, arg2)
+}
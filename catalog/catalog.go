@@ -0,0 +1,33 @@
+package catalog
+
+import (
+	"strings"
+
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Entry is a release or stemcell tarball listed in an index
+type Entry struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	URL     string `yaml:"url"`
+	SHA1    string `yaml:"sha1"`
+	// Signature is the URL of a detached signature of the tarball
+	Signature string `yaml:"signature"`
+}
+
+// Catalog is an index listing the versions of releases and stemcells available by name
+type Catalog interface {
+	Releases(name string) ([]Entry, error)
+	Stemcells(name string) ([]Entry, error)
+}
+
+// NewCatalog returns the catalog at location, which is either an http(s) URL or the path of a YAML or JSON index file
+func NewCatalog(location string, fs boshsys.FileSystem, httpClient bihttpclient.HTTPClient, logger boshlog.Logger) Catalog {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewHTTPCatalog(location, httpClient, logger)
+	}
+	return NewFileCatalog(strings.TrimPrefix(location, "file://"), fs, logger)
+}
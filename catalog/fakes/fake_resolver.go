@@ -0,0 +1,40 @@
+package fakes
+
+import (
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
+)
+
+type FakeResolver struct {
+	ResolveReleaseInputs []ResolveInput
+	ResolveReleaseEntry  bicatalog.Entry
+	ResolveReleaseErr    error
+
+	ResolveStemcellInputs []ResolveInput
+	ResolveStemcellEntry  bicatalog.Entry
+	ResolveStemcellErr    error
+
+	ResolvedRefsRefs []bicatalog.ResolvedRef
+}
+
+type ResolveInput struct {
+	Name    string
+	Version string
+}
+
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{}
+}
+
+func (r *FakeResolver) ResolveRelease(name, version string) (bicatalog.Entry, error) {
+	r.ResolveReleaseInputs = append(r.ResolveReleaseInputs, ResolveInput{Name: name, Version: version})
+	return r.ResolveReleaseEntry, r.ResolveReleaseErr
+}
+
+func (r *FakeResolver) ResolveStemcell(name, version string) (bicatalog.Entry, error) {
+	r.ResolveStemcellInputs = append(r.ResolveStemcellInputs, ResolveInput{Name: name, Version: version})
+	return r.ResolveStemcellEntry, r.ResolveStemcellErr
+}
+
+func (r *FakeResolver) ResolvedRefs() []bicatalog.ResolvedRef {
+	return r.ResolvedRefsRefs
+}
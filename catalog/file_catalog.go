@@ -0,0 +1,78 @@
+package catalog
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"gopkg.in/yaml.v2"
+)
+
+type fileCatalog struct {
+	path   string
+	fs     boshsys.FileSystem
+	logger boshlog.Logger
+	logTag string
+}
+
+type catalogFile struct {
+	Releases  []Entry `yaml:"releases"`
+	Stemcells []Entry `yaml:"stemcells"`
+}
+
+// NewFileCatalog returns a catalog read from a YAML or JSON file listing 'releases' and 'stemcells'
+// with their name, version, url and sha1
+func NewFileCatalog(path string, fs boshsys.FileSystem, logger boshlog.Logger) Catalog {
+	return &fileCatalog{
+		path:   path,
+		fs:     fs,
+		logger: logger,
+		logTag: "fileCatalog",
+	}
+}
+
+func (i *fileCatalog) Releases(name string) ([]Entry, error) {
+	contents, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	return filterByName(contents.Releases, name), nil
+}
+
+func (i *fileCatalog) Stemcells(name string) ([]Entry, error) {
+	contents, err := i.read()
+	if err != nil {
+		return nil, err
+	}
+	return filterByName(contents.Stemcells, name), nil
+}
+
+func (i *fileCatalog) read() (catalogFile, error) {
+	expandedPath, err := i.fs.ExpandPath(i.path)
+	if err != nil {
+		return catalogFile{}, bosherr.WrapErrorf(err, "Expanding index path '%s'", i.path)
+	}
+
+	bytes, err := i.fs.ReadFile(expandedPath)
+	if err != nil {
+		return catalogFile{}, bosherr.WrapErrorf(err, "Reading index file '%s'", expandedPath)
+	}
+
+	contents := catalogFile{}
+	err = yaml.Unmarshal(bytes, &contents)
+	if err != nil {
+		return catalogFile{}, bosherr.WrapErrorf(err, "Parsing index file '%s'", expandedPath)
+	}
+	i.logger.Debug(i.logTag, "Read index file '%s': %#v", expandedPath, contents)
+
+	return contents, nil
+}
+
+func filterByName(entries []Entry, name string) []Entry {
+	matching := []Entry{}
+	for _, entry := range entries {
+		if entry.Name == name {
+			matching = append(matching, entry)
+		}
+	}
+	return matching
+}
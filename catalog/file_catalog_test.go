@@ -0,0 +1,63 @@
+package catalog_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("FileCatalog", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		catalog Catalog
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fs.ExpandPathExpanded = "/fake/index.yml"
+		catalog = NewFileCatalog("~/index.yml", fs, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("lists the releases and stemcells of a YAML index file by name", func() {
+		fs.WriteFileString("/fake/index.yml", `---
+releases:
+- {name: fake-release, version: "1", url: "https://fake-release-1", sha1: fake-sha1-1}
+- {name: fake-other-release, version: "1", url: "https://fake-other-release-1", sha1: fake-other-sha1}
+- {name: fake-release, version: "2", url: "https://fake-release-2", sha1: fake-sha1-2}
+stemcells:
+- {name: fake-stemcell, version: "3262.2", url: "https://fake-stemcell", sha1: fake-stemcell-sha1}
+`)
+
+		releases, err := catalog.Releases("fake-release")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(Equal([]Entry{
+			{Name: "fake-release", Version: "1", URL: "https://fake-release-1", SHA1: "fake-sha1-1"},
+			{Name: "fake-release", Version: "2", URL: "https://fake-release-2", SHA1: "fake-sha1-2"},
+		}))
+
+		stemcells, err := catalog.Stemcells("fake-stemcell")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stemcells).To(Equal([]Entry{
+			{Name: "fake-stemcell", Version: "3262.2", URL: "https://fake-stemcell", SHA1: "fake-stemcell-sha1"},
+		}))
+	})
+
+	It("reads JSON index files", func() {
+		fs.WriteFileString("/fake/index.yml", `{"releases": [{"name": "fake-release", "version": "1", "url": "https://fake-release-1", "sha1": "fake-sha1-1"}]}`)
+
+		releases, err := catalog.Releases("fake-release")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(Equal([]Entry{
+			{Name: "fake-release", Version: "1", URL: "https://fake-release-1", SHA1: "fake-sha1-1"},
+		}))
+	})
+
+	It("returns an error when the index file can't be read", func() {
+		_, err := catalog.Releases("fake-release")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading index file '/fake/index.yml'"))
+	})
+})
@@ -0,0 +1,110 @@
+package catalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type httpCatalog struct {
+	url        string
+	httpClient bihttpclient.HTTPClient
+	logger     boshlog.Logger
+	logTag     string
+}
+
+// httpEntry is an entry of the bosh.io API, where stemcells list their tarballs as 'regular' or 'light'
+type httpEntry struct {
+	Name      string       `json:"name"`
+	Version   string       `json:"version"`
+	URL       string       `json:"url"`
+	SHA1      string       `json:"sha1"`
+	Signature string       `json:"signature"`
+	Regular   *httpTarball `json:"regular"`
+	Light     *httpTarball `json:"light"`
+}
+
+type httpTarball struct {
+	URL  string `json:"url"`
+	SHA1 string `json:"sha1"`
+}
+
+// NewHTTPCatalog returns a catalog served like the bosh.io API:
+// GET <url>/releases/<name> and GET <url>/stemcells/<name> return a JSON list of versions
+func NewHTTPCatalog(url string, httpClient bihttpclient.HTTPClient, logger boshlog.Logger) Catalog {
+	return &httpCatalog{
+		url:        strings.TrimSuffix(url, "/"),
+		httpClient: httpClient,
+		logger:     logger,
+		logTag:     "httpCatalog",
+	}
+}
+
+func (i *httpCatalog) Releases(name string) ([]Entry, error) {
+	return i.get("releases", name)
+}
+
+func (i *httpCatalog) Stemcells(name string) ([]Entry, error) {
+	return i.get("stemcells", name)
+}
+
+func (i *httpCatalog) get(kind, name string) ([]Entry, error) {
+	endpoint := i.url + "/" + kind + "/" + (&url.URL{Path: name}).EscapedPath()
+
+	response, err := i.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Querying index '%s'", endpoint)
+	}
+	defer func() {
+		if err = response.Body.Close(); err != nil {
+			i.logger.Warn(i.logTag, "Failed to close index response body: %s", err.Error())
+		}
+	}()
+
+	if response.StatusCode == http.StatusNotFound {
+		return []Entry{}, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, bosherr.Errorf("Querying index '%s': unexpected response status '%s'", endpoint, response.Status)
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading index response from '%s'", endpoint)
+	}
+
+	httpEntries := []httpEntry{}
+	err = json.Unmarshal(body, &httpEntries)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing index response from '%s'", endpoint)
+	}
+
+	entries := []Entry{}
+	for _, httpEntry := range httpEntries {
+		entry := Entry{
+			Name:      name,
+			Version:   httpEntry.Version,
+			URL:       httpEntry.URL,
+			SHA1:      httpEntry.SHA1,
+			Signature: httpEntry.Signature,
+		}
+
+		for _, tarball := range []*httpTarball{httpEntry.Regular, httpEntry.Light} {
+			if entry.URL == "" && tarball != nil {
+				entry.URL = tarball.URL
+				entry.SHA1 = tarball.SHA1
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package catalog_test
+
+import (
+	"net/http"
+
+	. "github.com/cloudfoundry/bosh-init/catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("HTTPCatalog", func() {
+	var (
+		server  *ghttp.Server
+		catalog Catalog
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		catalog = NewHTTPCatalog(server.URL()+"/api/v1/", bihttpclient.NewHTTPClient(http.DefaultClient, logger), logger)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists the versions of a release", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/api/v1/releases/github.com/cloudfoundry/bosh"),
+			ghttp.RespondWith(http.StatusOK, `[
+				{"name": "github.com/cloudfoundry/bosh", "version": "257.3", "url": "https://fake-bosh-257.3", "sha1": "fake-sha1"}
+			]`),
+		))
+
+		releases, err := catalog.Releases("github.com/cloudfoundry/bosh")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(Equal([]Entry{
+			{Name: "github.com/cloudfoundry/bosh", Version: "257.3", URL: "https://fake-bosh-257.3", SHA1: "fake-sha1"},
+		}))
+	})
+
+	It("uses the regular tarball of stemcells, or else the light tarball", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/api/v1/stemcells/fake-stemcell"),
+			ghttp.RespondWith(http.StatusOK, `[
+				{"name": "fake-stemcell", "version": "2", "regular": {"url": "https://fake-regular", "sha1": "fake-regular-sha1"}, "light": {"url": "https://fake-light-2", "sha1": "fake-light-sha1-2"}},
+				{"name": "fake-stemcell", "version": "1", "light": {"url": "https://fake-light-1", "sha1": "fake-light-sha1-1"}}
+			]`),
+		))
+
+		stemcells, err := catalog.Stemcells("fake-stemcell")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stemcells).To(Equal([]Entry{
+			{Name: "fake-stemcell", Version: "2", URL: "https://fake-regular", SHA1: "fake-regular-sha1"},
+			{Name: "fake-stemcell", Version: "1", URL: "https://fake-light-1", SHA1: "fake-light-sha1-1"},
+		}))
+	})
+
+	It("returns no versions when the index does not know the name", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+
+		releases, err := catalog.Releases("fake-release")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(BeEmpty())
+	})
+
+	It("returns an error when the index responds with an error", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, ""))
+
+		_, err := catalog.Releases("fake-release")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unexpected response status '500 Internal Server Error'"))
+	})
+})
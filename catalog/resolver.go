@@ -0,0 +1,180 @@
+package catalog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// LatestVersion resolves to the highest version listed in the index
+const LatestVersion = "latest"
+
+const (
+	ReleaseType  = "release"
+	StemcellType = "stemcell"
+)
+
+const noIndexMessage = "no index configured, set 'index' in the deployment manifest or BOSH_INIT_INDEX"
+
+// ResolvedRef is a release or stemcell version of the manifest and the index entry it resolved to
+type ResolvedRef struct {
+	Type             string
+	RequestedVersion string
+	Entry            Entry
+}
+
+// CatalogLoader returns the configured index, or nil when no index is configured
+type CatalogLoader func() (Catalog, error)
+
+// Resolver resolves a release or stemcell name and version to the tarball listed in an index
+type Resolver interface {
+	ResolveRelease(name, version string) (Entry, error)
+	ResolveStemcell(name, version string) (Entry, error)
+	// ResolvedRefs returns what was resolved so far, so that it can be recorded once the deploy succeeded
+	ResolvedRefs() []ResolvedRef
+}
+
+type resolver struct {
+	catalogLoader CatalogLoader
+	catalog       Catalog
+	catalogLoaded bool
+	resolvedRefs  []ResolvedRef
+	lock          sync.Mutex
+	logger        boshlog.Logger
+	logTag        string
+}
+
+// NewResolver returns a resolver that loads the catalog the first time a version is resolved
+func NewResolver(catalogLoader CatalogLoader, logger boshlog.Logger) Resolver {
+	return &resolver{
+		catalogLoader: catalogLoader,
+		resolvedRefs:  []ResolvedRef{},
+		logger:        logger,
+		logTag:        "catalogResolver",
+	}
+}
+
+func (r *resolver) ResolveRelease(name, version string) (Entry, error) {
+	catalog, err := r.loadCatalog()
+	if err != nil {
+		return Entry{}, bosherr.WrapErrorf(err, "Resolving release '%s' version '%s'", name, version)
+	}
+
+	if catalog == nil {
+		return Entry{}, bosherr.Errorf("Resolving release '%s' version '%s': %s", name, version, noIndexMessage)
+	}
+
+	entries, err := catalog.Releases(name)
+	if err != nil {
+		return Entry{}, bosherr.WrapErrorf(err, "Listing versions of release '%s'", name)
+	}
+
+	return r.resolve(ReleaseType, name, version, entries)
+}
+
+func (r *resolver) ResolveStemcell(name, version string) (Entry, error) {
+	catalog, err := r.loadCatalog()
+	if err != nil {
+		return Entry{}, bosherr.WrapErrorf(err, "Resolving stemcell '%s' version '%s'", name, version)
+	}
+
+	if catalog == nil {
+		return Entry{}, bosherr.Errorf("Resolving stemcell '%s' version '%s': %s", name, version, noIndexMessage)
+	}
+
+	entries, err := catalog.Stemcells(name)
+	if err != nil {
+		return Entry{}, bosherr.WrapErrorf(err, "Listing versions of stemcell '%s'", name)
+	}
+
+	return r.resolve(StemcellType, name, version, entries)
+}
+
+func (r *resolver) ResolvedRefs() []ResolvedRef {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]ResolvedRef{}, r.resolvedRefs...)
+}
+
+// loadCatalog loads the catalog once, as releases and stemcells are resolved concurrently
+func (r *resolver) loadCatalog() (Catalog, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.catalogLoaded {
+		return r.catalog, nil
+	}
+
+	catalog, err := r.catalogLoader()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Loading index")
+	}
+
+	r.catalog = catalog
+	r.catalogLoaded = true
+	return r.catalog, nil
+}
+
+func (r *resolver) resolve(refType, name, version string, entries []Entry) (Entry, error) {
+	var resolved *Entry
+	for i, entry := range entries {
+		if entry.URL == "" {
+			continue
+		}
+
+		if version == LatestVersion {
+			if resolved == nil || compareVersions(entry.Version, resolved.Version) > 0 {
+				resolved = &entries[i]
+			}
+		} else if entry.Version == version {
+			resolved = &entries[i]
+			break
+		}
+	}
+
+	if resolved == nil {
+		return Entry{}, bosherr.Errorf("Could not find %s '%s' version '%s' in the index", refType, name, version)
+	}
+
+	r.logger.Info(r.logTag, "Resolved %s '%s' version '%s' to version '%s' at '%s'", refType, name, version, resolved.Version, resolved.URL)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	resolvedRefs := []ResolvedRef{}
+	for _, resolvedRef := range r.resolvedRefs {
+		if resolvedRef.Type != refType || resolvedRef.Entry.Name != resolved.Name {
+			resolvedRefs = append(resolvedRefs, resolvedRef)
+		}
+	}
+	r.resolvedRefs = append(resolvedRefs, ResolvedRef{Type: refType, RequestedVersion: version, Entry: *resolved})
+
+	return *resolved, nil
+}
+
+// compareVersions compares dot separated versions, numerically when both segments are numbers
+func compareVersions(a, b string) int {
+	aSegments := strings.Split(a, ".")
+	bSegments := strings.Split(b, ".")
+
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		aNum, aErr := strconv.Atoi(aSegments[i])
+		bNum, bErr := strconv.Atoi(bSegments[i])
+
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aSegments[i] != bSegments[i]:
+			return strings.Compare(aSegments[i], bSegments[i])
+		}
+	}
+
+	return len(aSegments) - len(bSegments)
+}
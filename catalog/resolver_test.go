@@ -0,0 +1,124 @@
+package catalog_test
+
+import (
+	"errors"
+
+	. "github.com/cloudfoundry/bosh-init/catalog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type fakeCatalog struct {
+	releases  []Entry
+	stemcells []Entry
+	err       error
+}
+
+func (i fakeCatalog) Releases(name string) ([]Entry, error)  { return i.releases, i.err }
+func (i fakeCatalog) Stemcells(name string) ([]Entry, error) { return i.stemcells, i.err }
+
+var _ = Describe("Resolver", func() {
+	var (
+		catalog  fakeCatalog
+		resolver Resolver
+		logger   boshlog.Logger
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		catalog = fakeCatalog{
+			releases: []Entry{
+				{Name: "fake-release", Version: "257.3", URL: "https://fake-257.3", SHA1: "fake-sha1-257.3"},
+				{Name: "fake-release", Version: "257.15", URL: "https://fake-257.15", SHA1: "fake-sha1-257.15"},
+				{Name: "fake-release", Version: "99", URL: "https://fake-99", SHA1: "fake-sha1-99"},
+			},
+			stemcells: []Entry{
+				{Name: "fake-stemcell", Version: "3262.2", URL: "https://fake-stemcell", SHA1: "fake-stemcell-sha1"},
+			},
+		}
+		resolver = NewResolver(func() (Catalog, error) { return catalog, nil }, logger)
+	})
+
+	It("resolves a release version and returns it with the resolved refs", func() {
+		entry, err := resolver.ResolveRelease("fake-release", "257.3")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entry).To(Equal(Entry{Name: "fake-release", Version: "257.3", URL: "https://fake-257.3", SHA1: "fake-sha1-257.3"}))
+		Expect(resolver.ResolvedRefs()).To(Equal([]ResolvedRef{{Type: "release", RequestedVersion: "257.3", Entry: entry}}))
+	})
+
+	It("keeps the last resolution of a release", func() {
+		_, err := resolver.ResolveRelease("fake-release", "257.3")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = resolver.ResolveStemcell("fake-stemcell", "latest")
+		Expect(err).ToNot(HaveOccurred())
+		entry, err := resolver.ResolveRelease("fake-release", "latest")
+		Expect(err).ToNot(HaveOccurred())
+
+		resolvedRefs := resolver.ResolvedRefs()
+		Expect(resolvedRefs).To(HaveLen(2))
+		Expect(resolvedRefs[0].Type).To(Equal("stemcell"))
+		Expect(resolvedRefs[1]).To(Equal(ResolvedRef{Type: "release", RequestedVersion: "latest", Entry: entry}))
+	})
+
+	It("resolves 'latest' to the highest version", func() {
+		entry, err := resolver.ResolveRelease("fake-release", "latest")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entry.Version).To(Equal("257.15"))
+		Expect(resolver.ResolvedRefs()[0].RequestedVersion).To(Equal("latest"))
+	})
+
+	It("resolves a stemcell version", func() {
+		entry, err := resolver.ResolveStemcell("fake-stemcell", "3262.2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entry.URL).To(Equal("https://fake-stemcell"))
+		Expect(resolver.ResolvedRefs()[0].Type).To(Equal("stemcell"))
+	})
+
+	It("returns an error when the version is not in the index", func() {
+		_, err := resolver.ResolveRelease("fake-release", "1")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Could not find release 'fake-release' version '1' in the index"))
+		Expect(resolver.ResolvedRefs()).To(BeEmpty())
+	})
+
+	It("returns an error when the index can't be listed", func() {
+		catalog.err = errors.New("fake-index-err")
+
+		_, err := resolver.ResolveStemcell("fake-stemcell", "latest")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-index-err"))
+	})
+
+	It("returns an error when no index is configured", func() {
+		resolver = NewResolver(func() (Catalog, error) { return nil, nil }, logger)
+
+		_, err := resolver.ResolveRelease("fake-release", "latest")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no index configured, set 'index' in the deployment manifest or BOSH_INIT_INDEX"))
+	})
+
+	It("loads the index once", func() {
+		loads := 0
+		resolver = NewResolver(func() (Catalog, error) {
+			loads++
+			return catalog, nil
+		}, logger)
+
+		_, err := resolver.ResolveRelease("fake-release", "latest")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = resolver.ResolveStemcell("fake-stemcell", "latest")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loads).To(Equal(1))
+	})
+
+	It("returns an error when the index can't be loaded", func() {
+		resolver = NewResolver(func() (Catalog, error) { return nil, errors.New("fake-load-err") }, logger)
+
+		_, err := resolver.ResolveStemcell("fake-stemcell", "latest")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Loading index"))
+		Expect(err.Error()).To(ContainSubstring("fake-load-err"))
+	})
+})
@@ -25,6 +25,7 @@ type Cloud interface {
 	AttachDisk(vmCID, diskCID string) error
 	DetachDisk(vmCID, diskCID string) error
 	DeleteDisk(diskCID string) error
+	HasDisk(diskCID string) (bool, error)
 	fmt.Stringer
 }
 
@@ -93,21 +94,7 @@ func (c cloud) DeleteStemcell(stemcellCID string) error {
 }
 
 func (c cloud) HasVM(vmCID string) (bool, error) {
-	method := "has_vm"
-	cmdOutput, err := c.cpiCmdRunner.Run(c.context, method, vmCID)
-	if err != nil {
-		return false, err
-	}
-
-	if cmdOutput.Error != nil {
-		return false, NewCPIError(method, *cmdOutput.Error)
-	}
-
-	found, ok := cmdOutput.Result.(bool)
-	if !ok {
-		return false, bosherr.Errorf("Unexpected external CPI command result: '%#v'", cmdOutput.Result)
-	}
-	return found, nil
+	return c.has("has_vm", vmCID)
 }
 
 func (c cloud) CreateVM(
@@ -264,6 +251,27 @@ func (c cloud) DeleteDisk(diskCID string) error {
 	return nil
 }
 
+func (c cloud) HasDisk(diskCID string) (bool, error) {
+	return c.has("has_disk", diskCID)
+}
+
+func (c cloud) has(method string, cid string) (bool, error) {
+	cmdOutput, err := c.cpiCmdRunner.Run(c.context, method, cid)
+	if err != nil {
+		return false, err
+	}
+
+	if cmdOutput.Error != nil {
+		return false, NewCPIError(method, *cmdOutput.Error)
+	}
+
+	found, ok := cmdOutput.Result.(bool)
+	if !ok {
+		return false, bosherr.Errorf("Unexpected external CPI command result: '%#v'", cmdOutput.Result)
+	}
+	return found, nil
+}
+
 func (c cloud) String() string {
 	return fmt.Sprintf("Cloud{Context=%s}", c.context)
 }
@@ -200,6 +200,41 @@ var _ = Describe("Cloud", func() {
 		})
 	})
 
+	Describe("HasDisk", func() {
+		It("return true when disk exists", func() {
+			fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+				Result: true,
+			}
+
+			found, err := cloud.HasDisk("fake-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+
+			Expect(fakeCPICmdRunner.RunInputs).To(Equal([]fakebicloud.RunInput{
+				{
+					Context:   context,
+					Method:    "has_disk",
+					Arguments: []interface{}{"fake-disk-cid"},
+				},
+			}))
+		})
+
+		It("return false when disk does not exist", func() {
+			fakeCPICmdRunner.RunCmdOutput = CmdOutput{
+				Result: false,
+			}
+
+			found, err := cloud.HasDisk("fake-disk-cid")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		itHandlesCPIErrors("has_disk", func() error {
+			_, err := cloud.HasDisk("fake-disk-cid")
+			return err
+		})
+	})
+
 	Describe("CreateVM", func() {
 		var (
 			agentID           string
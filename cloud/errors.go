@@ -9,6 +9,8 @@ const (
 	DiskNotFoundError     = "Bosh::Clouds::DiskNotFound"
 	StemcellNotFoundError = "Bosh::Clouds::StemcellNotFound"
 	NotImplementedError   = "Bosh::Clouds::NotImplemented"
+	// InvalidCallError is the type of the error CPIs respond with to methods they do not know
+	InvalidCallError = "InvalidCall"
 )
 
 type Error interface {
@@ -34,6 +34,10 @@ type FakeCloud struct {
 	DeleteDiskInputs []DeleteDiskInput
 	DeleteDiskErr    error
 
+	HasDiskInput HasDiskInput
+	HasDiskFound bool
+	HasDiskErr   error
+
 	DeleteStemcellInputs []DeleteStemcellInput
 	DeleteStemcellErr    error
 
@@ -47,6 +51,10 @@ type CreateStemcellInput struct {
 	CloudProperties biproperty.Map
 }
 
+type HasDiskInput struct {
+	DiskCID string
+}
+
 type HasVMInput struct {
 	VMCID string
 }
@@ -110,6 +118,13 @@ func (c *FakeCloud) DeleteStemcell(stemcellCID string) error {
 	return c.DeleteStemcellErr
 }
 
+func (c *FakeCloud) HasDisk(diskCID string) (bool, error) {
+	c.HasDiskInput = HasDiskInput{
+		DiskCID: diskCID,
+	}
+	return c.HasDiskFound, c.HasDiskErr
+}
+
 func (c *FakeCloud) HasVM(vmCID string) (bool, error) {
 	c.HasVMInput = HasVMInput{
 		VMCID: vmCID,
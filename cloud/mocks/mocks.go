@@ -114,6 +114,17 @@ func (_mr *_MockCloudRecorder) DetachDisk(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DetachDisk", arg0, arg1)
 }
 
+func (_m *MockCloud) HasDisk(_param0 string) (bool, error) {
+	ret := _m.ctrl.Call(_m, "HasDisk", _param0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockCloudRecorder) HasDisk(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "HasDisk", arg0)
+}
+
 func (_m *MockCloud) HasVM(_param0 string) (bool, error) {
 	ret := _m.ctrl.Call(_m, "HasVM", _param0)
 	ret0, _ := ret[0].(bool)
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	biuifmt "github.com/cloudfoundry/bosh-init/ui/fmt"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+type cacheCmd struct {
+	ui               biui.UI
+	cacheProvider    func() (bitarball.Cache, error)
+	digestCalculator bicrypto.DigestCalculator
+	timeService      clock.Clock
+	logger           boshlog.Logger
+	logTag           string
+}
+
+func NewCacheCmd(
+	ui biui.UI,
+	cacheProvider func() (bitarball.Cache, error),
+	digestCalculator bicrypto.DigestCalculator,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Cmd {
+	return &cacheCmd{
+		ui:               ui,
+		cacheProvider:    cacheProvider,
+		digestCalculator: digestCalculator,
+		timeService:      timeService,
+		logger:           logger,
+		logTag:           "cacheCmd",
+	}
+}
+
+func (c *cacheCmd) Name() string {
+	return "cache"
+}
+
+func (c *cacheCmd) Meta() Meta {
+	env := map[string]MetaEnv{
+		"BOSH_INIT_CACHE_MAX_SIZE": MetaEnv{
+			Example:     "20G",
+			Default:     "unbounded",
+			Description: "Size the download cache is kept under by evicting the least recently used tarballs",
+		},
+	}
+	for name, metaEnv := range genericEnv {
+		env[name] = metaEnv
+	}
+
+	return Meta{
+		Synopsis: "List, prune or verify the downloaded release and stemcell tarballs and the built dev releases",
+		Usage:    "list | prune --older-than <duration> | verify",
+		Env:      env,
+	}
+}
+
+func (c *cacheCmd) Run(stage biui.Stage, args []string) error {
+	if len(args) == 0 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return bosherr.Error("Invalid usage - cache command requires a subcommand: list, prune or verify")
+	}
+
+	cache, err := c.cacheProvider()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return bosherr.Error("Invalid usage - cache list takes no arguments")
+		}
+		return c.list(cache)
+	case "prune":
+		if len(args) != 3 || args[1] != "--older-than" {
+			return bosherr.Error("Invalid usage - cache prune requires --older-than <duration>")
+		}
+		age, err := parseAge(args[2])
+		if err != nil {
+			return err
+		}
+		return c.prune(cache, age)
+	case "verify":
+		if len(args) != 1 {
+			return bosherr.Error("Invalid usage - cache verify takes no arguments")
+		}
+		return c.verify(cache, stage)
+	}
+
+	return bosherr.Errorf("Invalid usage - unknown cache subcommand '%s', must be list, prune or verify", args[0])
+}
+
+func (c *cacheCmd) list(cache bitarball.Cache) error {
+	entries, err := cache.List()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing cached tarballs")
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := tabwriter.NewWriter(buffer, 0, 8, 2, ' ', 0)
+
+	var totalSize int64
+	fmt.Fprintln(writer, "Source\tDigest\tSize\tLast Used")
+	for _, entry := range entries {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", c.source(entry), entry.SHA1, biuifmt.Bytes(entry.Size), c.lastUsed(entry))
+		totalSize += entry.Size
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return bosherr.WrapError(err, "Writing cache table")
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n") {
+		c.ui.PrintLinef("%s", strings.TrimRight(line, " "))
+	}
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("%d tarballs, %s", len(entries), biuifmt.Bytes(totalSize))
+
+	return nil
+}
+
+func (c *cacheCmd) prune(cache bitarball.Cache, age time.Duration) error {
+	entries, err := cache.List()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing cached tarballs")
+	}
+
+	cutoff := c.timeService.Now().Add(-age)
+
+	var count int
+	var freed int64
+	for _, entry := range entries {
+		if !entry.LastUsed.Before(cutoff) {
+			continue
+		}
+
+		err = cache.Delete(entry)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Pruning '%s'", c.source(entry))
+		}
+
+		c.ui.PrintLinef("Deleted %s (%s, last used %s)", c.source(entry), biuifmt.Bytes(entry.Size), c.lastUsed(entry))
+		count++
+		freed += entry.Size
+	}
+
+	c.ui.PrintLinef("Pruned %d tarballs, freed %s", count, biuifmt.Bytes(freed))
+	return nil
+}
+
+func (c *cacheCmd) verify(cache bitarball.Cache, stage biui.Stage) error {
+	entries, err := cache.List()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing cached tarballs")
+	}
+
+	corrupt := []string{}
+	for _, entry := range entries {
+		entry := entry
+		err = stage.Perform(fmt.Sprintf("Verifying %s", c.source(entry)), func() error {
+			digest, err := bicrypto.ParseMultipleDigest(entry.SHA1)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Parsing digest of '%s'", entry.Path)
+			}
+
+			err = digest.Verify(c.digestCalculator, entry.Path)
+			if err == nil {
+				return nil
+			}
+			if _, mismatch := err.(bicrypto.DigestMismatchError); !mismatch {
+				return err
+			}
+
+			corrupt = append(corrupt, fmt.Sprintf("%s: %s", c.source(entry), err.Error()))
+			return cache.Delete(entry)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(corrupt) > 0 {
+		c.ui.PrintLinef("")
+		c.ui.PrintLinef("Deleted %d corrupt tarballs:", len(corrupt))
+		for _, message := range corrupt {
+			c.ui.PrintLinef("  %s", message)
+		}
+		return nil
+	}
+
+	c.ui.PrintLinef("Verified %d tarballs", len(entries))
+	return nil
+}
+
+// source is the URL of the entry, or its path when it was saved before the cache recorded URLs
+func (c *cacheCmd) source(entry bitarball.CacheEntry) string {
+	if entry.URL == "" {
+		return entry.Path
+	}
+	return entry.URL
+}
+
+func (c *cacheCmd) lastUsed(entry bitarball.CacheEntry) string {
+	if entry.LastUsed.IsZero() {
+		return "unknown"
+	}
+	return entry.LastUsed.Local().Format("2006-01-02 15:04:05 MST")
+}
+
+// parseAge parses a Go duration such as '72h', or a number of days such as '7d'
+func parseAge(age string) (time.Duration, error) {
+	if strings.HasSuffix(age, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(age, "d"))
+		if err == nil && days >= 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	} else if duration, err := time.ParseDuration(age); err == nil && duration >= 0 {
+		return duration, nil
+	}
+
+	return 0, bosherr.Errorf("Invalid usage - --older-than '%s' must be a duration such as '72h' or '7d'", age)
+}
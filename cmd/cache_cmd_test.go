@@ -0,0 +1,141 @@
+package cmd_test
+
+import (
+	"errors"
+	"time"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("CacheCmd", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		fakeTimeService  *fakeclock.FakeClock
+		cache            bitarball.Cache
+		digestCalculator *fakebicrypto.FakeDigestCalculator
+		fakeUI           *fakebiui.FakeUI
+		fakeStage        *fakebiui.FakeStage
+		command          bicmd.Cmd
+
+		oldSource, newSource bitarball.Source
+		oldPath, newPath     string
+	)
+
+	saveTarball := func(source bitarball.Source, contents string) string {
+		Expect(fs.WriteFileString("/fake-download", contents)).To(Succeed())
+		Expect(cache.Save("/fake-download", source)).To(Succeed())
+		return cache.Path(source)
+	}
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fakeTimeService = fakeclock.NewFakeClock(time.Date(2016, time.March, 1, 12, 0, 0, 0, time.UTC))
+		cache = bitarball.NewCache("/fake-downloads", 0, fs, fakeTimeService, logger)
+		digestCalculator = fakebicrypto.NewFakeDigestCalculator()
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+
+		command = bicmd.NewCacheCmd(fakeUI, func() (bitarball.Cache, error) { return cache, nil }, digestCalculator, fakeTimeService, logger)
+
+		oldSource = bitarball.NewSource("https://fake-old-url", "fake-old-sha1", "fake-old")
+		oldPath = saveTarball(oldSource, "fake-old-contents")
+
+		fakeTimeService.Increment(10 * 24 * time.Hour)
+		newSource = bitarball.NewSource("https://fake-new-url", "fake-new-sha1", "fake-new")
+		newPath = saveTarball(newSource, "fake-new")
+	})
+
+	It("returns an error without a subcommand", func() {
+		err := command.Run(fakeStage, []string{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage - cache command requires a subcommand"))
+	})
+
+	Describe("list", func() {
+		It("prints the source, size and last use of the cached tarballs, least recently used first", func() {
+			err := command.Run(fakeStage, []string{"list"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeUI.Said[0]).To(MatchRegexp(`^Source\s+Digest\s+Size\s+Last Used$`))
+			Expect(fakeUI.Said[1]).To(MatchRegexp(`^https://fake-old-url\s+fake-old-sha1\s+17 B\s+2016-03-01 `))
+			Expect(fakeUI.Said[2]).To(MatchRegexp(`^https://fake-new-url\s+fake-new-sha1\s+8 B\s+2016-03-11 `))
+			Expect(fakeUI.Said[4]).To(Equal("2 tarballs, 25 B"))
+		})
+	})
+
+	Describe("prune", func() {
+		It("deletes the tarballs not used for longer than the given age", func() {
+			err := command.Run(fakeStage, []string{"prune", "--older-than", "7d"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists(oldPath)).To(BeFalse())
+			Expect(fs.FileExists(newPath)).To(BeTrue())
+			Expect(fakeUI.Said).To(ContainElement("Pruned 1 tarballs, freed 17 B"))
+		})
+
+		It("accepts Go durations", func() {
+			err := command.Run(fakeStage, []string{"prune", "--older-than", "1h"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists(oldPath)).To(BeFalse())
+			Expect(fs.FileExists(newPath)).To(BeTrue())
+		})
+
+		It("returns an error when the age is invalid", func() {
+			err := command.Run(fakeStage, []string{"prune", "--older-than", "a-week"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--older-than 'a-week' must be a duration such as '72h' or '7d'"))
+		})
+
+		It("returns an error without --older-than", func() {
+			err := command.Run(fakeStage, []string{"prune"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cache prune requires --older-than <duration>"))
+		})
+	})
+
+	Describe("verify", func() {
+		It("deletes the tarballs that don't match their SHA1", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				oldPath: {Value: "fake-corrupt-sha1"},
+				newPath: {Value: "fake-new-sha1"},
+			})
+
+			err := command.Run(fakeStage, []string{"verify"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists(oldPath)).To(BeFalse())
+			Expect(fs.FileExists(newPath)).To(BeTrue())
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Verifying https://fake-old-url"))
+			Expect(fakeStage.PerformCalls[1].Name).To(Equal("Verifying https://fake-new-url"))
+			Expect(fakeUI.Said).To(ContainElement("  https://fake-old-url: SHA1 'fake-corrupt-sha1' does not match expected SHA1 'fake-old-sha1'"))
+		})
+
+		It("returns an error when a tarball can't be checksummed", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				oldPath: {Err: errors.New("fake-calculate-error")},
+			})
+
+			err := command.Run(fakeStage, []string{"verify"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-calculate-error"))
+		})
+	})
+
+	It("returns an error for an unknown subcommand", func() {
+		err := command.Run(fakeStage, []string{"bogus"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unknown cache subcommand 'bogus'"))
+	})
+})
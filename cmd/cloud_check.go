@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"fmt"
+
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type CloudProblemType string
+
+const (
+	MissingVMProblem         CloudProblemType = "missing_vm"
+	UnresponsiveAgentProblem CloudProblemType = "unresponsive_agent"
+	UnmountedDiskProblem     CloudProblemType = "unmounted_disk"
+	MissingDiskProblem       CloudProblemType = "missing_disk"
+	MissingStemcellProblem   CloudProblemType = "missing_stemcell"
+)
+
+type CloudResolution string
+
+const (
+	SkipResolution           CloudResolution = "skip"
+	RecreateVMResolution     CloudResolution = "recreate_vm"
+	ForgetVMResolution       CloudResolution = "forget_vm"
+	ReattachDiskResolution   CloudResolution = "reattach_disk"
+	ForgetDiskResolution     CloudResolution = "forget_disk"
+	ForgetStemcellResolution CloudResolution = "forget_stemcell"
+)
+
+var cloudResolutionDescriptions = map[CloudResolution]string{
+	SkipResolution:           "Skip for now",
+	RecreateVMResolution:     "Recreate VM from the current stemcell",
+	ForgetVMResolution:       "Forget VM reference in deployment state",
+	ReattachDiskResolution:   "Reattach disk to VM",
+	ForgetDiskResolution:     "Forget disk reference in deployment state",
+	ForgetStemcellResolution: "Forget stemcell reference in deployment state",
+}
+
+func (r CloudResolution) Description() string {
+	return cloudResolutionDescriptions[r]
+}
+
+// CloudProblem is an inconsistency between the deployment state and the IaaS.
+// The first resolution is the safe default used by `cck --auto`.
+type CloudProblem struct {
+	Type        CloudProblemType
+	Description string
+	VMCID       string
+	DiskCID     string
+	StemcellCID string
+	Resolutions []CloudResolution
+}
+
+func (p CloudProblem) DefaultResolution() CloudResolution {
+	return p.Resolutions[0]
+}
+
+// CloudProblemResolver chooses one of the problem's resolutions.
+type CloudProblemResolver func(CloudProblem) (CloudResolution, error)
+
+func NewMissingVMProblem(vmCID string) CloudProblem {
+	return CloudProblem{
+		Type:        MissingVMProblem,
+		Description: fmt.Sprintf("VM '%s' is recorded in deployment state but missing in the cloud", vmCID),
+		VMCID:       vmCID,
+		Resolutions: []CloudResolution{RecreateVMResolution, ForgetVMResolution, SkipResolution},
+	}
+}
+
+func NewUnresponsiveAgentProblem(vmCID string) CloudProblem {
+	return CloudProblem{
+		Type:        UnresponsiveAgentProblem,
+		Description: fmt.Sprintf("Agent on VM '%s' is not responding", vmCID),
+		VMCID:       vmCID,
+		Resolutions: []CloudResolution{SkipResolution, RecreateVMResolution},
+	}
+}
+
+func NewUnmountedDiskProblem(vmCID, diskCID string) CloudProblem {
+	return CloudProblem{
+		Type:        UnmountedDiskProblem,
+		Description: fmt.Sprintf("Disk '%s' is not mounted on VM '%s'", diskCID, vmCID),
+		VMCID:       vmCID,
+		DiskCID:     diskCID,
+		Resolutions: []CloudResolution{ReattachDiskResolution, SkipResolution},
+	}
+}
+
+func NewMissingDiskProblem(diskCID string) CloudProblem {
+	return CloudProblem{
+		Type:        MissingDiskProblem,
+		Description: fmt.Sprintf("Disk '%s' is recorded in deployment state but the cloud reports it as not found", diskCID),
+		DiskCID:     diskCID,
+		Resolutions: []CloudResolution{SkipResolution, ForgetDiskResolution},
+	}
+}
+
+func NewMissingStemcellProblem(stemcellCID string) CloudProblem {
+	return CloudProblem{
+		Type:        MissingStemcellProblem,
+		Description: fmt.Sprintf("Stemcell '%s' is recorded in deployment state but the cloud reports it as not found", stemcellCID),
+		StemcellCID: stemcellCID,
+		Resolutions: []CloudResolution{ForgetStemcellResolution, SkipResolution},
+	}
+}
+
+func (c *instanceLifecycle) CloudCheck(stage biui.Stage, resolve CloudProblemResolver) error {
+	return c.withInstalledCpi(stage, true, func(ctx lifecycleContext) error {
+		var problems []CloudProblem
+		err := stage.Perform("Scanning for problems", func() error {
+			var err error
+			problems, err = c.scanForProblems(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(problems) == 0 {
+			c.ui.PrintLinef("No problems found")
+			return nil
+		}
+
+		c.ui.PrintLinef("Found %d problem(s)", len(problems))
+
+		for len(problems) > 0 {
+			problem := problems[0]
+			problems = problems[1:]
+
+			resolution, err := resolve(problem)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Choosing resolution for problem '%s'", problem.Description)
+			}
+
+			followUpProblems, err := c.resolveProblem(ctx, problem, resolution, stage)
+			if err != nil {
+				return err
+			}
+
+			problems = append(problems, followUpProblems...)
+		}
+
+		return nil
+	})
+}
+
+func (c *instanceLifecycle) scanForProblems(ctx lifecycleContext) ([]CloudProblem, error) {
+	problems := []CloudProblem{}
+
+	// the CPI API can not check whether a stemcell exists, a missing stemcell is found
+	// when recreating the VM from it fails, see cloudNotFoundProblems
+	diskRecord, diskFound, err := c.diskRepo.FindCurrent()
+	if err != nil {
+		return problems, bosherr.WrapError(err, "Finding current disk")
+	}
+
+	if diskFound {
+		diskExists, err := c.cloudHas(ctx.cloud.HasDisk, diskRecord.CID)
+		if err != nil {
+			return problems, bosherr.WrapErrorf(err, "Checking existence of disk '%s'", diskRecord.CID)
+		}
+
+		if !diskExists {
+			problems = append(problems, NewMissingDiskProblem(diskRecord.CID))
+			diskFound = false
+		}
+	}
+
+	vmCID, found, err := c.vmRepo.FindCurrent()
+	if err != nil {
+		return problems, bosherr.WrapError(err, "Finding current VM")
+	}
+
+	if !found {
+		return problems, nil
+	}
+
+	vmExists, err := ctx.cloud.HasVM(vmCID)
+	if err != nil {
+		return problems, bosherr.WrapErrorf(err, "Checking existence of VM '%s'", vmCID)
+	}
+
+	if !vmExists {
+		return append(problems, NewMissingVMProblem(vmCID)), nil
+	}
+
+	agentClient := c.agentClientFactory.NewAgentClient(ctx.directorID, ctx.installationManifest.Mbus, ctx.mbusClient)
+	_, err = agentClient.Ping()
+	if err != nil {
+		c.logger.Warn(c.logTag, "Pinging agent on VM '%s': %s", vmCID, err.Error())
+		return append(problems, NewUnresponsiveAgentProblem(vmCID)), nil
+	}
+
+	if !diskFound {
+		return problems, nil
+	}
+
+	diskCIDs, err := agentClient.ListDisk()
+	if err != nil {
+		return problems, bosherr.WrapErrorf(err, "Listing disks on VM '%s'", vmCID)
+	}
+
+	for _, diskCID := range diskCIDs {
+		if diskCID == diskRecord.CID {
+			return problems, nil
+		}
+	}
+
+	return append(problems, NewUnmountedDiskProblem(vmCID, diskRecord.CID)), nil
+}
+
+// cloudHas asks the CPI whether the resource exists, treating a CPI that does not implement or know the check
+// as reporting that it exists so that the scan does not fail on older CPIs.
+func (c *instanceLifecycle) cloudHas(has func(cid string) (bool, error), cid string) (bool, error) {
+	exists, err := has(cid)
+	if err != nil {
+		if cloudErr, ok := err.(bicloud.Error); ok && (cloudErr.Type() == bicloud.NotImplementedError || cloudErr.Type() == bicloud.InvalidCallError) {
+			c.logger.Debug(c.logTag, "Skipping existence check of '%s': %s", cid, err.Error())
+			return true, nil
+		}
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// resolveProblem applies the resolution and returns any problems discovered by the cloud while doing so.
+func (c *instanceLifecycle) resolveProblem(ctx lifecycleContext, problem CloudProblem, resolution CloudResolution, stage biui.Stage) ([]CloudProblem, error) {
+	stepName := fmt.Sprintf("%s (%s)", resolution.Description(), problem.Description)
+
+	switch resolution {
+	case SkipResolution:
+		return nil, stage.Perform(stepName, func() error {
+			return biui.NewSkipStageError(bosherr.Error(problem.Description), "Skipped")
+		})
+
+	case RecreateVMResolution:
+		err := stage.PerformComplex(stepName, func(recreateStage biui.Stage) error {
+			return c.recreateVM(ctx, ctx.drainOptions(false), recreateStage)
+		})
+		return c.cloudNotFoundProblems(err)
+
+	case ForgetVMResolution:
+		return nil, stage.Perform(stepName, func() error {
+			return c.vmRepo.ClearCurrent()
+		})
+
+	case ReattachDiskResolution:
+		err := stage.Perform(stepName, func() error {
+			err := ctx.cloud.AttachDisk(problem.VMCID, problem.DiskCID)
+			if err != nil {
+				return bosherr.WrapError(err, "Attaching disk in the cloud")
+			}
+
+			agentClient := c.agentClientFactory.NewAgentClient(ctx.directorID, ctx.installationManifest.Mbus, ctx.mbusClient)
+			err = agentClient.MountDisk(problem.DiskCID)
+			if err != nil {
+				return bosherr.WrapError(err, "Mounting disk")
+			}
+
+			return nil
+		})
+		return c.cloudNotFoundProblems(err)
+
+	case ForgetDiskResolution:
+		return nil, stage.Perform(stepName, func() error {
+			diskRecord, found, err := c.diskRepo.Find(problem.DiskCID)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Finding disk record '%s'", problem.DiskCID)
+			}
+
+			if !found {
+				return nil
+			}
+
+			return c.diskRepo.Delete(diskRecord)
+		})
+
+	case ForgetStemcellResolution:
+		return nil, stage.Perform(stepName, func() error {
+			stemcellRecords, err := c.stemcellRepo.All()
+			if err != nil {
+				return bosherr.WrapError(err, "Finding stemcell records")
+			}
+
+			for _, stemcellRecord := range stemcellRecords {
+				if stemcellRecord.CID == problem.StemcellCID {
+					return c.stemcellRepo.Delete(stemcellRecord)
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return nil, bosherr.Errorf("Unknown resolution '%s' for problem '%s'", resolution, problem.Description)
+}
+
+// cloudNotFoundProblems turns disk and stemcell not found CPI errors into new problems,
+// so that their stale references can be resolved as well.
+func (c *instanceLifecycle) cloudNotFoundProblems(err error) ([]CloudProblem, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	cloudErr, found := findCloudError(err)
+	if !found {
+		return nil, err
+	}
+
+	switch cloudErr.Type() {
+	case bicloud.DiskNotFoundError:
+		diskRecord, found, findErr := c.diskRepo.FindCurrent()
+		if findErr == nil && found {
+			return []CloudProblem{NewMissingDiskProblem(diskRecord.CID)}, nil
+		}
+	case bicloud.StemcellNotFoundError:
+		stemcellRecord, found, findErr := c.stemcellRepo.FindCurrent()
+		if findErr == nil && found {
+			return []CloudProblem{NewMissingStemcellProblem(stemcellRecord.CID)}, nil
+		}
+	}
+
+	return nil, err
+}
+
+func findCloudError(err error) (bicloud.Error, bool) {
+	for err != nil {
+		switch typedErr := err.(type) {
+		case bicloud.Error:
+			return typedErr, true
+		case bosherr.ComplexError:
+			err = typedErr.Cause
+		case biui.SkipStageError:
+			err = typedErr.Cause()
+		default:
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type cloudCheckCmd struct {
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	input                     *bufio.Reader
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error)
+	logTag                    string
+}
+
+func NewCloudCheckCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	input io.Reader,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return &cloudCheckCmd{
+		ui:                        ui,
+		fs:                        fs,
+		logger:                    logger,
+		input:                     bufio.NewReader(input),
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		logTag:                    "cloudCheckCmd",
+	}
+}
+
+func (c *cloudCheckCmd) Name() string {
+	return "cck"
+}
+
+func (c *cloudCheckCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Detect and repair differences between the deployment state and the cloud",
+		Usage:    "[--auto] <deployment_manifest_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *cloudCheckCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, auto, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := filepath.Abs(deploymentManifestPath)
+	if err != nil {
+		c.ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", deploymentManifestPath)
+		return bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", deploymentManifestPath)
+	}
+
+	if !c.fs.FileExists(manifestAbsFilePath) {
+		c.ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
+		return bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+	}
+
+	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	resolver := c.chooseResolution
+	if auto {
+		resolver = c.defaultResolution
+	}
+
+	return instanceLifecycle.CloudCheck(stage, resolver)
+}
+
+func (c *cloudCheckCmd) defaultResolution(problem CloudProblem) (CloudResolution, error) {
+	resolution := problem.DefaultResolution()
+
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("Problem: %s", problem.Description)
+	c.ui.PrintLinef("Resolution: %s", resolution.Description())
+
+	return resolution, nil
+}
+
+func (c *cloudCheckCmd) chooseResolution(problem CloudProblem) (CloudResolution, error) {
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("Problem: %s", problem.Description)
+	for i, resolution := range problem.Resolutions {
+		c.ui.PrintLinef("  %d. %s", i+1, resolution.Description())
+	}
+
+	for {
+		c.ui.BeginLinef("Choose a resolution [1-%d] (default: 1): ", len(problem.Resolutions))
+
+		line, err := c.input.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", bosherr.WrapError(err, "Reading resolution")
+		}
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			return problem.DefaultResolution(), nil
+		}
+
+		choice, err := strconv.Atoi(answer)
+		if err == nil && choice >= 1 && choice <= len(problem.Resolutions) {
+			return problem.Resolutions[choice-1], nil
+		}
+
+		c.ui.ErrorLinef("Invalid resolution '%s'", answer)
+	}
+}
+
+func (c *cloudCheckCmd) parseCmdInputs(args []string) (string, bool, error) {
+	auto := false
+	positionalArgs := []string{}
+	for _, arg := range args {
+		if arg == "--auto" {
+			auto = true
+		} else {
+			positionalArgs = append(positionalArgs, arg)
+		}
+	}
+
+	if len(positionalArgs) != 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return "", false, bosherr.Error("Invalid usage - cck command requires exactly 1 argument")
+	}
+	return positionalArgs[0], auto, nil
+}
@@ -0,0 +1,142 @@
+package cmd_test
+
+import (
+	"strings"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("CloudCheckCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+		fs                    *fakesys.FakeFileSystem
+		logger                boshlog.Logger
+		fakeUI                *fakebiui.FakeUI
+		fakeStage             *fakebiui.FakeStage
+
+		deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		problem                bicmd.CloudProblem
+	)
+
+	var provider = func(path string) (bicmd.InstanceLifecycle, error) {
+		Expect(path).To(Equal(deploymentManifestPath))
+		return mockInstanceLifecycle, nil
+	}
+
+	newCommand := func(input string) bicmd.Cmd {
+		return bicmd.NewCloudCheckCmd(fakeUI, fs, logger, strings.NewReader(input), provider)
+	}
+
+	// expectResolution runs the resolver passed to CloudCheck against the problem
+	expectResolution := func(expectedResolution bicmd.CloudResolution, expectedErr string) {
+		mockInstanceLifecycle.EXPECT().CloudCheck(fakeStage, gomock.Any()).Do(func(_ biui.Stage, resolve bicmd.CloudProblemResolver) {
+			resolution, err := resolve(problem)
+			if expectedErr != "" {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedErr))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolution).To(Equal(expectedResolution))
+		})
+	}
+
+	BeforeEach(func() {
+		mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+
+		problem = bicmd.NewMissingVMProblem("fake-vm-cid")
+	})
+
+	Context("with --auto", func() {
+		It("resolves problems with their default resolution", func() {
+			expectResolution(bicmd.RecreateVMResolution, "")
+
+			err := newCommand("").Run(fakeStage, []string{"--auto", deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Problem: VM 'fake-vm-cid' is recorded in deployment state but missing in the cloud"))
+			Expect(fakeUI.Said).To(ContainElement("Resolution: Recreate VM from the current stemcell"))
+		})
+
+		It("skips unresponsive agents by default", func() {
+			problem = bicmd.NewUnresponsiveAgentProblem("fake-vm-cid")
+			expectResolution(bicmd.SkipResolution, "")
+
+			err := newCommand("").Run(fakeStage, []string{"--auto", deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("without --auto", func() {
+		It("lists the resolutions and uses the chosen one", func() {
+			expectResolution(bicmd.ForgetVMResolution, "")
+
+			err := newCommand("2\n").Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("  1. Recreate VM from the current stemcell"))
+			Expect(fakeUI.Said).To(ContainElement("  2. Forget VM reference in deployment state"))
+			Expect(fakeUI.Said).To(ContainElement("  3. Skip for now"))
+		})
+
+		It("uses the default resolution when nothing is entered", func() {
+			expectResolution(bicmd.RecreateVMResolution, "")
+
+			err := newCommand("\n").Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("asks again after an invalid choice", func() {
+			expectResolution(bicmd.SkipResolution, "")
+
+			err := newCommand("7\n3\n").Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Errors).To(ContainElement("Invalid resolution '7'"))
+		})
+
+		It("returns an error when input ends", func() {
+			expectResolution("", "Reading resolution")
+
+			err := newCommand("").Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	It("returns the error from the instance lifecycle", func() {
+		checkErr := bosherr.Error("fake-cck-error")
+		mockInstanceLifecycle.EXPECT().CloudCheck(fakeStage, gomock.Any()).Return(checkErr)
+
+		err := newCommand("").Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(Equal(checkErr))
+	})
+
+	It("returns err unless exactly 1 argument is given", func() {
+		err := newCommand("").Run(fakeStage, []string{"--auto"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage - cck command requires exactly 1 argument"))
+	})
+})
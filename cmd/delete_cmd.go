@@ -40,13 +40,13 @@ func (c *deleteCmd) Name() string {
 func (c *deleteCmd) Meta() Meta {
 	return Meta{
 		Synopsis: "Delete existing deployment",
-		Usage:    "<deployment_manifest_path>",
+		Usage:    "[--skip-drain] <deployment_manifest_path>",
 		Env:      genericEnv,
 	}
 }
 
 func (c *deleteCmd) Run(stage biui.Stage, args []string) error {
-	deploymentManifestPath, err := c.parseCmdInputs(args)
+	deploymentManifestPath, skipDrain, err := c.parseCmdInputs(args)
 	if err != nil {
 		return err
 	}
@@ -69,13 +69,23 @@ func (c *deleteCmd) Run(stage biui.Stage, args []string) error {
 		return err
 	}
 
-	return deploymentDeleter.DeleteDeployment(stage)
+	return deploymentDeleter.DeleteDeployment(stage, skipDrain)
 }
 
-func (c *deleteCmd) parseCmdInputs(args []string) (string, error) {
-	if len(args) != 1 {
+func (c *deleteCmd) parseCmdInputs(args []string) (string, bool, error) {
+	skipDrain := false
+	positionalArgs := []string{}
+	for _, arg := range args {
+		if arg == "--skip-drain" {
+			skipDrain = true
+		} else {
+			positionalArgs = append(positionalArgs, arg)
+		}
+	}
+
+	if len(positionalArgs) != 1 {
 		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
-		return "", errors.New("Invalid usage - delete command requires exactly 1 argument")
+		return "", false, errors.New("Invalid usage - delete command requires exactly 1 argument")
 	}
-	return args[0], nil
+	return positionalArgs[0], skipDrain, nil
 }
@@ -70,14 +70,20 @@ var _ = Describe("DeleteCmd", func() {
 
 		Context("when the deployment manifest exists", func() {
 			It("sends the manifest on to the deleter", func() {
-				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage).Return(nil)
+				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, false).Return(nil)
 				newDeleteCmd().Run(fakeStage, []string{deploymentManifestPath})
 			})
 
+			It("tells the deleter to skip drain when --skip-drain is given", func() {
+				mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, true).Return(nil)
+				err := newDeleteCmd().Run(fakeStage, []string{"--skip-drain", deploymentManifestPath})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
 			Context("when the deployment deleter returns an error", func() {
 				It("sends the manifest on to the deleter", func() {
 					err := bosherr.Error("boom")
-					mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage).Return(err)
+					mockDeploymentDeleter.EXPECT().DeleteDeployment(fakeStage, false).Return(err)
 					returnedErr := newDeleteCmd().Run(fakeStage, []string{deploymentManifestPath})
 					Expect(returnedErr).To(Equal(err))
 				})
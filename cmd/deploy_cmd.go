@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	bihandoff "github.com/cloudfoundry/bosh-init/handoff"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -13,6 +14,7 @@ import (
 
 type deployCmd struct {
 	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error)
+	handoffProvider            func() (bihandoff.Handoff, error)
 	ui                         biui.UI
 	fs                         boshsys.FileSystem
 	eventLogger                biui.Stage
@@ -25,11 +27,13 @@ func NewDeployCmd(
 	fs boshsys.FileSystem,
 	logger boshlog.Logger,
 	deploymentPreparerProvider func(deploymentManifestPath string) (DeploymentPreparer, error),
+	handoffProvider func() (bihandoff.Handoff, error),
 ) Cmd {
 	return &deployCmd{
 		ui: ui,
 		fs: fs,
 		deploymentPreparerProvider: deploymentPreparerProvider,
+		handoffProvider:            handoffProvider,
 		logger: logger,
 		logTag: "deployCmd",
 	}
@@ -43,10 +47,111 @@ func (c *deployCmd) Meta() Meta {
 	return Meta{
 		Synopsis: "Create or update a deployment",
 		Usage:    "<deployment_manifest_path>",
-		Env:      genericEnv,
+		Env:      c.env(),
 	}
 }
 
+// handoffEnv configures the handoff to the deployed director, see handoff.NewConfigFromEnv
+var handoffEnv = map[string]MetaEnv{
+	"BOSH_INIT_DIRECTOR_URL": MetaEnv{
+		Example:     "https://10.0.0.6:25555",
+		Default:     "none",
+		Description: "Director waited for after deploying, enabling the handoff to the deployed director",
+	},
+	"BOSH_INIT_DIRECTOR_CA_CERT": MetaEnv{
+		Example:     "/path/to/director-ca.pem",
+		Default:     "none",
+		Description: "CA certificates the director and its UAA are verified against instead of the system CAs",
+	},
+	"BOSH_INIT_DIRECTOR_CLIENT": MetaEnv{
+		Example:     "admin",
+		Default:     "none",
+		Description: "UAA client, or basic auth user, authenticating with the director",
+	},
+	"BOSH_INIT_DIRECTOR_CLIENT_SECRET": MetaEnv{
+		Example:     "admin-secret",
+		Default:     "none",
+		Description: "Secret of BOSH_INIT_DIRECTOR_CLIENT",
+	},
+	"BOSH_INIT_DIRECTOR_WAIT_TIMEOUT": MetaEnv{
+		Example:     "20m",
+		Default:     "10m",
+		Description: "How long the director is waited for after deploying",
+	},
+	"BOSH_INIT_DIRECTOR_TASK_TIMEOUT": MetaEnv{
+		Example:     "1h",
+		Default:     "30m",
+		Description: "How long the director tasks of stemcell and release uploads are waited for",
+	},
+	"BOSH_INIT_DIRECTOR_STEMCELLS": MetaEnv{
+		Example:     "/path/to/stemcell.tgz,https://bosh.io/d/stemcell#sha1",
+		Default:     "none",
+		Description: "Comma separated 'path-or-url[#sha1]' stemcells uploaded to the director",
+	},
+	"BOSH_INIT_DIRECTOR_RELEASES": MetaEnv{
+		Example:     "/path/to/release.tgz,https://bosh.io/d/release#sha1",
+		Default:     "none",
+		Description: "Comma separated 'path-or-url[#sha1]' releases uploaded to the director",
+	},
+}
+
+func (c *deployCmd) env() map[string]MetaEnv {
+	env := deployEnv()
+	for name, metaEnv := range handoffEnv {
+		env[name] = metaEnv
+	}
+	return env
+}
+
+func deployEnv() map[string]MetaEnv {
+	env := map[string]MetaEnv{
+		"BOSH_INIT_INDEX": MetaEnv{
+			Example:     "https://bosh.io/api/v1",
+			Default:     "none",
+			Description: "Index file or URL used to resolve releases and stemcells specified by name and version, when the manifest does not set 'index'",
+		},
+		"BOSH_INIT_CA_CERT": MetaEnv{
+			Example:     "/path/to/ca.pem",
+			Default:     "none",
+			Description: "CA certificates trusted in addition to the system CAs when downloading over https",
+		},
+		"BOSH_INIT_MIRRORS": MetaEnv{
+			Example:     "https://bosh.io/d/=https://mirror.example.com/d/",
+			Default:     "none",
+			Description: "Comma separated 'from=to' rules rewriting download URL prefixes",
+		},
+		"BOSH_INIT_HTTP_AUTH": MetaEnv{
+			Example:     "releases.example.com=basic:user:password",
+			Default:     "none",
+			Description: "Comma separated 'host=token:<token>' or 'host=basic:<user>:<password>' credentials for http(s) downloads",
+		},
+		"NETRC": MetaEnv{
+			Example:     "/path/to/netrc",
+			Default:     "~/.netrc",
+			Description: "Netrc file with credentials for http(s) downloads",
+		},
+		"BOSH_INIT_S3_ENDPOINT": MetaEnv{
+			Example:     "https://s3.example.com",
+			Default:     "https://s3.<AWS_REGION>.amazonaws.com",
+			Description: "Endpoint of the S3 compatible store used for s3:// downloads, signed with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+		},
+		"BOSH_INIT_MAX_CONCURRENT_DOWNLOADS": MetaEnv{
+			Example:     "2",
+			Default:     "4",
+			Description: "Number of releases and stemcells downloaded and extracted at a time",
+		},
+		"BOSH_INIT_TRUSTED_KEYS": MetaEnv{
+			Example:     "/path/to/trusted-keys",
+			Default:     "none",
+			Description: "File or directory of OpenPGP or ed25519 public keys that must sign every release and stemcell",
+		},
+	}
+	for name, metaEnv := range genericEnv {
+		env[name] = metaEnv
+	}
+	return env
+}
+
 func (c *deployCmd) Run(stage biui.Stage, args []string) error {
 	deploymentManifestPath, err := c.parseCmdInputs(args)
 	if err != nil {
@@ -66,12 +171,23 @@ func (c *deployCmd) Run(stage biui.Stage, args []string) error {
 
 	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
 
+	// load the handoff settings first, so that they are not found invalid only after deploying
+	handoff, err := c.handoffProvider()
+	if err != nil {
+		return err
+	}
+
 	deploymentPreparer, err := c.deploymentPreparerProvider(manifestAbsFilePath)
 	if err != nil {
 		return err
 	}
 
-	return deploymentPreparer.PrepareDeployment(stage)
+	err = deploymentPreparer.PrepareDeployment(stage)
+	if err != nil {
+		return err
+	}
+
+	return handoff.Run(stage)
 }
 
 func (c *deployCmd) parseCmdInputs(args []string) (string, error) {
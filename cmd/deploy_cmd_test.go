@@ -13,8 +13,8 @@ import (
 	"github.com/cloudfoundry/bosh-init/deployment"
 	"github.com/golang/mock/gomock"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-golang/clock"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
@@ -26,10 +26,13 @@ import (
 	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
 	mock_stemcell "github.com/cloudfoundry/bosh-init/stemcell/mocks"
 
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bihandoff "github.com/cloudfoundry/bosh-init/handoff"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
@@ -44,13 +47,17 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 
+	fakebicatalog "github.com/cloudfoundry/bosh-init/catalog/fakes"
 	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
 	fakebideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest/fakes"
 	fakebideplval "github.com/cloudfoundry/bosh-init/deployment/manifest/fakes"
 	fakebivm "github.com/cloudfoundry/bosh-init/deployment/vm/fakes"
+	fakebihandoff "github.com/cloudfoundry/bosh-init/handoff/fakes"
 	fakebiinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest/fakes"
 	fakebirel "github.com/cloudfoundry/bosh-init/release/fakes"
 	fakebirelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest/fakes"
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
+	fakebisignature "github.com/cloudfoundry/bosh-init/signature/fakes"
 	fakebistemcell "github.com/cloudfoundry/bosh-init/stemcell/fakes"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
 	fakebihttpclient "github.com/cloudfoundry/bosh-utils/httpclient/fakes"
@@ -89,7 +96,7 @@ func rootDesc() {
 			mockRegistryServerManager *mock_registry.MockServerManager
 			mockRegistryServer        *mock_registry.MockServer
 			mockAgentClient           *mock_agentclient.MockAgentClient
-			mockAgentClientFactory    *mock_httpagent.MockAgentClientFactory
+			mockAgentClientFactory    *mock_agentclient.MockAgentClientFactory
 			mockCloudFactory          *mock_cloud.MockFactory
 
 			fakeCPIRelease *fakebirel.FakeRelease
@@ -110,6 +117,8 @@ func rootDesc() {
 			mockLegacyDeploymentStateMigrator *mock_config.MockLegacyDeploymentStateMigrator
 			setupDeploymentStateService       biconfig.DeploymentStateService
 			fakeDeploymentValidator           *fakebideplval.FakeValidator
+			fakeCatalogResolver               *fakebicatalog.FakeResolver
+			fakeSignatureVerifier             *fakebisignature.FakeVerifier
 
 			directorID          = "generated-director-uuid"
 			fakeUUIDGenerator   *fakeuuid.FakeGenerator
@@ -117,6 +126,9 @@ func rootDesc() {
 
 			fakeStage *fakebiui.FakeStage
 
+			fakeHandoff    *fakebihandoff.FakeHandoff
+			handoffLoadErr error
+
 			deploymentManifestPath string
 			deploymentStatePath    string
 			cpiReleaseTarballPath  string
@@ -149,6 +161,8 @@ func rootDesc() {
 			userInterface = biui.NewWriterUI(stdOut, stdErr, logger)
 			fakeFs = fakesys.NewFakeFileSystem()
 			fakeFs.EnableStrictTempRootBehavior()
+			fakeHandoff = fakebihandoff.NewFakeHandoff()
+			handoffLoadErr = nil
 			deploymentManifestPath = "/path/to/manifest.yml"
 			deploymentStatePath = "/path/to/manifest-state.json"
 			fakeFs.RegisterOpenFile(deploymentManifestPath, &fakesys.FakeFile{
@@ -167,9 +181,9 @@ func rootDesc() {
 			mockRegistryServerManager = mock_registry.NewMockServerManager(mockCtrl)
 			mockRegistryServer = mock_registry.NewMockServer(mockCtrl)
 
-			mockAgentClientFactory = mock_httpagent.NewMockAgentClientFactory(mockCtrl)
+			mockAgentClientFactory = mock_agentclient.NewMockAgentClientFactory(mockCtrl)
 			mockAgentClient = mock_agentclient.NewMockAgentClient(mockCtrl)
-			mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), gomock.Any()).Return(mockAgentClient).AnyTimes()
+			mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockAgentClient).AnyTimes()
 
 			mockCloudFactory = mock_cloud.NewMockFactory(mockCtrl)
 
@@ -196,6 +210,8 @@ func rootDesc() {
 			setupDeploymentStateService = biconfig.NewFileSystemDeploymentStateService(fakeFs, configUUIDGenerator, logger, biconfig.DeploymentStatePath(deploymentManifestPath))
 
 			fakeDeploymentValidator = fakebideplval.NewFakeValidator()
+			fakeCatalogResolver = fakebicatalog.NewFakeResolver()
+			fakeSignatureVerifier = fakebisignature.NewFakeVerifier()
 
 			fakeStage = fakebiui.NewFakeStage()
 
@@ -305,18 +321,22 @@ func rootDesc() {
 				deploymentRecord := deployment.NewRecord(deploymentRepo, releaseRepo, stemcellRepo, sha1Calculator)
 
 				fakeHTTPClient := fakebihttpclient.NewFakeHTTPClient()
-				tarballCache := bitarball.NewCache("fake-base-path", fakeFs, logger)
-				tarballProvider := bitarball.NewProvider(tarballCache, fakeFs, fakeHTTPClient, sha1Calculator, 1, 0, logger)
+				tarballCache := bitarball.NewCache("fake-base-path", 0, fakeFs, clock.NewClock(), logger)
+				schemeRegistry := bitarball.NewSchemeRegistry()
+				schemeRegistry.Register("http", bitarball.NewHTTPDownloader(fakeHTTPClient, bitarball.NewCredentialsProvider(nil, "", fakeFs), logger))
+				tarballProvider := bitarball.NewProvider(tarballCache, fakeFs, schemeRegistry, nil, crypto.NewDigestCalculator(fakeFs), 1, 0, clock.NewClock(), logger)
 
 				cpiInstaller := bicpirel.CpiInstaller{
 					ReleaseManager:   releaseManager,
 					InstallerFactory: mockInstallerFactory,
 					Validator:        bicpirel.NewValidator(),
 				}
-				releaseFetcher := birel.NewFetcher(tarballProvider, mockReleaseExtractor, releaseManager)
+				releaseFetcher := birel.NewFetcher(tarballProvider, mockReleaseExtractor, releaseManager, mock_release.NewMockDevReleaseBuilder(mockCtrl), fakeCatalogResolver, fakebisignature.NewFakeVerifier(), fakeFs)
 				stemcellFetcher := bistemcell.Fetcher{
 					TarballProvider:   tarballProvider,
 					StemcellExtractor: fakeStemcellExtractor,
+					CatalogResolver:   fakeCatalogResolver,
+					SignatureVerifier: fakebisignature.NewFakeVerifier(),
 				}
 				releaseSetAndInstallationManifestParser := bicmd.ReleaseSetAndInstallationManifestParser{
 					ReleaseSetParser:   fakeReleaseSetParser,
@@ -356,6 +376,12 @@ func rootDesc() {
 					cpiInstaller,
 					releaseFetcher,
 					stemcellFetcher,
+					fakeCatalogResolver,
+					biconfig.NewResolvedRefRepo(deploymentStateService),
+					biconfig.NewTarballRepo(deploymentStateService),
+					fakeSignatureVerifier,
+					biconfig.NewSignatureRepo(deploymentStateService),
+					2,
 					releaseSetAndInstallationManifestParser,
 					deploymentManifestParser,
 					tempRootConfigurator,
@@ -363,7 +389,11 @@ func rootDesc() {
 				), nil
 			}
 
-			command = bicmd.NewDeployCmd(userInterface, fakeFs, logger, doGet)
+			handoffProvider := func() (bihandoff.Handoff, error) {
+				return fakeHandoff, handoffLoadErr
+			}
+
+			command = bicmd.NewDeployCmd(userInterface, fakeFs, logger, doGet, handoffProvider)
 
 			expectLegacyMigrate = mockLegacyDeploymentStateMigrator.EXPECT().MigrateIfExists("/path/to/bosh-deployments.yml").AnyTimes()
 
@@ -407,8 +437,9 @@ func rootDesc() {
 				installationManifest.Registry,
 				fakeVMManager,
 				mockBlobstore,
+				biinstance.DrainOptions{MaxWait: boshDeploymentManifest.Update.MaxDrainWait},
 				gomock.Any(),
-			).Do(func(_, _, _, _, _, _ interface{}, stage biui.Stage) {
+			).Do(func(_, _, _, _, _, _, _ interface{}, stage biui.Stage) {
 				Expect(fakeStage.SubStages).To(ContainElement(stage))
 			}).Return(mockDeployment, nil).AnyTimes()
 
@@ -502,11 +533,13 @@ func rootDesc() {
 				Name: "validating",
 				Stage: &fakebiui.FakeStage{
 					PerformCalls: []*fakebiui.PerformCall{
-						{Name: "Validating release 'fake-cpi-release-name'"},
-						{Name: "Validating cpi release"},
 						{Name: "Validating deployment manifest"},
 						{Name: "Validating stemcell"},
+						{Name: "Validating release 'fake-cpi-release-name'"},
+						{Name: "Validating cpi release"},
+						{Name: "Validating deployment jobs"},
 					},
+					ConcurrencyLimits: []int{2},
 				},
 			}))
 		})
@@ -602,6 +635,39 @@ func rootDesc() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("hands off to the deployed director", func() {
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeHandoff.RunStages).To(Equal([]biui.Stage{fakeStage}))
+		})
+
+		Context("when loading the handoff settings fails", func() {
+			BeforeEach(func() {
+				handoffLoadErr = bosherr.Error("fake-handoff-load-error")
+			})
+
+			It("returns an error without deploying", func() {
+				expectDeploy.Times(0)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-handoff-load-error"))
+			})
+		})
+
+		Context("when the handoff fails", func() {
+			BeforeEach(func() {
+				fakeHandoff.RunErr = bosherr.Error("fake-handoff-error")
+			})
+
+			It("returns an error", func() {
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-handoff-error"))
+			})
+		})
+
 		It("updates the deployment record", func() {
 			err := command.Run(fakeStage, []string{deploymentManifestPath})
 			Expect(err).NotTo(HaveOccurred())
@@ -619,6 +685,77 @@ func rootDesc() {
 			}))
 		})
 
+		It("records the tarballs resolved in the index once deployed", func() {
+			fakeCatalogResolver.ResolvedRefsRefs = []bicatalog.ResolvedRef{
+				{Type: "release", RequestedVersion: "latest", Entry: bicatalog.Entry{Name: "fake-release", Version: "2", URL: "fake-url", SHA1: "fake-sha1"}},
+			}
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).NotTo(HaveOccurred())
+
+			deploymentState, err := setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.ResolvedRefs).To(Equal([]biconfig.ResolvedRefRecord{
+				{Type: "release", Name: "fake-release", RequestedVersion: "latest", Version: "2", URL: "fake-url", SHA1: "fake-sha1"},
+			}))
+		})
+
+		It("does not record local tarballs as deployed tarballs", func() {
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).NotTo(HaveOccurred())
+
+			deploymentState, err := setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Tarballs).To(BeEmpty())
+		})
+
+		Context("when the stemcell is downloaded from the version resolved in the index", func() {
+			var stemcellEntry bicatalog.Entry
+
+			BeforeEach(func() {
+				stemcellEntry = bicatalog.Entry{Name: "fake-stemcell", Version: "2", URL: "http://fake-stemcell-url", SHA1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"}
+				fakeCatalogResolver.ResolveStemcellEntry = stemcellEntry
+				fakeCatalogResolver.ResolvedRefsRefs = []bicatalog.ResolvedRef{
+					{Type: "stemcell", RequestedVersion: "latest", Entry: stemcellEntry},
+				}
+				boshDeploymentManifest.ResourcePools[0].Stemcell = bideplmanifest.StemcellRef{Name: "fake-stemcell", Version: "latest"}
+
+				tarballCache := bitarball.NewCache("fake-base-path", 0, fakeFs, clock.NewClock(), logger)
+				cachedStemcellPath := tarballCache.Path(bitarball.NewSource(stemcellEntry.URL, stemcellEntry.SHA1, "stemcell"))
+				fakeFs.WriteFileString(cachedStemcellPath, "")
+				fakeStemcellExtractor.SetExtractBehavior(cachedStemcellPath, extractedStemcell, nil)
+			})
+
+			It("records the source of the downloaded tarball once deployed", func() {
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).NotTo(HaveOccurred())
+
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.Tarballs).To(Equal([]biconfig.TarballRecord{
+					{Type: "stemcell", Name: "fake-stemcell", URL: "http://fake-stemcell-url", SHA1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+				}))
+			})
+		})
+
+		It("records the signers of the verified tarballs once deployed", func() {
+			fakeSignatureVerifier.SignedArtifactsArtifacts = []bisignature.SignedArtifact{
+				{
+					Artifact: bisignature.Artifact{Type: "release", Name: "fake-release", URL: "fake-url", SHA1: "fake-sha1", Signature: "fake-url.sig"},
+					Signer:   "fake-signer",
+				},
+			}
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).NotTo(HaveOccurred())
+
+			deploymentState, err := setupDeploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Signatures).To(Equal([]biconfig.SignatureRecord{
+				{Type: "release", Name: "fake-release", URL: "fake-url", SHA1: "fake-sha1", Signature: "fake-url.sig", Signer: "fake-signer"},
+			}))
+		})
+
 		It("deletes unused stemcells", func() {
 			expectStemcellDeleteUnused.Times(1)
 
@@ -880,7 +1017,7 @@ func rootDesc() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("no-stemcell-there"))
 
-				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[3]
+				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[1]
 				Expect(performCall.Name).To(Equal("Validating stemcell"))
 				Expect(performCall.Error.Error()).To(ContainSubstring("no-stemcell-there"))
 			})
@@ -896,7 +1033,7 @@ func rootDesc() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("not there"))
 
-				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[0]
+				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[2]
 				Expect(performCall.Name).To(Equal("Validating release 'fake-cpi-release-name'"))
 				Expect(performCall.Error.Error()).To(ContainSubstring("not there"))
 			})
@@ -944,10 +1081,18 @@ func rootDesc() {
 				err := command.Run(fakeStage, []string{deploymentManifestPath})
 				Expect(err).To(HaveOccurred())
 
-				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[2]
+				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[0]
 				Expect(performCall.Name).To(Equal("Validating deployment manifest"))
 				Expect(performCall.Error.Error()).To(Equal("Validating deployment manifest: fake-deployment-validation-error"))
 			})
+
+			It("does not download or extract the releases and the stemcell", func() {
+				expectCPIReleaseExtract.Times(0)
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+				Expect(fakeStage.PerformCalls[0].Stage.PerformCalls).To(HaveLen(1))
+			})
 		})
 
 		Context("when validating jobs fails", func() {
@@ -967,8 +1112,8 @@ func rootDesc() {
 				err := command.Run(fakeStage, []string{deploymentManifestPath})
 				Expect(err).To(HaveOccurred())
 
-				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[2]
-				Expect(performCall.Name).To(Equal("Validating deployment manifest"))
+				performCall := fakeStage.PerformCalls[0].Stage.PerformCalls[4]
+				Expect(performCall.Name).To(Equal("Validating deployment jobs"))
 				Expect(performCall.Error.Error()).To(Equal("Validating deployment jobs refer to jobs in release: fake-jobs-validation-error"))
 			})
 		})
@@ -1005,6 +1150,7 @@ func rootDesc() {
 					fakeVMManager,
 					mockBlobstore,
 					gomock.Any(),
+					gomock.Any(),
 				).Return(nil, errors.New("fake-deploy-error")).AnyTimes()
 
 				previousDeploymentState := biconfig.DeploymentState{
@@ -1032,6 +1178,32 @@ func rootDesc() {
 				Expect(deploymentState.Releases).To(Equal([]biconfig.ReleaseRecord{}))
 				Expect(deploymentState.CurrentReleaseIDs).To(Equal([]string{}))
 			})
+
+			It("does not record the tarballs resolved in the index", func() {
+				fakeCatalogResolver.ResolvedRefsRefs = []bicatalog.ResolvedRef{
+					{Type: "release", RequestedVersion: "latest", Entry: bicatalog.Entry{Name: "fake-release", Version: "2", URL: "fake-url", SHA1: "fake-sha1"}},
+				}
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.ResolvedRefs).To(BeEmpty())
+			})
+
+			It("does not record the signers of the verified tarballs", func() {
+				fakeSignatureVerifier.SignedArtifactsArtifacts = []bisignature.SignedArtifact{
+					{Artifact: bisignature.Artifact{Type: "release", Name: "fake-release"}, Signer: "fake-signer"},
+				}
+
+				err := command.Run(fakeStage, []string{deploymentManifestPath})
+				Expect(err).To(HaveOccurred())
+
+				deploymentState, err := setupDeploymentStateService.Load()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentState.Signatures).To(BeEmpty())
+			})
 		})
 
 		Context("when compiled releases are being used", func() {
@@ -1,24 +1,25 @@
 package cmd
 
 import (
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
 	bidepl "github.com/cloudfoundry/bosh-init/deployment"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
-	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 type DeploymentDeleter interface {
-	DeleteDeployment(stage biui.Stage) (err error)
+	DeleteDeployment(stage biui.Stage, skipDrain bool) (err error)
 }
 
 func NewDeploymentDeleter(
@@ -28,7 +29,7 @@ func NewDeploymentDeleter(
 	deploymentStateService biconfig.DeploymentStateService,
 	releaseManager birel.Manager,
 	cloudFactory bicloud.Factory,
-	agentClientFactory bihttpagent.AgentClientFactory,
+	agentClientFactory biagentclient.AgentClientFactory,
 	blobstoreFactory biblobstore.Factory,
 	deploymentManagerFactory bidepl.ManagerFactory,
 	deploymentManifestPath string,
@@ -36,6 +37,7 @@ func NewDeploymentDeleter(
 	cpiUninstaller biinstall.Uninstaller,
 	releaseFetcher birel.Fetcher,
 	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	deploymentParser bideplmanifest.Parser,
 	tempRootConfigurator TempRootConfigurator,
 	targetProvider biinstall.TargetProvider,
 ) DeploymentDeleter {
@@ -54,6 +56,7 @@ func NewDeploymentDeleter(
 		cpiUninstaller:                          cpiUninstaller,
 		releaseFetcher:                          releaseFetcher,
 		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		deploymentParser:                        deploymentParser,
 		tempRootConfigurator:                    tempRootConfigurator,
 		targetProvider:                          targetProvider,
 	}
@@ -66,7 +69,7 @@ type deploymentDeleter struct {
 	deploymentStateService                  biconfig.DeploymentStateService
 	releaseManager                          birel.Manager
 	cloudFactory                            bicloud.Factory
-	agentClientFactory                      bihttpagent.AgentClientFactory
+	agentClientFactory                      biagentclient.AgentClientFactory
 	blobstoreFactory                        biblobstore.Factory
 	deploymentManagerFactory                bidepl.ManagerFactory
 	deploymentManifestPath                  string
@@ -74,11 +77,12 @@ type deploymentDeleter struct {
 	cpiUninstaller                          biinstall.Uninstaller
 	releaseFetcher                          birel.Fetcher
 	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentParser                        bideplmanifest.Parser
 	tempRootConfigurator                    TempRootConfigurator
 	targetProvider                          biinstall.TargetProvider
 }
 
-func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
+func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage, skipDrain bool) (err error) {
 	c.ui.PrintLinef("Deployment state: '%s'", c.deploymentStateService.Path())
 
 	if !c.deploymentStateService.Exists() {
@@ -109,6 +113,7 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 	}()
 
 	var installationManifest biinstallmanifest.Manifest
+	drainOptions := biinstance.DrainOptions{Skip: skipDrain}
 	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
 		var releaseSetManifest birelsetmanifest.Manifest
 		releaseSetManifest, installationManifest, err = c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.deploymentManifestPath)
@@ -116,6 +121,14 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 			return err
 		}
 
+		// only the update settings are needed to delete, so the deployment manifest is not validated
+		var deploymentManifest bideplmanifest.Manifest
+		deploymentManifest, err = c.deploymentParser.Parse(c.deploymentManifestPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Parsing deployment manifest")
+		}
+		drainOptions.MaxWait = deploymentManifest.Update.MaxDrainWait
+
 		cpiReleaseName := installationManifest.Template.Release
 		cpiReleaseRef, found := releaseSetManifest.FindByName(cpiReleaseName)
 		if !found {
@@ -136,7 +149,7 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 
 	err = c.cpiInstaller.WithInstalledCpiRelease(installationManifest, target, stage, func(localCpiInstallation biinstall.Installation) error {
 		return localCpiInstallation.WithRunningRegistry(c.logger, stage, func() error {
-			err = c.findAndDeleteDeployment(stage, localCpiInstallation, deploymentState.DirectorID, installationManifest.Mbus)
+			err = c.findAndDeleteDeployment(stage, localCpiInstallation, deploymentState.DirectorID, installationManifest, drainOptions)
 
 			if err != nil {
 				return err
@@ -156,19 +169,19 @@ func (c *deploymentDeleter) DeleteDeployment(stage biui.Stage) (err error) {
 	return err
 }
 
-func (c *deploymentDeleter) findAndDeleteDeployment(stage biui.Stage, installation biinstall.Installation, directorID, installationMbus string) error {
-	deploymentManager, err := c.deploymentManager(installation, directorID, installationMbus)
+func (c *deploymentDeleter) findAndDeleteDeployment(stage biui.Stage, installation biinstall.Installation, directorID string, installationManifest biinstallmanifest.Manifest, drainOptions biinstance.DrainOptions) error {
+	deploymentManager, err := c.deploymentManager(installation, directorID, installationManifest)
 	if err != nil {
 		return err
 	}
-	err = c.findCurrentDeploymentAndDelete(stage, deploymentManager)
+	err = c.findCurrentDeploymentAndDelete(stage, deploymentManager, drainOptions)
 	if err != nil {
 		return bosherr.WrapError(err, "Deleting deployment")
 	}
 	return deploymentManager.Cleanup(stage)
 }
 
-func (c *deploymentDeleter) findCurrentDeploymentAndDelete(stage biui.Stage, deploymentManager bidepl.Manager) error {
+func (c *deploymentDeleter) findCurrentDeploymentAndDelete(stage biui.Stage, deploymentManager bidepl.Manager, drainOptions biinstance.DrainOptions) error {
 	c.logger.Debug(c.logTag, "Finding current deployment...")
 	deployment, found, err := deploymentManager.FindCurrent()
 	if err != nil {
@@ -182,23 +195,28 @@ func (c *deploymentDeleter) findCurrentDeploymentAndDelete(stage biui.Stage, dep
 			return nil
 		}
 
-		return deployment.Delete(deleteStage)
+		return deployment.Delete(deleteStage, drainOptions)
 	})
 }
 
-func (c *deploymentDeleter) deploymentManager(installation biinstall.Installation, directorID, installationMbus string) (bidepl.Manager, error) {
+func (c *deploymentDeleter) deploymentManager(installation biinstall.Installation, directorID string, installationManifest biinstallmanifest.Manifest) (bidepl.Manager, error) {
 	c.logger.Debug(c.logTag, "Creating cloud client...")
 	cloud, err := c.cloudFactory.NewCloud(installation, directorID)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Creating CPI client from CPI installation")
 	}
 
+	mbusClient, err := newMbusClient(installationManifest, biconfig.NewHostKeyRepo(c.deploymentStateService), c.logger)
+	if err != nil {
+		return nil, err
+	}
+
 	c.logger.Debug(c.logTag, "Creating agent client...")
-	agentClient := c.agentClientFactory.NewAgentClient(directorID, installationMbus)
+	agentClient := c.agentClientFactory.NewAgentClient(directorID, installationManifest.Mbus, mbusClient)
 
 	c.logger.Debug(c.logTag, "Creating blobstore client...")
 
-	blobstore, err := c.blobstoreFactory.Create(installationMbus, bihttpclient.CreateDefaultClientInsecureSkipVerify())
+	blobstore, err := c.blobstoreFactory.Create(installationManifest.Mbus, mbusClient)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Creating blobstore client")
 	}
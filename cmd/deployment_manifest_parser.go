@@ -18,26 +18,64 @@ func (y DeploymentManifestParser) GetDeploymentManifest(deploymentManifestPath s
 	var deploymentManifest bideplmanifest.Manifest
 	err := stage.Perform("Validating deployment manifest", func() error {
 		var err error
-		deploymentManifest, err = y.DeploymentParser.Parse(deploymentManifestPath)
+		deploymentManifest, err = y.ParseDeploymentManifest(deploymentManifestPath)
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Parsing deployment manifest '%s'", deploymentManifestPath)
+			return err
 		}
 
-		err = y.DeploymentValidator.Validate(deploymentManifest, releaseSetManifest)
+		return y.validate(deploymentManifest, releaseSetManifest)
+	})
+	if err != nil {
+		return bideplmanifest.Manifest{}, err
+	}
+
+	return deploymentManifest, nil
+}
+
+// ParseDeploymentManifest parses the deployment manifest without validating it,
+// so that its stemcell can be fetched while the releases are extracted
+func (y DeploymentManifestParser) ParseDeploymentManifest(deploymentManifestPath string) (bideplmanifest.Manifest, error) {
+	deploymentManifest, err := y.DeploymentParser.Parse(deploymentManifestPath)
+	if err != nil {
+		return bideplmanifest.Manifest{}, bosherr.WrapErrorf(err, "Parsing deployment manifest '%s'", deploymentManifestPath)
+	}
+
+	return deploymentManifest, nil
+}
+
+// ValidateDeploymentManifest validates a parsed deployment manifest against the release set,
+// so that an invalid manifest fails before any release or stemcell is downloaded
+func (y DeploymentManifestParser) ValidateDeploymentManifest(deploymentManifest bideplmanifest.Manifest, releaseSetManifest birelsetmanifest.Manifest, stage biui.Stage) error {
+	return stage.Perform("Validating deployment manifest", func() error {
+		err := y.DeploymentValidator.Validate(deploymentManifest, releaseSetManifest)
 		if err != nil {
 			return bosherr.WrapError(err, "Validating deployment manifest")
 		}
+		return nil
+	})
+}
 
-		err = y.DeploymentValidator.ValidateReleaseJobs(deploymentManifest, y.ReleaseManager)
+// ValidateDeploymentJobs validates that the jobs of a parsed deployment manifest refer to jobs of the extracted releases
+func (y DeploymentManifestParser) ValidateDeploymentJobs(deploymentManifest bideplmanifest.Manifest, stage biui.Stage) error {
+	return stage.Perform("Validating deployment jobs", func() error {
+		err := y.DeploymentValidator.ValidateReleaseJobs(deploymentManifest, y.ReleaseManager)
 		if err != nil {
 			return bosherr.WrapError(err, "Validating deployment jobs refer to jobs in release")
 		}
-
 		return nil
 	})
+}
+
+func (y DeploymentManifestParser) validate(deploymentManifest bideplmanifest.Manifest, releaseSetManifest birelsetmanifest.Manifest) error {
+	err := y.DeploymentValidator.Validate(deploymentManifest, releaseSetManifest)
 	if err != nil {
-		return bideplmanifest.Manifest{}, err
+		return bosherr.WrapError(err, "Validating deployment manifest")
 	}
 
-	return deploymentManifest, nil
+	err = y.DeploymentValidator.ValidateReleaseJobs(deploymentManifest, y.ReleaseManager)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating deployment jobs refer to jobs in release")
+	}
+
+	return nil
 }
@@ -3,22 +3,24 @@ package cmd
 import (
 	"strings"
 
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
 	bidepl "github.com/cloudfoundry/bosh-init/deployment"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
-	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
@@ -32,7 +34,7 @@ func NewDeploymentPreparer(
 	deploymentRecord bidepl.Record,
 	cloudFactory bicloud.Factory,
 	stemcellManagerFactory bistemcell.ManagerFactory,
-	agentClientFactory bihttpagent.AgentClientFactory,
+	agentClientFactory biagentclient.AgentClientFactory,
 	vmManagerFactory bivm.ManagerFactory,
 	blobstoreFactory biblobstore.Factory,
 	deployer bidepl.Deployer,
@@ -40,6 +42,12 @@ func NewDeploymentPreparer(
 	cpiInstaller bicpirel.CpiInstaller,
 	releaseFetcher birel.Fetcher,
 	stemcellFetcher bistemcell.Fetcher,
+	catalogResolver bicatalog.Resolver,
+	resolvedRefRepo biconfig.ResolvedRefRepo,
+	tarballRepo biconfig.TarballRepo,
+	signatureVerifier bisignature.Verifier,
+	signatureRepo biconfig.SignatureRepo,
+	maxConcurrentDownloads int,
 	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
 	deploymentManifestParser DeploymentManifestParser,
 	tempRootConfigurator TempRootConfigurator,
@@ -63,6 +71,12 @@ func NewDeploymentPreparer(
 		cpiInstaller:                            cpiInstaller,
 		releaseFetcher:                          releaseFetcher,
 		stemcellFetcher:                         stemcellFetcher,
+		catalogResolver:                         catalogResolver,
+		resolvedRefRepo:                         resolvedRefRepo,
+		tarballRepo:                             tarballRepo,
+		signatureVerifier:                       signatureVerifier,
+		signatureRepo:                           signatureRepo,
+		maxConcurrentDownloads:                  maxConcurrentDownloads,
 		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
 		deploymentManifestParser:                deploymentManifestParser,
 		tempRootConfigurator:                    tempRootConfigurator,
@@ -80,7 +94,7 @@ type DeploymentPreparer struct {
 	deploymentRecord                        bidepl.Record
 	cloudFactory                            bicloud.Factory
 	stemcellManagerFactory                  bistemcell.ManagerFactory
-	agentClientFactory                      bihttpagent.AgentClientFactory
+	agentClientFactory                      biagentclient.AgentClientFactory
 	vmManagerFactory                        bivm.ManagerFactory
 	blobstoreFactory                        biblobstore.Factory
 	deployer                                bidepl.Deployer
@@ -88,6 +102,12 @@ type DeploymentPreparer struct {
 	cpiInstaller                            bicpirel.CpiInstaller
 	releaseFetcher                          birel.Fetcher
 	stemcellFetcher                         bistemcell.Fetcher
+	catalogResolver                         bicatalog.Resolver
+	resolvedRefRepo                         biconfig.ResolvedRefRepo
+	tarballRepo                             biconfig.TarballRepo
+	signatureVerifier                       bisignature.Verifier
+	signatureRepo                           biconfig.SignatureRepo
+	maxConcurrentDownloads                  int
 	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
 	deploymentManifestParser                DeploymentManifestParser
 	tempRootConfigurator                    TempRootConfigurator
@@ -133,7 +153,17 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 		extractedStemcell    bistemcell.ExtractedStemcell
 		deploymentManifest   bideplmanifest.Manifest
 		installationManifest biinstallmanifest.Manifest
+		tarballs             []biconfig.TarballRecord
 	)
+	defer func() {
+		if extractedStemcell == nil {
+			return
+		}
+		deleteErr := extractedStemcell.Delete()
+		if deleteErr != nil {
+			c.logger.Warn(c.logTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
+		}
+	}()
 	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
 		var releaseSetManifest birelsetmanifest.Manifest
 		releaseSetManifest, installationManifest, err = c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.deploymentManifestPath)
@@ -141,55 +171,57 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 			return err
 		}
 
-		for _, releaseRef := range releaseSetManifest.Releases {
-			err = c.releaseFetcher.DownloadAndExtract(releaseRef, stage)
-			if err != nil {
+		deploymentManifest, err = c.deploymentManifestParser.ParseDeploymentManifest(c.deploymentManifestPath)
+		if err != nil {
+			return err
+		}
+
+		err = c.deploymentManifestParser.ValidateDeploymentManifest(deploymentManifest, releaseSetManifest, stage)
+		if err != nil {
+			return err
+		}
+
+		// the stemcell is fetched first as it is usually the largest download
+		fetches := []func(biui.Stage) error{
+			func(stage biui.Stage) error {
+				var err error
+				extractedStemcell, err = c.stemcellFetcher.GetStemcell(deploymentManifest, stage)
 				return err
-			}
+			},
+		}
+		for _, releaseRef := range releaseSetManifest.Releases {
+			releaseRef := releaseRef
+			fetches = append(fetches, func(stage biui.Stage) error {
+				return c.releaseFetcher.DownloadAndExtract(releaseRef, stage)
+			})
 		}
 
-		err := c.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
+		err = stage.PerformConcurrently(c.maxConcurrentDownloads, fetches)
 		if err != nil {
 			return err
 		}
 
-		deploymentManifest, err = c.deploymentManifestParser.GetDeploymentManifest(c.deploymentManifestPath, releaseSetManifest, stage)
+		err = c.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
 		if err != nil {
 			return err
 		}
 
-		extractedStemcell, err = c.stemcellFetcher.GetStemcell(deploymentManifest, stage)
-
-		nonCpiReleasesMap, _ := deploymentManifest.GetListOfTemplateReleases()
-		delete(nonCpiReleasesMap, installationManifest.Template.Release) // remove CPI release from nonCpiReleasesMap
-
-		for _, release := range c.releaseManager.List() {
-			if _, ok := nonCpiReleasesMap[release.Name()]; ok {
-				if release.IsCompiled() {
-					compilationOsAndVersion := release.Packages()[0].Stemcell
-					if strings.ToLower(compilationOsAndVersion) != strings.ToLower(extractedStemcell.OsAndVersion()) {
-						return bosherr.Errorf("OS/Version mismatch between deployment stemcell and compiled package stemcell for release '%s'", release.Name())
-					}
-				}
-			} else {
-				// It is a CPI release, check if it is compiled
-				if release.IsCompiled() {
-					return bosherr.Errorf("CPI is not allowed to be a compiled release. The provided CPI release '%s' is compiled", release.Name())
-				}
-			}
+		err = c.deploymentManifestParser.ValidateDeploymentJobs(deploymentManifest, stage)
+		if err != nil {
+			return err
 		}
 
-		return err
+		errs := compiledReleaseErrors(c.releaseManager.List(), releaseSetManifest, installationManifest, deploymentManifest, extractedStemcell)
+		if len(errs) > 0 {
+			return errs[0]
+		}
+
+		tarballs = c.deployedTarballs(releaseSetManifest, deploymentManifest)
+		return nil
 	})
 	if err != nil {
 		return err
 	}
-	defer func() {
-		deleteErr := extractedStemcell.Delete()
-		if deleteErr != nil {
-			c.logger.Warn(c.logTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
-		}
-	}()
 
 	isDeployed, err := c.deploymentRecord.IsDeployed(c.deploymentManifestPath, c.releaseManager.List(), extractedStemcell)
 	if err != nil {
@@ -198,7 +230,7 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 
 	if isDeployed {
 		c.ui.PrintLinef("No deployment, stemcell or release changes. Skipping deploy.")
-		return nil
+		return c.recordDeployedArtifacts(tarballs)
 	}
 
 	err = c.cpiInstaller.WithInstalledCpiRelease(installationManifest, target, stage, func(installation biinstall.Installation) error {
@@ -209,6 +241,7 @@ func (c *DeploymentPreparer) PrepareDeployment(stage biui.Stage) (err error) {
 				extractedStemcell,
 				installationManifest,
 				deploymentManifest,
+				tarballs,
 				stage)
 		})
 	})
@@ -223,6 +256,7 @@ func (c *DeploymentPreparer) deploy(
 	extractedStemcell bistemcell.ExtractedStemcell,
 	installationManifest biinstallmanifest.Manifest,
 	deploymentManifest bideplmanifest.Manifest,
+	tarballs []biconfig.TarballRecord,
 	stage biui.Stage,
 ) (err error) {
 	cloud, err := c.cloudFactory.NewCloud(installation, deploymentState.DirectorID)
@@ -237,10 +271,15 @@ func (c *DeploymentPreparer) deploy(
 		return err
 	}
 
-	agentClient := c.agentClientFactory.NewAgentClient(deploymentState.DirectorID, installationManifest.Mbus)
+	mbusClient, err := newMbusClient(installationManifest, biconfig.NewHostKeyRepo(c.deploymentStateService), c.logger)
+	if err != nil {
+		return err
+	}
+
+	agentClient := c.agentClientFactory.NewAgentClient(deploymentState.DirectorID, installationManifest.Mbus, mbusClient)
 	vmManager := c.vmManagerFactory.NewManager(cloud, agentClient)
 
-	blobstore, err := c.blobstoreFactory.Create(installationManifest.Mbus, bihttpclient.CreateDefaultClientInsecureSkipVerify())
+	blobstore, err := c.blobstoreFactory.Create(installationManifest.Mbus, mbusClient)
 	if err != nil {
 		return bosherr.WrapError(err, "Creating blobstore client")
 	}
@@ -258,6 +297,7 @@ func (c *DeploymentPreparer) deploy(
 			installationManifest.Registry,
 			vmManager,
 			blobstore,
+			biinstance.DrainOptions{MaxWait: deploymentManifest.Update.MaxDrainWait},
 			deployStage,
 		)
 		if err != nil {
@@ -269,7 +309,7 @@ func (c *DeploymentPreparer) deploy(
 			return bosherr.WrapError(err, "Updating deployment record")
 		}
 
-		return nil
+		return c.recordDeployedArtifacts(tarballs)
 	})
 	if err != nil {
 		return err
@@ -284,3 +324,71 @@ func (c *DeploymentPreparer) deploy(
 
 	return nil
 }
+
+// recordDeployedArtifacts records the sources of the deployed tarballs, those that the releases and stemcell resolved to in the index,
+// and the signers of their signatures, once they are deployed
+func (c *DeploymentPreparer) recordDeployedArtifacts(tarballs []biconfig.TarballRecord) error {
+	err := c.tarballRepo.UpdateCurrent(tarballs)
+	if err != nil {
+		return bosherr.WrapError(err, "Recording deployed tarballs")
+	}
+
+	for _, resolvedRef := range c.catalogResolver.ResolvedRefs() {
+		err = c.resolvedRefRepo.Save(resolvedRef.Type, resolvedRef.RequestedVersion, resolvedRef.Entry)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Recording resolved %s '%s'", resolvedRef.Type, resolvedRef.Entry.Name)
+		}
+	}
+
+	for _, signed := range c.signatureVerifier.SignedArtifacts() {
+		err = c.signatureRepo.Save(biconfig.SignatureRecord{
+			Type:      signed.Artifact.Type,
+			Name:      signed.Artifact.Name,
+			URL:       signed.Artifact.URL,
+			SHA1:      signed.Artifact.SHA1,
+			Signature: signed.Artifact.Signature,
+			Signer:    signed.Signer,
+		})
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Recording the signer of %s '%s'", signed.Artifact.Type, signed.Artifact.Name)
+		}
+	}
+
+	return nil
+}
+
+// deployedTarballs returns the sources of the downloaded release and stemcell tarballs,
+// with the URL and digest they resolved to in the index when the manifest only names a version.
+// Local tarballs and dev releases are left out as they are never cached.
+func (c *DeploymentPreparer) deployedTarballs(releaseSetManifest birelsetmanifest.Manifest, deploymentManifest bideplmanifest.Manifest) []biconfig.TarballRecord {
+	resolvedEntries := map[string]bicatalog.Entry{}
+	for _, resolvedRef := range c.catalogResolver.ResolvedRefs() {
+		resolvedEntries[resolvedRef.Type+"/"+resolvedRef.Entry.Name] = resolvedRef.Entry
+	}
+
+	tarballs := []biconfig.TarballRecord{}
+	addTarball := func(tarballType, name, url, sha1 string) {
+		if url == "" {
+			entry, found := resolvedEntries[tarballType+"/"+name]
+			if !found {
+				return
+			}
+			url, sha1 = entry.URL, entry.SHA1
+		}
+		if strings.HasPrefix(url, "file://") {
+			return
+		}
+		tarballs = append(tarballs, biconfig.TarballRecord{Type: tarballType, Name: name, URL: url, SHA1: sha1})
+	}
+
+	for _, releaseRef := range releaseSetManifest.Releases {
+		addTarball("release", releaseRef.Name, releaseRef.URL, releaseRef.SHA1)
+	}
+
+	stemcellRef, err := deploymentManifest.Stemcell(deploymentManifest.JobName())
+	if err == nil {
+		addTarball("stemcell", stemcellRef.Name, stemcellRef.URL, stemcellRef.SHA1)
+	}
+
+	return tarballs
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	// ExitCodeDefault is the exit status of a failed command, unless it exits with a specific status
+	ExitCodeDefault = 1
+
+	// ExitCodeInvalid is the exit status of the validate command when it found problems in a deployment manifest
+	ExitCodeInvalid = 1
+
+	// ExitCodeFailed is the exit status of the validate command when it could not validate a deployment manifest
+	ExitCodeFailed = 2
+)
+
+// ExitError is the message of a command error that exits bosh-init with a specific status,
+// usually wrapping the cause with bosherr.WrapComplexError
+type ExitError struct {
+	Message string
+	Code    int
+}
+
+func NewExitError(code int, message string) ExitError {
+	return ExitError{
+		Message: message,
+		Code:    code,
+	}
+}
+
+func (e ExitError) Error() string {
+	return e.Message
+}
+
+// ExitCode returns the exit status of a failed command, which is ExitCodeDefault unless the error wraps an ExitError
+func ExitCode(err error) int {
+	switch specificErr := err.(type) {
+	case ExitError:
+		return specificErr.Code
+	case bosherr.ComplexError:
+		if exitErr, ok := specificErr.Err.(ExitError); ok {
+			return exitErr.Code
+		}
+		return ExitCode(specificErr.Cause)
+	}
+	return ExitCodeDefault
+}
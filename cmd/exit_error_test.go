@@ -0,0 +1,30 @@
+package cmd_test
+
+import (
+	"errors"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+var _ = Describe("ExitCode", func() {
+	It("returns 1 for errors without an exit code", func() {
+		Expect(bicmd.ExitCode(errors.New("fake-error"))).To(Equal(1))
+		Expect(bicmd.ExitCode(bosherr.WrapError(errors.New("fake-cause"), "fake-error"))).To(Equal(1))
+	})
+
+	It("returns the code of an exit error", func() {
+		Expect(bicmd.ExitCode(bicmd.NewExitError(bicmd.ExitCodeFailed, "fake-error"))).To(Equal(2))
+	})
+
+	It("returns the code of an exit error wrapping the cause of the error", func() {
+		err := bosherr.WrapComplexError(errors.New("fake-cause"), bicmd.NewExitError(bicmd.ExitCodeFailed, "fake-error"))
+		err = bosherr.WrapError(err, "Command 'fake-cmd' failed")
+
+		Expect(bicmd.ExitCode(err)).To(Equal(2))
+		Expect(err.Error()).To(Equal("Command 'fake-cmd' failed: fake-error: fake-cause"))
+	})
+})
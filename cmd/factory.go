@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"net/http"
+	"os"
 	"path/filepath"
 	"time"
 
-	bihttpagent "github.com/cloudfoundry/bosh-agent/agentclient/http"
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
@@ -18,6 +21,8 @@ import (
 	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	bidir "github.com/cloudfoundry/bosh-init/director"
+	bihandoff "github.com/cloudfoundry/bosh-init/handoff"
 	biindex "github.com/cloudfoundry/bosh-init/index"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
@@ -25,10 +30,12 @@ import (
 	biregistry "github.com/cloudfoundry/bosh-init/registry"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
 	bistatepkg "github.com/cloudfoundry/bosh-init/state/pkg"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
 	bitemplate "github.com/cloudfoundry/bosh-init/templatescompiler"
 	bitemplateerb "github.com/cloudfoundry/bosh-init/templatescompiler/erbrenderer"
+	biuaa "github.com/cloudfoundry/bosh-init/uaa"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
@@ -44,37 +51,41 @@ type Factory interface {
 }
 
 type factory struct {
-	commands               CommandList
-	fs                     boshsys.FileSystem
-	ui                     biui.UI
-	timeService            clock.Clock
-	logger                 boshlog.Logger
-	uuidGenerator          boshuuid.Generator
-	workspaceRootPath      string
-	runner                 boshsys.CmdRunner
-	compressor             boshcmd.Compressor
-	agentClientFactory     bihttpagent.AgentClientFactory
-	registryServerManager  biregistry.ServerManager
-	sshTunnelFactory       bisshtunnel.Factory
-	instanceFactory        biinstance.Factory
-	instanceManagerFactory biinstance.ManagerFactory
-	deploymentFactory      bidepl.Factory
-	blobstoreFactory       biblobstore.Factory
-	eventLogger            biui.Stage
-	releaseExtractor       birel.Extractor
-	releaseManager         birel.Manager
-	releaseSetParser       birelsetmanifest.Parser
-	releaseJobResolver     bideplrel.JobResolver
-	installationParser     biinstallmanifest.Parser
-	deploymentParser       bideplmanifest.Parser
-	releaseSetValidator    birelsetmanifest.Validator
-	installationValidator  biinstallmanifest.Validator
-	deploymentValidator    bideplmanifest.Validator
-	cloudFactory           bicloud.Factory
-	stateBuilderFactory    biinstancestate.BuilderFactory
-	compiledPackageRepo    bistatepkg.CompiledPackageRepo
-	tarballProvider        bitarball.Provider
-	cpiReleaseValidator    *bicpirel.Validator
+	commands              CommandList
+	fs                    boshsys.FileSystem
+	ui                    biui.UI
+	timeService           clock.Clock
+	logger                boshlog.Logger
+	uuidGenerator         boshuuid.Generator
+	workspaceRootPath     string
+	indexLocation         string
+	runner                boshsys.CmdRunner
+	compressor            boshcmd.Compressor
+	agentClientFactory    biagentclient.AgentClientFactory
+	registryServerManager biregistry.ServerManager
+	instanceFactory       biinstance.Factory
+	deploymentFactory     bidepl.Factory
+	blobstoreFactory      biblobstore.Factory
+	eventLogger           biui.Stage
+	releaseExtractor      birel.Extractor
+	stemcellExtractor     bistemcell.Extractor
+	releaseManager        birel.Manager
+	releaseSetParser      birelsetmanifest.Parser
+	releaseJobResolver    bideplrel.JobResolver
+	installationParser    biinstallmanifest.Parser
+	deploymentParser      bideplmanifest.Parser
+	releaseSetValidator   birelsetmanifest.Validator
+	installationValidator biinstallmanifest.Validator
+	deploymentValidator   bideplmanifest.Validator
+	cloudFactory          bicloud.Factory
+	stateBuilderFactory   biinstancestate.BuilderFactory
+	compiledPackageRepo   bistatepkg.CompiledPackageRepo
+	tarballProvider       bitarball.Provider
+	tarballCache          bitarball.Cache
+	schemeRegistry        bitarball.SchemeRegistry
+	sourceConfig          *bitarball.SourceConfig
+	httpClient            *http.Client
+	cpiReleaseValidator   *bicpirel.Validator
 }
 
 func NewFactory(
@@ -84,6 +95,7 @@ func NewFactory(
 	logger boshlog.Logger,
 	uuidGenerator boshuuid.Generator,
 	workspaceRootPath string,
+	indexLocation string,
 ) Factory {
 	f := &factory{
 		fs:                fs,
@@ -92,12 +104,24 @@ func NewFactory(
 		logger:            logger,
 		uuidGenerator:     uuidGenerator,
 		workspaceRootPath: workspaceRootPath,
+		indexLocation:     indexLocation,
 	}
 	f.commands = CommandList{
-		"deploy":  f.createDeployCmd,
-		"delete":  f.createDeleteCmd,
-		"help":    f.createHelpCmd,
-		"version": f.createVersionCmd,
+		"deploy":           f.createDeployCmd,
+		"delete":           f.createDeleteCmd,
+		"stop":             f.createStopCmd,
+		"start":            f.createStartCmd,
+		"restart":          f.createRestartCmd,
+		"recreate":         f.createRecreateCmd,
+		"instances":        f.createInstancesCmd,
+		"cck":              f.createCloudCheckCmd,
+		"run-errand":       f.createRunErrandCmd,
+		"help":             f.createHelpCmd,
+		"version":          f.createVersionCmd,
+		"cache":            f.createCacheCmd,
+		"inspect-release":  f.createInspectReleaseCmd,
+		"inspect-stemcell": f.createInspectStemcellCmd,
+		"validate":         f.createValidateCmd,
 	}
 	return f
 }
@@ -118,7 +142,7 @@ func (f *factory) CreateCommand(name string) (Cmd, error) {
 
 func (f *factory) createDeployCmd() (Cmd, error) {
 	getter := func(deploymentManifestPath string) (DeploymentPreparer, error) {
-		f := &deploymentManagerFactory2{f: f, deploymentManifestPath: deploymentManifestPath}
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
 		deploymentPreparer, err := f.loadDeploymentPreparer()
 		if err != nil {
 			return deploymentPreparer, err
@@ -126,12 +150,12 @@ func (f *factory) createDeployCmd() (Cmd, error) {
 
 		return deploymentPreparer, nil
 	}
-	return NewDeployCmd(f.ui, f.fs, f.logger, getter), nil
+	return NewDeployCmd(f.ui, f.fs, f.logger, getter, f.loadHandoff), nil
 }
 
 func (f *factory) createDeleteCmd() (Cmd, error) {
 	getter := func(deploymentManifestPath string) (DeploymentDeleter, error) {
-		f := &deploymentManagerFactory2{f: f, deploymentManifestPath: deploymentManifestPath}
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
 		deploymentDeleter, err := f.loadDeploymentDeleter()
 		if err != nil {
 			return deploymentDeleter, err
@@ -142,6 +166,64 @@ func (f *factory) createDeleteCmd() (Cmd, error) {
 	return NewDeleteCmd(f.ui, f.fs, f.logger, getter), nil
 }
 
+func (f *factory) loadInstanceLifecycle(deploymentManifestPath string) (InstanceLifecycle, error) {
+	d := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+	return d.loadInstanceLifecycle()
+}
+
+func (f *factory) createStopCmd() (Cmd, error) {
+	return NewStopCmd(f.ui, f.fs, f.logger, f.loadInstanceLifecycle), nil
+}
+
+func (f *factory) createStartCmd() (Cmd, error) {
+	return NewStartCmd(f.ui, f.fs, f.logger, f.loadInstanceLifecycle), nil
+}
+
+func (f *factory) createRestartCmd() (Cmd, error) {
+	return NewRestartCmd(f.ui, f.fs, f.logger, f.loadInstanceLifecycle), nil
+}
+
+func (f *factory) createRecreateCmd() (Cmd, error) {
+	return NewRecreateCmd(f.ui, f.fs, f.logger, f.loadInstanceLifecycle), nil
+}
+
+func (f *factory) createInstancesCmd() (Cmd, error) {
+	getter := func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error) {
+		d := &deploymentManagerFactory2{f: f, ui: ui, deploymentManifestPath: deploymentManifestPath}
+		return d.loadInstanceLifecycle()
+	}
+
+	return NewInstancesCmd(f.ui, f.fs, f.timeService, f.logger, getter), nil
+}
+
+func (f *factory) createCloudCheckCmd() (Cmd, error) {
+	return NewCloudCheckCmd(f.ui, f.fs, f.logger, os.Stdin, f.loadInstanceLifecycle), nil
+}
+
+func (f *factory) createRunErrandCmd() (Cmd, error) {
+	return NewRunErrandCmd(f.ui, f.fs, f.logger, f.loadInstanceLifecycle), nil
+}
+
+func (f *factory) createCacheCmd() (Cmd, error) {
+	return NewCacheCmd(f.ui, f.loadTarballCache, bicrypto.NewDigestCalculator(f.fs), f.timeService, f.logger), nil
+}
+
+func (f *factory) createInspectReleaseCmd() (Cmd, error) {
+	return NewInspectReleaseCmd(f.ui, f.fs, f.timeService, f.logger, NewTempRootConfigurator(f.fs), filepath.Join(f.workspaceRootPath, "tmp", "inspect"), f.loadTarballProvider, f.loadReleaseExtractor()), nil
+}
+
+func (f *factory) createInspectStemcellCmd() (Cmd, error) {
+	return NewInspectStemcellCmd(f.ui, f.fs, f.timeService, f.logger, NewTempRootConfigurator(f.fs), filepath.Join(f.workspaceRootPath, "tmp", "inspect"), f.loadTarballProvider, f.loadStemcellExtractor()), nil
+}
+
+func (f *factory) createValidateCmd() (Cmd, error) {
+	getter := func(deploymentManifestPath string) (ManifestValidator, error) {
+		f := &deploymentManagerFactory2{f: f, ui: f.ui, deploymentManifestPath: deploymentManifestPath}
+		return f.loadManifestValidator()
+	}
+	return NewValidateCmd(f.ui, f.fs, f.logger, getter), nil
+}
+
 func (f *factory) createHelpCmd() (Cmd, error) {
 	return NewHelpCmd(f.ui, f.commands), nil
 }
@@ -185,28 +267,6 @@ func (f *factory) loadRegistryServerManager() biregistry.ServerManager {
 	return f.registryServerManager
 }
 
-func (f *factory) loadSSHTunnelFactory() bisshtunnel.Factory {
-	if f.sshTunnelFactory != nil {
-		return f.sshTunnelFactory
-	}
-
-	f.sshTunnelFactory = bisshtunnel.NewFactory(f.logger)
-	return f.sshTunnelFactory
-}
-
-func (f *factory) loadInstanceManagerFactory() biinstance.ManagerFactory {
-	if f.instanceManagerFactory != nil {
-		return f.instanceManagerFactory
-	}
-
-	f.instanceManagerFactory = biinstance.NewManagerFactory(
-		f.loadSSHTunnelFactory(),
-		f.loadInstanceFactory(),
-		f.logger,
-	)
-	return f.instanceManagerFactory
-}
-
 func (f *factory) loadInstanceFactory() biinstance.Factory {
 	if f.instanceFactory != nil {
 		return f.instanceFactory
@@ -214,6 +274,7 @@ func (f *factory) loadInstanceFactory() biinstance.Factory {
 
 	f.instanceFactory = biinstance.NewFactory(
 		f.loadBuilderFactory(),
+		f.timeService,
 	)
 	return f.instanceFactory
 }
@@ -270,12 +331,12 @@ func (f *factory) loadDeploymentFactory() bidepl.Factory {
 	return f.deploymentFactory
 }
 
-func (f *factory) loadAgentClientFactory() bihttpagent.AgentClientFactory {
+func (f *factory) loadAgentClientFactory() biagentclient.AgentClientFactory {
 	if f.agentClientFactory != nil {
 		return f.agentClientFactory
 	}
 
-	f.agentClientFactory = bihttpagent.NewAgentClientFactory(1*time.Second, f.logger)
+	f.agentClientFactory = biagentclient.NewAgentClientFactory(1*time.Second, f.timeService, f.logger)
 	return f.agentClientFactory
 }
 
@@ -301,22 +362,137 @@ func (f *factory) loadReleaseExtractor() birel.Extractor {
 		return f.releaseExtractor
 	}
 
-	releaseValidator := birel.NewValidator(f.fs)
+	releaseValidator := birel.NewValidator(f.fs, bicrypto.NewDigestCalculator(f.fs))
 	f.releaseExtractor = birel.NewExtractor(f.fs, f.loadCompressor(), releaseValidator, f.logger)
 	return f.releaseExtractor
 }
 
-func (f *factory) loadTarballProvider() bitarball.Provider {
+func (f *factory) loadStemcellExtractor() bistemcell.Extractor {
+	if f.stemcellExtractor != nil {
+		return f.stemcellExtractor
+	}
+
+	stemcellReader := bistemcell.NewReader(f.loadCompressor(), f.fs)
+	f.stemcellExtractor = bistemcell.NewExtractor(stemcellReader, f.fs)
+	return f.stemcellExtractor
+}
+
+func (f *factory) loadTarballProvider() (bitarball.Provider, error) {
 	if f.tarballProvider != nil {
-		return f.tarballProvider
+		return f.tarballProvider, nil
+	}
+
+	sourceConfig, err := f.loadSourceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	schemeRegistry, err := f.loadSchemeRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	tarballCache, err := f.loadTarballCache()
+	if err != nil {
+		return nil, err
+	}
+
+	digestCalculator := bicrypto.NewDigestCalculator(f.fs)
+	f.tarballProvider = bitarball.NewProvider(tarballCache, f.fs, schemeRegistry, sourceConfig.Mirrors, digestCalculator, 3, 500*time.Millisecond, f.timeService, f.logger)
+	return f.tarballProvider, nil
+}
+
+func (f *factory) loadSchemeRegistry() (bitarball.SchemeRegistry, error) {
+	if f.schemeRegistry != nil {
+		return f.schemeRegistry, nil
+	}
+
+	sourceConfig, err := f.loadSourceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := f.loadHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	schemeRegistry := bitarball.NewSchemeRegistry()
+	httpDownloader := bitarball.NewHTTPDownloader(
+		bihttpclient.NewHTTPClient(httpClient, f.logger),
+		bitarball.NewCredentialsProvider(sourceConfig.Credentials, sourceConfig.NetrcPath, f.fs),
+		f.logger,
+	)
+	schemeRegistry.Register("http", httpDownloader)
+	schemeRegistry.Register("https", httpDownloader)
+	schemeRegistry.Register("s3", bitarball.NewS3Downloader(bihttpclient.NewHTTPClient(httpClient, f.logger), sourceConfig.S3, f.timeService, f.logger))
+
+	f.schemeRegistry = schemeRegistry
+	return f.schemeRegistry, nil
+}
+
+func (f *factory) loadTarballCache() (bitarball.Cache, error) {
+	if f.tarballCache != nil {
+		return f.tarballCache, nil
+	}
+
+	sourceConfig, err := f.loadSourceConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	tarballCacheBasePath := filepath.Join(f.workspaceRootPath, "downloads")
-	tarballCache := bitarball.NewCache(tarballCacheBasePath, f.fs, f.logger)
-	httpClient := bihttpclient.NewHTTPClient(bitarball.HTTPClient, f.logger)
-	sha1Calculator := bicrypto.NewSha1Calculator(f.fs)
-	f.tarballProvider = bitarball.NewProvider(tarballCache, f.fs, httpClient, sha1Calculator, 3, 500*time.Millisecond, f.logger)
-	return f.tarballProvider
+	f.tarballCache = bitarball.NewCache(tarballCacheBasePath, sourceConfig.CacheMaxSize, f.fs, f.timeService, f.logger)
+	return f.tarballCache, nil
+}
+
+func (f *factory) loadSourceConfig() (bitarball.SourceConfig, error) {
+	if f.sourceConfig != nil {
+		return *f.sourceConfig, nil
+	}
+
+	sourceConfig, err := bitarball.NewSourceConfigFromEnv(os.Getenv)
+	if err != nil {
+		return bitarball.SourceConfig{}, bosherr.WrapError(err, "Loading download settings")
+	}
+
+	f.sourceConfig = &sourceConfig
+	return sourceConfig, nil
+}
+
+func (f *factory) loadHandoff() (bihandoff.Handoff, error) {
+	handoffConfig, err := bihandoff.NewConfigFromEnv(os.Getenv)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Loading director handoff settings")
+	}
+
+	return bihandoff.NewHandoff(
+		handoffConfig,
+		bidir.NewFactory(f.timeService, f.logger),
+		biuaa.NewFactory(f.logger),
+		f.fs,
+		f.ui,
+		f.timeService,
+		f.logger,
+	), nil
+}
+
+func (f *factory) loadHTTPClient() (*http.Client, error) {
+	if f.httpClient != nil {
+		return f.httpClient, nil
+	}
+
+	sourceConfig, err := f.loadSourceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	f.httpClient, err = bitarball.NewHTTPClient(sourceConfig.CACertPath, f.fs)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating download http client")
+	}
+
+	return f.httpClient, nil
 }
 
 func (f *factory) loadReleaseManager() birel.Manager {
@@ -370,7 +546,7 @@ func (f *factory) loadDeploymentValidator() bideplmanifest.Validator {
 		return f.deploymentValidator
 	}
 
-	f.deploymentValidator = bideplmanifest.NewValidator(f.logger)
+	f.deploymentValidator = bideplmanifest.NewValidator(bideplmanifest.NewLinkResolver(f.loadReleaseJobResolver()), f.logger)
 	return f.deploymentValidator
 }
 
@@ -394,6 +570,7 @@ func (f *factory) loadCloudFactory() bicloud.Factory {
 
 type deploymentManagerFactory2 struct {
 	f                             *factory
+	ui                            biui.UI
 	deploymentManifestPath        string
 	deploymentStateService        biconfig.DeploymentStateService
 	legacyDeploymentStateMigrator biconfig.LegacyDeploymentStateMigrator
@@ -404,29 +581,55 @@ type deploymentManagerFactory2 struct {
 	diskManagerFactory            bidisk.ManagerFactory
 	deploymentManagerFactory      bidepl.ManagerFactory
 	vmManagerFactory              bivm.ManagerFactory
+	sshTunnelFactory              bisshtunnel.Factory
+	instanceManagerFactory        biinstance.ManagerFactory
 	stemcellManagerFactory        bistemcell.ManagerFactory
 	installerFactory              biinstall.InstallerFactory
 	deployer                      bidepl.Deployer
+	catalogResolver               bicatalog.Resolver
+	signatureVerifier             bisignature.Verifier
+	tarballProvider               bitarball.Provider
 }
 
 func (d *deploymentManagerFactory2) loadDeploymentPreparer() (DeploymentPreparer, error) {
-	deploymentRepo := biconfig.NewDeploymentRepo(d.loadDeploymentStateService())
-	releaseRepo := biconfig.NewReleaseRepo(d.loadDeploymentStateService(), d.f.uuidGenerator)
-	sha1Calculator := bicrypto.NewSha1Calculator(d.f.fs)
-	deploymentRecord := bidepl.NewRecord(deploymentRepo, releaseRepo, d.loadStemcellRepo(), sha1Calculator)
 	cpiInstaller, err := d.loadCpiInstaller()
 	if err != nil {
 		return DeploymentPreparer{}, err
 	}
 
+	releaseFetcher, err := d.loadReleaseFetcher()
+	if err != nil {
+		return DeploymentPreparer{}, err
+	}
+
+	stemcellFetcher, err := d.loadStemcellFetcher()
+	if err != nil {
+		return DeploymentPreparer{}, err
+	}
+
+	sourceConfig, err := d.f.loadSourceConfig()
+	if err != nil {
+		return DeploymentPreparer{}, err
+	}
+
+	catalogResolver, err := d.loadCatalogResolver()
+	if err != nil {
+		return DeploymentPreparer{}, err
+	}
+
+	signatureVerifier, err := d.loadSignatureVerifier()
+	if err != nil {
+		return DeploymentPreparer{}, err
+	}
+
 	return NewDeploymentPreparer(
-		d.f.ui,
+		d.ui,
 		d.f.logger,
 		"DeploymentPreparer",
 		d.loadDeploymentStateService(),
 		d.loadLegacyDeploymentStateMigrator(),
 		d.f.loadReleaseManager(),
-		deploymentRecord,
+		d.loadDeploymentRecord(),
 		d.f.loadCloudFactory(),
 		d.loadStemcellManagerFactory(),
 		d.f.loadAgentClientFactory(),
@@ -435,8 +638,14 @@ func (d *deploymentManagerFactory2) loadDeploymentPreparer() (DeploymentPreparer
 		d.loadDeployer(),
 		d.deploymentManifestPath,
 		cpiInstaller,
-		d.loadReleaseFetcher(),
-		d.loadStemcellFetcher(),
+		releaseFetcher,
+		stemcellFetcher,
+		catalogResolver,
+		biconfig.NewResolvedRefRepo(d.loadDeploymentStateService()),
+		biconfig.NewTarballRepo(d.loadDeploymentStateService()),
+		signatureVerifier,
+		biconfig.NewSignatureRepo(d.loadDeploymentStateService()),
+		sourceConfig.MaxConcurrentDownloads,
 		d.loadReleaseSetAndInstallationManifestParser(),
 		d.loadDeploymentManifestParser(),
 		NewTempRootConfigurator(d.f.fs),
@@ -444,13 +653,55 @@ func (d *deploymentManagerFactory2) loadDeploymentPreparer() (DeploymentPreparer
 	), nil
 }
 
+func (d *deploymentManagerFactory2) loadManifestValidator() (ManifestValidator, error) {
+	releaseFetcher, err := d.loadReleaseFetcher()
+	if err != nil {
+		return ManifestValidator{}, err
+	}
+
+	stemcellFetcher, err := d.loadStemcellFetcher()
+	if err != nil {
+		return ManifestValidator{}, err
+	}
+
+	sourceConfig, err := d.f.loadSourceConfig()
+	if err != nil {
+		return ManifestValidator{}, err
+	}
+
+	// the CPI release is only validated, never installed
+	cpiInstaller := bicpirel.CpiInstaller{
+		ReleaseManager: d.f.loadReleaseManager(),
+		Validator:      bicpirel.NewValidator(),
+	}
+
+	return NewManifestValidator(
+		d.f.logger,
+		"ManifestValidator",
+		d.f.loadReleaseManager(),
+		d.deploymentManifestPath,
+		cpiInstaller,
+		releaseFetcher,
+		stemcellFetcher,
+		sourceConfig.MaxConcurrentDownloads,
+		d.loadReleaseSetAndInstallationManifestParser(),
+		d.loadDeploymentManifestParser(),
+		NewTempRootConfigurator(d.f.fs),
+		filepath.Join(d.f.workspaceRootPath, "tmp", "validate"),
+	), nil
+}
+
 func (d *deploymentManagerFactory2) loadDeploymentDeleter() (DeploymentDeleter, error) {
 	cpiInstaller, err := d.loadCpiInstaller()
 	if err != nil {
 		return nil, err
 	}
+	releaseFetcher, err := d.loadReleaseFetcher()
+	if err != nil {
+		return nil, err
+	}
 	return NewDeploymentDeleter(
-		d.f.ui,
+		d.ui,
 		"DeploymentDeleter",
 		d.f.logger,
 		d.loadDeploymentStateService(),
@@ -462,13 +713,59 @@ func (d *deploymentManagerFactory2) loadDeploymentDeleter() (DeploymentDeleter,
 		d.deploymentManifestPath,
 		cpiInstaller,
 		d.loadCpiUninstaller(),
-		d.loadReleaseFetcher(),
+		releaseFetcher,
+		d.loadReleaseSetAndInstallationManifestParser(),
+		d.f.loadDeploymentParser(),
+		NewTempRootConfigurator(d.f.fs),
+		d.loadTargetProvider(),
+	), nil
+}
+
+func (d *deploymentManagerFactory2) loadInstanceLifecycle() (InstanceLifecycle, error) {
+	cpiInstaller, err := d.loadCpiInstaller()
+	if err != nil {
+		return nil, err
+	}
+	releaseFetcher, err := d.loadReleaseFetcher()
+	if err != nil {
+		return nil, err
+	}
+	return NewInstanceLifecycle(
+		d.ui,
+		d.f.fs,
+		d.f.timeService,
+		"InstanceLifecycle",
+		d.f.logger,
+		d.loadDeploymentStateService(),
+		d.f.loadReleaseManager(),
+		d.loadDeploymentRecord(),
+		d.loadVMRepo(),
+		d.loadDiskRepo(),
+		d.loadStemcellRepo(),
+		d.f.loadCloudFactory(),
+		d.f.loadAgentClientFactory(),
+		d.f.loadBlobstoreFactory(),
+		d.loadDeploymentManagerFactory(),
+		d.loadStemcellManagerFactory(),
+		d.loadVMManagerFactory(),
+		d.loadDeployer(),
+		d.deploymentManifestPath,
+		cpiInstaller,
+		releaseFetcher,
 		d.loadReleaseSetAndInstallationManifestParser(),
+		d.loadDeploymentManifestParser(),
 		NewTempRootConfigurator(d.f.fs),
 		d.loadTargetProvider(),
 	), nil
 }
 
+func (d *deploymentManagerFactory2) loadDeploymentRecord() bidepl.Record {
+	deploymentRepo := biconfig.NewDeploymentRepo(d.loadDeploymentStateService())
+	releaseRepo := biconfig.NewReleaseRepo(d.loadDeploymentStateService(), d.f.uuidGenerator)
+	sha1Calculator := bicrypto.NewSha1Calculator(d.f.fs)
+	return bidepl.NewRecord(deploymentRepo, releaseRepo, d.loadStemcellRepo(), sha1Calculator)
+}
+
 func (d *deploymentManagerFactory2) loadDeploymentStateService() biconfig.DeploymentStateService {
 	if d.deploymentStateService != nil {
 		return d.deploymentStateService
@@ -546,7 +843,7 @@ func (d *deploymentManagerFactory2) loadDeploymentManagerFactory() bidepl.Manage
 
 	d.deploymentManagerFactory = bidepl.NewManagerFactory(
 		d.loadVMManagerFactory(),
-		d.f.loadInstanceManagerFactory(),
+		d.loadInstanceManagerFactory(),
 		d.loadDiskManagerFactory(),
 		d.loadStemcellManagerFactory(),
 		d.f.loadDeploymentFactory(),
@@ -554,6 +851,28 @@ func (d *deploymentManagerFactory2) loadDeploymentManagerFactory() bidepl.Manage
 	return d.deploymentManagerFactory
 }
 
+func (d *deploymentManagerFactory2) loadSSHTunnelFactory() bisshtunnel.Factory {
+	if d.sshTunnelFactory != nil {
+		return d.sshTunnelFactory
+	}
+
+	d.sshTunnelFactory = bisshtunnel.NewFactory(biconfig.NewHostKeyRepo(d.loadDeploymentStateService()), d.f.logger)
+	return d.sshTunnelFactory
+}
+
+func (d *deploymentManagerFactory2) loadInstanceManagerFactory() biinstance.ManagerFactory {
+	if d.instanceManagerFactory != nil {
+		return d.instanceManagerFactory
+	}
+
+	d.instanceManagerFactory = biinstance.NewManagerFactory(
+		d.loadSSHTunnelFactory(),
+		d.f.loadInstanceFactory(),
+		d.f.logger,
+	)
+	return d.instanceManagerFactory
+}
+
 func (d *deploymentManagerFactory2) loadVMManagerFactory() bivm.ManagerFactory {
 	if d.vmManagerFactory != nil {
 		return d.vmManagerFactory
@@ -586,8 +905,9 @@ func (d *deploymentManagerFactory2) loadDeployer() bidepl.Deployer {
 
 	d.deployer = bidepl.NewDeployer(
 		d.loadVMManagerFactory(),
-		d.f.loadInstanceManagerFactory(),
+		d.loadInstanceManagerFactory(),
 		d.f.loadDeploymentFactory(),
+		bideplmanifest.NewIPAM(biconfig.NewIPAllocationRepo(d.loadDeploymentStateService()), d.f.logger),
 		d.f.logger,
 	)
 	return d.deployer
@@ -599,7 +919,7 @@ func (d *deploymentManagerFactory2) loadInstallerFactory() biinstall.InstallerFa
 	}
 
 	d.installerFactory = biinstall.NewInstallerFactory(
-		d.f.ui,
+		d.ui,
 		d.f.loadCMDRunner(),
 		d.f.loadCompressor(),
 		d.f.loadReleaseJobResolver(),
@@ -631,22 +951,186 @@ func (d *deploymentManagerFactory2) loadCpiInstaller() (bicpirel.CpiInstaller, e
 	}, nil
 }
 
-func (d *deploymentManagerFactory2) loadReleaseFetcher() birel.Fetcher {
+func (d *deploymentManagerFactory2) loadReleaseFetcher() (birel.Fetcher, error) {
+	tarballProvider, err := d.loadTarballProvider()
+	if err != nil {
+		return birel.Fetcher{}, err
+	}
+
+	catalogResolver, err := d.loadCatalogResolver()
+	if err != nil {
+		return birel.Fetcher{}, err
+	}
+
+	signatureVerifier, err := d.loadSignatureVerifier()
+	if err != nil {
+		return birel.Fetcher{}, err
+	}
+
+	devReleaseBuilder, err := d.loadDevReleaseBuilder()
+	if err != nil {
+		return birel.Fetcher{}, err
+	}
+
 	return birel.NewFetcher(
-		d.f.loadTarballProvider(),
+		tarballProvider,
 		d.f.loadReleaseExtractor(),
 		d.f.loadReleaseManager(),
-	)
+		devReleaseBuilder,
+		catalogResolver,
+		signatureVerifier,
+		d.f.fs,
+	), nil
+}
+
+func (d *deploymentManagerFactory2) loadDevReleaseBuilder() (birel.DevReleaseBuilder, error) {
+	tarballCache, err := d.f.loadTarballCache()
+	if err != nil {
+		return nil, err
+	}
+
+	return birel.NewDevReleaseBuilder(
+		tarballCache,
+		filepath.Join(d.f.workspaceRootPath, "dev_releases"),
+		d.f.fs,
+		d.f.loadCMDRunner(),
+		d.f.loadCompressor(),
+		bicrypto.NewSha1Calculator(d.f.fs),
+		d.f.logger,
+	), nil
 }
 
-func (d *deploymentManagerFactory2) loadStemcellFetcher() bistemcell.Fetcher {
-	stemcellReader := bistemcell.NewReader(d.f.loadCompressor(), d.f.fs)
-	stemcellExtractor := bistemcell.NewExtractor(stemcellReader, d.f.fs)
+func (d *deploymentManagerFactory2) loadStemcellFetcher() (bistemcell.Fetcher, error) {
+	tarballProvider, err := d.loadTarballProvider()
+	if err != nil {
+		return bistemcell.Fetcher{}, err
+	}
+
+	catalogResolver, err := d.loadCatalogResolver()
+	if err != nil {
+		return bistemcell.Fetcher{}, err
+	}
+
+	signatureVerifier, err := d.loadSignatureVerifier()
+	if err != nil {
+		return bistemcell.Fetcher{}, err
+	}
 
 	return bistemcell.Fetcher{
-		TarballProvider:   d.f.loadTarballProvider(),
-		StemcellExtractor: stemcellExtractor,
+		TarballProvider:   tarballProvider,
+		StemcellExtractor: d.f.loadStemcellExtractor(),
+		CatalogResolver:   catalogResolver,
+		SignatureVerifier: signatureVerifier,
+	}, nil
+}
+
+// loadSignatureVerifier verifies release and stemcell signatures against the keys in BOSH_INIT_TRUSTED_KEYS
+func (d *deploymentManagerFactory2) loadSignatureVerifier() (bisignature.Verifier, error) {
+	if d.signatureVerifier != nil {
+		return d.signatureVerifier, nil
+	}
+
+	sourceConfig, err := d.f.loadSourceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	keyring := bisignature.Keyring{}
+	if sourceConfig.TrustedKeysPath != "" {
+		keyring, err = bisignature.LoadKeyring(sourceConfig.TrustedKeysPath, d.f.fs)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Loading BOSH_INIT_TRUSTED_KEYS")
+		}
+	}
+
+	schemeRegistry, err := d.f.loadSchemeRegistry()
+	if err != nil {
+		return nil, err
 	}
+
+	d.signatureVerifier = bisignature.NewVerifier(keyring, schemeRegistry, d.f.fs, d.f.logger)
+	return d.signatureVerifier, nil
+}
+
+// loadTarballProvider keeps the tarballs of the deployment, as recorded in its deployment state, in the download cache
+// while any command of the deployment downloads tarballs
+func (d *deploymentManagerFactory2) loadTarballProvider() (bitarball.Provider, error) {
+	if d.tarballProvider != nil {
+		return d.tarballProvider, nil
+	}
+
+	tarballCache, err := d.f.loadTarballCache()
+	if err != nil {
+		return nil, err
+	}
+
+	tarballRepo := biconfig.NewTarballRepo(d.loadDeploymentStateService())
+	resolvedRefRepo := biconfig.NewResolvedRefRepo(d.loadDeploymentStateService())
+	tarballCache.AddRetainer(func() ([]bitarball.Source, error) {
+		sources := []bitarball.Source{}
+		if !d.loadDeploymentStateService().Exists() {
+			return sources, nil
+		}
+
+		tarballRecords, err := tarballRepo.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range tarballRecords {
+			sources = append(sources, bitarball.NewSource(record.URL, record.SHA1, record.Name))
+		}
+
+		// deployment states saved before the deployed tarballs were recorded only list those resolved from the index
+		resolvedRefRecords, err := resolvedRefRepo.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range resolvedRefRecords {
+			sources = append(sources, bitarball.NewSource(record.URL, record.SHA1, record.Name))
+		}
+
+		return sources, nil
+	})
+
+	d.tarballProvider, err = d.f.loadTarballProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.tarballProvider, nil
+}
+
+func (d *deploymentManagerFactory2) loadCatalogResolver() (bicatalog.Resolver, error) {
+	if d.catalogResolver != nil {
+		return d.catalogResolver, nil
+	}
+
+	d.catalogResolver = bicatalog.NewResolver(d.loadCatalog, d.f.logger)
+	return d.catalogResolver, nil
+}
+
+// loadCatalog loads the index configured in the deployment manifest, or else in BOSH_INIT_INDEX
+func (d *deploymentManagerFactory2) loadCatalog() (bicatalog.Catalog, error) {
+	releaseSetManifest, err := d.f.loadReleaseSetParser().Parse(d.deploymentManifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	indexLocation := releaseSetManifest.Index
+	if indexLocation == "" {
+		indexLocation = d.f.indexLocation
+	}
+
+	if indexLocation == "" {
+		return nil, nil
+	}
+
+	httpClient, err := d.f.loadHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return bicatalog.NewCatalog(indexLocation, d.f.fs, bihttpclient.NewHTTPClient(httpClient, d.f.logger), d.f.logger), nil
 }
 
 func (d *deploymentManagerFactory2) loadReleaseSetAndInstallationManifestParser() ReleaseSetAndInstallationManifestParser {
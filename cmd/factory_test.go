@@ -37,6 +37,7 @@ var _ = Describe("cmd.Factory", func() {
 			logger,
 			uuidGenerator,
 			"/fake-path",
+			"",
 		)
 	})
 
@@ -60,6 +61,72 @@ var _ = Describe("cmd.Factory", func() {
 				Expect(cmd.Name()).To(Equal("delete"))
 			})
 		})
+
+		Describe("instance lifecycle commands", func() {
+			It("returns the stop, start, restart and recreate commands", func() {
+				for _, name := range []string{"stop", "start", "restart", "recreate"} {
+					cmd, err := factory.CreateCommand(name)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cmd.Name()).To(Equal(name))
+				}
+			})
+		})
+
+		Describe("instances command", func() {
+			It("returns instances command", func() {
+				cmd, err := factory.CreateCommand("instances")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("instances"))
+			})
+		})
+
+		Describe("cck command", func() {
+			It("returns cck command", func() {
+				cmd, err := factory.CreateCommand("cck")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("cck"))
+			})
+		})
+
+		Describe("run-errand command", func() {
+			It("returns run-errand command", func() {
+				cmd, err := factory.CreateCommand("run-errand")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("run-errand"))
+			})
+		})
+
+		Describe("cache command", func() {
+			It("returns cache command", func() {
+				cmd, err := factory.CreateCommand("cache")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("cache"))
+			})
+		})
+
+		Describe("inspect-release command", func() {
+			It("returns inspect-release command", func() {
+				cmd, err := factory.CreateCommand("inspect-release")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("inspect-release"))
+			})
+		})
+
+		Describe("inspect-stemcell command", func() {
+			It("returns inspect-stemcell command", func() {
+				cmd, err := factory.CreateCommand("inspect-stemcell")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("inspect-stemcell"))
+			})
+		})
+
+		Describe("validate command", func() {
+			It("returns validate command", func() {
+				cmd, err := factory.CreateCommand("validate")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmd.Name()).To(Equal("validate"))
+			})
+		})
 	})
 
 	Context("unknown command name", func() {
@@ -0,0 +1,563 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelpkg "github.com/cloudfoundry/bosh-init/release/pkg"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+// ReleaseInspection is the metadata of a release tarball printed by inspect-release
+type ReleaseInspection struct {
+	Name     string              `json:"name"`
+	Version  string              `json:"version"`
+	Compiled bool                `json:"compiled"`
+	Jobs     []JobInspection     `json:"jobs"`
+	Packages []PackageInspection `json:"packages"`
+}
+
+type JobInspection struct {
+	Name        string               `json:"name"`
+	Fingerprint string               `json:"fingerprint"`
+	SHA1        string               `json:"sha1"`
+	Packages    []string             `json:"packages"`
+	Properties  []PropertyInspection `json:"properties"`
+}
+
+type PropertyInspection struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+type PackageInspection struct {
+	Name         string   `json:"name"`
+	Fingerprint  string   `json:"fingerprint"`
+	SHA1         string   `json:"sha1"`
+	Stemcell     string   `json:"stemcell,omitempty"`
+	Dependencies []string `json:"dependencies"`
+}
+
+// StemcellInspection is the metadata of a stemcell tarball printed by inspect-stemcell
+type StemcellInspection struct {
+	Name            string                 `json:"name"`
+	Version         string                 `json:"version"`
+	OS              string                 `json:"operating_system"`
+	Infrastructure  string                 `json:"infrastructure,omitempty"`
+	SHA1            string                 `json:"sha1"`
+	CloudProperties map[string]interface{} `json:"cloud_properties"`
+}
+
+type inspectCmdFlags struct {
+	jsonOutput bool
+	sha1       string
+	source     string
+}
+
+type inspectReleaseCmd struct {
+	ui                      biui.UI
+	fs                      boshsys.FileSystem
+	timeService             clock.Clock
+	logger                  boshlog.Logger
+	tempRootConfigurator    TempRootConfigurator
+	tempRootPath            string
+	tarballProviderProvider func() (bitarball.Provider, error)
+	releaseExtractor        birel.Extractor
+	logTag                  string
+}
+
+func NewInspectReleaseCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+	tempRootConfigurator TempRootConfigurator,
+	tempRootPath string,
+	tarballProviderProvider func() (bitarball.Provider, error),
+	releaseExtractor birel.Extractor,
+) Cmd {
+	return &inspectReleaseCmd{
+		ui:                      ui,
+		fs:                      fs,
+		timeService:             timeService,
+		logger:                  logger,
+		tempRootConfigurator:    tempRootConfigurator,
+		tempRootPath:            tempRootPath,
+		tarballProviderProvider: tarballProviderProvider,
+		releaseExtractor:        releaseExtractor,
+		logTag:                  "inspectReleaseCmd",
+	}
+}
+
+func (c *inspectReleaseCmd) Name() string {
+	return "inspect-release"
+}
+
+func (c *inspectReleaseCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Show the jobs, packages and job properties of a release tarball",
+		Usage:    "[--json] [--sha1 <digest>] <release_tarball_path_or_url>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *inspectReleaseCmd) Run(stage biui.Stage, args []string) error {
+	flags, err := parseInspectCmdFlags(c.Name(), args)
+	if err != nil {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return err
+	}
+
+	ui := c.ui
+	if flags.jsonOutput {
+		ui = biui.NewQuietUI(c.ui)
+		stage = biui.NewStage(ui, c.timeService, c.logger)
+	}
+
+	err = c.tempRootConfigurator.PrepareAndSetTempRoot(c.tempRootPath, c.logger)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting temp root")
+	}
+
+	tarballPath, err := fetchInspectedTarball(flags, "release", c.fs, c.tarballProviderProvider, stage)
+	if err != nil {
+		return err
+	}
+
+	var release birel.Release
+	err = stage.Perform("Extracting release", func() error {
+		release, err = c.releaseExtractor.Extract(tarballPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Extracting release '%s'", tarballPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := release.Delete()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Failed to delete extracted release: %s", err.Error())
+		}
+	}()
+
+	inspection := inspectRelease(release)
+
+	if flags.jsonOutput {
+		return printInspectionJSON(c.ui, inspection)
+	}
+	return c.printTables(inspection)
+}
+
+func inspectRelease(release birel.Release) ReleaseInspection {
+	inspection := ReleaseInspection{
+		Name:     release.Name(),
+		Version:  release.Version(),
+		Compiled: release.IsCompiled(),
+		Jobs:     []JobInspection{},
+		Packages: []PackageInspection{},
+	}
+
+	for _, job := range release.Jobs() {
+		jobInspection := JobInspection{
+			Name:        job.Name,
+			Fingerprint: job.Fingerprint,
+			SHA1:        job.SHA1,
+			Packages:    append([]string{}, job.PackageNames...),
+			Properties:  []PropertyInspection{},
+		}
+
+		propertyNames := []string{}
+		for name := range job.Properties {
+			propertyNames = append(propertyNames, name)
+		}
+		sort.Strings(propertyNames)
+
+		for _, name := range propertyNames {
+			definition := job.Properties[name]
+			jobInspection.Properties = append(jobInspection.Properties, PropertyInspection{
+				Name:        name,
+				Description: definition.Description,
+				Default:     definition.Default,
+			})
+		}
+
+		inspection.Jobs = append(inspection.Jobs, jobInspection)
+	}
+
+	for _, pkg := range release.Packages() {
+		inspection.Packages = append(inspection.Packages, PackageInspection{
+			Name:         pkg.Name,
+			Fingerprint:  pkg.Fingerprint,
+			SHA1:         pkg.SHA1,
+			Stemcell:     pkg.Stemcell,
+			Dependencies: packageNames(pkg.Dependencies),
+		})
+	}
+
+	return inspection
+}
+
+func (c *inspectReleaseCmd) printTables(inspection ReleaseInspection) error {
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("Release: %s/%s", inspection.Name, inspection.Version)
+	if inspection.Compiled {
+		c.ui.PrintLinef("Compiled: yes")
+	} else {
+		c.ui.PrintLinef("Compiled: no")
+	}
+
+	c.ui.PrintLinef("")
+	err := printTable(c.ui, "Job\tFingerprint\tSHA1\tPackages", func(writer *tabwriter.Writer) {
+		for _, job := range inspection.Jobs {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", job.Name, job.Fingerprint, job.SHA1, valueOrNone(strings.Join(job.Packages, ", ")))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("")
+	err = printTable(c.ui, "Package\tFingerprint\tSHA1\tCompiled For\tDependencies", func(writer *tabwriter.Writer) {
+		for _, pkg := range inspection.Packages {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", pkg.Name, pkg.Fingerprint, pkg.SHA1, valueOrNone(pkg.Stemcell), valueOrNone(strings.Join(pkg.Dependencies, ", ")))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("Package dependency graph")
+	for _, line := range dependencyGraph(inspection.Packages) {
+		c.ui.PrintLinef("  %s", line)
+	}
+
+	for _, job := range inspection.Jobs {
+		c.ui.PrintLinef("")
+		if len(job.Properties) == 0 {
+			c.ui.PrintLinef("Job '%s' has no properties", job.Name)
+			continue
+		}
+
+		c.ui.PrintLinef("Job '%s' properties", job.Name)
+		err = printTable(c.ui, "Property\tDefault\tDescription", func(writer *tabwriter.Writer) {
+			for _, property := range job.Properties {
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", property.Name, formatDefault(property.Default), valueOrNone(property.Description))
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dependencyGraph indents each package under the packages that depend on it,
+// starting from the packages no other package depends on
+func dependencyGraph(packages []PackageInspection) []string {
+	packagesByName := map[string]PackageInspection{}
+	dependedOn := map[string]bool{}
+	for _, pkg := range packages {
+		packagesByName[pkg.Name] = pkg
+		for _, dependency := range pkg.Dependencies {
+			dependedOn[dependency] = true
+		}
+	}
+
+	lines := []string{}
+	var walk func(name string, depth int, path map[string]bool)
+	walk = func(name string, depth int, path map[string]bool) {
+		if path[name] {
+			lines = append(lines, fmt.Sprintf("%s%s (cycle)", strings.Repeat("  ", depth), name))
+			return
+		}
+		lines = append(lines, strings.Repeat("  ", depth)+name)
+
+		path[name] = true
+		for _, dependency := range packagesByName[name].Dependencies {
+			walk(dependency, depth+1, path)
+		}
+		delete(path, name)
+	}
+
+	for _, pkg := range packages {
+		if !dependedOn[pkg.Name] {
+			walk(pkg.Name, 0, map[string]bool{})
+		}
+	}
+
+	return lines
+}
+
+func formatDefault(value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
+
+func packageNames(packages []*birelpkg.Package) []string {
+	names := []string{}
+	for _, pkg := range packages {
+		names = append(names, pkg.Name)
+	}
+	return names
+}
+
+type inspectStemcellCmd struct {
+	ui                      biui.UI
+	fs                      boshsys.FileSystem
+	timeService             clock.Clock
+	logger                  boshlog.Logger
+	tempRootConfigurator    TempRootConfigurator
+	tempRootPath            string
+	tarballProviderProvider func() (bitarball.Provider, error)
+	stemcellExtractor       bistemcell.Extractor
+	logTag                  string
+}
+
+func NewInspectStemcellCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+	tempRootConfigurator TempRootConfigurator,
+	tempRootPath string,
+	tarballProviderProvider func() (bitarball.Provider, error),
+	stemcellExtractor bistemcell.Extractor,
+) Cmd {
+	return &inspectStemcellCmd{
+		ui:                      ui,
+		fs:                      fs,
+		timeService:             timeService,
+		logger:                  logger,
+		tempRootConfigurator:    tempRootConfigurator,
+		tempRootPath:            tempRootPath,
+		tarballProviderProvider: tarballProviderProvider,
+		stemcellExtractor:       stemcellExtractor,
+		logTag:                  "inspectStemcellCmd",
+	}
+}
+
+func (c *inspectStemcellCmd) Name() string {
+	return "inspect-stemcell"
+}
+
+func (c *inspectStemcellCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Show the OS, version, infrastructure and cloud properties of a stemcell tarball",
+		Usage:    "[--json] [--sha1 <digest>] <stemcell_tarball_path_or_url>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *inspectStemcellCmd) Run(stage biui.Stage, args []string) error {
+	flags, err := parseInspectCmdFlags(c.Name(), args)
+	if err != nil {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return err
+	}
+
+	ui := c.ui
+	if flags.jsonOutput {
+		ui = biui.NewQuietUI(c.ui)
+		stage = biui.NewStage(ui, c.timeService, c.logger)
+	}
+
+	err = c.tempRootConfigurator.PrepareAndSetTempRoot(c.tempRootPath, c.logger)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting temp root")
+	}
+
+	tarballPath, err := fetchInspectedTarball(flags, "stemcell", c.fs, c.tarballProviderProvider, stage)
+	if err != nil {
+		return err
+	}
+
+	var stemcell bistemcell.ExtractedStemcell
+	err = stage.Perform("Extracting stemcell", func() error {
+		stemcell, err = c.stemcellExtractor.Extract(tarballPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Extracting stemcell '%s'", tarballPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := stemcell.Delete()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Failed to delete extracted stemcell: %s", err.Error())
+		}
+	}()
+
+	manifest := stemcell.Manifest()
+	inspection := StemcellInspection{
+		Name:            manifest.Name,
+		Version:         manifest.Version,
+		OS:              manifest.OS,
+		SHA1:            manifest.SHA1,
+		CloudProperties: map[string]interface{}{},
+	}
+	for name, value := range manifest.CloudProperties {
+		inspection.CloudProperties[name] = value
+	}
+	if infrastructure, ok := manifest.CloudProperties["infrastructure"].(string); ok {
+		inspection.Infrastructure = infrastructure
+	}
+
+	if flags.jsonOutput {
+		return printInspectionJSON(c.ui, inspection)
+	}
+	return c.printTables(inspection)
+}
+
+func (c *inspectStemcellCmd) printTables(inspection StemcellInspection) error {
+	c.ui.PrintLinef("")
+	err := printTable(c.ui, "", func(writer *tabwriter.Writer) {
+		fmt.Fprintf(writer, "Name\t%s\n", inspection.Name)
+		fmt.Fprintf(writer, "Version\t%s\n", inspection.Version)
+		fmt.Fprintf(writer, "OS\t%s\n", valueOrNone(inspection.OS))
+		fmt.Fprintf(writer, "Infrastructure\t%s\n", valueOrNone(inspection.Infrastructure))
+		fmt.Fprintf(writer, "Image SHA1\t%s\n", valueOrNone(inspection.SHA1))
+	})
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range inspection.CloudProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.ui.PrintLinef("")
+	return printTable(c.ui, "Cloud Property\tValue", func(writer *tabwriter.Writer) {
+		for _, name := range names {
+			fmt.Fprintf(writer, "%s\t%s\n", name, formatDefault(inspection.CloudProperties[name]))
+		}
+	})
+}
+
+func parseInspectCmdFlags(cmdName string, args []string) (inspectCmdFlags, error) {
+	flags := inspectCmdFlags{}
+	positionalArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--json":
+			flags.jsonOutput = true
+		case "--sha1":
+			if i+1 == len(args) {
+				return inspectCmdFlags{}, bosherr.Errorf("Invalid usage - %s --sha1 requires a digest", cmdName)
+			}
+			i++
+			flags.sha1 = args[i]
+		default:
+			positionalArgs = append(positionalArgs, args[i])
+		}
+	}
+
+	if len(positionalArgs) != 1 {
+		return inspectCmdFlags{}, bosherr.Errorf("Invalid usage - %s command requires exactly 1 argument", cmdName)
+	}
+	flags.source = positionalArgs[0]
+
+	return flags, nil
+}
+
+// fetchInspectedTarball returns the path of a local tarball, or downloads a tarball URL through the tarball provider
+func fetchInspectedTarball(
+	flags inspectCmdFlags,
+	description string,
+	fs boshsys.FileSystem,
+	tarballProviderProvider func() (bitarball.Provider, error),
+	stage biui.Stage,
+) (string, error) {
+	url := flags.source
+	if !strings.Contains(url, "://") {
+		expandedPath, err := fs.ExpandPath(url)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Expanding path '%s'", url)
+		}
+
+		absPath, err := filepath.Abs(expandedPath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Getting absolute path of '%s'", expandedPath)
+		}
+
+		if !fs.FileExists(absPath) {
+			return "", bosherr.Errorf("The %s tarball '%s' does not exist", description, absPath)
+		}
+		url = "file://" + absPath
+	} else if !strings.HasPrefix(url, "file://") && flags.sha1 == "" {
+		return "", bosherr.Errorf("Invalid usage - downloading the %s from '%s' requires --sha1", description, url)
+	}
+
+	tarballProvider, err := tarballProviderProvider()
+	if err != nil {
+		return "", err
+	}
+
+	return tarballProvider.Get(bitarball.NewSource(url, flags.sha1, description), stage)
+}
+
+func printInspectionJSON(ui biui.UI, inspection interface{}) error {
+	bytes, err := json.MarshalIndent(inspection, "", "  ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling inspection")
+	}
+
+	ui.PrintLinef("%s", bytes)
+	return nil
+}
+
+// printTable prints the rows written by writeRows in aligned columns, under the tab separated header unless it is empty
+func printTable(ui biui.UI, header string, writeRows func(*tabwriter.Writer)) error {
+	buffer := &bytes.Buffer{}
+	writer := tabwriter.NewWriter(buffer, 0, 8, 2, ' ', 0)
+
+	if header != "" {
+		fmt.Fprintln(writer, header)
+	}
+	writeRows(writer)
+
+	err := writer.Flush()
+	if err != nil {
+		return bosherr.WrapError(err, "Writing table")
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n") {
+		ui.PrintLinef("%s", strings.TrimRight(line, " "))
+	}
+
+	return nil
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
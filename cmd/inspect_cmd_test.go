@@ -0,0 +1,264 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	mock_tarball "github.com/cloudfoundry/bosh-init/installation/tarball/mocks"
+	fakebirel "github.com/cloudfoundry/bosh-init/release/fakes"
+	bireljob "github.com/cloudfoundry/bosh-init/release/job"
+	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
+	birelpkg "github.com/cloudfoundry/bosh-init/release/pkg"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	fakebistemcell "github.com/cloudfoundry/bosh-init/stemcell/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("InspectCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		fs                  *fakesys.FakeFileSystem
+		logger              boshlog.Logger
+		fakeUI              *fakebiui.FakeUI
+		fakeStage           *fakebiui.FakeStage
+		mockTarballProvider *mock_tarball.MockProvider
+		tarballProvider     func() (bitarball.Provider, error)
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		mockTarballProvider = mock_tarball.NewMockProvider(mockCtrl)
+		tarballProvider = func() (bitarball.Provider, error) { return mockTarballProvider, nil }
+	})
+
+	Describe("inspect-release", func() {
+		var (
+			mockReleaseExtractor *mock_release.MockExtractor
+			release              *fakebirel.FakeRelease
+			command              bicmd.Cmd
+		)
+
+		BeforeEach(func() {
+			mockReleaseExtractor = mock_release.NewMockExtractor(mockCtrl)
+			command = bicmd.NewInspectReleaseCmd(fakeUI, fs, fakeclock.NewFakeClock(time.Now()), logger, bicmd.NewTempRootConfigurator(fs), "/fake-workspace/tmp/inspect", tarballProvider, mockReleaseExtractor)
+
+			pkgC := &birelpkg.Package{Name: "pkg-c", Fingerprint: "fake-fingerprint-c", SHA1: "fake-sha1-c"}
+			pkgB := &birelpkg.Package{Name: "pkg-b", Fingerprint: "fake-fingerprint-b", SHA1: "fake-sha1-b", Dependencies: []*birelpkg.Package{pkgC}}
+			pkgA := &birelpkg.Package{Name: "pkg-a", Fingerprint: "fake-fingerprint-a", SHA1: "fake-sha1-a", Dependencies: []*birelpkg.Package{pkgB, pkgC}}
+
+			release = fakebirel.New("fake-release", "1.2")
+			release.ReleasePackages = []*birelpkg.Package{pkgA, pkgB, pkgC}
+			release.ReleaseJobs = []bireljob.Job{
+				{
+					Name:         "fake-job",
+					Fingerprint:  "fake-job-fingerprint",
+					SHA1:         "fake-job-sha1",
+					PackageNames: []string{"pkg-a"},
+					Properties: map[string]bireljob.PropertyDefinition{
+						"fake.port": {Description: "Port to listen on", Default: 8080},
+						"fake.tags": {Default: biproperty.Map{"env": "test"}},
+						"fake.name": {Description: "Name of the fake"},
+					},
+				},
+				{Name: "fake-empty-job", Fingerprint: "fake-empty-fingerprint", SHA1: "fake-empty-sha1"},
+			}
+
+			fs.WriteFileString("/fake-release.tgz", "fake-tarball")
+		})
+
+		expectLocalExtract := func() {
+			mockTarballProvider.EXPECT().Get(bitarball.NewSource("file:///fake-release.tgz", "", "release"), gomock.Any()).Return("/fake-release.tgz", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(release, nil)
+		}
+
+		It("prints the jobs, packages, dependency graph and job properties of the release", func() {
+			expectLocalExtract()
+
+			err := command.Run(fakeStage, []string{"/fake-release.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+
+			output := strings.Join(fakeUI.Said, "\n")
+			Expect(output).To(ContainSubstring("Release: fake-release/1.2\nCompiled: no"))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^fake-job\s+fake-job-fingerprint\s+fake-job-sha1\s+pkg-a$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^fake-empty-job\s+fake-empty-fingerprint\s+fake-empty-sha1\s+-$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^pkg-a\s+fake-fingerprint-a\s+fake-sha1-a\s+-\s+pkg-b, pkg-c$`)))
+			Expect(output).To(ContainSubstring(`Package dependency graph
+  pkg-a
+    pkg-b
+      pkg-c
+    pkg-c`))
+			Expect(output).To(ContainSubstring("Job 'fake-job' properties"))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^fake\.name\s+-\s+Name of the fake$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^fake\.port\s+8080\s+Port to listen on$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^fake\.tags\s+\{"env":"test"\}\s+-$`)))
+			Expect(output).To(ContainSubstring("Job 'fake-empty-job' has no properties"))
+
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Extracting release"))
+			Expect(release.DeleteCalled).To(BeTrue())
+		})
+
+		It("extracts the release under the temp root", func() {
+			expectLocalExtract()
+
+			err := command.Run(fakeStage, []string{"/fake-release.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.TempRootPath).To(Equal("/fake-workspace/tmp/inspect"))
+		})
+
+		It("returns an error when the temp root can not be set", func() {
+			fs.ChangeTempRootErr = errors.New("fake-change-temp-root-error")
+
+			err := command.Run(fakeStage, []string{"/fake-release.tgz"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Setting temp root: fake-change-temp-root-error"))
+		})
+
+		It("prints the release as JSON", func() {
+			expectLocalExtract()
+
+			err := command.Run(fakeStage, []string{"--json", "/fake-release.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeUI.Said).To(HaveLen(1))
+			var inspection bicmd.ReleaseInspection
+			Expect(json.Unmarshal([]byte(fakeUI.Said[0]), &inspection)).To(Succeed())
+			Expect(inspection.Name).To(Equal("fake-release"))
+			Expect(inspection.Packages[0]).To(Equal(bicmd.PackageInspection{
+				Name:         "pkg-a",
+				Fingerprint:  "fake-fingerprint-a",
+				SHA1:         "fake-sha1-a",
+				Dependencies: []string{"pkg-b", "pkg-c"},
+			}))
+			Expect(inspection.Jobs[0].Properties[1]).To(Equal(bicmd.PropertyInspection{
+				Name:        "fake.port",
+				Description: "Port to listen on",
+				Default:     float64(8080),
+			}))
+		})
+
+		It("downloads the release from a URL with its digest", func() {
+			mockTarballProvider.EXPECT().Get(bitarball.NewSource("https://fake-url", "fake-sha1", "release"), fakeStage).Return("/fake-download", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-download").Return(release, nil)
+
+			err := command.Run(fakeStage, []string{"https://fake-url", "--sha1", "fake-sha1"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when a URL has no digest", func() {
+			err := command.Run(fakeStage, []string{"https://fake-url"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage - downloading the release from 'https://fake-url' requires --sha1"))
+		})
+
+		It("returns an error when the tarball does not exist", func() {
+			err := command.Run(fakeStage, []string{"/fake-missing.tgz"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("The release tarball '/fake-missing.tgz' does not exist"))
+		})
+
+		It("returns an error when the release can not be extracted", func() {
+			mockTarballProvider.EXPECT().Get(gomock.Any(), gomock.Any()).Return("/fake-release.tgz", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(nil, errors.New("fake-extract-error"))
+
+			err := command.Run(fakeStage, []string{"/fake-release.tgz"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-extract-error"))
+		})
+
+		It("returns an error without exactly one tarball", func() {
+			err := command.Run(fakeStage, []string{"--json"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid usage - inspect-release command requires exactly 1 argument"))
+		})
+	})
+
+	Describe("inspect-stemcell", func() {
+		var (
+			fakeStemcellExtractor *fakebistemcell.FakeExtractor
+			command               bicmd.Cmd
+		)
+
+		BeforeEach(func() {
+			fakeStemcellExtractor = fakebistemcell.NewFakeExtractor()
+			command = bicmd.NewInspectStemcellCmd(fakeUI, fs, fakeclock.NewFakeClock(time.Now()), logger, bicmd.NewTempRootConfigurator(fs), "/fake-workspace/tmp/inspect", tarballProvider, fakeStemcellExtractor)
+
+			fs.WriteFileString("/fake-stemcell.tgz", "fake-tarball")
+			fs.MkdirAll("/fake-extracted-stemcell", 0755)
+			stemcell := bistemcell.NewExtractedStemcell(bistemcell.Manifest{
+				Name:    "fake-stemcell",
+				Version: "3262.2",
+				OS:      "ubuntu-trusty",
+				SHA1:    "fake-image-sha1",
+				CloudProperties: biproperty.Map{
+					"infrastructure": "aws",
+					"disk":           3072,
+				},
+			}, "/fake-extracted-stemcell", fs)
+			fakeStemcellExtractor.SetExtractBehavior("/fake-stemcell.tgz", stemcell, nil)
+
+			mockTarballProvider.EXPECT().Get(bitarball.NewSource("file:///fake-stemcell.tgz", "", "stemcell"), gomock.Any()).Return("/fake-stemcell.tgz", nil)
+		})
+
+		It("prints the OS, version, infrastructure and cloud properties of the stemcell", func() {
+			err := command.Run(fakeStage, []string{"/fake-stemcell.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^Name\s+fake-stemcell$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^Version\s+3262.2$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^OS\s+ubuntu-trusty$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^Infrastructure\s+aws$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^Image SHA1\s+fake-image-sha1$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^disk\s+3072$`)))
+			Expect(fakeUI.Said).To(ContainElement(MatchRegexp(`^infrastructure\s+"aws"$`)))
+
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Extracting stemcell"))
+			Expect(fs.FileExists("/fake-extracted-stemcell")).To(BeFalse())
+			Expect(fs.TempRootPath).To(Equal("/fake-workspace/tmp/inspect"))
+		})
+
+		It("prints the stemcell as JSON", func() {
+			err := command.Run(fakeStage, []string{"--json", "/fake-stemcell.tgz"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeUI.Said).To(HaveLen(1))
+			var inspection bicmd.StemcellInspection
+			Expect(json.Unmarshal([]byte(fakeUI.Said[0]), &inspection)).To(Succeed())
+			Expect(inspection).To(Equal(bicmd.StemcellInspection{
+				Name:           "fake-stemcell",
+				Version:        "3262.2",
+				OS:             "ubuntu-trusty",
+				Infrastructure: "aws",
+				SHA1:           "fake-image-sha1",
+				CloudProperties: map[string]interface{}{
+					"infrastructure": "aws",
+					"disk":           float64(3072),
+				},
+			}))
+		})
+	})
+})
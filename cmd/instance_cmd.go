@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type instanceCmd struct {
+	name                      string
+	synopsis                  string
+	allowSkipDrain            bool
+	action                    func(InstanceLifecycle, biui.Stage, instanceCmdFlags) error
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error)
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	logTag                    string
+}
+
+type instanceCmdFlags struct {
+	hard      bool
+	skipDrain bool
+}
+
+func NewStopCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return newInstanceCmd("stop", "Stop jobs on the deployed instance, keeping the VM", false, func(instanceLifecycle InstanceLifecycle, stage biui.Stage, flags instanceCmdFlags) error {
+		return instanceLifecycle.Stop(stage, flags.hard)
+	}, ui, fs, logger, instanceLifecycleProvider)
+}
+
+func NewStartCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return newInstanceCmd("start", "Start jobs on the deployed instance", false, func(instanceLifecycle InstanceLifecycle, stage biui.Stage, flags instanceCmdFlags) error {
+		return instanceLifecycle.Start(stage, flags.hard)
+	}, ui, fs, logger, instanceLifecycleProvider)
+}
+
+func NewRestartCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return newInstanceCmd("restart", "Stop and start jobs on the deployed instance", false, func(instanceLifecycle InstanceLifecycle, stage biui.Stage, flags instanceCmdFlags) error {
+		return instanceLifecycle.Restart(stage, flags.hard)
+	}, ui, fs, logger, instanceLifecycleProvider)
+}
+
+func NewRecreateCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return newInstanceCmd("recreate", "Recreate the deployed VM from the current stemcell, keeping the persistent disk", true, func(instanceLifecycle InstanceLifecycle, stage biui.Stage, flags instanceCmdFlags) error {
+		return instanceLifecycle.Recreate(stage, flags.hard, flags.skipDrain)
+	}, ui, fs, logger, instanceLifecycleProvider)
+}
+
+func newInstanceCmd(
+	name string,
+	synopsis string,
+	allowSkipDrain bool,
+	action func(InstanceLifecycle, biui.Stage, instanceCmdFlags) error,
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return &instanceCmd{
+		name:                      name,
+		synopsis:                  synopsis,
+		allowSkipDrain:            allowSkipDrain,
+		action:                    action,
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		ui:                        ui,
+		fs:                        fs,
+		logger:                    logger,
+		logTag:                    name + "Cmd",
+	}
+}
+
+func (c *instanceCmd) Name() string {
+	return c.name
+}
+
+func (c *instanceCmd) Meta() Meta {
+	usage := "[--hard] <deployment_manifest_path>"
+	if c.allowSkipDrain {
+		usage = "[--hard] [--skip-drain] <deployment_manifest_path>"
+	}
+
+	return Meta{
+		Synopsis: c.synopsis,
+		Usage:    usage,
+		Env:      genericEnv,
+	}
+}
+
+func (c *instanceCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, flags, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := filepath.Abs(deploymentManifestPath)
+	if err != nil {
+		c.ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", deploymentManifestPath)
+		return bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", deploymentManifestPath)
+	}
+
+	if !c.fs.FileExists(manifestAbsFilePath) {
+		c.ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
+		return bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+	}
+
+	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	return c.action(instanceLifecycle, stage, flags)
+}
+
+func (c *instanceCmd) parseCmdInputs(args []string) (string, instanceCmdFlags, error) {
+	flags := instanceCmdFlags{}
+	positionalArgs := []string{}
+	for _, arg := range args {
+		switch {
+		case arg == "--hard":
+			flags.hard = true
+		case arg == "--skip-drain" && c.allowSkipDrain:
+			flags.skipDrain = true
+		default:
+			positionalArgs = append(positionalArgs, arg)
+		}
+	}
+
+	if len(positionalArgs) != 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return "", flags, bosherr.Errorf("Invalid usage - %s command requires exactly 1 argument", c.name)
+	}
+	return positionalArgs[0], flags, nil
+}
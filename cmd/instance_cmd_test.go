@@ -0,0 +1,132 @@
+package cmd_test
+
+import (
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+	fakeui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("InstanceCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+		fs                    *fakesys.FakeFileSystem
+		logger                boshlog.Logger
+
+		fakeUI                 *fakeui.FakeUI
+		fakeStage              *fakebiui.FakeStage
+		deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+	)
+
+	var provider = func(path string) (bicmd.InstanceLifecycle, error) {
+		Expect(path).To(Equal(deploymentManifestPath))
+		return mockInstanceLifecycle, nil
+	}
+
+	BeforeEach(func() {
+		mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+		fs = fakesys.NewFakeFileSystem()
+		fs.EnableStrictTempRootBehavior()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeUI = &fakeui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+	})
+
+	Describe("stop", func() {
+		It("stops the instance", func() {
+			mockInstanceLifecycle.EXPECT().Stop(fakeStage, false)
+			err := bicmd.NewStopCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeUI.Said).To(ContainElement("Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'"))
+		})
+
+		It("passes --hard", func() {
+			mockInstanceLifecycle.EXPECT().Stop(fakeStage, true)
+			err := bicmd.NewStopCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{"--hard", deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error from the instance lifecycle", func() {
+			stopErr := bosherr.Error("fake-stop-error")
+			mockInstanceLifecycle.EXPECT().Stop(fakeStage, false).Return(stopErr)
+			err := bicmd.NewStopCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(Equal(stopErr))
+		})
+	})
+
+	It("does not accept --skip-drain for stop", func() {
+		err := bicmd.NewStopCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{"--skip-drain", deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage - stop command requires exactly 1 argument"))
+	})
+
+	Describe("start", func() {
+		It("starts the instance", func() {
+			mockInstanceLifecycle.EXPECT().Start(fakeStage, true)
+			err := bicmd.NewStartCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{deploymentManifestPath, "--hard"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("restart", func() {
+		It("restarts the instance", func() {
+			mockInstanceLifecycle.EXPECT().Restart(fakeStage, false)
+			err := bicmd.NewRestartCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("recreate", func() {
+		It("recreates the instance", func() {
+			mockInstanceLifecycle.EXPECT().Recreate(fakeStage, true, false)
+			err := bicmd.NewRecreateCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{"--hard", deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("passes --skip-drain", func() {
+			mockInstanceLifecycle.EXPECT().Recreate(fakeStage, false, true)
+			err := bicmd.NewRecreateCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{"--skip-drain", deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when the deployment manifest does not exist", func() {
+		It("returns an error", func() {
+			err := bicmd.NewStopCmd(fakeUI, fs, logger, provider).Run(fakeStage, []string{"/garbage"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Deployment manifest does not exist at '/garbage'"))
+			Expect(fakeUI.Errors).To(ContainElement("Deployment '/garbage' does not exist"))
+		})
+	})
+
+	It("returns err unless exactly 1 argument is given", func() {
+		command := bicmd.NewRecreateCmd(fakeUI, fs, logger, provider)
+
+		err := command.Run(fakeStage, []string{"--hard"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage - recreate command requires exactly 1 argument"))
+
+		err = command.Run(fakeStage, []string{"1", "2"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage"))
+	})
+})
@@ -0,0 +1,497 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
+	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	bicloud "github.com/cloudfoundry/bosh-init/cloud"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
+	bidepl "github.com/cloudfoundry/bosh-init/deployment"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	biinstall "github.com/cloudfoundry/bosh-init/installation"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+// InstanceLifecycle inspects and operates on the instance of the deployment recorded in the deployment state.
+// When hard is true, persistent disks are detached on stop and re-attached on start.
+// When skipDrain is true, the job drain scripts are not run before the jobs are stopped.
+type InstanceLifecycle interface {
+	Status(stage biui.Stage) (InstanceStatus, error)
+	Stop(stage biui.Stage, hard bool) error
+	Start(stage biui.Stage, hard bool) error
+	Restart(stage biui.Stage, hard bool) error
+	Recreate(stage biui.Stage, hard bool, skipDrain bool) error
+	CloudCheck(stage biui.Stage, resolve CloudProblemResolver) error
+	RunErrand(stage biui.Stage, errandName string, logsDir string) (biinstance.ErrandResult, error)
+}
+
+func NewInstanceLifecycle(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logTag string,
+	logger boshlog.Logger,
+	deploymentStateService biconfig.DeploymentStateService,
+	releaseManager birel.Manager,
+	deploymentRecord bidepl.Record,
+	vmRepo biconfig.VMRepo,
+	diskRepo biconfig.DiskRepo,
+	stemcellRepo biconfig.StemcellRepo,
+	cloudFactory bicloud.Factory,
+	agentClientFactory biagentclient.AgentClientFactory,
+	blobstoreFactory biblobstore.Factory,
+	deploymentManagerFactory bidepl.ManagerFactory,
+	stemcellManagerFactory bistemcell.ManagerFactory,
+	vmManagerFactory bivm.ManagerFactory,
+	deployer bidepl.Deployer,
+	deploymentManifestPath string,
+	cpiInstaller bicpirel.CpiInstaller,
+	releaseFetcher birel.Fetcher,
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	deploymentManifestParser DeploymentManifestParser,
+	tempRootConfigurator TempRootConfigurator,
+	targetProvider biinstall.TargetProvider,
+) InstanceLifecycle {
+	return &instanceLifecycle{
+		ui:                                      ui,
+		fs:                                      fs,
+		timeService:                             timeService,
+		logTag:                                  logTag,
+		logger:                                  logger,
+		deploymentStateService:                  deploymentStateService,
+		releaseManager:                          releaseManager,
+		deploymentRecord:                        deploymentRecord,
+		vmRepo:                                  vmRepo,
+		diskRepo:                                diskRepo,
+		stemcellRepo:                            stemcellRepo,
+		cloudFactory:                            cloudFactory,
+		agentClientFactory:                      agentClientFactory,
+		blobstoreFactory:                        blobstoreFactory,
+		deploymentManagerFactory:                deploymentManagerFactory,
+		stemcellManagerFactory:                  stemcellManagerFactory,
+		vmManagerFactory:                        vmManagerFactory,
+		deployer:                                deployer,
+		deploymentManifestPath:                  deploymentManifestPath,
+		cpiInstaller:                            cpiInstaller,
+		releaseFetcher:                          releaseFetcher,
+		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		deploymentManifestParser:                deploymentManifestParser,
+		tempRootConfigurator:                    tempRootConfigurator,
+		targetProvider:                          targetProvider,
+	}
+}
+
+type instanceLifecycle struct {
+	ui                                      biui.UI
+	fs                                      boshsys.FileSystem
+	timeService                             clock.Clock
+	logTag                                  string
+	logger                                  boshlog.Logger
+	deploymentStateService                  biconfig.DeploymentStateService
+	releaseManager                          birel.Manager
+	deploymentRecord                        bidepl.Record
+	vmRepo                                  biconfig.VMRepo
+	diskRepo                                biconfig.DiskRepo
+	stemcellRepo                            biconfig.StemcellRepo
+	cloudFactory                            bicloud.Factory
+	agentClientFactory                      biagentclient.AgentClientFactory
+	blobstoreFactory                        biblobstore.Factory
+	deploymentManagerFactory                bidepl.ManagerFactory
+	stemcellManagerFactory                  bistemcell.ManagerFactory
+	vmManagerFactory                        bivm.ManagerFactory
+	deployer                                bidepl.Deployer
+	deploymentManifestPath                  string
+	cpiInstaller                            bicpirel.CpiInstaller
+	releaseFetcher                          birel.Fetcher
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentManifestParser                DeploymentManifestParser
+	tempRootConfigurator                    TempRootConfigurator
+	targetProvider                          biinstall.TargetProvider
+}
+
+type lifecycleContext struct {
+	cloud                bicloud.Cloud
+	blobstore            biblobstore.Blobstore
+	deploymentManager    bidepl.Manager
+	installationManifest biinstallmanifest.Manifest
+	deploymentManifest   bideplmanifest.Manifest
+	directorID           string
+	mbusClient           *http.Client
+}
+
+func (ctx lifecycleContext) drainOptions(skipDrain bool) biinstance.DrainOptions {
+	return biinstance.DrainOptions{
+		Skip:    skipDrain,
+		MaxWait: ctx.deploymentManifest.Update.MaxDrainWait,
+	}
+}
+
+func (c *instanceLifecycle) Status(stage biui.Stage) (InstanceStatus, error) {
+	status := InstanceStatus{}
+
+	err := c.withInstalledCpi(stage, false, func(ctx lifecycleContext) error {
+		deploymentState, err := c.deploymentStateService.Load()
+		if err != nil {
+			return bosherr.WrapError(err, "Loading deployment state")
+		}
+
+		if len(ctx.deploymentManifest.Jobs) > 0 {
+			status.Name = fmt.Sprintf("%s/0", ctx.deploymentManifest.JobName())
+		}
+		status.VMCID = deploymentState.CurrentVMCID
+
+		for _, stemcellRecord := range deploymentState.Stemcells {
+			if stemcellRecord.ID == deploymentState.CurrentStemcellID {
+				status.Stemcell = fmt.Sprintf("%s/%s", stemcellRecord.Name, stemcellRecord.Version)
+				status.StemcellCID = stemcellRecord.CID
+			}
+		}
+
+		for _, diskRecord := range deploymentState.Disks {
+			if diskRecord.ID == deploymentState.CurrentDiskID {
+				status.DiskCIDs = append(status.DiskCIDs, diskRecord.CID)
+			}
+		}
+
+		if status.VMCID == "" {
+			return nil
+		}
+
+		status.VMExists, err = ctx.cloud.HasVM(status.VMCID)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking existence of VM '%s'", status.VMCID)
+		}
+
+		if !status.VMExists {
+			return nil
+		}
+
+		agentClient := c.agentClientFactory.NewAgentClient(ctx.directorID, ctx.installationManifest.Mbus, ctx.mbusClient)
+
+		_, err = agentClient.Ping()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Pinging agent: %s", err.Error())
+			return nil
+		}
+
+		agentState, err := agentClient.GetFullState()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Getting agent state: %s", err.Error())
+			return nil
+		}
+
+		status.AgentResponsive = true
+		status.AgentID = agentState.AgentID
+		status.JobState = agentState.JobState
+
+		networkNames := []string{}
+		for networkName := range agentState.NetworkSpecs {
+			networkNames = append(networkNames, networkName)
+		}
+		sort.Strings(networkNames)
+		for _, networkName := range networkNames {
+			status.IPs = append(status.IPs, agentState.NetworkSpecs[networkName].IP)
+		}
+
+		for _, process := range agentState.Processes {
+			status.Processes = append(status.Processes, ProcessStatus{
+				Name:  process.Name,
+				State: process.State,
+			})
+		}
+
+		return nil
+	})
+
+	return status, err
+}
+
+func (c *instanceLifecycle) Stop(stage biui.Stage, hard bool) error {
+	return c.withCurrentDeployment(stage, false, func(ctx lifecycleContext, deployment bidepl.Deployment) error {
+		return stage.PerformComplex("stopping deployment", func(stopStage biui.Stage) error {
+			return deployment.Stop(stopStage, hard, ctx.drainOptions(false))
+		})
+	})
+}
+
+func (c *instanceLifecycle) Start(stage biui.Stage, hard bool) error {
+	return c.withCurrentDeployment(stage, false, func(ctx lifecycleContext, deployment bidepl.Deployment) error {
+		return stage.PerformComplex("starting deployment", func(startStage biui.Stage) error {
+			return deployment.Start(startStage, hard, ctx.deploymentManifest.Update.UpdateWatchTime)
+		})
+	})
+}
+
+func (c *instanceLifecycle) Restart(stage biui.Stage, hard bool) error {
+	return c.withCurrentDeployment(stage, false, func(ctx lifecycleContext, deployment bidepl.Deployment) error {
+		return stage.PerformComplex("restarting deployment", func(restartStage biui.Stage) error {
+			err := deployment.Stop(restartStage, hard, ctx.drainOptions(false))
+			if err != nil {
+				return err
+			}
+
+			return deployment.Start(restartStage, hard, ctx.deploymentManifest.Update.UpdateWatchTime)
+		})
+	})
+}
+
+func (c *instanceLifecycle) Recreate(stage biui.Stage, hard bool, skipDrain bool) error {
+	return c.withCurrentDeployment(stage, true, func(ctx lifecycleContext, deployment bidepl.Deployment) error {
+		return stage.PerformComplex("recreating deployment", func(recreateStage biui.Stage) error {
+			if hard {
+				err := deployment.Stop(recreateStage, true, ctx.drainOptions(skipDrain))
+				if err != nil {
+					return err
+				}
+			}
+
+			return c.recreateVM(ctx, ctx.drainOptions(skipDrain), recreateStage)
+		})
+	})
+}
+
+// recreateVM redeploys the current deployment manifest onto a new VM created from the current stemcell.
+// The persistent disk recorded in the deployment state is re-attached to the new VM.
+func (c *instanceLifecycle) recreateVM(ctx lifecycleContext, drainOptions biinstance.DrainOptions, stage biui.Stage) error {
+	stemcellManager := c.stemcellManagerFactory.NewManager(ctx.cloud)
+	cloudStemcells, err := stemcellManager.FindCurrent()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding current stemcell")
+	}
+
+	if len(cloudStemcells) == 0 {
+		return bosherr.Error("No current stemcell found in deployment state")
+	}
+
+	agentClient := c.agentClientFactory.NewAgentClient(ctx.directorID, ctx.installationManifest.Mbus, ctx.mbusClient)
+	vmManager := c.vmManagerFactory.NewManager(ctx.cloud, agentClient)
+
+	err = c.deploymentRecord.Clear()
+	if err != nil {
+		return bosherr.WrapError(err, "Clearing deployment record")
+	}
+
+	_, err = c.deployer.Deploy(
+		ctx.cloud,
+		ctx.deploymentManifest,
+		cloudStemcells[0],
+		ctx.installationManifest.Registry,
+		vmManager,
+		ctx.blobstore,
+		drainOptions,
+		stage,
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Recreating")
+	}
+
+	err = c.deploymentRecord.Update(c.deploymentManifestPath, c.releaseManager.List())
+	if err != nil {
+		return bosherr.WrapError(err, "Updating deployment record")
+	}
+
+	return nil
+}
+
+// RunErrand runs the errand on the deployed instance.
+// When logsDir is not empty, the errand's logs are downloaded into it.
+func (c *instanceLifecycle) RunErrand(stage biui.Stage, errandName string, logsDir string) (biinstance.ErrandResult, error) {
+	var result biinstance.ErrandResult
+
+	err := c.withCurrentDeployment(stage, true, func(ctx lifecycleContext, deployment bidepl.Deployment) error {
+		_, found := ctx.deploymentManifest.FindErrandByName(errandName)
+		if !found {
+			return bosherr.Errorf("Errand '%s' not found in deployment manifest", errandName)
+		}
+
+		return stage.PerformComplex(fmt.Sprintf("running errand '%s'", errandName), func(errandStage biui.Stage) error {
+			var err error
+			result, err = deployment.RunErrand(errandStage, errandName, ctx.deploymentManifest, logsDir != "")
+			if err != nil {
+				return err
+			}
+
+			if logsDir == "" {
+				return nil
+			}
+
+			logsPath := filepath.Join(logsDir, fmt.Sprintf("%s-%s.tgz", errandName, c.timeService.Now().Format("20060102-150405")))
+			return errandStage.Perform(fmt.Sprintf("Downloading logs to '%s'", logsPath), func() error {
+				localBlob, err := ctx.blobstore.Get(result.LogsBlobstoreID)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Getting logs blob '%s'", result.LogsBlobstoreID)
+				}
+				defer localBlob.DeleteSilently()
+
+				err = c.fs.CopyFile(localBlob.Path(), logsPath)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Copying logs to '%s'", logsPath)
+				}
+
+				return nil
+			})
+		})
+	})
+
+	return result, err
+}
+
+// withCurrentDeployment installs the CPI and yields the current deployment.
+// When needsReleases is true, all releases are extracted and the deployment manifest is fully validated,
+// so that job templates can be rendered and the registry is started for newly created VMs.
+func (c *instanceLifecycle) withCurrentDeployment(stage biui.Stage, needsReleases bool, fn func(lifecycleContext, bidepl.Deployment) error) error {
+	return c.withInstalledCpi(stage, needsReleases, func(ctx lifecycleContext) error {
+		c.logger.Debug(c.logTag, "Finding current deployment...")
+		deployment, found, err := ctx.deploymentManager.FindCurrent()
+		if err != nil {
+			return bosherr.WrapError(err, "Finding current deployment")
+		}
+
+		if !found {
+			return bosherr.Error("No current deployment found in deployment state")
+		}
+
+		return fn(ctx, deployment)
+	})
+}
+
+func (c *instanceLifecycle) withInstalledCpi(stage biui.Stage, needsReleases bool, fn func(lifecycleContext) error) error {
+	c.ui.PrintLinef("Deployment state: '%s'", c.deploymentStateService.Path())
+
+	if !c.deploymentStateService.Exists() {
+		return bosherr.Errorf("Deployment state does not exist at '%s'", c.deploymentStateService.Path())
+	}
+
+	deploymentState, err := c.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading deployment state")
+	}
+
+	target, err := c.targetProvider.NewTarget()
+	if err != nil {
+		return bosherr.WrapError(err, "Determining installation target")
+	}
+
+	err = c.tempRootConfigurator.PrepareAndSetTempRoot(target.TmpPath(), c.logger)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting temp root")
+	}
+
+	defer func() {
+		err := c.releaseManager.DeleteAll()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Deleting all extracted releases: %s", err.Error())
+		}
+	}()
+
+	ctx := lifecycleContext{
+		directorID: deploymentState.DirectorID,
+	}
+
+	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
+		var releaseSetManifest birelsetmanifest.Manifest
+		releaseSetManifest, ctx.installationManifest, err = c.releaseSetAndInstallationManifestParser.ReleaseSetAndInstallationManifest(c.deploymentManifestPath)
+		if err != nil {
+			return err
+		}
+
+		releaseRefs := releaseSetManifest.Releases
+		if !needsReleases {
+			cpiReleaseName := ctx.installationManifest.Template.Release
+			cpiReleaseRef, found := releaseSetManifest.FindByName(cpiReleaseName)
+			if !found {
+				return bosherr.Errorf("installation release '%s' must refer to a release in releases", cpiReleaseName)
+			}
+			releaseRefs = []birelmanifest.ReleaseRef{cpiReleaseRef}
+		}
+
+		for _, releaseRef := range releaseRefs {
+			err = c.releaseFetcher.DownloadAndExtract(releaseRef, stage)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = c.cpiInstaller.ValidateCpiRelease(ctx.installationManifest, stage)
+		if err != nil {
+			return err
+		}
+
+		if needsReleases {
+			ctx.deploymentManifest, err = c.deploymentManifestParser.GetDeploymentManifest(c.deploymentManifestPath, releaseSetManifest, stage)
+			return err
+		}
+
+		ctx.deploymentManifest, err = c.deploymentManifestParser.DeploymentParser.Parse(c.deploymentManifestPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Parsing deployment manifest '%s'", c.deploymentManifestPath)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.cpiInstaller.WithInstalledCpiRelease(ctx.installationManifest, target, stage, func(installation biinstall.Installation) error {
+		run := func() error {
+			err := c.loadContext(installation, &ctx)
+			if err != nil {
+				return err
+			}
+
+			return fn(ctx)
+		}
+
+		if needsReleases {
+			return installation.WithRunningRegistry(c.logger, stage, run)
+		}
+
+		return run()
+	})
+}
+
+func (c *instanceLifecycle) loadContext(installation biinstall.Installation, ctx *lifecycleContext) error {
+	var err error
+
+	c.logger.Debug(c.logTag, "Creating cloud client...")
+	ctx.cloud, err = c.cloudFactory.NewCloud(installation, ctx.directorID)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating CPI client from CPI installation")
+	}
+
+	ctx.mbusClient, err = newMbusClient(ctx.installationManifest, biconfig.NewHostKeyRepo(c.deploymentStateService), c.logger)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Debug(c.logTag, "Creating agent client...")
+	agentClient := c.agentClientFactory.NewAgentClient(ctx.directorID, ctx.installationManifest.Mbus, ctx.mbusClient)
+
+	c.logger.Debug(c.logTag, "Creating blobstore client...")
+	ctx.blobstore, err = c.blobstoreFactory.Create(ctx.installationManifest.Mbus, ctx.mbusClient)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating blobstore client")
+	}
+
+	c.logger.Debug(c.logTag, "Creating deployment manager...")
+	ctx.deploymentManager = c.deploymentManagerFactory.NewManager(ctx.cloud, agentClient, ctx.blobstore)
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+type InstanceStatus struct {
+	Name            string          `json:"name"`
+	VMCID           string          `json:"vm_cid"`
+	VMExists        bool            `json:"vm_exists"`
+	AgentID         string          `json:"agent_id"`
+	AgentResponsive bool            `json:"agent_responsive"`
+	IPs             []string        `json:"ips"`
+	Stemcell        string          `json:"stemcell"`
+	StemcellCID     string          `json:"stemcell_cid"`
+	DiskCIDs        []string        `json:"disk_cids"`
+	JobState        string          `json:"job_state"`
+	Processes       []ProcessStatus `json:"processes"`
+}
+
+type ProcessStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// Problems returns a description of everything that is unhealthy, or an empty list.
+func (s InstanceStatus) Problems() []string {
+	problems := []string{}
+
+	if s.VMCID == "" {
+		return append(problems, "No VM recorded in deployment state")
+	}
+
+	if !s.VMExists {
+		return append(problems, fmt.Sprintf("VM '%s' does not exist", s.VMCID))
+	}
+
+	if !s.AgentResponsive {
+		return append(problems, fmt.Sprintf("Agent on VM '%s' is not responding", s.VMCID))
+	}
+
+	if s.JobState != "running" {
+		problems = append(problems, fmt.Sprintf("Job state is '%s'", s.JobState))
+	}
+
+	for _, process := range s.Processes {
+		if process.State != "running" {
+			problems = append(problems, fmt.Sprintf("Process '%s' is '%s'", process.Name, process.State))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+type instancesCmd struct {
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	timeService               clock.Clock
+	logger                    boshlog.Logger
+	instanceLifecycleProvider func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error)
+	logTag                    string
+}
+
+func NewInstancesCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string, ui biui.UI) (InstanceLifecycle, error),
+) Cmd {
+	return &instancesCmd{
+		ui:                        ui,
+		fs:                        fs,
+		timeService:               timeService,
+		logger:                    logger,
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		logTag:                    "instancesCmd",
+	}
+}
+
+func (c *instancesCmd) Name() string {
+	return "instances"
+}
+
+func (c *instancesCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Show the VM, agent and job health of the deployed instance",
+		Usage:    "[--json] <deployment_manifest_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *instancesCmd) Run(stage biui.Stage, args []string) error {
+	deploymentManifestPath, jsonOutput, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := filepath.Abs(deploymentManifestPath)
+	if err != nil {
+		c.ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", deploymentManifestPath)
+		return bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", deploymentManifestPath)
+	}
+
+	if !c.fs.FileExists(manifestAbsFilePath) {
+		c.ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
+		return bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+	}
+
+	ui := c.ui
+	if jsonOutput {
+		ui = biui.NewQuietUI(c.ui)
+		stage = biui.NewStage(ui, c.timeService, c.logger)
+	}
+
+	ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath, ui)
+	if err != nil {
+		return err
+	}
+
+	status, err := instanceLifecycle.Status(stage)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		err = c.printJSON(status)
+	} else {
+		err = c.printTable(status)
+	}
+	if err != nil {
+		return err
+	}
+
+	problems := status.Problems()
+	if len(problems) > 0 {
+		return bosherr.Errorf("Instance is not healthy:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func (c *instancesCmd) printJSON(status InstanceStatus) error {
+	bytes, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling instance status")
+	}
+
+	c.ui.PrintLinef("%s", bytes)
+	return nil
+}
+
+func (c *instancesCmd) printTable(status InstanceStatus) error {
+	buffer := &bytes.Buffer{}
+	writer := tabwriter.NewWriter(buffer, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "Instance\tVM CID\tAgent ID\tIPs\tStemcell\tDisk CIDs\tState")
+	fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		status.Name,
+		c.valueOrNone(status.VMCID),
+		c.valueOrNone(status.AgentID),
+		c.valueOrNone(strings.Join(status.IPs, ", ")),
+		c.valueOrNone(status.Stemcell),
+		c.valueOrNone(strings.Join(status.DiskCIDs, ", ")),
+		c.state(status),
+	)
+
+	if len(status.Processes) > 0 {
+		fmt.Fprintln(writer, "")
+		fmt.Fprintln(writer, "Process\tState")
+		for _, process := range status.Processes {
+			fmt.Fprintf(writer, "%s\t%s\n", process.Name, process.State)
+		}
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return bosherr.WrapError(err, "Writing instance table")
+	}
+
+	c.ui.PrintLinef("")
+	for _, line := range strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n") {
+		c.ui.PrintLinef("%s", strings.TrimRight(line, " "))
+	}
+
+	return nil
+}
+
+func (c *instancesCmd) state(status InstanceStatus) string {
+	switch {
+	case status.VMCID == "":
+		return "no vm"
+	case !status.VMExists:
+		return "missing vm"
+	case !status.AgentResponsive:
+		return "unresponsive agent"
+	default:
+		return status.JobState
+	}
+}
+
+func (c *instancesCmd) valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func (c *instancesCmd) parseCmdInputs(args []string) (string, bool, error) {
+	jsonOutput := false
+	positionalArgs := []string{}
+	for _, arg := range args {
+		if arg == "--json" {
+			jsonOutput = true
+		} else {
+			positionalArgs = append(positionalArgs, arg)
+		}
+	}
+
+	if len(positionalArgs) != 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return "", false, bosherr.Error("Invalid usage - instances command requires exactly 1 argument")
+	}
+	return positionalArgs[0], jsonOutput, nil
+}
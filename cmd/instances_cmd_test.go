@@ -0,0 +1,139 @@
+package cmd_test
+
+import (
+	"time"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("InstancesCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+		fs                    *fakesys.FakeFileSystem
+		logger                boshlog.Logger
+		fakeUI                *fakebiui.FakeUI
+		fakeStage             *fakebiui.FakeStage
+		command               bicmd.Cmd
+		providedUI            biui.UI
+
+		deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+		healthyStatus          bicmd.InstanceStatus
+	)
+
+	BeforeEach(func() {
+		mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+
+		provider := func(path string, ui biui.UI) (bicmd.InstanceLifecycle, error) {
+			Expect(path).To(Equal(deploymentManifestPath))
+			providedUI = ui
+			return mockInstanceLifecycle, nil
+		}
+		command = bicmd.NewInstancesCmd(fakeUI, fs, fakeclock.NewFakeClock(time.Now()), logger, provider)
+
+		healthyStatus = bicmd.InstanceStatus{
+			Name:            "fake-job/0",
+			VMCID:           "fake-vm-cid",
+			VMExists:        true,
+			AgentID:         "fake-agent-id",
+			AgentResponsive: true,
+			IPs:             []string{"10.0.0.5"},
+			Stemcell:        "fake-stemcell/1",
+			StemcellCID:     "fake-stemcell-cid",
+			DiskCIDs:        []string{"fake-disk-cid"},
+			JobState:        "running",
+			Processes: []bicmd.ProcessStatus{
+				{Name: "fake-process", State: "running"},
+			},
+		}
+	})
+
+	It("prints a table of the instance status", func() {
+		mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(healthyStatus, nil)
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(providedUI).To(Equal(fakeUI))
+		Expect(fakeUI.Said).To(ContainElement("Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'"))
+		Expect(fakeUI.Said).To(ContainElement("Instance    VM CID       Agent ID       IPs       Stemcell         Disk CIDs      State"))
+		Expect(fakeUI.Said).To(ContainElement("fake-job/0  fake-vm-cid  fake-agent-id  10.0.0.5  fake-stemcell/1  fake-disk-cid  running"))
+		Expect(fakeUI.Said).To(ContainElement("fake-process  running"))
+	})
+
+	It("prints only json when --json is given", func() {
+		mockInstanceLifecycle.EXPECT().Status(gomock.Any()).Return(healthyStatus, nil)
+
+		err := command.Run(fakeStage, []string{"--json", deploymentManifestPath})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(providedUI).ToNot(Equal(fakeUI))
+		Expect(fakeUI.Said).To(HaveLen(1))
+		Expect(fakeUI.Said[0]).To(ContainSubstring(`"vm_cid": "fake-vm-cid"`))
+		Expect(fakeUI.Said[0]).To(ContainSubstring(`"agent_responsive": true`))
+		Expect(fakeStage.PerformCalls).To(BeEmpty())
+	})
+
+	Context("when the instance is not healthy", func() {
+		It("returns an error listing the problems", func() {
+			healthyStatus.JobState = "failing"
+			healthyStatus.Processes[0].State = "failing"
+			mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(healthyStatus, nil)
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Job state is 'failing'"))
+			Expect(err.Error()).To(ContainSubstring("Process 'fake-process' is 'failing'"))
+		})
+
+		It("reports a missing VM", func() {
+			healthyStatus.VMExists = false
+			mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(healthyStatus, nil)
+
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VM 'fake-vm-cid' does not exist"))
+			Expect(fakeUI.Said).To(ContainElement(ContainSubstring("missing vm")))
+		})
+	})
+
+	It("returns the error from the instance lifecycle", func() {
+		statusErr := bosherr.Error("fake-status-error")
+		mockInstanceLifecycle.EXPECT().Status(fakeStage).Return(bicmd.InstanceStatus{}, statusErr)
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(Equal(statusErr))
+	})
+
+	It("returns err unless exactly 1 argument is given", func() {
+		err := command.Run(fakeStage, []string{"--json"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage - instances command requires exactly 1 argument"))
+	})
+})
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+func NewManifestValidator(
+	logger boshlog.Logger,
+	logTag string,
+	releaseManager birel.Manager,
+	deploymentManifestPath string,
+	cpiInstaller bicpirel.CpiInstaller,
+	releaseFetcher birel.Fetcher,
+	stemcellFetcher bistemcell.Fetcher,
+	maxConcurrentDownloads int,
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser,
+	deploymentManifestParser DeploymentManifestParser,
+	tempRootConfigurator TempRootConfigurator,
+	tempRootPath string,
+) ManifestValidator {
+	return ManifestValidator{
+		logger:                                  logger,
+		logTag:                                  logTag,
+		releaseManager:                          releaseManager,
+		deploymentManifestPath:                  deploymentManifestPath,
+		cpiInstaller:                            cpiInstaller,
+		releaseFetcher:                          releaseFetcher,
+		stemcellFetcher:                         stemcellFetcher,
+		maxConcurrentDownloads:                  maxConcurrentDownloads,
+		releaseSetAndInstallationManifestParser: releaseSetAndInstallationManifestParser,
+		deploymentManifestParser:                deploymentManifestParser,
+		tempRootConfigurator:                    tempRootConfigurator,
+		tempRootPath:                            tempRootPath,
+	}
+}
+
+// ManifestValidator runs the validations of deploy against a deployment manifest,
+// fetching its releases and stemcell, without installing the CPI or calling the cloud
+type ManifestValidator struct {
+	logger                                  boshlog.Logger
+	logTag                                  string
+	releaseManager                          birel.Manager
+	deploymentManifestPath                  string
+	cpiInstaller                            bicpirel.CpiInstaller
+	releaseFetcher                          birel.Fetcher
+	stemcellFetcher                         bistemcell.Fetcher
+	maxConcurrentDownloads                  int
+	releaseSetAndInstallationManifestParser ReleaseSetAndInstallationManifestParser
+	deploymentManifestParser                DeploymentManifestParser
+	tempRootConfigurator                    TempRootConfigurator
+	tempRootPath                            string
+}
+
+// Validate returns every problem found in the manifest, instead of stopping at the first one.
+// It returns an error, with the problems found so far, when the manifest could not be validated,
+// e.g. when a release or the stemcell can not be downloaded or extracted.
+func (v ManifestValidator) Validate(stage biui.Stage) ([]error, error) {
+	err := v.tempRootConfigurator.PrepareAndSetTempRoot(v.tempRootPath, v.logger)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Setting temp root")
+	}
+
+	defer func() {
+		err := v.releaseManager.DeleteAll()
+		if err != nil {
+			v.logger.Warn(v.logTag, "Deleting all extracted releases: %s", err.Error())
+		}
+	}()
+
+	var extractedStemcell bistemcell.ExtractedStemcell
+	defer func() {
+		if extractedStemcell == nil {
+			return
+		}
+		deleteErr := extractedStemcell.Delete()
+		if deleteErr != nil {
+			v.logger.Warn(v.logTag, "Failed to delete extracted stemcell: %s", deleteErr.Error())
+		}
+	}()
+
+	problems := []error{}
+	var problemsLock sync.Mutex
+	addProblems := func(errs ...error) {
+		problemsLock.Lock()
+		defer problemsLock.Unlock()
+		problems = append(problems, errs...)
+	}
+
+	fetchErrs := []error{}
+	addFetchErr := func(err error) {
+		problemsLock.Lock()
+		defer problemsLock.Unlock()
+		fetchErrs = append(fetchErrs, err)
+	}
+
+	var (
+		releaseSetManifest   birelsetmanifest.Manifest
+		installationManifest biinstallmanifest.Manifest
+		deploymentManifest   bideplmanifest.Manifest
+	)
+	// sections that depend on an invalid section are not validated, as they would only report follow-up problems
+	releaseSetValid, installationValid, deploymentParsed, releasesFetched, stemcellFetched := false, false, false, false, false
+
+	err = stage.PerformComplex("validating", func(stage biui.Stage) error {
+		err := stage.Perform("Validating release set manifest", func() error {
+			var err error
+			releaseSetManifest, err = v.releaseSetAndInstallationManifestParser.ReleaseSetParser.Parse(v.deploymentManifestPath)
+			return err
+		})
+		if err != nil {
+			addProblems(validationProblems(err)...)
+		} else {
+			releaseSetValid = true
+		}
+
+		if releaseSetValid {
+			err = stage.Perform("Validating installation manifest", func() error {
+				var err error
+				installationManifest, err = v.releaseSetAndInstallationManifestParser.InstallationParser.Parse(v.deploymentManifestPath, releaseSetManifest)
+				return err
+			})
+			if err != nil {
+				addProblems(validationProblems(err)...)
+			} else {
+				installationValid = true
+			}
+		}
+
+		err = stage.Perform("Validating deployment manifest", func() error {
+			var err error
+			deploymentManifest, err = v.deploymentManifestParser.ParseDeploymentManifest(v.deploymentManifestPath)
+			if err != nil {
+				return err
+			}
+			deploymentParsed = true
+
+			if !releaseSetValid {
+				return nil
+			}
+			return v.deploymentManifestParser.DeploymentValidator.Validate(deploymentManifest, releaseSetManifest)
+		})
+		if err != nil {
+			addProblems(validationProblems(err)...)
+		}
+
+		fetches := []func(biui.Stage) error{}
+		if deploymentParsed {
+			fetches = append(fetches, func(stage biui.Stage) error {
+				var err error
+				extractedStemcell, err = v.stemcellFetcher.GetStemcell(deploymentManifest, stage)
+				if err != nil {
+					addFetchErr(err)
+				}
+				return nil
+			})
+		}
+		if releaseSetValid {
+			releasesFetched = true
+			for _, releaseRef := range releaseSetManifest.Releases {
+				releaseRef := releaseRef
+				fetches = append(fetches, func(stage biui.Stage) error {
+					err := v.releaseFetcher.DownloadAndExtract(releaseRef, stage)
+					if err != nil {
+						addFetchErr(err)
+						problemsLock.Lock()
+						releasesFetched = false
+						problemsLock.Unlock()
+					}
+					return nil
+				})
+			}
+		}
+
+		// errors are collected by the fetches themselves, so that one failing download doesn't skip the others
+		err = stage.PerformConcurrently(v.maxConcurrentDownloads, fetches)
+		if err != nil {
+			return err
+		}
+		stemcellFetched = extractedStemcell != nil
+
+		if installationValid {
+			if _, found := v.releaseManager.Find(installationManifest.Template.Release); found {
+				err = v.cpiInstaller.ValidateCpiRelease(installationManifest, stage)
+				if err != nil {
+					addProblems(bosherr.WrapError(err, "cloud_provider.template"))
+				}
+			}
+		}
+
+		if deploymentParsed && releasesFetched {
+			err = stage.Perform("Validating deployment jobs", func() error {
+				return v.deploymentManifestParser.DeploymentValidator.ValidateReleaseJobs(deploymentManifest, v.releaseManager)
+			})
+			if err != nil {
+				addProblems(validationProblems(err)...)
+			}
+		}
+
+		if installationValid && stemcellFetched {
+			err = stage.Perform("Validating compiled releases", func() error {
+				errs := compiledReleaseErrors(v.releaseManager.List(), releaseSetManifest, installationManifest, deploymentManifest, extractedStemcell)
+				if len(errs) > 0 {
+					return bosherr.NewMultiError(errs...)
+				}
+				return nil
+			})
+			if err != nil {
+				addProblems(validationProblems(err)...)
+			}
+		}
+
+		if len(fetchErrs) > 0 {
+			return bosherr.WrapError(bosherr.NewMultiError(fetchErrs...), "Fetching releases and stemcell")
+		}
+		if len(problems) > 0 {
+			return bosherr.Error("Deployment manifest is invalid")
+		}
+		return nil
+	})
+	if len(fetchErrs) > 0 {
+		return problems, err
+	}
+	if err != nil && len(problems) == 0 {
+		return nil, err
+	}
+
+	return problems, nil
+}
+
+// compiledReleaseErrors checks that the compiled releases of the deployment jobs were compiled against the deployment stemcell,
+// and that the CPI release is not compiled
+func compiledReleaseErrors(
+	releases []birel.Release,
+	releaseSetManifest birelsetmanifest.Manifest,
+	installationManifest biinstallmanifest.Manifest,
+	deploymentManifest bideplmanifest.Manifest,
+	extractedStemcell bistemcell.ExtractedStemcell,
+) []error {
+	errs := []error{}
+
+	nonCpiReleasesMap, _ := deploymentManifest.GetListOfTemplateReleases()
+	delete(nonCpiReleasesMap, installationManifest.Template.Release) // remove CPI release from nonCpiReleasesMap
+
+	for _, release := range releases {
+		if !release.IsCompiled() {
+			continue
+		}
+
+		if _, ok := nonCpiReleasesMap[release.Name()]; ok {
+			compilationOsAndVersion := release.Packages()[0].Stemcell
+			if strings.ToLower(compilationOsAndVersion) != strings.ToLower(extractedStemcell.OsAndVersion()) {
+				errs = append(errs, bosherr.Errorf("%s: OS/Version mismatch between deployment stemcell and compiled package stemcell for release '%s'", releasePath(releaseSetManifest, release.Name()), release.Name()))
+			}
+		} else {
+			// It is a CPI release
+			errs = append(errs, bosherr.Errorf("cloud_provider.template.release: CPI is not allowed to be a compiled release. The provided CPI release '%s' is compiled", release.Name()))
+		}
+	}
+
+	return errs
+}
+
+func releasePath(releaseSetManifest birelsetmanifest.Manifest, name string) string {
+	for releaseIdx, release := range releaseSetManifest.Releases {
+		if release.Name == name {
+			return fmt.Sprintf("releases[%d]", releaseIdx)
+		}
+	}
+	return "releases"
+}
+
+// validationProblems unwraps the problems listed by a validator from the context added by the parsers,
+// so that each problem is reported with its YAML path
+func validationProblems(err error) []error {
+	cause := err
+	for {
+		switch specificErr := cause.(type) {
+		case bosherr.MultiError:
+			return specificErr.Errors
+		case bosherr.ComplexError:
+			cause = specificErr.Cause
+		default:
+			return []error{err}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"net/http"
+
+	binet "github.com/cloudfoundry/bosh-init/common/net"
+	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// newMbusClient creates the HTTP client shared by the agent client and the agent blobstore,
+// which connects through the environment's HTTP proxy unless the installation manifest sets a proxy or jump host
+func newMbusClient(installationManifest biinstallmanifest.Manifest, hostKeyStore bisshtunnel.HostKeyStore, logger boshlog.Logger) (*http.Client, error) {
+	var dial binet.DialFunc
+
+	proxy := installationManifest.Proxy
+	if !proxy.IsEmpty() {
+		dialer, err := bisshtunnel.NewDialer(bisshtunnel.ProxyOptions{
+			URL: proxy.URL,
+			JumpHost: bisshtunnel.JumpHostOptions{
+				Host:                 proxy.JumpHost.Host,
+				Port:                 proxy.JumpHost.Port,
+				User:                 proxy.JumpHost.User,
+				PrivateKey:           proxy.JumpHost.PrivateKey,
+				PrivateKeyPassphrase: proxy.JumpHost.PrivateKeyPassphrase,
+				Password:             proxy.JumpHost.Password,
+				HostKey: bisshtunnel.HostKeyOptions{
+					HostKey:          proxy.JumpHost.HostKey,
+					KnownHostsPath:   proxy.JumpHost.KnownHosts,
+					TrustOnFirstUse:  proxy.JumpHost.TrustOnFirstUse,
+					SkipVerification: proxy.JumpHost.SkipHostKeyVerification,
+				},
+			},
+		}, hostKeyStore, logger)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Creating proxy dialer")
+		}
+		dial = dialer.Dial
+	}
+
+	mbusTLS := installationManifest.MbusTLS
+	client, err := binet.CreateHTTPClient(mbusTLS.CACert, mbusTLS.ClientCert, mbusTLS.ClientKey, dial)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating mbus HTTP client")
+	}
+
+	return client, nil
+}
@@ -1,9 +1,11 @@
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/cloudfoundry/bosh-init/cmd (interfaces: DeploymentDeleter)
+// Source: github.com/cloudfoundry/bosh-init/cmd (interfaces: DeploymentDeleter,InstanceLifecycle)
 
 package mocks
 
 import (
+	cmd "github.com/cloudfoundry/bosh-init/cmd"
+	instance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	ui "github.com/cloudfoundry/bosh-init/ui"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -29,12 +31,105 @@ func (_m *MockDeploymentDeleter) EXPECT() *_MockDeploymentDeleterRecorder {
 	return _m.recorder
 }
 
-func (_m *MockDeploymentDeleter) DeleteDeployment(_param0 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "DeleteDeployment", _param0)
+func (_m *MockDeploymentDeleter) DeleteDeployment(_param0 ui.Stage, _param1 bool) error {
+	ret := _m.ctrl.Call(_m, "DeleteDeployment", _param0, _param1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockDeploymentDeleterRecorder) DeleteDeployment(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteDeployment", arg0)
+func (_mr *_MockDeploymentDeleterRecorder) DeleteDeployment(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteDeployment", arg0, arg1)
+}
+
+// Mock of InstanceLifecycle interface
+type MockInstanceLifecycle struct {
+	ctrl     *gomock.Controller
+	recorder *_MockInstanceLifecycleRecorder
+}
+
+// Recorder for MockInstanceLifecycle (not exported)
+type _MockInstanceLifecycleRecorder struct {
+	mock *MockInstanceLifecycle
+}
+
+func NewMockInstanceLifecycle(ctrl *gomock.Controller) *MockInstanceLifecycle {
+	mock := &MockInstanceLifecycle{ctrl: ctrl}
+	mock.recorder = &_MockInstanceLifecycleRecorder{mock}
+	return mock
+}
+
+func (_m *MockInstanceLifecycle) EXPECT() *_MockInstanceLifecycleRecorder {
+	return _m.recorder
+}
+
+func (_m *MockInstanceLifecycle) CloudCheck(_param0 ui.Stage, _param1 cmd.CloudProblemResolver) error {
+	ret := _m.ctrl.Call(_m, "CloudCheck", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) CloudCheck(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CloudCheck", arg0, arg1)
+}
+
+func (_m *MockInstanceLifecycle) Recreate(_param0 ui.Stage, _param1 bool, _param2 bool) error {
+	ret := _m.ctrl.Call(_m, "Recreate", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Recreate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Recreate", arg0, arg1, arg2)
+}
+
+func (_m *MockInstanceLifecycle) Restart(_param0 ui.Stage, _param1 bool) error {
+	ret := _m.ctrl.Call(_m, "Restart", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Restart(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Restart", arg0, arg1)
+}
+
+func (_m *MockInstanceLifecycle) RunErrand(_param0 ui.Stage, _param1 string, _param2 string) (instance.ErrandResult, error) {
+	ret := _m.ctrl.Call(_m, "RunErrand", _param0, _param1, _param2)
+	ret0, _ := ret[0].(instance.ErrandResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) RunErrand(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RunErrand", arg0, arg1, arg2)
+}
+
+func (_m *MockInstanceLifecycle) Start(_param0 ui.Stage, _param1 bool) error {
+	ret := _m.ctrl.Call(_m, "Start", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Start(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Start", arg0, arg1)
+}
+
+func (_m *MockInstanceLifecycle) Status(_param0 ui.Stage) (cmd.InstanceStatus, error) {
+	ret := _m.ctrl.Call(_m, "Status", _param0)
+	ret0, _ := ret[0].(cmd.InstanceStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Status(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Status", arg0)
+}
+
+func (_m *MockInstanceLifecycle) Stop(_param0 ui.Stage, _param1 bool) error {
+	ret := _m.ctrl.Call(_m, "Stop", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceLifecycleRecorder) Stop(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Stop", arg0, arg1)
 }
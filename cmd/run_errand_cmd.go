@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type runErrandCmd struct {
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error)
+	logTag                    string
+}
+
+type runErrandInputs struct {
+	errandName             string
+	deploymentManifestPath string
+	downloadLogs           bool
+	logsDir                string
+}
+
+func NewRunErrandCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	instanceLifecycleProvider func(deploymentManifestPath string) (InstanceLifecycle, error),
+) Cmd {
+	return &runErrandCmd{
+		ui:                        ui,
+		fs:                        fs,
+		logger:                    logger,
+		instanceLifecycleProvider: instanceLifecycleProvider,
+		logTag:                    "runErrandCmd",
+	}
+}
+
+func (c *runErrandCmd) Name() string {
+	return "run-errand"
+}
+
+func (c *runErrandCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Run an errand job on the deployed instance",
+		Usage:    "[--download-logs] [--logs-dir=<dir>] <errand_name> <deployment_manifest_path>",
+		Env:      genericEnv,
+	}
+}
+
+func (c *runErrandCmd) Run(stage biui.Stage, args []string) error {
+	inputs, err := c.parseCmdInputs(args)
+	if err != nil {
+		return err
+	}
+
+	manifestAbsFilePath, err := filepath.Abs(inputs.deploymentManifestPath)
+	if err != nil {
+		c.ui.ErrorLinef("Failed getting absolute path to deployment file '%s'", inputs.deploymentManifestPath)
+		return bosherr.WrapErrorf(err, "Getting absolute path to deployment file '%s'", inputs.deploymentManifestPath)
+	}
+
+	if !c.fs.FileExists(manifestAbsFilePath) {
+		c.ui.ErrorLinef("Deployment '%s' does not exist", manifestAbsFilePath)
+		return bosherr.Errorf("Deployment manifest does not exist at '%s'", manifestAbsFilePath)
+	}
+
+	logsDir := ""
+	if inputs.downloadLogs {
+		logsDir, err = filepath.Abs(inputs.logsDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Getting absolute path to logs directory '%s'", inputs.logsDir)
+		}
+	}
+
+	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
+
+	instanceLifecycle, err := c.instanceLifecycleProvider(manifestAbsFilePath)
+	if err != nil {
+		return err
+	}
+
+	result, err := instanceLifecycle.RunErrand(stage, inputs.errandName, logsDir)
+	if err != nil {
+		return err
+	}
+
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("[stdout]")
+	c.printOutput(result.Stdout)
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("[stderr]")
+	c.printOutput(result.Stderr)
+	c.ui.PrintLinef("")
+
+	if result.ExitStatus != 0 {
+		return bosherr.Errorf("Errand '%s' completed with error (exit code %d)", inputs.errandName, result.ExitStatus)
+	}
+
+	c.ui.PrintLinef("Errand '%s' completed successfully (exit code 0)", inputs.errandName)
+	return nil
+}
+
+func (c *runErrandCmd) printOutput(output string) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		c.ui.PrintLinef("None")
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		c.ui.PrintLinef("%s", line)
+	}
+}
+
+func (c *runErrandCmd) parseCmdInputs(args []string) (runErrandInputs, error) {
+	inputs := runErrandInputs{logsDir: "."}
+	positionalArgs := []string{}
+	for _, arg := range args {
+		switch {
+		case arg == "--download-logs":
+			inputs.downloadLogs = true
+		case strings.HasPrefix(arg, "--logs-dir="):
+			inputs.downloadLogs = true
+			inputs.logsDir = strings.TrimPrefix(arg, "--logs-dir=")
+		default:
+			positionalArgs = append(positionalArgs, arg)
+		}
+	}
+
+	if len(positionalArgs) != 2 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return inputs, bosherr.Error("Invalid usage - run-errand command requires exactly 2 arguments")
+	}
+
+	inputs.errandName = positionalArgs[0]
+	inputs.deploymentManifestPath = positionalArgs[1]
+	return inputs, nil
+}
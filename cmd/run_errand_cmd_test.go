@@ -0,0 +1,122 @@
+package cmd_test
+
+import (
+	"path/filepath"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mock_cmd "github.com/cloudfoundry/bosh-init/cmd/mocks"
+	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/golang/mock/gomock"
+
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+)
+
+var _ = Describe("RunErrandCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		mockInstanceLifecycle *mock_cmd.MockInstanceLifecycle
+		fs                    *fakesys.FakeFileSystem
+		logger                boshlog.Logger
+		fakeUI                *fakebiui.FakeUI
+		fakeStage             *fakebiui.FakeStage
+		command               bicmd.Cmd
+
+		deploymentManifestPath = "/deployment-dir/fake-deployment-manifest.yml"
+	)
+
+	var provider = func(path string) (bicmd.InstanceLifecycle, error) {
+		Expect(path).To(Equal(deploymentManifestPath))
+		return mockInstanceLifecycle, nil
+	}
+
+	BeforeEach(func() {
+		mockInstanceLifecycle = mock_cmd.NewMockInstanceLifecycle(mockCtrl)
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		fs.WriteFileString(deploymentManifestPath, `---manifest-content`)
+
+		command = bicmd.NewRunErrandCmd(fakeUI, fs, logger, provider)
+	})
+
+	It("runs the errand and prints its output", func() {
+		mockInstanceLifecycle.EXPECT().RunErrand(fakeStage, "fake-errand", "").Return(biinstance.ErrandResult{
+			ExitStatus: 0,
+			Stdout:     "fake-stdout-1\nfake-stdout-2\n",
+		}, nil)
+
+		err := command.Run(fakeStage, []string{"fake-errand", deploymentManifestPath})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeUI.Said).To(Equal([]string{
+			"Deployment manifest: '/deployment-dir/fake-deployment-manifest.yml'",
+			"",
+			"[stdout]",
+			"fake-stdout-1",
+			"fake-stdout-2",
+			"",
+			"[stderr]",
+			"None",
+			"",
+			"Errand 'fake-errand' completed successfully (exit code 0)",
+		}))
+	})
+
+	It("returns an error with the exit code when the errand fails", func() {
+		mockInstanceLifecycle.EXPECT().RunErrand(fakeStage, "fake-errand", "").Return(biinstance.ErrandResult{
+			ExitStatus: 3,
+			Stderr:     "fake-stderr",
+		}, nil)
+
+		err := command.Run(fakeStage, []string{"fake-errand", deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Errand 'fake-errand' completed with error (exit code 3)"))
+		Expect(fakeUI.Said).To(ContainElement("fake-stderr"))
+	})
+
+	It("downloads logs into the current directory with --download-logs", func() {
+		currentDir, err := filepath.Abs(".")
+		Expect(err).ToNot(HaveOccurred())
+		mockInstanceLifecycle.EXPECT().RunErrand(fakeStage, "fake-errand", currentDir)
+
+		err = command.Run(fakeStage, []string{"--download-logs", "fake-errand", deploymentManifestPath})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("downloads logs into the given directory with --logs-dir", func() {
+		mockInstanceLifecycle.EXPECT().RunErrand(fakeStage, "fake-errand", "/fake-logs-dir")
+
+		err := command.Run(fakeStage, []string{"--logs-dir=/fake-logs-dir", "fake-errand", deploymentManifestPath})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns the error from the instance lifecycle", func() {
+		runErr := bosherr.Error("fake-run-errand-error")
+		mockInstanceLifecycle.EXPECT().RunErrand(fakeStage, "fake-errand", "").Return(biinstance.ErrandResult{}, runErr)
+
+		err := command.Run(fakeStage, []string{"fake-errand", deploymentManifestPath})
+		Expect(err).To(Equal(runErr))
+	})
+
+	It("returns err unless exactly 2 arguments are given", func() {
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid usage - run-errand command requires exactly 2 arguments"))
+	})
+})
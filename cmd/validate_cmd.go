@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type validateCmd struct {
+	ui                        biui.UI
+	fs                        boshsys.FileSystem
+	logger                    boshlog.Logger
+	manifestValidatorProvider func(deploymentManifestPath string) (ManifestValidator, error)
+	logTag                    string
+}
+
+func NewValidateCmd(
+	ui biui.UI,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+	manifestValidatorProvider func(deploymentManifestPath string) (ManifestValidator, error),
+) Cmd {
+	return &validateCmd{
+		ui:                        ui,
+		fs:                        fs,
+		logger:                    logger,
+		manifestValidatorProvider: manifestValidatorProvider,
+		logTag:                    "validateCmd",
+	}
+}
+
+func (c *validateCmd) Name() string {
+	return "validate"
+}
+
+func (c *validateCmd) Meta() Meta {
+	return Meta{
+		Synopsis: "Validate a deployment manifest, its releases and stemcell without deploying",
+		Usage:    "<deployment_manifest_path>",
+		Env:      deployEnv(),
+	}
+}
+
+// Run exits with ExitCodeInvalid when the manifest has problems, all of which are reported,
+// and with ExitCodeFailed when the manifest could not be validated, e.g. on invalid usage or when a release can not be downloaded
+func (c *validateCmd) Run(stage biui.Stage, args []string) error {
+	if len(args) != 1 {
+		c.logger.Error(c.logTag, "Invalid arguments: %#v", args)
+		return NewExitError(ExitCodeFailed, "Invalid usage - validate command requires exactly 1 argument")
+	}
+
+	manifestAbsFilePath, err := filepath.Abs(args[0])
+	if err != nil {
+		return bosherr.WrapComplexError(err, NewExitError(ExitCodeFailed, fmt.Sprintf("Getting absolute path to deployment file '%s'", args[0])))
+	}
+
+	if !c.fs.FileExists(manifestAbsFilePath) {
+		return NewExitError(ExitCodeFailed, fmt.Sprintf("Deployment manifest does not exist at '%s'", manifestAbsFilePath))
+	}
+
+	c.ui.PrintLinef("Deployment manifest: '%s'", manifestAbsFilePath)
+
+	manifestValidator, err := c.manifestValidatorProvider(manifestAbsFilePath)
+	if err != nil {
+		return bosherr.WrapComplexError(err, NewExitError(ExitCodeFailed, "Preparing validation"))
+	}
+
+	problems, err := manifestValidator.Validate(stage)
+	if err != nil {
+		// the problems found before the validation failed are still worth fixing
+		if len(problems) > 0 {
+			err = bosherr.NewMultiError(append([]error{err}, problems...)...)
+		}
+		return bosherr.WrapComplexError(err, NewExitError(ExitCodeFailed, fmt.Sprintf("Validating deployment manifest '%s'", manifestAbsFilePath)))
+	}
+
+	if len(problems) > 0 {
+		noun := "problems"
+		if len(problems) == 1 {
+			noun = "problem"
+		}
+		message := fmt.Sprintf("Found %d %s in deployment manifest '%s'", len(problems), noun, manifestAbsFilePath)
+		return bosherr.WrapComplexError(bosherr.NewMultiError(problems...), NewExitError(ExitCodeInvalid, message))
+	}
+
+	c.ui.PrintLinef("")
+	c.ui.PrintLinef("Deployment manifest '%s' is valid", manifestAbsFilePath)
+	return nil
+}
@@ -0,0 +1,347 @@
+package cmd_test
+
+import (
+	"errors"
+
+	bicmd "github.com/cloudfoundry/bosh-init/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-init/crypto"
+	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock"
+
+	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bicpirel "github.com/cloudfoundry/bosh-init/cpi/release"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	bireljob "github.com/cloudfoundry/bosh-init/release/job"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	bipkg "github.com/cloudfoundry/bosh-init/release/pkg"
+	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
+	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+
+	fakebicatalog "github.com/cloudfoundry/bosh-init/catalog/fakes"
+	fakebideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest/fakes"
+	fakebiinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest/fakes"
+	fakebirel "github.com/cloudfoundry/bosh-init/release/fakes"
+	fakebirelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest/fakes"
+	fakebisignature "github.com/cloudfoundry/bosh-init/signature/fakes"
+	fakebistemcell "github.com/cloudfoundry/bosh-init/stemcell/fakes"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("ValidateCmd", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	var (
+		command                 bicmd.Cmd
+		fakeFs                  *fakesys.FakeFileSystem
+		fakeUI                  *fakebiui.FakeUI
+		fakeStage               *fakebiui.FakeStage
+		logger                  boshlog.Logger
+		releaseManager          birel.Manager
+		mockReleaseExtractor    *mock_release.MockExtractor
+		fakeStemcellExtractor   *fakebistemcell.FakeExtractor
+		fakeReleaseSetParser    *fakebirelsetmanifest.FakeParser
+		fakeInstallationParser  *fakebiinstallmanifest.FakeParser
+		fakeDeploymentParser    *fakebideplmanifest.FakeParser
+		fakeDeploymentValidator *fakebideplmanifest.FakeValidator
+		fakeCPIRelease          *fakebirel.FakeRelease
+		fakeOtherRelease        *fakebirel.FakeRelease
+
+		deploymentManifestPath  string
+		cpiReleaseTarballPath   string
+		otherReleaseTarballPath string
+		stemcellTarballPath     string
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fakeFs = fakesys.NewFakeFileSystem()
+		fakeFs.EnableStrictTempRootBehavior()
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		releaseManager = birel.NewManager(logger)
+		mockReleaseExtractor = mock_release.NewMockExtractor(mockCtrl)
+		fakeStemcellExtractor = fakebistemcell.NewFakeExtractor()
+
+		deploymentManifestPath = "/path/to/manifest.yml"
+		cpiReleaseTarballPath = "/path/to/cpi-release.tgz"
+		otherReleaseTarballPath = "/path/to/other-release.tgz"
+		stemcellTarballPath = "/path/to/stemcell.tgz"
+		fakeFs.WriteFileString(deploymentManifestPath, "")
+		fakeFs.WriteFileString(cpiReleaseTarballPath, "")
+		fakeFs.WriteFileString(otherReleaseTarballPath, "")
+		fakeFs.WriteFileString(stemcellTarballPath, "")
+
+		fakeReleaseSetParser = fakebirelsetmanifest.NewFakeParser()
+		fakeReleaseSetParser.ParseManifest = birelsetmanifest.Manifest{
+			Releases: []birelmanifest.ReleaseRef{
+				{Name: "fake-cpi-release-name", URL: "file://" + cpiReleaseTarballPath},
+				{Name: "other-release", URL: "file://" + otherReleaseTarballPath},
+			},
+		}
+
+		fakeInstallationParser = fakebiinstallmanifest.NewFakeParser()
+		fakeInstallationParser.ParseManifest = biinstallmanifest.Manifest{
+			Template: biinstallmanifest.ReleaseJobRef{
+				Name:    "fake-cpi-release-job-name",
+				Release: "fake-cpi-release-name",
+			},
+		}
+
+		fakeDeploymentParser = fakebideplmanifest.NewFakeParser()
+		fakeDeploymentParser.ParseManifest = bideplmanifest.Manifest{
+			Name: "fake-deployment-name",
+			Jobs: []bideplmanifest.Job{
+				{
+					Name:      "fake-job-name",
+					Templates: []bideplmanifest.ReleaseJobRef{{Name: "not-cpi", Release: "other-release"}},
+				},
+			},
+			ResourcePools: []bideplmanifest.ResourcePool{
+				{Stemcell: bideplmanifest.StemcellRef{URL: "file://" + stemcellTarballPath}},
+			},
+		}
+
+		fakeDeploymentValidator = fakebideplmanifest.NewFakeValidator()
+		fakeDeploymentValidator.SetValidateBehavior([]fakebideplmanifest.ValidateOutput{{Err: nil}})
+		fakeDeploymentValidator.SetValidateReleaseJobsBehavior([]fakebideplmanifest.ValidateReleaseJobsOutput{{Err: nil}})
+
+		fakeCPIRelease = fakebirel.New("fake-cpi-release-name", "1.0")
+		fakeCPIRelease.ReleaseJobs = []bireljob.Job{
+			{Name: "fake-cpi-release-job-name", Templates: map[string]string{"templates/cpi.erb": "bin/cpi"}},
+		}
+		mockReleaseExtractor.EXPECT().Extract(cpiReleaseTarballPath).Return(fakeCPIRelease, nil).AnyTimes()
+
+		fakeOtherRelease = fakebirel.New("other-release", "1234")
+		fakeOtherRelease.ReleaseIsCompiled = true
+		fakeOtherRelease.ReleaseJobs = []bireljob.Job{{Name: "not-cpi"}}
+		fakeOtherRelease.ReleasePackages = []*bipkg.Package{{Stemcell: "ubuntu-trusty/fake-stemcell-version"}}
+
+		extractedStemcell := bistemcell.NewExtractedStemcell(
+			bistemcell.Manifest{
+				Name:            "fake-stemcell-name",
+				Version:         "fake-stemcell-version",
+				OS:              "ubuntu-trusty",
+				CloudProperties: biproperty.Map{},
+			},
+			"/fake-extracted-stemcell",
+			fakeFs,
+		)
+		fakeFs.MkdirAll("/fake-extracted-stemcell", 0755)
+		fakeStemcellExtractor.SetExtractBehavior(stemcellTarballPath, extractedStemcell, nil)
+	})
+
+	JustBeforeEach(func() {
+		getter := func(deploymentManifestPath string) (bicmd.ManifestValidator, error) {
+			tarballCache := bitarball.NewCache("/fake-cache", 0, fakeFs, clock.NewClock(), logger)
+			tarballProvider := bitarball.NewProvider(tarballCache, fakeFs, bitarball.NewSchemeRegistry(), nil, crypto.NewDigestCalculator(fakeFs), 1, 0, clock.NewClock(), logger)
+
+			cpiInstaller := bicpirel.CpiInstaller{
+				ReleaseManager: releaseManager,
+				Validator:      bicpirel.NewValidator(),
+			}
+			releaseFetcher := birel.NewFetcher(tarballProvider, mockReleaseExtractor, releaseManager, mock_release.NewMockDevReleaseBuilder(mockCtrl), fakebicatalog.NewFakeResolver(), fakebisignature.NewFakeVerifier(), fakeFs)
+			stemcellFetcher := bistemcell.Fetcher{
+				TarballProvider:   tarballProvider,
+				StemcellExtractor: fakeStemcellExtractor,
+				SignatureVerifier: fakebisignature.NewFakeVerifier(),
+			}
+
+			return bicmd.NewManifestValidator(
+				logger,
+				"validateCmd",
+				releaseManager,
+				deploymentManifestPath,
+				cpiInstaller,
+				releaseFetcher,
+				stemcellFetcher,
+				2,
+				bicmd.ReleaseSetAndInstallationManifestParser{
+					ReleaseSetParser:   fakeReleaseSetParser,
+					InstallationParser: fakeInstallationParser,
+				},
+				bicmd.DeploymentManifestParser{
+					DeploymentParser:    fakeDeploymentParser,
+					DeploymentValidator: fakeDeploymentValidator,
+					ReleaseManager:      releaseManager,
+				},
+				bicmd.NewTempRootConfigurator(fakeFs),
+				"/fake-workspace/tmp/validate",
+			), nil
+		}
+
+		command = bicmd.NewValidateCmd(fakeUI, fakeFs, logger, getter)
+	})
+
+	Context("when the manifest is valid", func() {
+		BeforeEach(func() {
+			mockReleaseExtractor.EXPECT().Extract(otherReleaseTarballPath).Return(fakeOtherRelease, nil)
+		})
+
+		It("runs every validation without writing the deployment state", func() {
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("validating"))
+			stageNames := []string{}
+			for _, call := range fakeStage.SubStages[0].PerformCalls {
+				stageNames = append(stageNames, call.Name)
+			}
+			Expect(stageNames).To(Equal([]string{
+				"Validating release set manifest",
+				"Validating installation manifest",
+				"Validating deployment manifest",
+				"Validating stemcell",
+				"Validating release 'fake-cpi-release-name'",
+				"Validating release 'other-release'",
+				"Validating cpi release",
+				"Validating deployment jobs",
+				"Validating compiled releases",
+			}))
+			Expect(fakeStage.SubStages[0].ConcurrencyLimits).To(Equal([]int{2}))
+
+			Expect(fakeUI.Said).To(ContainElement("Deployment manifest '/path/to/manifest.yml' is valid"))
+			Expect(fakeFs.FileExists(biconfig.DeploymentStatePath(deploymentManifestPath))).To(BeFalse())
+			Expect(fakeFs.TempRootPath).To(Equal("/fake-workspace/tmp/validate"))
+		})
+
+		It("deletes the extracted releases and stemcell", func() {
+			err := command.Run(fakeStage, []string{deploymentManifestPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCPIRelease.DeleteCalled).To(BeTrue())
+			Expect(fakeOtherRelease.DeleteCalled).To(BeTrue())
+			Expect(fakeFs.FileExists("/fake-extracted-stemcell")).To(BeFalse())
+		})
+	})
+
+	It("reports the problems of every section at once and exits with 1", func() {
+		mockReleaseExtractor.EXPECT().Extract(otherReleaseTarballPath).Return(fakeOtherRelease, nil)
+		fakeInstallationParser.ParseErr = bosherr.WrapError(
+			bosherr.NewMultiError(errors.New("cloud_provider.template.name must be provided")),
+			"Validating installation manifest",
+		)
+		fakeDeploymentValidator.SetValidateBehavior([]fakebideplmanifest.ValidateOutput{
+			{Err: bosherr.NewMultiError(errors.New("jobs[0].networks must be provided"), errors.New("networks[0].name must be provided"))},
+		})
+		fakeDeploymentValidator.SetValidateReleaseJobsBehavior([]fakebideplmanifest.ValidateReleaseJobsOutput{
+			{Err: bosherr.NewMultiError(errors.New("jobs[0].templates[0] must refer to a job in 'other-release'"))},
+		})
+		fakeOtherRelease.ReleasePackages = []*bipkg.Package{{Stemcell: "ubuntu-trusty/wrong-version"}}
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(1))
+		Expect(err.Error()).To(ContainSubstring("Found 4 problems in deployment manifest '/path/to/manifest.yml'"))
+		Expect(err.Error()).To(ContainSubstring("cloud_provider.template.name must be provided"))
+		Expect(err.Error()).To(ContainSubstring("jobs[0].networks must be provided\nnetworks[0].name must be provided"))
+		Expect(err.Error()).To(ContainSubstring("jobs[0].templates[0] must refer to a job in 'other-release'"))
+		Expect(err.Error()).ToNot(ContainSubstring("OS/Version mismatch"), "compiled releases are not checked without a valid installation")
+	})
+
+	It("reports compiled releases that don't match the stemcell and a compiled CPI release", func() {
+		mockReleaseExtractor.EXPECT().Extract(otherReleaseTarballPath).Return(fakeOtherRelease, nil)
+		fakeOtherRelease.ReleasePackages = []*bipkg.Package{{Stemcell: "ubuntu-trusty/wrong-version"}}
+		fakeCPIRelease.ReleaseIsCompiled = true
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(1))
+		Expect(err.Error()).To(ContainSubstring("releases[1]: OS/Version mismatch between deployment stemcell and compiled package stemcell for release 'other-release'"))
+		Expect(err.Error()).To(ContainSubstring("cloud_provider.template.release: CPI is not allowed to be a compiled release. The provided CPI release 'fake-cpi-release-name' is compiled"))
+	})
+
+	It("reports an invalid CPI release with its path", func() {
+		mockReleaseExtractor.EXPECT().Extract(otherReleaseTarballPath).Return(fakeOtherRelease, nil)
+		fakeCPIRelease.ReleaseJobs = []bireljob.Job{}
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("cloud_provider.template: Invalid CPI release 'fake-cpi-release-name': CPI release must contain specified job 'fake-cpi-release-job-name'"))
+	})
+
+	It("exits with 2 when a release can not be fetched, without validating the jobs against the releases", func() {
+		mockReleaseExtractor.EXPECT().Extract(otherReleaseTarballPath).Return(nil, errors.New("fake-extract-error"))
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(2))
+		Expect(err.Error()).To(ContainSubstring("Validating deployment manifest '/path/to/manifest.yml': Fetching releases and stemcell"))
+		Expect(err.Error()).To(ContainSubstring("fake-extract-error"))
+		Expect(fakeDeploymentValidator.ValidateReleaseJobsInputs).To(BeEmpty())
+	})
+
+	It("exits with 2 when the stemcell can not be fetched, reporting the problems found so far", func() {
+		mockReleaseExtractor.EXPECT().Extract(otherReleaseTarballPath).Return(fakeOtherRelease, nil)
+		fakeStemcellExtractor.SetExtractBehavior(stemcellTarballPath, nil, errors.New("fake-stemcell-extract-error"))
+		fakeInstallationParser.ParseErr = bosherr.WrapError(
+			bosherr.NewMultiError(errors.New("cloud_provider.template.name must be provided")),
+			"Validating installation manifest",
+		)
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(2))
+		Expect(err.Error()).To(ContainSubstring("fake-stemcell-extract-error"))
+		Expect(err.Error()).To(ContainSubstring("cloud_provider.template.name must be provided"))
+	})
+
+	It("only parses the rest of the manifest when the release set is invalid", func() {
+		fakeReleaseSetParser.ParseErr = bosherr.WrapError(
+			bosherr.NewMultiError(errors.New("releases[0].url must be provided"), errors.New("releases[1].name must be provided")),
+			"Validating release set manifest",
+		)
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(1))
+		Expect(err.Error()).To(ContainSubstring("Found 2 problems"))
+		Expect(err.Error()).To(ContainSubstring("releases[0].url must be provided\nreleases[1].name must be provided"))
+		Expect(err.Error()).ToNot(ContainSubstring("Validating release set manifest"))
+		Expect(fakeDeploymentParser.ParsePath).To(Equal(deploymentManifestPath))
+		Expect(fakeDeploymentValidator.ValidateInputs).To(BeEmpty())
+		Expect(fakeInstallationParser.ParsePath).To(BeEmpty())
+	})
+
+	It("exits with 2 when the temp root can not be set", func() {
+		fakeFs.ChangeTempRootErr = errors.New("fake-change-temp-root-error")
+
+		err := command.Run(fakeStage, []string{deploymentManifestPath})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(2))
+		Expect(err.Error()).To(Equal("Validating deployment manifest '/path/to/manifest.yml': Setting temp root: fake-change-temp-root-error"))
+	})
+
+	It("exits with 2 when the manifest does not exist", func() {
+		err := command.Run(fakeStage, []string{"/path/to/missing.yml"})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(2))
+		Expect(err.Error()).To(Equal("Deployment manifest does not exist at '/path/to/missing.yml'"))
+	})
+
+	It("exits with 2 on invalid usage", func() {
+		err := command.Run(fakeStage, []string{})
+		Expect(err).To(HaveOccurred())
+		Expect(bicmd.ExitCode(err)).To(Equal(2))
+		Expect(err.Error()).To(Equal("Invalid usage - validate command requires exactly 1 argument"))
+	})
+})
@@ -0,0 +1,74 @@
+package net
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+type DialFunc func(network, address string) (net.Conn, error)
+
+// CreateHTTPClient returns a client for an agent or blobstore endpoint.
+// When caCert is empty the server certificate is not verified.
+// When clientCert and clientKey are provided they are presented to the server (mutual TLS).
+// When dial is provided all connections are made with it instead of the environment's HTTP proxy.
+func CreateHTTPClient(caCert, clientCert, clientKey string, dial DialFunc) (*http.Client, error) {
+	var client *http.Client
+	if caCert == "" {
+		client = bihttpclient.CreateDefaultClientInsecureSkipVerify()
+	} else {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, bosherr.Error("Parsing CA certificate: no PEM encoded certificates found")
+		}
+		client = bihttpclient.CreateDefaultClient(certPool)
+	}
+
+	transport := client.Transport.(*http.Transport)
+	if dial != nil {
+		transport.Proxy = nil
+		transport.Dial = dial
+	}
+
+	if clientCert != "" || clientKey != "" {
+		certificate, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Parsing client certificate and key")
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if caCert != "" {
+		client.Transport = certificateErrorTransport{transport: transport}
+	}
+
+	return client, nil
+}
+
+type certificateErrorTransport struct {
+	transport http.RoundTripper
+}
+
+func (t certificateErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil && isCertificateError(err) {
+		return nil, bosherr.WrapErrorf(err, "Certificate presented by '%s' does not match the configured CA certificate", req.URL.Host)
+	}
+
+	return resp, err
+}
+
+func isCertificateError(err error) bool {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateInvalidErr x509.CertificateInvalidError
+
+	return errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certificateInvalidErr)
+}
@@ -0,0 +1,167 @@
+package net_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	binet "github.com/cloudfoundry/bosh-init/common/net"
+)
+
+var _ = Describe("CreateHTTPClient", func() {
+	var (
+		server   *httptest.Server
+		serverCA string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	startServer := func() {
+		server.StartTLS()
+		serverCA = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	}
+
+	It("does not verify the server certificate when no CA certificate is given", func() {
+		startServer()
+
+		client, err := binet.CreateHTTPClient("", "", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("verifies the server certificate against the CA certificate", func() {
+		startServer()
+
+		client, err := binet.CreateHTTPClient(serverCA, "", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("returns a clear error when the server certificate does not match the CA certificate", func() {
+		startServer()
+
+		otherCA, _ := generateCertificate()
+		client, err := binet.CreateHTTPClient(otherCA, "", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("does not match the configured CA certificate"))
+	})
+
+	It("presents the client certificate to the server", func() {
+		clientCert, clientKey := generateCertificate()
+		clientCAs := x509.NewCertPool()
+		Expect(clientCAs.AppendCertsFromPEM([]byte(clientCert))).To(BeTrue())
+		server.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		}
+		startServer()
+
+		client, err := binet.CreateHTTPClient(serverCA, clientCert, clientKey, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		client, err = binet.CreateHTTPClient(serverCA, "", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("makes connections with the dial function", func() {
+		startServer()
+
+		dialedAddresses := []string{}
+		dial := func(network, address string) (net.Conn, error) {
+			dialedAddresses = append(dialedAddresses, address)
+			return net.Dial(network, server.Listener.Addr().String())
+		}
+
+		client, err := binet.CreateHTTPClient(serverCA, "", "", dial)
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := client.Get(strings.Replace(server.URL, "127.0.0.1", "example.com", 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(dialedAddresses).To(HaveLen(1))
+		Expect(dialedAddresses[0]).To(HavePrefix("example.com:"))
+	})
+
+	It("uses the environment's HTTP proxy without a dial function", func() {
+		client, err := binet.CreateHTTPClient("", "", "", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.Transport.(*http.Transport).Proxy).ToNot(BeNil())
+	})
+
+	It("returns an error when the CA certificate is not PEM encoded", func() {
+		_, err := binet.CreateHTTPClient("not-a-certificate", "", "", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing CA certificate"))
+	})
+
+	It("returns an error when the client certificate and key do not form a pair", func() {
+		clientCert, _ := generateCertificate()
+		_, otherKey := generateCertificate()
+
+		_, err := binet.CreateHTTPClient("", clientCert, otherKey, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing client certificate and key"))
+	})
+})
+
+func generateCertificate() (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
@@ -4,22 +4,27 @@ import (
 	"net"
 )
 
+// LastAddress returns the last address of the network, the broadcast address of IPv4 networks
 func LastAddress(n *net.IPNet) net.IP {
-	ip := n.IP.To4()
-	if ip == nil {
-		ip = n.IP
-		return net.IP{
-			ip[0] | ^n.Mask[0], ip[1] | ^n.Mask[1], ip[2] | ^n.Mask[2],
-			ip[3] | ^n.Mask[3], ip[4] | ^n.Mask[4], ip[5] | ^n.Mask[5],
-			ip[6] | ^n.Mask[6], ip[7] | ^n.Mask[7], ip[8] | ^n.Mask[8],
-			ip[9] | ^n.Mask[9], ip[10] | ^n.Mask[10], ip[11] | ^n.Mask[11],
-			ip[12] | ^n.Mask[12], ip[13] | ^n.Mask[13], ip[14] | ^n.Mask[14],
-			ip[15] | ^n.Mask[15]}
+	ip := n.IP.To16()
+	if IsIPv4Net(n) {
+		ip = n.IP.To4()
 	}
 
-	return net.IPv4(
-		ip[0]|^n.Mask[0],
-		ip[1]|^n.Mask[1],
-		ip[2]|^n.Mask[2],
-		ip[3]|^n.Mask[3])
+	last := make(net.IP, len(ip))
+	for i := range ip {
+		last[i] = ip[i] | ^n.Mask[i]
+	}
+
+	return last.To16()
+}
+
+func IsIPv4Net(n *net.IPNet) bool {
+	return len(n.Mask) == net.IPv4len
+}
+
+// Netmask returns the mask of the network as an address, in dotted decimal notation for IPv4 networks
+// and in canonical IPv6 notation for IPv6 networks (e.g. ffff:ffff:ffff:ffff::)
+func Netmask(n *net.IPNet) string {
+	return net.IP(n.Mask).String()
 }
@@ -30,6 +30,22 @@ var _ = Describe("common.net ip helpers", func() {
 			Expect(
 				binet.LastAddress(netFor("2001:db8:1234::/48")),
 			).To(Equal(net.ParseIP("2001:db8:1234:ffff:ffff:ffff:ffff:ffff")))
+
+			Expect(
+				binet.LastAddress(netFor("::ffff:10.0.0.0/120")),
+			).To(Equal(net.ParseIP("::ffff:10.0.0.255")))
+		})
+	})
+
+	Describe("Netmask", func() {
+		It("returns the dotted decimal mask of IPv4 networks", func() {
+			Expect(binet.Netmask(netFor("10.0.0.0/22"))).To(Equal("255.255.252.0"))
+			Expect(binet.Netmask(netFor("10.0.0.0/32"))).To(Equal("255.255.255.255"))
+		})
+
+		It("returns the canonical mask of IPv6 networks", func() {
+			Expect(binet.Netmask(netFor("2001:db8:1234::/64"))).To(Equal("ffff:ffff:ffff:ffff::"))
+			Expect(binet.Netmask(netFor("2001:db8:1234::/52"))).To(Equal("ffff:ffff:ffff:f000::"))
 		})
 	})
 })
@@ -7,7 +7,7 @@ import (
 )
 
 func AbsolutifyPath(pathToManifest string, pathToFile string, fs boshsys.FileSystem) (string, error) {
-	if strings.HasPrefix(pathToFile, "http") {
+	if strings.HasPrefix(pathToFile, "http") || strings.HasPrefix(pathToFile, "s3://") {
 		return pathToFile, nil
 	}
 
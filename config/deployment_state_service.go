@@ -5,16 +5,21 @@ import (
 )
 
 type DeploymentState struct {
-	DirectorID          string           `json:"director_id"`
-	InstallationID      string           `json:"installation_id"`
-	CurrentVMCID        string           `json:"current_vm_cid"`
-	CurrentStemcellID   string           `json:"current_stemcell_id"`
-	CurrentDiskID       string           `json:"current_disk_id"`
-	CurrentReleaseIDs   []string         `json:"current_release_ids"`
-	CurrentManifestSHA1 string           `json:"current_manifest_sha1"`
-	Disks               []DiskRecord     `json:"disks"`
-	Stemcells           []StemcellRecord `json:"stemcells"`
-	Releases            []ReleaseRecord  `json:"releases"`
+	DirectorID          string               `json:"director_id"`
+	InstallationID      string               `json:"installation_id"`
+	CurrentVMCID        string               `json:"current_vm_cid"`
+	CurrentStemcellID   string               `json:"current_stemcell_id"`
+	CurrentDiskID       string               `json:"current_disk_id"`
+	CurrentReleaseIDs   []string             `json:"current_release_ids"`
+	CurrentManifestSHA1 string               `json:"current_manifest_sha1"`
+	Disks               []DiskRecord         `json:"disks"`
+	Stemcells           []StemcellRecord     `json:"stemcells"`
+	Releases            []ReleaseRecord      `json:"releases"`
+	HostKeys            []HostKeyRecord      `json:"host_keys,omitempty"`
+	IPAllocations       []IPAllocationRecord `json:"ip_allocations,omitempty"`
+	ResolvedRefs        []ResolvedRefRecord  `json:"resolved_refs,omitempty"`
+	Signatures          []SignatureRecord    `json:"signatures,omitempty"`
+	Tarballs            []TarballRecord      `json:"tarballs,omitempty"`
 }
 
 type StemcellRecord struct {
@@ -37,6 +42,44 @@ type ReleaseRecord struct {
 	Version string `json:"version"`
 }
 
+type IPAllocationRecord struct {
+	Job     string `json:"job"`
+	Network string `json:"network"`
+	IP      string `json:"ip"`
+}
+
+type ResolvedRefRecord struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	RequestedVersion string `json:"requested_version"`
+	Version          string `json:"version"`
+	URL              string `json:"url"`
+	SHA1             string `json:"sha1"`
+}
+
+// TarballRecord is the source of a release or stemcell tarball of the current deployment
+type TarballRecord struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	SHA1 string `json:"sha1"`
+}
+
+// SignatureRecord is the signer whose signature of a release or stemcell tarball was verified
+type SignatureRecord struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	SHA1      string `json:"sha1"`
+	Signature string `json:"signature"`
+	Signer    string `json:"signer"`
+}
+
+type HostKeyRecord struct {
+	Host        string `json:"host"`
+	Fingerprint string `json:"fingerprint"`
+}
+
 type DeploymentStateService interface {
 	Path() string
 	Exists() bool
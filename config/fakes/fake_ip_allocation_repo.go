@@ -0,0 +1,29 @@
+package fakes
+
+import (
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+)
+
+type FakeIPAllocationRepo struct {
+	Allocations map[string]string
+	FindErr     error
+
+	UpdateCurrentRecords []biconfig.IPAllocationRecord
+	UpdateCurrentErr     error
+}
+
+func NewFakeIPAllocationRepo() *FakeIPAllocationRepo {
+	return &FakeIPAllocationRepo{
+		Allocations: map[string]string{},
+	}
+}
+
+func (r *FakeIPAllocationRepo) Find(jobName, networkName string) (string, bool, error) {
+	ip, found := r.Allocations[jobName+"/"+networkName]
+	return ip, found, r.FindErr
+}
+
+func (r *FakeIPAllocationRepo) UpdateCurrent(records []biconfig.IPAllocationRecord) error {
+	r.UpdateCurrentRecords = records
+	return r.UpdateCurrentErr
+}
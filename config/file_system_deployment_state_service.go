@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -13,6 +14,8 @@ import (
 )
 
 type fileSystemDeploymentStateService struct {
+	// lock serializes the reads and writes of the deployment state file, which the repos load and save from concurrent stages
+	lock          sync.Mutex
 	configPath    string
 	fs            boshsys.FileSystem
 	uuidGenerator boshuuid.Generator
@@ -44,6 +47,9 @@ func (s *fileSystemDeploymentStateService) Exists() bool {
 }
 
 func (s *fileSystemDeploymentStateService) Load() (DeploymentState, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	if s.configPath == "" {
 		panic("configPath not yet set!")
 	}
@@ -74,6 +80,13 @@ func (s *fileSystemDeploymentStateService) Load() (DeploymentState, error) {
 }
 
 func (s *fileSystemDeploymentStateService) Save(deploymentState DeploymentState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.save(deploymentState)
+}
+
+func (s *fileSystemDeploymentStateService) save(deploymentState DeploymentState) error {
 	if s.configPath == "" {
 		panic("configPath not yet set!")
 	}
@@ -101,7 +114,7 @@ func (s *fileSystemDeploymentStateService) initDefaults(deploymentState *Deploym
 		}
 		deploymentState.DirectorID = uuid
 
-		err = s.Save(*deploymentState)
+		err = s.save(*deploymentState)
 		if err != nil {
 			return bosherr.WrapError(err, "Saving deployment state")
 		}
@@ -111,6 +124,9 @@ func (s *fileSystemDeploymentStateService) initDefaults(deploymentState *Deploym
 }
 
 func (s *fileSystemDeploymentStateService) Cleanup() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	err := s.fs.RemoveAll(s.configPath)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Could not delete deployment state file %s", s.configPath)
@@ -0,0 +1,62 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// HostKeyRepo records the SSH host key fingerprints trusted on first use, by host address.
+type HostKeyRepo interface {
+	Find(host string) (fingerprint string, found bool, err error)
+	Save(host, fingerprint string) error
+}
+
+type hostKeyRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewHostKeyRepo(deploymentStateService DeploymentStateService) HostKeyRepo {
+	return hostKeyRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r hostKeyRepo) Find(host string) (string, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	for _, record := range deploymentState.HostKeys {
+		if record.Host == host {
+			return record.Fingerprint, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func (r hostKeyRepo) Save(host, fingerprint string) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	records := []HostKeyRecord{}
+	for _, record := range deploymentState.HostKeys {
+		if record.Host != host {
+			records = append(records, record)
+		}
+	}
+
+	deploymentState.HostKeys = append(records, HostKeyRecord{
+		Host:        host,
+		Fingerprint: fingerprint,
+	})
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HostKeyRepo", func() {
+	var (
+		repo                   HostKeyRepo
+		deploymentStateService DeploymentStateService
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		repo = NewHostKeyRepo(deploymentStateService)
+	})
+
+	Describe("Save", func() {
+		It("records the fingerprint for the host", func() {
+			err := repo.Save("10.0.0.6:22", "fake-fingerprint")
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.HostKeys).To(Equal([]HostKeyRecord{
+				{Host: "10.0.0.6:22", Fingerprint: "fake-fingerprint"},
+			}))
+		})
+
+		It("replaces the previous fingerprint for the host", func() {
+			err := repo.Save("10.0.0.6:22", "fake-old-fingerprint")
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Save("10.0.0.7:22", "fake-other-fingerprint")
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Save("10.0.0.6:22", "fake-fingerprint")
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.HostKeys).To(Equal([]HostKeyRecord{
+				{Host: "10.0.0.7:22", Fingerprint: "fake-other-fingerprint"},
+				{Host: "10.0.0.6:22", Fingerprint: "fake-fingerprint"},
+			}))
+		})
+	})
+
+	Describe("Find", func() {
+		It("returns the fingerprint recorded for the host", func() {
+			err := repo.Save("10.0.0.6:22", "fake-fingerprint")
+			Expect(err).ToNot(HaveOccurred())
+
+			fingerprint, found, err := repo.Find("10.0.0.6:22")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(fingerprint).To(Equal("fake-fingerprint"))
+		})
+
+		It("returns false when no fingerprint is recorded for the host", func() {
+			_, found, err := repo.Find("10.0.0.6:22")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("returns the fingerprint recorded for the host after the vm changed", func() {
+			err := repo.Save("10.0.0.6:22", "fake-fingerprint")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = NewVMRepo(deploymentStateService).UpdateCurrent("fake-new-vm-cid")
+			Expect(err).ToNot(HaveOccurred())
+
+			fingerprint, found, err := repo.Find("10.0.0.6:22")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(fingerprint).To(Equal("fake-fingerprint"))
+		})
+	})
+})
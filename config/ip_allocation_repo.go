@@ -0,0 +1,55 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// IPAllocationRepo records the address of each job network on manual networks,
+// so that redeploys keep the same IP.
+type IPAllocationRepo interface {
+	Find(jobName, networkName string) (ip string, found bool, err error)
+	UpdateCurrent(records []IPAllocationRecord) error
+}
+
+type ipAllocationRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewIPAllocationRepo(deploymentStateService DeploymentStateService) IPAllocationRepo {
+	return ipAllocationRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r ipAllocationRepo) Find(jobName, networkName string) (string, bool, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Loading existing config")
+	}
+
+	for _, record := range deploymentState.IPAllocations {
+		if record.Job == jobName && record.Network == networkName {
+			return record.IP, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// UpdateCurrent replaces the recorded addresses with those of the current deployment,
+// releasing the addresses of job networks that are no longer deployed
+func (r ipAllocationRepo) UpdateCurrent(records []IPAllocationRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.IPAllocations = records
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
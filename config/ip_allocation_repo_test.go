@@ -0,0 +1,67 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IPAllocationRepo", func() {
+	var (
+		repo                   IPAllocationRepo
+		deploymentStateService DeploymentStateService
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		repo = NewIPAllocationRepo(deploymentStateService)
+	})
+
+	Describe("UpdateCurrent", func() {
+		It("replaces the recorded addresses", func() {
+			err := repo.UpdateCurrent([]IPAllocationRecord{
+				{Job: "fake-job", Network: "fake-network", IP: "10.0.0.6"},
+				{Job: "fake-job", Network: "fake-other-network", IP: "10.0.1.6"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.UpdateCurrent([]IPAllocationRecord{
+				{Job: "fake-job", Network: "fake-network", IP: "10.0.0.7"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.IPAllocations).To(Equal([]IPAllocationRecord{
+				{Job: "fake-job", Network: "fake-network", IP: "10.0.0.7"},
+			}))
+		})
+	})
+
+	Describe("Find", func() {
+		BeforeEach(func() {
+			err := repo.UpdateCurrent([]IPAllocationRecord{
+				{Job: "fake-job", Network: "fake-network", IP: "10.0.0.6"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the address recorded for the job network", func() {
+			ip, found, err := repo.Find("fake-job", "fake-network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(ip).To(Equal("10.0.0.6"))
+		})
+
+		It("returns false when no address is recorded for the job network", func() {
+			_, found, err := repo.Find("fake-job", "fake-other-network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,60 @@
+package config
+
+import (
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// ResolvedRefRepo records the tarballs that the release and stemcell versions of the manifest resolved to in the index
+type ResolvedRefRepo interface {
+	List() ([]ResolvedRefRecord, error)
+	Save(refType, requestedVersion string, entry bicatalog.Entry) error
+}
+
+type resolvedRefRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewResolvedRefRepo(deploymentStateService DeploymentStateService) ResolvedRefRepo {
+	return resolvedRefRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r resolvedRefRepo) List() ([]ResolvedRefRecord, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return []ResolvedRefRecord{}, bosherr.WrapError(err, "Loading existing config")
+	}
+	return deploymentState.ResolvedRefs, nil
+}
+
+func (r resolvedRefRepo) Save(refType, requestedVersion string, entry bicatalog.Entry) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	records := []ResolvedRefRecord{}
+	for _, record := range deploymentState.ResolvedRefs {
+		if record.Type != refType || record.Name != entry.Name {
+			records = append(records, record)
+		}
+	}
+
+	deploymentState.ResolvedRefs = append(records, ResolvedRefRecord{
+		Type:             refType,
+		Name:             entry.Name,
+		RequestedVersion: requestedVersion,
+		Version:          entry.Version,
+		URL:              entry.URL,
+		SHA1:             entry.SHA1,
+	})
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
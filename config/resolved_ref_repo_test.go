@@ -0,0 +1,55 @@
+package config_test
+
+import (
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
+	. "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolvedRefRepo", func() {
+	var (
+		repo                   ResolvedRefRepo
+		deploymentStateService DeploymentStateService
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		repo = NewResolvedRefRepo(deploymentStateService)
+	})
+
+	Describe("Save", func() {
+		It("records the resolved tarball in the deployment state", func() {
+			err := repo.Save("release", "latest", bicatalog.Entry{Name: "fake-name", Version: "2", URL: "fake-url", SHA1: "fake-sha1"})
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.ResolvedRefs).To(Equal([]ResolvedRefRecord{
+				{Type: "release", Name: "fake-name", RequestedVersion: "latest", Version: "2", URL: "fake-url", SHA1: "fake-sha1"},
+			}))
+		})
+
+		It("replaces the record of the same release or stemcell", func() {
+			err := repo.Save("release", "1", bicatalog.Entry{Name: "fake-name", Version: "1", URL: "fake-url-1", SHA1: "fake-sha1-1"})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Save("stemcell", "1", bicatalog.Entry{Name: "fake-name", Version: "1", URL: "fake-stemcell-url", SHA1: "fake-stemcell-sha1"})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Save("release", "latest", bicatalog.Entry{Name: "fake-name", Version: "2", URL: "fake-url-2", SHA1: "fake-sha1-2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			records, err := repo.List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]ResolvedRefRecord{
+				{Type: "stemcell", Name: "fake-name", RequestedVersion: "1", Version: "1", URL: "fake-stemcell-url", SHA1: "fake-stemcell-sha1"},
+				{Type: "release", Name: "fake-name", RequestedVersion: "latest", Version: "2", URL: "fake-url-2", SHA1: "fake-sha1-2"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,51 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// SignatureRepo records the signers of the release and stemcell tarballs whose signatures were verified
+type SignatureRepo interface {
+	List() ([]SignatureRecord, error)
+	Save(record SignatureRecord) error
+}
+
+type signatureRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewSignatureRepo(deploymentStateService DeploymentStateService) SignatureRepo {
+	return signatureRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r signatureRepo) List() ([]SignatureRecord, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return []SignatureRecord{}, bosherr.WrapError(err, "Loading existing config")
+	}
+	return deploymentState.Signatures, nil
+}
+
+func (r signatureRepo) Save(record SignatureRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	records := []SignatureRecord{}
+	for _, existing := range deploymentState.Signatures {
+		if existing.Type != record.Type || existing.Name != record.Name {
+			records = append(records, existing)
+		}
+	}
+	deploymentState.Signatures = append(records, record)
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SignatureRepo", func() {
+	var (
+		repo                   SignatureRepo
+		deploymentStateService DeploymentStateService
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		repo = NewSignatureRepo(deploymentStateService)
+	})
+
+	Describe("Save", func() {
+		It("records the signer in the deployment state", func() {
+			record := SignatureRecord{Type: "release", Name: "fake-name", URL: "fake-url", SHA1: "fake-sha1", Signature: "fake-signature-url", Signer: "fake-signer"}
+			err := repo.Save(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Signatures).To(Equal([]SignatureRecord{record}))
+		})
+
+		It("replaces the record of the same release or stemcell", func() {
+			err := repo.Save(SignatureRecord{Type: "release", Name: "fake-name", URL: "fake-url-1", Signer: "fake-signer-1"})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Save(SignatureRecord{Type: "stemcell", Name: "fake-name", URL: "fake-stemcell-url", Signer: "fake-stemcell-signer"})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Save(SignatureRecord{Type: "release", Name: "fake-name", URL: "fake-url-2", Signer: "fake-signer-2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			records, err := repo.List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]SignatureRecord{
+				{Type: "stemcell", Name: "fake-name", URL: "fake-stemcell-url", Signer: "fake-stemcell-signer"},
+				{Type: "release", Name: "fake-name", URL: "fake-url-2", Signer: "fake-signer-2"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,47 @@
+package config
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// TarballRepo records the sources of the release and stemcell tarballs of the current deployment,
+// so that they are kept in the download cache
+type TarballRepo interface {
+	List() ([]TarballRecord, error)
+	UpdateCurrent(records []TarballRecord) error
+}
+
+type tarballRepo struct {
+	deploymentStateService DeploymentStateService
+}
+
+func NewTarballRepo(deploymentStateService DeploymentStateService) TarballRepo {
+	return tarballRepo{
+		deploymentStateService: deploymentStateService,
+	}
+}
+
+func (r tarballRepo) List() ([]TarballRecord, error) {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return []TarballRecord{}, bosherr.WrapError(err, "Loading existing config")
+	}
+	return deploymentState.Tarballs, nil
+}
+
+// UpdateCurrent replaces the recorded tarballs with those of the current deployment
+func (r tarballRepo) UpdateCurrent(records []TarballRecord) error {
+	deploymentState, err := r.deploymentStateService.Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading existing config")
+	}
+
+	deploymentState.Tarballs = records
+
+	err = r.deploymentStateService.Save(deploymentState)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving new config")
+	}
+
+	return nil
+}
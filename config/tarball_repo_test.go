@@ -0,0 +1,59 @@
+package config_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/config"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TarballRepo", func() {
+	var (
+		repo                   TarballRepo
+		deploymentStateService DeploymentStateService
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := fakesys.NewFakeFileSystem()
+		fakeUUIDGenerator := &fakeuuid.FakeGenerator{}
+		deploymentStateService = NewFileSystemDeploymentStateService(fs, fakeUUIDGenerator, logger, "/fake/path")
+		repo = NewTarballRepo(deploymentStateService)
+	})
+
+	Describe("UpdateCurrent", func() {
+		It("records the tarballs in the deployment state", func() {
+			err := repo.UpdateCurrent([]TarballRecord{
+				{Type: "release", Name: "fake-release", URL: "fake-release-url", SHA1: "fake-release-sha1"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentState, err := deploymentStateService.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentState.Tarballs).To(Equal([]TarballRecord{
+				{Type: "release", Name: "fake-release", URL: "fake-release-url", SHA1: "fake-release-sha1"},
+			}))
+		})
+
+		It("replaces the tarballs of the previous deployment", func() {
+			err := repo.UpdateCurrent([]TarballRecord{
+				{Type: "release", Name: "fake-release", URL: "fake-release-url-1", SHA1: "fake-release-sha1-1"},
+				{Type: "stemcell", Name: "fake-stemcell", URL: "fake-stemcell-url", SHA1: "fake-stemcell-sha1"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = repo.UpdateCurrent([]TarballRecord{
+				{Type: "release", Name: "fake-release", URL: "fake-release-url-2", SHA1: "fake-release-sha1-2"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			records, err := repo.List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]TarballRecord{
+				{Type: "release", Name: "fake-release", URL: "fake-release-url-2", SHA1: "fake-release-sha1-2"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	DigestAlgorithmSHA1   = "sha1"
+	DigestAlgorithmSHA256 = "sha256"
+	DigestAlgorithmSHA512 = "sha512"
+)
+
+// digestAlgorithms are the supported algorithms, from the weakest to the strongest, with the hex length of their digests
+var digestAlgorithms = []struct {
+	name      string
+	hexLength int
+}{
+	{DigestAlgorithmSHA1, 40},
+	{DigestAlgorithmSHA256, 64},
+	{DigestAlgorithmSHA512, 128},
+}
+
+// Digest is the hex checksum of a file calculated with an algorithm
+type Digest struct {
+	Algorithm string
+	Value     string
+}
+
+// String formats the digest as 'algorithm:value', or as the bare value for SHA1
+func (d Digest) String() string {
+	if d.Algorithm == DigestAlgorithmSHA1 {
+		return d.Value
+	}
+	return d.Algorithm + ":" + d.Value
+}
+
+// MultipleDigest is a set of digests of the same file, written as 'sha1:...;sha256:...'.
+// A digest without an algorithm prefix is a SHA1.
+type MultipleDigest []Digest
+
+func ParseMultipleDigest(multipleDigest string) (MultipleDigest, error) {
+	digests := MultipleDigest{}
+	for _, piece := range strings.Split(multipleDigest, ";") {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			continue
+		}
+
+		digest, err := parseDigest(piece)
+		if err != nil {
+			return MultipleDigest{}, err
+		}
+		digests = append(digests, digest)
+	}
+
+	if len(digests) == 0 {
+		return MultipleDigest{}, bosherr.Error("Digest is empty")
+	}
+
+	return digests, nil
+}
+
+func parseDigest(digest string) (Digest, error) {
+	pieces := strings.SplitN(digest, ":", 2)
+	if len(pieces) == 1 {
+		// bare SHA1s predate algorithm prefixes and are accepted unchanged
+		return Digest{Algorithm: DigestAlgorithmSHA1, Value: digest}, nil
+	}
+
+	algorithm := strings.ToLower(pieces[0])
+	value := strings.ToLower(pieces[1])
+
+	strength := digestStrength(algorithm)
+	if strength < 0 {
+		return Digest{}, bosherr.Errorf("Unsupported digest algorithm '%s' in '%s', must be one of sha1, sha256 or sha512", pieces[0], digest)
+	}
+
+	_, err := hex.DecodeString(value)
+	if err != nil || len(value) != digestAlgorithms[strength].hexLength {
+		return Digest{}, bosherr.Errorf("Digest '%s' must be %d hex characters", digest, digestAlgorithms[strength].hexLength)
+	}
+
+	return Digest{Algorithm: algorithm, Value: value}, nil
+}
+
+// Strongest returns the digest calculated with the strongest algorithm
+func (m MultipleDigest) Strongest() Digest {
+	strongest := m[0]
+	for _, digest := range m[1:] {
+		if digestStrength(digest.Algorithm) > digestStrength(strongest.Algorithm) {
+			strongest = digest
+		}
+	}
+	return strongest
+}
+
+// Verify checks that every digest matches the file at filePath
+func (m MultipleDigest) Verify(calculator DigestCalculator, filePath string) error {
+	for _, expected := range m {
+		actual, err := calculator.Calculate(filePath, expected.Algorithm)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Calculating %s of '%s'", expected.Algorithm, filePath)
+		}
+
+		if actual.Value != expected.Value {
+			return DigestMismatchError{Expected: expected, Actual: actual}
+		}
+	}
+
+	return nil
+}
+
+// DigestMismatchError is returned by Verify when the file does not have the expected digest
+type DigestMismatchError struct {
+	Expected Digest
+	Actual   Digest
+}
+
+func (e DigestMismatchError) Error() string {
+	algorithm := strings.ToUpper(e.Expected.Algorithm)
+	return fmt.Sprintf("%s '%s' does not match expected %s '%s'", algorithm, e.Actual.Value, algorithm, e.Expected.Value)
+}
+
+func (m MultipleDigest) String() string {
+	digests := make([]string, len(m))
+	for i, digest := range m {
+		digests[i] = digest.String()
+	}
+	return strings.Join(digests, ";")
+}
+
+func digestStrength(algorithm string) int {
+	for i, digestAlgorithm := range digestAlgorithms {
+		if digestAlgorithm.name == algorithm {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type DigestCalculator interface {
+	Calculate(filePath string, algorithm string) (Digest, error)
+}
+
+type digestCalculator struct {
+	fs boshsys.FileSystem
+}
+
+func NewDigestCalculator(fs boshsys.FileSystem) DigestCalculator {
+	return digestCalculator{
+		fs: fs,
+	}
+}
+
+// Calculate returns the digest of the file, or of the files in the directory, at filePath
+func (c digestCalculator) Calculate(filePath string, algorithm string) (Digest, error) {
+	var h hash.Hash
+	switch algorithm {
+	case DigestAlgorithmSHA1:
+		h = sha1.New()
+	case DigestAlgorithmSHA256:
+		h = sha256.New()
+	case DigestAlgorithmSHA512:
+		h = sha512.New()
+	default:
+		return Digest{}, bosherr.Errorf("Unsupported digest algorithm '%s'", algorithm)
+	}
+
+	file, err := c.fs.OpenFile(filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return Digest{}, bosherr.WrapErrorf(err, "Calculating %s of '%s'", algorithm, filePath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return Digest{}, bosherr.WrapErrorf(err, "Opening file '%s' for %s calculation", filePath, algorithm)
+	}
+
+	if fileInfo.IsDir() {
+		err = c.fs.Walk(filePath+"/", func(path string, info os.FileInfo, err error) error {
+			if !info.IsDir() {
+				err := c.populateHash(path, h)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Calculating directory %s for %s", algorithm, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return Digest{}, err
+		}
+	} else {
+		err = c.populateHash(filePath, h)
+		if err != nil {
+			return Digest{}, bosherr.WrapErrorf(err, "Calculating file %s for %s", algorithm, filePath)
+		}
+	}
+
+	return Digest{Algorithm: algorithm, Value: fmt.Sprintf("%x", h.Sum(nil))}, nil
+}
+
+func (c digestCalculator) populateHash(filePath string, hash hash.Hash) error {
+	file, err := c.fs.OpenFile(filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening file '%s' for digest calculation", filePath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return bosherr.WrapError(err, "Copying file for digest calculation")
+	}
+
+	return nil
+}
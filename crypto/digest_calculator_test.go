@@ -0,0 +1,50 @@
+package crypto_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/crypto"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DigestCalculator", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		digestCalculator DigestCalculator
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		digestCalculator = NewDigestCalculator(fs)
+	})
+
+	Describe("Calculate", func() {
+		BeforeEach(func() {
+			fs.RegisterOpenFile("/fake-archive-path", &fakesys.FakeFile{
+				Contents: []byte("fake-archive-contents"),
+				Stats:    &fakesys.FakeFileStats{FileType: fakesys.FakeFileTypeFile},
+			})
+		})
+
+		It("returns the sha256 of the file", func() {
+			digest, err := digestCalculator.Calculate("/fake-archive-path", "sha256")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).To(Equal(Digest{
+				Algorithm: "sha256",
+				Value:     "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7",
+			}))
+		})
+
+		It("returns the sha512 of the file", func() {
+			digest, err := digestCalculator.Calculate("/fake-archive-path", "sha512")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest.Value).To(Equal("35cd3869d1b89be514442bd4acc7594097f904496365a8e4c1dadfd0f2b1f89b11f4bb9f9a201b7dbc5020fdb9caec1035907505331312e7f64b5dbf18b3feb2"))
+		})
+
+		It("returns an error when the algorithm is not supported", func() {
+			_, err := digestCalculator.Calculate("/fake-archive-path", "md5")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Unsupported digest algorithm 'md5'"))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package crypto_test
+
+import (
+	"errors"
+
+	. "github.com/cloudfoundry/bosh-init/crypto"
+	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MultipleDigest", func() {
+	const (
+		sha1Value   = "4603db250d7b5b78dfe17869649784353177b549"
+		sha256Value = "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7"
+	)
+
+	Describe("ParseMultipleDigest", func() {
+		It("parses a bare SHA1", func() {
+			digest, err := ParseMultipleDigest("fake-sha1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).To(Equal(MultipleDigest{{Algorithm: "sha1", Value: "fake-sha1"}}))
+		})
+
+		It("parses digests with an algorithm prefix", func() {
+			digest, err := ParseMultipleDigest("sha1:" + sha1Value + "; SHA256:" + sha256Value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).To(Equal(MultipleDigest{
+				{Algorithm: "sha1", Value: sha1Value},
+				{Algorithm: "sha256", Value: sha256Value},
+			}))
+		})
+
+		It("returns an error when the digest is empty", func() {
+			_, err := ParseMultipleDigest(" ; ")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Digest is empty"))
+		})
+
+		It("returns an error when the algorithm is not supported", func() {
+			_, err := ParseMultipleDigest("md5:fake-md5")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported digest algorithm 'md5'"))
+		})
+
+		It("returns an error when the value does not have the length of the algorithm", func() {
+			_, err := ParseMultipleDigest("sha256:" + sha1Value)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be 64 hex characters"))
+		})
+	})
+
+	It("formats SHA1s bare and other digests with their algorithm", func() {
+		digest := MultipleDigest{
+			{Algorithm: "sha1", Value: sha1Value},
+			{Algorithm: "sha256", Value: sha256Value},
+		}
+		Expect(digest.String()).To(Equal(sha1Value + ";sha256:" + sha256Value))
+	})
+
+	It("returns the strongest digest", func() {
+		digest := MultipleDigest{
+			{Algorithm: "sha256", Value: sha256Value},
+			{Algorithm: "sha1", Value: sha1Value},
+		}
+		Expect(digest.Strongest()).To(Equal(Digest{Algorithm: "sha256", Value: sha256Value}))
+	})
+
+	Describe("Verify", func() {
+		var (
+			digestCalculator *fakebicrypto.FakeDigestCalculator
+			digest           MultipleDigest
+		)
+
+		BeforeEach(func() {
+			digestCalculator = fakebicrypto.NewFakeDigestCalculator()
+			digest = MultipleDigest{{Algorithm: "sha256", Value: sha256Value}}
+		})
+
+		It("succeeds when every digest matches", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				"/fake-path": {Value: sha256Value},
+			})
+			Expect(digest.Verify(digestCalculator, "/fake-path")).To(Succeed())
+		})
+
+		It("returns an error when a digest does not match", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				"/fake-path": {Value: "fake-other-sha256"},
+			})
+			err := digest.Verify(digestCalculator, "/fake-path")
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(Equal(DigestMismatchError{
+				Expected: Digest{Algorithm: "sha256", Value: sha256Value},
+				Actual:   Digest{Algorithm: "sha256", Value: "fake-other-sha256"},
+			}))
+			Expect(err.Error()).To(Equal("SHA256 'fake-other-sha256' does not match expected SHA256 '" + sha256Value + "'"))
+		})
+
+		It("returns an error when calculating a digest fails", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				"/fake-path": {Err: errors.New("fake-calculate-error")},
+			})
+			err := digest.Verify(digestCalculator, "/fake-path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-calculate-error"))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package fakes
+
+import (
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+)
+
+type FakeDigestCalculator struct {
+	calculateInputs map[string]CalculateDigestInput
+}
+
+func NewFakeDigestCalculator() *FakeDigestCalculator {
+	return &FakeDigestCalculator{}
+}
+
+// CalculateDigestInput is the value calculated for a path with any algorithm
+type CalculateDigestInput struct {
+	Value string
+	Err   error
+}
+
+func (c *FakeDigestCalculator) Calculate(path string, algorithm string) (bicrypto.Digest, error) {
+	calculateInput := c.calculateInputs[path]
+	return bicrypto.Digest{Algorithm: algorithm, Value: calculateInput.Value}, calculateInput.Err
+}
+
+func (c *FakeDigestCalculator) SetCalculateBehavior(calculateInputs map[string]CalculateDigestInput) {
+	c.calculateInputs = calculateInputs
+}
@@ -24,6 +24,7 @@ type Deployer interface {
 		biinstallmanifest.Registry,
 		bivm.Manager,
 		biblobstore.Blobstore,
+		biinstance.DrainOptions,
 		biui.Stage,
 	) (Deployment, error)
 }
@@ -32,6 +33,7 @@ type deployer struct {
 	vmManagerFactory       bivm.ManagerFactory
 	instanceManagerFactory biinstance.ManagerFactory
 	deploymentFactory      Factory
+	ipam                   bideplmanifest.IPAM
 	logger                 boshlog.Logger
 	logTag                 string
 }
@@ -40,12 +42,14 @@ func NewDeployer(
 	vmManagerFactory bivm.ManagerFactory,
 	instanceManagerFactory biinstance.ManagerFactory,
 	deploymentFactory Factory,
+	ipam bideplmanifest.IPAM,
 	logger boshlog.Logger,
 ) Deployer {
 	return &deployer{
 		vmManagerFactory:       vmManagerFactory,
 		instanceManagerFactory: instanceManagerFactory,
 		deploymentFactory:      deploymentFactory,
+		ipam:                   ipam,
 		logger:                 logger,
 		logTag:                 "deployer",
 	}
@@ -58,13 +62,19 @@ func (d *deployer) Deploy(
 	registryConfig biinstallmanifest.Registry,
 	vmManager bivm.Manager,
 	blobstore biblobstore.Blobstore,
+	drainOptions biinstance.DrainOptions,
 	deployStage biui.Stage,
 ) (Deployment, error) {
+	deploymentManifest, err := d.ipam.AllocateIPs(deploymentManifest)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Allocating IP addresses")
+	}
+
 	instanceManager := d.instanceManagerFactory.NewManager(cloud, vmManager, blobstore)
 
 	pingTimeout := 10 * time.Second
 	pingDelay := 500 * time.Millisecond
-	if err := instanceManager.DeleteAll(pingTimeout, pingDelay, deployStage); err != nil {
+	if err := instanceManager.DeleteAll(pingTimeout, pingDelay, drainOptions, deployStage); err != nil {
 		return nil, err
 	}
 
@@ -73,6 +83,11 @@ func (d *deployer) Deploy(
 		return nil, err
 	}
 
+	err = d.ipam.SaveAllocations(deploymentManifest)
+	if err != nil {
+		return nil, err
+	}
+
 	stemcells := []bistemcell.CloudStemcell{cloudStemcell}
 	return d.deploymentFactory.NewDeployment(instances, disks, stemcells), nil
 }
@@ -87,11 +102,12 @@ func (d *deployer) createAllInstances(
 	instances := []biinstance.Instance{}
 	disks := []bidisk.Disk{}
 
-	if len(deploymentManifest.Jobs) != 1 {
-		return instances, disks, bosherr.Errorf("There must only be one job, found %d", len(deploymentManifest.Jobs))
+	serviceJobs := deploymentManifest.ServiceJobs()
+	if len(serviceJobs) != 1 {
+		return instances, disks, bosherr.Errorf("There must only be one service job (errand jobs aside), found %d", len(serviceJobs))
 	}
 
-	for _, jobSpec := range deploymentManifest.Jobs {
+	for _, jobSpec := range serviceJobs {
 		if jobSpec.Instances != 1 {
 			return instances, disks, bosherr.Errorf("Job '%s' must have only one instance, found %d", jobSpec.Name, jobSpec.Instances)
 		}
@@ -6,7 +6,6 @@ import (
 
 	. "github.com/cloudfoundry/bosh-init/deployment"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_instance_state "github.com/cloudfoundry/bosh-init/deployment/instance/state/mocks"
@@ -14,6 +13,7 @@ import (
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
 
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
@@ -50,7 +50,7 @@ var _ = Describe("Deployer", func() {
 		mockVMManagerFactory   *mock_vm.MockManagerFactory
 		fakeVMManager          *fakebivm.FakeManager
 		mockAgentClient        *mock_agentclient.MockAgentClient
-		mockAgentClientFactory *mock_httpagent.MockAgentClientFactory
+		mockAgentClientFactory *mock_agentclient.MockAgentClientFactory
 		fakeSSHTunnelFactory   *fakebisshtunnel.FakeFactory
 		fakeSSHTunnel          *fakebisshtunnel.FakeTunnel
 		cloud                  *fakebicloud.FakeCloud
@@ -69,6 +69,10 @@ var _ = Describe("Deployer", func() {
 		mockState               *mock_instance_state.MockState
 
 		mockBlobstore *mock_blobstore.MockBlobstore
+
+		drainOptions biinstance.DrainOptions
+
+		fakeIPAllocationRepo *fakebiconfig.FakeIPAllocationRepo
 	)
 
 	BeforeEach(func() {
@@ -109,9 +113,9 @@ var _ = Describe("Deployer", func() {
 
 		cloud = fakebicloud.NewFakeCloud()
 
-		mockAgentClientFactory = mock_httpagent.NewMockAgentClientFactory(mockCtrl)
+		mockAgentClientFactory = mock_agentclient.NewMockAgentClientFactory(mockCtrl)
 		mockAgentClient = mock_agentclient.NewMockAgentClient(mockCtrl)
-		mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), gomock.Any()).Return(mockAgentClient).AnyTimes()
+		mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockAgentClient).AnyTimes()
 
 		mockVMManagerFactory = mock_vm.NewMockManagerFactory(mockCtrl)
 		fakeVMManager = fakebivm.NewFakeManager()
@@ -146,7 +150,7 @@ var _ = Describe("Deployer", func() {
 		mockStateBuilder = mock_instance_state.NewMockBuilder(mockCtrl)
 		mockState = mock_instance_state.NewMockState(mockCtrl)
 
-		instanceFactory := biinstance.NewFactory(mockStateBuilderFactory)
+		instanceFactory := biinstance.NewFactory(mockStateBuilderFactory, clock.NewClock())
 		instanceManagerFactory := biinstance.NewManagerFactory(fakeSSHTunnelFactory, instanceFactory, logger)
 
 		mockBlobstore = mock_blobstore.NewMockBlobstore(mockCtrl)
@@ -155,10 +159,12 @@ var _ = Describe("Deployer", func() {
 		pingDelay := 500 * time.Millisecond
 		deploymentFactory := NewFactory(pingTimeout, pingDelay)
 
+		fakeIPAllocationRepo = fakebiconfig.NewFakeIPAllocationRepo()
 		deployer = NewDeployer(
 			mockVMManagerFactory,
 			instanceManagerFactory,
 			deploymentFactory,
+			bideplmanifest.NewIPAM(fakeIPAllocationRepo, logger),
 			logger,
 		)
 	})
@@ -191,13 +197,15 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("deletes existing vm", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeExistingVM.DeleteCalled).To(Equal(1))
+			Expect(fakeExistingVM.DrainInputs).To(Equal([]fakebivm.DrainInput{{DrainType: "shutdown"}}))
 
-			Expect(fakeStage.PerformCalls[:3]).To(Equal([]*fakebiui.PerformCall{
+			Expect(fakeStage.PerformCalls[:4]).To(Equal([]*fakebiui.PerformCall{
 				{Name: "Waiting for the agent on VM 'existing-vm-cid'"},
+				{Name: "Draining jobs on instance 'unknown/0'"},
 				{Name: "Stopping jobs on instance 'unknown/0'"},
 				{Name: "Deleting VM 'existing-vm-cid'"},
 			}))
@@ -205,7 +213,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("creates a vm", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVMManager.CreateInput).To(Equal(fakebivm.CreateInput{
@@ -232,7 +240,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("starts the SSH tunnel", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeSSHTunnel.Started).To(BeTrue())
 			Expect(fakeSSHTunnelFactory.NewSSHTunnelOptions).To(Equal(bisshtunnel.Options{
@@ -243,6 +251,7 @@ var _ = Describe("Deployer", func() {
 				Port:              124,
 				LocalForwardPort:  123,
 				RemoteForwardPort: 123,
+				HostKey:           bisshtunnel.HostKeyOptions{HostRecreated: true},
 			}))
 		})
 
@@ -252,7 +261,7 @@ var _ = Describe("Deployer", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+				_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-ssh-tunnel-start-error"))
 			})
@@ -260,7 +269,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("waits for the vm", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(fakeVM.WaitUntilReadyInputs).To(ContainElement(fakebivm.WaitUntilReadyInput{
 			Timeout: 10 * time.Minute,
@@ -269,7 +278,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("logs start and stop events to the eventLogger", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeStage.PerformCalls[1]).To(Equal(&fakebiui.PerformCall{
@@ -287,7 +296,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("logs start and stop events to the eventLogger", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-wait-error"))
 
@@ -299,7 +308,7 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("updates the vm", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVM.ApplyInputs).To(Equal([]fakebivm.ApplyInput{
@@ -309,14 +318,14 @@ var _ = Describe("Deployer", func() {
 	})
 
 	It("starts the agent", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVM.StartCalled).To(Equal(1))
 	})
 
 	It("waits until agent reports state as running", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeVM.WaitToBeRunningInputs).To(ContainElement(fakebivm.WaitInput{
@@ -325,19 +334,26 @@ var _ = Describe("Deployer", func() {
 		}))
 	})
 
+	It("saves the allocated IP addresses once the instances are running", func() {
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeIPAllocationRepo.UpdateCurrentRecords).To(Equal([]biconfig.IPAllocationRecord{}))
+	})
+
 	Context("when the deployment has an invalid disk pool specification", func() {
 		BeforeEach(func() {
 			deploymentManifest.Jobs[0].PersistentDiskPool = "fake-non-existent-persistent-disk-pool-name"
 		})
 
 		It("returns an error", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).To(HaveOccurred())
 		})
 	})
 
 	It("logs instance update ui stages", func() {
-		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+		_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeStage.PerformCalls[2:4]).To(Equal([]*fakebiui.PerformCall{
@@ -352,10 +368,17 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("fails with descriptive error", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("Applying the initial agent state: fake-apply-error"))
 		})
+
+		It("does not save the allocated IP addresses", func() {
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
+			Expect(err).To(HaveOccurred())
+
+			Expect(fakeIPAllocationRepo.UpdateCurrentRecords).To(BeNil())
+		})
 	})
 
 	Context("when starting agent services fails", func() {
@@ -364,7 +387,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("logs start and stop events to the eventLogger", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-start-error"))
 
@@ -384,7 +407,7 @@ var _ = Describe("Deployer", func() {
 		})
 
 		It("logs start and stop events to the eventLogger", func() {
-			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, fakeStage)
+			_, err := deployer.Deploy(cloud, deploymentManifest, cloudStemcell, registryConfig, fakeVMManager, mockBlobstore, drainOptions, fakeStage)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-wait-running-error"))
 
@@ -7,12 +7,17 @@ import (
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
 	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
 type Deployment interface {
-	Delete(biui.Stage) error
+	Stop(stage biui.Stage, detachDisks bool, drainOptions biinstance.DrainOptions) error
+	Start(stage biui.Stage, attachDisks bool, updateWatchTime bideplmanifest.WatchTime) error
+	RunErrand(stage biui.Stage, errandName string, deploymentManifest bideplmanifest.Manifest, fetchLogs bool) (biinstance.ErrandResult, error)
+	Delete(stage biui.Stage, drainOptions biinstance.DrainOptions) error
 }
 
 type deployment struct {
@@ -39,13 +44,48 @@ func NewDeployment(
 	}
 }
 
-func (d *deployment) Delete(deleteStage biui.Stage) error {
+func (d *deployment) Stop(stopStage biui.Stage, detachDisks bool, drainOptions biinstance.DrainOptions) error {
+	for _, instance := range d.instances {
+		if err := instance.Stop(d.pingTimeout, d.pingDelay, drainOptions, detachDisks, stopStage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *deployment) Start(startStage biui.Stage, attachDisks bool, updateWatchTime bideplmanifest.WatchTime) error {
+	// Only one instance will exist (for now), so it owns all the current disks
+	disks := []bidisk.Disk{}
+	if attachDisks {
+		disks = d.disks
+	}
+
+	for _, instance := range d.instances {
+		if err := instance.Start(disks, updateWatchTime, startStage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *deployment) RunErrand(errandStage biui.Stage, errandName string, deploymentManifest bideplmanifest.Manifest, fetchLogs bool) (biinstance.ErrandResult, error) {
+	// Only one instance will exist (for now), so errands are run on its VM
+	if len(d.instances) == 0 {
+		return biinstance.ErrandResult{}, bosherr.Errorf("No instance found to run errand '%s'", errandName)
+	}
+
+	return d.instances[0].RunErrand(errandName, deploymentManifest, fetchLogs, errandStage)
+}
+
+func (d *deployment) Delete(deleteStage biui.Stage, drainOptions biinstance.DrainOptions) error {
 	// le sigh... consuming from an array sucks without generics
 	for len(d.instances) > 0 {
 		lastIdx := len(d.instances) - 1
 		instance := d.instances[lastIdx]
 
-		if err := instance.Delete(d.pingTimeout, d.pingDelay, deleteStage); err != nil {
+		if err := instance.Delete(d.pingTimeout, d.pingDelay, drainOptions, deleteStage); err != nil {
 			return err
 		}
 
@@ -10,14 +10,17 @@ import (
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
+	mock_instance "github.com/cloudfoundry/bosh-init/deployment/instance/mocks"
 	mock_instance_state "github.com/cloudfoundry/bosh-init/deployment/instance/state/mocks"
 	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock"
 
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	bistemcell "github.com/cloudfoundry/bosh-init/stemcell"
@@ -27,6 +30,7 @@ import (
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 
+	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
 )
 
@@ -72,9 +76,10 @@ var _ = Describe("Deployment", func() {
 		var expectNormalFlow = func() {
 			gomock.InOrder(
 				mockCloud.EXPECT().HasVM("fake-vm-cid").Return(true, nil),
-				mockAgentClient.EXPECT().Ping().Return("any-state", nil),                   // ping to make sure agent is responsive
-				mockAgentClient.EXPECT().Stop(),                                            // stop all jobs
-				mockAgentClient.EXPECT().ListDisk().Return([]string{"fake-disk-cid"}, nil), // get mounted disks to be unmounted
+				mockAgentClient.EXPECT().Ping().Return("any-state", nil),                            // ping to make sure agent is responsive
+				mockAgentClient.EXPECT().Drain("shutdown", nil, time.Duration(0)).Return(true, nil), // drain all jobs
+				mockAgentClient.EXPECT().Stop(),                                                     // stop all jobs
+				mockAgentClient.EXPECT().ListDisk().Return([]string{"fake-disk-cid"}, nil),          // get mounted disks to be unmounted
 				mockAgentClient.EXPECT().UnmountDisk("fake-disk-cid"),
 				mockCloud.EXPECT().DeleteVM("fake-vm-cid"),
 				mockCloud.EXPECT().DeleteDisk("fake-disk-cid"),
@@ -137,13 +142,13 @@ var _ = Describe("Deployment", func() {
 			diskDeployer := bivm.NewDiskDeployer(diskManagerFactory, diskRepo, logger)
 
 			vmManagerFactory := bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeUUIDGenerator, fs, logger)
-			sshTunnelFactory := bisshtunnel.NewFactory(logger)
+			sshTunnelFactory := bisshtunnel.NewFactory(biconfig.NewHostKeyRepo(deploymentStateService), logger)
 
 			mockStateBuilderFactory = mock_instance_state.NewMockBuilderFactory(mockCtrl)
 			mockStateBuilder = mock_instance_state.NewMockBuilder(mockCtrl)
 			mockState = mock_instance_state.NewMockState(mockCtrl)
 
-			instanceFactory := biinstance.NewFactory(mockStateBuilderFactory)
+			instanceFactory := biinstance.NewFactory(mockStateBuilderFactory, clock.NewClock())
 			instanceManagerFactory := biinstance.NewManagerFactory(sshTunnelFactory, instanceFactory, logger)
 			stemcellManagerFactory := bistemcell.NewManagerFactory(stemcellRepo)
 
@@ -188,18 +193,19 @@ var _ = Describe("Deployment", func() {
 			It("stops agent, unmounts disk, deletes vm, deletes disk, deletes stemcell", func() {
 				expectNormalFlow()
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("logs validation stages", func() {
 				expectNormalFlow()
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 					{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+					{Name: "Draining jobs on instance 'unknown/0'"},
 					{Name: "Stopping jobs on instance 'unknown/0'"},
 					{Name: "Unmounting disk 'fake-disk-cid'"},
 					{Name: "Deleting VM 'fake-vm-cid'"},
@@ -211,7 +217,7 @@ var _ = Describe("Deployment", func() {
 			It("clears current vm, disk and stemcell", func() {
 				expectNormalFlow()
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).ToNot(HaveOccurred())
 
 				_, found, err := vmRepo.FindCurrent()
@@ -250,7 +256,7 @@ var _ = Describe("Deployment", func() {
 						mockCloud.EXPECT().DeleteStemcell("fake-stemcell-cid"),
 					)
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -259,7 +265,7 @@ var _ = Describe("Deployment", func() {
 				JustBeforeEach(func() {
 					expectNormalFlow()
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 
 					// reset event log recording
@@ -267,7 +273,7 @@ var _ = Describe("Deployment", func() {
 				})
 
 				It("does not delete anything", func() {
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 
 					Expect(fakeStage.PerformCalls).To(BeEmpty())
@@ -288,7 +294,7 @@ var _ = Describe("Deployment", func() {
 			})
 
 			It("does not delete anything", func() {
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls).To(BeEmpty())
@@ -308,14 +314,15 @@ var _ = Describe("Deployment", func() {
 
 			It("stops the agent and deletes the VM", func() {
 				gomock.InOrder(
-					mockAgentClient.EXPECT().Ping().Return("any-state", nil),                   // ping to make sure agent is responsive
-					mockAgentClient.EXPECT().Stop(),                                            // stop all jobs
-					mockAgentClient.EXPECT().ListDisk().Return([]string{"fake-disk-cid"}, nil), // get mounted disks to be unmounted
+					mockAgentClient.EXPECT().Ping().Return("any-state", nil),                            // ping to make sure agent is responsive
+					mockAgentClient.EXPECT().Drain("shutdown", nil, time.Duration(0)).Return(true, nil), // drain all jobs
+					mockAgentClient.EXPECT().Stop(),                                                     // stop all jobs
+					mockAgentClient.EXPECT().ListDisk().Return([]string{"fake-disk-cid"}, nil),          // get mounted disks to be unmounted
 					mockAgentClient.EXPECT().UnmountDisk("fake-disk-cid"),
 					mockCloud.EXPECT().DeleteVM("fake-vm-cid"),
 				)
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -327,7 +334,7 @@ var _ = Describe("Deployment", func() {
 				It("skips agent shutdown & deletes the VM (to ensure related resources are released by the CPI)", func() {
 					mockCloud.EXPECT().DeleteVM("fake-vm-cid")
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -337,7 +344,7 @@ var _ = Describe("Deployment", func() {
 						Message: "fake-vm-not-found-message",
 					}))
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -354,7 +361,7 @@ var _ = Describe("Deployment", func() {
 			It("deletes the disk", func() {
 				mockCloud.EXPECT().DeleteDisk("fake-disk-cid")
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -362,7 +369,7 @@ var _ = Describe("Deployment", func() {
 				It("deletes the disk (to ensure related resources are released by the CPI)", func() {
 					mockCloud.EXPECT().DeleteDisk("fake-disk-cid")
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -372,7 +379,7 @@ var _ = Describe("Deployment", func() {
 						Message: "fake-disk-not-found-message",
 					}))
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
@@ -389,7 +396,7 @@ var _ = Describe("Deployment", func() {
 			It("deletes the stemcell", func() {
 				mockCloud.EXPECT().DeleteStemcell("fake-stemcell-cid")
 
-				err := deployment.Delete(fakeStage)
+				err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -397,7 +404,7 @@ var _ = Describe("Deployment", func() {
 				It("deletes the stemcell (to ensure related resources are released by the CPI)", func() {
 					mockCloud.EXPECT().DeleteStemcell("fake-stemcell-cid")
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 
@@ -407,10 +414,80 @@ var _ = Describe("Deployment", func() {
 						Message: "fake-stemcell-not-found-message",
 					}))
 
-					err := deployment.Delete(fakeStage)
+					err := deployment.Delete(fakeStage, biinstance.DrainOptions{})
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
 		})
 	})
+
+	Describe("Stop and Start", func() {
+		var (
+			mockInstance *mock_instance.MockInstance
+			disk         *fakebidisk.FakeDisk
+			fakeStage    *fakebiui.FakeStage
+
+			pingTimeout = 10 * time.Second
+			pingDelay   = 500 * time.Millisecond
+
+			deployment Deployment
+		)
+
+		BeforeEach(func() {
+			mockInstance = mock_instance.NewMockInstance(mockCtrl)
+			disk = fakebidisk.NewFakeDisk("fake-disk-cid")
+			fakeStage = fakebiui.NewFakeStage()
+
+			deployment = NewDeployment(
+				[]biinstance.Instance{mockInstance},
+				[]bidisk.Disk{disk},
+				[]bistemcell.CloudStemcell{},
+				pingTimeout,
+				pingDelay,
+			)
+		})
+
+		It("stops the instances", func() {
+			drainOptions := biinstance.DrainOptions{Skip: true}
+			mockInstance.EXPECT().Stop(pingTimeout, pingDelay, drainOptions, true, fakeStage)
+
+			err := deployment.Stop(fakeStage, true, drainOptions)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("starts the instances without attaching disks", func() {
+			watchTime := bideplmanifest.WatchTime{Start: 0, End: 1000}
+			mockInstance.EXPECT().Start([]bidisk.Disk{}, watchTime, fakeStage)
+
+			err := deployment.Start(fakeStage, false, watchTime)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("starts the instances attaching the current disks", func() {
+			watchTime := bideplmanifest.WatchTime{Start: 0, End: 1000}
+			mockInstance.EXPECT().Start([]bidisk.Disk{disk}, watchTime, fakeStage)
+
+			err := deployment.Start(fakeStage, true, watchTime)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("runs errands on the instance", func() {
+			deploymentManifest := bideplmanifest.Manifest{Name: "fake-deployment-name"}
+			mockInstance.EXPECT().RunErrand("fake-errand", deploymentManifest, true, fakeStage).Return(biinstance.ErrandResult{ExitStatus: 1}, nil)
+
+			result, err := deployment.RunErrand(fakeStage, "fake-errand", deploymentManifest, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.ExitStatus).To(Equal(1))
+		})
+
+		Context("when stopping an instance fails", func() {
+			It("returns the error", func() {
+				mockInstance.EXPECT().Stop(pingTimeout, pingDelay, biinstance.DrainOptions{}, false, fakeStage).Return(bosherr.Error("fake-stop-error"))
+
+				err := deployment.Stop(fakeStage, false, biinstance.DrainOptions{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("fake-stop-error"))
+			})
+		})
+	})
 })
@@ -0,0 +1,44 @@
+package instance
+
+import (
+	"fmt"
+	"time"
+
+	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	drainTypeUpdate   = "update"
+	drainTypeShutdown = "shutdown"
+)
+
+// DrainOptions control how the job drain scripts are run before the jobs are stopped.
+// A zero MaxWait does not limit the time spent waiting for the drain scripts.
+type DrainOptions struct {
+	Skip    bool
+	MaxWait time.Duration
+}
+
+// drain runs the job drain scripts. The agent waits as long as the scripts ask for, dynamic drains included.
+// A drain that did not finish within MaxWait is cancelled, and the jobs are stopped anyway.
+func (i *instance) drain(drainType string, newState *bias.ApplySpec, options DrainOptions, stage biui.Stage) error {
+	stepName := fmt.Sprintf("Draining jobs on instance '%s/%d'", i.jobName, i.id)
+	return stage.Perform(stepName, func() error {
+		if options.Skip {
+			return biui.NewSkipStageError(bosherr.Error("Drain skipped by request"), "Drain skipped")
+		}
+
+		finished, err := i.vm.Drain(drainType, newState, options.MaxWait)
+		if err != nil {
+			return bosherr.WrapError(err, "Draining jobs")
+		}
+
+		if !finished {
+			i.logger.Warn(i.logTag, "Drain did not finish within %s, stopping jobs anyway", options.MaxWait)
+		}
+
+		return nil
+	})
+}
@@ -6,6 +6,7 @@ import (
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
 )
 
 type Factory interface {
@@ -22,13 +23,16 @@ type Factory interface {
 
 type factory struct {
 	stateBuilderFactory biinstancestate.BuilderFactory
+	timeService         clock.Clock
 }
 
 func NewFactory(
 	stateBuilderFactory biinstancestate.BuilderFactory,
+	timeService clock.Clock,
 ) Factory {
 	return &factory{
 		stateBuilderFactory: stateBuilderFactory,
+		timeService:         timeService,
 	}
 }
 
@@ -50,6 +54,7 @@ func (f *factory) NewInstance(
 		vmManager,
 		sshTunnelFactory,
 		stateBuilder,
+		f.timeService,
 		logger,
 	)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstancestate "github.com/cloudfoundry/bosh-init/deployment/instance/state"
@@ -14,6 +15,7 @@ import (
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
 )
 
 type Instance interface {
@@ -23,13 +25,39 @@ type Instance interface {
 	WaitUntilReady(biinstallmanifest.Registry, biui.Stage) error
 	UpdateDisks(bideplmanifest.Manifest, biui.Stage) ([]bidisk.Disk, error)
 	UpdateJobs(bideplmanifest.Manifest, biui.Stage) error
+	Stop(
+		pingTimeout time.Duration,
+		pingDelay time.Duration,
+		drainOptions DrainOptions,
+		detachDisks bool,
+		stage biui.Stage,
+	) error
+	Start(
+		disks []bidisk.Disk,
+		updateWatchTime bideplmanifest.WatchTime,
+		stage biui.Stage,
+	) error
+	RunErrand(
+		errandName string,
+		deploymentManifest bideplmanifest.Manifest,
+		fetchLogs bool,
+		stage biui.Stage,
+	) (ErrandResult, error)
 	Delete(
 		pingTimeout time.Duration,
 		pingDelay time.Duration,
+		drainOptions DrainOptions,
 		stage biui.Stage,
 	) error
 }
 
+type ErrandResult struct {
+	ExitStatus      int
+	Stdout          string
+	Stderr          string
+	LogsBlobstoreID string
+}
+
 type instance struct {
 	jobName          string
 	id               int
@@ -37,6 +65,7 @@ type instance struct {
 	vmManager        bivm.Manager
 	sshTunnelFactory bisshtunnel.Factory
 	stateBuilder     biinstancestate.Builder
+	timeService      clock.Clock
 	logger           boshlog.Logger
 	logTag           string
 }
@@ -48,6 +77,7 @@ func NewInstance(
 	vmManager bivm.Manager,
 	sshTunnelFactory bisshtunnel.Factory,
 	stateBuilder biinstancestate.Builder,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) Instance {
 	return &instance{
@@ -57,6 +87,7 @@ func NewInstance(
 		vmManager:        vmManager,
 		sshTunnelFactory: sshTunnelFactory,
 		stateBuilder:     stateBuilder,
+		timeService:      timeService,
 		logger:           logger,
 		logTag:           "instance",
 	}
@@ -85,19 +116,36 @@ func (i *instance) WaitUntilReady(
 	stepName := fmt.Sprintf("Waiting for the agent on VM '%s' to be ready", i.vm.CID())
 	err := stage.Perform(stepName, func() error {
 		if !registryConfig.IsEmpty() {
+			// the agent is only waited for on a VM that was just created, so the tunnel host has a new host key
 			sshTunnelOptions := bisshtunnel.Options{
-				Host:              registryConfig.SSHTunnel.Host,
-				Port:              registryConfig.SSHTunnel.Port,
-				User:              registryConfig.SSHTunnel.User,
-				Password:          registryConfig.SSHTunnel.Password,
-				PrivateKey:        registryConfig.SSHTunnel.PrivateKey,
-				LocalForwardPort:  registryConfig.Port,
-				RemoteForwardPort: registryConfig.Port,
+				Host:                 registryConfig.SSHTunnel.Host,
+				Port:                 registryConfig.SSHTunnel.Port,
+				User:                 registryConfig.SSHTunnel.User,
+				Password:             registryConfig.SSHTunnel.Password,
+				PrivateKey:           registryConfig.SSHTunnel.PrivateKey,
+				PrivateKeyPassphrase: registryConfig.SSHTunnel.PrivateKeyPassphrase,
+				LocalForwardPort:     registryConfig.Port,
+				RemoteForwardPort:    registryConfig.Port,
+				HostKey: bisshtunnel.HostKeyOptions{
+					HostKey:          registryConfig.SSHTunnel.HostKey,
+					KnownHostsPath:   registryConfig.SSHTunnel.KnownHosts,
+					TrustOnFirstUse:  registryConfig.SSHTunnel.TrustOnFirstUse,
+					HostRecreated:    true,
+					SkipVerification: registryConfig.SSHTunnel.SkipHostKeyVerification,
+				},
+				Proxy: proxyOptions(registryConfig.SSHTunnel.Proxy),
 			}
 			sshTunnel := i.sshTunnelFactory.NewSSHTunnel(sshTunnelOptions)
 			sshReadyErrCh := make(chan error)
 			sshErrCh := make(chan error)
 			go sshTunnel.Start(sshReadyErrCh, sshErrCh)
+			go func() {
+				for err := range sshErrCh {
+					if err != nil {
+						i.logger.Warn(i.logTag, "SSH tunnel: %s", err.Error())
+					}
+				}
+			}()
 
 			err := <-sshReadyErrCh
 			if err != nil {
@@ -111,6 +159,26 @@ func (i *instance) WaitUntilReady(
 	return err
 }
 
+func proxyOptions(proxy biinstallmanifest.Proxy) bisshtunnel.ProxyOptions {
+	return bisshtunnel.ProxyOptions{
+		URL: proxy.URL,
+		JumpHost: bisshtunnel.JumpHostOptions{
+			Host:                 proxy.JumpHost.Host,
+			Port:                 proxy.JumpHost.Port,
+			User:                 proxy.JumpHost.User,
+			PrivateKey:           proxy.JumpHost.PrivateKey,
+			PrivateKeyPassphrase: proxy.JumpHost.PrivateKeyPassphrase,
+			Password:             proxy.JumpHost.Password,
+			HostKey: bisshtunnel.HostKeyOptions{
+				HostKey:          proxy.JumpHost.HostKey,
+				KnownHostsPath:   proxy.JumpHost.KnownHosts,
+				TrustOnFirstUse:  proxy.JumpHost.TrustOnFirstUse,
+				SkipVerification: proxy.JumpHost.SkipHostKeyVerification,
+			},
+		},
+	}
+}
+
 func (i *instance) UpdateDisks(deploymentManifest bideplmanifest.Manifest, stage biui.Stage) ([]bidisk.Disk, error) {
 	diskPool, err := deploymentManifest.DiskPool(i.jobName)
 	if err != nil {
@@ -150,6 +218,9 @@ func (i *instance) UpdateJobs(
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Building state for instance '%s/%d'", i.jobName, i.id)
 	}
+	// jobs are only updated on VMs that were just created, so there are no running jobs to drain
+	newApplySpec := newAgentState.ToApplySpec()
+
 	stepName := fmt.Sprintf("Updating instance '%s/%d'", i.jobName, i.id)
 	err = stage.Perform(stepName, func() error {
 		err = i.vm.Stop()
@@ -157,7 +228,7 @@ func (i *instance) UpdateJobs(
 			return bosherr.WrapError(err, "Stopping the agent")
 		}
 
-		err = i.vm.Apply(newAgentState.ToApplySpec())
+		err = i.vm.Apply(newApplySpec)
 		if err != nil {
 			return bosherr.WrapError(err, "Applying the agent state")
 		}
@@ -195,9 +266,185 @@ func (i *instance) UpdateJobs(
 	return err
 }
 
+// Stop drains and stops the jobs on the instance, leaving the VM in place.
+// When detachDisks is true, persistent disks are also unmounted and detached from the VM.
+func (i *instance) Stop(
+	pingTimeout time.Duration,
+	pingDelay time.Duration,
+	drainOptions DrainOptions,
+	detachDisks bool,
+	stage biui.Stage,
+) error {
+	stepName := fmt.Sprintf("Waiting for the agent on VM '%s'", i.vm.CID())
+	err := stage.Perform(stepName, func() error {
+		if err := i.vm.WaitUntilReady(pingTimeout, pingDelay); err != nil {
+			return bosherr.WrapError(err, "Agent unreachable")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// jobs that stay on the VM see an update drain to their current spec, like 'bosh stop' does
+	drainType := drainTypeUpdate
+	if detachDisks {
+		drainType = drainTypeShutdown
+	}
+
+	if err = i.stopJobs(drainType, drainOptions, stage); err != nil {
+		return err
+	}
+
+	if !detachDisks {
+		return nil
+	}
+
+	disks, err := i.vm.Disks()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Getting VM '%s' disks", i.vm.CID())
+	}
+
+	if err = i.unmountDisks(stage); err != nil {
+		return err
+	}
+
+	for _, disk := range disks {
+		stepName := fmt.Sprintf("Detaching disk '%s'", disk.CID())
+		err = stage.Perform(stepName, func() error {
+			if err := i.vm.DetachDisk(disk); err != nil {
+				return bosherr.WrapErrorf(err, "Detaching disk '%s' from VM '%s'", disk.CID(), i.vm.CID())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start attaches the given disks (attaching is idempotent) and starts the jobs on the instance.
+func (i *instance) Start(
+	disks []bidisk.Disk,
+	updateWatchTime bideplmanifest.WatchTime,
+	stage biui.Stage,
+) error {
+	for _, disk := range disks {
+		stepName := fmt.Sprintf("Attaching disk '%s' to VM '%s'", disk.CID(), i.vm.CID())
+		err := stage.Perform(stepName, func() error {
+			return i.vm.AttachDisk(disk)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	stepName := fmt.Sprintf("Starting jobs on instance '%s/%d'", i.jobName, i.id)
+	err := stage.Perform(stepName, func() error {
+		return i.vm.Start()
+	})
+	if err != nil {
+		return err
+	}
+
+	return i.waitUntilJobsAreRunning(updateWatchTime, stage)
+}
+
+// RunErrand temporarily adds the errand's jobs to the instance, runs the errand and restores the service's jobs.
+// The errand is run by its first job, which must provide a 'bin/run' script.
+func (i *instance) RunErrand(
+	errandName string,
+	deploymentManifest bideplmanifest.Manifest,
+	fetchLogs bool,
+	stage biui.Stage,
+) (result ErrandResult, err error) {
+	errand, found := deploymentManifest.FindErrandByName(errandName)
+	if !found {
+		return result, bosherr.Errorf("Errand '%s' not found in deployment manifest", errandName)
+	}
+
+	errandManifest, err := deploymentManifest.WithColocatedErrand(errandName)
+	if err != nil {
+		return result, err
+	}
+
+	agentState, err := i.vm.GetState()
+	if err != nil {
+		return result, bosherr.WrapErrorf(err, "Getting state for instance '%s/%d'", i.jobName, i.id)
+	}
+
+	serviceState, err := i.stateBuilder.Build(i.jobName, i.id, deploymentManifest, stage, agentState)
+	if err != nil {
+		return result, bosherr.WrapErrorf(err, "Building state for instance '%s/%d'", i.jobName, i.id)
+	}
+
+	errandState, err := i.stateBuilder.Build(i.jobName, i.id, errandManifest, stage, agentState)
+	if err != nil {
+		return result, bosherr.WrapErrorf(err, "Building state for errand '%s'", errandName)
+	}
+
+	stepName := fmt.Sprintf("Adding errand '%s' to instance '%s/%d'", errandName, i.jobName, i.id)
+	err = stage.Perform(stepName, func() error {
+		return i.vm.ApplyErrand(errandState.ToApplySpec(), errand.Templates[0].Name)
+	})
+	if err != nil {
+		return result, bosherr.WrapError(err, "Applying the errand state")
+	}
+
+	defer func() {
+		stepName := fmt.Sprintf("Removing errand '%s' from instance '%s/%d'", errandName, i.jobName, i.id)
+		restoreErr := stage.Perform(stepName, func() error {
+			return i.vm.Apply(serviceState.ToApplySpec())
+		})
+		if restoreErr != nil && err == nil {
+			err = bosherr.WrapError(restoreErr, "Applying the agent state")
+		}
+	}()
+
+	stepName = fmt.Sprintf("Running errand '%s'", errandName)
+	err = stage.Perform(stepName, func() error {
+		agentResult, err := i.vm.RunErrand()
+		if err != nil {
+			return err
+		}
+
+		result.ExitStatus = agentResult.ExitStatus
+		result.Stdout = agentResult.Stdout
+		result.Stderr = agentResult.Stderr
+		return nil
+	})
+	if err != nil {
+		return result, bosherr.WrapErrorf(err, "Running errand '%s'", errandName)
+	}
+
+	if !fetchLogs {
+		return result, nil
+	}
+
+	errandJobNames := make([]string, len(errand.Templates))
+	for idx, template := range errand.Templates {
+		errandJobNames[idx] = template.Name
+	}
+
+	stepName = fmt.Sprintf("Fetching logs for errand '%s'", errandName)
+	err = stage.Perform(stepName, func() error {
+		var err error
+		result.LogsBlobstoreID, err = i.vm.FetchLogs(errandJobNames)
+		return err
+	})
+	if err != nil {
+		return result, bosherr.WrapErrorf(err, "Fetching logs for errand '%s'", errandName)
+	}
+
+	return result, nil
+}
+
 func (i *instance) Delete(
 	pingTimeout time.Duration,
 	pingDelay time.Duration,
+	drainOptions DrainOptions,
 	stage biui.Stage,
 ) error {
 	vmExists, err := i.vm.Exists()
@@ -206,7 +453,7 @@ func (i *instance) Delete(
 	}
 
 	if vmExists {
-		if err = i.shutdown(pingTimeout, pingDelay, stage); err != nil {
+		if err = i.shutdown(pingTimeout, pingDelay, drainOptions, stage); err != nil {
 			return err
 		}
 	}
@@ -226,6 +473,7 @@ func (i *instance) Delete(
 func (i *instance) shutdown(
 	pingTimeout time.Duration,
 	pingDelay time.Duration,
+	drainOptions DrainOptions,
 	stage biui.Stage,
 ) error {
 	stepName := fmt.Sprintf("Waiting for the agent on VM '%s'", i.vm.CID())
@@ -240,7 +488,7 @@ func (i *instance) shutdown(
 		return nil
 	}
 
-	if err := i.stopJobs(stage); err != nil {
+	if err := i.stopJobs(drainTypeShutdown, drainOptions, stage); err != nil {
 		return err
 	}
 	if err := i.unmountDisks(stage); err != nil {
@@ -257,12 +505,25 @@ func (i *instance) waitUntilJobsAreRunning(updateWatchTime bideplmanifest.WatchT
 
 	stepName := fmt.Sprintf("Waiting for instance '%s/%d' to be running", i.jobName, i.id)
 	return stage.Perform(stepName, func() error {
-		time.Sleep(start)
+		i.timeService.Sleep(start)
 		return i.vm.WaitToBeRunning(maxAttempts, delayBetweenAttempts)
 	})
 }
 
-func (i *instance) stopJobs(stage biui.Stage) error {
+func (i *instance) stopJobs(drainType string, drainOptions DrainOptions, stage biui.Stage) error {
+	var newState *bias.ApplySpec
+	if drainType == drainTypeUpdate && !drainOptions.Skip {
+		currentApplySpec, err := i.vm.CurrentApplySpec()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Getting the current spec of instance '%s/%d'", i.jobName, i.id)
+		}
+		newState = &currentApplySpec
+	}
+
+	if err := i.drain(drainType, newState, drainOptions, stage); err != nil {
+		return err
+	}
+
 	stepName := fmt.Sprintf("Stopping jobs on instance '%s/%d'", i.jobName, i.id)
 	return stage.Perform(stepName, func() error {
 		return i.vm.Stop()
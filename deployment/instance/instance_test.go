@@ -21,9 +21,11 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	"github.com/cloudfoundry/bosh-agent/agentclient"
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
 	fakebisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel/fakes"
 	fakebivm "github.com/cloudfoundry/bosh-init/deployment/vm/fakes"
+	bitestutils "github.com/cloudfoundry/bosh-init/testutils"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
 )
 
@@ -48,11 +50,13 @@ var _ = Describe("Instance", func() {
 		fakeSSHTunnelFactory *fakebisshtunnel.FakeFactory
 		fakeSSHTunnel        *fakebisshtunnel.FakeTunnel
 		fakeStage            *fakebiui.FakeStage
+		timeService          bitestutils.SleepingClock
 
 		instance Instance
 
-		pingTimeout = 1 * time.Second
-		pingDelay   = 500 * time.Millisecond
+		pingTimeout  = 1 * time.Second
+		pingDelay    = 500 * time.Millisecond
+		drainOptions DrainOptions
 
 		jobName  = "fake-job-name"
 		jobIndex = 0
@@ -71,6 +75,7 @@ var _ = Describe("Instance", func() {
 		mockState = mock_instance_state.NewMockState(mockCtrl)
 
 		logger := boshlog.NewLogger(boshlog.LevelNone)
+		timeService = bitestutils.NewSleepingClock(time.Now())
 
 		instance = NewInstance(
 			jobName,
@@ -79,15 +84,17 @@ var _ = Describe("Instance", func() {
 			fakeVMManager,
 			fakeSSHTunnelFactory,
 			mockStateBuilder,
+			timeService,
 			logger,
 		)
 
 		fakeStage = fakebiui.NewFakeStage()
+		drainOptions = DrainOptions{}
 	})
 
 	Describe("Delete", func() {
 		It("checks if the agent on the vm is responsive", func() {
-			err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+			err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeVM.WaitUntilReadyInputs).To(ContainElement(fakebivm.WaitUntilReadyInput{
@@ -97,18 +104,19 @@ var _ = Describe("Instance", func() {
 		})
 
 		It("deletes existing vm", func() {
-			err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+			err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeVM.DeleteCalled).To(Equal(1))
 		})
 
 		It("logs start and stop events", func() {
-			err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+			err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 				{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+				{Name: "Draining jobs on instance 'fake-job-name/0'"},
 				{Name: "Stopping jobs on instance 'fake-job-name/0'"},
 				{Name: "Deleting VM 'fake-vm-cid'"},
 			}))
@@ -116,7 +124,7 @@ var _ = Describe("Instance", func() {
 
 		Context("when agent is responsive", func() {
 			It("logs waiting for the agent event", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0]).To(Equal(&fakebiui.PerformCall{
@@ -125,18 +133,90 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("stops vm", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeVM.StopCalled).To(Equal(1))
 			})
 
+			It("runs the shutdown drain scripts before stopping the vm", func() {
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeVM.DrainInputs).To(Equal([]fakebivm.DrainInput{
+					{DrainType: "shutdown"},
+				}))
+			})
+
+			Context("when drain is skipped", func() {
+				BeforeEach(func() {
+					drainOptions.Skip = true
+				})
+
+				It("stops the vm without draining", func() {
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeVM.DrainInputs).To(HaveLen(0))
+					Expect(fakeVM.StopCalled).To(Equal(1))
+
+					Expect(fakeStage.PerformCalls[1].Name).To(Equal("Draining jobs on instance 'fake-job-name/0'"))
+					Expect(fakeStage.PerformCalls[1].SkipError).To(HaveOccurred())
+				})
+			})
+
+			Context("when a max drain wait is set", func() {
+				BeforeEach(func() {
+					drainOptions.MaxWait = 10 * time.Second
+				})
+
+				It("bounds the drain by the max drain wait", func() {
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeVM.DrainInputs).To(Equal([]fakebivm.DrainInput{
+						{DrainType: "shutdown", Timeout: 10 * time.Second},
+					}))
+				})
+			})
+
+			Context("when drain does not finish within the max drain wait", func() {
+				BeforeEach(func() {
+					fakeVM.DrainUnfinished = true
+				})
+
+				It("stops the vm anyway", func() {
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeVM.StopCalled).To(Equal(1))
+					Expect(fakeVM.DeleteCalled).To(Equal(1))
+				})
+			})
+
+			Context("when draining fails", func() {
+				var drainError = bosherr.Error("fake-drain-error")
+
+				BeforeEach(func() {
+					fakeVM.DrainErr = drainError
+				})
+
+				It("returns an error without stopping the vm", func() {
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+
+					Expect(fakeVM.StopCalled).To(Equal(0))
+					Expect(fakeVM.DeleteCalled).To(Equal(0))
+				})
+			})
+
 			It("unmounts vm disks", func() {
 				firstDisk := fakebidisk.NewFakeDisk("fake-disk-1")
 				secondDisk := fakebidisk.NewFakeDisk("fake-disk-2")
 				fakeVM.ListDisksDisks = []bidisk.Disk{firstDisk, secondDisk}
 
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeVM.UnmountDiskInputs).To(Equal([]fakebivm.UnmountDiskInput{
@@ -144,7 +224,7 @@ var _ = Describe("Instance", func() {
 					{Disk: secondDisk},
 				}))
 
-				Expect(fakeStage.PerformCalls[2:4]).To(Equal([]*fakebiui.PerformCall{
+				Expect(fakeStage.PerformCalls[3:5]).To(Equal([]*fakebiui.PerformCall{
 					{Name: "Unmounting disk 'fake-disk-1'"},
 					{Name: "Unmounting disk 'fake-disk-2'"},
 				}))
@@ -160,12 +240,13 @@ var _ = Describe("Instance", func() {
 				})
 
 				It("returns an error", func() {
-					err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-stop-error"))
 
 					Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 						{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+						{Name: "Draining jobs on instance 'fake-job-name/0'"},
 						{
 							Name:  "Stopping jobs on instance 'fake-job-name/0'",
 							Error: stopError,
@@ -181,13 +262,13 @@ var _ = Describe("Instance", func() {
 				})
 
 				It("returns an error", func() {
-					err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+					err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-unmount-error"))
 
-					Expect(fakeStage.PerformCalls[2].Name).To(Equal("Unmounting disk 'fake-disk'"))
-					Expect(fakeStage.PerformCalls[2].Error).To(HaveOccurred())
-					Expect(fakeStage.PerformCalls[2].Error.Error()).To(Equal("Unmounting disk 'fake-disk' from VM 'fake-vm-cid': fake-unmount-error"))
+					Expect(fakeStage.PerformCalls[3].Name).To(Equal("Unmounting disk 'fake-disk'"))
+					Expect(fakeStage.PerformCalls[3].Error).To(HaveOccurred())
+					Expect(fakeStage.PerformCalls[3].Error.Error()).To(Equal("Unmounting disk 'fake-disk' from VM 'fake-vm-cid': fake-unmount-error"))
 				})
 			})
 		})
@@ -198,7 +279,7 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("logs failed event", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0].Name).To(Equal("Waiting for the agent on VM 'fake-vm-cid'"))
@@ -216,12 +297,13 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("returns an error", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-delete-error"))
 
 				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
 					{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+					{Name: "Draining jobs on instance 'fake-job-name/0'"},
 					{Name: "Stopping jobs on instance 'fake-job-name/0'"},
 					{
 						Name:  "Deleting VM 'fake-vm-cid'",
@@ -241,23 +323,24 @@ var _ = Describe("Instance", func() {
 			})
 
 			It("deletes existing vm", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeVM.DeleteCalled).To(Equal(1))
 			})
 
 			It("does not contact the agent", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeVM.WaitUntilReadyInputs).To(HaveLen(0))
+				Expect(fakeVM.DrainInputs).To(HaveLen(0))
 				Expect(fakeVM.StopCalled).To(Equal(0))
 				Expect(fakeVM.UnmountDiskInputs).To(HaveLen(0))
 			})
 
 			It("logs vm delete as skipped", func() {
-				err := instance.Delete(pingTimeout, pingDelay, fakeStage)
+				err := instance.Delete(pingTimeout, pingDelay, drainOptions, fakeStage)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeStage.PerformCalls[0].Name).To(Equal("Deleting VM 'fake-vm-cid'"))
@@ -266,6 +349,262 @@ var _ = Describe("Instance", func() {
 		})
 	})
 
+	Describe("Stop", func() {
+		var (
+			firstDisk  *fakebidisk.FakeDisk
+			secondDisk *fakebidisk.FakeDisk
+		)
+
+		BeforeEach(func() {
+			firstDisk = fakebidisk.NewFakeDisk("fake-disk-1")
+			secondDisk = fakebidisk.NewFakeDisk("fake-disk-2")
+			fakeVM.ListDisksDisks = []bidisk.Disk{firstDisk, secondDisk}
+		})
+
+		It("stops the jobs without touching the disks", func() {
+			err := instance.Stop(pingTimeout, pingDelay, drainOptions, false, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.StopCalled).To(Equal(1))
+			Expect(fakeVM.UnmountDiskInputs).To(HaveLen(0))
+			Expect(fakeVM.DetachDiskInputs).To(HaveLen(0))
+			Expect(fakeVM.DeleteCalled).To(Equal(0))
+
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+				{Name: "Draining jobs on instance 'fake-job-name/0'"},
+				{Name: "Stopping jobs on instance 'fake-job-name/0'"},
+			}))
+		})
+
+		It("runs the update drain scripts with the current spec", func() {
+			fakeVM.CurrentApplySpecResult = bias.ApplySpec{Deployment: "fake-deployment-name"}
+
+			err := instance.Stop(pingTimeout, pingDelay, drainOptions, false, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.DrainInputs).To(Equal([]fakebivm.DrainInput{
+				{DrainType: "update", NewState: &bias.ApplySpec{Deployment: "fake-deployment-name"}},
+			}))
+		})
+
+		Context("when getting the current spec fails", func() {
+			BeforeEach(func() {
+				fakeVM.CurrentApplySpecErr = bosherr.Error("fake-get-state-error")
+			})
+
+			It("returns an error without stopping the jobs", func() {
+				err := instance.Stop(pingTimeout, pingDelay, drainOptions, false, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-get-state-error"))
+				Expect(fakeVM.StopCalled).To(Equal(0))
+			})
+		})
+
+		Context("when detaching disks", func() {
+			It("runs the shutdown drain scripts", func() {
+				err := instance.Stop(pingTimeout, pingDelay, drainOptions, true, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVM.DrainInputs).To(Equal([]fakebivm.DrainInput{
+					{DrainType: "shutdown"},
+				}))
+			})
+
+			It("unmounts and detaches the disks", func() {
+				err := instance.Stop(pingTimeout, pingDelay, drainOptions, true, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVM.UnmountDiskInputs).To(Equal([]fakebivm.UnmountDiskInput{
+					{Disk: firstDisk},
+					{Disk: secondDisk},
+				}))
+				Expect(fakeVM.DetachDiskInputs).To(Equal([]fakebivm.DetachDiskInput{
+					{Disk: firstDisk},
+					{Disk: secondDisk},
+				}))
+
+				Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+					{Name: "Waiting for the agent on VM 'fake-vm-cid'"},
+					{Name: "Draining jobs on instance 'fake-job-name/0'"},
+					{Name: "Stopping jobs on instance 'fake-job-name/0'"},
+					{Name: "Unmounting disk 'fake-disk-1'"},
+					{Name: "Unmounting disk 'fake-disk-2'"},
+					{Name: "Detaching disk 'fake-disk-1'"},
+					{Name: "Detaching disk 'fake-disk-2'"},
+				}))
+			})
+		})
+
+		Context("when agent fails to respond", func() {
+			BeforeEach(func() {
+				fakeVM.WaitUntilReadyErr = bosherr.Error("fake-wait-error")
+			})
+
+			It("returns an error", func() {
+				err := instance.Stop(pingTimeout, pingDelay, drainOptions, false, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Agent unreachable: fake-wait-error"))
+				Expect(fakeVM.StopCalled).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Start", func() {
+		var (
+			disk            *fakebidisk.FakeDisk
+			updateWatchTime bideplmanifest.WatchTime
+		)
+
+		BeforeEach(func() {
+			disk = fakebidisk.NewFakeDisk("fake-disk")
+			updateWatchTime = bideplmanifest.WatchTime{Start: 0, End: 5478}
+		})
+
+		It("attaches the disks, starts the jobs and waits for them to be running", func() {
+			err := instance.Start([]bidisk.Disk{disk}, updateWatchTime, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.AttachDiskInputs).To(Equal([]fakebivm.AttachDiskInput{
+				{Disk: disk},
+			}))
+			Expect(fakeVM.StartCalled).To(Equal(1))
+			Expect(fakeVM.WaitToBeRunningInputs).To(ContainElement(fakebivm.WaitInput{
+				MaxAttempts: 5,
+				Delay:       1 * time.Second,
+			}))
+
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Attaching disk 'fake-disk' to VM 'fake-vm-cid'"},
+				{Name: "Starting jobs on instance 'fake-job-name/0'"},
+				{Name: "Waiting for instance 'fake-job-name/0' to be running"},
+			}))
+		})
+
+		It("waits for the watch time start on the clock before checking the jobs", func() {
+			updateWatchTime = bideplmanifest.WatchTime{Start: 3000, End: 5478}
+			startTime := timeService.Now()
+
+			err := instance.Start([]bidisk.Disk{}, updateWatchTime, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(timeService.Now().Sub(startTime)).To(Equal(3 * time.Second))
+		})
+
+		Context("when starting vm fails", func() {
+			BeforeEach(func() {
+				fakeVM.StartErr = bosherr.Error("fake-start-error")
+			})
+
+			It("returns an error", func() {
+				err := instance.Start([]bidisk.Disk{}, updateWatchTime, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-start-error"))
+				Expect(fakeVM.WaitToBeRunningInputs).To(HaveLen(0))
+			})
+		})
+	})
+
+	Describe("RunErrand", func() {
+		var (
+			deploymentManifest bideplmanifest.Manifest
+			errandManifest     bideplmanifest.Manifest
+			mockErrandState    *mock_instance_state.MockState
+			agentState         agentclient.AgentState
+		)
+
+		BeforeEach(func() {
+			deploymentManifest = bideplmanifest.Manifest{
+				Name: "fake-deployment-name",
+				Jobs: []bideplmanifest.Job{
+					{
+						Name:      jobName,
+						Templates: []bideplmanifest.ReleaseJobRef{{Name: "fake-service-job", Release: "fake-release"}},
+					},
+					{
+						Name:      "fake-errand",
+						Lifecycle: bideplmanifest.JobLifecycleErrand,
+						Templates: []bideplmanifest.ReleaseJobRef{{Name: "fake-errand-job", Release: "fake-release"}},
+					},
+				},
+			}
+
+			var err error
+			errandManifest, err = deploymentManifest.WithColocatedErrand("fake-errand")
+			Expect(err).ToNot(HaveOccurred())
+
+			agentState = agentclient.AgentState{JobState: "running"}
+			fakeVM.GetStateResult = agentState
+			fakeVM.RunErrandResult = biagentclient.ErrandResult{ExitStatus: 0, Stdout: "fake-stdout", Stderr: "fake-stderr"}
+
+			mockErrandState = mock_instance_state.NewMockState(mockCtrl)
+			mockStateBuilder.EXPECT().Build(jobName, jobIndex, deploymentManifest, fakeStage, agentState).Return(mockState, nil).AnyTimes()
+			mockStateBuilder.EXPECT().Build(jobName, jobIndex, errandManifest, fakeStage, agentState).Return(mockErrandState, nil).AnyTimes()
+			mockState.EXPECT().ToApplySpec().Return(bias.ApplySpec{Deployment: "fake-service-spec"}).AnyTimes()
+			mockErrandState.EXPECT().ToApplySpec().Return(bias.ApplySpec{Deployment: "fake-errand-spec"}).AnyTimes()
+		})
+
+		It("applies the errand, runs it and restores the service jobs", func() {
+			result, err := instance.RunErrand("fake-errand", deploymentManifest, false, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ErrandResult{ExitStatus: 0, Stdout: "fake-stdout", Stderr: "fake-stderr"}))
+
+			Expect(fakeVM.ApplyErrandInputs).To(Equal([]fakebivm.ApplyErrandInput{
+				{ApplySpec: bias.ApplySpec{Deployment: "fake-errand-spec"}, ErrandJobName: "fake-errand-job"},
+			}))
+			Expect(fakeVM.ApplyInputs).To(Equal([]fakebivm.ApplyInput{
+				{ApplySpec: bias.ApplySpec{Deployment: "fake-service-spec"}},
+			}))
+			Expect(fakeVM.RunErrandCalled).To(Equal(1))
+			Expect(fakeVM.FetchLogsInputs).To(BeEmpty())
+
+			Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+				{Name: "Adding errand 'fake-errand' to instance 'fake-job-name/0'"},
+				{Name: "Running errand 'fake-errand'"},
+				{Name: "Removing errand 'fake-errand' from instance 'fake-job-name/0'"},
+			}))
+		})
+
+		It("fetches the errand logs when requested", func() {
+			fakeVM.FetchLogsBlobstoreID = "fake-logs-blob-id"
+
+			result, err := instance.RunErrand("fake-errand", deploymentManifest, true, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LogsBlobstoreID).To(Equal("fake-logs-blob-id"))
+			Expect(fakeVM.FetchLogsInputs).To(Equal([][]string{{"fake-errand-job"}}))
+		})
+
+		It("returns the result of a failing errand without an error", func() {
+			fakeVM.RunErrandResult = biagentclient.ErrandResult{ExitStatus: 2}
+
+			result, err := instance.RunErrand("fake-errand", deploymentManifest, false, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.ExitStatus).To(Equal(2))
+		})
+
+		Context("when running the errand fails", func() {
+			It("restores the service jobs and returns an error", func() {
+				fakeVM.RunErrandErr = bosherr.Error("fake-run-errand-error")
+
+				_, err := instance.RunErrand("fake-errand", deploymentManifest, false, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-run-errand-error"))
+				Expect(fakeVM.ApplyErrandInputs).To(HaveLen(1))
+				Expect(fakeVM.ApplyInputs).To(HaveLen(1))
+			})
+		})
+
+		Context("when the errand does not exist", func() {
+			It("returns an error", func() {
+				_, err := instance.RunErrand(jobName, deploymentManifest, false, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Errand 'fake-job-name' not found in deployment manifest"))
+				Expect(fakeVM.ApplyErrandInputs).To(BeEmpty())
+				Expect(fakeVM.ApplyInputs).To(BeEmpty())
+			})
+		})
+	})
+
 	Describe("UpdateJobs", func() {
 		var (
 			deploymentManifest bideplmanifest.Manifest
@@ -325,6 +664,13 @@ var _ = Describe("Instance", func() {
 			Expect(fakeVM.StartCalled).To(Equal(1))
 		})
 
+		It("does not drain the jobs of the new vm", func() {
+			err := instance.UpdateJobs(deploymentManifest, fakeStage)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVM.DrainInputs).To(HaveLen(0))
+		})
+
 		It("waits until agent reports state as running", func() {
 			err := instance.UpdateJobs(deploymentManifest, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
@@ -480,10 +826,52 @@ var _ = Describe("Instance", func() {
 				Port:              124,
 				LocalForwardPort:  125,
 				RemoteForwardPort: 125,
+				HostKey:           bisshtunnel.HostKeyOptions{HostRecreated: true},
 			}))
 			Expect(fakeSSHTunnel.Started).To(BeTrue())
 		})
 
+		Context("when host key verification is configured", func() {
+			BeforeEach(func() {
+				registryConfig.SSHTunnel.KnownHosts = "/fake/known_hosts"
+			})
+
+			It("starts the SSH tunnel with host key verification", func() {
+				err := instance.WaitUntilReady(registryConfig, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeSSHTunnelFactory.NewSSHTunnelOptions.HostKey).To(Equal(bisshtunnel.HostKeyOptions{
+					KnownHostsPath: "/fake/known_hosts",
+					HostRecreated:  true,
+				}))
+			})
+		})
+
+		Context("when a proxy is configured", func() {
+			BeforeEach(func() {
+				registryConfig.SSHTunnel.Proxy = biinstallmanifest.Proxy{
+					JumpHost: biinstallmanifest.JumpHost{
+						Host:       "fake-jump-host",
+						Port:       22,
+						User:       "fake-jump-user",
+						PrivateKey: "fake-jump-private-key-path",
+					},
+				}
+			})
+
+			It("starts the SSH tunnel through the proxy", func() {
+				err := instance.WaitUntilReady(registryConfig, fakeStage)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeSSHTunnelFactory.NewSSHTunnelOptions.Proxy).To(Equal(bisshtunnel.ProxyOptions{
+					JumpHost: bisshtunnel.JumpHostOptions{
+						Host:       "fake-jump-host",
+						Port:       22,
+						User:       "fake-jump-user",
+						PrivateKey: "fake-jump-private-key-path",
+					},
+				}))
+			})
+		})
+
 		It("waits for the vm", func() {
 			err := instance.WaitUntilReady(registryConfig, fakeStage)
 			Expect(err).NotTo(HaveOccurred())
@@ -12,6 +12,7 @@ import (
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_instance_state "github.com/cloudfoundry/bosh-init/deployment/instance/state/mocks"
 	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock"
 
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
@@ -74,7 +75,7 @@ var _ = Describe("Manager", func() {
 		mockStateBuilder = mock_instance_state.NewMockBuilder(mockCtrl)
 		mockState = mock_instance_state.NewMockState(mockCtrl)
 
-		instanceFactory = NewFactory(mockStateBuilderFactory)
+		instanceFactory = NewFactory(mockStateBuilderFactory, clock.NewClock())
 
 		mockBlobstore = mock_blobstore.NewMockBlobstore(mockCtrl)
 
@@ -180,6 +181,7 @@ var _ = Describe("Manager", func() {
 				fakeVMManager,
 				fakeSSHTunnelFactory,
 				mockStateBuilder,
+				clock.NewClock(),
 				logger,
 			)
 
@@ -336,6 +338,7 @@ var _ = Describe("Manager", func() {
 					PrivateKey:        "fake-ssh-private-key-path",
 					LocalForwardPort:  124,
 					RemoteForwardPort: 124,
+					HostKey:           bisshtunnel.HostKeyOptions{HostRecreated: true},
 				}))
 				Expect(fakeSSHTunnel.Started).To(BeTrue())
 			})
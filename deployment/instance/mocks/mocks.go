@@ -35,14 +35,14 @@ func (_m *MockInstance) EXPECT() *_MockInstanceRecorder {
 	return _m.recorder
 }
 
-func (_m *MockInstance) Delete(_param0 time.Duration, _param1 time.Duration, _param2 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "Delete", _param0, _param1, _param2)
+func (_m *MockInstance) Delete(_param0 time.Duration, _param1 time.Duration, _param2 instance.DrainOptions, _param3 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "Delete", _param0, _param1, _param2, _param3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockInstanceRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1, arg2)
+func (_mr *_MockInstanceRecorder) Delete(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1, arg2, arg3)
 }
 
 func (_m *MockInstance) Disks() ([]disk.Disk, error) {
@@ -76,6 +76,37 @@ func (_mr *_MockInstanceRecorder) JobName() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "JobName")
 }
 
+func (_m *MockInstance) RunErrand(_param0 string, _param1 manifest.Manifest, _param2 bool, _param3 ui.Stage) (instance.ErrandResult, error) {
+	ret := _m.ctrl.Call(_m, "RunErrand", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(instance.ErrandResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockInstanceRecorder) RunErrand(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RunErrand", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockInstance) Start(_param0 []disk.Disk, _param1 manifest.WatchTime, _param2 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "Start", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) Start(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Start", arg0, arg1, arg2)
+}
+
+func (_m *MockInstance) Stop(_param0 time.Duration, _param1 time.Duration, _param2 instance.DrainOptions, _param3 bool, _param4 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "Stop", _param0, _param1, _param2, _param3, _param4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) Stop(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Stop", arg0, arg1, arg2, arg3, arg4)
+}
+
 func (_m *MockInstance) UpdateDisks(_param0 manifest.Manifest, _param1 ui.Stage) ([]disk.Disk, error) {
 	ret := _m.ctrl.Call(_m, "UpdateDisks", _param0, _param1)
 	ret0, _ := ret[0].([]disk.Disk)
@@ -140,14 +171,14 @@ func (_mr *_MockManagerRecorder) Create(arg0, arg1, arg2, arg3, arg4, arg5 inter
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Create", arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
-func (_m *MockManager) DeleteAll(_param0 time.Duration, _param1 time.Duration, _param2 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "DeleteAll", _param0, _param1, _param2)
+func (_m *MockManager) DeleteAll(_param0 time.Duration, _param1 time.Duration, _param2 instance.DrainOptions, _param3 ui.Stage) error {
+	ret := _m.ctrl.Call(_m, "DeleteAll", _param0, _param1, _param2, _param3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockManagerRecorder) DeleteAll(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteAll", arg0, arg1, arg2)
+func (_mr *_MockManagerRecorder) DeleteAll(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeleteAll", arg0, arg1, arg2, arg3)
 }
 
 func (_m *MockManager) FindCurrent() ([]instance.Instance, error) {
@@ -22,6 +22,7 @@ type Builder interface {
 
 type builder struct {
 	releaseJobResolver        bideplrel.JobResolver
+	linkResolver              bideplmanifest.LinkResolver
 	jobDependencyCompiler     bistatejob.DependencyCompiler
 	jobListRenderer           bitemplate.JobListRenderer
 	renderedJobListCompressor bitemplate.RenderedJobListCompressor
@@ -32,6 +33,7 @@ type builder struct {
 
 func NewBuilder(
 	releaseJobResolver bideplrel.JobResolver,
+	linkResolver bideplmanifest.LinkResolver,
 	jobDependencyCompiler bistatejob.DependencyCompiler,
 	jobListRenderer bitemplate.JobListRenderer,
 	renderedJobListCompressor bitemplate.RenderedJobListCompressor,
@@ -40,6 +42,7 @@ func NewBuilder(
 ) Builder {
 	return &builder{
 		releaseJobResolver:        releaseJobResolver,
+		linkResolver:              linkResolver,
 		jobDependencyCompiler:     jobDependencyCompiler,
 		jobListRenderer:           jobListRenderer,
 		renderedJobListCompressor: renderedJobListCompressor,
@@ -81,7 +84,29 @@ func (b *builder) Build(jobName string, instanceID int, deploymentManifest bidep
 		return nil, err
 	}
 
-	renderedJobTemplates, err := b.renderJobTemplates(releaseJobs, releaseJobProperties, deploymentJob.Properties, deploymentManifest.Properties, deploymentManifest.Name, defaultAddress, stage)
+	az, err := deploymentManifest.AZ(jobName)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Finding az for job '%s'", jobName)
+	}
+
+	links, err := b.linkResolver.Resolve(deploymentManifest, jobName)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Resolving links for instance '%s/%d'", jobName, instanceID)
+	}
+
+	linkContexts, err := b.linkContexts(links, jobName, instanceID, defaultAddress, deploymentManifest)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Building link contexts for instance '%s/%d'", jobName, instanceID)
+	}
+
+	instanceContext := bitemplate.InstanceContext{
+		Address:  defaultAddress,
+		AZ:       az.Name,
+		Networks: b.networkContexts(initialState.NetworkInterfaces(), agentState),
+		Links:    linkContexts,
+	}
+
+	renderedJobTemplates, err := b.renderJobTemplates(releaseJobs, releaseJobProperties, deploymentJob.Properties, deploymentManifest.Properties, deploymentManifest.Name, instanceContext, stage)
 	if err != nil {
 		return nil, bosherr.WrapErrorf(err, "Rendering job templates for instance '%s/%d'", jobName, instanceID)
 	}
@@ -183,7 +208,7 @@ func (b *builder) renderJobTemplates(
 	jobProperties biproperty.Map,
 	globalProperties biproperty.Map,
 	deploymentName string,
-	address string,
+	instance bitemplate.InstanceContext,
 	stage biui.Stage,
 ) (renderedJobs, error) {
 	var (
@@ -191,7 +216,7 @@ func (b *builder) renderJobTemplates(
 		blobID                 string
 	)
 	err := stage.Perform("Rendering job templates", func() error {
-		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, address)
+		renderedJobList, err := b.jobListRenderer.Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, deploymentName, instance)
 		if err != nil {
 			return err
 		}
@@ -220,6 +245,69 @@ func (b *builder) renderJobTemplates(
 	}, nil
 }
 
+// linkContexts builds the contexts of consumed links from the instances of the jobs providing them.
+// bosh-init deploys a single instance, so a link provided by another job is provided by that job's first instance.
+func (b *builder) linkContexts(links map[string]map[string]bideplmanifest.Link, jobName string, instanceID int, address string, deploymentManifest bideplmanifest.Manifest) (map[string]map[string]bitemplate.LinkContext, error) {
+	linkContexts := map[string]map[string]bitemplate.LinkContext{}
+	for releaseJobName, consumedLinks := range links {
+		linkContexts[releaseJobName] = map[string]bitemplate.LinkContext{}
+		for linkName, link := range consumedLinks {
+			instance, err := b.linkInstanceContext(link.JobName, jobName, instanceID, address, deploymentManifest)
+			if err != nil {
+				return nil, err
+			}
+
+			linkContexts[releaseJobName][linkName] = bitemplate.LinkContext{
+				Address:    instance.Address,
+				Instances:  []bitemplate.LinkInstanceContext{instance},
+				Properties: link.Properties,
+			}
+		}
+	}
+	return linkContexts, nil
+}
+
+// linkInstanceContext describes the instance of the providing job. The consuming instance provides the link itself
+// when the jobs are the same, otherwise the provider is addressed by the static ip of its default network.
+// Jobs without a static ip run on the deployed instance, as errands do.
+func (b *builder) linkInstanceContext(providerJobName string, jobName string, instanceID int, address string, deploymentManifest bideplmanifest.Manifest) (bitemplate.LinkInstanceContext, error) {
+	providerJob, found := deploymentManifest.FindJobByName(providerJobName)
+	if !found {
+		return bitemplate.LinkInstanceContext{}, bosherr.Errorf("Job '%s' providing a link not found in deployment manifest", providerJobName)
+	}
+
+	az, err := deploymentManifest.AZ(providerJobName)
+	if err != nil {
+		return bitemplate.LinkInstanceContext{}, bosherr.WrapErrorf(err, "Finding az for job '%s'", providerJobName)
+	}
+
+	index := 0
+	if providerJobName == jobName {
+		index = instanceID
+	} else if staticIP := defaultStaticIP(providerJob); staticIP != "" {
+		address = staticIP
+	}
+
+	return bitemplate.LinkInstanceContext{
+		Name:      providerJobName,
+		Index:     index,
+		AZ:        az.Name,
+		Address:   address,
+		Bootstrap: index == 0,
+	}, nil
+}
+
+// defaultStaticIP returns the first static ip of the job's default gateway network, or of its only network
+func defaultStaticIP(job bideplmanifest.Job) string {
+	for _, jobNetwork := range job.Networks {
+		isDefault := len(job.Networks) == 1 || containsNetworkDefault(jobNetwork.Defaults, bideplmanifest.NetworkDefaultGateway)
+		if isDefault && len(jobNetwork.StaticIPs) > 0 {
+			return jobNetwork.StaticIPs[0]
+		}
+	}
+	return ""
+}
+
 func (b *builder) defaultAddress(networkRefs []NetworkRef, agentState agentclient.AgentState) (string, error) {
 
 	if (networkRefs == nil) || (len(networkRefs) == 0) {
@@ -230,21 +318,68 @@ func (b *builder) defaultAddress(networkRefs []NetworkRef, agentState agentclien
 		return networkIp(networkRefs[0], agentState), nil
 	}
 
-	for _, ref := range networkRefs {
-		if ref.Interface["default"] == nil {
+	// dual-stack jobs have a gateway default on each network, the address is taken from the one that is also the dns default
+	var gatewayRef *NetworkRef
+	for i, ref := range networkRefs {
+		defaults := networkDefaults(ref)
+		if !containsNetworkDefault(defaults, bideplmanifest.NetworkDefaultGateway) {
 			continue
 		}
 
-		for _, val := range ref.Interface["default"].([]bideplmanifest.NetworkDefault) {
-			if val == "gateway" {
-				return networkIp(ref, agentState), nil
-			}
+		if containsNetworkDefault(defaults, bideplmanifest.NetworkDefaultDNS) {
+			return networkIp(ref, agentState), nil
 		}
+
+		if gatewayRef == nil {
+			gatewayRef = &networkRefs[i]
+		}
+	}
+
+	if gatewayRef != nil {
+		return networkIp(*gatewayRef, agentState), nil
 	}
 
 	return "", errors.New("Must specify default network")
 }
 
+// networkContexts describes the instance networks to job templates
+func (b *builder) networkContexts(networkRefs []NetworkRef, agentState agentclient.AgentState) map[string]bitemplate.NetworkContext {
+	networkContexts := make(map[string]bitemplate.NetworkContext, len(networkRefs))
+	for _, ref := range networkRefs {
+		networkContext := bitemplate.NetworkContext{
+			IP: networkIp(ref, agentState),
+		}
+
+		if netmask, ok := ref.Interface["netmask"].(string); ok {
+			networkContext.Netmask = netmask
+		}
+		if gateway, ok := ref.Interface["gateway"].(string); ok {
+			networkContext.Gateway = gateway
+		}
+
+		for _, dflt := range networkDefaults(ref) {
+			networkContext.Default = append(networkContext.Default, string(dflt))
+		}
+
+		networkContexts[ref.Name] = networkContext
+	}
+	return networkContexts
+}
+
+func networkDefaults(networkRef NetworkRef) []bideplmanifest.NetworkDefault {
+	defaults, _ := networkRef.Interface["default"].([]bideplmanifest.NetworkDefault)
+	return defaults
+}
+
+func containsNetworkDefault(defaults []bideplmanifest.NetworkDefault, dflt bideplmanifest.NetworkDefault) bool {
+	for _, value := range defaults {
+		if value == dflt {
+			return true
+		}
+	}
+	return false
+}
+
 func networkIp(networkRef NetworkRef, agentState agentclient.AgentState) string {
 	if "dynamic" == networkRef.Interface["type"].(string) {
 		return agentState.NetworkSpecs[networkRef.Name].IP
@@ -3,6 +3,7 @@ package state
 import (
 	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	biblobstore "github.com/cloudfoundry/bosh-init/blobstore"
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
 	bistatejob "github.com/cloudfoundry/bosh-init/state/job"
 	bistatepkg "github.com/cloudfoundry/bosh-init/state/pkg"
@@ -44,6 +45,7 @@ func (f *builderFactory) NewBuilder(blobstore biblobstore.Blobstore, agentClient
 
 	return NewBuilder(
 		f.releaseJobResolver,
+		bideplmanifest.NewLinkResolver(f.releaseJobResolver),
 		jobDependencyCompiler,
 		f.jobRenderer,
 		f.renderedJobListCompressor,
@@ -6,6 +6,7 @@ import (
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_deployment_release "github.com/cloudfoundry/bosh-init/deployment/release/mocks"
 	mock_state_job "github.com/cloudfoundry/bosh-init/state/job/mocks"
+	bitemplate "github.com/cloudfoundry/bosh-init/templatescompiler"
 	mock_template "github.com/cloudfoundry/bosh-init/templatescompiler/mocks"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -20,6 +21,7 @@ import (
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 
+	fakebideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest/fakes"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
 )
 
@@ -44,6 +46,7 @@ func describeBuilder() {
 		mockJobListRenderer    *mock_template.MockJobListRenderer
 		mockCompressor         *mock_template.MockRenderedJobListCompressor
 		mockBlobstore          *mock_blobstore.MockBlobstore
+		fakeLinkResolver       *fakebideplmanifest.FakeLinkResolver
 
 		stateBuilder Builder
 	)
@@ -56,6 +59,7 @@ func describeBuilder() {
 		mockJobListRenderer = mock_template.NewMockJobListRenderer(mockCtrl)
 		mockCompressor = mock_template.NewMockRenderedJobListCompressor(mockCtrl)
 		mockBlobstore = mock_blobstore.NewMockBlobstore(mockCtrl)
+		fakeLinkResolver = fakebideplmanifest.NewFakeLinkResolver()
 	})
 
 	Describe("BuildInitialState", func() {
@@ -110,6 +114,7 @@ func describeBuilder() {
 
 			stateBuilder = NewBuilder(
 				mockReleaseJobResolver,
+				fakeLinkResolver,
 				mockDependencyCompiler,
 				mockJobListRenderer,
 				mockCompressor,
@@ -158,8 +163,11 @@ func describeBuilder() {
 			releasePackageRuby    *birelpkg.Package
 			releasePackageCPI     *birelpkg.Package
 
-			agentState biac.AgentState
-			expectedIP string
+			agentState              biac.AgentState
+			expectedIP              string
+			expectedAZ              string
+			expectedLinkContexts    map[string]map[string]bitemplate.LinkContext
+			expectedNetworkContexts map[string]bitemplate.NetworkContext
 
 			expectCompile *gomock.Call
 		)
@@ -171,6 +179,11 @@ func describeBuilder() {
 			jobName = "fake-deployment-job-name"
 			instanceID = 0
 			expectedIP = "1.2.3.4"
+			expectedAZ = ""
+			expectedLinkContexts = map[string]map[string]bitemplate.LinkContext{}
+			expectedNetworkContexts = map[string]bitemplate.NetworkContext{
+				"fake-network-name": {IP: "1.2.3.4", Default: []string{"dns", "gateway"}},
+			}
 
 			deploymentManifest = bideplmanifest.Manifest{
 				Name: "fake-deployment-name",
@@ -223,6 +236,7 @@ func describeBuilder() {
 
 			stateBuilder = NewBuilder(
 				mockReleaseJobResolver,
+				fakeLinkResolver,
 				mockDependencyCompiler,
 				mockJobListRenderer,
 				mockCompressor,
@@ -297,7 +311,12 @@ func describeBuilder() {
 				"fake-job-property": "fake-global-property-value",
 			}
 
-			mockJobListRenderer.EXPECT().Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, "fake-deployment-name", expectedIP).Return(mockRenderedJobList, nil)
+			mockJobListRenderer.EXPECT().Render(releaseJobs, releaseJobProperties, jobProperties, globalProperties, "fake-deployment-name", bitemplate.InstanceContext{
+				Address:  expectedIP,
+				AZ:       expectedAZ,
+				Networks: expectedNetworkContexts,
+				Links:    expectedLinkContexts,
+			}).Return(mockRenderedJobList, nil)
 
 			mockRenderedJobList.EXPECT().DeleteSilently()
 
@@ -337,12 +356,107 @@ func describeBuilder() {
 			Expect(state.NetworkInterfaces()).To(HaveLen(1))
 		})
 
+		Context("when the job consumes links", func() {
+			BeforeEach(func() {
+				fakeLinkResolver.Links = map[string]map[string]bideplmanifest.Link{
+					"fake-release-job-name": {
+						"fake-link-name": {
+							Name:       "fake-provided-link-name",
+							Type:       "fake-link-type",
+							JobName:    "fake-deployment-job-name",
+							Properties: biproperty.Map{"fake-link-property": "fake-link-property-value"},
+						},
+					},
+				}
+				expectedLinkContexts = map[string]map[string]bitemplate.LinkContext{
+					"fake-release-job-name": {
+						"fake-link-name": {
+							Address: "1.2.3.4",
+							Instances: []bitemplate.LinkInstanceContext{
+								{Name: "fake-deployment-job-name", Index: 0, Address: "1.2.3.4", Bootstrap: true},
+							},
+							Properties: biproperty.Map{"fake-link-property": "fake-link-property-value"},
+						},
+					},
+				}
+			})
+
+			It("renders the job templates with the links of the instance", func() {
+				_, err := stateBuilder.Build(jobName, instanceID, deploymentManifest, fakeStage, agentState)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeLinkResolver.ResolveInputs).To(Equal([]fakebideplmanifest.ResolveInput{
+					{Manifest: deploymentManifest, JobName: "fake-deployment-job-name"},
+				}))
+			})
+
+			Context("when the instance is not the first of its job", func() {
+				BeforeEach(func() {
+					instanceID = 1
+					expectedLinkContexts["fake-release-job-name"]["fake-link-name"].Instances[0] = bitemplate.LinkInstanceContext{
+						Name: "fake-deployment-job-name", Index: 1, Address: "1.2.3.4", Bootstrap: false,
+					}
+				})
+
+				It("renders the links with the index of the instance", func() {
+					_, err := stateBuilder.Build(jobName, instanceID, deploymentManifest, fakeStage, agentState)
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			Context("when the link is provided by another job", func() {
+				BeforeEach(func() {
+					deploymentManifest.AZs = []bideplmanifest.AZ{{Name: "fake-provider-az"}}
+					deploymentManifest.Jobs = append(deploymentManifest.Jobs, bideplmanifest.Job{
+						Name: "fake-provider-job-name",
+						AZs:  []string{"fake-provider-az"},
+						Networks: []bideplmanifest.JobNetwork{
+							{Name: "fake-network-name", StaticIPs: []string{"1.2.3.6"}},
+						},
+					})
+					fakeLinkResolver.Links["fake-release-job-name"]["fake-link-name"] = bideplmanifest.Link{
+						Name:       "fake-provided-link-name",
+						Type:       "fake-link-type",
+						JobName:    "fake-provider-job-name",
+						Properties: biproperty.Map{"fake-link-property": "fake-link-property-value"},
+					}
+					expectedLinkContexts["fake-release-job-name"]["fake-link-name"] = bitemplate.LinkContext{
+						Address: "1.2.3.6",
+						Instances: []bitemplate.LinkInstanceContext{
+							{Name: "fake-provider-job-name", Index: 0, AZ: "fake-provider-az", Address: "1.2.3.6", Bootstrap: true},
+						},
+						Properties: biproperty.Map{"fake-link-property": "fake-link-property-value"},
+					}
+				})
+
+				It("renders the links with the az and static ip of the providing job", func() {
+					_, err := stateBuilder.Build(jobName, instanceID, deploymentManifest, fakeStage, agentState)
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when the job is in an az", func() {
+			BeforeEach(func() {
+				deploymentManifest.AZs = []bideplmanifest.AZ{{Name: "fake-az"}}
+				deploymentManifest.Jobs[0].AZs = []string{"fake-az"}
+				expectedAZ = "fake-az"
+			})
+
+			It("renders the job templates with the az", func() {
+				_, err := stateBuilder.Build(jobName, instanceID, deploymentManifest, fakeStage, agentState)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
 		Context("dynamic network without IP address", func() {
 			Context("single network", func() {
 				BeforeEach(func() {
 					deploymentManifest.Jobs[0].Networks[0].StaticIPs = nil
 					deploymentManifest.Networks[0].Type = "dynamic"
 					expectedIP = "1.2.3.5"
+					expectedNetworkContexts = map[string]bitemplate.NetworkContext{
+						"fake-network-name": {IP: "1.2.3.5", Default: []string{"dns", "gateway"}},
+					}
 				})
 
 				It("should not fail", func() {
@@ -366,6 +480,10 @@ func describeBuilder() {
 			Context("multiple networks", func() {
 				BeforeEach(func() {
 					expectedIP = "1.2.3.6"
+					expectedNetworkContexts = map[string]bitemplate.NetworkContext{
+						"fake-network-name":         {IP: "1.2.3.4"},
+						"fake-dynamic-network-name": {IP: "1.2.3.6", Default: []string{"dns", "gateway"}},
+					}
 					deploymentManifest.Networks = append(
 						deploymentManifest.Networks,
 						bideplmanifest.Network{
@@ -14,6 +14,7 @@ import (
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
 
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
@@ -188,11 +189,11 @@ var _ = Describe("Manager", func() {
 			diskDeployer := bivm.NewDiskDeployer(diskManagerFactory, diskRepo, logger)
 
 			vmManagerFactory := bivm.NewManagerFactory(vmRepo, stemcellRepo, diskDeployer, fakeUUIDGenerator, fs, logger)
-			sshTunnelFactory := bisshtunnel.NewFactory(logger)
+			sshTunnelFactory := bisshtunnel.NewFactory(biconfig.NewHostKeyRepo(deploymentStateService), logger)
 
 			mockStateBuilderFactory = mock_instance_state.NewMockBuilderFactory(mockCtrl)
 
-			instanceFactory := biinstance.NewFactory(mockStateBuilderFactory)
+			instanceFactory := biinstance.NewFactory(mockStateBuilderFactory, clock.NewClock())
 			instanceManagerFactory := biinstance.NewManagerFactory(sshTunnelFactory, instanceFactory, logger)
 			stemcellManagerFactory := bistemcell.NewManagerFactory(stemcellRepo)
 
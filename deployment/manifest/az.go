@@ -0,0 +1,20 @@
+package manifest
+
+import (
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+)
+
+// AZ is an availability zone, its cloud properties are merged into the cloud properties of the VMs and disks placed in it
+type AZ struct {
+	Name            string
+	CloudProperties biproperty.Map
+}
+
+// mergeCloudProperties returns the AZ cloud properties deep merged with the given cloud properties, which take precedence
+func mergeCloudProperties(az AZ, cloudProperties biproperty.Map) biproperty.Map {
+	if len(az.CloudProperties) == 0 {
+		return cloudProperties
+	}
+
+	return deepMergeProperties(az.CloudProperties, cloudProperties)
+}
@@ -0,0 +1,30 @@
+package fakes
+
+import (
+	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+)
+
+type FakeLinkResolver struct {
+	ResolveInputs []ResolveInput
+	Links         map[string]map[string]bideplmanifest.Link
+	ResolveErr    error
+}
+
+type ResolveInput struct {
+	Manifest bideplmanifest.Manifest
+	JobName  string
+}
+
+func NewFakeLinkResolver() *FakeLinkResolver {
+	return &FakeLinkResolver{
+		Links: map[string]map[string]bideplmanifest.Link{},
+	}
+}
+
+func (r *FakeLinkResolver) Resolve(deploymentManifest bideplmanifest.Manifest, jobName string) (map[string]map[string]bideplmanifest.Link, error) {
+	r.ResolveInputs = append(r.ResolveInputs, ResolveInput{
+		Manifest: deploymentManifest,
+		JobName:  jobName,
+	})
+	return r.Links, r.ResolveErr
+}
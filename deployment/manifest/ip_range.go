@@ -0,0 +1,83 @@
+package manifest
+
+import (
+	"bytes"
+	"net"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// ipRange is an inclusive range of addresses, written in manifests as a single address or 'first - last'
+type ipRange struct {
+	first net.IP
+	last  net.IP
+}
+
+func parseIPRange(value string) (ipRange, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) > 2 {
+		return ipRange{}, bosherr.Errorf("Parsing ip range '%s'", value)
+	}
+
+	first := net.ParseIP(strings.TrimSpace(parts[0]))
+	if first == nil {
+		return ipRange{}, bosherr.Errorf("Parsing ip range '%s': '%s' is not an ip", value, strings.TrimSpace(parts[0]))
+	}
+
+	last := first
+	if len(parts) == 2 {
+		last = net.ParseIP(strings.TrimSpace(parts[1]))
+		if last == nil {
+			return ipRange{}, bosherr.Errorf("Parsing ip range '%s': '%s' is not an ip", value, strings.TrimSpace(parts[1]))
+		}
+	}
+
+	if compareIPs(first, last) > 0 {
+		return ipRange{}, bosherr.Errorf("Parsing ip range '%s': first address is after the last address", value)
+	}
+
+	return ipRange{first: first, last: last}, nil
+}
+
+func parseIPRanges(values []string) ([]ipRange, error) {
+	ranges := []ipRange{}
+	for _, value := range values {
+		r, err := parseIPRange(value)
+		if err != nil {
+			return ranges, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func (r ipRange) contains(ip net.IP) bool {
+	return compareIPs(r.first, ip) <= 0 && compareIPs(ip, r.last) <= 0
+}
+
+func (r ipRange) within(ipNet *net.IPNet) bool {
+	return ipNet.Contains(r.first) && ipNet.Contains(r.last)
+}
+
+func compareIPs(a, b net.IP) int {
+	return bytes.Compare(a.To16(), b.To16())
+}
+
+// nextIP returns the address after ip, or nil when ip is the last address of its family
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			if ip.To4() != nil && next.To4() == nil {
+				return nil
+			}
+			return next
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package manifest
+
+import (
+	"net"
+
+	binet "github.com/cloudfoundry/bosh-init/common/net"
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// IPAM assigns addresses on manual networks.
+// A static IP is used as is, otherwise the previously allocated address is kept when it is still available,
+// or the next free address of the network's subnets in the job's availability zone is allocated.
+// Gateways, reserved and static ranges, network addresses and IPv4 broadcast addresses are never allocated.
+type IPAM interface {
+	// AllocateIPs returns a copy of the manifest where every manual job network has a static IP
+	AllocateIPs(Manifest) (Manifest, error)
+	// SaveAllocations records the addresses of an allocated manifest once it is deployed,
+	// releasing those of job networks it no longer has
+	SaveAllocations(Manifest) error
+}
+
+type ipam struct {
+	repo   biconfig.IPAllocationRepo
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewIPAM(repo biconfig.IPAllocationRepo, logger boshlog.Logger) IPAM {
+	return &ipam{
+		repo:   repo,
+		logger: logger,
+		logTag: "ipam",
+	}
+}
+
+func (i *ipam) AllocateIPs(deploymentManifest Manifest) (Manifest, error) {
+	networkMap := deploymentManifest.networkMap()
+
+	jobs := append([]Job(nil), deploymentManifest.Jobs...)
+	for jobIdx, job := range jobs {
+		if job.Lifecycle == JobLifecycleErrand {
+			continue
+		}
+
+		jobs[jobIdx].Networks = append([]JobNetwork(nil), job.Networks...)
+		for networkIdx, jobNetwork := range job.Networks {
+			network, found := networkMap[jobNetwork.Name]
+			if !found || network.Type != Manual {
+				continue
+			}
+
+			ip, err := i.allocateIP(job.Name, deploymentManifest.azName(job), jobNetwork, network)
+			if err != nil {
+				return Manifest{}, err
+			}
+			jobs[jobIdx].Networks[networkIdx].StaticIPs = []string{ip}
+		}
+	}
+
+	allocatedManifest := deploymentManifest
+	allocatedManifest.Jobs = jobs
+	return allocatedManifest, nil
+}
+
+func (i *ipam) SaveAllocations(allocatedManifest Manifest) error {
+	networkMap := allocatedManifest.networkMap()
+
+	records := []biconfig.IPAllocationRecord{}
+	for _, job := range allocatedManifest.Jobs {
+		if job.Lifecycle == JobLifecycleErrand {
+			continue
+		}
+
+		for _, jobNetwork := range job.Networks {
+			network, found := networkMap[jobNetwork.Name]
+			if !found || network.Type != Manual || len(jobNetwork.StaticIPs) == 0 {
+				continue
+			}
+
+			records = append(records, biconfig.IPAllocationRecord{
+				Job:     job.Name,
+				Network: jobNetwork.Name,
+				IP:      jobNetwork.StaticIPs[0],
+			})
+		}
+	}
+
+	err := i.repo.UpdateCurrent(records)
+	if err != nil {
+		return bosherr.WrapError(err, "Saving allocated IP addresses")
+	}
+	return nil
+}
+
+func (i *ipam) allocateIP(jobName, azName string, jobNetwork JobNetwork, network Network) (string, error) {
+	if len(jobNetwork.StaticIPs) > 0 {
+		return jobNetwork.StaticIPs[0], nil
+	}
+
+	recordedIP, found, err := i.repo.Find(jobName, network.Name)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Finding IP allocated to job '%s' on network '%s'", jobName, network.Name)
+	}
+
+	if found {
+		available, err := isAvailable(network, azName, net.ParseIP(recordedIP))
+		if err != nil {
+			return "", err
+		}
+		if available {
+			i.logger.Debug(i.logTag, "Keeping IP '%s' allocated to job '%s' on network '%s'", recordedIP, jobName, network.Name)
+			return recordedIP, nil
+		}
+		i.logger.Info(i.logTag, "IP '%s' allocated to job '%s' is no longer available on network '%s'", recordedIP, jobName, network.Name)
+	}
+
+	for _, subnet := range network.Subnets {
+		if !subnet.InAZ(azName) {
+			continue
+		}
+
+		ip, found, err := firstAvailableIP(subnet)
+		if err != nil {
+			return "", err
+		}
+
+		if found {
+			i.logger.Info(i.logTag, "Allocated IP '%s' to job '%s' on network '%s'", ip, jobName, network.Name)
+			return ip.String(), nil
+		}
+	}
+
+	return "", bosherr.Errorf("No IP addresses available in network '%s'", network.Name)
+}
+
+func isAvailable(network Network, azName string, ip net.IP) (bool, error) {
+	if ip == nil {
+		return false, nil
+	}
+
+	subnet, found := network.SubnetFor(ip.String())
+	if !found || !subnet.InAZ(azName) {
+		return false, nil
+	}
+
+	excluded, err := excludedRanges(subnet)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range excluded {
+		if r.contains(ip) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func firstAvailableIP(subnet Subnet) (net.IP, bool, error) {
+	_, ipNet, err := net.ParseCIDR(subnet.Range)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Parsing subnet range '%s'", subnet.Range)
+	}
+
+	excluded, err := excludedRanges(subnet)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ip := ipNet.IP
+	for ip != nil && ipNet.Contains(ip) {
+		skipped := false
+		for _, r := range excluded {
+			if r.contains(ip) {
+				ip = nextIP(r.last)
+				skipped = true
+				break
+			}
+		}
+
+		if !skipped {
+			return ip, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// excludedRanges returns the addresses of the subnet that can't be allocated
+func excludedRanges(subnet Subnet) ([]ipRange, error) {
+	_, ipNet, err := net.ParseCIDR(subnet.Range)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing subnet range '%s'", subnet.Range)
+	}
+
+	excluded := []ipRange{
+		{first: ipNet.IP, last: ipNet.IP},
+	}
+
+	if binet.IsIPv4Net(ipNet) {
+		broadcast := binet.LastAddress(ipNet)
+		excluded = append(excluded, ipRange{first: broadcast, last: broadcast})
+	}
+
+	if gateway := net.ParseIP(subnet.Gateway); gateway != nil {
+		excluded = append(excluded, ipRange{first: gateway, last: gateway})
+	}
+
+	reserved, err := parseIPRanges(subnet.Reserved)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing reserved ranges of subnet '%s'", subnet.Range)
+	}
+
+	static, err := parseIPRanges(subnet.Static)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing static ranges of subnet '%s'", subnet.Range)
+	}
+
+	return append(append(excluded, reserved...), static...), nil
+}
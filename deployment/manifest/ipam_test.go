@@ -0,0 +1,175 @@
+package manifest_test
+
+import (
+	"errors"
+
+	biconfig "github.com/cloudfoundry/bosh-init/config"
+	. "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakebiconfig "github.com/cloudfoundry/bosh-init/config/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("IPAM", func() {
+	var (
+		repo               *fakebiconfig.FakeIPAllocationRepo
+		ipam               IPAM
+		deploymentManifest Manifest
+	)
+
+	BeforeEach(func() {
+		repo = fakebiconfig.NewFakeIPAllocationRepo()
+		ipam = NewIPAM(repo, boshlog.NewLogger(boshlog.LevelNone))
+
+		deploymentManifest = Manifest{
+			Networks: []Network{
+				{
+					Name: "fake-manual-network",
+					Type: Manual,
+					Subnets: []Subnet{
+						{
+							Range:    "10.0.0.0/29",
+							Gateway:  "10.0.0.1",
+							Reserved: []string{"10.0.0.2 - 10.0.0.4"},
+							Static:   []string{"10.0.0.5"},
+						},
+						{
+							Range:    "10.0.1.0/24",
+							Gateway:  "10.0.1.1",
+							Reserved: []string{"10.0.1.2 - 10.0.1.9", "10.0.1.11"},
+						},
+					},
+				},
+				{
+					Name: "fake-dynamic-network",
+					Type: Dynamic,
+				},
+			},
+			Jobs: []Job{
+				{
+					Name: "fake-job",
+					Networks: []JobNetwork{
+						{Name: "fake-manual-network"},
+						{Name: "fake-dynamic-network"},
+					},
+				},
+			},
+		}
+	})
+
+	It("allocates the first free address that is not a gateway, reserved, static, network or broadcast address", func() {
+		allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.0.6"}))
+		Expect(allocatedManifest.Jobs[0].Networks[1].StaticIPs).To(BeEmpty())
+		Expect(deploymentManifest.Jobs[0].Networks[0].StaticIPs).To(BeEmpty())
+		Expect(repo.UpdateCurrentRecords).To(BeNil())
+	})
+
+	It("allocates from the next subnet when a subnet is full", func() {
+		deploymentManifest.Networks[0].Subnets[0].Reserved = []string{"10.0.0.2 - 10.0.0.6"}
+
+		allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.1.10"}))
+	})
+
+	It("keeps the previously allocated address when it is still available", func() {
+		repo.Allocations["fake-job/fake-manual-network"] = "10.0.1.100"
+
+		allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.1.100"}))
+	})
+
+	It("allocates a new address when the previously allocated address was reserved since", func() {
+		repo.Allocations["fake-job/fake-manual-network"] = "10.0.1.11"
+
+		allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.0.6"}))
+	})
+
+	It("uses the static ip from the manifest", func() {
+		deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.0.0.5"}
+		repo.Allocations["fake-job/fake-manual-network"] = "10.0.1.100"
+
+		allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.0.5"}))
+	})
+
+	It("allocates addresses from IPv6 subnets", func() {
+		deploymentManifest.Networks[0].Subnets = []Subnet{
+			{
+				Range:    "2001:db8::/64",
+				Gateway:  "2001:db8::1",
+				Reserved: []string{"2001:db8::2 - 2001:db8::ff"},
+			},
+		}
+
+		allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"2001:db8::100"}))
+	})
+
+	Context("when the subnets are in azs", func() {
+		BeforeEach(func() {
+			deploymentManifest.AZs = []AZ{{Name: "z1"}, {Name: "z2"}}
+			deploymentManifest.Networks[0].Subnets[0].AZ = "z1"
+			deploymentManifest.Networks[0].Subnets[1].AZ = "z2"
+			deploymentManifest.Jobs[0].AZs = []string{"z2"}
+		})
+
+		It("allocates from the subnets in the az of the job", func() {
+			allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.1.10"}))
+		})
+
+		It("does not keep a previously allocated address from another az", func() {
+			repo.Allocations["fake-job/fake-manual-network"] = "10.0.0.6"
+
+			allocatedManifest, err := ipam.AllocateIPs(deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(allocatedManifest.Jobs[0].Networks[0].StaticIPs).To(Equal([]string{"10.0.1.10"}))
+		})
+	})
+
+	It("returns an error when no address is available", func() {
+		deploymentManifest.Networks[0].Subnets = deploymentManifest.Networks[0].Subnets[:1]
+		deploymentManifest.Networks[0].Subnets[0].Reserved = []string{"10.0.0.2 - 10.0.0.6"}
+
+		_, err := ipam.AllocateIPs(deploymentManifest)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("No IP addresses available in network 'fake-manual-network'"))
+	})
+
+	Describe("SaveAllocations", func() {
+		It("replaces the recorded addresses with those of the manual job networks", func() {
+			deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.0.0.6"}
+			deploymentManifest.Jobs = append(deploymentManifest.Jobs, Job{
+				Name:      "fake-errand",
+				Lifecycle: JobLifecycleErrand,
+				Networks:  []JobNetwork{{Name: "fake-manual-network", StaticIPs: []string{"10.0.0.7"}}},
+			})
+
+			err := ipam.SaveAllocations(deploymentManifest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(repo.UpdateCurrentRecords).To(Equal([]biconfig.IPAllocationRecord{
+				{Job: "fake-job", Network: "fake-manual-network", IP: "10.0.0.6"},
+			}))
+		})
+
+		It("returns an error when the allocations can't be saved", func() {
+			repo.UpdateCurrentErr = errors.New("fake-save-error")
+
+			err := ipam.SaveAllocations(deploymentManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-save-error"))
+		})
+	})
+})
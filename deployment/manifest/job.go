@@ -13,7 +13,9 @@ type Job struct {
 	PersistentDisk     int
 	PersistentDiskPool string
 	ResourcePool       string
-	Properties         biproperty.Map
+	// AZs lists the availability zones the job may be placed in, bosh-init places its instance in the first one
+	AZs        []string
+	Properties biproperty.Map
 }
 
 type JobLifecycle string
@@ -27,6 +29,10 @@ type ReleaseJobRef struct {
 	Name       string
 	Release    string
 	Properties *biproperty.Map
+	// Provides maps the names of links provided by the release job to the names they are provided as ('as')
+	Provides map[string]string
+	// Consumes maps the names of links consumed by the release job to the names of the provided links ('from')
+	Consumes map[string]string
 }
 
 type JobNetwork struct {
@@ -41,3 +47,13 @@ const (
 	NetworkDefaultDNS     NetworkDefault = "dns"
 	NetworkDefaultGateway NetworkDefault = "gateway"
 )
+
+// HasNetworkDefaults reports whether any of the job's networks sets a default
+func (j Job) HasNetworkDefaults() bool {
+	for _, jobNetwork := range j.Networks {
+		if len(jobNetwork.Defaults) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package manifest
+
+import (
+	"fmt"
+)
+
+// Keys records which keys the manifest used. Manifests with instance_groups, stemcells, vm_types and disk_types
+// are parsed into jobs, resource pools and disk pools, validation errors cite the keys of the manifest instead.
+type Keys struct {
+	InstanceGroups bool
+	VMTypes        bool
+	DiskTypes      bool
+	// VMTypeResourcePools describes the resource pools built from the vm_type of a job, by resource pool index
+	VMTypeResourcePools map[int]VMTypeResourcePool
+}
+
+// VMTypeResourcePool is a resource pool built from the vm_type, stemcell and networks of a job
+type VMTypeResourcePool struct {
+	JobIndex      int
+	StemcellIndex int
+}
+
+func (k Keys) job(idx int) string {
+	if k.InstanceGroups {
+		return fmt.Sprintf("instance_groups[%d]", idx)
+	}
+	return fmt.Sprintf("jobs[%d]", idx)
+}
+
+func (k Keys) template(jobIdx, templateIdx int) string {
+	if k.InstanceGroups {
+		return fmt.Sprintf("%s.jobs[%d]", k.job(jobIdx), templateIdx)
+	}
+	return fmt.Sprintf("%s.templates[%d]", k.job(jobIdx), templateIdx)
+}
+
+func (k Keys) templates(jobIdx int) string {
+	if k.InstanceGroups {
+		return k.job(jobIdx) + ".jobs"
+	}
+	return k.job(jobIdx) + ".templates"
+}
+
+// resourcePoolKey returns the job key referring to a resource pool, and what it refers to
+func (k Keys) resourcePoolKey() (string, string) {
+	if k.VMTypes {
+		return "vm_type", "vm_type"
+	}
+	return "resource_pool", "resource pool"
+}
+
+// persistentDiskPoolKey returns the job key referring to a disk pool, and what it refers to
+func (k Keys) persistentDiskPoolKey() (string, string) {
+	if k.DiskTypes {
+		return "persistent_disk_type", "disk_type"
+	}
+	return "persistent_disk_pool", "disk pool"
+}
+
+func (k Keys) diskPool(idx int) string {
+	if k.DiskTypes {
+		return fmt.Sprintf("disk_types[%d]", idx)
+	}
+	return fmt.Sprintf("disk_pools[%d]", idx)
+}
+
+func (k Keys) resourcePoolStemcell(idx int) string {
+	if vmTypeResourcePool, found := k.VMTypeResourcePools[idx]; found {
+		return fmt.Sprintf("stemcells[%d]", vmTypeResourcePool.StemcellIndex)
+	}
+	return fmt.Sprintf("resource_pools[%d].stemcell", idx)
+}
@@ -0,0 +1,199 @@
+package manifest
+
+import (
+	"strings"
+
+	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
+	bireljob "github.com/cloudfoundry/bosh-init/release/job"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+)
+
+// Link is a link provided by a release job of the deployment
+type Link struct {
+	// Name is the name the link is provided as
+	Name string
+	Type string
+	// JobName is the name of the deployment job providing the link
+	JobName    string
+	Properties biproperty.Map
+}
+
+// LinkResolver resolves the links consumed by release jobs within the deployment.
+// A consumed link is satisfied by the link provided as its 'from' name, or else by the only link provided with its type.
+type LinkResolver interface {
+	// Resolve returns the links consumed by each release job of the job, keyed by release job name and link name
+	Resolve(deploymentManifest Manifest, jobName string) (map[string]map[string]Link, error)
+}
+
+type linkResolver struct {
+	releaseJobResolver bideplrel.JobResolver
+}
+
+func NewLinkResolver(releaseJobResolver bideplrel.JobResolver) LinkResolver {
+	return &linkResolver{
+		releaseJobResolver: releaseJobResolver,
+	}
+}
+
+func (r *linkResolver) Resolve(deploymentManifest Manifest, jobName string) (map[string]map[string]Link, error) {
+	job, found := deploymentManifest.FindJobByName(jobName)
+	if !found {
+		return nil, bosherr.Errorf("Could not find job with name: %s", jobName)
+	}
+
+	links := map[string]map[string]Link{}
+	var providedLinks []Link
+	errs := []error{}
+
+	for _, jobRef := range job.Templates {
+		releaseJob, err := r.releaseJobResolver.Resolve(jobRef.Name, jobRef.Release)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Resolving job '%s' in release '%s'", jobRef.Name, jobRef.Release)
+		}
+
+		if len(releaseJob.Consumes) == 0 {
+			continue
+		}
+
+		if providedLinks == nil {
+			providedLinks, err = r.providedLinks(deploymentManifest)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, consumed := range releaseJob.Consumes {
+			link, found, err := r.resolveLink(job, jobRef, consumed, providedLinks)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if found {
+				if links[jobRef.Name] == nil {
+					links[jobRef.Name] = map[string]Link{}
+				}
+				links[jobRef.Name][consumed.Name] = link
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, bosherr.NewMultiError(errs...)
+	}
+
+	return links, nil
+}
+
+func (r *linkResolver) providedLinks(deploymentManifest Manifest) ([]Link, error) {
+	links := []Link{}
+	for _, job := range deploymentManifest.Jobs {
+		for _, jobRef := range job.Templates {
+			releaseJob, err := r.releaseJobResolver.Resolve(jobRef.Name, jobRef.Release)
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Resolving job '%s' in release '%s'", jobRef.Name, jobRef.Release)
+			}
+
+			for _, provided := range releaseJob.Provides {
+				name := provided.Name
+				if as := jobRef.Provides[provided.Name]; as != "" {
+					name = as
+				}
+
+				links = append(links, Link{
+					Name:       name,
+					Type:       provided.Type,
+					JobName:    job.Name,
+					Properties: linkProperties(provided, releaseJob, jobRef, job, deploymentManifest.Properties),
+				})
+			}
+		}
+	}
+	return links, nil
+}
+
+func (r *linkResolver) resolveLink(job Job, jobRef ReleaseJobRef, consumed bireljob.LinkDefinition, providedLinks []Link) (Link, bool, error) {
+	from := jobRef.Consumes[consumed.Name]
+
+	candidates := []Link{}
+	for _, link := range providedLinks {
+		if from != "" {
+			if link.Name != from {
+				continue
+			}
+			if link.Type != consumed.Type {
+				return Link{}, false, bosherr.Errorf("Job '%s' in instance group '%s' consumes link '%s' of type '%s', but the link provided as '%s' is of type '%s'", jobRef.Name, job.Name, consumed.Name, consumed.Type, from, link.Type)
+			}
+		} else if link.Type != consumed.Type {
+			continue
+		}
+		candidates = append(candidates, link)
+	}
+
+	switch len(candidates) {
+	case 0:
+		if consumed.Optional {
+			return Link{}, false, nil
+		}
+		return Link{}, false, bosherr.Errorf("Job '%s' in instance group '%s' consumes link '%s' of type '%s', but no job in the deployment provides it", jobRef.Name, job.Name, consumed.Name, consumed.Type)
+	case 1:
+		return candidates[0], true, nil
+	}
+
+	providers := make([]string, len(candidates))
+	for i, link := range candidates {
+		providers[i] = "'" + link.Name + "' in instance group '" + link.JobName + "'"
+	}
+	return Link{}, false, bosherr.Errorf("Job '%s' in instance group '%s' consumes link '%s' of type '%s', but it is provided by %s, use 'from' to choose one", jobRef.Name, job.Name, consumed.Name, consumed.Type, strings.Join(providers, ", "))
+}
+
+// linkProperties returns the properties exposed by a provided link, looked up like the properties of the job's templates:
+// in the release job properties if they are set, or else in the deployment job and global properties, defaulting to the job spec.
+func linkProperties(provided bireljob.LinkDefinition, releaseJob bireljob.Job, jobRef ReleaseJobRef, job Job, globalProperties biproperty.Map) biproperty.Map {
+	sources := []biproperty.Map{job.Properties, globalProperties}
+	if jobRef.Properties != nil {
+		sources = []biproperty.Map{*jobRef.Properties}
+	}
+
+	properties := biproperty.Map{}
+	for _, name := range provided.Properties {
+		value := releaseJob.Properties[name].Default
+		for _, source := range sources {
+			if found, ok := lookupProperty(source, name); ok {
+				value = found
+				break
+			}
+		}
+		setProperty(properties, name, value)
+	}
+	return properties
+}
+
+func lookupProperty(properties biproperty.Map, name string) (biproperty.Property, bool) {
+	var value biproperty.Property = properties
+	for _, key := range strings.Split(name, ".") {
+		nested, ok := value.(biproperty.Map)
+		if !ok {
+			return nil, false
+		}
+		value, ok = nested[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func setProperty(properties biproperty.Map, name string, value biproperty.Property) {
+	keys := strings.Split(name, ".")
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := properties[key].(biproperty.Map)
+		if !ok {
+			nested = biproperty.Map{}
+			properties[key] = nested
+		}
+		properties = nested
+	}
+	properties[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,165 @@
+package manifest_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
+	birel "github.com/cloudfoundry/bosh-init/release"
+	fakebirel "github.com/cloudfoundry/bosh-init/release/fakes"
+	bireljob "github.com/cloudfoundry/bosh-init/release/job"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	biproperty "github.com/cloudfoundry/bosh-utils/property"
+)
+
+var _ = Describe("LinkResolver", func() {
+	var (
+		fakeRelease        *fakebirel.FakeRelease
+		linkResolver       LinkResolver
+		deploymentManifest Manifest
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		releaseManager := birel.NewManager(logger)
+
+		fakeRelease = fakebirel.New("fake-release-name", "1.0")
+		fakeRelease.ReleaseJobs = []bireljob.Job{
+			{
+				Name:     "fake-web",
+				Consumes: []bireljob.LinkDefinition{{Name: "db", Type: "database"}},
+			},
+			{
+				Name: "fake-postgres",
+				Properties: map[string]bireljob.PropertyDefinition{
+					"postgres.port":    {Default: 5432},
+					"postgres.user":    {Default: "fake-default-user"},
+					"postgres.unknown": {},
+				},
+				Provides: []bireljob.LinkDefinition{
+					{Name: "db", Type: "database", Properties: []string{"postgres.port", "postgres.user"}},
+				},
+			},
+			{
+				Name:     "fake-mysql",
+				Provides: []bireljob.LinkDefinition{{Name: "db", Type: "database"}},
+			},
+		}
+		releaseManager.Add(fakeRelease)
+
+		linkResolver = NewLinkResolver(bideplrel.NewJobResolver(releaseManager))
+
+		deploymentManifest = Manifest{
+			Properties: biproperty.Map{
+				"postgres": biproperty.Map{"user": "fake-global-user"},
+			},
+			Jobs: []Job{
+				{
+					Name: "fake-instance-group",
+					Templates: []ReleaseJobRef{
+						{Name: "fake-web", Release: "fake-release-name"},
+						{Name: "fake-postgres", Release: "fake-release-name"},
+					},
+					Properties: biproperty.Map{
+						"postgres": biproperty.Map{"port": 6543},
+					},
+				},
+			},
+		}
+	})
+
+	It("resolves a consumed link to the only provided link of its type", func() {
+		links, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links).To(Equal(map[string]map[string]Link{
+			"fake-web": {
+				"db": {
+					Name:    "db",
+					Type:    "database",
+					JobName: "fake-instance-group",
+					Properties: biproperty.Map{
+						"postgres": biproperty.Map{
+							"port": 6543,
+							"user": "fake-global-user",
+						},
+					},
+				},
+			},
+		}))
+	})
+
+	It("uses the release job properties for the link properties when they are set", func() {
+		deploymentManifest.Jobs[0].Templates[1].Properties = &biproperty.Map{
+			"postgres": biproperty.Map{"user": "fake-job-user"},
+		}
+
+		links, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(links["fake-web"]["db"].Properties).To(Equal(biproperty.Map{
+			"postgres": biproperty.Map{
+				"port": 5432,
+				"user": "fake-job-user",
+			},
+		}))
+	})
+
+	Context("when several jobs provide a link of the consumed type", func() {
+		BeforeEach(func() {
+			deploymentManifest.Jobs[0].Templates = append(deploymentManifest.Jobs[0].Templates, ReleaseJobRef{
+				Name:     "fake-mysql",
+				Release:  "fake-release-name",
+				Provides: map[string]string{"db": "fake-mysql-db"},
+			})
+		})
+
+		It("returns an error", func() {
+			_, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Job 'fake-web' in instance group 'fake-instance-group' consumes link 'db' of type 'database', but it is provided by 'db' in instance group 'fake-instance-group', 'fake-mysql-db' in instance group 'fake-instance-group', use 'from' to choose one"))
+		})
+
+		It("resolves the link provided as the 'from' name", func() {
+			deploymentManifest.Jobs[0].Templates[0].Consumes = map[string]string{"db": "fake-mysql-db"}
+
+			links, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(links["fake-web"]["db"].Name).To(Equal("fake-mysql-db"))
+			Expect(links["fake-web"]["db"].Properties).To(Equal(biproperty.Map{}))
+		})
+	})
+
+	Context("when no job provides the consumed link", func() {
+		BeforeEach(func() {
+			deploymentManifest.Jobs[0].Templates = deploymentManifest.Jobs[0].Templates[:1]
+		})
+
+		It("returns an error", func() {
+			_, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Job 'fake-web' in instance group 'fake-instance-group' consumes link 'db' of type 'database', but no job in the deployment provides it"))
+		})
+
+		It("skips the link when it is optional", func() {
+			fakeRelease.ReleaseJobs[0].Consumes[0].Optional = true
+
+			links, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(links).To(BeEmpty())
+		})
+	})
+
+	It("returns an error when the 'from' link is of another type", func() {
+		fakeRelease.ReleaseJobs[2].Provides[0].Type = "fake-other-type"
+		deploymentManifest.Jobs[0].Templates = append(deploymentManifest.Jobs[0].Templates, ReleaseJobRef{
+			Name:     "fake-mysql",
+			Release:  "fake-release-name",
+			Provides: map[string]string{"db": "fake-mysql-db"},
+		})
+		deploymentManifest.Jobs[0].Templates[0].Consumes = map[string]string{"db": "fake-mysql-db"}
+
+		_, err := linkResolver.Resolve(deploymentManifest, "fake-instance-group")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("the link provided as 'fake-mysql-db' is of type 'fake-other-type'"))
+	})
+})
@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"time"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 )
@@ -12,11 +14,15 @@ type Manifest struct {
 	Networks      []Network
 	DiskPools     []DiskPool
 	ResourcePools []ResourcePool
+	AZs           []AZ
 	Update        Update
+	Keys          Keys
 }
 
 type Update struct {
 	UpdateWatchTime WatchTime
+	// MaxDrainWait limits the time spent waiting for job drain scripts, zero means no limit
+	MaxDrainWait time.Duration
 }
 
 // NetworkInterfaces returns a map of network names to network interfaces.
@@ -43,12 +49,94 @@ func (d Manifest) NetworkInterfaces(jobName string) (map[string]biproperty.Map,
 		ifaceMap[job.Networks[0].Name]["default"] = []NetworkDefault{NetworkDefaultDNS, NetworkDefaultGateway}
 	}
 
+	if ipv4Network, ipv6Network, ok := d.dualStackNetworks(job); ok && !job.HasNetworkDefaults() {
+		ifaceMap[ipv4Network.Name]["default"] = []NetworkDefault{NetworkDefaultDNS, NetworkDefaultGateway}
+		ifaceMap[ipv6Network.Name]["default"] = []NetworkDefault{NetworkDefaultGateway}
+	}
+
 	return ifaceMap, nil
 }
 
+// dualStackNetworks returns the IPv4 and IPv6 network of a job that has exactly one network of each family
+func (d Manifest) dualStackNetworks(job Job) (JobNetwork, JobNetwork, bool) {
+	if len(job.Networks) != 2 {
+		return JobNetwork{}, JobNetwork{}, false
+	}
+
+	networkMap := d.networkMap()
+	first, second := job.Networks[0], job.Networks[1]
+	firstIsIPv6 := networkMap[first.Name].IsIPv6(first.StaticIPs)
+	secondIsIPv6 := networkMap[second.Name].IsIPv6(second.StaticIPs)
+
+	switch {
+	case !firstIsIPv6 && secondIsIPv6:
+		return first, second, true
+	case firstIsIPv6 && !secondIsIPv6:
+		return second, first, true
+	}
+
+	return JobNetwork{}, JobNetwork{}, false
+}
+
 func (d Manifest) JobName() string {
-	// Currently we deploy only one job
-	return d.Jobs[0].Name
+	// Currently we deploy only one job, errands are run on its VM
+	serviceJobs := d.ServiceJobs()
+	if len(serviceJobs) == 0 {
+		return ""
+	}
+	return serviceJobs[0].Name
+}
+
+// ServiceJobs returns the jobs that are deployed, excluding errands.
+func (d Manifest) ServiceJobs() []Job {
+	jobs := []Job{}
+	for _, job := range d.Jobs {
+		if job.Lifecycle != JobLifecycleErrand {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+func (d Manifest) FindErrandByName(errandName string) (Job, bool) {
+	job, found := d.FindJobByName(errandName)
+	if !found || job.Lifecycle != JobLifecycleErrand {
+		return Job{}, false
+	}
+
+	return job, true
+}
+
+// WithColocatedErrand returns a copy of the manifest where the errand's templates are added to the service job,
+// so that the errand can be rendered and run on the deployed VM without removing the service's jobs.
+// Errand job properties apply to the errand's templates only.
+func (d Manifest) WithColocatedErrand(errandName string) (Manifest, error) {
+	errand, found := d.FindErrandByName(errandName)
+	if !found {
+		return Manifest{}, bosherr.Errorf("Errand '%s' not found in deployment manifest", errandName)
+	}
+
+	serviceJobName := d.JobName()
+
+	jobs := make([]Job, 0, len(d.Jobs))
+	for _, job := range d.Jobs {
+		if job.Name == serviceJobName {
+			templates := append([]ReleaseJobRef{}, job.Templates...)
+			for _, template := range errand.Templates {
+				if template.Properties == nil {
+					errandProperties := errand.Properties
+					template.Properties = &errandProperties
+				}
+				templates = append(templates, template)
+			}
+			job.Templates = templates
+		}
+		jobs = append(jobs, job)
+	}
+
+	manifest := d
+	manifest.Jobs = jobs
+	return manifest, nil
 }
 
 func (d Manifest) Stemcell(jobName string) (StemcellRef, error) {
@@ -65,13 +153,19 @@ func (d Manifest) ResourcePool(jobName string) (ResourcePool, error) {
 		return ResourcePool{}, bosherr.Errorf("Could not find job with name: %s", jobName)
 	}
 
-	for _, resourcePool := range d.ResourcePools {
-		if resourcePool.Name == job.ResourcePool {
-			return resourcePool, nil
-		}
+	resourcePool, found := d.findResourcePool(job.ResourcePool)
+	if !found {
+		err := bosherr.Errorf("Could not find resource pool '%s' for job '%s'", job.ResourcePool, jobName)
+		return ResourcePool{}, err
+	}
+
+	az, err := d.AZ(jobName)
+	if err != nil {
+		return ResourcePool{}, err
 	}
-	err := bosherr.Errorf("Could not find resource pool '%s' for job '%s'", job.ResourcePool, jobName)
-	return ResourcePool{}, err
+	resourcePool.CloudProperties = mergeCloudProperties(az, resourcePool.CloudProperties)
+
+	return resourcePool, nil
 }
 
 func (d Manifest) DiskPool(jobName string) (DiskPool, error) {
@@ -80,25 +174,76 @@ func (d Manifest) DiskPool(jobName string) (DiskPool, error) {
 		return DiskPool{}, bosherr.Errorf("Could not find job with name: %s", jobName)
 	}
 
+	diskPool := DiskPool{}
 	if job.PersistentDiskPool != "" {
-		for _, diskPool := range d.DiskPools {
-			if diskPool.Name == job.PersistentDiskPool {
-				return diskPool, nil
+		found := false
+		for _, pool := range d.DiskPools {
+			if pool.Name == job.PersistentDiskPool {
+				diskPool = pool
+				found = true
+				break
 			}
 		}
-		err := bosherr.Errorf("Could not find persistent disk pool '%s' for job '%s'", job.PersistentDiskPool, jobName)
+		if !found {
+			err := bosherr.Errorf("Could not find persistent disk pool '%s' for job '%s'", job.PersistentDiskPool, jobName)
+			return DiskPool{}, err
+		}
+	} else if job.PersistentDisk > 0 {
+		diskPool = DiskPool{
+			DiskSize:        job.PersistentDisk,
+			CloudProperties: biproperty.Map{},
+		}
+	} else {
+		return DiskPool{}, nil
+	}
+
+	az, err := d.AZ(jobName)
+	if err != nil {
 		return DiskPool{}, err
 	}
+	diskPool.CloudProperties = mergeCloudProperties(az, diskPool.CloudProperties)
 
-	if job.PersistentDisk > 0 {
-		diskPool := DiskPool{
-			DiskSize:        job.PersistentDisk,
-			CloudProperties: biproperty.Map{},
+	return diskPool, nil
+}
+
+// AZ returns the availability zone of the job's instance: the first of the job's azs, or else the az of its resource pool.
+// An empty AZ is returned when the job is not placed in an availability zone.
+func (d Manifest) AZ(jobName string) (AZ, error) {
+	job, found := d.FindJobByName(jobName)
+	if !found {
+		return AZ{}, bosherr.Errorf("Could not find job with name: %s", jobName)
+	}
+
+	azName := d.azName(job)
+	if azName == "" {
+		return AZ{}, nil
+	}
+
+	for _, az := range d.AZs {
+		if az.Name == azName {
+			return az, nil
 		}
-		return diskPool, nil
 	}
+	return AZ{}, bosherr.Errorf("Could not find az '%s' for job '%s'", azName, jobName)
+}
+
+func (d Manifest) azName(job Job) string {
+	if len(job.AZs) > 0 {
+		return job.AZs[0]
+	}
+	if resourcePool, found := d.findResourcePool(job.ResourcePool); found {
+		return resourcePool.AZ
+	}
+	return ""
+}
 
-	return DiskPool{}, nil
+func (d Manifest) findResourcePool(name string) (ResourcePool, bool) {
+	for _, resourcePool := range d.ResourcePools {
+		if resourcePool.Name == name {
+			return resourcePool, true
+		}
+	}
+	return ResourcePool{}, false
 }
 
 func (d Manifest) networkMap() map[string]Network {
@@ -120,13 +265,15 @@ func (d Manifest) FindJobByName(jobName string) (Job, bool) {
 }
 
 func (d Manifest) GetListOfTemplateReleases() (map[string]string, bool) {
-	if len(d.Jobs) != 1 {
+	if len(d.ServiceJobs()) != 1 {
 		return nil, false
 	} else {
 		result := make(map[string]string)
 
-		for _, job := range d.Jobs[0].Templates {
-			result[job.Release] = job.Release
+		for _, job := range d.Jobs {
+			for _, template := range job.Templates {
+				result[template.Release] = template.Release
+			}
 		}
 
 		return result, true
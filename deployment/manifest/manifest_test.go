@@ -156,6 +156,66 @@ var _ = Describe("Manifest", func() {
 				})
 			})
 
+			Context("given a dual-stack job with an IPv4 and an IPv6 network", func() {
+				BeforeEach(func() {
+					deploymentManifest.Networks = append(deploymentManifest.Networks, Network{
+						Name: "fake-ipv6-network-name",
+						Type: "manual",
+						Subnets: []Subnet{
+							{
+								Range:           "2001:db8:1::/64",
+								Gateway:         "2001:db8:1::1",
+								CloudProperties: biproperty.Map{},
+							},
+						},
+					})
+					deploymentManifest.Jobs = append(deploymentManifest.Jobs, Job{
+						Name: "dual-stack-job",
+						Networks: []JobNetwork{
+							{
+								Name:      "fake-ipv6-network-name",
+								StaticIPs: []string{"2001:db8:1::6"},
+							},
+							{
+								Name:      "fake-manual-network-name",
+								StaticIPs: []string{"1.2.3.6"},
+							},
+						},
+					})
+				})
+
+				It("sets dns and gateway defaults on the IPv4 network and a gateway default on the IPv6 network when none are specified", func() {
+					Expect(deploymentManifest.NetworkInterfaces("dual-stack-job")).To(Equal(map[string]biproperty.Map{
+						"fake-ipv6-network-name": biproperty.Map{
+							"type":             "manual",
+							"ip":               "2001:db8:1::6",
+							"netmask":          "ffff:ffff:ffff:ffff::",
+							"gateway":          "2001:db8:1::1",
+							"cloud_properties": biproperty.Map{},
+							"default":          []NetworkDefault{"gateway"},
+						},
+						"fake-manual-network-name": biproperty.Map{
+							"type":             "manual",
+							"ip":               "1.2.3.6",
+							"netmask":          "255.255.252.0",
+							"gateway":          "1.1.1.1",
+							"cloud_properties": biproperty.Map{},
+							"default":          []NetworkDefault{"dns", "gateway"},
+						},
+					}))
+				})
+
+				It("keeps the specified defaults", func() {
+					deploymentManifest.Jobs[3].Networks[0].Defaults = []NetworkDefault{NetworkDefaultDNS, NetworkDefaultGateway}
+					deploymentManifest.Jobs[3].Networks[1].Defaults = []NetworkDefault{NetworkDefaultGateway}
+
+					networkInterfaces, err := deploymentManifest.NetworkInterfaces("dual-stack-job")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(networkInterfaces["fake-ipv6-network-name"]["default"]).To(Equal([]NetworkDefault{"dns", "gateway"}))
+					Expect(networkInterfaces["fake-manual-network-name"]["default"]).To(Equal([]NetworkDefault{"gateway"}))
+				})
+			})
+
 			It("returns an error when the deployment does not have a job with requested name", func() {
 				networkInterfaces, err := deploymentManifest.NetworkInterfaces("non-existant-job")
 				Expect(networkInterfaces).To(Equal(map[string]biproperty.Map{}))
@@ -368,4 +428,229 @@ var _ = Describe("Manifest", func() {
 			})
 		})
 	})
+
+	Describe("AZ", func() {
+		BeforeEach(func() {
+			deploymentManifest = Manifest{
+				AZs: []AZ{
+					{
+						Name: "z1",
+						CloudProperties: biproperty.Map{
+							"availability_zone": "zone-1",
+							"fake-key":          "fake-az-value",
+						},
+					},
+					{
+						Name: "z2",
+						CloudProperties: biproperty.Map{
+							"availability_zone": "zone-2",
+						},
+					},
+				},
+				ResourcePools: []ResourcePool{
+					{
+						Name: "fake-resource-pool-name",
+						AZ:   "z2",
+						CloudProperties: biproperty.Map{
+							"fake-key": "fake-resource-pool-value",
+						},
+					},
+				},
+				DiskPools: []DiskPool{
+					{
+						Name:     "fake-disk-pool-name",
+						DiskSize: 1024,
+						CloudProperties: biproperty.Map{
+							"fake-disk-key": "fake-disk-value",
+						},
+					},
+				},
+				Jobs: []Job{
+					{
+						Name:               "fake-job-name",
+						ResourcePool:       "fake-resource-pool-name",
+						PersistentDiskPool: "fake-disk-pool-name",
+						AZs:                []string{"z1"},
+					},
+				},
+			}
+		})
+
+		It("is the first az of the job", func() {
+			az, err := deploymentManifest.AZ("fake-job-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(az.Name).To(Equal("z1"))
+		})
+
+		It("merges the az cloud properties into the resource pool cloud properties", func() {
+			resourcePool, err := deploymentManifest.ResourcePool("fake-job-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resourcePool.CloudProperties).To(Equal(biproperty.Map{
+				"availability_zone": "zone-1",
+				"fake-key":          "fake-resource-pool-value",
+			}))
+			Expect(deploymentManifest.ResourcePools[0].CloudProperties).To(Equal(biproperty.Map{
+				"fake-key": "fake-resource-pool-value",
+			}))
+		})
+
+		It("deep merges nested az cloud properties", func() {
+			deploymentManifest.AZs[0].CloudProperties["fake-nested"] = biproperty.Map{
+				"fake-az-key":     "fake-az-value",
+				"fake-shared-key": "fake-az-value",
+			}
+			deploymentManifest.ResourcePools[0].CloudProperties["fake-nested"] = biproperty.Map{
+				"fake-shared-key":        "fake-resource-pool-value",
+				"fake-resource-pool-key": "fake-resource-pool-value",
+			}
+
+			resourcePool, err := deploymentManifest.ResourcePool("fake-job-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resourcePool.CloudProperties["fake-nested"]).To(Equal(biproperty.Map{
+				"fake-az-key":            "fake-az-value",
+				"fake-shared-key":        "fake-resource-pool-value",
+				"fake-resource-pool-key": "fake-resource-pool-value",
+			}))
+			Expect(deploymentManifest.AZs[0].CloudProperties["fake-nested"]).To(Equal(biproperty.Map{
+				"fake-az-key":     "fake-az-value",
+				"fake-shared-key": "fake-az-value",
+			}))
+		})
+
+		It("merges the az cloud properties into the disk pool cloud properties", func() {
+			diskPool, err := deploymentManifest.DiskPool("fake-job-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskPool.CloudProperties).To(Equal(biproperty.Map{
+				"availability_zone": "zone-1",
+				"fake-key":          "fake-az-value",
+				"fake-disk-key":     "fake-disk-value",
+			}))
+		})
+
+		Context("when the job does not specify azs", func() {
+			BeforeEach(func() {
+				deploymentManifest.Jobs[0].AZs = nil
+			})
+
+			It("is the az of the resource pool", func() {
+				az, err := deploymentManifest.AZ("fake-job-name")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(az.Name).To(Equal("z2"))
+			})
+		})
+
+		Context("when neither the job nor its resource pool specify an az", func() {
+			BeforeEach(func() {
+				deploymentManifest.Jobs[0].AZs = nil
+				deploymentManifest.ResourcePools[0].AZ = ""
+			})
+
+			It("is empty", func() {
+				az, err := deploymentManifest.AZ("fake-job-name")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(az).To(Equal(AZ{}))
+			})
+
+			It("does not change the resource pool cloud properties", func() {
+				resourcePool, err := deploymentManifest.ResourcePool("fake-job-name")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resourcePool.CloudProperties).To(Equal(biproperty.Map{
+					"fake-key": "fake-resource-pool-value",
+				}))
+			})
+		})
+
+		Context("when the az is not defined", func() {
+			BeforeEach(func() {
+				deploymentManifest.Jobs[0].AZs = []string{"fake-missing-az"}
+			})
+
+			It("returns an error", func() {
+				_, err := deploymentManifest.AZ("fake-job-name")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Could not find az 'fake-missing-az' for job 'fake-job-name'"))
+			})
+		})
+	})
+
+	Describe("errands", func() {
+		var errandProperties biproperty.Map
+
+		BeforeEach(func() {
+			errandProperties = biproperty.Map{"fake-errand-property": "fake-value"}
+			templateProperties := biproperty.Map{"fake-template-property": "fake-value"}
+
+			deploymentManifest = Manifest{
+				Jobs: []Job{
+					{
+						Name:      "fake-errand",
+						Lifecycle: JobLifecycleErrand,
+						Templates: []ReleaseJobRef{
+							{Name: "fake-errand-job", Release: "fake-errand-release"},
+							{Name: "fake-other-errand-job", Release: "fake-errand-release", Properties: &templateProperties},
+						},
+						Properties: errandProperties,
+					},
+					{
+						Name:      "fake-service",
+						Lifecycle: JobLifecycleService,
+						Templates: []ReleaseJobRef{
+							{Name: "fake-service-job", Release: "fake-service-release"},
+						},
+					},
+				},
+			}
+		})
+
+		It("excludes errands from the service jobs", func() {
+			Expect(deploymentManifest.ServiceJobs()).To(Equal([]Job{deploymentManifest.Jobs[1]}))
+			Expect(deploymentManifest.JobName()).To(Equal("fake-service"))
+		})
+
+		It("has no job name without a service job", func() {
+			deploymentManifest.Jobs = deploymentManifest.Jobs[:1]
+			Expect(deploymentManifest.JobName()).To(BeEmpty())
+		})
+
+		It("finds errands by name", func() {
+			errand, found := deploymentManifest.FindErrandByName("fake-errand")
+			Expect(found).To(BeTrue())
+			Expect(errand.Name).To(Equal("fake-errand"))
+
+			_, found = deploymentManifest.FindErrandByName("fake-service")
+			Expect(found).To(BeFalse())
+		})
+
+		It("includes releases of errand templates in the template releases", func() {
+			releases, ok := deploymentManifest.GetListOfTemplateReleases()
+			Expect(ok).To(BeTrue())
+			Expect(releases).To(Equal(map[string]string{
+				"fake-errand-release":  "fake-errand-release",
+				"fake-service-release": "fake-service-release",
+			}))
+		})
+
+		Describe("WithColocatedErrand", func() {
+			It("adds the errand templates to the service job", func() {
+				errandManifest, err := deploymentManifest.WithColocatedErrand("fake-errand")
+				Expect(err).ToNot(HaveOccurred())
+
+				serviceJob, found := errandManifest.FindJobByName("fake-service")
+				Expect(found).To(BeTrue())
+				Expect(serviceJob.Templates).To(HaveLen(3))
+				Expect(serviceJob.Templates[0].Name).To(Equal("fake-service-job"))
+				Expect(serviceJob.Templates[1].Name).To(Equal("fake-errand-job"))
+				Expect(*serviceJob.Templates[1].Properties).To(Equal(errandProperties))
+				Expect(*serviceJob.Templates[2].Properties).To(Equal(biproperty.Map{"fake-template-property": "fake-value"}))
+
+				Expect(deploymentManifest.Jobs[1].Templates).To(HaveLen(1))
+			})
+
+			It("returns an error when the errand does not exist", func() {
+				_, err := deploymentManifest.WithColocatedErrand("fake-service")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Errand 'fake-service' not found in deployment manifest"))
+			})
+		})
+	})
 })
@@ -1,10 +1,9 @@
 package manifest
 
 import (
-	"encoding/hex"
-	"fmt"
 	"net"
 
+	binet "github.com/cloudfoundry/bosh-init/common/net"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 )
@@ -30,12 +29,56 @@ type Network struct {
 }
 
 type Subnet struct {
-	Range           string
-	Gateway         string
+	Range   string
+	Gateway string
+	// Reserved addresses are never used by bosh-init
+	Reserved []string
+	// Static addresses may only be used as static IPs, they are excluded from allocation
+	Static []string
+	// AZ is the availability zone the subnet is in, a subnet without an AZ may be used from any AZ
+	AZ              string
 	DNS             []string
 	CloudProperties biproperty.Map
 }
 
+// InAZ reports whether an instance in the given availability zone may use the subnet
+func (s Subnet) InAZ(azName string) bool {
+	return s.AZ == "" || azName == "" || s.AZ == azName
+}
+
+// SubnetFor returns the subnet whose range contains the ip
+func (n Network) SubnetFor(ip string) (Subnet, bool) {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return Subnet{}, false
+	}
+
+	for _, subnet := range n.Subnets {
+		_, ipNet, err := net.ParseCIDR(subnet.Range)
+		if err == nil && ipNet.Contains(parsedIP) {
+			return subnet, true
+		}
+	}
+
+	return Subnet{}, false
+}
+
+// IsIPv6 reports whether a job network with the given static IPs is an IPv6 network.
+// Without static IPs the family of the first subnet range is used, networks without subnets are IPv4.
+func (n Network) IsIPv6(staticIPs []string) bool {
+	if len(staticIPs) > 0 {
+		ip := net.ParseIP(staticIPs[0])
+		return ip != nil && ip.To4() == nil
+	}
+
+	if len(n.Subnets) > 0 {
+		_, ipNet, err := net.ParseCIDR(n.Subnets[0].Range)
+		return err == nil && !binet.IsIPv4Net(ipNet)
+	}
+
+	return false
+}
+
 // Interface returns a property map representing a generic network interface.
 // Expected Keys: ip, type, cloud properties.
 // Optional Keys: netmask, gateway, dns
@@ -45,22 +88,25 @@ func (n Network) Interface(staticIPs []string, networkDefaults []NetworkDefault)
 	}
 
 	if n.Type == Manual {
-		networkInterface["gateway"] = n.Subnets[0].Gateway
-		if len(n.Subnets[0].DNS) > 0 {
-			networkInterface["dns"] = n.Subnets[0].DNS
+		subnet := n.Subnets[0]
+		if len(staticIPs) > 0 {
+			if staticIPSubnet, found := n.SubnetFor(staticIPs[0]); found {
+				subnet = staticIPSubnet
+			}
 		}
 
-		_, ipNet, err := net.ParseCIDR(n.Subnets[0].Range)
-		if err != nil {
-			return biproperty.Map{}, bosherr.WrapError(err, "Failed to parse subnet range")
+		networkInterface["gateway"] = subnet.Gateway
+		if len(subnet.DNS) > 0 {
+			networkInterface["dns"] = subnet.DNS
 		}
-		ipParts, err := hex.DecodeString(ipNet.Mask.String())
+
+		_, ipNet, err := net.ParseCIDR(subnet.Range)
 		if err != nil {
-			return biproperty.Map{}, bosherr.WrapError(err, "Failed to convert subnet range to IP string")
+			return biproperty.Map{}, bosherr.WrapError(err, "Failed to parse subnet range")
 		}
-		networkInterface["netmask"] = fmt.Sprintf("%v.%v.%v.%v", ipParts[0], ipParts[1], ipParts[2], ipParts[3])
+		networkInterface["netmask"] = binet.Netmask(ipNet)
 
-		networkInterface["cloud_properties"] = n.Subnets[0].CloudProperties
+		networkInterface["cloud_properties"] = subnet.CloudProperties
 	} else {
 		networkInterface["cloud_properties"] = n.CloudProperties
 	}
@@ -65,6 +65,49 @@ var _ = Describe("Network", func() {
 				}))
 			})
 
+			Context("when the network has several subnets", func() {
+				BeforeEach(func() {
+					network.Subnets = append(network.Subnets, Subnet{
+						Range:   "10.0.1.0/24",
+						Gateway: "10.0.1.1",
+						DNS:     []string{"10.0.1.2"},
+						CloudProperties: biproperty.Map{
+							"cp_key": "other_cp_value",
+						},
+					})
+				})
+
+				It("uses the subnet that contains the ip", func() {
+					iface, err := network.Interface([]string{"10.0.1.20"}, []NetworkDefault{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(iface).To(Equal(biproperty.Map{
+						"type":    "manual",
+						"ip":      "10.0.1.20",
+						"gateway": "10.0.1.1",
+						"netmask": "255.255.255.0",
+						"dns":     []string{"10.0.1.2"},
+						"cloud_properties": biproperty.Map{
+							"cp_key": "other_cp_value",
+						},
+					}))
+				})
+			})
+
+			Context("when the subnet is an IPv6 range", func() {
+				BeforeEach(func() {
+					network.Subnets[0].Range = "2001:db8:1234::/48"
+					network.Subnets[0].Gateway = "2001:db8:1234::1"
+				})
+
+				It("includes the expanded IPv6 netmask", func() {
+					iface, err := network.Interface([]string{"2001:db8:1234::6"}, []NetworkDefault{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(iface["ip"]).To(Equal("2001:db8:1234::6"))
+					Expect(iface["gateway"]).To(Equal("2001:db8:1234::1"))
+					Expect(iface["netmask"]).To(Equal("ffff:ffff:ffff::"))
+				})
+			})
+
 			Context("when range is invalid", func() {
 				BeforeEach(func() {
 					network.Subnets[0].Range = "invalid-range"
@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"time"
+
 	biutil "github.com/cloudfoundry/bosh-init/common/util"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -25,6 +27,11 @@ type manifest struct {
 	Networks       []network
 	ResourcePools  []resourcePool `yaml:"resource_pools"`
 	DiskPools      []diskPool     `yaml:"disk_pools"`
+	AZs            []az           `yaml:"azs"`
+	Stemcells      []stemcell     `yaml:"stemcells"`
+	VMTypes        []vmType       `yaml:"vm_types"`
+	VMExtensions   []vmType       `yaml:"vm_extensions"`
+	DiskTypes      []diskPool     `yaml:"disk_types"`
 	Jobs           []job
 	InstanceGroups []job `yaml:"instance_groups"`
 	Properties     map[interface{}]interface{}
@@ -32,6 +39,7 @@ type manifest struct {
 
 type UpdateSpec struct {
 	UpdateWatchTime *string `yaml:"update_watch_time"`
+	MaxDrainWait    *int    `yaml:"max_drain_wait"`
 }
 
 type network struct {
@@ -48,6 +56,9 @@ type network struct {
 type subnet struct {
 	Range           string                      `yaml:"range"`
 	Gateway         string                      `yaml:"gateway"`
+	Reserved        []string                    `yaml:"reserved"`
+	Static          []string                    `yaml:"static"`
+	AZ              string                      `yaml:"az"`
 	DNS             []string                    `yaml:"dns"`
 	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
 }
@@ -55,11 +66,32 @@ type subnet struct {
 type resourcePool struct {
 	Name            string                      `yaml:"name"`
 	Network         string                      `yaml:"network"`
+	AZ              string                      `yaml:"az"`
 	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
 	Env             map[interface{}]interface{} `yaml:"env"`
 	Stemcell        stemcellRef                 `yaml:"stemcell"`
 }
 
+// vmType is used for both vm_types and vm_extensions
+type vmType struct {
+	Name            string                      `yaml:"name"`
+	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
+}
+
+type stemcell struct {
+	Alias     string `yaml:"alias"`
+	Name      string `yaml:"name"`
+	Version   string `yaml:"version"`
+	URL       string `yaml:"url"`
+	SHA1      string `yaml:"sha1"`
+	Signature string `yaml:"signature"`
+}
+
+type az struct {
+	Name            string                      `yaml:"name"`
+	CloudProperties map[interface{}]interface{} `yaml:"cloud_properties"`
+}
+
 type diskPool struct {
 	Name            string                      `yaml:"name"`
 	DiskSize        int                         `yaml:"disk_size"`
@@ -73,10 +105,18 @@ type job struct {
 	Templates          []releaseJobRef
 	Jobs               []releaseJobRef `yaml:"jobs"`
 	Networks           []jobNetwork
-	PersistentDisk     int    `yaml:"persistent_disk"`
-	PersistentDiskPool string `yaml:"persistent_disk_pool"`
-	ResourcePool       string `yaml:"resource_pool"`
+	PersistentDisk     int      `yaml:"persistent_disk"`
+	PersistentDiskPool string   `yaml:"persistent_disk_pool"`
+	ResourcePool       string   `yaml:"resource_pool"`
+	AZs                []string `yaml:"azs"`
 	Properties         map[interface{}]interface{}
+
+	// instance groups refer to stemcells, vm_types, vm_extensions and disk_types instead of resource and disk pools
+	Stemcell           string                      `yaml:"stemcell"`
+	VMType             string                      `yaml:"vm_type"`
+	VMExtensions       []string                    `yaml:"vm_extensions"`
+	PersistentDiskType string                      `yaml:"persistent_disk_type"`
+	Env                map[interface{}]interface{} `yaml:"env"`
 }
 
 type releaseJobRef struct {
@@ -86,11 +126,22 @@ type releaseJobRef struct {
 	// This is a pointer so we can differentiate between `properties: {}`
 	// and not specifying the key at all.
 	Properties *map[interface{}]interface{}
+
+	Provides map[string]linkRef `yaml:"provides"`
+	Consumes map[string]linkRef `yaml:"consumes"`
+}
+
+type linkRef struct {
+	As   string `yaml:"as"`
+	From string `yaml:"from"`
 }
 
 type stemcellRef struct {
-	URL  string
-	SHA1 string
+	Name      string
+	Version   string
+	URL       string
+	SHA1      string
+	Signature string
 }
 
 type jobNetwork struct {
@@ -154,12 +205,26 @@ func (p *parser) parseDeploymentManifest(depManifest manifest, path string) (Man
 
 	deployment.ResourcePools = resourcePools
 
-	diskPools, err := p.parseDiskPoolManifests(depManifest.DiskPools)
+	if len(depManifest.DiskPools) > 0 && len(depManifest.DiskTypes) > 0 {
+		return Manifest{}, bosherr.Error("Deployment specifies both disk_pools and disk_types keys, only one is allowed")
+	}
+
+	rawDiskPools := depManifest.DiskPools
+	if len(depManifest.DiskTypes) > 0 {
+		rawDiskPools = depManifest.DiskTypes
+	}
+	diskPools, err := p.parseDiskPoolManifests(rawDiskPools)
 	if err != nil {
-		return Manifest{}, bosherr.WrapErrorf(err, "Parsing disk_pools: %#v", depManifest.DiskPools)
+		return Manifest{}, bosherr.WrapErrorf(err, "Parsing disk_pools: %#v", rawDiskPools)
 	}
 	deployment.DiskPools = diskPools
 
+	azs, err := p.parseAZManifests(depManifest.AZs)
+	if err != nil {
+		return Manifest{}, bosherr.WrapErrorf(err, "Parsing azs: %#v", depManifest.AZs)
+	}
+	deployment.AZs = azs
+
 	if len(depManifest.Jobs) > 0 && len(depManifest.InstanceGroups) > 0 {
 		return Manifest{}, bosherr.Error("Deployment specifies both jobs and instance_groups keys, only one is allowed")
 	}
@@ -174,6 +239,31 @@ func (p *parser) parseDeploymentManifest(depManifest manifest, path string) (Man
 	}
 	deployment.Jobs = jobs
 
+	deployment.Keys = Keys{
+		InstanceGroups: len(depManifest.InstanceGroups) > 0,
+		DiskTypes:      len(depManifest.DiskTypes) > 0,
+	}
+
+	if len(depManifest.Stemcells) > 0 || len(depManifest.VMTypes) > 0 {
+		if len(depManifest.ResourcePools) > 0 {
+			return Manifest{}, bosherr.Error("Deployment specifies both resource_pools and stemcells or vm_types keys, only one is allowed")
+		}
+
+		resourcePools, vmTypeResourcePools, err := p.parseVMTypeResourcePools(depManifest, rawJobs, path)
+		if err != nil {
+			return Manifest{}, bosherr.WrapError(err, "Parsing vm_types")
+		}
+		deployment.ResourcePools = resourcePools
+		deployment.Keys.VMTypes = true
+		deployment.Keys.VMTypeResourcePools = vmTypeResourcePools
+
+		for i, rawJob := range rawJobs {
+			if rawJob.VMType != "" {
+				deployment.Jobs[i].ResourcePool = rawJob.Name
+			}
+		}
+	}
+
 	properties, err := biproperty.BuildMap(depManifest.Properties)
 	if err != nil {
 		return Manifest{}, bosherr.WrapErrorf(err, "Parsing global manifest properties: %#v", depManifest.Properties)
@@ -186,9 +276,15 @@ func (p *parser) parseDeploymentManifest(depManifest manifest, path string) (Man
 			return Manifest{}, bosherr.WrapError(err, "Parsing update watch time")
 		}
 
-		deployment.Update = Update{
-			UpdateWatchTime: updateWatchTime,
+		deployment.Update.UpdateWatchTime = updateWatchTime
+	}
+
+	if depManifest.Update.MaxDrainWait != nil {
+		if *depManifest.Update.MaxDrainWait < 0 {
+			return Manifest{}, bosherr.Errorf("Parsing update max drain wait: must not be negative (%d)", *depManifest.Update.MaxDrainWait)
 		}
+
+		deployment.Update.MaxDrainWait = time.Duration(*depManifest.Update.MaxDrainWait) * time.Millisecond
 	}
 
 	return deployment, nil
@@ -204,6 +300,14 @@ func (p *parser) parseJobManifests(rawJobs []job) ([]Job, error) {
 			PersistentDisk:     rawJob.PersistentDisk,
 			PersistentDiskPool: rawJob.PersistentDiskPool,
 			ResourcePool:       rawJob.ResourcePool,
+			AZs:                rawJob.AZs,
+		}
+
+		if rawJob.PersistentDiskType != "" {
+			if rawJob.PersistentDiskPool != "" {
+				return jobs, bosherr.Error("Deployment specifies both persistent_disk_pool and persistent_disk_type keys for instance_group " + job.Name + ", only one is allowed")
+			}
+			job.PersistentDiskPool = rawJob.PersistentDiskType
 		}
 
 		if len(rawJob.Templates) > 0 && len(rawJob.Jobs) > 0 {
@@ -232,6 +336,20 @@ func (p *parser) parseJobManifests(rawJobs []job) ([]Job, error) {
 					ref.Properties = &properties
 				}
 
+				if rawJobRef.Provides != nil {
+					ref.Provides = map[string]string{}
+					for linkName, rawLinkRef := range rawJobRef.Provides {
+						ref.Provides[linkName] = rawLinkRef.As
+					}
+				}
+
+				if rawJobRef.Consumes != nil {
+					ref.Consumes = map[string]string{}
+					for linkName, rawLinkRef := range rawJobRef.Consumes {
+						ref.Consumes[linkName] = rawLinkRef.From
+					}
+				}
+
 				releaseJobRefs[i] = ref
 			}
 			job.Templates = releaseJobRefs
@@ -296,6 +414,9 @@ func (p *parser) parseNetworkManifests(rawNetworks []network) ([]Network, error)
 			network.Subnets = append(network.Subnets, Subnet{
 				Range:           subnet.Range,
 				Gateway:         subnet.Gateway,
+				Reserved:        subnet.Reserved,
+				Static:          subnet.Static,
+				AZ:              subnet.AZ,
 				DNS:             subnet.DNS,
 				CloudProperties: cloudProperties,
 			})
@@ -313,6 +434,7 @@ func (p *parser) parseResourcePoolManifests(rawResourcePools []resourcePool, pat
 		resourcePool := ResourcePool{
 			Name:     rawResourcePool.Name,
 			Network:  rawResourcePool.Network,
+			AZ:       rawResourcePool.AZ,
 			Stemcell: StemcellRef(rawResourcePool.Stemcell),
 		}
 
@@ -328,9 +450,18 @@ func (p *parser) parseResourcePoolManifests(rawResourcePools []resourcePool, pat
 		}
 		resourcePool.Env = env
 
-		resourcePool.Stemcell.URL, err = biutil.AbsolutifyPath(path, resourcePool.Stemcell.URL, p.fs)
-		if err != nil {
-			return resourcePools, bosherr.WrapErrorf(err, "Resolving stemcell path '%s", resourcePool.Stemcell.URL)
+		if resourcePool.Stemcell.URL != "" {
+			resourcePool.Stemcell.URL, err = biutil.AbsolutifyPath(path, resourcePool.Stemcell.URL, p.fs)
+			if err != nil {
+				return resourcePools, bosherr.WrapErrorf(err, "Resolving stemcell path '%s", resourcePool.Stemcell.URL)
+			}
+		}
+
+		if resourcePool.Stemcell.Signature != "" {
+			resourcePool.Stemcell.Signature, err = biutil.AbsolutifyPath(path, resourcePool.Stemcell.Signature, p.fs)
+			if err != nil {
+				return resourcePools, bosherr.WrapErrorf(err, "Resolving stemcell signature path '%s", resourcePool.Stemcell.Signature)
+			}
 		}
 
 		resourcePools[i] = resourcePool
@@ -339,6 +470,160 @@ func (p *parser) parseResourcePoolManifests(rawResourcePools []resourcePool, pat
 	return resourcePools, nil
 }
 
+// parseVMTypeResourcePools builds a resource pool for each instance group that has a vm_type,
+// from its stemcell, vm_type, vm_extensions and env
+func (p *parser) parseVMTypeResourcePools(depManifest manifest, rawJobs []job, path string) ([]ResourcePool, map[int]VMTypeResourcePool, error) {
+	stemcells := map[string]StemcellRef{}
+	stemcellIndexes := map[string]int{}
+	for stemcellIdx, rawStemcell := range depManifest.Stemcells {
+		if _, found := stemcells[rawStemcell.Alias]; found {
+			return []ResourcePool{}, nil, bosherr.Errorf("Duplicate stemcells alias '%s'", rawStemcell.Alias)
+		}
+
+		url := rawStemcell.URL
+		if url != "" {
+			var err error
+			url, err = biutil.AbsolutifyPath(path, rawStemcell.URL, p.fs)
+			if err != nil {
+				return []ResourcePool{}, nil, bosherr.WrapErrorf(err, "Resolving stemcell path '%s", rawStemcell.URL)
+			}
+		}
+		signature := rawStemcell.Signature
+		if signature != "" {
+			var err error
+			signature, err = biutil.AbsolutifyPath(path, rawStemcell.Signature, p.fs)
+			if err != nil {
+				return []ResourcePool{}, nil, bosherr.WrapErrorf(err, "Resolving stemcell signature path '%s", rawStemcell.Signature)
+			}
+		}
+		stemcells[rawStemcell.Alias] = StemcellRef{
+			Name:      rawStemcell.Name,
+			Version:   rawStemcell.Version,
+			URL:       url,
+			SHA1:      rawStemcell.SHA1,
+			Signature: signature,
+		}
+		stemcellIndexes[rawStemcell.Alias] = stemcellIdx
+	}
+
+	vmTypes, err := p.parseVMTypeCloudProperties("vm_types", depManifest.VMTypes)
+	if err != nil {
+		return []ResourcePool{}, nil, err
+	}
+
+	vmExtensions, err := p.parseVMTypeCloudProperties("vm_extensions", depManifest.VMExtensions)
+	if err != nil {
+		return []ResourcePool{}, nil, err
+	}
+
+	resourcePools := []ResourcePool{}
+	vmTypeResourcePools := map[int]VMTypeResourcePool{}
+	for jobIdx, rawJob := range rawJobs {
+		if rawJob.VMType == "" {
+			continue
+		}
+
+		if rawJob.ResourcePool != "" {
+			return resourcePools, nil, bosherr.Errorf("Instance group '%s' specifies both resource_pool and vm_type, only one is allowed", rawJob.Name)
+		}
+
+		cloudProperties, found := vmTypes[rawJob.VMType]
+		if !found {
+			return resourcePools, nil, bosherr.Errorf("Instance group '%s' refers to unknown vm_type '%s'", rawJob.Name, rawJob.VMType)
+		}
+
+		for _, extensionName := range rawJob.VMExtensions {
+			extensionCloudProperties, found := vmExtensions[extensionName]
+			if !found {
+				return resourcePools, nil, bosherr.Errorf("Instance group '%s' refers to unknown vm_extension '%s'", rawJob.Name, extensionName)
+			}
+			cloudProperties = deepMergeProperties(cloudProperties, extensionCloudProperties)
+		}
+
+		stemcell, found := stemcells[rawJob.Stemcell]
+		if !found {
+			return resourcePools, nil, bosherr.Errorf("Instance group '%s' refers to unknown stemcell '%s'", rawJob.Name, rawJob.Stemcell)
+		}
+
+		env, err := biproperty.BuildMap(rawJob.Env)
+		if err != nil {
+			return resourcePools, nil, bosherr.WrapErrorf(err, "Parsing instance group '%s' env: %#v", rawJob.Name, rawJob.Env)
+		}
+
+		resourcePool := ResourcePool{
+			Name:            rawJob.Name,
+			CloudProperties: cloudProperties,
+			Env:             env,
+			Stemcell:        stemcell,
+		}
+		if len(rawJob.Networks) > 0 {
+			resourcePool.Network = rawJob.Networks[0].Name
+		}
+
+		vmTypeResourcePools[len(resourcePools)] = VMTypeResourcePool{
+			JobIndex:      jobIdx,
+			StemcellIndex: stemcellIndexes[rawJob.Stemcell],
+		}
+		resourcePools = append(resourcePools, resourcePool)
+	}
+
+	return resourcePools, vmTypeResourcePools, nil
+}
+
+// parseVMTypeCloudProperties returns the cloud properties of the vm_types or vm_extensions by name
+func (p *parser) parseVMTypeCloudProperties(key string, rawVMTypes []vmType) (map[string]biproperty.Map, error) {
+	cloudPropertiesByName := map[string]biproperty.Map{}
+	for _, rawVMType := range rawVMTypes {
+		if _, found := cloudPropertiesByName[rawVMType.Name]; found {
+			return cloudPropertiesByName, bosherr.Errorf("Duplicate %s name '%s'", key, rawVMType.Name)
+		}
+
+		cloudProperties, err := biproperty.BuildMap(rawVMType.CloudProperties)
+		if err != nil {
+			return cloudPropertiesByName, bosherr.WrapErrorf(err, "Parsing '%s' cloud_properties: %#v", rawVMType.Name, rawVMType.CloudProperties)
+		}
+		cloudPropertiesByName[rawVMType.Name] = cloudProperties
+	}
+	return cloudPropertiesByName, nil
+}
+
+// deepMergeProperties returns the base properties overridden by the given properties, nested maps are merged rather than replaced
+func deepMergeProperties(base, overrides biproperty.Map) biproperty.Map {
+	merged := biproperty.Map{}
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range overrides {
+		baseMap, baseIsMap := merged[key].(biproperty.Map)
+		overridesMap, overridesIsMap := value.(biproperty.Map)
+		if baseIsMap && overridesIsMap {
+			merged[key] = deepMergeProperties(baseMap, overridesMap)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+func (p *parser) parseAZManifests(rawAZs []az) ([]AZ, error) {
+	azs := make([]AZ, len(rawAZs), len(rawAZs))
+	for i, rawAZ := range rawAZs {
+		cloudProperties, err := biproperty.BuildMap(rawAZ.CloudProperties)
+		if err != nil {
+			return azs, bosherr.WrapErrorf(err, "Parsing az '%s' cloud_properties: %#v", rawAZ.Name, rawAZ.CloudProperties)
+		}
+
+		azs[i] = AZ{
+			Name:            rawAZ.Name,
+			CloudProperties: cloudProperties,
+		}
+	}
+
+	return azs, nil
+}
+
 func (p *parser) parseDiskPoolManifests(rawDiskPools []diskPool) ([]DiskPool, error) {
 	diskPools := make([]DiskPool, len(rawDiskPools), len(rawDiskPools))
 	for i, rawDiskPool := range rawDiskPools {
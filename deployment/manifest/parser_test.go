@@ -2,6 +2,8 @@ package manifest_test
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	. "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	. "github.com/onsi/ginkgo"
@@ -55,8 +57,14 @@ var _ = Describe("Parser", func() {
 name: fake-deployment-name
 update:
   update_watch_time: 2000-7000
+  max_drain_wait: 60000
+azs:
+- name: z1
+  cloud_properties:
+    availability_zone: fake-zone
 resource_pools:
 - name: fake-resource-pool-name
+  az: z1
   cloud_properties:
     fake-property: fake-property-value
   env:
@@ -71,6 +79,7 @@ networks:
   subnets:
   - range: 1.2.3.0/22
     gateway: 1.1.1.1
+    az: z1
     dns: [2.2.2.2]
     cloud_properties:
       cp_key: cp_value
@@ -95,6 +104,7 @@ jobs:
   persistent_disk: 1024
   persistent_disk_pool: fake-disk-pool-name
   resource_pool: fake-resource-pool
+  azs: [z1]
   properties:
     fake-prop-key:
       nested-prop-key: fake-prop-value
@@ -116,6 +126,7 @@ properties:
 					Start: 2000,
 					End:   7000,
 				},
+				MaxDrainWait: 60 * time.Second,
 			},
 			Networks: []Network{
 				{
@@ -126,6 +137,7 @@ properties:
 						{
 							Range:   "1.2.3.0/22",
 							Gateway: "1.1.1.1",
+							AZ:      "z1",
 							DNS:     []string{"2.2.2.2"},
 							CloudProperties: biproperty.Map{
 								"cp_key": "cp_value",
@@ -148,6 +160,7 @@ properties:
 			ResourcePools: []ResourcePool{
 				{
 					Name: "fake-resource-pool-name",
+					AZ:   "z1",
 					CloudProperties: biproperty.Map{
 						"fake-property": "fake-property-value",
 					},
@@ -161,6 +174,14 @@ properties:
 					},
 				},
 			},
+			AZs: []AZ{
+				{
+					Name: "z1",
+					CloudProperties: biproperty.Map{
+						"availability_zone": "fake-zone",
+					},
+				},
+			},
 			DiskPools: []DiskPool{
 				{
 					Name:     "fake-disk-pool-name",
@@ -186,6 +207,7 @@ properties:
 					PersistentDisk:     1024,
 					PersistentDiskPool: "fake-disk-pool-name",
 					ResourcePool:       "fake-resource-pool",
+					AZs:                []string{"z1"},
 					Properties: biproperty.Map{
 						"fake-prop-key": biproperty.Map{
 							"nested-prop-key": "fake-prop-value",
@@ -224,6 +246,7 @@ resource_pools:
 				Jobs:       []Job{},
 				Networks:   []Network{},
 				DiskPools:  []DiskPool{},
+				AZs:        []AZ{},
 				ResourcePools: []ResourcePool{
 					{
 						Name:            "fake-resource-pool-name",
@@ -243,6 +266,31 @@ resource_pools:
 		})
 	})
 
+	Context("when stemcell has a name and version instead of a url", func() {
+		BeforeEach(func() {
+			contents := `
+---
+name: fake-deployment-manifest
+
+resource_pools:
+- name: fake-resource-pool-name
+  stemcell:
+    name: fake-stemcell-name
+    version: 3262.2
+`
+			fakeFs.WriteFileString(comboManifestPath, contents)
+		})
+
+		It("leaves the url empty to be resolved through the index", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentManifest.ResourcePools[0].Stemcell).To(Equal(StemcellRef{
+				Name:    "fake-stemcell-name",
+				Version: "3262.2",
+			}))
+		})
+	})
+
 	Context("when stemcell url is a file path", func() {
 		Context("that begin with 'file:///'", func() {
 			BeforeEach(func() {
@@ -267,6 +315,7 @@ resource_pools:
 					Jobs:       []Job{},
 					Networks:   []Network{},
 					DiskPools:  []DiskPool{},
+					AZs:        []AZ{},
 					ResourcePools: []ResourcePool{
 						{
 							Name:            "fake-resource-pool-name",
@@ -309,6 +358,7 @@ resource_pools:
 					Jobs:       []Job{},
 					Networks:   []Network{},
 					DiskPools:  []DiskPool{},
+					AZs:        []AZ{},
 					ResourcePools: []ResourcePool{
 						{
 							Name:            "fake-resource-pool-name",
@@ -352,6 +402,7 @@ resource_pools:
 					Jobs:       []Job{},
 					Networks:   []Network{},
 					DiskPools:  []DiskPool{},
+					AZs:        []AZ{},
 					ResourcePools: []ResourcePool{
 						{
 							Name:            "fake-resource-pool-name",
@@ -500,6 +551,25 @@ name: fake-deployment-name
 			Expect(deploymentManifest.Name).To(Equal("fake-deployment-name"))
 			Expect(deploymentManifest.Update.UpdateWatchTime.Start).To(Equal(0))
 			Expect(deploymentManifest.Update.UpdateWatchTime.End).To(Equal(300000))
+			Expect(deploymentManifest.Update.MaxDrainWait).To(BeZero())
+		})
+	})
+
+	Context("when max drain wait is negative", func() {
+		BeforeEach(func() {
+			contents := `
+---
+name: fake-deployment-name
+update:
+  max_drain_wait: -1
+`
+			fakeFs.WriteFileString(comboManifestPath, contents)
+		})
+
+		It("returns an error", func() {
+			_, err := parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing update max drain wait: must not be negative"))
 		})
 	})
 
@@ -620,6 +690,201 @@ instance_groups:
 		})
 	})
 
+	Context("when the manifest uses stemcells, vm_types and disk_types", func() {
+		BeforeEach(func() {
+			contents := `
+---
+name: fake-deployment-name
+stemcells:
+- alias: default
+  url: http://fake-stemcell-url
+  sha1: fake-stemcell-sha1
+vm_types:
+- name: fake-vm-type
+  cloud_properties:
+    instance_type: m3.xlarge
+    ephemeral_disk:
+      size: 25000
+      type: gp2
+vm_extensions:
+- name: fake-vm-extension
+  cloud_properties:
+    ephemeral_disk:
+      size: 50000
+    elbs: [fake-elb]
+disk_types:
+- name: fake-disk-type
+  disk_size: 32768
+  cloud_properties:
+    type: gp2
+networks:
+- name: fake-network-name
+  type: dynamic
+instance_groups:
+- name: bosh
+  instances: 1
+  stemcell: default
+  vm_type: fake-vm-type
+  vm_extensions: [fake-vm-extension]
+  persistent_disk_type: fake-disk-type
+  env:
+    bosh:
+      password: secret
+  networks:
+  - name: fake-network-name
+  jobs:
+  - name: fake-job
+    release: fake-release
+    properties:
+      fake-key: fake-value
+`
+			fakeFs.WriteFileString(comboManifestPath, contents)
+		})
+
+		It("normalizes the instance group vm into a resource pool", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deploymentManifest.ResourcePools).To(Equal([]ResourcePool{
+				{
+					Name:    "bosh",
+					Network: "fake-network-name",
+					CloudProperties: biproperty.Map{
+						"instance_type": "m3.xlarge",
+						"ephemeral_disk": biproperty.Map{
+							"size": 50000,
+							"type": "gp2",
+						},
+						"elbs": biproperty.List{"fake-elb"},
+					},
+					Env: biproperty.Map{
+						"bosh": biproperty.Map{
+							"password": "secret",
+						},
+					},
+					Stemcell: StemcellRef{
+						URL:  "http://fake-stemcell-url",
+						SHA1: "fake-stemcell-sha1",
+					},
+				},
+			}))
+			Expect(deploymentManifest.Jobs[0].ResourcePool).To(Equal("bosh"))
+		})
+
+		It("normalizes disk_types into disk pools", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deploymentManifest.DiskPools).To(Equal([]DiskPool{
+				{
+					Name:     "fake-disk-type",
+					DiskSize: 32768,
+					CloudProperties: biproperty.Map{
+						"type": "gp2",
+					},
+				},
+			}))
+			Expect(deploymentManifest.Jobs[0].PersistentDiskPool).To(Equal("fake-disk-type"))
+		})
+
+		It("parses the jobs of the instance group with their release and properties", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deploymentManifest.Jobs[0].Templates).To(Equal([]ReleaseJobRef{
+				{
+					Name:       "fake-job",
+					Release:    "fake-release",
+					Properties: &biproperty.Map{"fake-key": "fake-value"},
+				},
+			}))
+		})
+
+		It("records the keys the jobs, resource pools and disk pools were parsed from", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deploymentManifest.Keys).To(Equal(Keys{
+				InstanceGroups: true,
+				VMTypes:        true,
+				DiskTypes:      true,
+				VMTypeResourcePools: map[int]VMTypeResourcePool{
+					0: {JobIndex: 0, StemcellIndex: 0},
+				},
+			}))
+		})
+
+		It("returns an error when vm_types names are not unique", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, strings.Replace(contents, "vm_types:\n", "vm_types:\n- name: fake-vm-type\n", 1))
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Duplicate vm_types name 'fake-vm-type'"))
+		})
+
+		It("returns an error when stemcells aliases are not unique", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, strings.Replace(contents, "stemcells:\n", "stemcells:\n- alias: default\n", 1))
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Duplicate stemcells alias 'default'"))
+		})
+
+		It("returns an error when the instance group refers to an unknown stemcell", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, strings.Replace(contents, "stemcell: default", "stemcell: fake-missing-stemcell", 1))
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Instance group 'bosh' refers to unknown stemcell 'fake-missing-stemcell'"))
+		})
+
+		It("returns an error when the instance group refers to an unknown vm_type", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, strings.Replace(contents, "vm_type: fake-vm-type", "vm_type: fake-missing-vm-type", 1))
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Instance group 'bosh' refers to unknown vm_type 'fake-missing-vm-type'"))
+		})
+
+		It("returns an error when the instance group refers to an unknown vm_extension", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, strings.Replace(contents, "vm_extensions: [fake-vm-extension]", "vm_extensions: [fake-missing-vm-extension]", 1))
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Instance group 'bosh' refers to unknown vm_extension 'fake-missing-vm-extension'"))
+		})
+
+		It("returns an error when resource_pools are also specified", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, contents+"resource_pools:\n- name: fake-resource-pool-name\n")
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Deployment specifies both resource_pools and stemcells or vm_types keys, only one is allowed"))
+		})
+
+		It("returns an error when disk_pools are also specified", func() {
+			contents, err := fakeFs.ReadFileString(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			fakeFs.WriteFileString(comboManifestPath, contents+"disk_pools:\n- name: fake-disk-pool-name\n")
+
+			_, err = parser.Parse(comboManifestPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Deployment specifies both disk_pools and disk_types keys, only one is allowed"))
+		})
+	})
+
 	Context("when both templates and jobs are present at job level in deployment manifest", func() {
 		BeforeEach(func() {
 			contents := `
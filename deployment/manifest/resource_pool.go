@@ -7,14 +7,20 @@ import (
 type ResourcePool struct {
 	Name            string
 	Network         string
+	AZ              string
 	CloudProperties biproperty.Map
 	Env             biproperty.Map
 	Stemcell        StemcellRef
 }
 
 type StemcellRef struct {
-	URL  string
-	SHA1 string
+	// Name and Version resolve the stemcell URL and SHA1 through the index when URL is not set
+	Name    string
+	Version string
+	URL     string
+	SHA1    string
+	// Signature is the URL or path of a detached signature of the stemcell tarball
+	Signature string
 }
 
 func (s StemcellRef) GetURL() string {
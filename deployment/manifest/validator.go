@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	binet "github.com/cloudfoundry/bosh-init/common/net"
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -18,12 +19,14 @@ type Validator interface {
 }
 
 type validator struct {
-	logger boshlog.Logger
+	linkResolver LinkResolver
+	logger       boshlog.Logger
 }
 
-func NewValidator(logger boshlog.Logger) Validator {
+func NewValidator(linkResolver LinkResolver, logger boshlog.Logger) Validator {
 	return &validator{
-		logger: logger,
+		linkResolver: linkResolver,
+		logger:       logger,
 	}
 }
 
@@ -33,10 +36,40 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 		errs = append(errs, bosherr.Error("name must be provided"))
 	}
 
+	azNames := map[string]struct{}{}
+	for idx, az := range deploymentManifest.AZs {
+		if v.isBlank(az.Name) {
+			errs = append(errs, bosherr.Errorf("azs[%d].name must be provided", idx))
+		} else if _, found := azNames[az.Name]; found {
+			errs = append(errs, bosherr.Errorf("azs[%d].name '%s' must be unique", idx, az.Name))
+		}
+		azNames[az.Name] = struct{}{}
+	}
+
 	networksErrors := v.validateNetworks(deploymentManifest.Networks)
 	errs = append(errs, networksErrors...)
 
+	for idx, network := range deploymentManifest.Networks {
+		for subnetIdx, subnet := range network.Subnets {
+			if _, found := azNames[subnet.AZ]; subnet.AZ != "" && !found {
+				errs = append(errs, bosherr.Errorf("networks[%d].subnets[%d].az '%s' must refer to an az in azs", idx, subnetIdx, subnet.AZ))
+			}
+		}
+	}
+
+	keys := deploymentManifest.Keys
+	validatedStemcells := map[string]struct{}{}
 	for idx, resourcePool := range deploymentManifest.ResourcePools {
+		// resource pools built from vm_types are named after their job and use its first network, the job is validated below
+		if _, found := keys.VMTypeResourcePools[idx]; found {
+			stemcellPath := keys.resourcePoolStemcell(idx)
+			if _, validated := validatedStemcells[stemcellPath]; !validated {
+				errs = append(errs, v.validateStemcell(resourcePool.Stemcell, stemcellPath)...)
+				validatedStemcells[stemcellPath] = struct{}{}
+			}
+			continue
+		}
+
 		if v.isBlank(resourcePool.Name) {
 			errs = append(errs, bosherr.Errorf("resource_pools[%d].name must be provided", idx))
 		}
@@ -46,81 +79,97 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 			errs = append(errs, bosherr.Errorf("resource_pools[%d].network must be the name of a network", idx))
 		}
 
-		if v.isBlank(resourcePool.Stemcell.URL) {
-			errs = append(errs, bosherr.Errorf("resource_pools[%d].stemcell.url must be provided", idx))
-		}
-
-		matched, err := regexp.MatchString("^(file|http|https)://", resourcePool.Stemcell.URL)
-		if err != nil || !matched {
-			errs = append(errs, bosherr.Errorf("resource_pools[%d].stemcell.url must be a valid URL (file:// or http(s)://)", idx))
+		if _, found := azNames[resourcePool.AZ]; resourcePool.AZ != "" && !found {
+			errs = append(errs, bosherr.Errorf("resource_pools[%d].az '%s' must refer to an az in azs", idx, resourcePool.AZ))
 		}
 
-		if strings.HasPrefix(resourcePool.Stemcell.URL, "http") && v.isBlank(resourcePool.Stemcell.SHA1) {
-			errs = append(errs, bosherr.Errorf("resource_pools[%d].stemcell.sha1 must be provided for http URL", idx))
-		}
+		errs = append(errs, v.validateStemcell(resourcePool.Stemcell, keys.resourcePoolStemcell(idx))...)
 	}
 
 	for idx, diskPool := range deploymentManifest.DiskPools {
 		if v.isBlank(diskPool.Name) {
-			errs = append(errs, bosherr.Errorf("disk_pools[%d].name must be provided", idx))
+			errs = append(errs, bosherr.Errorf("%s.name must be provided", keys.diskPool(idx)))
 		}
 		if diskPool.DiskSize <= 0 {
-			errs = append(errs, bosherr.Errorf("disk_pools[%d].disk_size must be > 0", idx))
+			errs = append(errs, bosherr.Errorf("%s.disk_size must be > 0", keys.diskPool(idx)))
 		}
 	}
 
-	if len(deploymentManifest.Jobs) > 1 {
-		errs = append(errs, bosherr.Error("jobs must be of size 1"))
+	if len(deploymentManifest.ServiceJobs()) != 1 {
+		if keys.InstanceGroups {
+			errs = append(errs, bosherr.Error("instance_groups must have exactly 1 instance group with lifecycle 'service'"))
+		} else {
+			errs = append(errs, bosherr.Error("jobs must have exactly 1 job with lifecycle 'service'"))
+		}
 	}
 
+	resourcePoolKey, resourcePoolNoun := keys.resourcePoolKey()
+	diskPoolKey, diskPoolNoun := keys.persistentDiskPoolKey()
 	for idx, job := range deploymentManifest.Jobs {
 		if v.isBlank(job.Name) {
-			errs = append(errs, bosherr.Errorf("jobs[%d].name must be provided", idx))
+			errs = append(errs, bosherr.Errorf("%s.name must be provided", keys.job(idx)))
 		}
-		if job.PersistentDisk < 0 {
-			errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk must be >= 0", idx))
+
+		if job.Lifecycle != "" && job.Lifecycle != JobLifecycleService && job.Lifecycle != JobLifecycleErrand {
+			errs = append(errs, bosherr.Errorf("%s.lifecycle must be 'service' or 'errand' ('%s' not supported)", keys.job(idx), job.Lifecycle))
 		}
-		if job.PersistentDiskPool != "" {
-			if _, ok := v.diskPoolNames(deploymentManifest)[job.PersistentDiskPool]; !ok {
-				errs = append(errs, bosherr.Errorf("jobs[%d].persistent_disk_pool must be the name of a disk pool", idx))
+
+		if job.Lifecycle == JobLifecycleErrand {
+			// errands are run on the VM of the service job, so they have no networks, resource pool or disk of their own
+			if len(job.Templates) == 0 {
+				errs = append(errs, bosherr.Errorf("%s must be a non-empty array for errands", keys.templates(idx)))
 			}
-		}
-		if job.Instances < 0 {
-			errs = append(errs, bosherr.Errorf("jobs[%d].instances must be >= 0", idx))
-		}
-		if len(job.Networks) == 0 {
-			errs = append(errs, bosherr.Errorf("jobs[%d].networks must be a non-empty array", idx))
-		}
-		if v.isBlank(job.ResourcePool) {
-			errs = append(errs, bosherr.Errorf("jobs[%d].resource_pool must be provided", idx))
 		} else {
-			if _, ok := v.resourcePoolNames(deploymentManifest)[job.ResourcePool]; !ok {
-				errs = append(errs, bosherr.Errorf("jobs[%d].resource_pool must be the name of a resource pool", idx))
+			if job.PersistentDisk < 0 {
+				errs = append(errs, bosherr.Errorf("%s.persistent_disk must be >= 0", keys.job(idx)))
+			}
+			if job.PersistentDiskPool != "" {
+				if _, ok := v.diskPoolNames(deploymentManifest)[job.PersistentDiskPool]; !ok {
+					errs = append(errs, bosherr.Errorf("%s.%s must be the name of a %s", keys.job(idx), diskPoolKey, diskPoolNoun))
+				}
+			}
+			if job.Instances < 0 {
+				errs = append(errs, bosherr.Errorf("%s.instances must be >= 0", keys.job(idx)))
+			}
+			if len(job.Networks) == 0 {
+				errs = append(errs, bosherr.Errorf("%s.networks must be a non-empty array", keys.job(idx)))
+			}
+			if v.isBlank(job.ResourcePool) {
+				errs = append(errs, bosherr.Errorf("%s.%s must be provided", keys.job(idx), resourcePoolKey))
+			} else {
+				if _, ok := v.resourcePoolNames(deploymentManifest)[job.ResourcePool]; !ok {
+					errs = append(errs, bosherr.Errorf("%s.%s must be the name of a %s", keys.job(idx), resourcePoolKey, resourcePoolNoun))
+				}
 			}
-		}
 
-		errs = append(errs, v.validateJobNetworks(job.Networks, deploymentManifest.Networks, idx)...)
+			if len(job.AZs) > 1 {
+				errs = append(errs, bosherr.Errorf("%s.azs must contain at most one az", keys.job(idx)))
+			}
+			for azIdx, azName := range job.AZs {
+				if _, found := azNames[azName]; !found {
+					errs = append(errs, bosherr.Errorf("%s.azs[%d] '%s' must refer to an az in azs", keys.job(idx), azIdx, azName))
+				}
+			}
 
-		if job.Lifecycle != "" && job.Lifecycle != JobLifecycleService {
-			errs = append(errs, bosherr.Errorf("jobs[%d].lifecycle must be 'service' ('%s' not supported)", idx, job.Lifecycle))
+			errs = append(errs, v.validateJobNetworks(job.Networks, deploymentManifest.Networks, deploymentManifest.azName(job), keys.job(idx))...)
 		}
 
 		templateNames := map[string]struct{}{}
 		for templateIdx, template := range job.Templates {
 			if v.isBlank(template.Name) {
-				errs = append(errs, bosherr.Errorf("jobs[%d].templates[%d].name must be provided", idx, templateIdx))
+				errs = append(errs, bosherr.Errorf("%s.name must be provided", keys.template(idx, templateIdx)))
 			}
 			if _, found := templateNames[template.Name]; found {
-				errs = append(errs, bosherr.Errorf("jobs[%d].templates[%d].name '%s' must be unique", idx, templateIdx, template.Name))
+				errs = append(errs, bosherr.Errorf("%s.name '%s' must be unique", keys.template(idx, templateIdx), template.Name))
 			}
 			templateNames[template.Name] = struct{}{}
 
 			if v.isBlank(template.Release) {
-				errs = append(errs, bosherr.Errorf("jobs[%d].templates[%d].release must be provided", idx, templateIdx))
+				errs = append(errs, bosherr.Errorf("%s.release must be provided", keys.template(idx, templateIdx)))
 			} else {
 				_, found := releaseSetManifest.FindByName(template.Release)
 				if !found {
-					errs = append(errs, bosherr.Errorf("jobs[%d].templates[%d].release '%s' must refer to release in releases", idx, templateIdx, template.Release))
+					errs = append(errs, bosherr.Errorf("%s.release '%s' must refer to release in releases", keys.template(idx, templateIdx), template.Release))
 				}
 			}
 		}
@@ -135,16 +184,17 @@ func (v *validator) Validate(deploymentManifest Manifest, releaseSetManifest bir
 
 func (v *validator) ValidateReleaseJobs(deploymentManifest Manifest, releaseManager birel.Manager) error {
 	errs := []error{}
+	keys := deploymentManifest.Keys
 
 	for idx, job := range deploymentManifest.Jobs {
 		for templateIdx, template := range job.Templates {
 			release, found := releaseManager.Find(template.Release)
 			if !found {
-				errs = append(errs, bosherr.Errorf("jobs[%d].templates[%d].release '%s' must refer to release in releases", idx, templateIdx, template.Release))
+				errs = append(errs, bosherr.Errorf("%s.release '%s' must refer to release in releases", keys.template(idx, templateIdx), template.Release))
 			} else {
 				_, found := release.FindJobByName(template.Name)
 				if !found {
-					errs = append(errs, bosherr.Errorf("jobs[%d].templates[%d] must refer to a job in '%s', but there is no job named '%s'", idx, templateIdx, release.Name(), template.Name))
+					errs = append(errs, bosherr.Errorf("%s must refer to a job in '%s', but there is no job named '%s'", keys.template(idx, templateIdx), release.Name(), template.Name))
 				}
 			}
 		}
@@ -154,6 +204,17 @@ func (v *validator) ValidateReleaseJobs(deploymentManifest Manifest, releaseMana
 		return bosherr.NewMultiError(errs...)
 	}
 
+	for idx, job := range deploymentManifest.Jobs {
+		_, err := v.linkResolver.Resolve(deploymentManifest, job.Name)
+		if err != nil {
+			errs = append(errs, bosherr.WrapErrorf(err, "%s links must be satisfied", keys.job(idx)))
+		}
+	}
+
+	if len(errs) > 0 {
+		return bosherr.NewMultiError(errs...)
+	}
+
 	return nil
 }
 
@@ -208,19 +269,38 @@ func (in *somethingIpNet) Try(fn func(*net.IPNet) error) error {
 	return fn(in.ipNet)
 }
 
-func (v *validator) validateRange(idx int, ipRange string) ([]error, maybeIPNet) {
+func (v *validator) validateRange(idx, subnetIdx int, ipRange string) ([]error, maybeIPNet) {
 	if v.isBlank(ipRange) {
-		return []error{bosherr.Errorf("networks[%d].subnets[0].range must be provided", idx)}, &nothingIpNet{}
+		return []error{bosherr.Errorf("networks[%d].subnets[%d].range must be provided", idx, subnetIdx)}, &nothingIpNet{}
 	} else {
 		_, ipNet, err := net.ParseCIDR(ipRange)
 		if err != nil {
-			return []error{bosherr.Errorf("networks[%d].subnets[0].range must be an ip range", idx)}, &nothingIpNet{}
+			return []error{bosherr.Errorf("networks[%d].subnets[%d].range must be an ip range", idx, subnetIdx)}, &nothingIpNet{}
 		}
 
 		return []error{}, &somethingIpNet{ipNet: ipNet}
 	}
 }
 
+func (v *validator) validateSubnetRanges(idx, subnetIdx int, key string, ranges []string, ipNet maybeIPNet) []error {
+	errs := []error{}
+	for rangeIdx, value := range ranges {
+		r, err := parseIPRange(value)
+		if err != nil {
+			errs = append(errs, bosherr.Errorf("networks[%d].subnets[%d].%s[%d] must be an ip or an ip range 'first - last'", idx, subnetIdx, key, rangeIdx))
+			continue
+		}
+
+		_ = ipNet.Try(func(ipNet *net.IPNet) error {
+			if !r.within(ipNet) {
+				errs = append(errs, bosherr.Errorf("networks[%d].subnets[%d].%s[%d] '%s' must be within the subnet range '%s'", idx, subnetIdx, key, rangeIdx, value, ipNet))
+			}
+			return nil
+		})
+	}
+	return errs
+}
+
 func (v *validator) validateNetworks(networks []Network) []error {
 	errs := []error{}
 
@@ -242,30 +322,35 @@ func (v *validator) validateNetwork(network Network, networkIdx int) []error {
 		errs = append(errs, bosherr.Errorf("networks[%d].type must be 'manual', 'dynamic', or 'vip'", networkIdx))
 	}
 	if network.Type == Manual {
-		if len(network.Subnets) != 1 {
-			errs = append(errs, bosherr.Errorf("networks[%d].subnets must be of size 1", networkIdx))
-		} else {
-			ipRange := network.Subnets[0].Range
-			rangeErrors, maybeIpNet := v.validateRange(networkIdx, ipRange)
+		if len(network.Subnets) == 0 {
+			errs = append(errs, bosherr.Errorf("networks[%d].subnets must be provided", networkIdx))
+		}
+
+		for subnetIdx, subnet := range network.Subnets {
+			rangeErrors, maybeIpNet := v.validateRange(networkIdx, subnetIdx, subnet.Range)
 			errs = append(errs, rangeErrors...)
 
-			gateway := network.Subnets[0].Gateway
-			gatewayErrors := v.validateGateway(networkIdx, gateway, maybeIpNet)
+			gatewayErrors := v.validateGateway(networkIdx, subnetIdx, subnet.Gateway, maybeIpNet)
 			errs = append(errs, gatewayErrors...)
+
+			errs = append(errs, v.validateSubnetRanges(networkIdx, subnetIdx, "reserved", subnet.Reserved, maybeIpNet)...)
+			errs = append(errs, v.validateSubnetRanges(networkIdx, subnetIdx, "static", subnet.Static, maybeIpNet)...)
 		}
 	}
 
 	return errs
 }
 
-func (v *validator) validateJobNetworks(jobNetworks []JobNetwork, networks []Network, jobIdx int) []error {
+func (v *validator) validateJobNetworks(jobNetworks []JobNetwork, networks []Network, azName string, jobPath string) []error {
 	errs := []error{}
 	defaultCounts := make(map[NetworkDefault]int)
+	gatewayCountsByFamily := make(map[bool]int)
+	families := []bool{}
 
 	for networkIdx, jobNetwork := range jobNetworks {
 
 		if v.isBlank(jobNetwork.Name) {
-			errs = append(errs, bosherr.Errorf("jobs[%d].networks[%d].name must be provided", jobIdx, networkIdx))
+			errs = append(errs, bosherr.Errorf("%s.networks[%d].name must be provided", jobPath, networkIdx))
 		}
 
 		var matchingNetwork Network
@@ -278,34 +363,42 @@ func (v *validator) validateJobNetworks(jobNetworks []JobNetwork, networks []Net
 		}
 
 		if !found {
-			errs = append(errs, bosherr.Errorf("jobs[%d].networks[%d] not found in networks", jobIdx, networkIdx))
+			errs = append(errs, bosherr.Errorf("%s.networks[%d] not found in networks", jobPath, networkIdx))
 		}
 
 		for ipIdx, ip := range jobNetwork.StaticIPs {
-			staticIPErrors := v.validateStaticIP(ip, jobNetwork, matchingNetwork, jobIdx, networkIdx, ipIdx)
+			staticIPErrors := v.validateStaticIP(ip, jobNetwork, matchingNetwork, azName, jobPath, networkIdx, ipIdx)
 			errs = append(errs, staticIPErrors...)
 		}
 
 		for defaultIdx, value := range jobNetwork.Defaults {
 			if value != NetworkDefaultDNS && value != NetworkDefaultGateway {
-				errs = append(errs, bosherr.Errorf("jobs[%d].networks[%d].default[%d] must be 'dns' or 'gateway'", jobIdx, networkIdx, defaultIdx))
+				errs = append(errs, bosherr.Errorf("%s.networks[%d].default[%d] must be 'dns' or 'gateway'", jobPath, networkIdx, defaultIdx))
 			}
 		}
 
+		isIPv6 := matchingNetwork.IsIPv6(jobNetwork.StaticIPs)
+		families = append(families, isIPv6)
+
 		for _, dflt := range jobNetwork.Defaults {
-			count, present := defaultCounts[dflt]
-			if present {
-				defaultCounts[dflt] = count + 1
-			} else {
-				defaultCounts[dflt] = 1
+			defaultCounts[dflt]++
+			if dflt == NetworkDefaultGateway {
+				gatewayCountsByFamily[isIPv6]++
 			}
 		}
 	}
+
+	// a job with one IPv4 and one IPv6 network may have a gateway default for each, and gets defaults assigned when it sets none
+	dualStack := len(families) == 2 && families[0] != families[1]
+	assignedDefaults := dualStack && len(defaultCounts) == 0
+
 	for _, dflt := range []NetworkDefault{"dns", "gateway"} {
-		count, found := defaultCounts[dflt]
-		if len(jobNetworks) > 1 && !found {
+		_, found := defaultCounts[dflt]
+		if len(jobNetworks) > 1 && !found && !assignedDefaults {
 			errs = append(errs, bosherr.Errorf("with multiple networks, a default for '%s' must be specified", dflt))
-		} else if count > 1 {
+		} else if dflt == NetworkDefaultDNS && defaultCounts[dflt] > 1 {
+			errs = append(errs, bosherr.Errorf("only one network can be the default for '%s'", dflt))
+		} else if dflt == NetworkDefaultGateway && (gatewayCountsByFamily[false] > 1 || gatewayCountsByFamily[true] > 1) {
 			errs = append(errs, bosherr.Errorf("only one network can be the default for '%s'", dflt))
 		}
 	}
@@ -313,39 +406,61 @@ func (v *validator) validateJobNetworks(jobNetworks []JobNetwork, networks []Net
 	return errs
 }
 
-func (v *validator) validateStaticIP(ip string, jobNetwork JobNetwork, network Network, jobIdx, networkIdx, ipIdx int) []error {
+func (v *validator) validateStaticIP(ip string, jobNetwork JobNetwork, network Network, azName string, jobPath string, networkIdx, ipIdx int) []error {
 	if !v.isValidIP(ip) {
-		return []error{bosherr.Errorf("jobs[%d].networks[%d].static_ips[%d] must be a valid IP", jobIdx, networkIdx, ipIdx)}
+		return []error{bosherr.Errorf("%s.networks[%d].static_ips[%d] must be a valid IP", jobPath, networkIdx, ipIdx)}
 	}
 
 	if network.Type != Manual {
 		return []error{}
 	}
 
-	foundInSubnetRange := false
-	for _, subnet := range network.Subnets {
-		_, rangeNet, err := net.ParseCIDR(subnet.Range)
-		if err == nil && rangeNet.Contains(net.ParseIP(ip)) {
-			foundInSubnetRange = true
+	subnet, found := network.SubnetFor(ip)
+	if !found {
+		return []error{bosherr.Errorf("%s.networks[%d] static ip '%s' must be within subnet range", jobPath, networkIdx, ip)}
+	}
+
+	if !subnet.InAZ(azName) {
+		return []error{bosherr.Errorf("%s.networks[%d] static ip '%s' must be within a subnet of az '%s'", jobPath, networkIdx, ip, azName)}
+	}
+
+	staticIP := net.ParseIP(ip)
+	if staticIP.Equal(net.ParseIP(subnet.Gateway)) {
+		return []error{bosherr.Errorf("%s.networks[%d] static ip '%s' can't be the subnet gateway", jobPath, networkIdx, ip)}
+	}
+
+	reserved, err := parseIPRanges(subnet.Reserved)
+	if err != nil {
+		return []error{}
+	}
+	for _, r := range reserved {
+		if r.contains(staticIP) {
+			return []error{bosherr.Errorf("%s.networks[%d] static ip '%s' can't be within a reserved range", jobPath, networkIdx, ip)}
 		}
 	}
 
-	if foundInSubnetRange {
+	static, err := parseIPRanges(subnet.Static)
+	if err != nil || len(static) == 0 {
 		return []error{}
-	} else {
-		return []error{bosherr.Errorf("jobs[%d].networks[%d] static ip '%s' must be within subnet range", jobIdx, networkIdx, ip)}
 	}
+	for _, r := range static {
+		if r.contains(staticIP) {
+			return []error{}
+		}
+	}
+
+	return []error{bosherr.Errorf("%s.networks[%d] static ip '%s' must be within a static range of its subnet", jobPath, networkIdx, ip)}
 }
 
-func (v *validator) validateGateway(idx int, gateway string, ipNet maybeIPNet) []error {
+func (v *validator) validateGateway(idx, subnetIdx int, gateway string, ipNet maybeIPNet) []error {
 	if v.isBlank(gateway) {
-		return []error{bosherr.Errorf("networks[%d].subnets[0].gateway must be provided", idx)}
+		return []error{bosherr.Errorf("networks[%d].subnets[%d].gateway must be provided", idx, subnetIdx)}
 	} else {
 		errors := []error{}
 		_ = ipNet.Try(func(ipNet *net.IPNet) error {
 			gatewayIp := net.ParseIP(gateway)
 			if gatewayIp == nil {
-				errors = append(errors, bosherr.Errorf("networks[%d].subnets[0].gateway must be an ip", idx))
+				errors = append(errors, bosherr.Errorf("networks[%d].subnets[%d].gateway must be an ip", idx, subnetIdx))
 			}
 
 			if !ipNet.Contains(gatewayIp) {
@@ -356,7 +471,7 @@ func (v *validator) validateGateway(idx int, gateway string, ipNet maybeIPNet) [
 				errors = append(errors, bosherr.Errorf("subnet gateway can't be the network address '%s'", gatewayIp))
 			}
 
-			if binet.LastAddress(ipNet).Equal(gatewayIp) {
+			if binet.IsIPv4Net(ipNet) && binet.LastAddress(ipNet).Equal(gatewayIp) {
 				errors = append(errors, bosherr.Errorf("subnet gateway can't be the broadcast address '%s'", gatewayIp))
 			}
 
@@ -366,3 +481,40 @@ func (v *validator) validateGateway(idx int, gateway string, ipNet maybeIPNet) [
 		return errors
 	}
 }
+
+// validateStemcell checks that the stemcell has a URL, or else a name and version to resolve through the index
+func (v *validator) validateStemcell(stemcell StemcellRef, path string) []error {
+	errs := []error{}
+
+	if v.isBlank(stemcell.URL) && !v.isBlank(stemcell.Name) {
+		if v.isBlank(stemcell.Version) {
+			errs = append(errs, bosherr.Errorf("%s.version must be provided with %s.name", path, path))
+		}
+		return errs
+	}
+
+	if v.isBlank(stemcell.URL) {
+		errs = append(errs, bosherr.Errorf("%s.url must be provided", path))
+	}
+
+	matched, err := regexp.MatchString("^(file|http|https|s3)://", stemcell.URL)
+	if err != nil || !matched {
+		errs = append(errs, bosherr.Errorf("%s.url must be a valid URL (file://, http(s):// or s3://)", path))
+	}
+
+	if strings.HasPrefix(stemcell.URL, "http") && v.isBlank(stemcell.SHA1) {
+		errs = append(errs, bosherr.Errorf("%s.sha1 must be provided for http URL", path))
+	}
+
+	if strings.HasPrefix(stemcell.URL, "s3://") && v.isBlank(stemcell.SHA1) {
+		errs = append(errs, bosherr.Errorf("%s.sha1 must be provided for s3 URL", path))
+	}
+
+	if !v.isBlank(stemcell.SHA1) {
+		if _, err := bicrypto.ParseMultipleDigest(stemcell.SHA1); err != nil {
+			errs = append(errs, bosherr.WrapErrorf(err, "%s.sha1 must be a SHA1 or digests such as 'sha256:...'", path))
+		}
+	}
+
+	return errs
+}
@@ -1,6 +1,7 @@
 package manifest_test
 
 import (
+	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
 	birel "github.com/cloudfoundry/bosh-init/release"
 	bireljob "github.com/cloudfoundry/bosh-init/release/job"
 	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
@@ -109,7 +110,7 @@ var _ = Describe("Validator", func() {
 		fakeRelease = fakebirel.New("fake-release-name", "1.0")
 		fakeRelease.ReleaseJobs = []bireljob.Job{{Name: "fake-job-name"}}
 		releaseManager.Add(fakeRelease)
-		validator = NewValidator(logger)
+		validator = NewValidator(NewLinkResolver(bideplrel.NewJobResolver(releaseManager)), logger)
 	})
 
 	Describe("Validate", func() {
@@ -225,7 +226,7 @@ var _ = Describe("Validator", func() {
 
 			err = validator.Validate(deploymentManifest, validReleaseSetManifest)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("resource_pools[0].stemcell.url must be a valid URL (file:// or http(s)://)"))
+			Expect(err.Error()).To(ContainSubstring("resource_pools[0].stemcell.url must be a valid URL (file://, http(s):// or s3://)"))
 
 			deploymentManifest = Manifest{
 				ResourcePools: []ResourcePool{
@@ -240,6 +241,36 @@ var _ = Describe("Validator", func() {
 			err = validator.Validate(deploymentManifest, validReleaseSetManifest)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("resource_pools[0].stemcell.sha1 must be provided for http URL"))
+
+			deploymentManifest = Manifest{
+				ResourcePools: []ResourcePool{
+					{
+						Stemcell: StemcellRef{
+							URL:  "https://fake-url",
+							SHA1: "sha512:fake-sha512",
+						},
+					},
+				},
+			}
+
+			err = validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("resource_pools[0].stemcell.sha1 must be a SHA1 or digests such as 'sha256:...'"))
+
+			deploymentManifest = Manifest{
+				ResourcePools: []ResourcePool{
+					{
+						Stemcell: StemcellRef{
+							Name: "fake-stemcell-name",
+						},
+					},
+				},
+			}
+
+			err = validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("resource_pools[0].stemcell.version must be provided with resource_pools[0].stemcell.name"))
+			Expect(err.Error()).ToNot(ContainSubstring("resource_pools[0].stemcell.url"))
 		})
 
 		It("validates disk pool name", func() {
@@ -285,6 +316,74 @@ var _ = Describe("Validator", func() {
 			Expect(err.Error()).To(ContainSubstring("disk_pools[0].disk_size must be > 0"))
 		})
 
+		Describe("azs", func() {
+			var deploymentManifest Manifest
+
+			BeforeEach(func() {
+				deploymentManifest = validManifest
+				deploymentManifest.AZs = []AZ{{Name: "z1"}}
+				deploymentManifest.ResourcePools = []ResourcePool{validManifest.ResourcePools[0]}
+				deploymentManifest.ResourcePools[0].AZ = "z1"
+				deploymentManifest.Jobs = []Job{validManifest.Jobs[0]}
+				deploymentManifest.Jobs[0].AZs = []string{"z1"}
+			})
+
+			It("does not error when the resource pool and job refer to azs", func() {
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("validates az names are provided and unique", func() {
+				deploymentManifest.AZs = []AZ{{Name: "z1"}, {Name: ""}, {Name: "z1"}}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("azs[1].name must be provided"))
+				Expect(err.Error()).To(ContainSubstring("azs[2].name 'z1' must be unique"))
+			})
+
+			It("validates the resource pool az refers to an az", func() {
+				deploymentManifest.ResourcePools[0].AZ = "fake-missing-az"
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("resource_pools[0].az 'fake-missing-az' must refer to an az in azs"))
+			})
+
+			It("validates the job azs refer to azs", func() {
+				deploymentManifest.Jobs[0].AZs = []string{"fake-missing-az"}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobs[0].azs[0] 'fake-missing-az' must refer to an az in azs"))
+			})
+
+			It("validates the job has at most one az", func() {
+				deploymentManifest.AZs = []AZ{{Name: "z1"}, {Name: "z2"}}
+				deploymentManifest.Jobs[0].AZs = []string{"z1", "z2"}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobs[0].azs must contain at most one az"))
+			})
+
+			It("validates the subnet az refers to an az", func() {
+				deploymentManifest.Networks = []Network{
+					{
+						Name: "fake-network-name",
+						Type: Manual,
+						Subnets: []Subnet{
+							{Range: "10.10.0.0/24", Gateway: "10.10.0.1", AZ: "fake-missing-az"},
+						},
+					},
+				}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("networks[0].subnets[0].az 'fake-missing-az' must refer to an az in azs"))
+			})
+		})
+
 		Describe("networks", func() {
 			It("validates name is present", func() {
 				deploymentManifest := Manifest{
@@ -356,7 +455,7 @@ var _ = Describe("Validator", func() {
 			})
 
 			Context("manual networks", func() {
-				It("validates that there is exactly 1 subnet", func() {
+				It("validates that there is at least 1 subnet", func() {
 					deploymentManifest := Manifest{
 						Networks: []Network{
 							{
@@ -368,7 +467,51 @@ var _ = Describe("Validator", func() {
 
 					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
 					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("networks[0].subnets must be of size 1"))
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets must be provided"))
+				})
+
+				It("validates every subnet", func() {
+					err := validator.Validate(Manifest{
+						Networks: []Network{
+							{
+								Type: "manual",
+								Subnets: []Subnet{
+									{
+										Range:   "10.10.0.0/24",
+										Gateway: "10.10.0.1",
+									},
+									{
+										Range: "10.10.1.0/24",
+									},
+								},
+							},
+						},
+					}, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).ToNot(ContainSubstring("networks[0].subnets[0]"))
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets[1].gateway must be provided"))
+				})
+
+				It("validates that reserved and static ranges are ip ranges within the subnet range", func() {
+					err := validator.Validate(Manifest{
+						Networks: []Network{
+							{
+								Type: "manual",
+								Subnets: []Subnet{{
+									Range:    "10.10.0.0/24",
+									Gateway:  "10.10.0.1",
+									Reserved: []string{"10.10.0.2 - 10.10.0.10", "not-an-ip"},
+									Static:   []string{"10.10.0.20", "10.10.0.30 - 10.10.1.30", "10.10.0.40 - 10.10.0.35"},
+								}},
+							},
+						},
+					}, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).ToNot(ContainSubstring("networks[0].subnets[0].reserved[0]"))
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets[0].reserved[1] must be an ip or an ip range 'first - last'"))
+					Expect(err.Error()).ToNot(ContainSubstring("networks[0].subnets[0].static[0]"))
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets[0].static[1] '10.10.0.30 - 10.10.1.30' must be within the subnet range '10.10.0.0/24'"))
+					Expect(err.Error()).To(ContainSubstring("networks[0].subnets[0].static[2] must be an ip or an ip range 'first - last'"))
 				})
 
 				It("validates that range is present", func() {
@@ -499,6 +642,22 @@ var _ = Describe("Validator", func() {
 					Expect(err.Error()).To(ContainSubstring("subnet gateway can't be the network address '10.10.0.0'"))
 				})
 
+				It("allows the last ip in an IPv6 range as gateway", func() {
+					err := validator.Validate(Manifest{
+						Networks: []Network{
+							{
+								Type: "manual",
+								Subnets: []Subnet{{
+									Range:   "2001:db8::/64",
+									Gateway: "2001:db8::ffff:ffff:ffff:ffff",
+								}},
+							},
+						},
+					}, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).ToNot(ContainSubstring("gateway"))
+				})
+
 				It("validates that the gateway is not the last ip in the range", func() {
 					err := validator.Validate(Manifest{
 						Networks: []Network{
@@ -567,7 +726,7 @@ var _ = Describe("Validator", func() {
 			})
 		})
 
-		It("validates that there is only one job", func() {
+		It("validates that there is only one service job", func() {
 			deploymentManifest := Manifest{
 				Jobs: []Job{
 					{},
@@ -577,7 +736,38 @@ var _ = Describe("Validator", func() {
 
 			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("jobs must be of size 1"))
+			Expect(err.Error()).To(ContainSubstring("jobs must have exactly 1 job with lifecycle 'service'"))
+		})
+
+		It("validates that there is a service job next to the errand jobs", func() {
+			deploymentManifest := validManifest
+			deploymentManifest.Jobs = []Job{
+				{
+					Name:      "fake-errand-name",
+					Lifecycle: JobLifecycleErrand,
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs must have exactly 1 job with lifecycle 'service'"))
+		})
+
+		It("permits errand jobs next to the service job", func() {
+			deploymentManifest := validManifest
+			deploymentManifest.Jobs = append(deploymentManifest.Jobs, Job{
+				Name:      "fake-errand-name",
+				Lifecycle: JobLifecycleErrand,
+				Templates: []ReleaseJobRef{
+					{
+						Name:    "fake-errand-job-name",
+						Release: "fake-release-name",
+					},
+				},
+			})
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("validates job name", func() {
@@ -784,6 +974,98 @@ var _ = Describe("Validator", func() {
 				Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] not found in networks"))
 			})
 
+			Context("when the network has several subnets", func() {
+				var deploymentManifest Manifest
+
+				BeforeEach(func() {
+					deploymentManifest = Manifest{
+						Networks: []Network{
+							{
+								Name: "fake-network-name",
+								Type: "manual",
+								Subnets: []Subnet{
+									{
+										Range:    "10.10.0.0/24",
+										Gateway:  "10.10.0.1",
+										Reserved: []string{"10.10.0.2 - 10.10.0.9"},
+									},
+									{
+										Range:   "10.10.1.0/24",
+										Gateway: "10.10.1.1",
+										Static:  []string{"10.10.1.10 - 10.10.1.20"},
+									},
+								},
+							},
+						},
+						Jobs: []Job{
+							{
+								Networks: []JobNetwork{
+									{
+										Name: "fake-network-name",
+									},
+								},
+							},
+						},
+					}
+				})
+
+				It("allows a static ip in any subnet", func() {
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.0.10"}
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).ToNot(ContainSubstring("static ip"))
+
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.1.15"}
+					err = validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).ToNot(ContainSubstring("static ip"))
+				})
+
+				It("validates that the static ip is not the gateway", func() {
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.0.1"}
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] static ip '10.10.0.1' can't be the subnet gateway"))
+				})
+
+				It("validates that the static ip is not reserved", func() {
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.0.5"}
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] static ip '10.10.0.5' can't be within a reserved range"))
+				})
+
+				Context("when the subnets are in azs", func() {
+					BeforeEach(func() {
+						deploymentManifest.AZs = []AZ{{Name: "z1"}, {Name: "z2"}}
+						deploymentManifest.Networks[0].Subnets[0].AZ = "z1"
+						deploymentManifest.Networks[0].Subnets[1].AZ = "z2"
+						deploymentManifest.Jobs[0].AZs = []string{"z2"}
+					})
+
+					It("allows a static ip from a subnet in the az of the job", func() {
+						deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.1.15"}
+						err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).ToNot(ContainSubstring("static ip"))
+					})
+
+					It("validates that the static ip is from a subnet in the az of the job", func() {
+						deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.0.10"}
+						err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] static ip '10.10.0.10' must be within a subnet of az 'z2'"))
+					})
+				})
+
+				It("validates that the static ip is within a static range when the subnet has static ranges", func() {
+					deploymentManifest.Jobs[0].Networks[0].StaticIPs = []string{"10.10.1.30"}
+					err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("jobs[0].networks[0] static ip '10.10.1.30' must be within a static range of its subnet"))
+				})
+			})
+
 			It("validates job network static ip is in the subnet range", func() {
 				deploymentManifest := Manifest{
 					Networks: []Network{
@@ -892,6 +1174,60 @@ var _ = Describe("Validator", func() {
 					})
 				})
 
+				Context("with an IPv4 and an IPv6 network", func() {
+					BeforeEach(func() {
+						deploymentManifest = Manifest{
+							Networks: []Network{
+								{
+									Name:    "fake-ipv4-network",
+									Type:    "manual",
+									Subnets: []Subnet{{Range: "10.10.0.0/24", Gateway: "10.10.0.1"}},
+								},
+								{
+									Name:    "fake-ipv6-network",
+									Type:    "manual",
+									Subnets: []Subnet{{Range: "2001:db8::/64", Gateway: "2001:db8::1"}},
+								},
+							},
+							Jobs: []Job{
+								{
+									Networks: []JobNetwork{
+										{Name: "fake-ipv4-network", StaticIPs: []string{"10.10.0.6"}},
+										{Name: "fake-ipv6-network", StaticIPs: []string{"2001:db8::6"}},
+									},
+								},
+							},
+						}
+					})
+
+					It("doesn't require any defaults to be set", func() {
+						err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).ToNot(ContainSubstring("default"))
+						Expect(err.Error()).ToNot(ContainSubstring("static ip"))
+						Expect(err.Error()).ToNot(ContainSubstring("networks[1]"))
+					})
+
+					It("allows a default gateway for each network", func() {
+						deploymentManifest.Jobs[0].Networks[0].Defaults = []NetworkDefault{"dns", "gateway"}
+						deploymentManifest.Jobs[0].Networks[1].Defaults = []NetworkDefault{"gateway"}
+
+						err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).ToNot(ContainSubstring("default"))
+					})
+
+					It("validates a default dns can only be specified for a single network", func() {
+						deploymentManifest.Jobs[0].Networks[0].Defaults = []NetworkDefault{"dns", "gateway"}
+						deploymentManifest.Jobs[0].Networks[1].Defaults = []NetworkDefault{"dns", "gateway"}
+
+						err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("only one network can be the default for 'dns'"))
+						Expect(err.Error()).ToNot(ContainSubstring("only one network can be the default for 'gateway'"))
+					})
+				})
+
 				Context("with only one network", func() {
 					BeforeEach(func() {
 						deploymentManifest = Manifest{
@@ -929,14 +1265,31 @@ var _ = Describe("Validator", func() {
 			deploymentManifest := Manifest{
 				Jobs: []Job{
 					{
-						Lifecycle: "errand",
+						Lifecycle: "fake-lifecycle",
+					},
+				},
+			}
+
+			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("jobs[0].lifecycle must be 'service' or 'errand' ('fake-lifecycle' not supported)"))
+		})
+
+		It("validates errand jobs have templates", func() {
+			deploymentManifest := Manifest{
+				Jobs: []Job{
+					{
+						Name:      "fake-errand-name",
+						Lifecycle: JobLifecycleErrand,
 					},
 				},
 			}
 
 			err := validator.Validate(deploymentManifest, validReleaseSetManifest)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("jobs[0].lifecycle must be 'service' ('errand' not supported)"))
+			Expect(err.Error()).To(ContainSubstring("jobs[0].templates must be a non-empty array for errands"))
+			Expect(err.Error()).ToNot(ContainSubstring("jobs[0].networks"))
+			Expect(err.Error()).ToNot(ContainSubstring("jobs[0].resource_pool"))
 		})
 
 		It("permits job templates to reference an undeclared release", func() {
@@ -1007,6 +1360,74 @@ var _ = Describe("Validator", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("jobs[0].templates[0].release 'fake-other-release-name' must refer to release in releases"))
 		})
+		Context("when the manifest uses instance_groups, stemcells, vm_types and disk_types", func() {
+			var deploymentManifest Manifest
+
+			BeforeEach(func() {
+				deploymentManifest = validManifest
+				deploymentManifest.Keys = Keys{
+					InstanceGroups: true,
+					VMTypes:        true,
+					DiskTypes:      true,
+					VMTypeResourcePools: map[int]VMTypeResourcePool{
+						0: {JobIndex: 0, StemcellIndex: 1},
+					},
+				}
+			})
+
+			It("cites the stemcell the resource pool was built from", func() {
+				deploymentManifest.ResourcePools = []ResourcePool{
+					{Name: "fake-job-name", Network: "fake-network-name", Stemcell: StemcellRef{Name: "fake-stemcell-name"}},
+				}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("stemcells[1].version must be provided with stemcells[1].name"))
+				Expect(err.Error()).ToNot(ContainSubstring("resource_pools"))
+			})
+
+			It("cites the instance group instead of the resource pool built from it", func() {
+				deploymentManifest.ResourcePools = []ResourcePool{
+					{Name: "fake-job-name", Stemcell: StemcellRef{URL: "file://fake-stemcell-url"}},
+				}
+				deploymentManifest.Jobs = []Job{
+					{Name: "fake-job-name", Lifecycle: "service", ResourcePool: "fake-job-name"},
+				}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("instance_groups[0].networks must be a non-empty array"))
+				Expect(err.Error()).ToNot(ContainSubstring("resource_pools"))
+			})
+
+			It("cites the instance group, its jobs, vm_type and persistent_disk_type", func() {
+				deploymentManifest.Jobs = []Job{
+					{
+						Name:               "fake-job-name",
+						Lifecycle:          "service",
+						PersistentDiskPool: "fake-missing-disk-type",
+						Templates:          []ReleaseJobRef{{Name: "fake-job-name", Release: "fake-other-release-name"}},
+						Networks:           []JobNetwork{{Name: "fake-missing-network-name"}},
+					},
+				}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("instance_groups[0].vm_type must be provided"))
+				Expect(err.Error()).To(ContainSubstring("instance_groups[0].persistent_disk_type must be the name of a disk_type"))
+				Expect(err.Error()).To(ContainSubstring("instance_groups[0].networks[0] not found in networks"))
+				Expect(err.Error()).To(ContainSubstring("instance_groups[0].jobs[0].release 'fake-other-release-name' must refer to release in releases"))
+				Expect(err.Error()).ToNot(ContainSubstring("templates"))
+			})
+
+			It("cites disk_types", func() {
+				deploymentManifest.DiskPools = []DiskPool{{Name: "fake-disk-type"}}
+
+				err := validator.Validate(deploymentManifest, validReleaseSetManifest)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("disk_types[0].disk_size must be > 0"))
+			})
+		})
 	})
 
 	Describe("ValidateReleaseJobs", func() {
@@ -1024,5 +1445,40 @@ var _ = Describe("Validator", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("jobs[0].templates[0] must refer to a job in 'fake-release-name', but there is no job named 'fake-other-job-name'"))
 		})
+
+		Context("when release jobs consume links", func() {
+			BeforeEach(func() {
+				fakeRelease.ReleaseJobs = []bireljob.Job{
+					{
+						Name:     "fake-job-name",
+						Consumes: []bireljob.LinkDefinition{{Name: "fake-db", Type: "fake-db-type"}},
+					},
+					{
+						Name:     "fake-db-job-name",
+						Provides: []bireljob.LinkDefinition{{Name: "fake-db", Type: "fake-db-type"}},
+					},
+				}
+			})
+
+			It("does not error when the links are provided within the deployment", func() {
+				deploymentManifest := validManifest
+				deploymentManifest.Jobs[0].Templates = append(deploymentManifest.Jobs[0].Templates, ReleaseJobRef{
+					Name:    "fake-db-job-name",
+					Release: "fake-release-name",
+				})
+
+				err := validator.ValidateReleaseJobs(deploymentManifest, releaseManager)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("validates required links are provided within the deployment", func() {
+				deploymentManifest := validManifest
+
+				err := validator.ValidateReleaseJobs(deploymentManifest, releaseManager)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("jobs[0] links must be satisfied"))
+				Expect(err.Error()).To(ContainSubstring("Job 'fake-job-name' in instance group 'fake-job-name' consumes link 'fake-db' of type 'fake-db-type', but no job in the deployment provides it"))
+			})
+		})
 	})
 })
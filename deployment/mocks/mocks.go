@@ -4,7 +4,7 @@
 package mocks
 
 import (
-	agentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	agentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	blobstore "github.com/cloudfoundry/bosh-init/blobstore"
 	cloud "github.com/cloudfoundry/bosh-init/cloud"
 	deployment "github.com/cloudfoundry/bosh-init/deployment"
@@ -39,14 +39,45 @@ func (_m *MockDeployment) EXPECT() *_MockDeploymentRecorder {
 	return _m.recorder
 }
 
-func (_m *MockDeployment) Delete(_param0 ui.Stage) error {
-	ret := _m.ctrl.Call(_m, "Delete", _param0)
+func (_m *MockDeployment) Delete(_param0 ui.Stage, _param1 instance.DrainOptions) error {
+	ret := _m.ctrl.Call(_m, "Delete", _param0, _param1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockDeploymentRecorder) Delete(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0)
+func (_mr *_MockDeploymentRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1)
+}
+
+func (_m *MockDeployment) RunErrand(_param0 ui.Stage, _param1 string, _param2 manifest.Manifest, _param3 bool) (instance.ErrandResult, error) {
+	ret := _m.ctrl.Call(_m, "RunErrand", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(instance.ErrandResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDeploymentRecorder) RunErrand(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RunErrand", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockDeployment) Start(_param0 ui.Stage, _param1 bool, _param2 manifest.WatchTime) error {
+	ret := _m.ctrl.Call(_m, "Start", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentRecorder) Start(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Start", arg0, arg1, arg2)
+}
+
+func (_m *MockDeployment) Stop(_param0 ui.Stage, _param1 bool, _param2 instance.DrainOptions) error {
+	ret := _m.ctrl.Call(_m, "Stop", _param0, _param1, _param2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDeploymentRecorder) Stop(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Stop", arg0, arg1, arg2)
 }
 
 // Mock of Factory interface
@@ -101,15 +132,15 @@ func (_m *MockDeployer) EXPECT() *_MockDeployerRecorder {
 	return _m.recorder
 }
 
-func (_m *MockDeployer) Deploy(_param0 cloud.Cloud, _param1 manifest.Manifest, _param2 stemcell.CloudStemcell, _param3 manifest0.Registry, _param4 vm.Manager, _param5 blobstore.Blobstore, _param6 ui.Stage) (deployment.Deployment, error) {
-	ret := _m.ctrl.Call(_m, "Deploy", _param0, _param1, _param2, _param3, _param4, _param5, _param6)
+func (_m *MockDeployer) Deploy(_param0 cloud.Cloud, _param1 manifest.Manifest, _param2 stemcell.CloudStemcell, _param3 manifest0.Registry, _param4 vm.Manager, _param5 blobstore.Blobstore, _param6 instance.DrainOptions, _param7 ui.Stage) (deployment.Deployment, error) {
+	ret := _m.ctrl.Call(_m, "Deploy", _param0, _param1, _param2, _param3, _param4, _param5, _param6, _param7)
 	ret0, _ := ret[0].(deployment.Deployment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-func (_mr *_MockDeployerRecorder) Deploy(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "Deploy", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+func (_mr *_MockDeployerRecorder) Deploy(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Deploy", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 }
 
 // Mock of Manager interface
@@ -0,0 +1,117 @@
+package sshtunnel
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// newClientConfig authenticates with the private key file, the keys of a running ssh-agent (SSH_AUTH_SOCK)
+// and the password, in that order, skipping those that are not available
+func newClientConfig(user, privateKey, passphrase, password string, logger boshlog.Logger, logTag string) (*ssh.ClientConfig, error) {
+	authMethods := []ssh.AuthMethod{}
+
+	if privateKey != "" {
+		logger.Debug(logTag, "Reading private key file '%s'", privateKey)
+		keyContents, err := ioutil.ReadFile(privateKey)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading private key file '%s'", privateKey)
+		}
+
+		logger.Debug(logTag, "Parsing private key file '%s'", privateKey)
+		signer, err := parsePrivateKey(keyContents, passphrase)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing private key file '%s'", privateKey)
+		}
+
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+
+	if agentSocket := os.Getenv("SSH_AUTH_SOCK"); agentSocket != "" {
+		logger.Debug(logTag, "Adding ssh-agent auth method using '%s'", agentSocket)
+		authMethods = append(authMethods, ssh.PublicKeysCallback(agentSigners(agentSocket)))
+	}
+
+	if password != "" {
+		logger.Debug(logTag, "Adding password auth method to ssh config")
+
+		keyboardInteractiveChallenge := func(
+			user,
+			instruction string,
+			questions []string,
+			echos []bool,
+		) (answers []string, err error) {
+			if len(questions) == 0 {
+				return []string{}, nil
+			}
+			return []string{password}, nil
+		}
+		authMethods = append(authMethods, ssh.KeyboardInteractive(keyboardInteractiveChallenge))
+		authMethods = append(authMethods, ssh.Password(password))
+	}
+
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: authMethods,
+	}, nil
+}
+
+// parsePrivateKey parses a PEM encoded private key, decrypting it with passphrase when it is encrypted
+func parsePrivateKey(contents []byte, passphrase string) (ssh.Signer, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return ssh.ParsePrivateKey(contents)
+	}
+
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		return nil, bosherr.Error("Private keys in OpenSSH format are not supported, convert the key to PEM format with 'ssh-keygen -p -m PEM -f <key>'")
+	}
+
+	if !x509.IsEncryptedPEMBlock(block) {
+		return ssh.ParsePrivateKey(contents)
+	}
+
+	if passphrase == "" {
+		return nil, bosherr.Error("Private key is encrypted but no passphrase was provided")
+	}
+
+	der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Decrypting private key")
+	}
+
+	return ssh.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}))
+}
+
+// agentSigners returns the keys held by the ssh-agent listening on agentSocket.
+// The signers use the agent connection, so it stays open until the keys are requested again.
+func agentSigners(agentSocket string) func() ([]ssh.Signer, error) {
+	var conn net.Conn
+
+	return func() ([]ssh.Signer, error) {
+		if conn != nil {
+			conn.Close()
+		}
+
+		var err error
+		conn, err = net.Dial("unix", agentSocket)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Connecting to ssh-agent at '%s'", agentSocket)
+		}
+
+		signers, err := agent.NewClient(conn).Signers()
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Listing ssh-agent keys")
+		}
+
+		return signers, nil
+	}
+}
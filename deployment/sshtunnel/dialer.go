@@ -0,0 +1,149 @@
+package sshtunnel
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// ProxyOptions configure how connections to the agent and the SSH tunnel host are made.
+// URL is a socks5:// proxy URL. JumpHost is an SSH server that connections are forwarded through.
+// At most one of them is set; when neither is set connections are made directly.
+type ProxyOptions struct {
+	URL      string
+	JumpHost JumpHostOptions
+}
+
+func (o ProxyOptions) IsEmpty() bool {
+	return o == ProxyOptions{}
+}
+
+type JumpHostOptions struct {
+	Host                 string
+	Port                 int
+	User                 string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	Password             string
+
+	HostKey HostKeyOptions
+}
+
+func (o JumpHostOptions) IsEmpty() bool {
+	return o == JumpHostOptions{}
+}
+
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// NewDialer returns a dialer that connects through the configured proxy.
+// The jump host's key is verified like the SSH tunnel host's, with hostKeyStore recording keys trusted on first use.
+func NewDialer(options ProxyOptions, hostKeyStore HostKeyStore, logger boshlog.Logger) (Dialer, error) {
+	directDialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	if !options.JumpHost.IsEmpty() {
+		return &jumpHostDialer{
+			options:      options.JumpHost,
+			forward:      directDialer,
+			hostKeyStore: hostKeyStore,
+			logger:       logger,
+			logTag:       "jumpHostDialer",
+		}, nil
+	}
+
+	if options.URL != "" {
+		proxyURL, err := url.Parse(options.URL)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing proxy URL '%s'", options.URL)
+		}
+
+		if proxyURL.Scheme != "socks5" {
+			return nil, bosherr.Errorf("Unsupported proxy scheme '%s', expected 'socks5'", proxyURL.Scheme)
+		}
+
+		dialer, err := proxy.FromURL(proxyURL, directDialer)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Creating socks5 dialer for '%s'", proxyURL.Host)
+		}
+
+		return dialer, nil
+	}
+
+	return directDialer, nil
+}
+
+type jumpHostDialer struct {
+	options      JumpHostOptions
+	forward      Dialer
+	hostKeyStore HostKeyStore
+	logger       boshlog.Logger
+	logTag       string
+
+	client *ssh.Client
+	lock   sync.Mutex
+}
+
+func (d *jumpHostDialer) Dial(network, address string) (net.Conn, error) {
+	client, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	d.logger.Debug(d.logTag, "Dialing %s through jump host", address)
+	conn, err := client.Dial(network, address)
+	if err != nil {
+		d.disconnect(client)
+		return nil, bosherr.WrapErrorf(err, "Dialing '%s' through jump host", address)
+	}
+
+	return conn, nil
+}
+
+func (d *jumpHostDialer) connect() (*ssh.Client, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	config, err := newClientConfig(d.options.User, d.options.PrivateKey, d.options.PrivateKeyPassphrase, d.options.Password, d.logger, d.logTag)
+	if err != nil {
+		return nil, err
+	}
+
+	config.HostKeyCallback, err = newHostKeyCallback(d.options.HostKey, d.hostKeyStore, d.logger, d.logTag)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Configuring jump host key verification")
+	}
+
+	jumpHostAddr := fmt.Sprintf("%s:%d", d.options.Host, d.options.Port)
+	d.logger.Debug(d.logTag, "Connecting to jump host at %s", jumpHostAddr)
+
+	d.client, err = dialSSH(d.forward, jumpHostAddr, config)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Connecting to jump host '%s'", jumpHostAddr)
+	}
+
+	return d.client, nil
+}
+
+// disconnect drops a broken jump host connection so that the next Dial reconnects
+func (d *jumpHostDialer) disconnect(client *ssh.Client) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.client == client {
+		client.Close()
+		d.client = nil
+	}
+}
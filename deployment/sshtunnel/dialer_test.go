@@ -0,0 +1,271 @@
+package sshtunnel
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"net"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/crypto/ssh"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("NewDialer", func() {
+	var (
+		logger boshlog.Logger
+		store  *fakeHostKeyStore
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		store = &fakeHostKeyStore{fingerprints: map[string]string{}}
+	})
+
+	It("returns a direct dialer when no proxy is configured", func() {
+		dialer, err := NewDialer(ProxyOptions{}, store, logger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dialer).To(BeAssignableToTypeOf(&net.Dialer{}))
+	})
+
+	It("returns a jump host dialer when a jump host is configured", func() {
+		dialer, err := NewDialer(ProxyOptions{
+			JumpHost: JumpHostOptions{Host: "fake-jump-host", Port: 22, User: "fake-user"},
+		}, store, logger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dialer).To(BeAssignableToTypeOf(&jumpHostDialer{}))
+	})
+
+	Describe("with a jump host", func() {
+		var (
+			listener net.Listener
+			jumpHost JumpHostOptions
+		)
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			go serveFakeSSH(listener)
+
+			host, port, err := net.SplitHostPort(listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+			portNum, err := strconv.Atoi(port)
+			Expect(err).ToNot(HaveOccurred())
+
+			jumpHost = JumpHostOptions{Host: host, Port: portNum, User: "fake-user"}
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		It("verifies the jump host key", func() {
+			jumpHost.HostKey = HostKeyOptions{HostKey: fakeHostKey}
+
+			dialer, err := NewDialer(ProxyOptions{JumpHost: jumpHost}, store, logger)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = dialer.Dial("tcp", "10.0.0.6:6868")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Host key verification failed"))
+		})
+
+		It("records the jump host key when trusting on first use", func() {
+			jumpHost.HostKey = HostKeyOptions{TrustOnFirstUse: true}
+
+			dialer, err := NewDialer(ProxyOptions{JumpHost: jumpHost}, store, logger)
+			Expect(err).ToNot(HaveOccurred())
+
+			dialer.Dial("tcp", "10.0.0.6:6868")
+			Expect(store.fingerprints).To(HaveLen(1))
+		})
+	})
+
+	It("returns an error when the proxy URL scheme is not socks5", func() {
+		_, err := NewDialer(ProxyOptions{URL: "http://fake-proxy:3128"}, store, logger)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unsupported proxy scheme 'http'"))
+	})
+
+	Describe("with a socks5 proxy", func() {
+		var (
+			proxy       *fakeSOCKS5Proxy
+			proxyAddr   string
+			listener    net.Listener
+			requireAuth bool
+		)
+
+		BeforeEach(func() {
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			proxyAddr = listener.Addr().String()
+			requireAuth = false
+		})
+
+		JustBeforeEach(func() {
+			proxy = &fakeSOCKS5Proxy{listener: listener, requireAuth: requireAuth, requests: make(chan []byte, 1)}
+			go proxy.serve()
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		It("connects to the address through the proxy", func() {
+			dialer, err := NewDialer(ProxyOptions{URL: "socks5://" + proxyAddr}, store, logger)
+			Expect(err).ToNot(HaveOccurred())
+
+			conn, err := dialer.Dial("tcp", "fake-agent-host:6868")
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			Expect(<-proxy.requests).To(Equal(append(
+				[]byte{5, 1, 0, 3, byte(len("fake-agent-host"))},
+				append([]byte("fake-agent-host"), 0x1a, 0xd4)...,
+			)))
+
+			_, err = conn.Write([]byte("ping"))
+			Expect(err).ToNot(HaveOccurred())
+
+			echo := make([]byte, 4)
+			_, err = io.ReadFull(conn, echo)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(echo)).To(Equal("ping"))
+		})
+
+		Context("when the proxy requires a username and password", func() {
+			BeforeEach(func() {
+				requireAuth = true
+			})
+
+			It("authenticates with the credentials from the URL", func() {
+				dialer, err := NewDialer(ProxyOptions{URL: "socks5://fake-user:fake-password@" + proxyAddr}, store, logger)
+				Expect(err).ToNot(HaveOccurred())
+
+				conn, err := dialer.Dial("tcp", "10.0.0.6:6868")
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+
+				Expect(<-proxy.requests).To(Equal([]byte{5, 1, 0, 1, 10, 0, 0, 6, 0x1a, 0xd4}))
+			})
+
+			It("returns an error when the credentials are rejected", func() {
+				dialer, err := NewDialer(ProxyOptions{URL: "socks5://fake-user:wrong-password@" + proxyAddr}, store, logger)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = dialer.Dial("tcp", "10.0.0.6:6868")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("username/password authentication failed"))
+			})
+
+			It("returns an error when no credentials are given", func() {
+				dialer, err := NewDialer(ProxyOptions{URL: "socks5://" + proxyAddr}, store, logger)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = dialer.Dial("tcp", "10.0.0.6:6868")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("no acceptable authentication methods"))
+			})
+		})
+	})
+})
+
+// fakeSOCKS5Proxy accepts a single connection, records its connect request and echoes what it receives
+type fakeSOCKS5Proxy struct {
+	listener    net.Listener
+	requireAuth bool
+	requests    chan []byte
+}
+
+func (p *fakeSOCKS5Proxy) serve() {
+	defer GinkgoRecover()
+
+	conn, err := p.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	greeting := make([]byte, 2)
+	_, err = io.ReadFull(conn, greeting)
+	Expect(err).ToNot(HaveOccurred())
+	methods := make([]byte, greeting[1])
+	_, err = io.ReadFull(conn, methods)
+	Expect(err).ToNot(HaveOccurred())
+
+	if p.requireAuth {
+		if len(methods) < 2 || methods[1] != 2 {
+			conn.Write([]byte{5, 0xff})
+			return
+		}
+		conn.Write([]byte{5, 2})
+
+		header := make([]byte, 2)
+		io.ReadFull(conn, header)
+		username := make([]byte, header[1])
+		io.ReadFull(conn, username)
+		passwordLen := make([]byte, 1)
+		io.ReadFull(conn, passwordLen)
+		password := make([]byte, passwordLen[0])
+		io.ReadFull(conn, password)
+
+		if string(username) != "fake-user" || string(password) != "fake-password" {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	} else {
+		conn.Write([]byte{5, 0})
+	}
+
+	request := make([]byte, 4)
+	io.ReadFull(conn, request)
+	var addr []byte
+	switch request[3] {
+	case 1:
+		addr = make([]byte, 4+2)
+	case 3:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		request = append(request, length[0])
+		addr = make([]byte, int(length[0])+2)
+	}
+	io.ReadFull(conn, addr)
+	p.requests <- append(request, addr...)
+
+	conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+
+	io.Copy(conn, conn)
+}
+
+// serveFakeSSH completes the SSH handshake of every connection with a generated host key, accepting any client
+func serveFakeSSH(listener net.Listener) {
+	defer GinkgoRecover()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	signer, err := ssh.NewSignerFromKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			ssh.NewServerConn(conn, config)
+		}()
+	}
+}
@@ -0,0 +1,221 @@
+package sshtunnel
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const hostKeyVerificationFailed = "Host key verification failed"
+
+// HostKeyOptions select how the SSH server's host key is verified.
+// HostKey is a public key in authorized_keys format.
+// KnownHostsPath is an OpenSSH known_hosts file.
+// TrustOnFirstUse accepts the first key seen for a host and rejects later changes.
+// HostRecreated tells trust on first use that bosh-init just created the host,
+// so the key recorded for its address belonged to the VM it replaced.
+// SkipVerification accepts any host key; one of the options must be set.
+type HostKeyOptions struct {
+	HostKey          string
+	KnownHostsPath   string
+	TrustOnFirstUse  bool
+	HostRecreated    bool
+	SkipVerification bool
+}
+
+// HostKeyStore records host key fingerprints trusted on first use
+type HostKeyStore interface {
+	Find(host string) (fingerprint string, found bool, err error)
+	Save(host, fingerprint string) error
+}
+
+type hostKeyCallback func(hostname string, remote net.Addr, key ssh.PublicKey) error
+
+// Fingerprint returns the OpenSSH SHA256 fingerprint of the key
+func Fingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + strings.TrimRight(base64.StdEncoding.EncodeToString(sum[:]), "=")
+}
+
+func newHostKeyCallback(options HostKeyOptions, store HostKeyStore, logger boshlog.Logger, logTag string) (hostKeyCallback, error) {
+	switch {
+	case options.HostKey != "":
+		expectedKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(options.HostKey))
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Parsing host key")
+		}
+
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if !bytes.Equal(key.Marshal(), expectedKey.Marshal()) {
+				return bosherr.Errorf("%s for '%s': presented key %s does not match the configured host key %s",
+					hostKeyVerificationFailed, hostname, Fingerprint(key), Fingerprint(expectedKey))
+			}
+			return nil
+		}, nil
+
+	case options.KnownHostsPath != "":
+		contents, err := ioutil.ReadFile(options.KnownHostsPath)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading known_hosts file '%s'", options.KnownHostsPath)
+		}
+
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			knownKeys, revoked := findKnownHostKeys(contents, hostname)
+
+			for _, revokedKey := range revoked {
+				if bytes.Equal(key.Marshal(), revokedKey.Marshal()) {
+					return bosherr.Errorf("%s for '%s': presented key %s is revoked in '%s'",
+						hostKeyVerificationFailed, hostname, Fingerprint(key), options.KnownHostsPath)
+				}
+			}
+
+			if len(knownKeys) == 0 {
+				return bosherr.Errorf("%s for '%s': host is not in '%s' (presented key %s)",
+					hostKeyVerificationFailed, hostname, options.KnownHostsPath, Fingerprint(key))
+			}
+
+			for _, knownKey := range knownKeys {
+				if bytes.Equal(key.Marshal(), knownKey.Marshal()) {
+					return nil
+				}
+			}
+
+			return bosherr.Errorf("%s for '%s': presented key %s does not match the keys in '%s'",
+				hostKeyVerificationFailed, hostname, Fingerprint(key), options.KnownHostsPath)
+		}, nil
+
+	case options.TrustOnFirstUse:
+		replaceTrusted := options.HostRecreated
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint := Fingerprint(key)
+
+			trustedFingerprint, found, err := store.Find(hostname)
+			if err != nil {
+				return bosherr.WrapError(err, "Finding trusted host key")
+			}
+
+			if replaceTrusted {
+				replaceTrusted = false
+				if found && trustedFingerprint != fingerprint {
+					logger.Info(logTag, "Replacing host key %s for recreated host '%s' with %s", trustedFingerprint, hostname, fingerprint)
+					found = false
+				}
+			}
+
+			if !found {
+				logger.Info(logTag, "Trusting host key %s for '%s' on first use", fingerprint, hostname)
+				err = store.Save(hostname, fingerprint)
+				if err != nil {
+					return bosherr.WrapError(err, "Saving trusted host key")
+				}
+				return nil
+			}
+
+			if trustedFingerprint != fingerprint {
+				return bosherr.Errorf("%s for '%s': host key changed from %s to %s since it was first trusted. Someone could be eavesdropping on the connection",
+					hostKeyVerificationFailed, hostname, trustedFingerprint, fingerprint)
+			}
+
+			return nil
+		}, nil
+
+	case options.SkipVerification:
+		logger.Warn(logTag, "Host key verification is disabled by skip_host_key_verification")
+		return nil, nil
+	}
+
+	return nil, bosherr.Error("Host key verification is not configured, set one of host_key, known_hosts, trust_on_first_use or skip_host_key_verification")
+}
+
+// findKnownHostKeys returns the keys listed in known_hosts contents for hostname (host:port),
+// along with the keys marked @revoked for it
+func findKnownHostKeys(contents []byte, hostname string) (keys []ssh.PublicKey, revoked []ssh.PublicKey) {
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		host, port = hostname, "22"
+	}
+
+	pattern := host
+	if port != "22" {
+		pattern = "[" + host + "]:" + port
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		marker := ""
+		if strings.HasPrefix(fields[0], "@") {
+			marker = fields[0]
+			fields = fields[1:]
+		}
+
+		if len(fields) < 3 || marker == "@cert-authority" {
+			continue
+		}
+
+		if !knownHostsEntryMatches(fields[0], pattern) {
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[1:], " ")))
+		if err != nil {
+			continue
+		}
+
+		if marker == "@revoked" {
+			revoked = append(revoked, key)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, revoked
+}
+
+func knownHostsEntryMatches(hosts string, pattern string) bool {
+	if strings.HasPrefix(hosts, "|1|") {
+		parts := strings.Split(hosts[len("|1|"):], "|")
+		if len(parts) != 2 {
+			return false
+		}
+
+		salt, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return false
+		}
+
+		hash, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(pattern))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+
+	for _, host := range strings.Split(hosts, ",") {
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
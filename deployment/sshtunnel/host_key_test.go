@@ -0,0 +1,228 @@
+package sshtunnel
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/crypto/ssh"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	fakeHostKey       = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg= fake-host-1"
+	fakeOtherHostKey  = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBMybfmaGpvJjAowSpLRYqwXrCJTidKjnW/I5ARZRYdR13FFpNeFZ49Zf7XZSI6YmO6Qyu16Sy85gV3OzVEz+SFs= fake-host-2"
+	fakeHostKeySHA256 = "SHA256:A4I+aUeYoStcXmn8T/45Nr5nu9Mj5/ui/ngZthE+Yck"
+)
+
+var _ = Describe("newHostKeyCallback", func() {
+	var (
+		logger       boshlog.Logger
+		hostKey      ssh.PublicKey
+		otherHostKey ssh.PublicKey
+		store        *fakeHostKeyStore
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		store = &fakeHostKeyStore{fingerprints: map[string]string{}}
+
+		var err error
+		hostKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(fakeHostKey))
+		Expect(err).ToNot(HaveOccurred())
+		otherHostKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(fakeOtherHostKey))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("computes OpenSSH SHA256 fingerprints", func() {
+		Expect(Fingerprint(hostKey)).To(Equal(fakeHostKeySHA256))
+	})
+
+	It("returns an error when no verification is configured", func() {
+		_, err := newHostKeyCallback(HostKeyOptions{}, store, logger, "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Host key verification is not configured"))
+	})
+
+	It("accepts any key when verification is skipped", func() {
+		callback, err := newHostKeyCallback(HostKeyOptions{SkipVerification: true}, store, logger, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(callback).To(BeNil())
+	})
+
+	Context("with a host key", func() {
+		var callback hostKeyCallback
+
+		BeforeEach(func() {
+			var err error
+			callback, err = newHostKeyCallback(HostKeyOptions{HostKey: fakeHostKey}, store, logger, "")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts the configured key", func() {
+			Expect(callback("10.0.0.6:22", nil, hostKey)).To(Succeed())
+		})
+
+		It("rejects any other key", func() {
+			err := callback("10.0.0.6:22", nil, otherHostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Host key verification failed for '10.0.0.6:22'"))
+			Expect(err.Error()).To(ContainSubstring("does not match the configured host key " + fakeHostKeySHA256))
+		})
+
+		It("returns an error when the host key cannot be parsed", func() {
+			_, err := newHostKeyCallback(HostKeyOptions{HostKey: "not-a-key"}, store, logger, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing host key"))
+		})
+	})
+
+	Context("with a known_hosts file", func() {
+		var knownHostsPath string
+
+		writeKnownHosts := func(contents string) hostKeyCallback {
+			err := ioutil.WriteFile(knownHostsPath, []byte(contents), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			callback, err := newHostKeyCallback(HostKeyOptions{KnownHostsPath: knownHostsPath}, store, logger, "")
+			Expect(err).ToNot(HaveOccurred())
+			return callback
+		}
+
+		BeforeEach(func() {
+			file, err := ioutil.TempFile("", "known_hosts")
+			Expect(err).ToNot(HaveOccurred())
+			file.Close()
+			knownHostsPath = file.Name()
+		})
+
+		AfterEach(func() {
+			os.Remove(knownHostsPath)
+		})
+
+		It("accepts a key listed for the host", func() {
+			callback := writeKnownHosts("# comment\nother-host ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBMybfmaGpvJjAowSpLRYqwXrCJTidKjnW/I5ARZRYdR13FFpNeFZ49Zf7XZSI6YmO6Qyu16Sy85gV3OzVEz+SFs=\n10.0.0.6,director ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n")
+			Expect(callback("10.0.0.6:22", nil, hostKey)).To(Succeed())
+		})
+
+		It("matches non-standard ports in brackets", func() {
+			callback := writeKnownHosts("[10.0.0.6]:2222 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n10.0.0.6 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBMybfmaGpvJjAowSpLRYqwXrCJTidKjnW/I5ARZRYdR13FFpNeFZ49Zf7XZSI6YmO6Qyu16Sy85gV3OzVEz+SFs=\n")
+			Expect(callback("10.0.0.6:2222", nil, hostKey)).To(Succeed())
+			Expect(callback("10.0.0.6:2222", nil, otherHostKey)).ToNot(Succeed())
+		})
+
+		It("matches hashed host names", func() {
+			callback := writeKnownHosts("|1|qA3lMWSNE5toPXSsyhdg6F/GoR8=|lvntIBDZl49GWo4TtFrHJpCzqhs= ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n")
+			Expect(callback("10.0.0.6:22", nil, hostKey)).To(Succeed())
+		})
+
+		It("rejects an unknown host", func() {
+			callback := writeKnownHosts("other-host ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n")
+
+			err := callback("10.0.0.6:22", nil, hostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("host is not in '" + knownHostsPath + "'"))
+		})
+
+		It("rejects a mismatched key", func() {
+			callback := writeKnownHosts("10.0.0.6 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBMybfmaGpvJjAowSpLRYqwXrCJTidKjnW/I5ARZRYdR13FFpNeFZ49Zf7XZSI6YmO6Qyu16Sy85gV3OzVEz+SFs=\n")
+
+			err := callback("10.0.0.6:22", nil, hostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not match the keys in '" + knownHostsPath + "'"))
+		})
+
+		It("rejects a revoked key", func() {
+			callback := writeKnownHosts("@revoked * ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n10.0.0.6 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n@revoked 10.0.0.6 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBJ40eSM2O0yjOaT7JVerKAAMa4PsofFEG2oVQc6IXNl5E5AbcqHyLEqYBDPT2DvUlINOTrM9QEbvokXBolWCajg=\n")
+
+			err := callback("10.0.0.6:22", nil, hostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is revoked"))
+		})
+
+		It("returns an error when the file cannot be read", func() {
+			_, err := newHostKeyCallback(HostKeyOptions{KnownHostsPath: "/nonexistent/known_hosts"}, store, logger, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading known_hosts file '/nonexistent/known_hosts'"))
+		})
+	})
+
+	Context("with trust on first use", func() {
+		var callback hostKeyCallback
+
+		BeforeEach(func() {
+			var err error
+			callback, err = newHostKeyCallback(HostKeyOptions{TrustOnFirstUse: true}, store, logger, "")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("records the fingerprint of the first key", func() {
+			Expect(callback("10.0.0.6:22", nil, hostKey)).To(Succeed())
+			Expect(store.fingerprints).To(Equal(map[string]string{"10.0.0.6:22": fakeHostKeySHA256}))
+		})
+
+		It("accepts the recorded key", func() {
+			store.fingerprints["10.0.0.6:22"] = fakeHostKeySHA256
+			Expect(callback("10.0.0.6:22", nil, hostKey)).To(Succeed())
+		})
+
+		It("rejects a key that changed", func() {
+			store.fingerprints["10.0.0.6:22"] = fakeHostKeySHA256
+
+			err := callback("10.0.0.6:22", nil, otherHostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Host key verification failed for '10.0.0.6:22': host key changed from " + fakeHostKeySHA256))
+			Expect(store.fingerprints["10.0.0.6:22"]).To(Equal(fakeHostKeySHA256))
+		})
+
+		It("returns an error when the store fails", func() {
+			store.findErr = errors.New("fake-find-error")
+
+			err := callback("10.0.0.6:22", nil, hostKey)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-find-error"))
+		})
+
+		Context("when the host was just recreated", func() {
+			BeforeEach(func() {
+				var err error
+				callback, err = newHostKeyCallback(HostKeyOptions{TrustOnFirstUse: true, HostRecreated: true}, store, logger, "")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("replaces the key recorded for the previous vm on the first connection", func() {
+				store.fingerprints["10.0.0.6:22"] = fakeHostKeySHA256
+
+				Expect(callback("10.0.0.6:22", nil, otherHostKey)).To(Succeed())
+				Expect(store.fingerprints["10.0.0.6:22"]).To(Equal(Fingerprint(otherHostKey)))
+			})
+
+			It("rejects a key that changed on later connections", func() {
+				Expect(callback("10.0.0.6:22", nil, otherHostKey)).To(Succeed())
+
+				err := callback("10.0.0.6:22", nil, hostKey)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("host key changed"))
+			})
+		})
+	})
+})
+
+type fakeHostKeyStore struct {
+	fingerprints map[string]string
+	findErr      error
+}
+
+func (s *fakeHostKeyStore) Find(host string) (string, bool, error) {
+	fingerprint, found := s.fingerprints[host]
+	return fingerprint, found, s.findErr
+}
+
+func (s *fakeHostKeyStore) Save(host, fingerprint string) error {
+	s.fingerprints[host] = fingerprint
+	return nil
+}
@@ -3,7 +3,6 @@ package sshtunnel
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"strings"
 	"time"
@@ -24,54 +23,34 @@ type sshTunnel struct {
 	authFailureTimeout       time.Duration
 	timeService              clock.Clock
 	startDialDelay           time.Duration
+	keepAliveInterval        time.Duration
+	reconnectTimeout         time.Duration
+	reconnectMaxDelay        time.Duration
 	options                  Options
-	remoteListener           net.Listener
+	hostKeyStore             HostKeyStore
 	logger                   boshlog.Logger
 	logTag                   string
 }
 
+// Start connects to the remote server and forwards connections on the remote forward port to the local forward port.
+// The SSH connection is kept alive and, when it drops, re-established with backoff until reconnectTimeout passes.
 func (s *sshTunnel) Start(readyErrCh chan<- error, errCh chan<- error) {
-	authMethods := []ssh.AuthMethod{}
-
-	if s.options.PrivateKey != "" {
-		s.logger.Debug(s.logTag, "Reading private key file '%s'", s.options.PrivateKey)
-		keyContents, err := ioutil.ReadFile(s.options.PrivateKey)
-		if err != nil {
-			readyErrCh <- bosherr.WrapErrorf(err, "Reading private key file '%s'", s.options.PrivateKey)
-			return
-		}
-
-		s.logger.Debug(s.logTag, "Parsing private key file '%s'", s.options.PrivateKey)
-		signer, err := ssh.ParsePrivateKey(keyContents)
-		if err != nil {
-			readyErrCh <- bosherr.WrapErrorf(err, "Parsing private key file '%s'", s.options.PrivateKey)
-			return
-		}
-
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	sshConfig, err := newClientConfig(s.options.User, s.options.PrivateKey, s.options.PrivateKeyPassphrase, s.options.Password, s.logger, s.logTag)
+	if err != nil {
+		readyErrCh <- err
+		return
 	}
 
-	if s.options.Password != "" {
-		s.logger.Debug(s.logTag, "Adding password auth method to ssh tunnel config")
-
-		keyboardInteractiveChallenge := func(
-			user,
-			instruction string,
-			questions []string,
-			echos []bool,
-		) (answers []string, err error) {
-			if len(questions) == 0 {
-				return []string{}, nil
-			}
-			return []string{s.options.Password}, nil
-		}
-		authMethods = append(authMethods, ssh.KeyboardInteractive(keyboardInteractiveChallenge))
-		authMethods = append(authMethods, ssh.Password(s.options.Password))
+	sshConfig.HostKeyCallback, err = newHostKeyCallback(s.options.HostKey, s.hostKeyStore, s.logger, s.logTag)
+	if err != nil {
+		readyErrCh <- bosherr.WrapError(err, "Configuring host key verification")
+		return
 	}
 
-	sshConfig := &ssh.ClientConfig{
-		User: s.options.User,
-		Auth: authMethods,
+	dialer, err := NewDialer(s.options.Proxy, s.hostKeyStore, s.logger)
+	if err != nil {
+		readyErrCh <- bosherr.WrapError(err, "Creating proxy dialer")
+		return
 	}
 
 	s.logger.Debug(s.logTag, "Dialing remote server at %s:%d", s.options.Host, s.options.Port)
@@ -84,10 +63,9 @@ func (s *sshTunnel) Start(readyErrCh chan<- error, errCh chan<- error) {
 	}
 
 	var conn *ssh.Client
-	var err error
 	for i := 0; ; i++ {
 		s.logger.Debug(s.logTag, "Making attempt #%d", i)
-		conn, err = ssh.Dial("tcp", remoteAddr, sshConfig)
+		conn, err = dialSSH(dialer, remoteAddr, sshConfig)
 
 		if err == nil {
 			break
@@ -100,64 +78,204 @@ func (s *sshTunnel) Start(readyErrCh chan<- error, errCh chan<- error) {
 
 		s.logger.Debug(s.logTag, "Attempt failed #%d: Dialing remote server: %s", i, err.Error())
 
-		time.Sleep(s.startDialDelay)
+		s.timeService.Sleep(s.startDialDelay)
 	}
 
-	remoteListenAddr := fmt.Sprintf("127.0.0.1:%d", s.options.RemoteForwardPort)
-	s.logger.Debug(s.logTag, "Listening on remote server %s", remoteListenAddr)
-	s.remoteListener, err = conn.Listen("tcp", remoteListenAddr)
+	remoteListener, err := s.listen(conn)
 	if err != nil {
-		readyErrCh <- bosherr.WrapError(err, "Listening on remote server")
+		conn.Close()
+		readyErrCh <- err
 		return
 	}
 
 	readyErrCh <- nil
+
 	for {
-		remoteConn, err := s.remoteListener.Accept()
-		s.logger.Debug(s.logTag, "Received connection")
+		stopKeepAlive := make(chan struct{})
+		go keepAlive(conn, s.keepAliveInterval, s.timeService, stopKeepAlive, s.logger, s.logTag)
+
+		s.forward(remoteListener, errCh)
+
+		close(stopKeepAlive)
+		conn.Close()
+
+		s.logger.Warn(s.logTag, "Lost connection to remote server %s, reconnecting", remoteAddr)
+		conn, remoteListener, err = s.reconnect(dialer, remoteAddr, sshConfig)
 		if err != nil {
-			errCh <- bosherr.WrapError(err, "Accepting connection on remote server")
+			s.reportError(errCh, bosherr.WrapError(err, "Reconnecting to remote server"))
+			return
 		}
-		defer func() {
-			if err = remoteConn.Close(); err != nil {
-				s.logger.Warn(s.logTag, "Failed to close remote listener connection: %s", err.Error())
+	}
+}
+
+func (s *sshTunnel) listen(conn *ssh.Client) (net.Listener, error) {
+	remoteListenAddr := fmt.Sprintf("127.0.0.1:%d", s.options.RemoteForwardPort)
+	s.logger.Debug(s.logTag, "Listening on remote server %s", remoteListenAddr)
+
+	remoteListener, err := conn.Listen("tcp", remoteListenAddr)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listening on remote server")
+	}
+
+	return remoteListener, nil
+}
+
+// reconnect re-dials the remote server and listens again, doubling the delay between attempts up to reconnectMaxDelay
+func (s *sshTunnel) reconnect(dialer Dialer, remoteAddr string, sshConfig *ssh.ClientConfig) (*ssh.Client, net.Listener, error) {
+	deadline := s.timeService.Now().Add(s.reconnectTimeout)
+	delay := s.startDialDelay
+
+	for i := 1; ; i++ {
+		conn, err := dialSSH(dialer, remoteAddr, sshConfig)
+		if err == nil {
+			var remoteListener net.Listener
+			remoteListener, err = s.listen(conn)
+			if err == nil {
+				s.logger.Info(s.logTag, "Reconnected to remote server %s after %d attempt(s)", remoteAddr, i)
+				return conn, remoteListener, nil
 			}
-		}()
+			conn.Close()
+		}
+
+		if strings.Contains(err.Error(), hostKeyVerificationFailed) || !s.timeService.Now().Before(deadline) {
+			return nil, nil, err
+		}
+
+		s.logger.Debug(s.logTag, "Reconnect attempt #%d failed, retrying in %s: %s", i, delay, err.Error())
+		s.timeService.Sleep(delay)
+
+		delay *= 2
+		if delay > s.reconnectMaxDelay {
+			delay = s.reconnectMaxDelay
+		}
+	}
+}
+
+// forward accepts connections until the remote listener fails, which happens when the SSH connection drops
+func (s *sshTunnel) forward(remoteListener net.Listener, errCh chan<- error) {
+	for {
+		remoteConn, err := remoteListener.Accept()
+		if err != nil {
+			s.logger.Debug(s.logTag, "Accepting connection on remote server: %s", err.Error())
+			return
+		}
 
-		s.logger.Debug(s.logTag, "Dialing local server")
-		localDialAddr := fmt.Sprintf("127.0.0.1:%d", s.options.LocalForwardPort)
-		localConn, err := net.Dial("tcp", localDialAddr)
+		s.logger.Debug(s.logTag, "Received connection")
+		go s.handle(remoteConn, errCh)
+	}
+}
+
+func (s *sshTunnel) handle(remoteConn net.Conn, errCh chan<- error) {
+	defer func() {
+		if err := remoteConn.Close(); err != nil {
+			s.logger.Debug(s.logTag, "Failed to close remote listener connection: %s", err.Error())
+		}
+	}()
+
+	s.logger.Debug(s.logTag, "Dialing local server")
+	localDialAddr := fmt.Sprintf("127.0.0.1:%d", s.options.LocalForwardPort)
+	localConn, err := net.Dial("tcp", localDialAddr)
+	if err != nil {
+		s.reportError(errCh, bosherr.WrapError(err, "Dialing local server"))
+		return
+	}
+
+	defer func() {
+		if err := localConn.Close(); err != nil {
+			s.logger.Debug(s.logTag, "Failed to close local dial connection: %s", err.Error())
+		}
+	}()
+
+	// Once either direction finishes both connections are closed, so only the first copy error is reported
+	copyErrCh := make(chan error, 2)
+
+	go func() {
+		bytesNum, err := io.Copy(remoteConn, localConn)
+		s.logger.Debug(s.logTag, "Copying bytes from local to remote %d", bytesNum)
+		if err != nil {
+			err = bosherr.WrapError(err, "Copying bytes from local to remote")
+		}
+		copyErrCh <- err
+	}()
+
+	go func() {
+		bytesNum, err := io.Copy(localConn, remoteConn)
+		s.logger.Debug(s.logTag, "Copying bytes from remote to local %d", bytesNum)
 		if err != nil {
-			errCh <- bosherr.WrapError(err, "Dialing local server")
+			err = bosherr.WrapError(err, "Copying bytes from remote to local")
+		}
+		copyErrCh <- err
+	}()
+
+	if err := <-copyErrCh; err != nil {
+		s.reportError(errCh, err)
+	}
+}
+
+// reportError sends err to errCh, waiting for the receiver so that no error is lost
+func (s *sshTunnel) reportError(errCh chan<- error, err error) {
+	s.logger.Debug(s.logTag, "Reporting tunnel error: %s", err.Error())
+	errCh <- err
+}
+
+type keepAliveClient interface {
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+// keepAlive sends an OpenSSH keepalive request every interval until stop is closed.
+// When a request fails or is not answered within the interval the connection is closed.
+func keepAlive(client keepAliveClient, interval time.Duration, timeService clock.Clock, stop <-chan struct{}, logger boshlog.Logger, logTag string) {
+	ticker := timeService.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
 			return
+		case <-ticker.C():
 		}
 
+		replyTimer := timeService.NewTimer(interval)
+		replyErrCh := make(chan error, 1)
 		go func() {
-			bytesNum, err := io.Copy(remoteConn, localConn)
-			defer func() {
-				if err = localConn.Close(); err != nil {
-					s.logger.Warn(s.logTag, "Failed to close local dial connection: %s", err.Error())
-				}
-			}()
-			s.logger.Debug(s.logTag, "Copying bytes from local to remote %d", bytesNum)
-			if err != nil {
-				errCh <- bosherr.WrapError(err, "Copying bytes from local to remote")
-			}
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			replyErrCh <- err
 		}()
 
-		go func() {
-			bytesNum, err := io.Copy(localConn, remoteConn)
-			defer func() {
-				if err = localConn.Close(); err != nil {
-					s.logger.Warn(s.logTag, "Failed to close local dial connection: %s", err.Error())
-				}
-			}()
-			s.logger.Debug(s.logTag, "Copying bytes from remote to local %d", bytesNum)
-			if err != nil {
-				errCh <- bosherr.WrapError(err, "Copying bytes from remote to local")
+		select {
+		case <-stop:
+			replyTimer.Stop()
+			return
+		case err := <-replyErrCh:
+			replyTimer.Stop()
+			if err == nil {
+				continue
 			}
-		}()
+			logger.Warn(logTag, "Sending keepalive: %s", err.Error())
+		case <-replyTimer.C():
+			logger.Warn(logTag, "Keepalive was not answered within %s", interval)
+		}
+
+		client.Close()
+		return
+	}
+}
+
+// dialSSH opens an SSH connection over a connection made by dialer
+func dialSSH(dialer Dialer, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
+
+	return ssh.NewClient(clientConn, chans, reqs), nil
 }
 
 type SSHRetryStrategy struct {
@@ -182,6 +300,10 @@ func (s *SSHRetryStrategy) IsRetryable(err error) bool {
 		return false
 	}
 
+	if strings.Contains(err.Error(), hostKeyVerificationFailed) {
+		return false
+	}
+
 	if strings.Contains(err.Error(), "unable to authenticate") {
 		return now.Before(s.authStartTime.Add(s.AuthFailureTimeout))
 	}
@@ -8,14 +8,18 @@ import (
 )
 
 type Options struct {
-	Host       string
-	Port       int
-	User       string
-	PrivateKey string
-	Password   string
+	Host                 string
+	Port                 int
+	User                 string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	Password             string
 
 	LocalForwardPort  int
 	RemoteForwardPort int
+
+	HostKey HostKeyOptions
+	Proxy   ProxyOptions
 }
 
 func (o Options) IsEmpty() bool {
@@ -27,12 +31,14 @@ type Factory interface {
 }
 
 type factory struct {
-	logger boshlog.Logger
+	hostKeyStore HostKeyStore
+	logger       boshlog.Logger
 }
 
-func NewFactory(logger boshlog.Logger) Factory {
+func NewFactory(hostKeyStore HostKeyStore, logger boshlog.Logger) Factory {
 	return &factory{
-		logger: logger,
+		hostKeyStore: hostKeyStore,
+		logger:       logger,
 	}
 }
 
@@ -42,8 +48,12 @@ func (s *factory) NewSSHTunnel(options Options) SSHTunnel {
 		connectionRefusedTimeout: 5 * time.Minute,
 		authFailureTimeout:       2 * time.Minute,
 		startDialDelay:           500 * time.Millisecond,
+		keepAliveInterval:        30 * time.Second,
+		reconnectTimeout:         5 * time.Minute,
+		reconnectMaxDelay:        30 * time.Second,
 		timeService:              timeService,
 		options:                  options,
+		hostKeyStore:             s.hostKeyStore,
 		logger:                   s.logger,
 		logTag:                   "sshTunnel",
 	}
@@ -79,6 +79,10 @@ var _ = Describe("SSH", func() {
 				Expect(sshRetryStrategy.IsRetryable(errors.New("no common algorithms"))).To(BeFalse())
 			})
 
+			It("host key verification errors fail immediately", func() {
+				Expect(sshRetryStrategy.IsRetryable(errors.New("ssh: handshake failed: Host key verification failed for '10.0.0.6:22'"))).To(BeFalse())
+			})
+
 			It("all other errors fail after the connection refused timeout", func() {
 				Expect(sshRetryStrategy.IsRetryable(errors.New("another error"))).To(BeTrue())
 
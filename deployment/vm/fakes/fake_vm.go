@@ -3,8 +3,9 @@ package fakes
 import (
 	"time"
 
-	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	boshagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	biui "github.com/cloudfoundry/bosh-init/ui"
@@ -26,6 +27,9 @@ type FakeVM struct {
 	ApplyInputs []ApplyInput
 	ApplyErr    error
 
+	ApplyErrandInputs []ApplyErrandInput
+	ApplyErrandErr    error
+
 	StartCalled int
 	StartErr    error
 
@@ -59,11 +63,32 @@ type FakeVM struct {
 	RunScriptInputs []string
 	RunScriptErrors map[string]error
 
-	GetStateResult biagentclient.AgentState
+	RunErrandCalled int
+	RunErrandResult biagentclient.ErrandResult
+	RunErrandErr    error
+
+	FetchLogsInputs      [][]string
+	FetchLogsBlobstoreID string
+	FetchLogsErr         error
+
+	DrainInputs     []DrainInput
+	DrainUnfinished bool
+	DrainErr        error
+
+	CurrentApplySpecResult bias.ApplySpec
+	CurrentApplySpecErr    error
+
+	GetStateResult boshagentclient.AgentState
 	GetStateCalled int
 	GetStateErr    error
 }
 
+type DrainInput struct {
+	DrainType string
+	NewState  *bias.ApplySpec
+	Timeout   time.Duration
+}
+
 type UpdateDisksInput struct {
 	DiskPool bideplmanifest.DiskPool
 	Stage    biui.Stage
@@ -73,6 +98,11 @@ type ApplyInput struct {
 	ApplySpec bias.ApplySpec
 }
 
+type ApplyErrandInput struct {
+	ApplySpec     bias.ApplySpec
+	ErrandJobName string
+}
+
 type WaitUntilReadyInput struct {
 	Timeout time.Duration
 	Delay   time.Duration
@@ -148,6 +178,15 @@ func (vm *FakeVM) Apply(applySpec bias.ApplySpec) error {
 	return vm.ApplyErr
 }
 
+func (vm *FakeVM) ApplyErrand(applySpec bias.ApplySpec, errandJobName string) error {
+	vm.ApplyErrandInputs = append(vm.ApplyErrandInputs, ApplyErrandInput{
+		ApplySpec:     applySpec,
+		ErrandJobName: errandJobName,
+	})
+
+	return vm.ApplyErrandErr
+}
+
 func (vm *FakeVM) Start() error {
 	vm.StartCalled++
 	return vm.StartErr
@@ -196,6 +235,20 @@ func (vm *FakeVM) Stop() error {
 	return vm.StopErr
 }
 
+func (vm *FakeVM) Drain(drainType string, newState *bias.ApplySpec, timeout time.Duration) (bool, error) {
+	vm.DrainInputs = append(vm.DrainInputs, DrainInput{
+		DrainType: drainType,
+		NewState:  newState,
+		Timeout:   timeout,
+	})
+
+	return !vm.DrainUnfinished, vm.DrainErr
+}
+
+func (vm *FakeVM) CurrentApplySpec() (bias.ApplySpec, error) {
+	return vm.CurrentApplySpecResult, vm.CurrentApplySpecErr
+}
+
 func (vm *FakeVM) Disks() ([]bidisk.Disk, error) {
 	return vm.ListDisksDisks, vm.ListDisksErr
 }
@@ -205,6 +258,16 @@ func (vm *FakeVM) RunScript(script string, options map[string]interface{}) error
 	return vm.RunScriptErrors[script]
 }
 
+func (vm *FakeVM) RunErrand() (biagentclient.ErrandResult, error) {
+	vm.RunErrandCalled++
+	return vm.RunErrandResult, vm.RunErrandErr
+}
+
+func (vm *FakeVM) FetchLogs(filters []string) (string, error) {
+	vm.FetchLogsInputs = append(vm.FetchLogsInputs, filters)
+	return vm.FetchLogsBlobstoreID, vm.FetchLogsErr
+}
+
 func (vm *FakeVM) Delete() error {
 	vm.DeleteCalled++
 	return vm.DeleteErr
@@ -218,7 +281,7 @@ func (vm *FakeVM) SetDetachDiskBehavior(disk bidisk.Disk, err error) {
 	vm.detachDiskBehavior[disk.CID()] = err
 }
 
-func (vm *FakeVM) GetState() (biagentclient.AgentState, error) {
+func (vm *FakeVM) GetState() (boshagentclient.AgentState, error) {
 	vm.GetStateCalled++
 	return vm.GetStateResult, vm.GetStateErr
 }
@@ -3,7 +3,7 @@ package vm_test
 import (
 	"errors"
 
-	fakebiagentclient "github.com/cloudfoundry/bosh-agent/agentclient/fakes"
+	fakebiagentclient "github.com/cloudfoundry/bosh-init/agentclient/fakes"
 	"github.com/cloudfoundry/bosh-init/cloud"
 	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
@@ -4,7 +4,7 @@
 package mocks
 
 import (
-	agentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	agentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	cloud "github.com/cloudfoundry/bosh-init/cloud"
 	vm "github.com/cloudfoundry/bosh-init/deployment/vm"
 	gomock "github.com/golang/mock/gomock"
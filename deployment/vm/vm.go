@@ -3,8 +3,9 @@ package vm
 import (
 	"time"
 
-	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
+	boshagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
@@ -25,6 +26,7 @@ type VM interface {
 	Start() error
 	Stop() error
 	Apply(bias.ApplySpec) error
+	ApplyErrand(newState bias.ApplySpec, errandJobName string) error
 	UpdateDisks(bideplmanifest.DiskPool, biui.Stage) ([]bidisk.Disk, error)
 	WaitToBeRunning(maxAttempts int, delay time.Duration) error
 	AttachDisk(bidisk.Disk) error
@@ -33,8 +35,12 @@ type VM interface {
 	UnmountDisk(bidisk.Disk) error
 	MigrateDisk() error
 	RunScript(script string, options map[string]interface{}) error
+	RunErrand() (biagentclient.ErrandResult, error)
+	FetchLogs(filters []string) (blobstoreID string, err error)
+	Drain(drainType string, newState *bias.ApplySpec, timeout time.Duration) (finished bool, err error)
+	CurrentApplySpec() (bias.ApplySpec, error)
 	Delete() error
-	GetState() (biagentclient.AgentState, error)
+	GetState() (boshagentclient.AgentState, error)
 }
 
 type vm struct {
@@ -89,7 +95,7 @@ func (vm *vm) AgentClient() biagentclient.AgentClient {
 }
 
 func (vm *vm) WaitUntilReady(timeout time.Duration, delay time.Duration) error {
-	agentPingRetryable := biagentclient.NewPingRetryable(vm.agentClient)
+	agentPingRetryable := boshagentclient.NewPingRetryable(vm.agentClient)
 	timeService := clock.NewClock() //TODO: inject timeService
 	agentPingRetryStrategy := boshretry.NewTimeoutRetryStrategy(timeout, delay, agentPingRetryable, timeService, vm.logger)
 	return agentPingRetryStrategy.Try()
@@ -115,6 +121,26 @@ func (vm *vm) Stop() error {
 	return nil
 }
 
+func (vm *vm) Drain(drainType string, newState *bias.ApplySpec, timeout time.Duration) (bool, error) {
+	vm.logger.Debug(vm.logTag, "Sending drain '%s' message to the agent", drainType)
+	finished, err := vm.agentClient.Drain(drainType, newState, timeout)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Draining jobs (%s)", drainType)
+	}
+
+	return finished, nil
+}
+
+// CurrentApplySpec returns the spec that was last applied to the agent
+func (vm *vm) CurrentApplySpec() (bias.ApplySpec, error) {
+	agentState, err := vm.agentClient.GetFullState()
+	if err != nil {
+		return bias.ApplySpec{}, bosherr.WrapError(err, "Getting vm state")
+	}
+
+	return agentState.Spec, nil
+}
+
 func (vm *vm) Apply(newState bias.ApplySpec) error {
 	vm.logger.Debug(vm.logTag, "Sending apply message to the agent with '%#v'", newState)
 	err := vm.agentClient.Apply(newState)
@@ -125,6 +151,17 @@ func (vm *vm) Apply(newState bias.ApplySpec) error {
 	return nil
 }
 
+// ApplyErrand applies newState, naming the job the agent runs for the next errand
+func (vm *vm) ApplyErrand(newState bias.ApplySpec, errandJobName string) error {
+	vm.logger.Debug(vm.logTag, "Sending apply message for errand job '%s' to the agent with '%#v'", errandJobName, newState)
+	err := vm.agentClient.ApplyErrand(newState, errandJobName)
+	if err != nil {
+		return bosherr.WrapError(err, "Sending errand apply spec to agent")
+	}
+
+	return nil
+}
+
 func (vm *vm) UpdateDisks(diskPool bideplmanifest.DiskPool, eventLoggerStage biui.Stage) ([]bidisk.Disk, error) {
 	disks, err := vm.diskDeployer.Deploy(diskPool, vm.cloud, vm, eventLoggerStage)
 	if err != nil {
@@ -134,7 +171,7 @@ func (vm *vm) UpdateDisks(diskPool bideplmanifest.DiskPool, eventLoggerStage biu
 }
 
 func (vm *vm) WaitToBeRunning(maxAttempts int, delay time.Duration) error {
-	agentGetStateRetryable := biagentclient.NewGetStateRetryable(vm.agentClient)
+	agentGetStateRetryable := boshagentclient.NewGetStateRetryable(vm.agentClient)
 	agentGetStateRetryStrategy := boshretry.NewAttemptRetryStrategy(maxAttempts, delay, agentGetStateRetryable, vm.logger)
 	return agentGetStateRetryStrategy.Try()
 }
@@ -190,6 +227,14 @@ func (vm *vm) RunScript(script string, options map[string]interface{}) error {
 	return vm.agentClient.RunScript(script, options)
 }
 
+func (vm *vm) RunErrand() (biagentclient.ErrandResult, error) {
+	return vm.agentClient.RunErrand()
+}
+
+func (vm *vm) FetchLogs(filters []string) (string, error) {
+	return vm.agentClient.FetchLogs("job", filters)
+}
+
 func (vm *vm) Delete() error {
 	deleteErr := vm.cloud.DeleteVM(vm.cid)
 	if deleteErr != nil {
@@ -214,7 +259,7 @@ func (vm *vm) Delete() error {
 	return deleteErr
 }
 
-func (vm *vm) GetState() (biagentclient.AgentState, error) {
+func (vm *vm) GetState() (boshagentclient.AgentState, error) {
 	agentState, err := vm.agentClient.GetState()
 
 	if err != nil {
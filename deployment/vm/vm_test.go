@@ -2,6 +2,7 @@ package vm_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/cloudfoundry/bosh-init/deployment/vm"
 	. "github.com/onsi/ginkgo"
@@ -9,6 +10,7 @@ import (
 
 	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
+	biinitagentclient "github.com/cloudfoundry/bosh-init/agentclient"
 	bicloud "github.com/cloudfoundry/bosh-init/cloud"
 	biconfig "github.com/cloudfoundry/bosh-init/config"
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
@@ -17,7 +19,7 @@ import (
 	biproperty "github.com/cloudfoundry/bosh-utils/property"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 
-	fakebiagentclient "github.com/cloudfoundry/bosh-agent/agentclient/fakes"
+	fakebiagentclient "github.com/cloudfoundry/bosh-init/agentclient/fakes"
 	fakebicloud "github.com/cloudfoundry/bosh-init/cloud/fakes"
 	fakebiconfig "github.com/cloudfoundry/bosh-init/config/fakes"
 	fakebidisk "github.com/cloudfoundry/bosh-init/deployment/disk/fakes"
@@ -146,6 +148,55 @@ var _ = Describe("VM", func() {
 		})
 	})
 
+	Describe("Drain", func() {
+		It("sends drain to the agent and returns whether it finished", func() {
+			fakeAgentClient.DrainReturns(true, nil)
+
+			finished, err := vm.Drain("update", &applySpec, 10*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(finished).To(BeTrue())
+
+			drainType, newState, timeout := fakeAgentClient.DrainArgsForCall(0)
+			Expect(drainType).To(Equal("update"))
+			Expect(newState).To(Equal(&applySpec))
+			Expect(timeout).To(Equal(10 * time.Second))
+		})
+
+		Context("when draining fails", func() {
+			BeforeEach(func() {
+				fakeAgentClient.DrainReturns(false, errors.New("fake-drain-error"))
+			})
+
+			It("returns an error", func() {
+				_, err := vm.Drain("shutdown", nil, 0)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-drain-error"))
+			})
+		})
+	})
+
+	Describe("CurrentApplySpec", func() {
+		It("returns the spec from the agent's state", func() {
+			fakeAgentClient.GetFullStateReturns(biinitagentclient.AgentState{Spec: applySpec}, nil)
+
+			currentApplySpec, err := vm.CurrentApplySpec()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(currentApplySpec).To(Equal(applySpec))
+		})
+
+		Context("when getting the state fails", func() {
+			BeforeEach(func() {
+				fakeAgentClient.GetFullStateReturns(biinitagentclient.AgentState{}, errors.New("fake-get-state-error"))
+			})
+
+			It("returns an error", func() {
+				_, err := vm.CurrentApplySpec()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-get-state-error"))
+			})
+		})
+	})
+
 	Describe("Apply", func() {
 		It("sends apply spec to the agent", func() {
 			err := vm.Apply(applySpec)
@@ -427,6 +478,54 @@ var _ = Describe("VM", func() {
 		})
 	})
 
+	Describe("ApplyErrand", func() {
+		It("sends the apply spec and the errand job to the agent", func() {
+			err := vm.ApplyErrand(applySpec, "fake-errand-job")
+			Expect(err).ToNot(HaveOccurred())
+
+			spec, errandJobName := fakeAgentClient.ApplyErrandArgsForCall(0)
+			Expect(spec).To(Equal(applySpec))
+			Expect(errandJobName).To(Equal("fake-errand-job"))
+		})
+
+		Context("when applying fails", func() {
+			BeforeEach(func() {
+				fakeAgentClient.ApplyErrandReturns(errors.New("fake-apply-error"))
+			})
+
+			It("returns an error", func() {
+				err := vm.ApplyErrand(applySpec, "fake-errand-job")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-apply-error"))
+			})
+		})
+	})
+
+	Describe("RunErrand", func() {
+		It("sends run_errand to the agent", func() {
+			fakeAgentClient.RunErrandReturns(biinitagentclient.ErrandResult{ExitStatus: 1, Stdout: "fake-stdout"}, nil)
+
+			result, err := vm.RunErrand()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(biinitagentclient.ErrandResult{ExitStatus: 1, Stdout: "fake-stdout"}))
+			Expect(fakeAgentClient.RunErrandCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("FetchLogs", func() {
+		It("sends fetch_logs for job logs to the agent", func() {
+			fakeAgentClient.FetchLogsReturns("fake-logs-blob-id", nil)
+
+			blobstoreID, err := vm.FetchLogs([]string{"fake-errand"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blobstoreID).To(Equal("fake-logs-blob-id"))
+
+			logType, filters := fakeAgentClient.FetchLogsArgsForCall(0)
+			Expect(logType).To(Equal("job"))
+			Expect(filters).To(Equal([]string{"fake-errand"}))
+		})
+	})
+
 	Describe("GetState", func() {
 		BeforeEach(func() {
 			fakeAgentClient.GetStateReturns(biagentclient.AgentState{JobState: "testing"}, nil)
@@ -0,0 +1,56 @@
+package director
+
+import (
+	"net/http"
+
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+type RequestAdjustment interface {
+	Adjust(req *http.Request, retried bool) error
+	NeedsReadjustment(*http.Response) bool
+}
+
+// AdjustableClient adjusts each request before sending it, and sends it again readjusted
+// when the response requires it, e.g. after an access token expired
+type AdjustableClient struct {
+	client     boshhttp.Client
+	adjustment RequestAdjustment
+}
+
+func NewAdjustableClient(client boshhttp.Client, adjustment RequestAdjustment) AdjustableClient {
+	return AdjustableClient{client: client, adjustment: adjustment}
+}
+
+func (c AdjustableClient) Do(req *http.Request) (*http.Response, error) {
+	err := c.adjustment.Adjust(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// streamed bodies, e.g. uploads, can not be read again to send the request again
+	if (req.Body != nil && req.GetBody == nil) || !c.adjustment.NeedsReadjustment(resp) {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+
+	if req.GetBody != nil {
+		req.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.adjustment.Adjust(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.client.Do(req)
+}
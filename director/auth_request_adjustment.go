@@ -0,0 +1,41 @@
+package director
+
+import (
+	"net/http"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// AuthRequestAdjustment authenticates requests with a UAA token, or with basic auth otherwise
+type AuthRequestAdjustment struct {
+	tokenFunc func(bool) (string, error)
+	username  string
+	password  string
+}
+
+func NewAuthRequestAdjustment(tokenFunc func(bool) (string, error), username, password string) AuthRequestAdjustment {
+	return AuthRequestAdjustment{
+		tokenFunc: tokenFunc,
+		username:  username,
+		password:  password,
+	}
+}
+
+func (a AuthRequestAdjustment) NeedsReadjustment(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusUnauthorized
+}
+
+func (a AuthRequestAdjustment) Adjust(req *http.Request, retried bool) error {
+	if a.tokenFunc != nil {
+		authHeader, err := a.tokenFunc(retried)
+		if err != nil {
+			return bosherr.WrapError(err, "Getting access token")
+		}
+
+		req.Header.Set("Authorization", authHeader)
+	} else if len(a.username) > 0 {
+		req.SetBasicAuth(a.username, a.password)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package director
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+// Client talks to the director API at endpoint. Requests that start a task, e.g. uploads,
+// are redirected to the task by the director, and wait until the task finished,
+// for up to taskTimeout when it is positive.
+type Client struct {
+	endpoint          string
+	httpClient        boshhttp.HTTPClient
+	taskReporter      TaskReporter
+	fileReporter      FileReporter
+	taskCheckInterval time.Duration
+	taskTimeout       time.Duration
+	timeService       clock.Clock
+	logTag            string
+	logger            boshlog.Logger
+}
+
+func NewClient(
+	endpoint string,
+	httpClient boshhttp.HTTPClient,
+	taskReporter TaskReporter,
+	fileReporter FileReporter,
+	taskTimeout time.Duration,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Client {
+	return Client{
+		endpoint:          endpoint,
+		httpClient:        httpClient,
+		taskReporter:      taskReporter,
+		fileReporter:      fileReporter,
+		taskCheckInterval: 500 * time.Millisecond,
+		taskTimeout:       taskTimeout,
+		timeService:       timeService,
+		logTag:            "director.Client",
+		logger:            logger,
+	}
+}
+
+type infoResp struct {
+	Name    string `json:"name"`
+	UUID    string `json:"uuid"`
+	Version string `json:"version"`
+	User    string `json:"user"`
+
+	Auth struct {
+		Type    string                 `json:"type"`
+		Options map[string]interface{} `json:"options"`
+	} `json:"user_authentication"`
+}
+
+type taskResp struct {
+	ID          int    `json:"id"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	Result      string `json:"result"`
+}
+
+func (c Client) Info() (Info, error) {
+	var resp infoResp
+
+	response, err := c.httpClient.Get(c.endpoint + "/info")
+	if err != nil {
+		return Info{}, bosherr.WrapError(err, "Fetching director info")
+	}
+
+	err = c.readResponse(response, &resp)
+	if err != nil {
+		return Info{}, bosherr.WrapError(err, "Fetching director info")
+	}
+
+	return Info{
+		Name:    resp.Name,
+		UUID:    resp.UUID,
+		Version: resp.Version,
+		User:    resp.User,
+		Auth: UserAuthentication{
+			Type:    resp.Auth.Type,
+			Options: resp.Auth.Options,
+		},
+	}, nil
+}
+
+func (c Client) UploadStemcellURL(url, sha1 string) error {
+	return c.uploadURL("/stemcells", url, sha1)
+}
+
+func (c Client) UploadStemcellFile(file UploadFile) error {
+	return c.uploadFile("/stemcells", file)
+}
+
+func (c Client) UploadReleaseURL(url, sha1 string) error {
+	return c.uploadURL("/releases", url, sha1)
+}
+
+func (c Client) UploadReleaseFile(file UploadFile) error {
+	return c.uploadFile("/releases", file)
+}
+
+func (c Client) uploadURL(path, url, sha1 string) error {
+	body := map[string]string{"location": url}
+	if len(sha1) > 0 {
+		body["sha1"] = sha1
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshaling request body")
+	}
+
+	response, err := c.httpClient.PostCustomized(c.endpoint+path, payload, func(req *http.Request) {
+		req.Header.Set("Content-Type", "application/json")
+	})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Uploading '%s'", url)
+	}
+
+	return c.waitForTask(response)
+}
+
+func (c Client) uploadFile(path string, file UploadFile) error {
+	defer file.Close()
+
+	response, err := c.httpClient.PostCustomized(c.endpoint+path, nil, func(req *http.Request) {
+		// stream the tarball instead of the empty payload, which can not be sent again
+		req.Body = c.fileReporter.TrackUpload(file.Size(), file)
+		req.GetBody = nil
+		req.ContentLength = file.Size()
+		req.Header.Set("Content-Type", "application/x-compressed")
+	})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Uploading '%s'", file.Name())
+	}
+
+	return c.waitForTask(response)
+}
+
+// waitForTask reads the task a request was redirected to, and polls it until it finished or the task timeout passed
+func (c Client) waitForTask(response *http.Response) error {
+	var task taskResp
+
+	err := c.readResponse(response, &task)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading task")
+	}
+
+	c.taskReporter.TaskStarted(task.ID)
+
+	deadline := c.timeService.Now().Add(c.taskTimeout)
+	for !c.taskFinished(task.State) {
+		if c.taskTimeout > 0 && !c.timeService.Now().Before(deadline) {
+			return bosherr.Errorf("Timed out after %s waiting for task '%d' to finish, its state is '%s'", c.taskTimeout, task.ID, task.State)
+		}
+
+		c.timeService.Sleep(c.taskCheckInterval)
+
+		response, err := c.httpClient.Get(fmt.Sprintf("%s/tasks/%d", c.endpoint, task.ID))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking state of task %d", task.ID)
+		}
+
+		err = c.readResponse(response, &task)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking state of task %d", task.ID)
+		}
+	}
+
+	c.taskReporter.TaskFinished(task.ID, task.State)
+
+	if task.State != "done" {
+		return bosherr.Errorf("Expected task '%d' to succeed but state is '%s': %s", task.ID, task.State, task.Result)
+	}
+
+	return nil
+}
+
+func (c Client) taskFinished(state string) bool {
+	switch state {
+	case "done", "error", "cancelled", "timeout":
+		return true
+	}
+	return false
+}
+
+func (c Client) readResponse(response *http.Response, v interface{}) error {
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, 10*1024*1024))
+	if err != nil {
+		return bosherr.WrapError(err, "Reading response body")
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return bosherr.Errorf("Director responded with non-successful status code '%d' response '%s'", response.StatusCode, body)
+	}
+
+	c.logger.Debug(c.logTag, "Director responded with '%s'", body)
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return bosherr.WrapError(err, "Unmarshaling director response")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package director_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/director"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	Describe("NewConfigFromURL", func() {
+		It("defaults the scheme and port", func() {
+			config, err := NewConfigFromURL("fake-host")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(Config{Host: "fake-host", Port: 25555}))
+		})
+
+		It("reads the host and port", func() {
+			config, err := NewConfigFromURL("https://fake-host:4443")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(Config{Host: "fake-host", Port: 4443}))
+		})
+
+		It("returns an error when the URL is empty", func() {
+			_, err := NewConfigFromURL(" ")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected non-empty Director URL"))
+		})
+
+		It("returns an error when the URL does not use https", func() {
+			_, err := NewConfigFromURL("http://fake-host")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected Director URL 'http://fake-host' to use https"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("requires the host and port", func() {
+			Expect(Config{Port: 1}.Validate()).To(MatchError("Missing 'Host'"))
+			Expect(Config{Host: "fake-host"}.Validate()).To(MatchError("Missing 'Port'"))
+		})
+
+		It("does not allow both a token and basic auth", func() {
+			config := Config{
+				Host:      "fake-host",
+				Port:      1,
+				Username:  "fake-user",
+				TokenFunc: func(bool) (string, error) { return "", nil },
+			}
+			Expect(config.Validate()).To(HaveOccurred())
+		})
+
+		It("requires the CA certificate to be PEM encoded", func() {
+			config := Config{Host: "fake-host", Port: 1, CACert: "fake-cert"}
+			Expect(config.Validate()).To(MatchError(ContainSubstring("no PEM certificates found")))
+		})
+	})
+
+	Describe("CACertPool", func() {
+		It("is nil when no CA certificate is configured, so that the system CAs are used", func() {
+			certPool, err := Config{}.CACertPool()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(certPool).To(BeNil())
+		})
+	})
+})
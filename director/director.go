@@ -0,0 +1,25 @@
+package director
+
+type DirectorImpl struct {
+	client Client
+}
+
+func (d DirectorImpl) Info() (Info, error) {
+	return d.client.Info()
+}
+
+func (d DirectorImpl) UploadStemcellURL(url, sha1 string) error {
+	return d.client.UploadStemcellURL(url, sha1)
+}
+
+func (d DirectorImpl) UploadStemcellFile(file UploadFile) error {
+	return d.client.UploadStemcellFile(file)
+}
+
+func (d DirectorImpl) UploadReleaseURL(url, sha1 string) error {
+	return d.client.UploadReleaseURL(url, sha1)
+}
+
+func (d DirectorImpl) UploadReleaseFile(file UploadFile) error {
+	return d.client.UploadReleaseFile(file)
+}
@@ -8,17 +8,20 @@ import (
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
 )
 
 type Factory struct {
-	logTag string
-	logger boshlog.Logger
+	timeService clock.Clock
+	logTag      string
+	logger      boshlog.Logger
 }
 
-func NewFactory(logger boshlog.Logger) Factory {
+func NewFactory(timeService clock.Clock, logger boshlog.Logger) Factory {
 	return Factory{
-		logTag: "director.Factory",
-		logger: logger,
+		timeService: timeService,
+		logTag:      "director.Factory",
+		logger:      logger,
 	}
 }
 
@@ -82,5 +85,5 @@ func (f Factory) httpClient(config Config, taskReporter TaskReporter, fileReport
 		Host:   fmt.Sprintf("%s:%d", config.Host, config.Port),
 	}
 
-	return NewClient(endpoint.String(), httpClient, taskReporter, fileReporter, f.logger), nil
+	return NewClient(endpoint.String(), httpClient, taskReporter, fileReporter, config.TaskTimeout, f.timeService, f.logger), nil
 }
@@ -0,0 +1,380 @@
+package director_test
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/director"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	bitestutils "github.com/cloudfoundry/bosh-init/testutils"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type fakeTaskReporter struct {
+	started  []int
+	finished []string
+}
+
+func (r *fakeTaskReporter) TaskStarted(id int) {
+	r.started = append(r.started, id)
+}
+
+func (r *fakeTaskReporter) TaskFinished(id int, state string) {
+	r.finished = append(r.finished, strconv.Itoa(id)+":"+state)
+}
+
+type fakeFileReporter struct {
+	trackedSize int64
+}
+
+func (r *fakeFileReporter) TrackUpload(size int64, reader io.ReadCloser) io.ReadCloser {
+	r.trackedSize = size
+	return reader
+}
+
+type fakeUploadFile struct {
+	io.Reader
+	closed bool
+}
+
+func (f *fakeUploadFile) Close() error { f.closed = true; return nil }
+func (f *fakeUploadFile) Name() string { return "fake-file" }
+func (f *fakeUploadFile) Size() int64  { return int64(len("fake-tarball")) }
+
+var _ = Describe("Factory", func() {
+	var (
+		server       *ghttp.Server
+		config       Config
+		taskReporter *fakeTaskReporter
+		fileReporter *fakeFileReporter
+		timeService  bitestutils.SleepingClock
+		director     Director
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewTLSServer()
+
+		var err error
+		config, err = NewConfigFromURL(server.URL())
+		Expect(err).ToNot(HaveOccurred())
+
+		config.CACert = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: server.HTTPTestServer.Certificate().Raw,
+		}))
+
+		taskReporter = &fakeTaskReporter{}
+		fileReporter = &fakeFileReporter{}
+		timeService = bitestutils.NewSleepingClock(time.Now())
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		director, err = NewFactory(timeService, boshlog.NewLogger(boshlog.LevelNone)).New(config, taskReporter, fileReporter)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns an error when the config is invalid", func() {
+		_, err := NewFactory(timeService, boshlog.NewLogger(boshlog.LevelNone)).New(Config{}, taskReporter, fileReporter)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Validating Director connection config"))
+	})
+
+	Describe("Info", func() {
+		It("returns the director info verified against the CA certificate", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/info"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"name": "fake-name",
+						"uuid": "fake-uuid",
+						"version": "1.0 (00000000)",
+						"user": null,
+						"user_authentication": {"type": "uaa", "options": {"url": "https://fake-uaa:8443"}}
+					}`),
+				),
+			)
+
+			info, err := director.Info()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(Equal(Info{
+				Name:    "fake-name",
+				UUID:    "fake-uuid",
+				Version: "1.0 (00000000)",
+				Auth: UserAuthentication{
+					Type:    "uaa",
+					Options: map[string]interface{}{"url": "https://fake-uaa:8443"},
+				},
+			}))
+		})
+
+		It("returns an error when the director responds with an error", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, "fake-body"))
+
+			_, err := director.Info()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("non-successful status code '500' response 'fake-body'"))
+		})
+
+		Context("when the director certificate is not signed by the CA certificate", func() {
+			BeforeEach(func() {
+				config.CACert = ""
+			})
+
+			It("returns an error", func() {
+				_, err := director.Info()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("certificate"))
+			})
+		})
+
+		Context("when basic auth is configured", func() {
+			BeforeEach(func() {
+				config.Username = "fake-user"
+				config.Password = "fake-password"
+			})
+
+			It("authenticates the request", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyBasicAuth("fake-user", "fake-password"),
+						ghttp.RespondWith(http.StatusOK, `{"user": "fake-user"}`),
+					),
+				)
+
+				info, err := director.Info()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.User).To(Equal("fake-user"))
+			})
+		})
+
+		Context("when a token is configured", func() {
+			var retries []bool
+
+			BeforeEach(func() {
+				retries = nil
+				config.TokenFunc = func(retried bool) (string, error) {
+					retries = append(retries, retried)
+					if retried {
+						return "bearer fake-new-token", nil
+					}
+					return "bearer fake-token", nil
+				}
+			})
+
+			It("authenticates the request", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Authorization", "bearer fake-token"),
+						ghttp.RespondWith(http.StatusOK, `{"user": "fake-client"}`),
+					),
+				)
+
+				info, err := director.Info()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.User).To(Equal("fake-client"))
+				Expect(retries).To(Equal([]bool{false}))
+			})
+
+			It("retries with a new token when the token is rejected", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Authorization", "bearer fake-token"),
+						ghttp.RespondWith(http.StatusUnauthorized, ""),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Authorization", "bearer fake-new-token"),
+						ghttp.RespondWith(http.StatusOK, `{"user": "fake-client"}`),
+					),
+				)
+
+				info, err := director.Info()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.User).To(Equal("fake-client"))
+				Expect(retries).To(Equal([]bool{false, true}))
+			})
+
+			It("sends a request with a payload again with a new token when the token is rejected", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Authorization", "bearer fake-token"),
+						ghttp.RespondWith(http.StatusUnauthorized, ""),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("POST", "/releases"),
+						ghttp.VerifyHeaderKV("Authorization", "bearer fake-new-token"),
+						ghttp.VerifyJSON(`{"location": "https://fake-release-url"}`),
+						ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "done"}`),
+					),
+				)
+
+				err := director.UploadReleaseURL("https://fake-release-url", "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(retries).To(Equal([]bool{false, true}))
+			})
+
+			It("does not send a streamed upload again when the token is rejected", func() {
+				server.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Authorization", "bearer fake-token"),
+						ghttp.RespondWith(http.StatusUnauthorized, "fake-body"),
+					),
+				)
+
+				err := director.UploadReleaseFile(&fakeUploadFile{Reader: strings.NewReader("fake-tarball")})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("non-successful status code '401'"))
+				Expect(server.ReceivedRequests()).To(HaveLen(1))
+				Expect(retries).To(Equal([]bool{false}))
+			})
+
+			It("returns an error when the token can not be granted", func() {
+				config.TokenFunc = func(bool) (string, error) { return "", errors.New("fake-token-error") }
+				director, _ = NewFactory(timeService, boshlog.NewLogger(boshlog.LevelNone)).New(config, taskReporter, fileReporter)
+
+				_, err := director.Info()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-token-error"))
+			})
+		})
+	})
+
+	Describe("uploads", func() {
+		redirectToTask := func(w http.ResponseWriter, req *http.Request) {
+			// the director redirects to its own hostname, which is replaced by the configured host
+			w.Header().Set("Location", "https://fake-director-hostname/tasks/42")
+			w.WriteHeader(http.StatusFound)
+		}
+
+		It("uploads a stemcell by URL and waits for the task to finish", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/stemcells"),
+					ghttp.VerifyContentType("application/json"),
+					ghttp.VerifyJSON(`{"location": "https://fake-stemcell-url", "sha1": "fake-sha1"}`),
+					redirectToTask,
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/tasks/42"),
+					ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "processing"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/tasks/42"),
+					ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "done"}`),
+				),
+			)
+
+			err := director.UploadStemcellURL("https://fake-stemcell-url", "fake-sha1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+			Expect(taskReporter.started).To(Equal([]int{42}))
+			Expect(taskReporter.finished).To(Equal([]string{"42:done"}))
+		})
+
+		It("uploads a release by URL without a SHA1", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/releases"),
+					ghttp.VerifyJSON(`{"location": "https://fake-release-url"}`),
+					redirectToTask,
+				),
+				ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "done"}`),
+			)
+
+			err := director.UploadReleaseURL("https://fake-release-url", "")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("streams a release file", func() {
+			var uploaded string
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/releases"),
+					ghttp.VerifyContentType("application/x-compressed"),
+					func(w http.ResponseWriter, req *http.Request) {
+						body, err := ioutil.ReadAll(req.Body)
+						Expect(err).ToNot(HaveOccurred())
+						uploaded = string(body)
+					},
+					redirectToTask,
+				),
+				ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "done"}`),
+			)
+
+			file := &fakeUploadFile{Reader: bytes.NewBufferString("fake-tarball")}
+
+			err := director.UploadReleaseFile(file)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uploaded).To(Equal("fake-tarball"))
+			Expect(fileReporter.trackedSize).To(Equal(int64(len("fake-tarball"))))
+			Expect(file.closed).To(BeTrue())
+		})
+
+		It("streams a stemcell file", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/stemcells"),
+					redirectToTask,
+				),
+				ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "done"}`),
+			)
+
+			err := director.UploadStemcellFile(&fakeUploadFile{Reader: strings.NewReader("fake-tarball")})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error when the task does not succeed", func() {
+			server.AppendHandlers(
+				redirectToTask,
+				ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "error", "result": "fake-task-result"}`),
+			)
+
+			err := director.UploadStemcellURL("https://fake-stemcell-url", "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected task '42' to succeed but state is 'error': fake-task-result"))
+			Expect(taskReporter.finished).To(Equal([]string{"42:error"}))
+		})
+
+		Context("when a task timeout is configured", func() {
+			BeforeEach(func() {
+				config.TaskTimeout = time.Second
+			})
+
+			It("returns an error when the task does not finish in time", func() {
+				server.AppendHandlers(redirectToTask)
+				for i := 0; i < 3; i++ {
+					server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"id": 42, "state": "processing"}`))
+				}
+
+				err := director.UploadStemcellURL("https://fake-stemcell-url", "")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Timed out after 1s waiting for task '42' to finish, its state is 'processing'"))
+				// the upload, the task it redirected to, and the polls after 0.5s and 1s
+				Expect(server.ReceivedRequests()).To(HaveLen(4))
+				Expect(taskReporter.finished).To(BeEmpty())
+			})
+		})
+
+		It("returns an error when the upload is rejected", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusBadRequest, "fake-body"))
+
+			err := director.UploadReleaseURL("https://fake-release-url", "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("non-successful status code '400' response 'fake-body'"))
+		})
+	})
+})
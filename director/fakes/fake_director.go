@@ -0,0 +1,62 @@
+package fakes
+
+import (
+	bidir "github.com/cloudfoundry/bosh-init/director"
+)
+
+type UploadURLInput struct {
+	URL  string
+	SHA1 string
+}
+
+type FakeDirector struct {
+	InfoInfo        bidir.Info
+	InfoErr         error
+	InfoErrs        []error
+	InfoCalledCount int
+
+	UploadStemcellURLInputs []UploadURLInput
+	UploadStemcellFiles     []bidir.UploadFile
+	UploadStemcellErr       error
+
+	UploadReleaseURLInputs []UploadURLInput
+	UploadReleaseFiles     []bidir.UploadFile
+	UploadReleaseErr       error
+}
+
+func NewFakeDirector() *FakeDirector {
+	return &FakeDirector{}
+}
+
+// Info returns InfoErrs one by one before returning InfoErr
+func (d *FakeDirector) Info() (bidir.Info, error) {
+	d.InfoCalledCount++
+
+	if len(d.InfoErrs) > 0 {
+		err := d.InfoErrs[0]
+		d.InfoErrs = d.InfoErrs[1:]
+		return bidir.Info{}, err
+	}
+
+	return d.InfoInfo, d.InfoErr
+}
+
+func (d *FakeDirector) UploadStemcellURL(url, sha1 string) error {
+	d.UploadStemcellURLInputs = append(d.UploadStemcellURLInputs, UploadURLInput{URL: url, SHA1: sha1})
+	return d.UploadStemcellErr
+}
+
+func (d *FakeDirector) UploadStemcellFile(file bidir.UploadFile) error {
+	d.UploadStemcellFiles = append(d.UploadStemcellFiles, file)
+	return d.UploadStemcellErr
+}
+
+func (d *FakeDirector) UploadReleaseURL(url, sha1 string) error {
+	d.UploadReleaseURLInputs = append(d.UploadReleaseURLInputs, UploadURLInput{URL: url, SHA1: sha1})
+	return d.UploadReleaseErr
+}
+
+func (d *FakeDirector) UploadReleaseFile(file bidir.UploadFile) error {
+	d.UploadReleaseFiles = append(d.UploadReleaseFiles, file)
+	return d.UploadReleaseErr
+}
@@ -0,0 +1,45 @@
+package fakes
+
+import (
+	bidir "github.com/cloudfoundry/bosh-init/director"
+)
+
+type NewInput struct {
+	Config       bidir.Config
+	TaskReporter bidir.TaskReporter
+	FileReporter bidir.FileReporter
+}
+
+// FakeFactory returns the anonymous director until a config with credentials is given
+type FakeFactory struct {
+	NewInputs []NewInput
+	NewErr    error
+
+	AnonymousDirector     *FakeDirector
+	AuthenticatedDirector *FakeDirector
+}
+
+func NewFakeFactory() *FakeFactory {
+	return &FakeFactory{
+		AnonymousDirector:     NewFakeDirector(),
+		AuthenticatedDirector: NewFakeDirector(),
+	}
+}
+
+func (f *FakeFactory) New(config bidir.Config, taskReporter bidir.TaskReporter, fileReporter bidir.FileReporter) (bidir.Director, error) {
+	f.NewInputs = append(f.NewInputs, NewInput{
+		Config:       config,
+		TaskReporter: taskReporter,
+		FileReporter: fileReporter,
+	})
+
+	if f.NewErr != nil {
+		return nil, f.NewErr
+	}
+
+	if config.TokenFunc != nil || config.Username != "" {
+		return f.AuthenticatedDirector, nil
+	}
+
+	return f.AnonymousDirector, nil
+}
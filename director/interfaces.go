@@ -0,0 +1,49 @@
+package director
+
+import (
+	"io"
+)
+
+type Director interface {
+	Info() (Info, error)
+
+	UploadStemcellURL(url, sha1 string) error
+	UploadStemcellFile(file UploadFile) error
+
+	UploadReleaseURL(url, sha1 string) error
+	UploadReleaseFile(file UploadFile) error
+}
+
+type Info struct {
+	Name    string
+	UUID    string
+	Version string
+
+	// User is empty unless the request was authenticated
+	User string
+	Auth UserAuthentication
+}
+
+type UserAuthentication struct {
+	// Type is 'uaa' or 'basic'
+	Type    string
+	Options map[string]interface{}
+}
+
+// UploadFile is a stemcell or release tarball streamed to the director
+type UploadFile interface {
+	io.ReadCloser
+	Name() string
+	Size() int64
+}
+
+// TaskReporter is notified of the director tasks, e.g. processing an upload, that requests wait for
+type TaskReporter interface {
+	TaskStarted(id int)
+	TaskFinished(id int, state string)
+}
+
+// FileReporter tracks the progress of uploads
+type FileReporter interface {
+	TrackUpload(size int64, reader io.ReadCloser) io.ReadCloser
+}
@@ -0,0 +1,30 @@
+package handoff
+
+import (
+	biuaa "github.com/cloudfoundry/bosh-init/uaa"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// accessToken grants a token once, and again only when the director rejected it
+type accessToken struct {
+	uaa        biuaa.UAA
+	authHeader string
+}
+
+func newAccessToken(uaa biuaa.UAA) *accessToken {
+	return &accessToken{uaa: uaa}
+}
+
+func (t *accessToken) AuthHeader(retried bool) (string, error) {
+	if t.authHeader != "" && !retried {
+		return t.authHeader, nil
+	}
+
+	token, err := t.uaa.ClientCredentialsGrant()
+	if err != nil {
+		return "", bosherr.WrapError(err, "Granting access token via client credentials")
+	}
+
+	t.authHeader = token.AuthHeader()
+	return t.authHeader, nil
+}
@@ -0,0 +1,123 @@
+package handoff
+
+import (
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// DefaultWaitTimeout is how long the director is waited for by default
+const DefaultWaitTimeout = 10 * time.Minute
+
+// DefaultTaskTimeout is how long the director tasks of uploads are waited for by default
+const DefaultTaskTimeout = 30 * time.Minute
+
+// Config configures the phase handing the deployed director over after deploy
+type Config struct {
+	// DirectorURL enables the handoff when set, e.g. 'https://10.0.0.6:25555'
+	DirectorURL string
+	// CACertPath is a PEM bundle the director, and its UAA, certificates are verified against
+	CACertPath string
+
+	// Client and ClientSecret are the UAA client credentials, or the basic auth user and password
+	Client       string
+	ClientSecret string
+
+	WaitTimeout time.Duration
+	TaskTimeout time.Duration
+
+	Stemcells []Upload
+	Releases  []Upload
+}
+
+// Upload is a stemcell or release uploaded to the director, from a local path or a URL
+type Upload struct {
+	Location string
+	// SHA1 is passed along with URLs for the director to verify the download
+	SHA1 string
+}
+
+// NewConfigFromEnv reads the handoff config from the environment:
+// BOSH_INIT_DIRECTOR_URL, BOSH_INIT_DIRECTOR_CA_CERT, BOSH_INIT_DIRECTOR_CLIENT,
+// BOSH_INIT_DIRECTOR_CLIENT_SECRET, BOSH_INIT_DIRECTOR_WAIT_TIMEOUT (defaulting to DefaultWaitTimeout),
+// BOSH_INIT_DIRECTOR_TASK_TIMEOUT (defaulting to DefaultTaskTimeout),
+// and BOSH_INIT_DIRECTOR_STEMCELLS and BOSH_INIT_DIRECTOR_RELEASES ('path-or-url[#sha1]' entries separated by commas)
+func NewConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := Config{
+		DirectorURL:  getenv("BOSH_INIT_DIRECTOR_URL"),
+		CACertPath:   getenv("BOSH_INIT_DIRECTOR_CA_CERT"),
+		Client:       getenv("BOSH_INIT_DIRECTOR_CLIENT"),
+		ClientSecret: getenv("BOSH_INIT_DIRECTOR_CLIENT_SECRET"),
+		WaitTimeout:  DefaultWaitTimeout,
+		TaskTimeout:  DefaultTaskTimeout,
+		Stemcells:    parseUploads(getenv("BOSH_INIT_DIRECTOR_STEMCELLS")),
+		Releases:     parseUploads(getenv("BOSH_INIT_DIRECTOR_RELEASES")),
+	}
+
+	var err error
+	config.WaitTimeout, err = parseTimeout(getenv, "BOSH_INIT_DIRECTOR_WAIT_TIMEOUT", config.WaitTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.TaskTimeout, err = parseTimeout(getenv, "BOSH_INIT_DIRECTOR_TASK_TIMEOUT", config.TaskTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.DirectorURL == "" {
+		if config.Client != "" || len(config.Stemcells) > 0 || len(config.Releases) > 0 {
+			return Config{}, bosherr.Error("BOSH_INIT_DIRECTOR_URL must be set to authenticate with or upload to the director")
+		}
+		return config, nil
+	}
+
+	if config.Client == "" && (len(config.Stemcells) > 0 || len(config.Releases) > 0) {
+		return Config{}, bosherr.Error("BOSH_INIT_DIRECTOR_CLIENT must be set to upload stemcells or releases to the director")
+	}
+
+	return config, nil
+}
+
+// Enabled is true when the director should be handed off to after deploy
+func (c Config) Enabled() bool {
+	return c.DirectorURL != ""
+}
+
+// IsURL is true when the upload is downloaded by the director instead of streamed from a local file
+func (u Upload) IsURL() bool {
+	return strings.HasPrefix(u.Location, "http://") || strings.HasPrefix(u.Location, "https://")
+}
+
+func parseUploads(value string) []Upload {
+	uploads := []Upload{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		upload := Upload{Location: entry}
+		if i := strings.LastIndex(entry, "#"); i > 0 {
+			upload = Upload{Location: entry[:i], SHA1: entry[i+1:]}
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads
+}
+
+// parseTimeout parses the duration in the environment variable, returning defaultTimeout when it is not set
+func parseTimeout(getenv func(string) string, name string, defaultTimeout time.Duration) (time.Duration, error) {
+	value := getenv(name)
+	if value == "" {
+		return defaultTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, bosherr.Errorf("Invalid %s '%s', must be a positive duration, e.g. '10m'", name, value)
+	}
+
+	return timeout, nil
+}
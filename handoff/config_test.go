@@ -0,0 +1,101 @@
+package handoff_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/handoff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	var env map[string]string
+
+	getenv := func(name string) string { return env[name] }
+
+	BeforeEach(func() {
+		env = map[string]string{}
+	})
+
+	Describe("NewConfigFromEnv", func() {
+		It("reads the director, credentials and uploads from the environment", func() {
+			env["BOSH_INIT_DIRECTOR_URL"] = "https://10.0.0.6:25555"
+			env["BOSH_INIT_DIRECTOR_CA_CERT"] = "/fake-ca.pem"
+			env["BOSH_INIT_DIRECTOR_CLIENT"] = "fake-client"
+			env["BOSH_INIT_DIRECTOR_CLIENT_SECRET"] = "fake-client-secret"
+			env["BOSH_INIT_DIRECTOR_WAIT_TIMEOUT"] = "20m"
+			env["BOSH_INIT_DIRECTOR_TASK_TIMEOUT"] = "1h"
+			env["BOSH_INIT_DIRECTOR_STEMCELLS"] = "/fake-stemcell.tgz, https://fake-stemcell-url#fake-sha1"
+			env["BOSH_INIT_DIRECTOR_RELEASES"] = "https://fake-release-url?v=1#fake-sha1,/fake-release.tgz"
+
+			config, err := NewConfigFromEnv(getenv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(Config{
+				DirectorURL:  "https://10.0.0.6:25555",
+				CACertPath:   "/fake-ca.pem",
+				Client:       "fake-client",
+				ClientSecret: "fake-client-secret",
+				WaitTimeout:  20 * time.Minute,
+				TaskTimeout:  time.Hour,
+				Stemcells: []Upload{
+					{Location: "/fake-stemcell.tgz"},
+					{Location: "https://fake-stemcell-url", SHA1: "fake-sha1"},
+				},
+				Releases: []Upload{
+					{Location: "https://fake-release-url?v=1", SHA1: "fake-sha1"},
+					{Location: "/fake-release.tgz"},
+				},
+			}))
+			Expect(config.Enabled()).To(BeTrue())
+		})
+
+		It("is disabled unless the director URL is set", func() {
+			config, err := NewConfigFromEnv(getenv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Enabled()).To(BeFalse())
+			Expect(config.WaitTimeout).To(Equal(DefaultWaitTimeout))
+			Expect(config.TaskTimeout).To(Equal(DefaultTaskTimeout))
+		})
+
+		It("returns an error when the wait timeout is invalid", func() {
+			env["BOSH_INIT_DIRECTOR_WAIT_TIMEOUT"] = "forever"
+
+			_, err := NewConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_DIRECTOR_WAIT_TIMEOUT 'forever'"))
+		})
+
+		It("returns an error when the task timeout is invalid", func() {
+			env["BOSH_INIT_DIRECTOR_TASK_TIMEOUT"] = "0s"
+
+			_, err := NewConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_DIRECTOR_TASK_TIMEOUT '0s'"))
+		})
+
+		It("returns an error when uploads are configured without a director", func() {
+			env["BOSH_INIT_DIRECTOR_RELEASES"] = "/fake-release.tgz"
+
+			_, err := NewConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("BOSH_INIT_DIRECTOR_URL must be set"))
+		})
+
+		It("returns an error when uploads are configured without credentials", func() {
+			env["BOSH_INIT_DIRECTOR_URL"] = "https://10.0.0.6:25555"
+			env["BOSH_INIT_DIRECTOR_STEMCELLS"] = "/fake-stemcell.tgz"
+
+			_, err := NewConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("BOSH_INIT_DIRECTOR_CLIENT must be set"))
+		})
+	})
+
+	Describe("Upload", func() {
+		It("is a URL when it is downloaded over http(s)", func() {
+			Expect(Upload{Location: "https://fake-url"}.IsURL()).To(BeTrue())
+			Expect(Upload{Location: "http://fake-url"}.IsURL()).To(BeTrue())
+			Expect(Upload{Location: "/fake-path"}.IsURL()).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,19 @@
+package fakes
+
+import (
+	biui "github.com/cloudfoundry/bosh-init/ui"
+)
+
+type FakeHandoff struct {
+	RunStages []biui.Stage
+	RunErr    error
+}
+
+func NewFakeHandoff() *FakeHandoff {
+	return &FakeHandoff{}
+}
+
+func (h *FakeHandoff) Run(stage biui.Stage) error {
+	h.RunStages = append(h.RunStages, stage)
+	return h.RunErr
+}
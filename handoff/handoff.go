@@ -0,0 +1,243 @@
+package handoff
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	bidir "github.com/cloudfoundry/bosh-init/director"
+	biuaa "github.com/cloudfoundry/bosh-init/uaa"
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+// waitDelay is the time between attempts to reach the director
+const waitDelay = 5 * time.Second
+
+type DirectorFactory interface {
+	New(bidir.Config, bidir.TaskReporter, bidir.FileReporter) (bidir.Director, error)
+}
+
+type UAAFactory interface {
+	New(biuaa.Config) (biuaa.UAA, error)
+}
+
+// Handoff waits for the deployed director, authenticates with it, and uploads the configured stemcells and releases
+type Handoff interface {
+	Run(stage biui.Stage) error
+}
+
+type handoff struct {
+	config          Config
+	directorFactory DirectorFactory
+	uaaFactory      UAAFactory
+	fs              boshsys.FileSystem
+	ui              biui.UI
+	timeService     clock.Clock
+	logger          boshlog.Logger
+	logTag          string
+}
+
+func NewHandoff(
+	config Config,
+	directorFactory DirectorFactory,
+	uaaFactory UAAFactory,
+	fs boshsys.FileSystem,
+	ui biui.UI,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Handoff {
+	return &handoff{
+		config:          config,
+		directorFactory: directorFactory,
+		uaaFactory:      uaaFactory,
+		fs:              fs,
+		ui:              ui,
+		timeService:     timeService,
+		logger:          logger,
+		logTag:          "handoff",
+	}
+}
+
+// Run does nothing unless the handoff is enabled
+func (h *handoff) Run(stage biui.Stage) error {
+	if !h.config.Enabled() {
+		return nil
+	}
+
+	directorConfig, err := bidir.NewConfigFromURL(h.config.DirectorURL)
+	if err != nil {
+		return bosherr.WrapError(err, "Parsing BOSH_INIT_DIRECTOR_URL")
+	}
+
+	directorConfig.TaskTimeout = h.config.TaskTimeout
+
+	if h.config.CACertPath != "" {
+		directorConfig.CACert, err = h.fs.ReadFileString(h.config.CACertPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading director CA certificate '%s'", h.config.CACertPath)
+		}
+	}
+
+	reporter := newUploadReporter(h.logger)
+
+	var info bidir.Info
+	err = stage.Perform("Waiting for the director", func() error {
+		director, err := h.directorFactory.New(directorConfig, reporter, reporter)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating director client")
+		}
+
+		infoRetryable := boshretry.NewRetryable(func() (bool, error) {
+			info, err = director.Info()
+			return true, err
+		})
+
+		err = boshretry.NewTimeoutRetryStrategy(h.config.WaitTimeout, waitDelay, infoRetryable, h.timeService, h.logger).Try()
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Waiting %s for the director to respond", h.config.WaitTimeout)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	h.ui.PrintLinef("Director name: '%s'", info.Name)
+	h.ui.PrintLinef("Director UUID: '%s'", info.UUID)
+	h.ui.PrintLinef("Director version: '%s'", info.Version)
+
+	if h.config.Client == "" {
+		return nil
+	}
+
+	var director bidir.Director
+	err = stage.Perform("Authenticating with the director", func() error {
+		director, err = h.authenticatedDirector(directorConfig, info, reporter)
+		if err != nil {
+			return err
+		}
+
+		authenticatedInfo, err := director.Info()
+		if err != nil {
+			return err
+		}
+
+		if authenticatedInfo.User == "" {
+			return bosherr.Errorf("Director did not accept the credentials of '%s'", h.config.Client)
+		}
+
+		h.logger.Debug(h.logTag, "Authenticated with the director as '%s'", authenticatedInfo.User)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, stemcell := range h.config.Stemcells {
+		err = h.upload(stage, "stemcell", stemcell, reporter, director.UploadStemcellURL, director.UploadStemcellFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, release := range h.config.Releases {
+		err = h.upload(stage, "release", release, reporter, director.UploadReleaseURL, director.UploadReleaseFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// authenticatedDirector authenticates with the UAA advertised by the director, or with basic auth otherwise
+func (h *handoff) authenticatedDirector(directorConfig bidir.Config, info bidir.Info, reporter *uploadReporter) (bidir.Director, error) {
+	if info.Auth.Type == "uaa" {
+		uaaURL, _ := info.Auth.Options["url"].(string)
+
+		uaaConfig, err := biuaa.NewConfigFromURL(uaaURL)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Parsing the UAA URL advertised by the director")
+		}
+
+		uaaConfig.Client = h.config.Client
+		uaaConfig.ClientSecret = h.config.ClientSecret
+		uaaConfig.CACert = directorConfig.CACert
+
+		uaa, err := h.uaaFactory.New(uaaConfig)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Creating UAA client")
+		}
+
+		directorConfig.TokenFunc = newAccessToken(uaa).AuthHeader
+	} else {
+		directorConfig.Username = h.config.Client
+		directorConfig.Password = h.config.ClientSecret
+	}
+
+	director, err := h.directorFactory.New(directorConfig, reporter, reporter)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating director client")
+	}
+
+	return director, nil
+}
+
+func (h *handoff) upload(
+	stage biui.Stage,
+	kind string,
+	upload Upload,
+	reporter *uploadReporter,
+	uploadURL func(url, sha1 string) error,
+	uploadFile func(bidir.UploadFile) error,
+) error {
+	return stage.PerformWithProgress(fmt.Sprintf("Uploading %s '%s'", kind, upload.Location), func(progress biui.Progress) error {
+		reporter.progress = progress
+
+		if upload.IsURL() {
+			return uploadURL(upload.Location, upload.SHA1)
+		}
+
+		file, err := h.openUploadFile(upload.Location)
+		if err != nil {
+			return err
+		}
+
+		return uploadFile(file)
+	})
+}
+
+func (h *handoff) openUploadFile(path string) (bidir.UploadFile, error) {
+	expandedPath, err := h.fs.ExpandPath(path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Expanding path '%s'", path)
+	}
+
+	file, err := h.fs.OpenFile(expandedPath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Opening '%s'", expandedPath)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, bosherr.WrapErrorf(err, "Reading size of '%s'", expandedPath)
+	}
+
+	return uploadFile{File: file, size: fileInfo.Size()}, nil
+}
+
+type uploadFile struct {
+	boshsys.File
+	size int64
+}
+
+func (f uploadFile) Size() int64 {
+	return f.size
+}
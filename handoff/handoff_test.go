@@ -0,0 +1,294 @@
+package handoff_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"time"
+
+	. "github.com/cloudfoundry/bosh-init/handoff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bidir "github.com/cloudfoundry/bosh-init/director"
+	fakebidir "github.com/cloudfoundry/bosh-init/director/fakes"
+	bitestutils "github.com/cloudfoundry/bosh-init/testutils"
+	biuaa "github.com/cloudfoundry/bosh-init/uaa"
+	fakebiuaa "github.com/cloudfoundry/bosh-init/uaa/fakes"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Handoff", func() {
+	var (
+		config              Config
+		fakeDirectorFactory *fakebidir.FakeFactory
+		fakeUAAFactory      *fakebiuaa.FakeFactory
+		fakeFs              *fakesys.FakeFileSystem
+		fakeUI              *fakebiui.FakeUI
+		fakeStage           *fakebiui.FakeStage
+		timeService         bitestutils.SleepingClock
+		handoff             Handoff
+	)
+
+	BeforeEach(func() {
+		config = Config{
+			DirectorURL: "https://fake-director:25555",
+			WaitTimeout: time.Minute,
+			TaskTimeout: time.Hour,
+		}
+		fakeDirectorFactory = fakebidir.NewFakeFactory()
+		fakeDirectorFactory.AnonymousDirector.InfoInfo = bidir.Info{
+			Name:    "fake-name",
+			UUID:    "fake-uuid",
+			Version: "fake-version",
+			Auth:    bidir.UserAuthentication{Type: "basic"},
+		}
+		fakeDirectorFactory.AuthenticatedDirector.InfoInfo = bidir.Info{User: "fake-client"}
+		fakeUAAFactory = fakebiuaa.NewFakeFactory()
+		fakeFs = fakesys.NewFakeFileSystem()
+		fakeUI = &fakebiui.FakeUI{}
+		fakeStage = fakebiui.NewFakeStage()
+		timeService = bitestutils.NewSleepingClock(time.Now())
+	})
+
+	JustBeforeEach(func() {
+		handoff = NewHandoff(config, fakeDirectorFactory, fakeUAAFactory, fakeFs, fakeUI, timeService, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("does nothing when it is not enabled", func() {
+		config.DirectorURL = ""
+		handoff = NewHandoff(config, fakeDirectorFactory, fakeUAAFactory, fakeFs, fakeUI, timeService, boshlog.NewLogger(boshlog.LevelNone))
+
+		err := handoff.Run(fakeStage)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeDirectorFactory.NewInputs).To(BeEmpty())
+		Expect(fakeStage.PerformCalls).To(BeEmpty())
+	})
+
+	It("waits for the director and prints its info", func() {
+		fakeDirectorFactory.AnonymousDirector.InfoErrs = []error{errors.New("fake-connection-error"), errors.New("fake-connection-error")}
+
+		err := handoff.Run(fakeStage)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeDirectorFactory.AnonymousDirector.InfoCalledCount).To(Equal(3))
+		Expect(fakeDirectorFactory.NewInputs[0].Config).To(Equal(bidir.Config{Host: "fake-director", Port: 25555, TaskTimeout: time.Hour}))
+		Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
+			{Name: "Waiting for the director"},
+		}))
+		Expect(fakeUI.Said).To(Equal([]string{
+			"Director name: 'fake-name'",
+			"Director UUID: 'fake-uuid'",
+			"Director version: 'fake-version'",
+		}))
+	})
+
+	It("returns an error when the director does not respond in time", func() {
+		fakeDirectorFactory.AnonymousDirector.InfoErr = errors.New("fake-connection-error")
+
+		err := handoff.Run(fakeStage)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Waiting 1m0s for the director to respond"))
+		Expect(err.Error()).To(ContainSubstring("fake-connection-error"))
+		Expect(fakeDirectorFactory.AnonymousDirector.InfoCalledCount).To(BeNumerically(">", 1))
+	})
+
+	Context("when a CA certificate is configured", func() {
+		BeforeEach(func() {
+			config.CACertPath = "/fake-ca.pem"
+			fakeFs.WriteFileString("/fake-ca.pem", "fake-ca-cert")
+		})
+
+		It("verifies the director against it", func() {
+			err := handoff.Run(fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeDirectorFactory.NewInputs[0].Config.CACert).To(Equal("fake-ca-cert"))
+		})
+
+		It("returns an error when it can not be read", func() {
+			fakeFs.ReadFileError = errors.New("fake-read-error")
+
+			err := handoff.Run(fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading director CA certificate '/fake-ca.pem'"))
+		})
+	})
+
+	Context("when the director URL is invalid", func() {
+		BeforeEach(func() {
+			config.DirectorURL = "http://fake-director"
+		})
+
+		It("returns an error", func() {
+			err := handoff.Run(fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing BOSH_INIT_DIRECTOR_URL"))
+		})
+	})
+
+	Context("when credentials are configured", func() {
+		BeforeEach(func() {
+			config.Client = "fake-client"
+			config.ClientSecret = "fake-client-secret"
+		})
+
+		It("authenticates with basic auth", func() {
+			err := handoff.Run(fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStage.PerformCalls[1].Name).To(Equal("Authenticating with the director"))
+			Expect(fakeDirectorFactory.NewInputs[1].Config).To(Equal(bidir.Config{
+				Host:        "fake-director",
+				Port:        25555,
+				Username:    "fake-client",
+				Password:    "fake-client-secret",
+				TaskTimeout: time.Hour,
+			}))
+			Expect(fakeDirectorFactory.AuthenticatedDirector.InfoCalledCount).To(Equal(1))
+		})
+
+		It("returns an error when the director does not accept the credentials", func() {
+			fakeDirectorFactory.AuthenticatedDirector.InfoInfo = bidir.Info{}
+
+			err := handoff.Run(fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Director did not accept the credentials of 'fake-client'"))
+		})
+
+		Context("when the director uses UAA", func() {
+			BeforeEach(func() {
+				config.CACertPath = "/fake-ca.pem"
+				fakeFs.WriteFileString("/fake-ca.pem", "fake-ca-cert")
+
+				fakeDirectorFactory.AnonymousDirector.InfoInfo.Auth = bidir.UserAuthentication{
+					Type:    "uaa",
+					Options: map[string]interface{}{"url": "https://fake-uaa:8443"},
+				}
+				fakeUAAFactory.NewUAA.ClientCredentialsGrantToken = biuaa.Token{Type: "bearer", Value: "fake-token"}
+			})
+
+			It("authenticates with a token granted for the client credentials", func() {
+				err := handoff.Run(fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeUAAFactory.NewConfigs).To(Equal([]biuaa.Config{{
+					Host:         "fake-uaa",
+					Port:         8443,
+					Client:       "fake-client",
+					ClientSecret: "fake-client-secret",
+					CACert:       "fake-ca-cert",
+				}}))
+
+				tokenFunc := fakeDirectorFactory.NewInputs[1].Config.TokenFunc
+				Expect(tokenFunc).ToNot(BeNil())
+
+				authHeader, err := tokenFunc(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(authHeader).To(Equal("bearer fake-token"))
+
+				_, err = tokenFunc(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUAAFactory.NewUAA.ClientCredentialsGrantCalledCount).To(Equal(1))
+
+				_, err = tokenFunc(true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeUAAFactory.NewUAA.ClientCredentialsGrantCalledCount).To(Equal(2))
+			})
+
+			It("returns an error when the token can not be granted", func() {
+				fakeUAAFactory.NewUAA.ClientCredentialsGrantErr = errors.New("fake-grant-error")
+
+				err := handoff.Run(fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = fakeDirectorFactory.NewInputs[1].Config.TokenFunc(false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-grant-error"))
+			})
+
+			It("returns an error when the UAA URL is missing", func() {
+				fakeDirectorFactory.AnonymousDirector.InfoInfo.Auth.Options = map[string]interface{}{}
+
+				err := handoff.Run(fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Parsing the UAA URL advertised by the director"))
+			})
+		})
+
+		Context("when stemcells and releases are configured", func() {
+			BeforeEach(func() {
+				config.Stemcells = []Upload{
+					{Location: "https://fake-stemcell-url", SHA1: "fake-sha1"},
+					{Location: "/fake-stemcell.tgz"},
+				}
+				config.Releases = []Upload{
+					{Location: "/fake-release.tgz"},
+					{Location: "https://fake-release-url"},
+				}
+				fakeFs.WriteFileString("/fake-stemcell.tgz", "fake-stemcell")
+				fakeFs.WriteFileString("/fake-release.tgz", "fake-release")
+			})
+
+			It("uploads them", func() {
+				err := handoff.Run(fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				director := fakeDirectorFactory.AuthenticatedDirector
+				Expect(director.UploadStemcellURLInputs).To(Equal([]fakebidir.UploadURLInput{
+					{URL: "https://fake-stemcell-url", SHA1: "fake-sha1"},
+				}))
+				Expect(director.UploadStemcellFiles).To(HaveLen(1))
+				Expect(director.UploadStemcellFiles[0].Name()).To(Equal("/fake-stemcell.tgz"))
+				Expect(director.UploadStemcellFiles[0].Size()).To(Equal(int64(len("fake-stemcell"))))
+
+				Expect(director.UploadReleaseFiles).To(HaveLen(1))
+				content, err := ioutil.ReadAll(director.UploadReleaseFiles[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(content)).To(Equal("fake-release"))
+				Expect(director.UploadReleaseURLInputs).To(Equal([]fakebidir.UploadURLInput{
+					{URL: "https://fake-release-url"},
+				}))
+
+				names := []string{}
+				for _, call := range fakeStage.PerformCalls {
+					names = append(names, call.Name)
+				}
+				Expect(names).To(Equal([]string{
+					"Waiting for the director",
+					"Authenticating with the director",
+					"Uploading stemcell 'https://fake-stemcell-url'",
+					"Uploading stemcell '/fake-stemcell.tgz'",
+					"Uploading release '/fake-release.tgz'",
+					"Uploading release 'https://fake-release-url'",
+				}))
+			})
+
+			It("reports the upload progress and the task processing it", func() {
+				err := handoff.Run(fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				reporter := fakeDirectorFactory.NewInputs[1]
+				file := fakeDirectorFactory.AuthenticatedDirector.UploadReleaseFiles[0]
+
+				// replay the upload within the stage of the last upload
+				tracked := reporter.FileReporter.TrackUpload(file.Size(), ioutil.NopCloser(file))
+				reporter.TaskReporter.TaskStarted(42)
+				_, err = ioutil.ReadAll(tracked)
+				Expect(err).ToNot(HaveOccurred())
+
+				lastCall := fakeStage.PerformCalls[len(fakeStage.PerformCalls)-1]
+				Expect(lastCall.Progress).To(ContainElement("task 42"))
+			})
+
+			It("returns an error when an upload fails", func() {
+				fakeDirectorFactory.AuthenticatedDirector.UploadReleaseErr = errors.New("fake-upload-error")
+
+				err := handoff.Run(fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-upload-error"))
+				Expect(fakeDirectorFactory.AuthenticatedDirector.UploadReleaseURLInputs).To(BeEmpty())
+			})
+		})
+	})
+})
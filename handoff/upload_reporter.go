@@ -0,0 +1,62 @@
+package handoff
+
+import (
+	"fmt"
+	"io"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// uploadReporter reports the progress of the upload being performed, and the task processing it
+type uploadReporter struct {
+	progress biui.Progress
+	logger   boshlog.Logger
+	logTag   string
+}
+
+func newUploadReporter(logger boshlog.Logger) *uploadReporter {
+	return &uploadReporter{logger: logger, logTag: "uploadReporter"}
+}
+
+func (r *uploadReporter) TaskStarted(id int) {
+	r.logger.Debug(r.logTag, "Director task '%d' started", id)
+	r.report(fmt.Sprintf("task %d", id))
+}
+
+func (r *uploadReporter) TaskFinished(id int, state string) {
+	r.logger.Debug(r.logTag, "Director task '%d' finished with state '%s'", id, state)
+}
+
+func (r *uploadReporter) TrackUpload(size int64, reader io.ReadCloser) io.ReadCloser {
+	return &progressReader{ReadCloser: reader, size: size, reporter: r}
+}
+
+func (r *uploadReporter) report(message string) {
+	if r.progress != nil {
+		r.progress.Report(message)
+	}
+}
+
+// progressReader reports every 10% of an upload
+type progressReader struct {
+	io.ReadCloser
+	size     int64
+	read     int64
+	reported int64
+	reporter *uploadReporter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+
+	if r.size > 0 {
+		if step := r.read * 10 / r.size; step > r.reported {
+			r.reported = step
+			r.reporter.report(fmt.Sprintf("%d%%", step*10))
+		}
+	}
+
+	return n, err
+}
@@ -9,6 +9,8 @@ type Manifest struct {
 	Template   ReleaseJobRef
 	Properties biproperty.Map
 	Mbus       string
+	MbusTLS    MbusTLS
+	Proxy      Proxy
 	Registry   Registry
 }
 
@@ -29,12 +31,61 @@ func (r Registry) IsEmpty() bool {
 	return r == Registry{}
 }
 
+// MbusTLS holds the PEM encoded certificates used to verify the agent (mbus_ca)
+// and, optionally, to authenticate to it (mbus_client_cert and mbus_client_key).
+type MbusTLS struct {
+	CACert     string
+	ClientCert string
+	ClientKey  string
+}
+
+func (t MbusTLS) IsEmpty() bool {
+	return t == MbusTLS{}
+}
+
 type SSHTunnel struct {
-	User       string
-	Host       string
-	Port       int
-	Password   string
-	PrivateKey string `yaml:"private_key"`
+	User                 string
+	Host                 string
+	Port                 int
+	Password             string
+	PrivateKey           string `yaml:"private_key"`
+	PrivateKeyPassphrase string `yaml:"private_key_passphrase"`
+
+	HostKey                 string `yaml:"host_key"`
+	KnownHosts              string `yaml:"known_hosts"`
+	TrustOnFirstUse         bool   `yaml:"trust_on_first_use"`
+	SkipHostKeyVerification bool   `yaml:"skip_host_key_verification"`
+
+	Proxy Proxy `yaml:"-"`
+}
+
+// Proxy routes the connections to the agent and to the SSH tunnel host
+// through a socks5:// proxy URL or through an SSH jump host.
+type Proxy struct {
+	URL      string
+	JumpHost JumpHost `yaml:"jump_host"`
+}
+
+func (p Proxy) IsEmpty() bool {
+	return p == Proxy{}
+}
+
+type JumpHost struct {
+	User                 string
+	Host                 string
+	Port                 int
+	Password             string
+	PrivateKey           string `yaml:"private_key"`
+	PrivateKeyPassphrase string `yaml:"private_key_passphrase"`
+
+	HostKey                 string `yaml:"host_key"`
+	KnownHosts              string `yaml:"known_hosts"`
+	TrustOnFirstUse         bool   `yaml:"trust_on_first_use"`
+	SkipHostKeyVerification bool   `yaml:"skip_host_key_verification"`
+}
+
+func (j JumpHost) IsEmpty() bool {
+	return j == JumpHost{}
 }
 
 func (m *Manifest) PopulateRegistry(username string, password string, host string, port int, sshTunnel SSHTunnel) {
@@ -29,10 +29,14 @@ type manifest struct {
 }
 
 type installation struct {
-	Template   template
-	Properties map[interface{}]interface{}
-	SSHTunnel  SSHTunnel `yaml:"ssh_tunnel"`
-	Mbus       string
+	Template       template
+	Properties     map[interface{}]interface{}
+	SSHTunnel      SSHTunnel `yaml:"ssh_tunnel"`
+	Mbus           string
+	MbusCA         string `yaml:"mbus_ca"`
+	MbusClientCert string `yaml:"mbus_client_cert"`
+	MbusClientKey  string `yaml:"mbus_client_key"`
+	Proxy          Proxy
 }
 
 func (i installation) HasSSHTunnel() bool {
@@ -74,6 +78,27 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 		}
 	}
 
+	if comboManifest.CloudProvider.SSHTunnel.KnownHosts != "" {
+		comboManifest.CloudProvider.SSHTunnel.KnownHosts, err = biutil.AbsolutifyPath(path, comboManifest.CloudProvider.SSHTunnel.KnownHosts, p.fs)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Expanding known_hosts path")
+		}
+	}
+
+	if comboManifest.CloudProvider.Proxy.JumpHost.PrivateKey != "" {
+		comboManifest.CloudProvider.Proxy.JumpHost.PrivateKey, err = biutil.AbsolutifyPath(path, comboManifest.CloudProvider.Proxy.JumpHost.PrivateKey, p.fs)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Expanding proxy jump_host private_key path")
+		}
+	}
+
+	if comboManifest.CloudProvider.Proxy.JumpHost.KnownHosts != "" {
+		comboManifest.CloudProvider.Proxy.JumpHost.KnownHosts, err = biutil.AbsolutifyPath(path, comboManifest.CloudProvider.Proxy.JumpHost.KnownHosts, p.fs)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Expanding proxy jump_host known_hosts path")
+		}
+	}
+
 	installationManifest := Manifest{
 		Name: comboManifest.Name,
 		Template: ReleaseJobRef{
@@ -81,6 +106,12 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 			Release: comboManifest.CloudProvider.Template.Release,
 		},
 		Mbus: comboManifest.CloudProvider.Mbus,
+		MbusTLS: MbusTLS{
+			CACert:     comboManifest.CloudProvider.MbusCA,
+			ClientCert: comboManifest.CloudProvider.MbusClientCert,
+			ClientKey:  comboManifest.CloudProvider.MbusClientKey,
+		},
+		Proxy: comboManifest.CloudProvider.Proxy,
 	}
 
 	properties, err := biproperty.BuildMap(comboManifest.CloudProvider.Properties)
@@ -94,7 +125,9 @@ func (p *parser) Parse(path string, releaseSetManifest birelsetmanifest.Manifest
 		if err != nil {
 			return Manifest{}, bosherr.WrapError(err, "Generating registry password")
 		}
-		installationManifest.PopulateRegistry("registry", password, "127.0.0.1", 6901, comboManifest.CloudProvider.SSHTunnel)
+		sshTunnel := comboManifest.CloudProvider.SSHTunnel
+		sshTunnel.Proxy = installationManifest.Proxy
+		installationManifest.PopulateRegistry("registry", password, "127.0.0.1", 6901, sshTunnel)
 	}
 
 	err = p.validator.Validate(installationManifest, releaseSetManifest)
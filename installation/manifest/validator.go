@@ -1,8 +1,14 @@
 package manifest
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"os"
 	"strings"
 
+	"golang.org/x/crypto/ssh"
+
 	birelsetmanifest "github.com/cloudfoundry/bosh-init/release/set/manifest"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -40,6 +46,12 @@ func (v *validator) Validate(manifest Manifest, releaseSetManifest birelsetmanif
 		errs = append(errs, bosherr.Errorf("cloud_provider.template.release '%s' must refer to a release in releases", cpiReleaseName))
 	}
 
+	errs = append(errs, v.validateMbusTLS(manifest)...)
+	errs = append(errs, v.validateProxy(manifest.Proxy)...)
+	if !manifest.Registry.IsEmpty() {
+		errs = append(errs, v.validateSSHTunnelHostKey(manifest.Registry.SSHTunnel)...)
+	}
+
 	if len(errs) > 0 {
 		return bosherr.NewMultiError(errs...)
 	}
@@ -47,6 +59,116 @@ func (v *validator) Validate(manifest Manifest, releaseSetManifest birelsetmanif
 	return nil
 }
 
+func (v *validator) validateMbusTLS(manifest Manifest) []error {
+	errs := []error{}
+	mbusTLS := manifest.MbusTLS
+
+	if mbusTLS.IsEmpty() {
+		return errs
+	}
+
+	mbusURL, err := url.Parse(manifest.Mbus)
+	if err != nil || mbusURL.Scheme != "https" {
+		errs = append(errs, bosherr.Error("cloud_provider.mbus must be an https URL when mbus_ca, mbus_client_cert or mbus_client_key is provided"))
+	}
+
+	if mbusTLS.CACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(mbusTLS.CACert)) {
+		errs = append(errs, bosherr.Error("cloud_provider.mbus_ca must be a PEM encoded certificate"))
+	}
+
+	if (mbusTLS.ClientCert == "") != (mbusTLS.ClientKey == "") {
+		errs = append(errs, bosherr.Error("cloud_provider.mbus_client_cert and cloud_provider.mbus_client_key must be provided together"))
+	} else if mbusTLS.ClientCert != "" {
+		_, err := tls.X509KeyPair([]byte(mbusTLS.ClientCert), []byte(mbusTLS.ClientKey))
+		if err != nil {
+			errs = append(errs, bosherr.Errorf("cloud_provider.mbus_client_cert and cloud_provider.mbus_client_key must be a PEM encoded certificate and matching private key: %s", err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateProxy(proxy Proxy) []error {
+	errs := []error{}
+
+	if proxy.URL != "" && !proxy.JumpHost.IsEmpty() {
+		return append(errs, bosherr.Error("cloud_provider.proxy must specify either url or jump_host, not both"))
+	}
+
+	if proxy.URL != "" {
+		proxyURL, err := url.Parse(proxy.URL)
+		if err != nil || proxyURL.Scheme != "socks5" || proxyURL.Host == "" {
+			errs = append(errs, bosherr.Error("cloud_provider.proxy.url must be a socks5:// URL"))
+		}
+	}
+
+	if !proxy.JumpHost.IsEmpty() {
+		if v.isBlank(proxy.JumpHost.Host) {
+			errs = append(errs, bosherr.Error("cloud_provider.proxy.jump_host.host must be provided"))
+		}
+		if proxy.JumpHost.Port <= 0 {
+			errs = append(errs, bosherr.Error("cloud_provider.proxy.jump_host.port must be provided"))
+		}
+		if v.isBlank(proxy.JumpHost.User) {
+			errs = append(errs, bosherr.Error("cloud_provider.proxy.jump_host.user must be provided"))
+		}
+		if proxy.JumpHost.Password == "" && proxy.JumpHost.PrivateKey == "" && os.Getenv("SSH_AUTH_SOCK") == "" {
+			errs = append(errs, bosherr.Error("cloud_provider.proxy.jump_host.password or cloud_provider.proxy.jump_host.private_key must be provided when no ssh-agent is running (SSH_AUTH_SOCK is not set)"))
+		}
+		if proxy.JumpHost.PrivateKeyPassphrase != "" && proxy.JumpHost.PrivateKey == "" {
+			errs = append(errs, bosherr.Error("cloud_provider.proxy.jump_host.private_key must be provided with private_key_passphrase"))
+		}
+		errs = append(errs, v.validateHostKey("cloud_provider.proxy.jump_host", proxy.JumpHost.HostKey, proxy.JumpHost.KnownHosts, proxy.JumpHost.TrustOnFirstUse, proxy.JumpHost.SkipHostKeyVerification)...)
+	}
+
+	return errs
+}
+
+func (v *validator) validateSSHTunnelHostKey(sshTunnel SSHTunnel) []error {
+	errs := []error{}
+
+	if sshTunnel.PrivateKeyPassphrase != "" && sshTunnel.PrivateKey == "" {
+		errs = append(errs, bosherr.Error("cloud_provider.ssh_tunnel.private_key must be provided with private_key_passphrase"))
+	}
+
+	errs = append(errs, v.validateHostKey("cloud_provider.ssh_tunnel", sshTunnel.HostKey, sshTunnel.KnownHosts, sshTunnel.TrustOnFirstUse, sshTunnel.SkipHostKeyVerification)...)
+
+	return errs
+}
+
+// validateHostKey checks that exactly one way of verifying the SSH host key is chosen,
+// skip_host_key_verification being the explicit opt-out
+func (v *validator) validateHostKey(field string, hostKey string, knownHosts string, trustOnFirstUse bool, skipVerification bool) []error {
+	errs := []error{}
+
+	options := 0
+	if hostKey != "" {
+		options++
+		_, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+		if err != nil {
+			errs = append(errs, bosherr.Errorf("%s.host_key must be a public key in authorized_keys format: %s", field, err.Error()))
+		}
+	}
+	if knownHosts != "" {
+		options++
+	}
+	if trustOnFirstUse {
+		options++
+	}
+	if skipVerification {
+		options++
+	}
+
+	if options == 0 {
+		errs = append(errs, bosherr.Errorf("%s must specify one of host_key, known_hosts, trust_on_first_use or skip_host_key_verification", field))
+	}
+	if options > 1 {
+		errs = append(errs, bosherr.Errorf("%s must specify only one of host_key, known_hosts, trust_on_first_use and skip_host_key_verification", field))
+	}
+
+	return errs
+}
+
 func (v *validator) isBlank(str string) bool {
 	return str == "" || strings.TrimSpace(str) == ""
 }
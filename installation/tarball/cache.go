@@ -2,45 +2,95 @@ package tarball
 
 import (
 	"crypto/sha1"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
 )
 
+const cacheIndexFileName = "index.json"
+
 type Cache interface {
 	Get(source Source) (path string, found bool)
 	Path(source Source) (path string)
+	PartialPath(source Source) (path string)
 	Save(sourcePath string, source Source) error
+
+	// Retain keeps the tarball of the source from being evicted while this process runs
+	Retain(source Source)
+	// AddRetainer keeps the tarballs of the sources listed by the retainer from being evicted.
+	// Retainers are called when evicting, after the sources they list may have been recorded.
+	AddRetainer(retainer Retainer)
+
+	List() ([]CacheEntry, error)
+	Delete(entry CacheEntry) error
+}
+
+type Retainer func() ([]Source, error)
+
+// CacheEntry is a tarball saved in the cache.
+// Tarballs saved before the cache kept an index have no URL and a zero LastUsed time.
+type CacheEntry struct {
+	Path     string    `json:"-"`
+	URL      string    `json:"url"`
+	SHA1     string    `json:"sha1"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
 }
 
 type cache struct {
-	basePath string
-	fs       boshsys.FileSystem
-	logger   boshlog.Logger
-	logTag   string
+	basePath    string
+	maxSize     int64
+	fs          boshsys.FileSystem
+	timeService clock.Clock
+	logger      boshlog.Logger
+	logTag      string
+
+	lock      sync.Mutex
+	retained  map[string]bool
+	retainers []Retainer
 }
 
-func NewCache(basePath string, fs boshsys.FileSystem, logger boshlog.Logger) Cache {
+// NewCache returns a cache of the tarballs under basePath.
+// When maxSize is positive, the least recently used tarballs are evicted on Save
+// until the cache fits in maxSize bytes, keeping the retained ones.
+func NewCache(basePath string, maxSize int64, fs boshsys.FileSystem, timeService clock.Clock, logger boshlog.Logger) Cache {
 	return &cache{
-		basePath: basePath,
-		fs:       fs,
-		logger:   logger,
-		logTag:   "tarballCache",
+		basePath:    basePath,
+		maxSize:     maxSize,
+		fs:          fs,
+		timeService: timeService,
+		logger:      logger,
+		logTag:      "tarballCache",
+		retained:    map[string]bool{},
 	}
 }
 
 func (c *cache) Get(source Source) (string, bool) {
 	cachedPath := c.Path(source)
-	if c.fs.FileExists(cachedPath) {
-		c.logger.Debug(c.logTag, "Found cached tarball at: '%s'", cachedPath)
-		return cachedPath, true
+	if !c.fs.FileExists(cachedPath) {
+		return "", false
 	}
 
-	return "", false
+	c.logger.Debug(c.logTag, "Found cached tarball at: '%s'", cachedPath)
+	c.Retain(source)
+
+	err := c.touch(cachedPath, source)
+	if err != nil {
+		c.logger.Warn(c.logTag, "Failed to record use of cached tarball '%s': %s", cachedPath, err.Error())
+	}
+
+	return cachedPath, true
 }
 
 func (c *cache) Save(sourcePath string, source Source) error {
@@ -55,11 +105,259 @@ func (c *cache) Save(sourcePath string, source Source) error {
 	}
 
 	c.logger.Debug(c.logTag, "Saving tarball in cache at: '%s'", c.Path(source))
+	c.Retain(source)
+
+	err = c.touch(c.Path(source), source)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Recording tarball '%s' in cache index", c.Path(source))
+	}
+
+	if c.maxSize > 0 {
+		err = c.evict()
+		if err != nil {
+			return bosherr.WrapError(err, "Evicting least recently used tarballs from cache")
+		}
+	}
+
 	return nil
 }
 
+func (c *cache) Retain(source Source) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.retained[c.Path(source)] = true
+}
+
+func (c *cache) AddRetainer(retainer Retainer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.retainers = append(c.retainers, retainer)
+}
+
+func (c *cache) List() ([]CacheEntry, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.list()
+}
+
+func (c *cache) Delete(entry CacheEntry) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.delete(entry)
+}
+
+// PartialPath is where the source is downloaded to before it is saved, so that an interrupted download can be resumed
+func (c *cache) PartialPath(source Source) string {
+	return c.Path(source) + ".partial"
+}
+
 func (c *cache) Path(source Source) string {
 	urlSHA1 := sha1.Sum([]byte(source.GetURL()))
-	filename := fmt.Sprintf("%x-%s", string(urlSHA1[:]), source.GetSHA1())
+	filename := fmt.Sprintf("%x-%s", string(urlSHA1[:]), c.digestKey(source.GetSHA1()))
 	return filepath.Join(c.basePath, filename)
 }
+
+// digestKey is the strongest digest of the source, so that sources listing more digests share the tarball.
+// Bare SHA1s are kept as they are to find the tarballs cached before digests had algorithms.
+func (c *cache) digestKey(multipleDigest string) string {
+	digest, err := bicrypto.ParseMultipleDigest(multipleDigest)
+	if err != nil {
+		return multipleDigest
+	}
+
+	strongest := digest.Strongest()
+	if strongest.Algorithm == bicrypto.DigestAlgorithmSHA1 {
+		return strongest.Value
+	}
+	return strongest.Algorithm + "-" + strongest.Value
+}
+
+func (c *cache) digestFromKey(key string) string {
+	for _, algorithm := range []string{bicrypto.DigestAlgorithmSHA256, bicrypto.DigestAlgorithmSHA512} {
+		if strings.HasPrefix(key, algorithm+"-") {
+			return algorithm + ":" + strings.TrimPrefix(key, algorithm+"-")
+		}
+	}
+	return key
+}
+
+// touch records the source of a cached tarball, its size and the current time as its last use
+func (c *cache) touch(cachedPath string, source Source) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	index, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := c.fs.Stat(cachedPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking size of '%s'", cachedPath)
+	}
+
+	index[filepath.Base(cachedPath)] = CacheEntry{
+		URL:      source.GetURL(),
+		SHA1:     source.GetSHA1(),
+		Size:     fileInfo.Size(),
+		LastUsed: c.timeService.Now(),
+	}
+
+	return c.writeIndex(index)
+}
+
+// evict deletes the least recently used tarballs that are not retained until the cache fits in its maximum size
+func (c *cache) evict() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entries, err := c.list()
+	if err != nil {
+		return err
+	}
+
+	retained := map[string]bool{}
+	for path := range c.retained {
+		retained[path] = true
+	}
+	for _, retainer := range c.retainers {
+		sources, err := retainer()
+		if err != nil {
+			return bosherr.WrapError(err, "Listing retained tarballs")
+		}
+		for _, source := range sources {
+			retained[c.Path(source)] = true
+		}
+	}
+
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.Size
+	}
+
+	for _, entry := range entries {
+		if totalSize <= c.maxSize {
+			break
+		}
+		if retained[entry.Path] {
+			continue
+		}
+
+		c.logger.Debug(c.logTag, "Evicting tarball '%s' last used at %s", entry.Path, entry.LastUsed)
+		err = c.delete(entry)
+		if err != nil {
+			return err
+		}
+		totalSize -= entry.Size
+	}
+
+	if totalSize > c.maxSize {
+		c.logger.Warn(c.logTag, "Cache size %d exceeds the maximum size %d with the tarballs in use", totalSize, c.maxSize)
+	}
+
+	return nil
+}
+
+// list returns the tarballs in the cache, least recently used first
+func (c *cache) list() ([]CacheEntry, error) {
+	entries := []CacheEntry{}
+	if !c.fs.FileExists(c.basePath) {
+		return entries, nil
+	}
+
+	index, err := c.readIndex()
+	if err != nil {
+		return entries, err
+	}
+
+	err = c.fs.Walk(c.basePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Base(path)
+		if info.IsDir() || filepath.Dir(path) != filepath.Clean(c.basePath) || name == cacheIndexFileName || strings.HasSuffix(name, ".partial") {
+			return nil
+		}
+
+		entry, found := index[name]
+		if !found {
+			entry = CacheEntry{Size: info.Size()}
+			if parts := strings.SplitN(name, "-", 2); len(parts) == 2 {
+				entry.SHA1 = c.digestFromKey(parts[1])
+			}
+		}
+		entry.Path = path
+
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return entries, bosherr.WrapErrorf(err, "Listing cache directory '%s'", c.basePath)
+	}
+
+	sort.Stable(cacheEntriesByLastUsed(entries))
+	return entries, nil
+}
+
+func (c *cache) delete(entry CacheEntry) error {
+	err := c.fs.RemoveAll(entry.Path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Deleting cached tarball '%s'", entry.Path)
+	}
+
+	index, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+
+	delete(index, filepath.Base(entry.Path))
+	return c.writeIndex(index)
+}
+
+func (c *cache) indexPath() string {
+	return filepath.Join(c.basePath, cacheIndexFileName)
+}
+
+func (c *cache) readIndex() (map[string]CacheEntry, error) {
+	index := map[string]CacheEntry{}
+	if !c.fs.FileExists(c.indexPath()) {
+		return index, nil
+	}
+
+	bytes, err := c.fs.ReadFile(c.indexPath())
+	if err != nil {
+		return index, bosherr.WrapErrorf(err, "Reading cache index '%s'", c.indexPath())
+	}
+
+	err = json.Unmarshal(bytes, &index)
+	if err != nil {
+		return index, bosherr.WrapErrorf(err, "Unmarshalling cache index '%s'", c.indexPath())
+	}
+
+	return index, nil
+}
+
+func (c *cache) writeIndex(index map[string]CacheEntry) error {
+	bytes, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling cache index")
+	}
+
+	err = c.fs.WriteFile(c.indexPath(), bytes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing cache index '%s'", c.indexPath())
+	}
+
+	return nil
+}
+
+type cacheEntriesByLastUsed []CacheEntry
+
+func (s cacheEntriesByLastUsed) Len() int           { return len(s) }
+func (s cacheEntriesByLastUsed) Less(i, j int) bool { return s[i].LastUsed.Before(s[j].LastUsed) }
+func (s cacheEntriesByLastUsed) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
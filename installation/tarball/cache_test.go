@@ -1,25 +1,34 @@
 package tarball_test
 
 import (
+	"errors"
+	"time"
+
 	. "github.com/cloudfoundry/bosh-init/installation/tarball"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("Cache", func() {
 	var (
-		cache Cache
-		fs    *fakesys.FakeFileSystem
+		cache           Cache
+		fs              *fakesys.FakeFileSystem
+		fakeTimeService *fakeclock.FakeClock
+		logger          boshlog.Logger
 	)
 
 	BeforeEach(func() {
-		logger := boshlog.NewLogger(boshlog.LevelNone)
+		logger = boshlog.NewLogger(boshlog.LevelNone)
 		fs = fakesys.NewFakeFileSystem()
+		fakeTimeService = fakeclock.NewFakeClock(time.Date(2016, time.March, 1, 12, 0, 0, 0, time.UTC))
 		cache = NewCache(
 			"/fake-base-path",
+			0,
 			fs,
+			fakeTimeService,
 			logger,
 		)
 	})
@@ -96,4 +105,148 @@ var _ = Describe("Cache", func() {
 		})).To(Equal("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1"))
 		Expect(fs.FileExists("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1")).To(BeTrue())
 	})
+
+	It("keys the tarballs of sources with several digests by the strongest one", func() {
+		sha256 := "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7"
+		Expect(cache.Path(NewSource("http://foo.bar.com", "sha1:4603db250d7b5b78dfe17869649784353177b549;sha256:"+sha256, "some tarball"))).To(
+			Equal("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-sha256-" + sha256))
+		Expect(cache.Path(NewSource("http://foo.bar.com", "sha1:4603db250d7b5b78dfe17869649784353177b549", "some tarball"))).To(
+			Equal("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-4603db250d7b5b78dfe17869649784353177b549"))
+	})
+
+	Describe("List", func() {
+		It("lists the saved tarballs with their source, size and last use, least recently used first", func() {
+			fs.WriteFileString("source-path", "fake-contents")
+			err := cache.Save("source-path", NewSource("http://foo.bar.com", "fake-sha1", "some tarball"))
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeTimeService.Increment(time.Hour)
+			fs.WriteFileString("other-source-path", "fake")
+			err = cache.Save("other-source-path", NewSource("http://baz.bar.com", "fake-other-sha1", "other tarball"))
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeTimeService.Increment(time.Hour)
+			_, found := cache.Get(NewSource("http://foo.bar.com", "fake-sha1", "some tarball"))
+			Expect(found).To(BeTrue())
+
+			entries, err := cache.List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]CacheEntry{
+				{
+					Path:     "/fake-base-path/1f0252cde20aabebbee3ae5f298dead475758edb-fake-other-sha1",
+					URL:      "http://baz.bar.com",
+					SHA1:     "fake-other-sha1",
+					Size:     4,
+					LastUsed: time.Date(2016, time.March, 1, 13, 0, 0, 0, time.UTC),
+				},
+				{
+					Path:     "/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1",
+					URL:      "http://foo.bar.com",
+					SHA1:     "fake-sha1",
+					Size:     13,
+					LastUsed: time.Date(2016, time.March, 1, 14, 0, 0, 0, time.UTC),
+				},
+			}))
+		})
+
+		It("lists tarballs saved before the cache had an index with the SHA1 of their name", func() {
+			fs.WriteFileString("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1", "fake-contents")
+			fs.WriteFileString("/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1.partial", "fake")
+
+			entries, err := cache.List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]CacheEntry{
+				{
+					Path: "/fake-base-path/587cd74a86333e7f1ebca70474a1f4456e4b5d3e-fake-sha1",
+					SHA1: "fake-sha1",
+					Size: 13,
+				},
+			}))
+		})
+	})
+
+	It("deletes tarballs", func() {
+		fs.WriteFileString("source-path", "fake-contents")
+		err := cache.Save("source-path", NewSource("http://foo.bar.com", "fake-sha1", "some tarball"))
+		Expect(err).ToNot(HaveOccurred())
+
+		entries, err := cache.List()
+		Expect(err).ToNot(HaveOccurred())
+
+		err = cache.Delete(entries[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		_, found := cache.Get(NewSource("http://foo.bar.com", "fake-sha1", "some tarball"))
+		Expect(found).To(BeFalse())
+
+		entries, err = cache.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	Context("when the cache has a maximum size", func() {
+		var (
+			firstSource, secondSource, thirdSource Source
+		)
+
+		save := func(c Cache, source Source, contents string) {
+			fs.WriteFileString("source-path", contents)
+			Expect(c.Save("source-path", source)).To(Succeed())
+			fakeTimeService.Increment(time.Minute)
+		}
+
+		BeforeEach(func() {
+			firstSource = NewSource("http://first", "fake-first-sha1", "first")
+			secondSource = NewSource("http://second", "fake-second-sha1", "second")
+			thirdSource = NewSource("http://third", "fake-third-sha1", "third")
+
+			// tarballs saved by a previous run are not retained
+			previousCache := NewCache("/fake-base-path", 0, fs, fakeTimeService, logger)
+			save(previousCache, firstSource, "1234")
+			save(previousCache, secondSource, "1234")
+
+			cache = NewCache("/fake-base-path", 10, fs, fakeTimeService, logger)
+		})
+
+		It("evicts the least recently used tarballs on save", func() {
+			save(cache, thirdSource, "1234")
+
+			Expect(fs.FileExists(cache.Path(firstSource))).To(BeFalse())
+			Expect(fs.FileExists(cache.Path(secondSource))).To(BeTrue())
+			Expect(fs.FileExists(cache.Path(thirdSource))).To(BeTrue())
+		})
+
+		It("keeps the tarballs used by this process", func() {
+			_, found := cache.Get(firstSource)
+			Expect(found).To(BeTrue())
+
+			save(cache, thirdSource, "1234")
+
+			Expect(fs.FileExists(cache.Path(firstSource))).To(BeTrue())
+			Expect(fs.FileExists(cache.Path(secondSource))).To(BeFalse())
+		})
+
+		It("keeps the tarballs listed by the retainers", func() {
+			cache.AddRetainer(func() ([]Source, error) {
+				return []Source{firstSource, secondSource}, nil
+			})
+
+			save(cache, thirdSource, "1234")
+
+			Expect(fs.FileExists(cache.Path(firstSource))).To(BeTrue())
+			Expect(fs.FileExists(cache.Path(secondSource))).To(BeTrue())
+			Expect(fs.FileExists(cache.Path(thirdSource))).To(BeTrue())
+		})
+
+		It("returns an error when a retainer fails", func() {
+			cache.AddRetainer(func() ([]Source, error) {
+				return nil, errors.New("fake-retainer-error")
+			})
+
+			fs.WriteFileString("source-path", "1234")
+			err := cache.Save("source-path", thirdSource)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-retainer-error"))
+		})
+	})
 })
@@ -0,0 +1,145 @@
+package tarball
+
+import (
+	"net/http"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Credentials authenticate HTTP requests with a bearer token, or else with basic auth
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+func (c Credentials) Apply(request *http.Request) {
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+		return
+	}
+	request.SetBasicAuth(c.Username, c.Password)
+}
+
+// CredentialsProvider finds the credentials for a host,
+// from the credentials set in the environment or else from the machines of a netrc file
+type CredentialsProvider interface {
+	Find(host string) (Credentials, bool, error)
+}
+
+type credentialsProvider struct {
+	credentials map[string]Credentials
+	netrcPath   string
+	fs          boshsys.FileSystem
+}
+
+func NewCredentialsProvider(credentials map[string]Credentials, netrcPath string, fs boshsys.FileSystem) CredentialsProvider {
+	return &credentialsProvider{
+		credentials: credentials,
+		netrcPath:   netrcPath,
+		fs:          fs,
+	}
+}
+
+func (p *credentialsProvider) Find(host string) (Credentials, bool, error) {
+	if credentials, found := p.credentials[host]; found {
+		return credentials, true, nil
+	}
+
+	if p.netrcPath == "" {
+		return Credentials{}, false, nil
+	}
+
+	netrcPath, err := p.fs.ExpandPath(p.netrcPath)
+	if err != nil {
+		return Credentials{}, false, bosherr.WrapErrorf(err, "Expanding netrc path '%s'", p.netrcPath)
+	}
+
+	if !p.fs.FileExists(netrcPath) {
+		return Credentials{}, false, nil
+	}
+
+	contents, err := p.fs.ReadFileString(netrcPath)
+	if err != nil {
+		return Credentials{}, false, bosherr.WrapErrorf(err, "Reading netrc file '%s'", netrcPath)
+	}
+
+	credentials, found := findNetrcMachine(contents, host)
+	return credentials, found, nil
+}
+
+// findNetrcMachine returns the login and password of the netrc machine named host, or else of the default machine
+func findNetrcMachine(contents, host string) (Credentials, bool) {
+	var (
+		machine      *Credentials
+		defaultEntry *Credentials
+		current      *Credentials
+	)
+
+	tokens := netrcTokens(contents)
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(tokens) && tokens[i+1] == host && machine == nil {
+				machine = &Credentials{}
+				current = machine
+			}
+			i++
+		case "default":
+			defaultEntry = &Credentials{}
+			current = defaultEntry
+		case "login", "password", "account":
+			if current != nil && i+1 < len(tokens) {
+				switch tokens[i] {
+				case "login":
+					current.Username = tokens[i+1]
+				case "password":
+					current.Password = tokens[i+1]
+				}
+			}
+			i++
+		case "macdef":
+			current = nil
+			i++
+		}
+	}
+
+	if machine != nil {
+		return *machine, true
+	}
+	if defaultEntry != nil {
+		return *defaultEntry, true
+	}
+	return Credentials{}, false
+}
+
+// netrcTokens splits a netrc file into tokens, leaving out the bodies of macros,
+// which follow the 'macdef name' line and run until the next blank line
+func netrcTokens(contents string) []string {
+	tokens := []string{}
+	inMacro := false
+
+	for _, line := range strings.Split(contents, "\n") {
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			tokens = append(tokens, field)
+			if field == "macdef" {
+				if i+1 < len(fields) {
+					tokens = append(tokens, fields[i+1])
+				}
+				inMacro = true
+				break
+			}
+		}
+	}
+
+	return tokens
+}
@@ -0,0 +1,113 @@
+package tarball_test
+
+import (
+	"net/http"
+
+	. "github.com/cloudfoundry/bosh-init/installation/tarball"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("CredentialsProvider", func() {
+	var (
+		fs                  *fakesys.FakeFileSystem
+		credentials         map[string]Credentials
+		credentialsProvider CredentialsProvider
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fs.ExpandPathExpanded = "/home/fake-user/.netrc"
+		credentials = map[string]Credentials{}
+		credentialsProvider = NewCredentialsProvider(credentials, "~/.netrc", fs)
+
+		err := fs.WriteFileString("/home/fake-user/.netrc", `
+machine fake-other-host login fake-other-user password fake-other-password
+machine fake-host
+  login fake-netrc-user
+  password fake-netrc-password
+  account fake-account
+default login fake-default-user password fake-default-password
+`)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("finds the credentials set for the host", func() {
+		credentials["fake-host"] = Credentials{Token: "fake-token"}
+
+		found, ok, err := credentialsProvider.Find("fake-host")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal(Credentials{Token: "fake-token"}))
+	})
+
+	It("finds the netrc machine of the host", func() {
+		found, ok, err := credentialsProvider.Find("fake-host")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal(Credentials{Username: "fake-netrc-user", Password: "fake-netrc-password"}))
+	})
+
+	It("falls back to the default netrc machine", func() {
+		found, ok, err := credentialsProvider.Find("fake-unknown-host")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal(Credentials{Username: "fake-default-user", Password: "fake-default-password"}))
+	})
+
+	It("skips the bodies of netrc macros, which run until the next blank line", func() {
+		err := fs.WriteFileString("/home/fake-user/.netrc", `machine fake-macro-host login fake-macro-user password fake-macro-password
+macdef init
+machine fake-host login fake-macro-body-user password fake-macro-body-password
+cd /pub
+
+machine fake-host login fake-netrc-user password fake-netrc-password
+`)
+		Expect(err).ToNot(HaveOccurred())
+
+		found, ok, err := credentialsProvider.Find("fake-host")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal(Credentials{Username: "fake-netrc-user", Password: "fake-netrc-password"}))
+	})
+
+	It("finds nothing when neither a machine of the host nor a default machine is in the netrc file", func() {
+		err := fs.WriteFileString("/home/fake-user/.netrc", "machine fake-other-host login fake-other-user password fake-other-password\n")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, ok, err := credentialsProvider.Find("fake-host")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("finds nothing when the netrc file does not exist", func() {
+		Expect(fs.RemoveAll("/home/fake-user/.netrc")).To(Succeed())
+
+		_, ok, err := credentialsProvider.Find("fake-host")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Credentials", func() {
+	It("sets a bearer authorization header for a token", func() {
+		request, err := http.NewRequest("GET", "https://fake-host", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Credentials{Token: "fake-token"}.Apply(request)
+		Expect(request.Header.Get("Authorization")).To(Equal("Bearer fake-token"))
+	})
+
+	It("sets basic auth for a username and password", func() {
+		request, err := http.NewRequest("GET", "https://fake-host", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Credentials{Username: "fake-user", Password: "fake-password"}.Apply(request)
+		username, password, ok := request.BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("fake-user"))
+		Expect(password).To(Equal("fake-password"))
+	})
+})
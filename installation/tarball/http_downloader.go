@@ -0,0 +1,126 @@
+package tarball
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type httpDownloader struct {
+	httpClient          bihttpclient.HTTPClient
+	credentialsProvider CredentialsProvider
+	logger              boshlog.Logger
+	logTag              string
+}
+
+// NewHTTPDownloader returns a downloader for http(s) URLs,
+// authenticating with the credentials of the URL host when there are some
+func NewHTTPDownloader(httpClient bihttpclient.HTTPClient, credentialsProvider CredentialsProvider, logger boshlog.Logger) Downloader {
+	return &httpDownloader{
+		httpClient:          httpClient,
+		credentialsProvider: credentialsProvider,
+		logger:              logger,
+		logTag:              "httpDownloader",
+	}
+}
+
+func (d *httpDownloader) Open(sourceURL string, offset int64) (Stream, error) {
+	parsedURL, err := url.Parse(sourceURL)
+	if err != nil {
+		return Stream{}, bosherr.WrapErrorf(err, "Parsing URL '%s'", sourceURL)
+	}
+
+	credentials, found, err := d.credentialsProvider.Find(parsedURL.Host)
+	if err != nil {
+		return Stream{}, bosherr.WrapErrorf(err, "Finding credentials for '%s'", parsedURL.Host)
+	}
+
+	return openRange(d.httpClient, sourceURL, offset, func(request *http.Request) {
+		if found {
+			d.logger.Debug(d.logTag, "Authenticating request to '%s'", parsedURL.Host)
+			credentials.Apply(request)
+		}
+	})
+}
+
+// openRange gets sourceURL from offset on with a Range request, calling authorize after the headers are set.
+// It falls back to the whole body when the server ignores the range.
+func openRange(httpClient bihttpclient.HTTPClient, sourceURL string, offset int64, authorize func(*http.Request)) (Stream, error) {
+	response, err := httpClient.GetCustomized(sourceURL, func(request *http.Request) {
+		if offset > 0 {
+			request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		authorize(request)
+	})
+	if err != nil {
+		return Stream{}, bosherr.WrapError(err, "Unable to download")
+	}
+
+	switch {
+	case response.StatusCode == http.StatusPartialContent:
+		start, size, err := parseContentRange(response.Header.Get("Content-Range"))
+		if err != nil {
+			_ = response.Body.Close()
+			return Stream{}, err
+		}
+		return Stream{Body: response.Body, Offset: start, Size: size}, nil
+
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		_ = response.Body.Close()
+
+		// the partial download is already complete when the offset is the size of the source
+		_, size, err := parseContentRange(response.Header.Get("Content-Range"))
+		if err == nil && size == offset {
+			return Stream{Body: ioutil.NopCloser(strings.NewReader("")), Offset: offset, Size: size}, nil
+		}
+		return openRange(httpClient, sourceURL, 0, authorize)
+
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return Stream{Body: response.Body, Offset: 0, Size: response.ContentLength}, nil
+	}
+
+	_ = response.Body.Close()
+	return Stream{}, bosherr.Errorf("Unable to download: unexpected response status '%s'", response.Status)
+}
+
+// parseContentRange returns the first byte and the total size of a 'bytes first-last/size' or 'bytes */size' header,
+// with a size of -1 when it is '*'
+func parseContentRange(contentRange string) (int64, int64, error) {
+	invalidErr := bosherr.Errorf("Unable to download: invalid Content-Range '%s'", contentRange)
+
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, 0, invalidErr
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(contentRange, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, invalidErr
+	}
+
+	size := int64(-1)
+	if parts[1] != "*" {
+		var err error
+		size, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, invalidErr
+		}
+	}
+
+	if parts[0] == "*" {
+		return 0, size, nil
+	}
+
+	start, err := strconv.ParseInt(strings.SplitN(parts[0], "-", 2)[0], 10, 64)
+	if err != nil {
+		return 0, 0, invalidErr
+	}
+
+	return start, size, nil
+}
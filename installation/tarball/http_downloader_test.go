@@ -0,0 +1,141 @@
+package tarball_test
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	. "github.com/cloudfoundry/bosh-init/installation/tarball"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("HTTPDownloader", func() {
+	var (
+		server      *ghttp.Server
+		credentials map[string]Credentials
+		downloader  Downloader
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		credentials = map[string]Credentials{}
+	})
+
+	JustBeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		httpClient := bihttpclient.NewHTTPClient(http.DefaultClient, logger)
+		downloader = NewHTTPDownloader(httpClient, NewCredentialsProvider(credentials, "", fakesys.NewFakeFileSystem()), logger)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("writes the response body", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/fake-release.tgz"),
+			func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(BeEmpty())
+			},
+			ghttp.RespondWith(http.StatusOK, "fake-body"),
+		))
+
+		stream, err := downloader.Open(server.URL()+"/fake-release.tgz", 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stream.Offset).To(Equal(int64(0)))
+		Expect(stream.Size).To(Equal(int64(9)))
+		Expect(readStream(stream)).To(Equal("fake-body"))
+	})
+
+	It("authenticates with basic auth when the host has a username and password", func() {
+		credentials[server.Addr()] = Credentials{Username: "fake-user", Password: "fake-password"}
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyBasicAuth("fake-user", "fake-password"),
+			ghttp.RespondWith(http.StatusOK, "fake-body"),
+		))
+
+		_, err := downloader.Open(server.URL()+"/fake-release.tgz", 0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("authenticates with a bearer token when the host has a token", func() {
+		credentials[server.Addr()] = Credentials{Token: "fake-token"}
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyHeaderKV("Authorization", "Bearer fake-token"),
+			ghttp.RespondWith(http.StatusOK, "fake-body"),
+		))
+
+		_, err := downloader.Open(server.URL()+"/fake-release.tgz", 0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns an error when the server responds with an error status", func() {
+		server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, ""))
+
+		_, err := downloader.Open(server.URL()+"/fake-release.tgz", 0)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unexpected response status '401 Unauthorized'"))
+	})
+
+	Context("when resuming from an offset", func() {
+		It("requests the remaining range", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyHeaderKV("Range", "bytes=5-"),
+				ghttp.RespondWith(http.StatusPartialContent, "body", http.Header{"Content-Range": {"bytes 5-8/9"}}),
+			))
+
+			stream, err := downloader.Open(server.URL()+"/fake-release.tgz", 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stream.Offset).To(Equal(int64(5)))
+			Expect(stream.Size).To(Equal(int64(9)))
+			Expect(readStream(stream)).To(Equal("body"))
+		})
+
+		It("starts over when the server ignores the range", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, "fake-body"))
+
+			stream, err := downloader.Open(server.URL()+"/fake-release.tgz", 5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stream.Offset).To(Equal(int64(0)))
+			Expect(readStream(stream)).To(Equal("fake-body"))
+		})
+
+		It("returns an empty stream when the offset is the size of the source", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusRequestedRangeNotSatisfiable, "", http.Header{"Content-Range": {"bytes */9"}}))
+
+			stream, err := downloader.Open(server.URL()+"/fake-release.tgz", 9)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stream.Offset).To(Equal(int64(9)))
+			Expect(readStream(stream)).To(BeEmpty())
+		})
+
+		It("starts over when the range is not satisfiable", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusRequestedRangeNotSatisfiable, "", http.Header{"Content-Range": {"bytes */4"}}),
+				ghttp.CombineHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.Header.Get("Range")).To(BeEmpty())
+					},
+					ghttp.RespondWith(http.StatusOK, "fake"),
+				),
+			)
+
+			stream, err := downloader.Open(server.URL()+"/fake-release.tgz", 9)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stream.Offset).To(Equal(int64(0)))
+			Expect(readStream(stream)).To(Equal("fake"))
+		})
+	})
+})
+
+func readStream(stream Stream) string {
+	defer stream.Body.Close()
+	contents, err := ioutil.ReadAll(stream.Body)
+	Expect(err).ToNot(HaveOccurred())
+	return string(contents)
+}
@@ -0,0 +1,23 @@
+package tarball
+
+import (
+	"strings"
+)
+
+// Mirror rewrites source URLs starting with From to start with To
+type Mirror struct {
+	From string
+	To   string
+}
+
+type Mirrors []Mirror
+
+// Rewrite returns the URL rewritten by the first matching mirror, or the URL itself
+func (m Mirrors) Rewrite(url string) string {
+	for _, mirror := range m {
+		if strings.HasPrefix(url, mirror.From) {
+			return mirror.To + strings.TrimPrefix(url, mirror.From)
+		}
+	}
+	return url
+}
@@ -0,0 +1,87 @@
+package tarball
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	biui "github.com/cloudfoundry/bosh-init/ui"
+	biuifmt "github.com/cloudfoundry/bosh-init/ui/fmt"
+	"github.com/pivotal-golang/clock"
+)
+
+// unknownSizeReportInterval is how many bytes are written between reports when the download size is unknown
+const unknownSizeReportInterval = 100 * 1024 * 1024
+
+// progressWriter reports every 10% of a download, or every 100 MB when its size is unknown,
+// and the throughput when the download finishes
+type progressWriter struct {
+	dst         io.Writer
+	offset      int64
+	size        int64
+	progress    biui.Progress
+	timeService clock.Clock
+
+	startTime    time.Time
+	written      int64
+	lastReported int64
+}
+
+func newProgressWriter(dst io.Writer, offset int64, size int64, progress biui.Progress, timeService clock.Clock) *progressWriter {
+	w := &progressWriter{
+		dst:         dst,
+		offset:      offset,
+		size:        size,
+		progress:    progress,
+		timeService: timeService,
+		startTime:   timeService.Now(),
+	}
+
+	w.lastReported = w.step(offset)
+	if offset > 0 {
+		w.progress.Report(fmt.Sprintf("resuming at %s", w.format(offset)))
+	}
+
+	return w
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.dst.Write(p)
+	w.written += int64(n)
+
+	if step := w.step(w.offset + w.written); step > w.lastReported {
+		w.lastReported = step
+		if w.size > 0 {
+			w.progress.Report(fmt.Sprintf("%d%%", step*10))
+		} else {
+			w.progress.Report(biuifmt.Bytes(step * unknownSizeReportInterval))
+		}
+	}
+
+	return n, err
+}
+
+// Finish reports the average throughput of the bytes written
+func (w *progressWriter) Finish() {
+	elapsed := w.timeService.Now().Sub(w.startTime)
+	if w.written == 0 || elapsed <= 0 {
+		return
+	}
+
+	w.progress.Report(fmt.Sprintf("(%s/s)", biuifmt.Bytes(int64(float64(w.written)/elapsed.Seconds()))))
+}
+
+// step is the number of completed tenths of the download, or of 100 MB chunks when the size is unknown
+func (w *progressWriter) step(position int64) int64 {
+	if w.size > 0 {
+		return position * 10 / w.size
+	}
+	return position / unknownSizeReportInterval
+}
+
+func (w *progressWriter) format(position int64) string {
+	if w.size > 0 {
+		return fmt.Sprintf("%d%%", position*100/w.size)
+	}
+	return biuifmt.Bytes(position)
+}
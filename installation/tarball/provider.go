@@ -5,16 +5,18 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
-	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
 )
 
 type Source interface {
@@ -27,6 +29,20 @@ type Provider interface {
 	Get(Source, biui.Stage) (path string, err error)
 }
 
+type source struct {
+	url         string
+	sha1        string
+	description string
+}
+
+func NewSource(url, sha1, description string) Source {
+	return source{url: url, sha1: sha1, description: description}
+}
+
+func (s source) GetURL() string      { return s.url }
+func (s source) GetSHA1() string     { return s.sha1 }
+func (s source) Description() string { return s.description }
+
 var HTTPClient = &http.Client{
 	Transport: &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
@@ -41,10 +57,12 @@ var HTTPClient = &http.Client{
 type provider struct {
 	cache            Cache
 	fs               boshsys.FileSystem
-	httpClient       bihttpclient.HTTPClient
-	sha1Calculator   bicrypto.SHA1Calculator
+	schemeRegistry   SchemeRegistry
+	mirrors          Mirrors
+	digestCalculator bicrypto.DigestCalculator
 	downloadAttempts int
 	delayTimeout     time.Duration
+	timeService      clock.Clock
 	logger           boshlog.Logger
 	logTag           string
 }
@@ -52,19 +70,23 @@ type provider struct {
 func NewProvider(
 	cache Cache,
 	fs boshsys.FileSystem,
-	httpClient bihttpclient.HTTPClient,
-	sha1Calculator bicrypto.SHA1Calculator,
+	schemeRegistry SchemeRegistry,
+	mirrors Mirrors,
+	digestCalculator bicrypto.DigestCalculator,
 	downloadAttempts int,
 	delayTimeout time.Duration,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) Provider {
 	return &provider{
 		cache:            cache,
 		fs:               fs,
-		httpClient:       httpClient,
-		sha1Calculator:   sha1Calculator,
+		schemeRegistry:   schemeRegistry,
+		mirrors:          mirrors,
+		digestCalculator: digestCalculator,
 		downloadAttempts: downloadAttempts,
 		delayTimeout:     delayTimeout,
+		timeService:      timeService,
 		logger:           logger,
 		logTag:           "tarballProvider",
 	}
@@ -84,12 +106,23 @@ func (p *provider) Get(source Source, stage biui.Stage) (string, error) {
 		return expandedPath, nil
 	}
 
-	if !strings.HasPrefix(source.GetURL(), "http") {
-		return "", bosherr.Errorf("Invalid source URL: '%s', must be either file:// or http(s)://", source.GetURL())
+	downloadURL := p.mirrors.Rewrite(source.GetURL())
+	if downloadURL != source.GetURL() {
+		p.logger.Debug(p.logTag, "Rewrote source URL '%s' to mirror '%s'", source.GetURL(), downloadURL)
+	}
+
+	downloader, found := p.schemeRegistry.Downloader(downloadURL)
+	if !found {
+		return "", bosherr.Errorf("Invalid source URL: '%s', must be either file:// or %s://", downloadURL, strings.Join(p.schemeRegistry.Schemes(), ":// or "))
+	}
+
+	digest, err := bicrypto.ParseMultipleDigest(source.GetSHA1())
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Parsing digest of '%s'", source.GetURL())
 	}
 
 	var cachedPath string
-	err := stage.Perform(fmt.Sprintf("Downloading %s", source.Description()), func() error {
+	err = stage.PerformWithProgress(fmt.Sprintf("Downloading %s", source.Description()), func(progress biui.Progress) error {
 		var found bool
 		cachedPath, found = p.cache.Get(source)
 		if found {
@@ -97,10 +130,10 @@ func (p *provider) Get(source Source, stage biui.Stage) (string, error) {
 			return biui.NewSkipStageError(bosherr.Error("Already downloaded"), "Found in local cache")
 		}
 
-		retryStrategy := boshretry.NewAttemptRetryStrategy(p.downloadAttempts, p.delayTimeout, p.downloadRetryable(source), p.logger)
+		retryStrategy := boshretry.NewAttemptRetryStrategy(p.downloadAttempts, p.delayTimeout, p.downloadRetryable(source, digest, downloader, downloadURL, progress), p.logger)
 		err := retryStrategy.Try()
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Failed to download from '%s'", source.GetURL())
+			return bosherr.WrapErrorf(err, "Failed to download from '%s'", downloadURL)
 		}
 
 		p.logger.Debug(p.logTag, "Using the downloaded tarball: '%s'", cachedPath)
@@ -114,47 +147,94 @@ func (p *provider) Get(source Source, stage biui.Stage) (string, error) {
 	return p.cache.Path(source), nil
 }
 
-func (p *provider) downloadRetryable(source Source) boshretry.Retryable {
+func (p *provider) downloadRetryable(source Source, digest bicrypto.MultipleDigest, downloader Downloader, downloadURL string, progress biui.Progress) boshretry.Retryable {
 	return boshretry.NewRetryable(func() (bool, error) {
-		downloadedFile, err := p.fs.TempFile("tarballProvider")
+		partialPath := p.cache.PartialPath(source)
+
+		err := p.download(downloader, downloadURL, partialPath, progress)
 		if err != nil {
-			return true, bosherr.WrapError(err, "Unable to create temporary file")
+			// keep the partial download to resume from it on the next attempt
+			return true, err
 		}
-		defer func() {
-			if err = p.fs.RemoveAll(downloadedFile.Name()); err != nil {
-				p.logger.Warn(p.logTag, "Failed to remove downloaded file: %s", err.Error())
-			}
-		}()
 
-		response, err := p.httpClient.Get(source.GetURL())
+		err = digest.Verify(p.digestCalculator, partialPath)
 		if err != nil {
-			return true, bosherr.WrapError(err, "Unable to download")
+			p.removePartial(partialPath)
+			return true, bosherr.WrapError(err, "Verifying downloaded file")
 		}
-		defer func() {
-			if err = response.Body.Close(); err != nil {
-				p.logger.Warn(p.logTag, "Failed to close download response body: %s", err.Error())
-			}
-		}()
 
-		_, err = io.Copy(downloadedFile, response.Body)
+		err = p.cache.Save(partialPath, source)
 		if err != nil {
-			return true, bosherr.WrapError(err, "Saving downloaded bits to temporary file")
+			p.removePartial(partialPath)
+			return true, bosherr.WrapError(err, "Saving downloaded file in cache")
 		}
 
-		downloadedSha1, err := p.sha1Calculator.Calculate(downloadedFile.Name())
+		return false, nil
+	})
+}
+
+// download appends the bits of downloadURL to the partial file, resuming from its size
+func (p *provider) download(downloader Downloader, downloadURL string, partialPath string, progress biui.Progress) error {
+	err := p.fs.MkdirAll(filepath.Dir(partialPath), os.FileMode(0766))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating download directory '%s'", filepath.Dir(partialPath))
+	}
+
+	var offset int64
+	if p.fs.FileExists(partialPath) {
+		fileInfo, err := p.fs.Stat(partialPath)
 		if err != nil {
-			return true, bosherr.WrapError(err, "Calculating sha1 for downloaded file")
+			return bosherr.WrapErrorf(err, "Checking partial download '%s'", partialPath)
 		}
+		offset = fileInfo.Size()
+	}
 
-		if downloadedSha1 != source.GetSHA1() {
-			return true, bosherr.Errorf("SHA1 of downloaded file '%s' does not match expected SHA1 '%s'", downloadedSha1, source.GetSHA1())
+	stream, err := downloader.Open(downloadURL, offset)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := stream.Body.Close(); err != nil {
+			p.logger.Warn(p.logTag, "Failed to close download stream: %s", err.Error())
 		}
+	}()
 
-		err = p.cache.Save(downloadedFile.Name(), source)
-		if err != nil {
-			return true, bosherr.WrapError(err, "Saving downloaded file in cache")
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	switch stream.Offset {
+	case offset:
+		if offset > 0 {
+			p.logger.Debug(p.logTag, "Resuming download of '%s' at byte %d", downloadURL, offset)
 		}
+	case 0:
+		p.logger.Debug(p.logTag, "Restarting download of '%s', the source can't resume at byte %d", downloadURL, offset)
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	default:
+		p.removePartial(partialPath)
+		return bosherr.Errorf("Unable to download: requested to resume at byte %d, but the source resumed at byte %d", offset, stream.Offset)
+	}
 
-		return false, nil
-	})
+	partialFile, err := p.fs.OpenFile(partialPath, flags, os.FileMode(0644))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening partial download '%s'", partialPath)
+	}
+	defer func() {
+		if err := partialFile.Close(); err != nil {
+			p.logger.Warn(p.logTag, "Failed to close partial download: %s", err.Error())
+		}
+	}()
+
+	progressWriter := newProgressWriter(partialFile, stream.Offset, stream.Size, progress, p.timeService)
+	_, err = io.Copy(progressWriter, stream.Body)
+	progressWriter.Finish()
+	if err != nil {
+		return bosherr.WrapError(err, "Saving downloaded bits to partial file")
+	}
+
+	return nil
+}
+
+func (p *provider) removePartial(partialPath string) {
+	if err := p.fs.RemoveAll(partialPath); err != nil {
+		p.logger.Warn(p.logTag, "Failed to remove partial download: %s", err.Error())
+	}
 }
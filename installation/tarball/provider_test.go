@@ -2,8 +2,10 @@ package tarball_test
 
 import (
 	"errors"
+	"io"
 	"io/ioutil"
-	"os"
+	"strings"
+	"time"
 
 	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
 	. "github.com/cloudfoundry/bosh-init/installation/tarball"
@@ -13,29 +15,40 @@ import (
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("Provider", func() {
 	var (
-		provider       Provider
-		cache          Cache
-		fs             *fakesys.FakeFileSystem
-		httpClient     *fakebihttpclient.FakeHTTPClient
-		sha1Calculator *fakebicrypto.FakeSha1Calculator
-		source         *fakeSource
-		fakeStage      *fakebiui.FakeStage
+		provider         Provider
+		cache            Cache
+		fs               *fakesys.FakeFileSystem
+		httpClient       *fakebihttpclient.FakeHTTPClient
+		digestCalculator *fakebicrypto.FakeDigestCalculator
+		schemeRegistry   SchemeRegistry
+		mirrors          Mirrors
+		source           *fakeSource
+		fakeStage        *fakebiui.FakeStage
+		logger           boshlog.Logger
 	)
 
 	BeforeEach(func() {
 		fs = fakesys.NewFakeFileSystem()
-		logger := boshlog.NewLogger(boshlog.LevelNone)
-		cache = NewCache("/fake-base-path", fs, logger)
-		sha1Calculator = fakebicrypto.NewFakeSha1Calculator()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		cache = NewCache("/fake-base-path", 0, fs, fakeclock.NewFakeClock(time.Now()), logger)
+		digestCalculator = fakebicrypto.NewFakeDigestCalculator()
 		httpClient = fakebihttpclient.NewFakeHTTPClient()
-		provider = NewProvider(cache, fs, httpClient, sha1Calculator, 3, 0, logger)
+		schemeRegistry = NewSchemeRegistry()
+		schemeRegistry.Register("http", NewHTTPDownloader(httpClient, NewCredentialsProvider(nil, "", fs), logger))
+		schemeRegistry.Register("https", NewHTTPDownloader(httpClient, NewCredentialsProvider(nil, "", fs), logger))
+		mirrors = nil
 		fakeStage = fakebiui.NewFakeStage()
 	})
 
+	JustBeforeEach(func() {
+		provider = NewProvider(cache, fs, schemeRegistry, mirrors, digestCalculator, 3, 0, fakeclock.NewFakeClock(time.Now()), logger)
+	})
+
 	Describe("Get", func() {
 		Context("when URL starts with file://", func() {
 			BeforeEach(func() {
@@ -79,23 +92,16 @@ var _ = Describe("Provider", func() {
 
 			Context("when tarball is not present in cache", func() {
 				var (
-					tempDownloadFilePath string
+					partialPath string
 				)
 
 				BeforeEach(func() {
-					tempDownloadFile, err := ioutil.TempFile("", "temp-download-file")
-					Expect(err).ToNot(HaveOccurred())
-					fs.ReturnTempFile = tempDownloadFile
-					tempDownloadFilePath = tempDownloadFile.Name()
-					sha1Calculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateInput{
-						tempDownloadFilePath: {Sha1: "fake-sha1"},
+					partialPath = "/fake-base-path/9db1fb7c47637e8709e944a232e1aa98ce6fec26-fake-sha1.partial"
+					digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+						partialPath: {Value: "fake-sha1"},
 					})
 				})
 
-				AfterEach(func() {
-					os.RemoveAll(tempDownloadFilePath)
-				})
-
 				Context("when downloading succeds", func() {
 					BeforeEach(func() {
 						httpClient.SetGetBehavior("fake-body", 200, nil)
@@ -112,19 +118,67 @@ var _ = Describe("Provider", func() {
 						Expect(httpClient.GetInputs[0].Endpoint).To(Equal("http://fake-url"))
 					})
 
+					It("moves the partial download into the cache", func() {
+						_, err := provider.Get(source, fakeStage)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(fs.FileExists(partialPath)).To(BeFalse())
+						contents, err := fs.ReadFileString("/fake-base-path/9db1fb7c47637e8709e944a232e1aa98ce6fec26-fake-sha1")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(contents).To(Equal("fake-body"))
+					})
+
 					It("logs downloading stage", func() {
 						_, err := provider.Get(source, fakeStage)
 						Expect(err).ToNot(HaveOccurred())
 
-						Expect(fakeStage.PerformCalls).To(Equal([]*fakebiui.PerformCall{
-							{Name: "Downloading fake-description"},
-						}))
+						Expect(fakeStage.PerformCalls).To(HaveLen(1))
+						Expect(fakeStage.PerformCalls[0].Name).To(Equal("Downloading fake-description"))
+						Expect(fakeStage.PerformCalls[0].Error).ToNot(HaveOccurred())
+					})
+
+					Context("when the source has SHA256 and SHA1 digests", func() {
+						var sha256 string
+
+						BeforeEach(func() {
+							sha256 = "2b3c7b1eb1f4c5fed8cd7f4bcbb1d3e8e4a5ad77b2bab3cd04bd2d8a4cc47ed1"
+							source = newFakeSource("http://fake-url", "fake-sha1;sha256:"+sha256, "fake-description")
+							digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+								"/fake-base-path/9db1fb7c47637e8709e944a232e1aa98ce6fec26-sha256-" + sha256 + ".partial": {Value: sha256},
+							})
+						})
+
+						It("verifies every digest", func() {
+							_, err := provider.Get(source, fakeStage)
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("SHA1 '" + sha256 + "' does not match expected SHA1 'fake-sha1'"))
+						})
+
+						It("caches the tarball under the strongest digest", func() {
+							source = newFakeSource("http://fake-url", "sha256:"+sha256, "fake-description")
+							path, err := provider.Get(source, fakeStage)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(path).To(Equal("/fake-base-path/9db1fb7c47637e8709e944a232e1aa98ce6fec26-sha256-" + sha256))
+						})
+					})
+
+					Context("when the digest is invalid", func() {
+						BeforeEach(func() {
+							source = newFakeSource("http://fake-url", "md5:fake-md5", "fake-description")
+						})
+
+						It("returns an error without downloading", func() {
+							_, err := provider.Get(source, fakeStage)
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("Parsing digest of 'http://fake-url': Unsupported digest algorithm 'md5'"))
+							Expect(httpClient.GetInputs).To(BeEmpty())
+						})
 					})
 
 					Context("when sha1 does not match", func() {
 						BeforeEach(func() {
-							sha1Calculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateInput{
-								tempDownloadFilePath: {Sha1: "fake-sha2"},
+							digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+								partialPath: {Value: "fake-sha2"},
 							})
 						})
 
@@ -144,27 +198,71 @@ var _ = Describe("Provider", func() {
 						It("removes the downloaded file", func() {
 							_, err := provider.Get(source, fakeStage)
 							Expect(err).To(HaveOccurred())
-							Expect(fs.FileExists(tempDownloadFilePath)).To(BeFalse())
+							Expect(fs.FileExists(partialPath)).To(BeFalse())
 						})
 					})
 
 					Context("when saving to cache fails", func() {
 						BeforeEach(func() {
-							// Creating cache base directory fails
-							fs.MkdirAllError = errors.New("fake-mkdir-error")
+							fs.RenameError = errors.New("fake-rename-error")
 						})
 
 						It("returns an error", func() {
 							_, err := provider.Get(source, fakeStage)
 							Expect(err).To(HaveOccurred())
-							Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
+							Expect(err.Error()).To(ContainSubstring("fake-rename-error"))
 						})
 
 						It("removes the downloaded file", func() {
 							_, err := provider.Get(source, fakeStage)
 							Expect(err).To(HaveOccurred())
-							Expect(fs.FileExists(tempDownloadFilePath)).To(BeFalse())
+							Expect(fs.FileExists(partialPath)).To(BeFalse())
+						})
+					})
+
+					Context("when creating the download directory fails", func() {
+						BeforeEach(func() {
+							fs.MkdirAllError = errors.New("fake-mkdir-error")
 						})
+
+						It("returns an error", func() {
+							_, err := provider.Get(source, fakeStage)
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
+						})
+					})
+				})
+
+				Context("when a mirror matches the URL", func() {
+					BeforeEach(func() {
+						mirrors = Mirrors{
+							{From: "http://other-url", To: "http://other-mirror"},
+							{From: "http://fake-url", To: "https://fake-mirror"},
+						}
+						httpClient.SetGetBehavior("fake-body", 200, nil)
+					})
+
+					It("downloads from the mirror and caches the tarball under the original URL", func() {
+						path, err := provider.Get(source, fakeStage)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(path).To(Equal("/fake-base-path/9db1fb7c47637e8709e944a232e1aa98ce6fec26-fake-sha1"))
+
+						Expect(httpClient.GetInputs).To(HaveLen(1))
+						Expect(httpClient.GetInputs[0].Endpoint).To(Equal("https://fake-mirror"))
+					})
+				})
+
+				Context("when the server responds with an error status", func() {
+					BeforeEach(func() {
+						httpClient.SetGetBehavior("", 404, nil)
+						httpClient.SetGetBehavior("", 404, nil)
+						httpClient.SetGetBehavior("", 404, nil)
+					})
+
+					It("returns an error", func() {
+						_, err := provider.Get(source, fakeStage)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("unexpected response status"))
 					})
 				})
 
@@ -182,14 +280,94 @@ var _ = Describe("Provider", func() {
 
 						Expect(httpClient.GetInputs).To(HaveLen(3))
 					})
+				})
+			})
+		})
 
-					It("removes the downloaded file", func() {
-						_, err := provider.Get(source, fakeStage)
-						Expect(err).To(HaveOccurred())
-						Expect(fs.FileExists(tempDownloadFilePath)).To(BeFalse())
-					})
+		Context("when the download is interrupted", func() {
+			var (
+				downloader  *fakeDownloader
+				partialPath string
+			)
+
+			BeforeEach(func() {
+				source = newFakeSource("s3://fake-bucket/fake-key", "fake-sha1", "fake-description")
+				partialPath = "/fake-base-path/68e3a719d645505ce7897674fedb3f8ce5e7e742-fake-sha1.partial"
+				downloader = &fakeDownloader{}
+				schemeRegistry.Register("s3", downloader)
+				digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+					partialPath: {Value: "fake-sha1"},
 				})
 			})
+
+			It("resumes from the partial download of the previous attempt", func() {
+				downloader.Streams = []fakeStreamResult{
+					{Body: "fake-", Size: 10, ReadErr: errors.New("fake-connection-reset")},
+					{Body: "body!", Offset: 5, Size: 10},
+				}
+
+				_, err := provider.Get(source, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(downloader.Offsets).To(Equal([]int64{0, 5}))
+			})
+
+			It("resumes from a partial download left by a previous run", func() {
+				Expect(fs.WriteFileString(partialPath, "fake-")).To(Succeed())
+				downloader.Streams = []fakeStreamResult{{Body: "body!", Offset: 5, Size: 10}}
+
+				_, err := provider.Get(source, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(downloader.Offsets).To(Equal([]int64{5}))
+				Expect(fakeStage.PerformCalls[0].Progress).To(Equal([]string{"resuming at 50%", "100%"}))
+			})
+
+			It("starts over when the source can't resume", func() {
+				Expect(fs.WriteFileString(partialPath, "fake-")).To(Succeed())
+				downloader.Streams = []fakeStreamResult{{Body: "fake-body!", Offset: 0, Size: 10}}
+
+				_, err := provider.Get(source, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(downloader.Offsets).To(Equal([]int64{5}))
+				Expect(fakeStage.PerformCalls[0].Progress).To(Equal([]string{"100%"}))
+			})
+
+			It("returns an error and removes the partial download when the source resumes at another offset", func() {
+				Expect(fs.WriteFileString(partialPath, "fake-")).To(Succeed())
+				downloader.Streams = []fakeStreamResult{
+					{Body: "body!", Offset: 3, Size: 10},
+					{Body: "body!", Offset: 3, Size: 10},
+					{Body: "body!", Offset: 3, Size: 10},
+				}
+
+				_, err := provider.Get(source, fakeStage)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("but the source resumed at byte 3"))
+				Expect(fs.FileExists(partialPath)).To(BeFalse())
+
+				Expect(downloader.Offsets).To(Equal([]int64{5, 0, 0}))
+			})
+		})
+
+		Context("when URL uses a registered scheme", func() {
+			var downloader *fakeDownloader
+
+			BeforeEach(func() {
+				source = newFakeSource("s3://fake-bucket/fake-key", "fake-sha1", "fake-description")
+				downloader = &fakeDownloader{Streams: []fakeStreamResult{{Body: "fake-body", Size: -1}}}
+				schemeRegistry.Register("s3", downloader)
+				digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+					"/fake-base-path/68e3a719d645505ce7897674fedb3f8ce5e7e742-fake-sha1.partial": {Value: "fake-sha1"},
+				})
+			})
+
+			It("downloads the tarball with the downloader of the scheme", func() {
+				_, err := provider.Get(source, fakeStage)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(downloader.URLs).To(Equal([]string{"s3://fake-bucket/fake-key"}))
+			})
 		})
 
 		Context("when URL does not start with either file:// or http(s)://", func() {
@@ -200,7 +378,7 @@ var _ = Describe("Provider", func() {
 			It("returns an error", func() {
 				_, err := provider.Get(source, fakeStage)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("Invalid source URL: 'invalid-url'"))
+				Expect(err.Error()).To(ContainSubstring("Invalid source URL: 'invalid-url', must be either file:// or http:// or https://"))
 			})
 		})
 	})
@@ -219,3 +397,38 @@ func newFakeSource(url, sha1, description string) *fakeSource {
 func (s *fakeSource) GetURL() string      { return s.url }
 func (s *fakeSource) GetSHA1() string     { return s.sha1 }
 func (s *fakeSource) Description() string { return s.description }
+
+type fakeDownloader struct {
+	URLs    []string
+	Offsets []int64
+	Streams []fakeStreamResult
+}
+
+type fakeStreamResult struct {
+	Body    string
+	Offset  int64
+	Size    int64
+	ReadErr error
+}
+
+func (d *fakeDownloader) Open(url string, offset int64) (Stream, error) {
+	d.URLs = append(d.URLs, url)
+	d.Offsets = append(d.Offsets, offset)
+
+	result := d.Streams[0]
+	d.Streams = d.Streams[1:]
+
+	var body io.Reader = strings.NewReader(result.Body)
+	if result.ReadErr != nil {
+		body = io.MultiReader(body, &erroringReader{err: result.ReadErr})
+	}
+	return Stream{Body: ioutil.NopCloser(body), Offset: result.Offset, Size: result.Size}, nil
+}
+
+type erroringReader struct {
+	err error
+}
+
+func (r *erroringReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,189 @@
+package tarball
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	bihttpclient "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+const emptyPayloadSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config is the account used to download s3:// URLs.
+// Objects are downloaded anonymously when there is no access key.
+type S3Config struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	// Endpoint replaces the AWS endpoint of the region, for S3 compatible stores
+	Endpoint string
+}
+
+type s3Downloader struct {
+	httpClient  bihttpclient.HTTPClient
+	config      S3Config
+	timeService clock.Clock
+	logger      boshlog.Logger
+	logTag      string
+}
+
+// NewS3Downloader returns a downloader for s3://bucket/key URLs, signing requests with AWS signature version 4
+func NewS3Downloader(httpClient bihttpclient.HTTPClient, config S3Config, timeService clock.Clock, logger boshlog.Logger) Downloader {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	return &s3Downloader{
+		httpClient:  httpClient,
+		config:      config,
+		timeService: timeService,
+		logger:      logger,
+		logTag:      "s3Downloader",
+	}
+}
+
+func (d *s3Downloader) Open(sourceURL string, offset int64) (Stream, error) {
+	objectURL, err := d.objectURL(sourceURL)
+	if err != nil {
+		return Stream{}, err
+	}
+
+	d.logger.Debug(d.logTag, "Downloading '%s' from '%s'", sourceURL, objectURL)
+
+	return openRange(d.httpClient, objectURL, offset, func(request *http.Request) {
+		if d.config.AccessKeyID != "" {
+			SignS3Request(request, d.config, d.timeService.Now())
+		}
+	})
+}
+
+// objectURL returns the path-style https URL of the object of an s3://bucket/key URL
+func (d *s3Downloader) objectURL(sourceURL string) (string, error) {
+	parsedURL, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Parsing URL '%s'", sourceURL)
+	}
+
+	bucket := parsedURL.Host
+	key := strings.TrimPrefix(parsedURL.Path, "/")
+	if bucket == "" || key == "" {
+		return "", bosherr.Errorf("Invalid S3 URL '%s', must be s3://bucket/key", sourceURL)
+	}
+
+	endpoint := d.config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", d.config.Region)
+	}
+
+	return strings.TrimSuffix(endpoint, "/") + "/" + awsEscape(bucket, false) + "/" + awsEscape(key, false), nil
+}
+
+// SignS3Request adds an AWS signature version 4 Authorization header to a request without a body.
+// The host, range and x-amz-* headers are signed.
+func SignS3Request(request *http.Request, config S3Config, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", emptyPayloadSHA256)
+	if config.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", config.SessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		lowerName := strings.ToLower(name)
+		if lowerName == "range" || strings.HasPrefix(lowerName, "x-amz-") {
+			headers[lowerName] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	headerNames := []string{}
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	canonicalHeaders := ""
+	for _, name := range headerNames {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadSHA256,
+	}, "\n")
+
+	scope := strings.Join([]string{date, config.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	for _, part := range []string{config.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func canonicalQuery(query url.Values) string {
+	params := []string{}
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters, and '/' unless encodeSlash is set
+func awsEscape(value string, encodeSlash bool) string {
+	escaped := ""
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/' && !encodeSlash:
+			escaped += string(b)
+		default:
+			escaped += fmt.Sprintf("%%%02X", b)
+		}
+	}
+	return escaped
+}
+
+func hexSHA256(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,61 @@
+package tarball
+
+import (
+	"io"
+	"net/url"
+	"sort"
+)
+
+// Stream is the body of a download opened from a byte offset
+type Stream struct {
+	Body io.ReadCloser
+	// Offset is the byte the body starts at, 0 when the source can't resume from the requested offset
+	Offset int64
+	// Size is the total size of the source, -1 when unknown
+	Size int64
+}
+
+// Downloader opens the contents of a source URL from a byte offset on
+type Downloader interface {
+	Open(url string, offset int64) (Stream, error)
+}
+
+// SchemeRegistry maps source URL schemes to the downloaders that fetch them
+type SchemeRegistry interface {
+	Register(scheme string, downloader Downloader)
+	Downloader(url string) (Downloader, bool)
+	Schemes() []string
+}
+
+type schemeRegistry struct {
+	downloaders map[string]Downloader
+}
+
+func NewSchemeRegistry() SchemeRegistry {
+	return &schemeRegistry{
+		downloaders: map[string]Downloader{},
+	}
+}
+
+func (r *schemeRegistry) Register(scheme string, downloader Downloader) {
+	r.downloaders[scheme] = downloader
+}
+
+func (r *schemeRegistry) Downloader(sourceURL string) (Downloader, bool) {
+	parsedURL, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil, false
+	}
+
+	downloader, found := r.downloaders[parsedURL.Scheme]
+	return downloader, found
+}
+
+func (r *schemeRegistry) Schemes() []string {
+	schemes := []string{}
+	for scheme := range r.downloaders {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
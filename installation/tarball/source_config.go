@@ -0,0 +1,200 @@
+package tarball
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// DefaultMaxConcurrentDownloads is the number of releases and stemcells fetched at a time by default
+const DefaultMaxConcurrentDownloads = 4
+
+// SourceConfig configures how tarballs are downloaded
+type SourceConfig struct {
+	// CACertPath is a PEM bundle trusted in addition to the system CAs for https downloads
+	CACertPath  string
+	Mirrors     Mirrors
+	Credentials map[string]Credentials
+	NetrcPath   string
+	S3          S3Config
+	// CacheMaxSize is the size in bytes the download cache is kept under, 0 when it is unbounded
+	CacheMaxSize int64
+	// TrustedKeysPath is a file or directory of the public keys that must sign releases and stemcells
+	TrustedKeysPath string
+	// MaxConcurrentDownloads is the number of releases and stemcells downloaded and extracted at a time
+	MaxConcurrentDownloads int
+}
+
+// NewSourceConfigFromEnv reads the source config from the environment:
+// BOSH_INIT_CA_CERT, BOSH_INIT_MIRRORS ('from=to' rules separated by commas),
+// BOSH_INIT_HTTP_AUTH ('host=token:<token>' or 'host=basic:<user>:<password>' entries separated by commas),
+// NETRC (defaulting to ~/.netrc), and AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN,
+// AWS_REGION (or AWS_DEFAULT_REGION) and BOSH_INIT_S3_ENDPOINT for s3:// URLs,
+// BOSH_INIT_CACHE_MAX_SIZE (e.g. '20G') for the download cache,
+// BOSH_INIT_TRUSTED_KEYS for the keys release and stemcell signatures are verified against,
+// and BOSH_INIT_MAX_CONCURRENT_DOWNLOADS (defaulting to DefaultMaxConcurrentDownloads)
+func NewSourceConfigFromEnv(getenv func(string) string) (SourceConfig, error) {
+	config := SourceConfig{
+		CACertPath:  getenv("BOSH_INIT_CA_CERT"),
+		Mirrors:     Mirrors{},
+		Credentials: map[string]Credentials{},
+		NetrcPath:   getenv("NETRC"),
+		S3: S3Config{
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+			Region:          getenv("AWS_REGION"),
+			Endpoint:        getenv("BOSH_INIT_S3_ENDPOINT"),
+		},
+		TrustedKeysPath:        getenv("BOSH_INIT_TRUSTED_KEYS"),
+		MaxConcurrentDownloads: DefaultMaxConcurrentDownloads,
+	}
+
+	if config.NetrcPath == "" {
+		config.NetrcPath = "~/.netrc"
+	}
+
+	if config.S3.Region == "" {
+		config.S3.Region = getenv("AWS_DEFAULT_REGION")
+	}
+
+	for _, rule := range splitList(getenv("BOSH_INIT_MIRRORS")) {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return SourceConfig{}, bosherr.Errorf("Invalid BOSH_INIT_MIRRORS rule '%s', must be 'from=to'", rule)
+		}
+		config.Mirrors = append(config.Mirrors, Mirror{From: parts[0], To: parts[1]})
+	}
+
+	for _, entry := range splitList(getenv("BOSH_INIT_HTTP_AUTH")) {
+		host, credentials, err := parseHTTPAuthEntry(entry)
+		if err != nil {
+			return SourceConfig{}, err
+		}
+		config.Credentials[host] = credentials
+	}
+
+	if maxSize := getenv("BOSH_INIT_CACHE_MAX_SIZE"); maxSize != "" {
+		var err error
+		config.CacheMaxSize, err = ParseSize(maxSize)
+		if err != nil {
+			return SourceConfig{}, bosherr.WrapError(err, "Invalid BOSH_INIT_CACHE_MAX_SIZE")
+		}
+	}
+
+	if maxConcurrentDownloads := getenv("BOSH_INIT_MAX_CONCURRENT_DOWNLOADS"); maxConcurrentDownloads != "" {
+		var err error
+		config.MaxConcurrentDownloads, err = strconv.Atoi(maxConcurrentDownloads)
+		if err != nil || config.MaxConcurrentDownloads < 1 {
+			return SourceConfig{}, bosherr.Errorf("Invalid BOSH_INIT_MAX_CONCURRENT_DOWNLOADS '%s', must be a positive number", maxConcurrentDownloads)
+		}
+	}
+
+	return config, nil
+}
+
+// ParseSize parses a byte count with an optional K, M, G or T binary unit suffix, e.g. '512M'
+func ParseSize(size string) (int64, error) {
+	units := map[string]int64{
+		"K": 1024,
+		"M": 1024 * 1024,
+		"G": 1024 * 1024 * 1024,
+		"T": 1024 * 1024 * 1024 * 1024,
+	}
+
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	multiplier := int64(1)
+	if len(value) > 0 {
+		if unitMultiplier, found := units[value[len(value)-1:]]; found {
+			multiplier = unitMultiplier
+			value = value[:len(value)-1]
+		}
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return 0, bosherr.Errorf("Size '%s' must be a number of bytes with an optional K, M, G or T suffix", size)
+	}
+
+	return number * multiplier, nil
+}
+
+// NewHTTPClient returns the client used for http(s) downloads,
+// trusting the PEM bundle at caCertPath in addition to the system CAs when it is set
+func NewHTTPClient(caCertPath string, fs boshsys.FileSystem) (*http.Client, error) {
+	if caCertPath == "" {
+		return HTTPClient, nil
+	}
+
+	caCert, err := fs.ReadFile(caCertPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading CA certificate '%s'", caCertPath)
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, bosherr.Errorf("Parsing CA certificate '%s': no PEM certificates found", caCertPath)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			Dial: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 0 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     &tls.Config{RootCAs: rootCAs},
+		},
+	}, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseHTTPAuthEntry parses a 'host=token:<token>' or 'host=basic:<user>:<password>' entry.
+// The token and password are taken as is, so they may contain ':' themselves.
+func parseHTTPAuthEntry(entry string) (string, Credentials, error) {
+	invalidErr := bosherr.Errorf("Invalid BOSH_INIT_HTTP_AUTH entry '%s', must be 'host=token:<token>' or 'host=basic:<user>:<password>'", entry)
+
+	parts := strings.SplitN(entry, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", Credentials{}, invalidErr
+	}
+
+	host, value := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(value, "token:"):
+		token := strings.TrimPrefix(value, "token:")
+		if token == "" {
+			return "", Credentials{}, invalidErr
+		}
+		return host, Credentials{Token: token}, nil
+	case strings.HasPrefix(value, "basic:"):
+		userAndPassword := strings.SplitN(strings.TrimPrefix(value, "basic:"), ":", 2)
+		if len(userAndPassword) != 2 || userAndPassword[0] == "" {
+			return "", Credentials{}, invalidErr
+		}
+		return host, Credentials{Username: userAndPassword[0], Password: userAndPassword[1]}, nil
+	default:
+		return "", Credentials{}, invalidErr
+	}
+}
@@ -0,0 +1,155 @@
+package tarball_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/installation/tarball"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("SourceConfig", func() {
+	var env map[string]string
+
+	getenv := func(name string) string { return env[name] }
+
+	BeforeEach(func() {
+		env = map[string]string{}
+	})
+
+	Describe("NewSourceConfigFromEnv", func() {
+		It("reads mirrors, credentials and the S3 account from the environment", func() {
+			env["BOSH_INIT_CA_CERT"] = "/fake-ca.pem"
+			env["BOSH_INIT_MIRRORS"] = "https://bosh.io/d/=https://fake-mirror/d/, http://fake-from=http://fake-to"
+			env["BOSH_INIT_HTTP_AUTH"] = "fake-host=basic:fake-user:fake-pass:word,fake-token-host=token:fake:token"
+			env["NETRC"] = "/fake-netrc"
+			env["AWS_ACCESS_KEY_ID"] = "fake-access-key-id"
+			env["AWS_SECRET_ACCESS_KEY"] = "fake-secret-access-key"
+			env["AWS_SESSION_TOKEN"] = "fake-session-token"
+			env["AWS_DEFAULT_REGION"] = "eu-west-1"
+			env["BOSH_INIT_S3_ENDPOINT"] = "https://fake-s3-endpoint"
+			env["BOSH_INIT_TRUSTED_KEYS"] = "/fake-trusted-keys"
+			env["BOSH_INIT_MAX_CONCURRENT_DOWNLOADS"] = "2"
+
+			config, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(SourceConfig{
+				CACertPath: "/fake-ca.pem",
+				Mirrors: Mirrors{
+					{From: "https://bosh.io/d/", To: "https://fake-mirror/d/"},
+					{From: "http://fake-from", To: "http://fake-to"},
+				},
+				Credentials: map[string]Credentials{
+					"fake-host":       {Username: "fake-user", Password: "fake-pass:word"},
+					"fake-token-host": {Token: "fake:token"},
+				},
+				NetrcPath: "/fake-netrc",
+				S3: S3Config{
+					AccessKeyID:     "fake-access-key-id",
+					SecretAccessKey: "fake-secret-access-key",
+					SessionToken:    "fake-session-token",
+					Region:          "eu-west-1",
+					Endpoint:        "https://fake-s3-endpoint",
+				},
+				TrustedKeysPath:        "/fake-trusted-keys",
+				MaxConcurrentDownloads: 2,
+			}))
+		})
+
+		It("defaults the netrc path to the home directory", func() {
+			config, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.NetrcPath).To(Equal("~/.netrc"))
+		})
+
+		It("defaults the number of concurrent downloads", func() {
+			config, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.MaxConcurrentDownloads).To(Equal(DefaultMaxConcurrentDownloads))
+		})
+
+		It("returns an error when the number of concurrent downloads is not positive", func() {
+			env["BOSH_INIT_MAX_CONCURRENT_DOWNLOADS"] = "0"
+
+			_, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_MAX_CONCURRENT_DOWNLOADS '0', must be a positive number"))
+		})
+
+		It("prefers AWS_REGION to AWS_DEFAULT_REGION", func() {
+			env["AWS_REGION"] = "fake-region"
+			env["AWS_DEFAULT_REGION"] = "fake-default-region"
+
+			config, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.S3.Region).To(Equal("fake-region"))
+		})
+
+		It("returns an error when a mirror rule is invalid", func() {
+			env["BOSH_INIT_MIRRORS"] = "https://bosh.io/d/"
+
+			_, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_MIRRORS rule 'https://bosh.io/d/', must be 'from=to'"))
+		})
+
+		It("returns an error when a credentials entry has no host", func() {
+			env["BOSH_INIT_HTTP_AUTH"] = "fake-host"
+
+			_, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_HTTP_AUTH entry 'fake-host', must be 'host=token:<token>' or 'host=basic:<user>:<password>'"))
+		})
+
+		It("returns an error when a credentials entry has no scheme", func() {
+			env["BOSH_INIT_HTTP_AUTH"] = "fake-host=fake-user:fake-password"
+
+			_, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_HTTP_AUTH entry 'fake-host=fake-user:fake-password'"))
+		})
+
+		It("returns an error when a basic credentials entry has no password", func() {
+			env["BOSH_INIT_HTTP_AUTH"] = "fake-host=basic:fake-user"
+
+			_, err := NewSourceConfigFromEnv(getenv)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid BOSH_INIT_HTTP_AUTH entry 'fake-host=basic:fake-user'"))
+		})
+	})
+
+	Describe("NewHTTPClient", func() {
+		var fs *fakesys.FakeFileSystem
+
+		BeforeEach(func() {
+			fs = fakesys.NewFakeFileSystem()
+		})
+
+		It("returns the default client when there is no CA certificate", func() {
+			client, err := NewHTTPClient("", fs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client).To(Equal(HTTPClient))
+		})
+
+		It("returns an error when the CA certificate has no PEM certificates", func() {
+			Expect(fs.WriteFileString("/fake-ca.pem", "fake-not-a-cert")).To(Succeed())
+
+			_, err := NewHTTPClient("/fake-ca.pem", fs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing CA certificate '/fake-ca.pem'"))
+		})
+	})
+})
+
+var _ = Describe("Mirrors", func() {
+	It("rewrites the URL with the first matching mirror", func() {
+		mirrors := Mirrors{
+			{From: "https://fake-other/", To: "https://fake-other-mirror/"},
+			{From: "https://bosh.io/d/", To: "https://fake-mirror/d/"},
+			{From: "https://bosh.io/", To: "https://fake-second-mirror/"},
+		}
+
+		Expect(mirrors.Rewrite("https://bosh.io/d/fake-release")).To(Equal("https://fake-mirror/d/fake-release"))
+		Expect(mirrors.Rewrite("https://fake-unmatched/fake-release")).To(Equal("https://fake-unmatched/fake-release"))
+	})
+})
@@ -13,7 +13,6 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 
-	mock_httpagent "github.com/cloudfoundry/bosh-agent/agentclient/http/mocks"
 	mock_agentclient "github.com/cloudfoundry/bosh-init/agentclient/mocks"
 	mock_blobstore "github.com/cloudfoundry/bosh-init/blobstore/mocks"
 	mock_cloud "github.com/cloudfoundry/bosh-init/cloud/mocks"
@@ -21,6 +20,7 @@ import (
 	mock_install "github.com/cloudfoundry/bosh-init/installation/mocks"
 	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
 	"github.com/golang/mock/gomock"
+	"github.com/pivotal-golang/clock"
 
 	biagentclient "github.com/cloudfoundry/bosh-agent/agentclient"
 	bias "github.com/cloudfoundry/bosh-agent/agentclient/applyspec"
@@ -31,8 +31,10 @@ import (
 	bidisk "github.com/cloudfoundry/bosh-init/deployment/disk"
 	biinstance "github.com/cloudfoundry/bosh-init/deployment/instance"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
+	bideplrel "github.com/cloudfoundry/bosh-init/deployment/release"
 	bisshtunnel "github.com/cloudfoundry/bosh-init/deployment/sshtunnel"
 	bivm "github.com/cloudfoundry/bosh-init/deployment/vm"
+	bihandoff "github.com/cloudfoundry/bosh-init/handoff"
 	biinstall "github.com/cloudfoundry/bosh-init/installation"
 	biinstallmanifest "github.com/cloudfoundry/bosh-init/installation/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
@@ -49,7 +51,10 @@ import (
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 
+	fakebicatalog "github.com/cloudfoundry/bosh-init/catalog/fakes"
 	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
+	fakebihandoff "github.com/cloudfoundry/bosh-init/handoff/fakes"
+	fakebisignature "github.com/cloudfoundry/bosh-init/signature/fakes"
 	fakebistemcell "github.com/cloudfoundry/bosh-init/stemcell/fakes"
 	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
 	fakebihttpclient "github.com/cloudfoundry/bosh-utils/httpclient/fakes"
@@ -79,7 +84,7 @@ var _ = Describe("bosh-init", func() {
 			mockCloudFactory       *mock_cloud.MockFactory
 			mockCloud              *mock_cloud.MockCloud
 			mockAgentClient        *mock_agentclient.MockAgentClient
-			mockAgentClientFactory *mock_httpagent.MockAgentClientFactory
+			mockAgentClientFactory *mock_agentclient.MockAgentClientFactory
 			mockReleaseExtractor   *mock_release.MockExtractor
 
 			mockStateBuilderFactory *mock_instance_state.MockBuilderFactory
@@ -103,8 +108,6 @@ var _ = Describe("bosh-init", func() {
 			deploymentRepo                biconfig.DeploymentRepo
 			releaseRepo                   biconfig.ReleaseRepo
 
-			sshTunnelFactory bisshtunnel.Factory
-
 			diskManagerFactory bidisk.ManagerFactory
 			diskDeployer       bivm.DiskDeployer
 
@@ -349,10 +352,9 @@ cloud_provider:
 			installationValidator := biinstallmanifest.NewValidator(logger)
 			installationParser := biinstallmanifest.NewParser(fs, fakeRegistryUUIDGenerator, logger, installationValidator)
 
-			deploymentValidator := bideplmanifest.NewValidator(logger)
+			deploymentValidator := bideplmanifest.NewValidator(bideplmanifest.NewLinkResolver(bideplrel.NewJobResolver(releaseManager)), logger)
 
-			instanceFactory := biinstance.NewFactory(mockStateBuilderFactory)
-			instanceManagerFactory := biinstance.NewManagerFactory(sshTunnelFactory, instanceFactory, logger)
+			instanceFactory := biinstance.NewFactory(mockStateBuilderFactory, clock.NewClock())
 
 			pingTimeout := 1 * time.Second
 			pingDelay := 100 * time.Millisecond
@@ -368,6 +370,9 @@ cloud_provider:
 				deploymentRepo = biconfig.NewDeploymentRepo(deploymentStateService)
 				releaseRepo = biconfig.NewReleaseRepo(deploymentStateService, fakeRepoUUIDGenerator)
 
+				sshTunnelFactory := bisshtunnel.NewFactory(biconfig.NewHostKeyRepo(deploymentStateService), logger)
+				instanceManagerFactory := biinstance.NewManagerFactory(sshTunnelFactory, instanceFactory, logger)
+
 				legacyDeploymentStateMigrator = biconfig.NewLegacyDeploymentStateMigrator(deploymentStateService, fs, fakeUUIDGenerator, logger)
 				deploymentRecord := bidepl.NewRecord(deploymentRepo, releaseRepo, stemcellRepo, fakeSHA1Calculator)
 				stemcellManagerFactory = bistemcell.NewManagerFactory(stemcellRepo)
@@ -378,21 +383,25 @@ cloud_provider:
 					vmManagerFactory,
 					instanceManagerFactory,
 					deploymentFactory,
+					bideplmanifest.NewIPAM(biconfig.NewIPAllocationRepo(deploymentStateService), logger),
 					logger,
 				)
 				fakeHTTPClient := fakebihttpclient.NewFakeHTTPClient()
-				tarballCache := bitarball.NewCache("fake-base-path", fs, logger)
-				tarballProvider := bitarball.NewProvider(tarballCache, fs, fakeHTTPClient, fakeSHA1Calculator, 1, 0, logger)
+				tarballCache := bitarball.NewCache("fake-base-path", 0, fs, clock.NewClock(), logger)
+				schemeRegistry := bitarball.NewSchemeRegistry()
+				schemeRegistry.Register("http", bitarball.NewHTTPDownloader(fakeHTTPClient, bitarball.NewCredentialsProvider(nil, "", fs), logger))
+				tarballProvider := bitarball.NewProvider(tarballCache, fs, schemeRegistry, nil, fakebicrypto.NewFakeDigestCalculator(), 1, 0, clock.NewClock(), logger)
 
 				cpiInstaller := bicpirel.CpiInstaller{
 					ReleaseManager:   releaseManager,
 					InstallerFactory: mockInstallerFactory,
 					Validator:        bicpirel.NewValidator(),
 				}
-				releaseFetcher := birel.NewFetcher(tarballProvider, mockReleaseExtractor, releaseManager)
+				releaseFetcher := birel.NewFetcher(tarballProvider, mockReleaseExtractor, releaseManager, mock_release.NewMockDevReleaseBuilder(mockCtrl), fakebicatalog.NewFakeResolver(), fakebisignature.NewFakeVerifier(), fs)
 				stemcellFetcher := bistemcell.Fetcher{
 					TarballProvider:   tarballProvider,
 					StemcellExtractor: fakeStemcellExtractor,
+					SignatureVerifier: fakebisignature.NewFakeVerifier(),
 				}
 
 				releaseSetAndInstallationManifestParser := ReleaseSetAndInstallationManifestParser{
@@ -433,6 +442,12 @@ cloud_provider:
 					cpiInstaller,
 					releaseFetcher,
 					stemcellFetcher,
+					fakebicatalog.NewFakeResolver(),
+					biconfig.NewResolvedRefRepo(deploymentStateService),
+					biconfig.NewTarballRepo(deploymentStateService),
+					fakebisignature.NewFakeVerifier(),
+					biconfig.NewSignatureRepo(deploymentStateService),
+					2,
 					releaseSetAndInstallationManifestParser,
 					deploymentManifestParser,
 					tempRootConfigurator,
@@ -440,11 +455,16 @@ cloud_provider:
 				), nil
 			}
 
+			handoffProvider := func() (bihandoff.Handoff, error) {
+				return fakebihandoff.NewFakeHandoff(), nil
+			}
+
 			return NewDeployCmd(
 				ui,
 				fs,
 				logger,
 				doGet,
+				handoffProvider,
 			)
 		}
 
@@ -492,6 +512,7 @@ cloud_provider:
 
 				// shutdown old vm
 				mockAgentClient.EXPECT().Ping().Return("any-state", nil),
+				mockAgentClient.EXPECT().Drain("shutdown", nil, time.Duration(0)).Return(true, nil),
 				mockAgentClient.EXPECT().Stop(),
 				mockAgentClient.EXPECT().ListDisk().Return([]string{oldDiskCID}, nil),
 				mockAgentClient.EXPECT().UnmountDisk(oldDiskCID),
@@ -578,6 +599,7 @@ cloud_provider:
 
 				// shutdown old vm
 				mockAgentClient.EXPECT().Ping().Return("any-state", nil),
+				mockAgentClient.EXPECT().Drain("shutdown", nil, time.Duration(0)).Return(true, nil),
 				mockAgentClient.EXPECT().Stop(),
 				mockAgentClient.EXPECT().ListDisk().Return([]string{oldDiskCID}, nil),
 				mockAgentClient.EXPECT().UnmountDisk(oldDiskCID),
@@ -611,6 +633,7 @@ cloud_provider:
 
 				// shutdown old vm
 				mockAgentClient.EXPECT().Ping().Return("any-state", nil),
+				mockAgentClient.EXPECT().Drain("shutdown", nil, time.Duration(0)).Return(true, nil),
 				mockAgentClient.EXPECT().Stop(),
 				mockAgentClient.EXPECT().ListDisk().Return([]string{oldDiskCID}, nil),
 				mockAgentClient.EXPECT().UnmountDisk(oldDiskCID),
@@ -646,6 +669,7 @@ cloud_provider:
 
 				// shutdown old vm
 				mockAgentClient.EXPECT().Ping().Return("any-state", nil),
+				mockAgentClient.EXPECT().Drain("shutdown", nil, time.Duration(0)).Return(true, nil),
 				mockAgentClient.EXPECT().Stop(),
 				mockAgentClient.EXPECT().ListDisk().Return([]string{oldDiskCID}, nil),
 				mockAgentClient.EXPECT().UnmountDisk(oldDiskCID),
@@ -697,8 +721,6 @@ cloud_provider:
 			mockInstallerFactory = mock_install.NewMockInstallerFactory(mockCtrl)
 			mockCloudFactory = mock_cloud.NewMockFactory(mockCtrl)
 
-			sshTunnelFactory = bisshtunnel.NewFactory(logger)
-
 			fakeRepoUUIDGenerator = fakeuuid.NewFakeGenerator()
 
 			mockCloud = mock_cloud.NewMockCloud(mockCtrl)
@@ -722,10 +744,10 @@ cloud_provider:
 			stdErr = gbytes.NewBuffer()
 			fakeStage = fakebiui.NewFakeStage()
 
-			mockAgentClientFactory = mock_httpagent.NewMockAgentClientFactory(mockCtrl)
+			mockAgentClientFactory = mock_agentclient.NewMockAgentClientFactory(mockCtrl)
 			mockAgentClient = mock_agentclient.NewMockAgentClient(mockCtrl)
 
-			mockAgentClientFactory.EXPECT().NewAgentClient(directorID, mbusURL).Return(mockAgentClient).AnyTimes()
+			mockAgentClientFactory.EXPECT().NewAgentClient(directorID, mbusURL, gomock.Any()).Return(mockAgentClient).AnyTimes()
 
 			writeDeploymentManifest()
 			writeCPIReleaseTarball()
@@ -796,7 +818,7 @@ cloud_provider:
 				expectDeployFlow()
 
 				// new directorID will be generated
-				mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), mbusURL).Return(mockAgentClient)
+				mockAgentClientFactory.EXPECT().NewAgentClient(gomock.Any(), mbusURL, gomock.Any()).Return(mockAgentClient)
 
 				err := newDeployCmd().Run(fakeStage, []string{deploymentManifestPath})
 				Expect(err).ToNot(HaveOccurred())
@@ -903,6 +925,7 @@ cloud_provider:
 
 				mockCloud.EXPECT().HasVM(gomock.Any()).Return(true, nil).AnyTimes()
 				mockAgentClient.EXPECT().Ping().AnyTimes()
+				mockAgentClient.EXPECT().Drain(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 				mockAgentClient.EXPECT().Stop().AnyTimes()
 				mockAgentClient.EXPECT().ListDisk().AnyTimes()
 			}
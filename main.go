@@ -39,6 +39,7 @@ func main() {
 		logger,
 		boshuuid.NewGenerator(),
 		workspaceRootPath,
+		os.Getenv("BOSH_INIT_INDEX"),
 	)
 
 	cmdRunner := bicmd.NewRunner(cmdFactory)
@@ -110,5 +111,5 @@ func fail(err error, ui biui.UI, logger boshlog.Logger, callback func()) {
 	if callback != nil {
 		callback()
 	}
-	os.Exit(1)
+	os.Exit(bicmd.ExitCode(err))
 }
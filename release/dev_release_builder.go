@@ -0,0 +1,587 @@
+package release
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	"github.com/cloudfoundry/bosh-init/installation/tarball"
+	bireljobmanifest "github.com/cloudfoundry/bosh-init/release/job/manifest"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/yaml"
+)
+
+// DevReleaseBuilder builds a dev release tarball from a release repository directory,
+// so that it can be extracted like any other release tarball.
+// Built tarballs are saved in the tarball cache, under the release directory and the fingerprint
+// of the release jobs and packages, so they are listed, pruned and evicted like downloaded tarballs.
+type DevReleaseBuilder interface {
+	Build(releaseDir string) (tarballPath string, err error)
+}
+
+type devReleaseBuilder struct {
+	cache          tarball.Cache
+	legacyCacheDir string
+	fs             boshsys.FileSystem
+	cmdRunner      boshsys.CmdRunner
+	compressor     boshcmd.Compressor
+	sha1Calculator bicrypto.SHA1Calculator
+	logger         boshlog.Logger
+	logTag         string
+}
+
+type releaseConfig struct {
+	Name      string `yaml:"name"`
+	FinalName string `yaml:"final_name"`
+	DevName   string `yaml:"dev_name"`
+}
+
+type packageSpec struct {
+	Name          string   `yaml:"name"`
+	Dependencies  []string `yaml:"dependencies"`
+	Files         []string `yaml:"files"`
+	ExcludedFiles []string `yaml:"excluded_files"`
+}
+
+// devReleaseFile is a file of a dev release job or package, at its path within the job or package archive.
+// The modes of the packaging scripts are left out of the fingerprint.
+type devReleaseFile struct {
+	path        string
+	sourcePath  string
+	excludeMode bool
+}
+
+type devReleaseJob struct {
+	name        string
+	fingerprint string
+	files       []devReleaseFile
+}
+
+type devReleasePackage struct {
+	name         string
+	fingerprint  string
+	dependencies []string
+	files        []devReleaseFile
+	prePackaging bool
+}
+
+// NewDevReleaseBuilder returns a builder saving dev release tarballs in cache.
+// legacyCacheDir is where dev release tarballs were kept before, it is deleted on the first build.
+func NewDevReleaseBuilder(
+	cache tarball.Cache,
+	legacyCacheDir string,
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	compressor boshcmd.Compressor,
+	sha1Calculator bicrypto.SHA1Calculator,
+	logger boshlog.Logger,
+) DevReleaseBuilder {
+	return &devReleaseBuilder{
+		cache:          cache,
+		legacyCacheDir: legacyCacheDir,
+		fs:             fs,
+		cmdRunner:      cmdRunner,
+		compressor:     compressor,
+		sha1Calculator: sha1Calculator,
+		logger:         logger,
+		logTag:         "devReleaseBuilder",
+	}
+}
+
+func (b *devReleaseBuilder) Build(releaseDir string) (string, error) {
+	b.removeLegacyCacheDir()
+
+	name, err := b.releaseName(releaseDir)
+	if err != nil {
+		return "", err
+	}
+
+	packages, err := b.readPackages(releaseDir)
+	if err != nil {
+		return "", err
+	}
+
+	jobs, err := b.readJobs(releaseDir)
+	if err != nil {
+		return "", err
+	}
+
+	releaseFingerprint := sha1.New()
+	fmt.Fprintf(releaseFingerprint, "%s\n", name)
+	for _, pkg := range packages {
+		fmt.Fprintf(releaseFingerprint, "package %s %s\n", pkg.name, pkg.fingerprint)
+	}
+	for _, job := range jobs {
+		fmt.Fprintf(releaseFingerprint, "job %s %s\n", job.name, job.fingerprint)
+	}
+	fingerprint := fmt.Sprintf("%x", releaseFingerprint.Sum(nil))
+
+	url := fmt.Sprintf("file://%s#%s", releaseDir, fingerprint)
+	description := fmt.Sprintf("dev release '%s'", name)
+
+	tarballPath, found, err := b.findCached(url, description)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		b.logger.Debug(b.logTag, "Found cached dev release '%s' at '%s'", name, tarballPath)
+		return tarballPath, nil
+	}
+
+	b.logger.Info(b.logTag, "Building dev release '%s' from '%s'", name, releaseDir)
+
+	stagingDir, err := b.fs.TempDir("bosh-init-dev-release")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Creating dev release staging directory")
+	}
+	defer func() {
+		if err := b.fs.RemoveAll(stagingDir); err != nil {
+			b.logger.Warn(b.logTag, "Failed to remove dev release staging directory: %s", err.Error())
+		}
+	}()
+
+	releaseManifest := birelmanifest.Manifest{
+		Name:               name,
+		Version:            "0+dev." + fingerprint[:12],
+		UncommittedChanges: true,
+	}
+
+	for _, pkg := range packages {
+		var prepare func(string) error
+		if pkg.prePackaging {
+			prepare = b.prePackagingRunner(releaseDir)
+		}
+
+		archiveSHA1, err := b.buildArchive(pkg.files, filepath.Join(stagingDir, "packages", pkg.name+".tgz"), prepare)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Building package '%s'", pkg.name)
+		}
+
+		releaseManifest.Packages = append(releaseManifest.Packages, birelmanifest.PackageRef{
+			Name:         pkg.name,
+			Fingerprint:  pkg.fingerprint,
+			SHA1:         archiveSHA1,
+			Dependencies: pkg.dependencies,
+		})
+	}
+
+	for _, job := range jobs {
+		archiveSHA1, err := b.buildArchive(job.files, filepath.Join(stagingDir, "jobs", job.name+".tgz"), nil)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Building job '%s'", job.name)
+		}
+
+		releaseManifest.Jobs = append(releaseManifest.Jobs, birelmanifest.JobRef{
+			Name:        job.name,
+			Fingerprint: job.fingerprint,
+			SHA1:        archiveSHA1,
+		})
+	}
+
+	releaseManifestBytes, err := yaml.Marshal(releaseManifest)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Marshalling dev release manifest")
+	}
+
+	err = b.fs.WriteFile(filepath.Join(stagingDir, "release.MF"), releaseManifestBytes)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Writing dev release manifest")
+	}
+
+	tarballPath, err = b.save(stagingDir, url, description)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Saving dev release '%s'", name)
+	}
+
+	return tarballPath, nil
+}
+
+// findCached returns the cached tarball built for the url, which names the release directory and fingerprint.
+// The cache records the SHA1 of the tarball, which is only known once it was built.
+func (b *devReleaseBuilder) findCached(url, description string) (string, bool, error) {
+	entries, err := b.cache.List()
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Listing cached tarballs")
+	}
+
+	for _, entry := range entries {
+		if entry.URL == url {
+			tarballPath, found := b.cache.Get(tarball.NewSource(entry.URL, entry.SHA1, description))
+			return tarballPath, found, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// save compresses the staging directory into the cache
+func (b *devReleaseBuilder) save(stagingDir, url, description string) (string, error) {
+	compressedPath, err := b.compressor.CompressFilesInDir(stagingDir)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Compressing '%s'", stagingDir)
+	}
+	defer func() {
+		if err := b.compressor.CleanUp(compressedPath); err != nil {
+			b.logger.Warn(b.logTag, "Failed to clean up compressed file: %s", err.Error())
+		}
+	}()
+
+	tarballSHA1, err := b.sha1Calculator.Calculate(compressedPath)
+	if err != nil {
+		return "", err
+	}
+
+	source := tarball.NewSource(url, tarballSHA1, description)
+
+	// the compressed tarball is copied next to the cache first, as the cache saves tarballs by renaming them
+	partialPath := b.cache.PartialPath(source)
+	err = b.fs.MkdirAll(filepath.Dir(partialPath), os.ModePerm)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Creating directory for '%s'", partialPath)
+	}
+
+	err = b.fs.CopyFile(compressedPath, partialPath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Copying tarball to '%s'", partialPath)
+	}
+
+	err = b.cache.Save(partialPath, source)
+	if err != nil {
+		return "", err
+	}
+
+	return b.cache.Path(source), nil
+}
+
+func (b *devReleaseBuilder) removeLegacyCacheDir() {
+	if b.legacyCacheDir == "" || !b.fs.FileExists(b.legacyCacheDir) {
+		return
+	}
+
+	b.logger.Debug(b.logTag, "Removing dev releases built before they were cached at '%s'", b.legacyCacheDir)
+	if err := b.fs.RemoveAll(b.legacyCacheDir); err != nil {
+		b.logger.Warn(b.logTag, "Failed to remove dev release directory '%s': %s", b.legacyCacheDir, err.Error())
+	}
+}
+
+func (b *devReleaseBuilder) releaseName(releaseDir string) (string, error) {
+	config := releaseConfig{}
+	for _, configFile := range []string{"final.yml", "dev.yml"} {
+		configPath := filepath.Join(releaseDir, "config", configFile)
+		if !b.fs.FileExists(configPath) {
+			continue
+		}
+
+		configBytes, err := b.fs.ReadFile(configPath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Reading release config '%s'", configPath)
+		}
+
+		err = yaml.Unmarshal(configBytes, &config)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Parsing release config '%s'", configPath)
+		}
+	}
+
+	for _, name := range []string{config.DevName, config.FinalName, config.Name} {
+		if name != "" {
+			return name, nil
+		}
+	}
+
+	return "", bosherr.Errorf("Release directory '%s' must specify a name in config/final.yml", releaseDir)
+}
+
+func (b *devReleaseBuilder) readPackages(releaseDir string) ([]devReleasePackage, error) {
+	specPaths, err := b.fs.Glob(filepath.Join(releaseDir, "packages", "*", "spec"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Finding package specs")
+	}
+	sort.Strings(specPaths)
+
+	packages := []devReleasePackage{}
+	for _, specPath := range specPaths {
+		spec := packageSpec{}
+		err := b.readYAML(specPath, &spec)
+		if err != nil {
+			return nil, err
+		}
+
+		packageDir := filepath.Dir(specPath)
+		files := []devReleaseFile{
+			{path: "packaging", sourcePath: filepath.Join(packageDir, "packaging"), excludeMode: true},
+		}
+
+		prePackagingPath := filepath.Join(packageDir, "pre_packaging")
+		prePackaging := b.fs.FileExists(prePackagingPath)
+		if prePackaging {
+			files = append(files, devReleaseFile{path: "pre_packaging", sourcePath: prePackagingPath, excludeMode: true})
+		}
+
+		sourceFiles, err := b.packageSourceFiles(releaseDir, spec)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Finding files of package '%s'", spec.Name)
+		}
+		files = append(files, sourceFiles...)
+
+		fingerprint, err := b.fingerprint(files, spec.Dependencies)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Fingerprinting package '%s'", spec.Name)
+		}
+
+		packages = append(packages, devReleasePackage{
+			name:         spec.Name,
+			fingerprint:  fingerprint,
+			dependencies: spec.Dependencies,
+			files:        files,
+			prePackaging: prePackaging,
+		})
+	}
+
+	return packages, nil
+}
+
+// packageSourceFiles returns the files matching the package spec in src/, or else in blobs/
+func (b *devReleaseBuilder) packageSourceFiles(releaseDir string, spec packageSpec) ([]devReleaseFile, error) {
+	filesByPath := map[string]string{}
+	for _, pattern := range spec.Files {
+		for _, baseDir := range []string{"blobs", "src"} {
+			baseDirPath := filepath.Join(releaseDir, baseDir)
+			matches, err := b.fs.RecursiveGlob(filepath.Join(baseDirPath, pattern))
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Matching files '%s'", pattern)
+			}
+
+			for _, match := range matches {
+				info, err := b.fs.Stat(match)
+				if err != nil {
+					return nil, bosherr.WrapErrorf(err, "Checking file '%s'", match)
+				}
+				if info.IsDir() {
+					continue
+				}
+
+				relativePath, err := filepath.Rel(baseDirPath, match)
+				if err != nil {
+					return nil, bosherr.WrapErrorf(err, "Finding relative path of '%s'", match)
+				}
+				filesByPath[relativePath] = match
+			}
+		}
+	}
+
+	for _, pattern := range spec.ExcludedFiles {
+		for relativePath := range filesByPath {
+			excluded, err := filepath.Match(pattern, relativePath)
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Matching excluded files '%s'", pattern)
+			}
+			if excluded {
+				delete(filesByPath, relativePath)
+			}
+		}
+	}
+
+	if len(spec.Files) > 0 && len(filesByPath) == 0 {
+		return nil, bosherr.Errorf("No files match '%s' in src/ or blobs/", strings.Join(spec.Files, "', '"))
+	}
+
+	files := []devReleaseFile{}
+	for relativePath, sourcePath := range filesByPath {
+		files = append(files, devReleaseFile{path: relativePath, sourcePath: sourcePath})
+	}
+	return files, nil
+}
+
+func (b *devReleaseBuilder) readJobs(releaseDir string) ([]devReleaseJob, error) {
+	specPaths, err := b.fs.Glob(filepath.Join(releaseDir, "jobs", "*", "spec"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Finding job specs")
+	}
+	sort.Strings(specPaths)
+
+	jobs := []devReleaseJob{}
+	for _, specPath := range specPaths {
+		spec := bireljobmanifest.Manifest{}
+		err := b.readYAML(specPath, &spec)
+		if err != nil {
+			return nil, err
+		}
+
+		jobDir := filepath.Dir(specPath)
+		files := []devReleaseFile{
+			{path: "job.MF", sourcePath: specPath},
+			{path: "monit", sourcePath: filepath.Join(jobDir, "monit")},
+		}
+		for template := range spec.Templates {
+			files = append(files, devReleaseFile{
+				path:       filepath.Join("templates", template),
+				sourcePath: filepath.Join(jobDir, "templates", template),
+			})
+		}
+
+		fingerprint, err := b.fingerprint(files, nil)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Fingerprinting job '%s'", spec.Name)
+		}
+
+		jobs = append(jobs, devReleaseJob{
+			name:        spec.Name,
+			fingerprint: fingerprint,
+			files:       files,
+		})
+	}
+
+	return jobs, nil
+}
+
+func (b *devReleaseBuilder) readYAML(path string, out interface{}) error {
+	contents, err := b.fs.ReadFile(path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading '%s'", path)
+	}
+
+	err = yaml.Unmarshal(contents, out)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing '%s'", path)
+	}
+
+	return nil
+}
+
+// fingerprint is computed like BOSH computes job and package fingerprints: the SHA1 of the path, SHA1
+// and mode ('100755' when executable, else '100644') of each file sorted by path,
+// followed by the sorted dependency names joined by commas
+func (b *devReleaseBuilder) fingerprint(files []devReleaseFile, dependencies []string) (string, error) {
+	sortedFiles := append([]devReleaseFile{}, files...)
+	sort.Sort(devReleaseFilesByPath(sortedFiles))
+
+	h := sha1.New()
+	for _, file := range sortedFiles {
+		info, err := b.fs.Stat(file.sourcePath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Checking file '%s'", file.sourcePath)
+		}
+
+		fileSHA1, err := b.sha1Calculator.Calculate(file.sourcePath)
+		if err != nil {
+			return "", err
+		}
+
+		mode := ""
+		if !file.excludeMode {
+			mode = "100644"
+			if info.Mode()&0111 != 0 {
+				mode = "100755"
+			}
+		}
+
+		fmt.Fprintf(h, "%s%s%s", file.path, fileSHA1, mode)
+	}
+
+	sortedDependencies := append([]string{}, dependencies...)
+	sort.Strings(sortedDependencies)
+	fmt.Fprint(h, strings.Join(sortedDependencies, ","))
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// prePackagingRunner runs the pre_packaging script of a package in its archive directory, as BOSH does,
+// and removes the script afterwards as it is not needed to compile the package
+func (b *devReleaseBuilder) prePackagingRunner(releaseDir string) func(string) error {
+	return func(archiveDir string) error {
+		_, _, _, err := b.cmdRunner.RunComplexCommand(boshsys.Command{
+			Name: "bash",
+			Args: []string{"-x", "pre_packaging"},
+			Env: map[string]string{
+				"BUILD_DIR":   archiveDir,
+				"RELEASE_DIR": releaseDir,
+			},
+			WorkingDir: archiveDir,
+		})
+		if err != nil {
+			return bosherr.WrapError(err, "Running pre_packaging")
+		}
+
+		return b.fs.RemoveAll(filepath.Join(archiveDir, "pre_packaging"))
+	}
+}
+
+// buildArchive compresses the files into an archive at archivePath, and returns the SHA1 of the archive.
+// When prepare is set, it runs in the archive directory once the files were copied into it.
+func (b *devReleaseBuilder) buildArchive(files []devReleaseFile, archivePath string, prepare func(archiveDir string) error) (string, error) {
+	archiveDir, err := b.fs.TempDir("bosh-init-dev-release-archive")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Creating archive directory")
+	}
+	defer func() {
+		if err := b.fs.RemoveAll(archiveDir); err != nil {
+			b.logger.Warn(b.logTag, "Failed to remove archive directory: %s", err.Error())
+		}
+	}()
+
+	for _, file := range files {
+		dst := filepath.Join(archiveDir, file.path)
+		err := b.fs.MkdirAll(filepath.Dir(dst), os.ModePerm)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Creating directory for '%s'", file.path)
+		}
+
+		err = b.fs.CopyFile(file.sourcePath, dst)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Copying '%s'", file.sourcePath)
+		}
+	}
+
+	if prepare != nil {
+		err = prepare(archiveDir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = b.fs.MkdirAll(filepath.Dir(archivePath), os.ModePerm)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Creating directory for '%s'", archivePath)
+	}
+
+	err = b.compress(archiveDir, archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	return b.sha1Calculator.Calculate(archivePath)
+}
+
+func (b *devReleaseBuilder) compress(dir string, archivePath string) error {
+	compressedPath, err := b.compressor.CompressFilesInDir(dir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Compressing '%s'", dir)
+	}
+	defer func() {
+		if err := b.compressor.CleanUp(compressedPath); err != nil {
+			b.logger.Warn(b.logTag, "Failed to clean up compressed file: %s", err.Error())
+		}
+	}()
+
+	err = b.fs.CopyFile(compressedPath, archivePath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Copying archive to '%s'", archivePath)
+	}
+
+	return nil
+}
+
+type devReleaseFilesByPath []devReleaseFile
+
+func (s devReleaseFilesByPath) Len() int           { return len(s) }
+func (s devReleaseFilesByPath) Less(i, j int) bool { return s[i].path < s[j].path }
+func (s devReleaseFilesByPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,287 @@
+package release_test
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/cloudfoundry/bosh-init/release"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+func sha1Hex(contents string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(contents)))
+}
+
+var _ = Describe("DevReleaseBuilder", func() {
+	var (
+		fs                boshsys.FileSystem
+		releaseDir        string
+		cacheDir          string
+		legacyCacheDir    string
+		tarballCache      bitarball.Cache
+		devReleaseBuilder DevReleaseBuilder
+		releaseExtractor  Extractor
+	)
+
+	writeFile := func(path, contents string) {
+		err := fs.MkdirAll(filepath.Dir(filepath.Join(releaseDir, path)), os.ModePerm)
+		Expect(err).ToNot(HaveOccurred())
+		err = fs.WriteFileString(filepath.Join(releaseDir, path), contents)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs = boshsys.NewOsFileSystem(logger)
+		compressor := boshcmd.NewTarballCompressor(boshsys.NewExecCmdRunner(logger), fs)
+
+		var err error
+		releaseDir, err = fs.TempDir("dev-release-builder-test-release")
+		Expect(err).ToNot(HaveOccurred())
+		cacheDir, err = fs.TempDir("dev-release-builder-test-cache")
+		Expect(err).ToNot(HaveOccurred())
+		legacyCacheDir = filepath.Join(cacheDir, "dev_releases")
+
+		tarballCache = bitarball.NewCache(filepath.Join(cacheDir, "downloads"), 0, fs, clock.NewClock(), logger)
+		devReleaseBuilder = NewDevReleaseBuilder(tarballCache, legacyCacheDir, fs, boshsys.NewExecCmdRunner(logger), compressor, bicrypto.NewSha1Calculator(fs), logger)
+		releaseExtractor = NewExtractor(fs, compressor, NewValidator(fs, bicrypto.NewDigestCalculator(fs)), logger)
+
+		writeFile("config/final.yml", "---\nfinal_name: fake-release-name\n")
+
+		writeFile("jobs/fake-job/spec", `---
+name: fake-job
+templates:
+  ctl.erb: bin/ctl
+packages:
+- fake-package
+properties:
+  fake-property:
+    default: fake-default
+`)
+		writeFile("jobs/fake-job/monit", "fake-monit")
+		writeFile("jobs/fake-job/templates/ctl.erb", "fake-ctl")
+
+		writeFile("packages/fake-package/spec", `---
+name: fake-package
+dependencies:
+- fake-dependency
+files:
+- fake-package/**/*
+excluded_files:
+- fake-package/*.log
+`)
+		writeFile("packages/fake-package/packaging", "fake-packaging")
+		writeFile("src/fake-package/main.go", "fake-source")
+		writeFile("src/fake-package/nested/file", "fake-nested-source")
+		writeFile("src/fake-package/build.log", "fake-log")
+
+		writeFile("packages/fake-dependency/spec", "---\nname: fake-dependency\nfiles:\n- fake-dependency.tgz\n")
+		writeFile("packages/fake-dependency/packaging", "fake-packaging")
+		writeFile("blobs/fake-dependency.tgz", "fake-blob")
+	})
+
+	AfterEach(func() {
+		Expect(fs.RemoveAll(releaseDir)).To(Succeed())
+		Expect(fs.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	It("builds a release tarball from the jobs and packages of the release directory", func() {
+		tarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		release, err := releaseExtractor.Extract(tarballPath)
+		Expect(err).ToNot(HaveOccurred())
+		defer release.Delete()
+
+		Expect(release.Name()).To(Equal("fake-release-name"))
+		Expect(release.Version()).To(HavePrefix("0+dev."))
+
+		job, found := release.FindJobByName("fake-job")
+		Expect(found).To(BeTrue())
+		Expect(job.Templates).To(Equal(map[string]string{"ctl.erb": "bin/ctl"}))
+		Expect(job.Packages).To(HaveLen(1))
+		Expect(job.Packages[0].Name).To(Equal("fake-package"))
+
+		packages := release.Packages()
+		Expect(packages).To(HaveLen(2))
+
+		var pkgDir string
+		for _, pkg := range packages {
+			if pkg.Name == "fake-package" {
+				pkgDir = pkg.ExtractedPath
+				Expect(pkg.Dependencies).To(HaveLen(1))
+				Expect(pkg.Dependencies[0].Name).To(Equal("fake-dependency"))
+			}
+		}
+		Expect(filepath.Join(pkgDir, "packaging")).To(BeAnExistingFile())
+		Expect(filepath.Join(pkgDir, "fake-package", "main.go")).To(BeAnExistingFile())
+		Expect(filepath.Join(pkgDir, "fake-package", "nested", "file")).To(BeAnExistingFile())
+		Expect(filepath.Join(pkgDir, "fake-package", "build.log")).ToNot(BeAnExistingFile())
+	})
+
+	It("fingerprints the jobs and packages as BOSH does", func() {
+		tarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		release, err := releaseExtractor.Extract(tarballPath)
+		Expect(err).ToNot(HaveOccurred())
+		defer release.Delete()
+
+		specContents, err := fs.ReadFileString(filepath.Join(releaseDir, "jobs", "fake-job", "spec"))
+		Expect(err).ToNot(HaveOccurred())
+
+		job, found := release.FindJobByName("fake-job")
+		Expect(found).To(BeTrue())
+		Expect(job.Fingerprint).To(Equal(sha1Hex(
+			"job.MF" + sha1Hex(specContents) + "100644" +
+				"monit" + sha1Hex("fake-monit") + "100644" +
+				"templates/ctl.erb" + sha1Hex("fake-ctl") + "100644",
+		)))
+
+		fingerprints := map[string]string{}
+		for _, pkg := range release.Packages() {
+			fingerprints[pkg.Name] = pkg.Fingerprint
+		}
+		Expect(fingerprints).To(Equal(map[string]string{
+			"fake-package": sha1Hex(
+				"fake-package/main.go" + sha1Hex("fake-source") + "100644" +
+					"fake-package/nested/file" + sha1Hex("fake-nested-source") + "100644" +
+					"packaging" + sha1Hex("fake-packaging") +
+					"fake-dependency",
+			),
+			"fake-dependency": sha1Hex(
+				"fake-dependency.tgz" + sha1Hex("fake-blob") + "100644" +
+					"packaging" + sha1Hex("fake-packaging"),
+			),
+		}))
+	})
+
+	It("saves the tarball in the tarball cache under the release directory and fingerprint", func() {
+		tarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		tarballSHA1, err := bicrypto.NewSha1Calculator(fs).Calculate(tarballPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		entries, err := tarballCache.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Path).To(Equal(tarballPath))
+		Expect(entries[0].URL).To(MatchRegexp("^file://" + releaseDir + "#[0-9a-f]{40}$"))
+		Expect(entries[0].SHA1).To(Equal(tarballSHA1))
+	})
+
+	It("removes the directory dev releases were kept in before", func() {
+		Expect(fs.MkdirAll(legacyCacheDir, os.ModePerm)).To(Succeed())
+		Expect(fs.WriteFileString(filepath.Join(legacyCacheDir, "fake-release-name-fake-fingerprint.tgz"), "fake-tarball")).To(Succeed())
+
+		_, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(legacyCacheDir).ToNot(BeADirectory())
+	})
+
+	It("runs the pre_packaging script of a package in the package directory before archiving it", func() {
+		writeFile("packages/fake-package/pre_packaging", `set -e
+test "$RELEASE_DIR" = "`+releaseDir+`"
+cd "$BUILD_DIR"
+echo fake-generated > fake-package/generated
+rm fake-package/nested/file
+`)
+
+		tarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		release, err := releaseExtractor.Extract(tarballPath)
+		Expect(err).ToNot(HaveOccurred())
+		defer release.Delete()
+
+		var pkgDir string
+		for _, pkg := range release.Packages() {
+			if pkg.Name == "fake-package" {
+				pkgDir = pkg.ExtractedPath
+			}
+		}
+		Expect(filepath.Join(pkgDir, "fake-package", "generated")).To(BeAnExistingFile())
+		Expect(filepath.Join(pkgDir, "fake-package", "nested", "file")).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(pkgDir, "pre_packaging")).ToNot(BeAnExistingFile())
+	})
+
+	It("returns an error when the pre_packaging script of a package fails", func() {
+		writeFile("packages/fake-package/pre_packaging", "exit 1\n")
+
+		_, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Building package 'fake-package'"))
+		Expect(err.Error()).To(ContainSubstring("Running pre_packaging"))
+	})
+
+	It("reuses the cached tarball when the release directory has not changed", func() {
+		firstTarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		secondTarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secondTarballPath).To(Equal(firstTarballPath))
+	})
+
+	It("builds a new tarball when a package source changes", func() {
+		firstTarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		writeFile("src/fake-package/main.go", "fake-changed-source")
+
+		secondTarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secondTarballPath).ToNot(Equal(firstTarballPath))
+	})
+
+	It("ignores changes to excluded files", func() {
+		firstTarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		writeFile("src/fake-package/build.log", "fake-changed-log")
+
+		secondTarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secondTarballPath).To(Equal(firstTarballPath))
+	})
+
+	It("uses the dev name when config/dev.yml sets it", func() {
+		writeFile("config/dev.yml", "---\ndev_name: fake-dev-name\n")
+
+		tarballPath, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		release, err := releaseExtractor.Extract(tarballPath)
+		Expect(err).ToNot(HaveOccurred())
+		defer release.Delete()
+		Expect(release.Name()).To(Equal("fake-dev-name"))
+	})
+
+	It("returns an error when the release has no name", func() {
+		Expect(fs.RemoveAll(filepath.Join(releaseDir, "config"))).To(Succeed())
+
+		_, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("must specify a name in config/final.yml"))
+	})
+
+	It("returns an error when no files match a package spec", func() {
+		Expect(fs.RemoveAll(filepath.Join(releaseDir, "blobs"))).To(Succeed())
+
+		_, err := devReleaseBuilder.Build(releaseDir)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Finding files of package 'fake-dependency'"))
+		Expect(err.Error()).To(ContainSubstring("No files match 'fake-dependency.tgz' in src/ or blobs/"))
+	})
+})
@@ -96,6 +96,7 @@ properties: {}
 								Name:          "cpi",
 								Fingerprint:   "fake-release-job-fingerprint",
 								SHA1:          "fake-release-job-sha1",
+								ArchivePath:   "/extracted-release-path/jobs/cpi.tgz",
 								ExtractedPath: "/extracted-release-path/extracted_jobs/cpi",
 								Templates: map[string]string{
 									"cpi.erb":     "bin/cpi",
@@ -2,28 +2,69 @@ package release
 
 import (
 	"fmt"
+	"strings"
+
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
 	"github.com/cloudfoundry/bosh-init/installation/tarball"
 	"github.com/cloudfoundry/bosh-init/release/manifest"
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
 	"github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
 type Fetcher struct {
-	tarballProvider  tarball.Provider
-	releaseExtractor Extractor
-	releaseManager   Manager
+	tarballProvider   tarball.Provider
+	releaseExtractor  Extractor
+	releaseManager    Manager
+	devReleaseBuilder DevReleaseBuilder
+	catalogResolver   bicatalog.Resolver
+	signatureVerifier bisignature.Verifier
+	fs                boshsys.FileSystem
 }
 
-func NewFetcher(tarballProvider tarball.Provider, releaseExtractor Extractor, releaseManager Manager) Fetcher {
+func NewFetcher(
+	tarballProvider tarball.Provider,
+	releaseExtractor Extractor,
+	releaseManager Manager,
+	devReleaseBuilder DevReleaseBuilder,
+	catalogResolver bicatalog.Resolver,
+	signatureVerifier bisignature.Verifier,
+	fs boshsys.FileSystem,
+) Fetcher {
 	return Fetcher{
-		tarballProvider:  tarballProvider,
-		releaseExtractor: releaseExtractor,
-		releaseManager:   releaseManager,
+		tarballProvider:   tarballProvider,
+		releaseExtractor:  releaseExtractor,
+		releaseManager:    releaseManager,
+		devReleaseBuilder: devReleaseBuilder,
+		catalogResolver:   catalogResolver,
+		signatureVerifier: signatureVerifier,
+		fs:                fs,
 	}
 }
 
 func (f Fetcher) DownloadAndExtract(releaseRef manifest.ReleaseRef, stage ui.Stage) error {
-	releasePath, err := f.tarballProvider.Get(releaseRef, stage)
+	if releaseRef.URL == "" {
+		err := stage.Perform(fmt.Sprintf("Resolving release '%s' version '%s'", releaseRef.Name, releaseRef.Version), func() error {
+			entry, err := f.catalogResolver.ResolveRelease(releaseRef.Name, releaseRef.Version)
+			if err != nil {
+				return err
+			}
+
+			releaseRef.Version = entry.Version
+			releaseRef.URL = entry.URL
+			releaseRef.SHA1 = entry.SHA1
+			if releaseRef.Signature == "" {
+				releaseRef.Signature = entry.Signature
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	releasePath, err := f.releaseTarball(releaseRef, stage)
 	if err != nil {
 		return err
 	}
@@ -37,9 +78,72 @@ func (f Fetcher) DownloadAndExtract(releaseRef manifest.ReleaseRef, stage ui.Sta
 		if release.Name() != releaseRef.Name {
 			return bosherr.Errorf("Release name '%s' does not match the name in release tarball '%s'", releaseRef.Name, release.Name())
 		}
+
+		if releaseRef.Version != "" && releaseRef.Version != bicatalog.LatestVersion && releaseRef.Version != release.Version() {
+			return bosherr.Errorf("Release version '%s' does not match the version in release tarball '%s'", releaseRef.Version, release.Version())
+		}
 		f.releaseManager.Add(release)
 
 		return nil
 	})
 	return err
 }
+
+// releaseTarball returns the path of the release tarball,
+// building a dev release when the release url is a local release directory and no signatures are required
+func (f Fetcher) releaseTarball(releaseRef manifest.ReleaseRef, stage ui.Stage) (string, error) {
+	if strings.HasPrefix(releaseRef.URL, "file://") {
+		releaseDir, err := f.fs.ExpandPath(strings.TrimPrefix(releaseRef.URL, "file://"))
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Expanding release path '%s'", releaseRef.URL)
+		}
+
+		if info, err := f.fs.Stat(releaseDir); err == nil && info.IsDir() {
+			if f.signatureVerifier.RequiresSignature() {
+				return "", bosherr.Errorf("The dev release '%s' built from '%s' can not be signed, but trusted keys are configured in BOSH_INIT_TRUSTED_KEYS", releaseRef.Name, releaseDir)
+			}
+
+			var releasePath string
+			err = stage.Perform(fmt.Sprintf("Building dev release '%s' from '%s'", releaseRef.Name, releaseDir), func() error {
+				var err error
+				releasePath, err = f.devReleaseBuilder.Build(releaseDir)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Building dev release from '%s'", releaseDir)
+				}
+				return nil
+			})
+			return releasePath, err
+		}
+	}
+
+	releasePath, err := f.tarballProvider.Get(releaseRef, stage)
+	if err != nil {
+		return "", err
+	}
+
+	err = f.verifySignature(releaseRef, releasePath, stage)
+	if err != nil {
+		return "", err
+	}
+
+	return releasePath, nil
+}
+
+// verifySignature checks the signature of a downloaded release tarball before it is extracted
+func (f Fetcher) verifySignature(releaseRef manifest.ReleaseRef, releasePath string, stage ui.Stage) error {
+	artifact := bisignature.Artifact{
+		Type:      "release",
+		Name:      releaseRef.Name,
+		URL:       releaseRef.URL,
+		SHA1:      releaseRef.SHA1,
+		Signature: releaseRef.Signature,
+	}
+
+	if releaseRef.Signature == "" {
+		return f.signatureVerifier.Verify(artifact, releasePath)
+	}
+
+	return stage.Perform(fmt.Sprintf("Verifying signature of release '%s'", releaseRef.Name), func() error {
+		return f.signatureVerifier.Verify(artifact, releasePath)
+	})
+}
@@ -0,0 +1,170 @@
+package release_test
+
+import (
+	"errors"
+
+	. "github.com/cloudfoundry/bosh-init/release"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/mock/gomock"
+
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
+	fakebicatalog "github.com/cloudfoundry/bosh-init/catalog/fakes"
+	mock_tarball "github.com/cloudfoundry/bosh-init/installation/tarball/mocks"
+	fakebirel "github.com/cloudfoundry/bosh-init/release/fakes"
+	birelmanifest "github.com/cloudfoundry/bosh-init/release/manifest"
+	mock_release "github.com/cloudfoundry/bosh-init/release/mocks"
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
+	fakebisignature "github.com/cloudfoundry/bosh-init/signature/fakes"
+	fakebiui "github.com/cloudfoundry/bosh-init/ui/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Fetcher", func() {
+	var (
+		mockCtrl              *gomock.Controller
+		mockTarballProvider   *mock_tarball.MockProvider
+		mockReleaseExtractor  *mock_release.MockExtractor
+		mockDevReleaseBuilder *mock_release.MockDevReleaseBuilder
+		fakeResolver          *fakebicatalog.FakeResolver
+		fakeVerifier          *fakebisignature.FakeVerifier
+		fakeFS                *fakesys.FakeFileSystem
+		fakeStage             *fakebiui.FakeStage
+		releaseManager        Manager
+		fetcher               Fetcher
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockTarballProvider = mock_tarball.NewMockProvider(mockCtrl)
+		mockReleaseExtractor = mock_release.NewMockExtractor(mockCtrl)
+		mockDevReleaseBuilder = mock_release.NewMockDevReleaseBuilder(mockCtrl)
+		fakeResolver = fakebicatalog.NewFakeResolver()
+		fakeVerifier = fakebisignature.NewFakeVerifier()
+		fakeFS = fakesys.NewFakeFileSystem()
+		fakeStage = fakebiui.NewFakeStage()
+		releaseManager = NewManager(boshlog.NewLogger(boshlog.LevelNone))
+
+		fetcher = NewFetcher(mockTarballProvider, mockReleaseExtractor, releaseManager, mockDevReleaseBuilder, fakeResolver, fakeVerifier, fakeFS)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("downloads and extracts the release tarball", func() {
+		releaseRef := birelmanifest.ReleaseRef{Name: "fake-release", URL: "https://fake-url", SHA1: "fake-sha1"}
+		mockTarballProvider.EXPECT().Get(releaseRef, fakeStage).Return("/fake-release.tgz", nil)
+		mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(fakebirel.New("fake-release", "1"), nil)
+
+		err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releaseManager.List()).To(HaveLen(1))
+		Expect(fakeResolver.ResolveReleaseInputs).To(BeEmpty())
+	})
+
+	Context("when the release has a signature", func() {
+		var releaseRef birelmanifest.ReleaseRef
+
+		BeforeEach(func() {
+			releaseRef = birelmanifest.ReleaseRef{Name: "fake-release", URL: "https://fake-url", SHA1: "fake-sha1", Signature: "https://fake-url.sig"}
+			mockTarballProvider.EXPECT().Get(releaseRef, fakeStage).Return("/fake-release.tgz", nil)
+		})
+
+		It("verifies the signature of the tarball before extracting it", func() {
+			mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(fakebirel.New("fake-release", "1"), nil)
+
+			err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeVerifier.VerifyInputs).To(Equal([]fakebisignature.VerifyInput{
+				{
+					Artifact:    bisignature.Artifact{Type: "release", Name: "fake-release", URL: "https://fake-url", SHA1: "fake-sha1", Signature: "https://fake-url.sig"},
+					TarballPath: "/fake-release.tgz",
+				},
+			}))
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Verifying signature of release 'fake-release'"))
+		})
+
+		It("returns an error without extracting the tarball when the signature does not verify", func() {
+			fakeVerifier.VerifyErr = errors.New("fake-verify-error")
+
+			err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-verify-error"))
+		})
+	})
+
+	It("lets the verifier decide whether unsigned releases are trusted", func() {
+		releaseRef := birelmanifest.ReleaseRef{Name: "fake-release", URL: "https://fake-url", SHA1: "fake-sha1"}
+		mockTarballProvider.EXPECT().Get(releaseRef, fakeStage).Return("/fake-release.tgz", nil)
+		fakeVerifier.VerifyErr = errors.New("fake-unsigned-error")
+
+		err := fetcher.DownloadAndExtract(releaseRef, fakeStage)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-unsigned-error"))
+		Expect(fakeStage.PerformCalls).To(BeEmpty())
+	})
+
+	Context("when the release has a version instead of a url", func() {
+		BeforeEach(func() {
+			fakeResolver.ResolveReleaseEntry = bicatalog.Entry{Name: "fake-release", Version: "2", URL: "https://fake-url-2", SHA1: "fake-sha1-2"}
+		})
+
+		It("downloads the tarball that the version resolves to in the index", func() {
+			resolvedRef := birelmanifest.ReleaseRef{Name: "fake-release", Version: "2", URL: "https://fake-url-2", SHA1: "fake-sha1-2"}
+			mockTarballProvider.EXPECT().Get(resolvedRef, fakeStage).Return("/fake-release.tgz", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(fakebirel.New("fake-release", "2"), nil)
+
+			err := fetcher.DownloadAndExtract(birelmanifest.ReleaseRef{Name: "fake-release", Version: "latest"}, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeResolver.ResolveReleaseInputs).To(Equal([]fakebicatalog.ResolveInput{{Name: "fake-release", Version: "latest"}}))
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Resolving release 'fake-release' version 'latest'"))
+		})
+
+		It("verifies the signature listed in the index", func() {
+			fakeResolver.ResolveReleaseEntry.Signature = "https://fake-url-2.sig"
+			mockTarballProvider.EXPECT().Get(gomock.Any(), fakeStage).Return("/fake-release.tgz", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(fakebirel.New("fake-release", "2"), nil)
+
+			err := fetcher.DownloadAndExtract(birelmanifest.ReleaseRef{Name: "fake-release", Version: "2"}, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeVerifier.VerifyInputs[0].Artifact.Signature).To(Equal("https://fake-url-2.sig"))
+		})
+
+		It("returns an error when the tarball has another version", func() {
+			mockTarballProvider.EXPECT().Get(gomock.Any(), fakeStage).Return("/fake-release.tgz", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-release.tgz").Return(fakebirel.New("fake-release", "1"), nil)
+
+			err := fetcher.DownloadAndExtract(birelmanifest.ReleaseRef{Name: "fake-release", Version: "2"}, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Release version '2' does not match the version in release tarball '1'"))
+		})
+	})
+
+	Context("when the release url is a local release directory", func() {
+		BeforeEach(func() {
+			fakeFS.MkdirAll("/fake-release-dir", 0755)
+		})
+
+		It("builds a dev release from the directory", func() {
+			mockDevReleaseBuilder.EXPECT().Build("/fake-release-dir").Return("/fake-dev-release.tgz", nil)
+			mockReleaseExtractor.EXPECT().Extract("/fake-dev-release.tgz").Return(fakebirel.New("fake-release", "0+dev.1"), nil)
+
+			err := fetcher.DownloadAndExtract(birelmanifest.ReleaseRef{Name: "fake-release", URL: "file:///fake-release-dir"}, fakeStage)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeStage.PerformCalls[0].Name).To(Equal("Building dev release 'fake-release' from '/fake-release-dir'"))
+			Expect(fakeVerifier.VerifyInputs).To(BeEmpty())
+		})
+
+		It("returns an error without building the dev release when signatures are required", func() {
+			fakeVerifier.RequiresSignatureResult = true
+
+			err := fetcher.DownloadAndExtract(birelmanifest.ReleaseRef{Name: "fake-release", URL: "file:///fake-release-dir"}, fakeStage)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("The dev release 'fake-release' built from '/fake-release-dir' can not be signed, but trusted keys are configured in BOSH_INIT_TRUSTED_KEYS"))
+			Expect(fakeStage.PerformCalls).To(BeEmpty())
+		})
+	})
+})
@@ -9,11 +9,14 @@ type Job struct {
 	Name          string
 	Fingerprint   string
 	SHA1          string
+	ArchivePath   string
 	ExtractedPath string
 	Templates     map[string]string
 	PackageNames  []string
 	Packages      []*birelpkg.Package
 	Properties    map[string]PropertyDefinition
+	Provides      []LinkDefinition
+	Consumes      []LinkDefinition
 }
 
 type PropertyDefinition struct {
@@ -21,6 +24,15 @@ type PropertyDefinition struct {
 	Default     biproperty.Property
 }
 
+// LinkDefinition is a link provided or consumed by a job.
+// Provided links expose the listed job properties, consumed links may be optional.
+type LinkDefinition struct {
+	Name       string
+	Type       string
+	Optional   bool
+	Properties []string
+}
+
 func (j Job) FindTemplateByValue(value string) (string, bool) {
 	for template, templateTarget := range j.Templates {
 		if templateTarget == value {
@@ -5,9 +5,18 @@ type Manifest struct {
 	Templates  map[string]string             `yaml:"templates"`
 	Packages   []string                      `yaml:"packages"`
 	Properties map[string]PropertyDefinition `yaml:"properties"`
+	Provides   []LinkDefinition              `yaml:"provides"`
+	Consumes   []LinkDefinition              `yaml:"consumes"`
 }
 
 type PropertyDefinition struct {
 	Description string      `yaml:"description"`
 	Default     interface{} `yaml:"default"`
 }
+
+type LinkDefinition struct {
+	Name       string   `yaml:"name"`
+	Type       string   `yaml:"type"`
+	Optional   bool     `yaml:"optional"`
+	Properties []string `yaml:"properties"`
+}
@@ -76,5 +76,12 @@ func (r *reader) Read() (Job, error) {
 	}
 	job.Properties = jobProperties
 
+	for _, rawLink := range jobManifest.Provides {
+		job.Provides = append(job.Provides, LinkDefinition(rawLink))
+	}
+	for _, rawLink := range jobManifest.Consumes {
+		job.Consumes = append(job.Consumes, LinkDefinition(rawLink))
+	}
+
 	return job, nil
 }
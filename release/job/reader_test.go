@@ -42,6 +42,36 @@ properties:
 				)
 			})
 
+			Context("when the job manifest declares links", func() {
+				BeforeEach(func() {
+					fakeFs.WriteFileString(
+						"/extracted/job/job.MF",
+						`---
+name: fake-job
+provides:
+- name: fake-provided-link
+  type: fake-link-type
+  properties: [fake-property]
+consumes:
+- name: fake-consumed-link
+  type: fake-other-link-type
+  optional: true
+`,
+					)
+				})
+
+				It("returns a job with the provided and consumed links", func() {
+					job, err := reader.Read()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(job.Provides).To(Equal([]LinkDefinition{
+						{Name: "fake-provided-link", Type: "fake-link-type", Properties: []string{"fake-property"}},
+					}))
+					Expect(job.Consumes).To(Equal([]LinkDefinition{
+						{Name: "fake-consumed-link", Type: "fake-other-link-type", Optional: true},
+					}))
+				})
+			})
+
 			It("returns a job with the details from the manifest", func() {
 				job, err := reader.Read()
 				Expect(err).NotTo(HaveOccurred())
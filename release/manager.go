@@ -1,12 +1,17 @@
 package release
 
 import (
+	"sort"
+	"sync"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
+// Manager keeps the extracted releases; it is safe for concurrent use
 type Manager interface {
 	Add(Release)
+	// List returns the releases sorted by name, as they may be added in any order when fetched concurrently
 	List() []Release
 	Find(name string) (releases Release, found bool)
 	DeleteAll() error
@@ -16,7 +21,8 @@ type manager struct {
 	logger boshlog.Logger
 	logTag string
 
-	releases []Release
+	releasesLock sync.RWMutex
+	releases     []Release
 }
 
 func NewManager(
@@ -31,14 +37,25 @@ func NewManager(
 
 func (m *manager) Add(release Release) {
 	m.logger.Info(m.logTag, "Adding extracted release '%s-%s'", release.Name(), release.Version())
+
+	m.releasesLock.Lock()
+	defer m.releasesLock.Unlock()
 	m.releases = append(m.releases, release)
 }
 
 func (m *manager) List() []Release {
-	return append([]Release(nil), m.releases...)
+	m.releasesLock.RLock()
+	defer m.releasesLock.RUnlock()
+
+	releases := append([]Release(nil), m.releases...)
+	sort.Stable(releasesByName(releases))
+	return releases
 }
 
 func (m *manager) Find(name string) (Release, bool) {
+	m.releasesLock.RLock()
+	defer m.releasesLock.RUnlock()
+
 	for _, release := range m.releases {
 		if release.Name() == name {
 			return release, true
@@ -48,6 +65,9 @@ func (m *manager) Find(name string) (Release, bool) {
 }
 
 func (m *manager) DeleteAll() error {
+	m.releasesLock.Lock()
+	defer m.releasesLock.Unlock()
+
 	for _, release := range m.releases {
 		deleteErr := release.Delete()
 		if deleteErr != nil {
@@ -57,3 +77,9 @@ func (m *manager) DeleteAll() error {
 	m.releases = []Release{}
 	return nil
 }
+
+type releasesByName []Release
+
+func (r releasesByName) Len() int           { return len(r) }
+func (r releasesByName) Less(i, j int) bool { return r[i].Name() < r[j].Name() }
+func (r releasesByName) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
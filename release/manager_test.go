@@ -1,6 +1,8 @@
 package release_test
 
 import (
+	"sync"
+
 	. "github.com/cloudfoundry/bosh-init/release"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -25,14 +27,30 @@ var _ = Describe("Manager", func() {
 	})
 
 	Describe("List", func() {
-		It("returns all releases that have been added", func() {
-			releaseManager.Add(releaseA)
+		It("returns all releases that have been added, sorted by name", func() {
 			releaseManager.Add(releaseB)
+			releaseManager.Add(releaseA)
 
 			Expect(releaseManager.List()).To(Equal([]Release{releaseA, releaseB}))
 		})
 	})
 
+	Describe("Add", func() {
+		It("adds releases concurrently", func() {
+			var waitGroup sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				waitGroup.Add(1)
+				go func() {
+					defer waitGroup.Done()
+					releaseManager.Add(releaseA)
+				}()
+			}
+			waitGroup.Wait()
+
+			Expect(releaseManager.List()).To(HaveLen(10))
+		})
+	})
+
 	Describe("Find", func() {
 		It("returns false when no releases have been added", func() {
 			_, found := releaseManager.Find("release-a")
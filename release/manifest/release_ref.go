@@ -6,8 +6,12 @@ import (
 
 type ReleaseRef struct {
 	Name string
-	URL  string
-	SHA1 string
+	// Version resolves the release URL and SHA1 through the index when URL is not set
+	Version string
+	URL     string
+	SHA1    string
+	// Signature is the URL or path of a detached signature of the release tarball
+	Signature string
 }
 
 func (r ReleaseRef) GetURL() string {
@@ -1,5 +1,5 @@
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/cloudfoundry/bosh-init/release (interfaces: Manager,Extractor)
+// Source: github.com/cloudfoundry/bosh-init/release (interfaces: Manager,Extractor,DevReleaseBuilder)
 
 package mocks
 
@@ -99,3 +99,35 @@ func (_m *MockExtractor) Extract(_param0 string) (release.Release, error) {
 func (_mr *_MockExtractorRecorder) Extract(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Extract", arg0)
 }
+
+// Mock of DevReleaseBuilder interface
+type MockDevReleaseBuilder struct {
+	ctrl     *gomock.Controller
+	recorder *_MockDevReleaseBuilderRecorder
+}
+
+// Recorder for MockDevReleaseBuilder (not exported)
+type _MockDevReleaseBuilderRecorder struct {
+	mock *MockDevReleaseBuilder
+}
+
+func NewMockDevReleaseBuilder(ctrl *gomock.Controller) *MockDevReleaseBuilder {
+	mock := &MockDevReleaseBuilder{ctrl: ctrl}
+	mock.recorder = &_MockDevReleaseBuilderRecorder{mock}
+	return mock
+}
+
+func (_m *MockDevReleaseBuilder) EXPECT() *_MockDevReleaseBuilderRecorder {
+	return _m.recorder
+}
+
+func (_m *MockDevReleaseBuilder) Build(_param0 string) (string, error) {
+	ret := _m.ctrl.Call(_m, "Build", _param0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDevReleaseBuilderRecorder) Build(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Build", arg0)
+}
@@ -116,6 +116,7 @@ func (r *reader) newJobsFromManifestJobs(packages []*birelpkg.Package, manifestJ
 
 		job.Fingerprint = manifestJob.Fingerprint
 		job.SHA1 = manifestJob.SHA1
+		job.ArchivePath = jobArchivePath
 		for _, pkgName := range job.PackageNames {
 			pkg, found := r.findPackageByName(packages, pkgName)
 			if !found {
@@ -90,6 +90,7 @@ packages:
 									Name:          "fake-job",
 									Fingerprint:   "fake-job-fingerprint",
 									SHA1:          "fake-job-sha",
+									ArchivePath:   "/extracted/release/jobs/fake-job.tgz",
 									ExtractedPath: "/extracted/release/extracted_jobs/fake-job",
 									Templates:     map[string]string{"some_template": "some_file"},
 									PackageNames:  []string{"fake-package"},
@@ -281,6 +282,7 @@ packages:
 									Name:          "fake-job",
 									Fingerprint:   "fake-job-fingerprint",
 									SHA1:          "fake-job-sha",
+									ArchivePath:   "/extracted/release/jobs/fake-job.tgz",
 									ExtractedPath: "/extracted/release/extracted_jobs/fake-job",
 									Templates:     map[string]string{"some_template": "some_file"},
 									PackageNames:  []string{"fake-package"},
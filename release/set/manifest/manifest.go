@@ -5,6 +5,9 @@ import (
 )
 
 type Manifest struct {
+	// Index is the location of the index that release and stemcell versions are resolved in,
+	// which takes precedence over BOSH_INIT_INDEX
+	Index    string
 	Releases []birelmanifest.ReleaseRef
 }
 
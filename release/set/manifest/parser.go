@@ -21,6 +21,7 @@ type parser struct {
 }
 
 type manifest struct {
+	Index    string `yaml:"index"`
 	Releases []birelmanifest.ReleaseRef
 }
 
@@ -47,13 +48,32 @@ func (p *parser) Parse(path string) (Manifest, error) {
 	p.logger.Debug(p.logTag, "Parsed release set manifest: %#v", comboManifest)
 
 	for i, releaseRef := range comboManifest.Releases {
+		if releaseRef.Signature != "" {
+			comboManifest.Releases[i].Signature, err = biutil.AbsolutifyPath(path, releaseRef.Signature, p.fs)
+			if err != nil {
+				return Manifest{}, bosherr.WrapErrorf(err, "Resolving release signature path '%s", releaseRef.Signature)
+			}
+		}
+
+		if releaseRef.URL == "" {
+			continue
+		}
+
 		comboManifest.Releases[i].URL, err = biutil.AbsolutifyPath(path, releaseRef.URL, p.fs)
 		if err != nil {
 			return Manifest{}, bosherr.WrapErrorf(err, "Resolving release path '%s", releaseRef.URL)
 		}
 	}
 
+	if comboManifest.Index != "" {
+		comboManifest.Index, err = biutil.AbsolutifyPath(path, comboManifest.Index, p.fs)
+		if err != nil {
+			return Manifest{}, bosherr.WrapErrorf(err, "Resolving index path '%s'", comboManifest.Index)
+		}
+	}
+
 	releaseSetManifest := Manifest{
+		Index:    comboManifest.Index,
 		Releases: comboManifest.Releases,
 	}
 
@@ -161,6 +161,55 @@ releases:
 		})
 	})
 
+	Context("when release has a version instead of a url", func() {
+		BeforeEach(func() {
+			fakeFs.WriteFileString(comboManifestPath, `
+---
+releases:
+- name: fake-release-name
+  version: 257.3
+`)
+		})
+
+		It("leaves the url empty to be resolved through the index", func() {
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(deploymentManifest).To(Equal(manifest.Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{
+						Name:    "fake-release-name",
+						Version: "257.3",
+					},
+				},
+			}))
+		})
+	})
+
+	Context("when the manifest configures an index", func() {
+		It("keeps an index url", func() {
+			fakeFs.WriteFileString(comboManifestPath, `
+---
+index: https://fake-index/index.yml
+releases: []
+`)
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentManifest.Index).To(Equal("https://fake-index/index.yml"))
+		})
+
+		It("resolves a relative index path against the manifest directory", func() {
+			fakeFs.WriteFileString(comboManifestPath, `
+---
+index: index.yml
+releases: []
+`)
+			deploymentManifest, err := parser.Parse(comboManifestPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentManifest.Index).To(Equal("/path/to/manifest/index.yml"))
+		})
+	})
+
 	It("parses release set manifest from combo manifest file", func() {
 		deploymentManifest, err := parser.Parse(comboManifestPath)
 		Expect(err).ToNot(HaveOccurred())
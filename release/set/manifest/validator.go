@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strings"
 
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
@@ -39,18 +40,32 @@ func (v *validator) Validate(manifest Manifest) error {
 		}
 		releaseNames[release.Name] = struct{}{}
 
+		if v.isBlank(release.URL) && !v.isBlank(release.Version) {
+			continue
+		}
+
 		if v.isBlank(release.URL) {
 			errs = append(errs, bosherr.Errorf("releases[%d].url must be provided", releaseIdx))
 		}
 
-		matched, err := regexp.MatchString("^(file|http|https)://", release.URL)
+		matched, err := regexp.MatchString("^(file|http|https|s3)://", release.URL)
 		if err != nil || !matched {
-			errs = append(errs, bosherr.Errorf("releases[%d].url must be a valid URL (file:// or http(s)://)", releaseIdx))
+			errs = append(errs, bosherr.Errorf("releases[%d].url must be a valid URL (file://, http(s):// or s3://)", releaseIdx))
 		}
 
 		if strings.HasPrefix(release.URL, "http") && v.isBlank(release.SHA1) {
 			errs = append(errs, bosherr.Errorf("releases[%d].sha1 must be provided for http URL", releaseIdx))
 		}
+
+		if strings.HasPrefix(release.URL, "s3://") && v.isBlank(release.SHA1) {
+			errs = append(errs, bosherr.Errorf("releases[%d].sha1 must be provided for s3 URL", releaseIdx))
+		}
+
+		if !v.isBlank(release.SHA1) {
+			if _, err := bicrypto.ParseMultipleDigest(release.SHA1); err != nil {
+				errs = append(errs, bosherr.WrapErrorf(err, "releases[%d].sha1 must be a SHA1 or digests such as 'sha256:...'", releaseIdx))
+			}
+		}
 	}
 
 	if len(errs) > 0 {
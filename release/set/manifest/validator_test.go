@@ -81,12 +81,24 @@ var _ = Describe("Validator", func() {
 			Expect(err.Error()).To(ContainSubstring("releases[0].url must be provided"))
 		})
 
-		It("accepts file://, http://, https:// as valid URLs", func() {
+		It("does not require urls of releases with a version", func() {
+			manifest := Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{Name: "fake-release-name", Version: "latest"},
+				},
+			}
+
+			err := validator.Validate(manifest)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts file://, http://, https://, s3:// as valid URLs", func() {
 			manifest := Manifest{
 				Releases: []birelmanifest.ReleaseRef{
 					{Name: "fake-release-name-1", URL: "file://fake-file"},
 					{Name: "fake-release-name-2", URL: "http://fake-http", SHA1: "fake-sha1"},
 					{Name: "fake-release-name-3", URL: "https://fake-https", SHA1: "fake-sha2"},
+					{Name: "fake-release-name-4", URL: "s3://fake-bucket/fake-key", SHA1: "fake-sha3"},
 				},
 			}
 
@@ -106,6 +118,41 @@ var _ = Describe("Validator", func() {
 			Expect(err.Error()).To(ContainSubstring("releases[0].sha1 must be provided for http URL"))
 		})
 
+		It("validates releases with s3 urls have sha1", func() {
+			manifest := Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{Name: "fake-release-name", URL: "s3://fake-bucket/fake-key"},
+				},
+			}
+
+			err := validator.Validate(manifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("releases[0].sha1 must be provided for s3 URL"))
+		})
+
+		It("validates releases have valid digests", func() {
+			manifest := Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{Name: "fake-release-name", URL: "http://fake-url", SHA1: "fake-sha1;sha256:fake-sha256"},
+				},
+			}
+
+			err := validator.Validate(manifest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("releases[0].sha1 must be a SHA1 or digests such as 'sha256:...': Digest 'sha256:fake-sha256' must be 64 hex characters"))
+		})
+
+		It("accepts releases with sha256 digests", func() {
+			manifest := Manifest{
+				Releases: []birelmanifest.ReleaseRef{
+					{Name: "fake-release-name", URL: "http://fake-url", SHA1: "sha256:7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7"},
+				},
+			}
+
+			err := validator.Validate(manifest)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
 		It("validates releases have valid urls", func() {
 			manifest := Manifest{
 				Releases: []birelmanifest.ReleaseRef{
@@ -115,7 +162,7 @@ var _ = Describe("Validator", func() {
 
 			err := validator.Validate(manifest)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("releases[0].url must be a valid URL (file:// or http(s)://)"))
+			Expect(err.Error()).To(ContainSubstring("releases[0].url must be a valid URL (file://, http(s):// or s3://)"))
 		})
 
 		It("validates releases are unique", func() {
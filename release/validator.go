@@ -6,6 +6,7 @@ import (
 	"path"
 	"sort"
 
+	bicrypto "github.com/cloudfoundry/bosh-init/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -15,11 +16,12 @@ type Validator interface {
 }
 
 type validator struct {
-	fs boshsys.FileSystem
+	fs               boshsys.FileSystem
+	digestCalculator bicrypto.DigestCalculator
 }
 
-func NewValidator(fs boshsys.FileSystem) Validator {
-	return &validator{fs: fs}
+func NewValidator(fs boshsys.FileSystem, digestCalculator bicrypto.DigestCalculator) Validator {
+	return &validator{fs: fs, digestCalculator: digestCalculator}
 }
 
 func (v *validator) Validate(release Release) error {
@@ -81,6 +83,11 @@ func (v *validator) validateReleaseJobs(release Release) error {
 
 		if job.SHA1 == "" {
 			errs = append(errs, fmt.Errorf("Job '%s' sha1 is missing", job.Name))
+		} else if job.ArchivePath != "" {
+			err := v.validateArchive(job.ArchivePath, job.SHA1)
+			if err != nil {
+				errs = append(errs, bosherr.WrapErrorf(err, "Job '%s' archive is corrupt", job.Name))
+			}
 		}
 
 		monitPath := path.Join(job.ExtractedPath, "monit")
@@ -131,6 +138,11 @@ func (v *validator) validateReleasePackages(release Release) error {
 
 		if pkg.SHA1 == "" {
 			errs = append(errs, fmt.Errorf("Package '%s' sha1 is missing", pkg.Name))
+		} else if pkg.ArchivePath != "" {
+			err := v.validateArchive(pkg.ArchivePath, pkg.SHA1)
+			if err != nil {
+				errs = append(errs, bosherr.WrapErrorf(err, "Package '%s' archive is corrupt", pkg.Name))
+			}
 		}
 
 		if release.IsCompiled() {
@@ -157,3 +169,13 @@ func (v *validator) validateReleasePackages(release Release) error {
 
 	return nil
 }
+
+// validateArchive checks the archive of a job or package against the digests in the release manifest
+func (v *validator) validateArchive(archivePath string, multipleDigest string) error {
+	digest, err := bicrypto.ParseMultipleDigest(multipleDigest)
+	if err != nil {
+		return err
+	}
+
+	return digest.Verify(v.digestCalculator, archivePath)
+}
@@ -5,16 +5,21 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	fakebicrypto "github.com/cloudfoundry/bosh-init/crypto/fakes"
 	bireljob "github.com/cloudfoundry/bosh-init/release/job"
 	birelpkg "github.com/cloudfoundry/bosh-init/release/pkg"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
 var _ = Describe("Validator", func() {
-	var fakeFs *fakesys.FakeFileSystem
+	var (
+		fakeFs           *fakesys.FakeFileSystem
+		digestCalculator *fakebicrypto.FakeDigestCalculator
+	)
 
 	BeforeEach(func() {
 		fakeFs = fakesys.NewFakeFileSystem()
+		digestCalculator = fakebicrypto.NewFakeDigestCalculator()
 	})
 
 	It("validates a valid release without error", func() {
@@ -47,14 +52,14 @@ var _ = Describe("Validator", func() {
 			fakeFs,
 			false,
 		)
-		validator := NewValidator(fakeFs)
+		validator := NewValidator(fakeFs, digestCalculator)
 
 		err := validator.Validate(release)
 		Expect(err).NotTo(HaveOccurred())
 	})
 
 	It("returns all errors with an empty release", func() {
-		validator := NewValidator(fakeFs)
+		validator := NewValidator(fakeFs, digestCalculator)
 		release := NewRelease(
 			"",
 			"",
@@ -80,7 +85,7 @@ var _ = Describe("Validator", func() {
 			fakeFs,
 			false,
 		)
-		validator := NewValidator(fakeFs)
+		validator := NewValidator(fakeFs, digestCalculator)
 
 		err := validator.Validate(release)
 		Expect(err).To(HaveOccurred())
@@ -122,7 +127,7 @@ var _ = Describe("Validator", func() {
 				fakeFs,
 				false,
 			)
-			validator := NewValidator(fakeFs)
+			validator := NewValidator(fakeFs, digestCalculator)
 
 			err := validator.Validate(release)
 			Expect(err).To(HaveOccurred())
@@ -154,7 +159,7 @@ var _ = Describe("Validator", func() {
 				fakeFs,
 				false,
 			)
-			validator := NewValidator(fakeFs)
+			validator := NewValidator(fakeFs, digestCalculator)
 
 			err := validator.Validate(release)
 			Expect(err).To(HaveOccurred())
@@ -190,7 +195,7 @@ var _ = Describe("Validator", func() {
 				fakeFs,
 				false,
 			)
-			validator := NewValidator(fakeFs)
+			validator := NewValidator(fakeFs, digestCalculator)
 
 			err := validator.Validate(release)
 			Expect(err).To(HaveOccurred())
@@ -233,7 +238,7 @@ var _ = Describe("Validator", func() {
 				fakeFs,
 				true,
 			)
-			validator := NewValidator(fakeFs)
+			validator := NewValidator(fakeFs, digestCalculator)
 
 			err := validator.Validate(release)
 			Expect(err).NotTo(HaveOccurred())
@@ -280,7 +285,7 @@ var _ = Describe("Validator", func() {
 				fakeFs,
 				true,
 			)
-			validator := NewValidator(fakeFs)
+			validator := NewValidator(fakeFs, digestCalculator)
 
 			err := validator.Validate(release)
 			Expect(err).To(HaveOccurred())
@@ -318,7 +323,7 @@ var _ = Describe("Validator", func() {
 				fakeFs,
 				true,
 			)
-			validator := NewValidator(fakeFs)
+			validator := NewValidator(fakeFs, digestCalculator)
 
 			err := validator.Validate(release)
 			Expect(err).To(HaveOccurred())
@@ -326,4 +331,57 @@ var _ = Describe("Validator", func() {
 		})
 	})
 
+	Context("when jobs and packages have archives", func() {
+		var release Release
+
+		BeforeEach(func() {
+			fakeFs.WriteFileString("/some/job/path/monit", "")
+			release = NewRelease(
+				"fake-release",
+				"fake-version",
+				[]bireljob.Job{
+					{
+						Name:          "fake-job",
+						Fingerprint:   "fake-job-fingerprint",
+						SHA1:          "fake-job-sha1",
+						ArchivePath:   "/some/release/path/jobs/fake-job.tgz",
+						ExtractedPath: "/some/job/path",
+					},
+				},
+				[]*birelpkg.Package{
+					{
+						Name:        "fake-package",
+						Fingerprint: "fake-package-fingerprint",
+						SHA1:        "sha256:7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7",
+						ArchivePath: "/some/release/path/packages/fake-package.tgz",
+					},
+				},
+				"/some/release/path",
+				fakeFs,
+				false,
+			)
+		})
+
+		It("validates the archives match their digests", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				"/some/release/path/jobs/fake-job.tgz":         {Value: "fake-job-sha1"},
+				"/some/release/path/packages/fake-package.tgz": {Value: "7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7"},
+			})
+
+			err := NewValidator(fakeFs, digestCalculator).Validate(release)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns errors with each corrupt archive", func() {
+			digestCalculator.SetCalculateBehavior(map[string]fakebicrypto.CalculateDigestInput{
+				"/some/release/path/jobs/fake-job.tgz":         {Value: "fake-other-sha1"},
+				"/some/release/path/packages/fake-package.tgz": {Value: "fake-other-sha256"},
+			})
+
+			err := NewValidator(fakeFs, digestCalculator).Validate(release)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Job 'fake-job' archive is corrupt: SHA1 'fake-other-sha1' does not match expected SHA1 'fake-job-sha1'"))
+			Expect(err.Error()).To(ContainSubstring("Package 'fake-package' archive is corrupt: SHA256 'fake-other-sha256' does not match expected SHA256 '7fc7c4986b7c2167816f3f1459755c3e9488014455ef06a77b96cf27e40f09e7'"))
+		})
+	})
 })
@@ -0,0 +1,36 @@
+package fakes
+
+import (
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
+)
+
+type FakeVerifier struct {
+	VerifyInputs []VerifyInput
+	VerifyErr    error
+
+	RequiresSignatureResult bool
+
+	SignedArtifactsArtifacts []bisignature.SignedArtifact
+}
+
+type VerifyInput struct {
+	Artifact    bisignature.Artifact
+	TarballPath string
+}
+
+func NewFakeVerifier() *FakeVerifier {
+	return &FakeVerifier{}
+}
+
+func (v *FakeVerifier) Verify(artifact bisignature.Artifact, tarballPath string) error {
+	v.VerifyInputs = append(v.VerifyInputs, VerifyInput{Artifact: artifact, TarballPath: tarballPath})
+	return v.VerifyErr
+}
+
+func (v *FakeVerifier) RequiresSignature() bool {
+	return v.RequiresSignatureResult
+}
+
+func (v *FakeVerifier) SignedArtifacts() []bisignature.SignedArtifact {
+	return v.SignedArtifactsArtifacts
+}
@@ -0,0 +1,215 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/openpgp"
+)
+
+const (
+	openPGPPublicKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	openPGPSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+)
+
+// Keyring is the set of public keys trusted to sign release and stemcell tarballs
+type Keyring struct {
+	openPGPKeys openpgp.EntityList
+	ed25519Keys []ed25519Key
+}
+
+type ed25519Key struct {
+	identity  string
+	publicKey ed25519.PublicKey
+}
+
+// LoadKeyring reads the trusted keys in the file at path, or in the files directly under the directory at path.
+// Files are either OpenPGP public keys, armored or binary,
+// or lines of 'ed25519 <base64 public key> <identity>' where empty lines and lines starting with '#' are ignored.
+func LoadKeyring(path string, fs boshsys.FileSystem) (Keyring, error) {
+	keyring := Keyring{}
+
+	expandedPath, err := fs.ExpandPath(path)
+	if err != nil {
+		return keyring, bosherr.WrapErrorf(err, "Expanding trusted keys path '%s'", path)
+	}
+
+	info, err := fs.Stat(expandedPath)
+	if err != nil {
+		return keyring, bosherr.WrapErrorf(err, "Checking trusted keys path '%s'", expandedPath)
+	}
+
+	keyPaths := []string{expandedPath}
+	if info.IsDir() {
+		keyPaths = []string{}
+		err = fs.Walk(expandedPath, func(keyPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && filepath.Dir(keyPath) == filepath.Clean(expandedPath) {
+				keyPaths = append(keyPaths, keyPath)
+			}
+			return nil
+		})
+		if err != nil {
+			return keyring, bosherr.WrapErrorf(err, "Listing trusted keys in '%s'", expandedPath)
+		}
+		sort.Strings(keyPaths)
+	}
+
+	for _, keyPath := range keyPaths {
+		contents, err := fs.ReadFile(keyPath)
+		if err != nil {
+			return keyring, bosherr.WrapErrorf(err, "Reading trusted keys '%s'", keyPath)
+		}
+
+		err = keyring.add(contents)
+		if err != nil {
+			return keyring, bosherr.WrapErrorf(err, "Parsing trusted keys '%s'", keyPath)
+		}
+	}
+
+	if keyring.Empty() {
+		return keyring, bosherr.Errorf("No trusted keys found in '%s'", expandedPath)
+	}
+
+	return keyring, nil
+}
+
+func (k *Keyring) add(contents []byte) error {
+	trimmed := bytes.TrimSpace(contents)
+
+	if bytes.HasPrefix(trimmed, []byte(openPGPPublicKeyHeader)) {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(trimmed))
+		if err != nil {
+			return bosherr.WrapError(err, "Reading armored OpenPGP keys")
+		}
+		k.openPGPKeys = append(k.openPGPKeys, entities...)
+		return nil
+	}
+
+	// binary OpenPGP packets have the high bit of their first byte set
+	if len(contents) > 0 && contents[0]&0x80 != 0 {
+		entities, err := openpgp.ReadKeyRing(bytes.NewReader(contents))
+		if err != nil {
+			return bosherr.WrapError(err, "Reading OpenPGP keys")
+		}
+		k.openPGPKeys = append(k.openPGPKeys, entities...)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "ed25519" {
+			return bosherr.Errorf("Line %d must be 'ed25519 <base64 public key> <identity>'", lineNumber)
+		}
+
+		publicKey, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return bosherr.Errorf("Line %d must have a base64 ed25519 public key of %d bytes", lineNumber, ed25519.PublicKeySize)
+		}
+
+		identity := strings.Join(fields[2:], " ")
+		if identity == "" {
+			identity = fields[1]
+		}
+
+		k.ed25519Keys = append(k.ed25519Keys, ed25519Key{identity: identity, publicKey: ed25519.PublicKey(publicKey)})
+	}
+
+	return scanner.Err()
+}
+
+func (k Keyring) Empty() bool {
+	return len(k.openPGPKeys) == 0 && len(k.ed25519Keys) == 0
+}
+
+// Verify checks the detached signature of the file at filePath, and returns the identity of the trusted key that made it.
+// The signature is either an OpenPGP signature, armored or binary, or a base64 ed25519 signature of the SHA-512 digest of the file,
+// so that neither needs the file in memory.
+func (k Keyring) Verify(filePath string, signature []byte, fs boshsys.FileSystem) (string, error) {
+	trimmed := bytes.TrimSpace(signature)
+
+	file, err := fs.OpenFile(filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Opening '%s'", filePath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if !bytes.HasPrefix(trimmed, []byte(openPGPSignatureHeader)) {
+		ed25519Signature, err := base64.StdEncoding.DecodeString(string(trimmed))
+		if err == nil && len(ed25519Signature) == ed25519.SignatureSize {
+			return k.verifyEd25519(file, ed25519Signature)
+		}
+	}
+
+	var signer *openpgp.Entity
+	if bytes.HasPrefix(trimmed, []byte(openPGPSignatureHeader)) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(k.openPGPKeys, file, bytes.NewReader(trimmed))
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(k.openPGPKeys, file, bytes.NewReader(signature))
+	}
+	if err != nil {
+		return "", bosherr.WrapError(err, "Checking OpenPGP signature against the trusted keys")
+	}
+
+	return openPGPIdentity(signer), nil
+}
+
+func (k Keyring) verifyEd25519(file io.Reader, signature []byte) (string, error) {
+	if len(k.ed25519Keys) == 0 {
+		return "", bosherr.Error("Checking ed25519 signature: no trusted ed25519 keys")
+	}
+
+	hash := sha512.New()
+	_, err := io.Copy(hash, file)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Computing the SHA-512 digest")
+	}
+	digest := hash.Sum(nil)
+
+	for _, key := range k.ed25519Keys {
+		if ed25519.Verify(key.publicKey, digest, signature) {
+			return fmt.Sprintf("%s (ed25519)", key.identity), nil
+		}
+	}
+
+	return "", bosherr.Error("Checking ed25519 signature: not signed by any of the trusted keys")
+}
+
+// openPGPIdentity is the name of the primary identity of the key, or its first identity, with the key id
+func openPGPIdentity(entity *openpgp.Entity) string {
+	names := []string{}
+	for name, identity := range entity.Identities {
+		if identity.SelfSignature != nil && identity.SelfSignature.IsPrimaryId != nil && *identity.SelfSignature.IsPrimaryId {
+			names = []string{name}
+			break
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return entity.PrimaryKey.KeyIdString()
+	}
+	return fmt.Sprintf("%s (%s)", names[0], entity.PrimaryKey.KeyIdString())
+}
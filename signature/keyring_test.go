@@ -0,0 +1,201 @@
+package signature_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"io/ioutil"
+
+	. "github.com/cloudfoundry/bosh-init/signature"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func newOpenPGPEntity(name string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	Expect(err).ToNot(HaveOccurred())
+
+	// serializing the private key self-signs the identities and subkeys of the new entity
+	Expect(entity.SerializePrivate(ioutil.Discard, nil)).To(Succeed())
+	return entity
+}
+
+func armoredOpenPGPPublicKey(entity *openpgp.Entity) string {
+	buffer := &bytes.Buffer{}
+	writer, err := armor.Encode(buffer, openpgp.PublicKeyType, nil)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(entity.Serialize(writer)).To(Succeed())
+	Expect(writer.Close()).To(Succeed())
+	return buffer.String()
+}
+
+func armoredOpenPGPSignature(entity *openpgp.Entity, contents string) []byte {
+	buffer := &bytes.Buffer{}
+	err := openpgp.ArmoredDetachSign(buffer, entity, bytes.NewBufferString(contents), nil)
+	Expect(err).ToNot(HaveOccurred())
+	return buffer.Bytes()
+}
+
+func newEd25519Key() (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	return publicKey, privateKey
+}
+
+func ed25519Signature(privateKey ed25519.PrivateKey, contents string) []byte {
+	digest := sha512.Sum512([]byte(contents))
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, digest[:])) + "\n")
+}
+
+var _ = Describe("Keyring", func() {
+	var (
+		fakeFS *fakesys.FakeFileSystem
+	)
+
+	BeforeEach(func() {
+		fakeFS = fakesys.NewFakeFileSystem()
+		fakeFS.WriteFileString("/fake-release.tgz", "fake-release-contents")
+	})
+
+	Describe("LoadKeyring", func() {
+		It("returns an error when the file has no keys", func() {
+			fakeFS.WriteFileString("/fake-keys", "# no keys yet\n\n")
+
+			_, err := LoadKeyring("/fake-keys", fakeFS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No trusted keys found in '/fake-keys'"))
+		})
+
+		It("returns an error when a line is not an ed25519 key", func() {
+			fakeFS.WriteFileString("/fake-keys", "# comment\nrsa fake-key fake-identity\n")
+
+			_, err := LoadKeyring("/fake-keys", fakeFS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Line 2 must be 'ed25519 <base64 public key> <identity>'"))
+		})
+
+		It("returns an error when an ed25519 key has the wrong size", func() {
+			fakeFS.WriteFileString("/fake-keys", "ed25519 ZmFrZS1rZXk= fake-identity\n")
+
+			_, err := LoadKeyring("/fake-keys", fakeFS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Line 1 must have a base64 ed25519 public key of 32 bytes"))
+		})
+
+		It("returns an error when an armored OpenPGP key is invalid", func() {
+			fakeFS.WriteFileString("/fake-keys", "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nfake\n-----END PGP PUBLIC KEY BLOCK-----\n")
+
+			_, err := LoadKeyring("/fake-keys", fakeFS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading armored OpenPGP keys"))
+		})
+
+		It("loads the keys of every file directly under a directory", func() {
+			entity := newOpenPGPEntity("fake-pgp-signer")
+			publicKey, privateKey := newEd25519Key()
+			fakeFS.MkdirAll("/fake-keys", 0755)
+			fakeFS.WriteFileString("/fake-keys/pgp.asc", armoredOpenPGPPublicKey(entity))
+			fakeFS.WriteFileString("/fake-keys/ed25519", "ed25519 "+base64.StdEncoding.EncodeToString(publicKey)+" fake-ed25519-signer\n")
+
+			keyring, err := LoadKeyring("/fake-keys", fakeFS)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyring.Empty()).To(BeFalse())
+
+			signer, err := keyring.Verify("/fake-release.tgz", ed25519Signature(privateKey, "fake-release-contents"), fakeFS)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer).To(Equal("fake-ed25519-signer (ed25519)"))
+		})
+	})
+
+	Describe("Verify", func() {
+		Context("with OpenPGP keys", func() {
+			var (
+				entity  *openpgp.Entity
+				keyring Keyring
+			)
+
+			BeforeEach(func() {
+				entity = newOpenPGPEntity("fake-pgp-signer")
+				fakeFS.WriteFileString("/fake-keys.asc", armoredOpenPGPPublicKey(entity))
+
+				var err error
+				keyring, err = LoadKeyring("/fake-keys.asc", fakeFS)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the identity of the key that signed the file", func() {
+				signer, err := keyring.Verify("/fake-release.tgz", armoredOpenPGPSignature(entity, "fake-release-contents"), fakeFS)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(signer).To(Equal("fake-pgp-signer <fake-pgp-signer@example.com> (" + entity.PrimaryKey.KeyIdString() + ")"))
+			})
+
+			It("accepts binary signatures", func() {
+				signature := &bytes.Buffer{}
+				err := openpgp.DetachSign(signature, entity, bytes.NewBufferString("fake-release-contents"), nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = keyring.Verify("/fake-release.tgz", signature.Bytes(), fakeFS)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns an error when the file was modified", func() {
+				_, err := keyring.Verify("/fake-release.tgz", armoredOpenPGPSignature(entity, "other-contents"), fakeFS)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Checking OpenPGP signature against the trusted keys"))
+			})
+
+			It("returns an error when the file was signed by an untrusted key", func() {
+				untrusted := newOpenPGPEntity("fake-untrusted-signer")
+
+				_, err := keyring.Verify("/fake-release.tgz", armoredOpenPGPSignature(untrusted, "fake-release-contents"), fakeFS)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Checking OpenPGP signature against the trusted keys"))
+			})
+		})
+
+		Context("with ed25519 keys", func() {
+			var (
+				privateKey ed25519.PrivateKey
+				keyring    Keyring
+			)
+
+			BeforeEach(func() {
+				var publicKey ed25519.PublicKey
+				publicKey, privateKey = newEd25519Key()
+				fakeFS.WriteFileString("/fake-keys", "# release signers\ned25519 "+base64.StdEncoding.EncodeToString(publicKey)+" fake-ed25519-signer\n")
+
+				var err error
+				keyring, err = LoadKeyring("/fake-keys", fakeFS)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns an error when the file was modified", func() {
+				_, err := keyring.Verify("/fake-release.tgz", ed25519Signature(privateKey, "other-contents"), fakeFS)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not signed by any of the trusted keys"))
+			})
+
+			It("returns an error when the file contents were signed instead of their SHA-512 digest", func() {
+				signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte("fake-release-contents")))
+
+				_, err := keyring.Verify("/fake-release.tgz", []byte(signature), fakeFS)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not signed by any of the trusted keys"))
+			})
+
+			It("returns an error when the file was signed by an untrusted key", func() {
+				_, untrustedKey := newEd25519Key()
+
+				_, err := keyring.Verify("/fake-release.tgz", ed25519Signature(untrustedKey, "fake-release-contents"), fakeFS)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not signed by any of the trusted keys"))
+			})
+		})
+	})
+})
@@ -0,0 +1,168 @@
+package signature
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// maxSignatureSize bounds the size of the signatures read into memory
+const maxSignatureSize = 1024 * 1024
+
+// Artifact is a release or stemcell tarball with an optional detached signature
+type Artifact struct {
+	// Type is either 'release' or 'stemcell'
+	Type      string
+	Name      string
+	URL       string
+	SHA1      string
+	Signature string
+}
+
+func (a Artifact) description() string {
+	if a.Name == "" {
+		return a.Type
+	}
+	return fmt.Sprintf("%s '%s'", a.Type, a.Name)
+}
+
+// SignedArtifact is an artifact whose signature was verified, with the identity of the trusted key that made it
+type SignedArtifact struct {
+	Artifact Artifact
+	Signer   string
+}
+
+// Verifier checks the signatures of release and stemcell tarballs against a trust policy
+type Verifier interface {
+	// Verify checks the signature of the artifact tarball at tarballPath and remembers its signer.
+	// Artifacts without a signature are accepted when no trusted keys are configured.
+	Verify(artifact Artifact, tarballPath string) error
+
+	// RequiresSignature is true when trusted keys are configured, so that unsigned artifacts are rejected
+	RequiresSignature() bool
+
+	// SignedArtifacts returns the artifacts verified so far, so that their signers are only recorded once deployed
+	SignedArtifacts() []SignedArtifact
+}
+
+type verifier struct {
+	keyring         Keyring
+	schemeRegistry  bitarball.SchemeRegistry
+	signedArtifacts []SignedArtifact
+	signedLock      sync.Mutex
+	fs              boshsys.FileSystem
+	logger          boshlog.Logger
+	logTag          string
+}
+
+// NewVerifier returns a verifier that requires every artifact to be signed by one of the keys of the keyring,
+// or that only verifies the artifacts with a signature when the keyring is empty.
+// Signatures are read from paths, file:// URLs or the URLs of the scheme registry.
+func NewVerifier(
+	keyring Keyring,
+	schemeRegistry bitarball.SchemeRegistry,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) Verifier {
+	return &verifier{
+		keyring:        keyring,
+		schemeRegistry: schemeRegistry,
+		fs:             fs,
+		logger:         logger,
+		logTag:         "signatureVerifier",
+	}
+}
+
+func (v *verifier) Verify(artifact Artifact, tarballPath string) error {
+	if artifact.Signature == "" {
+		if !v.keyring.Empty() {
+			return bosherr.Errorf("The %s has no signature, but trusted keys are configured in BOSH_INIT_TRUSTED_KEYS", artifact.description())
+		}
+
+		v.logger.Debug(v.logTag, "Skipping signature verification of unsigned %s", artifact.description())
+		return nil
+	}
+
+	if v.keyring.Empty() {
+		return bosherr.Errorf("Verifying the signature of the %s requires trusted keys in BOSH_INIT_TRUSTED_KEYS", artifact.description())
+	}
+
+	signature, err := v.readSignature(artifact.Signature)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading the signature of the %s from '%s'", artifact.description(), artifact.Signature)
+	}
+
+	signer, err := v.keyring.Verify(tarballPath, signature, v.fs)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Verifying the signature of the %s", artifact.description())
+	}
+
+	v.logger.Info(v.logTag, "Verified the signature of the %s by '%s'", artifact.description(), signer)
+
+	v.signedLock.Lock()
+	defer v.signedLock.Unlock()
+
+	signedArtifacts := []SignedArtifact{}
+	for _, existing := range v.signedArtifacts {
+		if existing.Artifact.Type != artifact.Type || existing.Artifact.Name != artifact.Name {
+			signedArtifacts = append(signedArtifacts, existing)
+		}
+	}
+	v.signedArtifacts = append(signedArtifacts, SignedArtifact{Artifact: artifact, Signer: signer})
+
+	return nil
+}
+
+func (v *verifier) RequiresSignature() bool {
+	return !v.keyring.Empty()
+}
+
+func (v *verifier) SignedArtifacts() []SignedArtifact {
+	v.signedLock.Lock()
+	defer v.signedLock.Unlock()
+
+	return append([]SignedArtifact{}, v.signedArtifacts...)
+}
+
+func (v *verifier) readSignature(location string) ([]byte, error) {
+	if !strings.Contains(location, "://") || strings.HasPrefix(location, "file://") {
+		path, err := v.fs.ExpandPath(strings.TrimPrefix(location, "file://"))
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Expanding path '%s'", location)
+		}
+		return v.fs.ReadFile(path)
+	}
+
+	downloader, found := v.schemeRegistry.Downloader(location)
+	if !found {
+		return nil, bosherr.Errorf("Invalid signature URL, must be a path or either file:// or %s://", strings.Join(v.schemeRegistry.Schemes(), ":// or "))
+	}
+
+	stream, err := downloader.Open(location, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := stream.Body.Close(); err != nil {
+			v.logger.Warn(v.logTag, "Failed to close signature stream: %s", err.Error())
+		}
+	}()
+
+	signature, err := ioutil.ReadAll(io.LimitReader(stream.Body, maxSignatureSize+1))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Downloading signature")
+	}
+
+	if len(signature) > maxSignatureSize {
+		return nil, bosherr.Errorf("Signature is larger than %d bytes", maxSignatureSize)
+	}
+
+	return signature, nil
+}
@@ -0,0 +1,176 @@
+package signature_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/cloudfoundry/bosh-init/signature"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"golang.org/x/crypto/ed25519"
+)
+
+type fakeDownloader struct {
+	contents  map[string]string
+	openedURL string
+}
+
+func (d *fakeDownloader) Open(url string, offset int64) (bitarball.Stream, error) {
+	d.openedURL = url
+	contents, found := d.contents[url]
+	if !found {
+		return bitarball.Stream{}, errors.New("fake-not-found-error")
+	}
+	return bitarball.Stream{Body: ioutil.NopCloser(strings.NewReader(contents)), Size: int64(len(contents))}, nil
+}
+
+var _ = Describe("Verifier", func() {
+	var (
+		fakeFS         *fakesys.FakeFileSystem
+		downloader     *fakeDownloader
+		schemeRegistry bitarball.SchemeRegistry
+		privateKey     ed25519.PrivateKey
+		keyring        Keyring
+		artifact       Artifact
+		verifier       Verifier
+	)
+
+	BeforeEach(func() {
+		fakeFS = fakesys.NewFakeFileSystem()
+		fakeFS.WriteFileString("/fake-release.tgz", "fake-release-contents")
+
+		downloader = &fakeDownloader{contents: map[string]string{}}
+		schemeRegistry = bitarball.NewSchemeRegistry()
+		schemeRegistry.Register("https", downloader)
+
+		var publicKey ed25519.PublicKey
+		publicKey, privateKey = newEd25519Key()
+		fakeFS.WriteFileString("/fake-keys", "ed25519 "+base64.StdEncoding.EncodeToString(publicKey)+" fake-signer\n")
+
+		var err error
+		keyring, err = LoadKeyring("/fake-keys", fakeFS)
+		Expect(err).ToNot(HaveOccurred())
+
+		artifact = Artifact{Type: "release", Name: "fake-release", URL: "https://fake-url", SHA1: "fake-sha1"}
+	})
+
+	JustBeforeEach(func() {
+		verifier = NewVerifier(keyring, schemeRegistry, fakeFS, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Context("when no trusted keys are configured", func() {
+		BeforeEach(func() {
+			keyring = Keyring{}
+		})
+
+		It("accepts unsigned artifacts", func() {
+			err := verifier.Verify(artifact, "/fake-release.tgz")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verifier.SignedArtifacts()).To(BeEmpty())
+		})
+
+		It("does not require signatures", func() {
+			Expect(verifier.RequiresSignature()).To(BeFalse())
+		})
+
+		It("returns an error for signed artifacts", func() {
+			artifact.Signature = "/fake-release.tgz.sig"
+
+			err := verifier.Verify(artifact, "/fake-release.tgz")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Verifying the signature of the release 'fake-release' requires trusted keys in BOSH_INIT_TRUSTED_KEYS"))
+		})
+	})
+
+	It("requires signatures", func() {
+		Expect(verifier.RequiresSignature()).To(BeTrue())
+	})
+
+	It("returns an error for unsigned artifacts", func() {
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("The release 'fake-release' has no signature, but trusted keys are configured in BOSH_INIT_TRUSTED_KEYS"))
+	})
+
+	It("verifies signatures read from a path and remembers the signer", func() {
+		fakeFS.WriteFile("/fake-release.tgz.sig", ed25519Signature(privateKey, "fake-release-contents"))
+		artifact.Signature = "/fake-release.tgz.sig"
+
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verifier.SignedArtifacts()).To(Equal([]SignedArtifact{{Artifact: artifact, Signer: "fake-signer (ed25519)"}}))
+	})
+
+	It("verifies signatures read from file:// URLs", func() {
+		fakeFS.WriteFile("/fake-release.tgz.sig", ed25519Signature(privateKey, "fake-release-contents"))
+		artifact.Signature = "file:///fake-release.tgz.sig"
+
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verifier.SignedArtifacts()).To(HaveLen(1))
+	})
+
+	It("downloads signatures with the downloader of their scheme", func() {
+		downloader.contents["https://fake-url.sig"] = string(ed25519Signature(privateKey, "fake-release-contents"))
+		artifact.Signature = "https://fake-url.sig"
+
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(downloader.openedURL).To(Equal("https://fake-url.sig"))
+		Expect(verifier.SignedArtifacts()).To(HaveLen(1))
+	})
+
+	It("returns an error when the signature URL has an unknown scheme", func() {
+		artifact.Signature = "ftp://fake-url.sig"
+
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Invalid signature URL, must be a path or either file:// or https://"))
+	})
+
+	It("returns an error when the signature can not be downloaded", func() {
+		artifact.Signature = "https://fake-missing.sig"
+
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading the signature of the release 'fake-release' from 'https://fake-missing.sig'"))
+		Expect(err.Error()).To(ContainSubstring("fake-not-found-error"))
+	})
+
+	It("returns an error without remembering the signer when the signature does not match", func() {
+		fakeFS.WriteFile("/fake-release.tgz.sig", ed25519Signature(privateKey, "other-contents"))
+		artifact.Signature = "/fake-release.tgz.sig"
+
+		err := verifier.Verify(artifact, "/fake-release.tgz")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Verifying the signature of the release 'fake-release'"))
+		Expect(verifier.SignedArtifacts()).To(BeEmpty())
+	})
+
+	It("remembers the last signer of each artifact", func() {
+		fakeFS.WriteFile("/fake-release.tgz.sig", ed25519Signature(privateKey, "fake-release-contents"))
+		artifact.Signature = "/fake-release.tgz.sig"
+
+		fakeFS.WriteFileString("/fake-stemcell.tgz", "fake-release-contents")
+		fakeFS.WriteFileString("/other-fake-release.tgz", "fake-release-contents")
+
+		Expect(verifier.Verify(artifact, "/fake-release.tgz")).To(Succeed())
+
+		otherArtifact := Artifact{Type: "stemcell", Name: "fake-stemcell", Signature: "/fake-release.tgz.sig"}
+		Expect(verifier.Verify(otherArtifact, "/fake-stemcell.tgz")).To(Succeed())
+
+		artifact.URL = "https://other-fake-url"
+		Expect(verifier.Verify(artifact, "/other-fake-release.tgz")).To(Succeed())
+
+		Expect(verifier.SignedArtifacts()).To(Equal([]SignedArtifact{
+			{Artifact: otherArtifact, Signer: "fake-signer (ed25519)"},
+			{Artifact: artifact, Signer: "fake-signer (ed25519)"},
+		}))
+	})
+})
@@ -1,8 +1,12 @@
 package stemcell
 
 import (
+	"fmt"
+
+	bicatalog "github.com/cloudfoundry/bosh-init/catalog"
 	bideplmanifest "github.com/cloudfoundry/bosh-init/deployment/manifest"
 	bitarball "github.com/cloudfoundry/bosh-init/installation/tarball"
+	bisignature "github.com/cloudfoundry/bosh-init/signature"
 	biui "github.com/cloudfoundry/bosh-init/ui"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
@@ -10,6 +14,8 @@ import (
 type Fetcher struct {
 	TarballProvider   bitarball.Provider
 	StemcellExtractor Extractor
+	CatalogResolver   bicatalog.Resolver
+	SignatureVerifier bisignature.Verifier
 }
 
 func (s Fetcher) GetStemcell(deploymentManifest bideplmanifest.Manifest, stage biui.Stage) (ExtractedStemcell, error) {
@@ -18,11 +24,48 @@ func (s Fetcher) GetStemcell(deploymentManifest bideplmanifest.Manifest, stage b
 		return nil, err
 	}
 
+	if stemcell.URL == "" {
+		err = stage.Perform(fmt.Sprintf("Resolving stemcell '%s' version '%s'", stemcell.Name, stemcell.Version), func() error {
+			entry, err := s.CatalogResolver.ResolveStemcell(stemcell.Name, stemcell.Version)
+			if err != nil {
+				return err
+			}
+
+			stemcell.URL = entry.URL
+			stemcell.SHA1 = entry.SHA1
+			if stemcell.Signature == "" {
+				stemcell.Signature = entry.Signature
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	stemcellTarballPath, err := s.TarballProvider.Get(stemcell, stage)
 	if err != nil {
 		return nil, err
 	}
 
+	artifact := bisignature.Artifact{
+		Type:      "stemcell",
+		Name:      stemcell.Name,
+		URL:       stemcell.URL,
+		SHA1:      stemcell.SHA1,
+		Signature: stemcell.Signature,
+	}
+	if stemcell.Signature == "" {
+		err = s.SignatureVerifier.Verify(artifact, stemcellTarballPath)
+	} else {
+		err = stage.Perform("Verifying signature of stemcell", func() error {
+			return s.SignatureVerifier.Verify(artifact, stemcellTarballPath)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	var extractedStemcell ExtractedStemcell
 	err = stage.Perform("Validating stemcell", func() error {
 		extractedStemcell, err = s.StemcellExtractor.Extract(stemcellTarballPath)
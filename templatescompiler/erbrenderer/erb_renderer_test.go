@@ -2,6 +2,8 @@ package erbrenderer_test
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
 
 	. "github.com/cloudfoundry/bosh-init/templatescompiler/erbrenderer"
 	fakebierbrenderer "github.com/cloudfoundry/bosh-init/templatescompiler/erbrenderer/fakes"
@@ -12,6 +14,12 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type jsonTemplateEvaluationContext string
+
+func (c jsonTemplateEvaluationContext) MarshalJSON() ([]byte, error) {
+	return []byte(c), nil
+}
+
 var _ = Describe("ErbRenderer", func() {
 	var (
 		fs          *fakesys.FakeFileSystem
@@ -85,4 +93,85 @@ var _ = Describe("ErbRenderer", func() {
 			Expect(err.Error()).To(ContainSubstring("fake-cmd-error"))
 		})
 	})
+
+	Describe("rendering templates that consume links", func() {
+		var (
+			linkContext jsonTemplateEvaluationContext
+			render      func(erbContents string) (string, error)
+		)
+
+		BeforeEach(func() {
+			linkContext = jsonTemplateEvaluationContext(`{
+				"job": {"name": "fake-job-name"},
+				"index": 0,
+				"job_properties": {},
+				"default_properties": {},
+				"links": {
+					"fake-db": {
+						"address": "10.0.0.5",
+						"instances": [{"name": "fake-db-job-name", "index": 0, "az": "fake-az", "address": "10.0.0.5", "bootstrap": true}],
+						"properties": {"port": 5432}
+					}
+				}
+			}`)
+
+			render = func(erbContents string) (string, error) {
+				logger := boshlog.NewLogger(boshlog.LevelNone)
+				osFS := boshsys.NewOsFileSystem(logger)
+				erbRenderer := NewERBRenderer(osFS, boshsys.NewExecCmdRunner(logger), logger)
+
+				srcFile, err := ioutil.TempFile("", "source.txt.erb")
+				Expect(err).ToNot(HaveOccurred())
+				defer os.Remove(srcFile.Name())
+
+				_, err = srcFile.WriteString(erbContents)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(srcFile.Close()).To(Succeed())
+
+				destFile, err := ioutil.TempFile("", "dest.txt")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(destFile.Close()).To(Succeed())
+				defer os.Remove(destFile.Name())
+
+				err = erbRenderer.Render(srcFile.Name(), destFile.Name(), linkContext)
+				if err != nil {
+					return "", err
+				}
+
+				contents, err := ioutil.ReadFile(destFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+				return string(contents), nil
+			}
+		})
+
+		It("renders the address, instances and properties of a resolved link", func() {
+			contents, err := render(`<%= link("fake-db").address %>:<%= link("fake-db").p("port") %> <% link("fake-db").instances.each do |i| %><%= i.name %>/<%= i.index %> <%= i.az %> <%= i.bootstrap %><% end %>`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(Equal("10.0.0.5:5432 fake-db-job-name/0 fake-az true"))
+		})
+
+		It("returns an error when the link is missing", func() {
+			_, err := render(`<%= link("fake-missing").address %>`)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Can't find link 'fake-missing'"))
+		})
+
+		It("renders the if_link block of a resolved link", func() {
+			contents, err := render(`<% if_link("fake-db") do |db| %><%= db.address %><% end.else do %>no-db<% end %>`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(Equal("10.0.0.5"))
+		})
+
+		It("renders the else_if_link block when the first link is missing", func() {
+			contents, err := render(`<% if_link("fake-missing") do |missing| %>missing<% end.else_if_link("fake-db") do |db| %><%= db.p("port") %><% end %>`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(Equal("5432"))
+		})
+
+		It("renders the else block when no link is provided", func() {
+			contents, err := render(`<% if_link("fake-missing") do |missing| %>missing<% end.else_if_link("fake-other-missing") do |other| %>other<% end.else do %>no-links<% end %>`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(Equal("no-links"))
+		})
+	})
 })
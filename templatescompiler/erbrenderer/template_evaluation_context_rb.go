@@ -29,6 +29,7 @@ class TemplateEvaluationContext
   def initialize(spec)
     @name = spec["job"]["name"] if spec["job"].is_a?(Hash)
     @index = spec["index"]
+    @links = spec["links"] || {}
 
     if !spec['job_properties'].nil?
       properties1 = spec['job_properties']
@@ -73,8 +74,18 @@ class TemplateEvaluationContext
     InactiveElseBlock.new
   end
   
+  def link(name)
+    link_spec = @links[name]
+    raise UnknownLink.new(name) if link_spec.nil?
+    EvaluationLink.new(link_spec)
+  end
+
   def if_link(name)
-    false
+    link_spec = @links[name]
+    return ActiveElseBlock.new(self) if link_spec.nil?
+
+    yield EvaluationLink.new(link_spec)
+    InactiveElseBlock.new
   end
 
   private
@@ -131,6 +142,66 @@ class TemplateEvaluationContext
     end
   end
 
+  class UnknownLink < StandardError
+    def initialize(name)
+      super("Can't find link '#{name}'")
+    end
+  end
+
+  class EvaluationLinkInstance
+    attr_reader :name, :index, :az, :address, :bootstrap
+
+    def initialize(instance)
+      @name = instance["name"]
+      @index = instance["index"]
+      @az = instance["az"]
+      @address = instance["address"]
+      @bootstrap = instance["bootstrap"]
+    end
+  end
+
+  class EvaluationLink
+    attr_reader :address, :instances, :properties
+
+    def initialize(link_spec)
+      @address = link_spec["address"]
+      @instances = (link_spec["instances"] || []).map { |instance| EvaluationLinkInstance.new(instance) }
+      @properties = link_spec["properties"] || {}
+    end
+
+    def p(*args)
+      names = Array(args[0])
+
+      names.each do |name|
+        result = lookup_property(name)
+        return result unless result.nil?
+      end
+
+      return args[1] if args.length == 2
+      raise UnknownProperty.new(names)
+    end
+
+    def if_p(*names)
+      values = names.map do |name|
+        value = lookup_property(name)
+        return ActiveElseBlock.new(self) if value.nil?
+        value
+      end
+
+      yield *values
+      InactiveElseBlock.new
+    end
+
+    private
+
+    def lookup_property(name)
+      name.split(".").inject(@properties) do |ref, key|
+        return nil unless ref.is_a?(Hash)
+        ref[key]
+      end
+    end
+  end
+
   class ActiveElseBlock
     def initialize(template)
       @context = template
@@ -143,6 +214,10 @@ class TemplateEvaluationContext
     def else_if_p(*names, &block)
       @context.if_p(*names, &block)
     end
+
+    def else_if_link(name, &block)
+      @context.if_link(name, &block)
+    end
   end
 
   class InactiveElseBlock
@@ -151,6 +226,10 @@ class TemplateEvaluationContext
     def else_if_p(*names)
       InactiveElseBlock.new
     end
+
+    def else_if_link(name)
+      InactiveElseBlock.new
+    end
   end
 end
 
@@ -16,7 +16,7 @@ type jobEvaluationContext struct {
 	jobProperties        biproperty.Map
 	globalProperties     biproperty.Map
 	deploymentName       string
-	address              string
+	instance             InstanceContext
 	logger               boshlog.Logger
 	logTag               string
 }
@@ -33,7 +33,10 @@ type RootContext struct {
 	Address    string     `json:"address,omitempty"`
 
 	// Usually is accessed with <%= spec.networks.default.ip %>
-	NetworkContexts map[string]networkContext `json:"networks"`
+	NetworkContexts map[string]NetworkContext `json:"networks"`
+
+	// Accessed with link() and if_link()
+	Links map[string]LinkContext `json:"links"`
 
 	//TODO: this should be a map[string]interface{}
 	GlobalProperties  biproperty.Map  `json:"global_properties"`  // values from manifest's top-level properties
@@ -46,10 +49,37 @@ type jobContext struct {
 	Name string `json:"name"`
 }
 
-type networkContext struct {
-	IP      string `json:"ip"`
-	Netmask string `json:"netmask"`
-	Gateway string `json:"gateway"`
+// InstanceContext describes the instance the templates are rendered for
+type InstanceContext struct {
+	Address  string
+	AZ       string
+	Networks map[string]NetworkContext
+	// Links are keyed by release job name and consumed link name
+	Links map[string]map[string]LinkContext
+}
+
+// LinkContext is a consumed link, as returned by link() in templates
+type LinkContext struct {
+	Address    string                `json:"address"`
+	Instances  []LinkInstanceContext `json:"instances"`
+	Properties biproperty.Map        `json:"properties"`
+}
+
+type LinkInstanceContext struct {
+	Name      string `json:"name"`
+	Index     int    `json:"index"`
+	AZ        string `json:"az"`
+	Address   string `json:"address"`
+	Bootstrap bool   `json:"bootstrap"`
+}
+
+// NetworkContext describes an instance network, netmask is in dotted decimal notation for IPv4 networks
+// and in canonical notation for IPv6 networks
+type NetworkContext struct {
+	IP      string   `json:"ip"`
+	Netmask string   `json:"netmask"`
+	Gateway string   `json:"gateway"`
+	Default []string `json:"default,omitempty"`
 }
 
 func NewJobEvaluationContext(
@@ -58,7 +88,7 @@ func NewJobEvaluationContext(
 	jobProperties biproperty.Map,
 	globalProperties biproperty.Map,
 	deploymentName string,
-	address string,
+	instance InstanceContext,
 	logger boshlog.Logger,
 ) bierbrenderer.TemplateEvaluationContext {
 	return jobEvaluationContext{
@@ -67,7 +97,7 @@ func NewJobEvaluationContext(
 		jobProperties:        jobProperties,
 		globalProperties:     globalProperties,
 		deploymentName:       deploymentName,
-		address:              address,
+		instance:             instance,
 		logger:               logger,
 		logTag:               "jobEvaluationContext",
 	}
@@ -84,14 +114,19 @@ func (ec jobEvaluationContext) MarshalJSON() ([]byte, error) {
 		JobContext:        jobContext{Name: ec.releaseJob.Name},
 		Deployment:        ec.deploymentName,
 		NetworkContexts:   ec.buildNetworkContexts(),
+		Links:             ec.buildLinkContexts(),
 		GlobalProperties:  ec.globalProperties,
 		ClusterProperties: ec.jobProperties,
 		JobProperties:     ec.releaseJobProperties,
 		DefaultProperties: defaultProperties,
 	}
 
-	if len(ec.address) > 0 {
-		context.Address = ec.address
+	if len(ec.instance.Address) > 0 {
+		context.Address = ec.instance.Address
+	}
+
+	if len(ec.instance.AZ) > 0 {
+		context.AZ = ec.instance.AZ
 	}
 
 	ec.logger.Debug(ec.logTag, "Marshalling context %#v", context)
@@ -112,11 +147,25 @@ func (ec jobEvaluationContext) propertyDefaults(properties map[string]bireljob.P
 	return result
 }
 
-func (ec jobEvaluationContext) buildNetworkContexts() map[string]networkContext {
+func (ec jobEvaluationContext) buildNetworkContexts() map[string]NetworkContext {
 	// IP is being returned by agent
-	return map[string]networkContext{
-		"default": networkContext{
+	networkContexts := map[string]NetworkContext{
+		"default": NetworkContext{
 			IP: "",
 		},
 	}
+
+	for name, networkContext := range ec.instance.Networks {
+		networkContexts[name] = networkContext
+	}
+
+	return networkContexts
+}
+
+func (ec jobEvaluationContext) buildLinkContexts() map[string]LinkContext {
+	linkContexts := map[string]LinkContext{}
+	for name, linkContext := range ec.instance.Links[ec.releaseJob.Name] {
+		linkContexts[name] = linkContext
+	}
+	return linkContexts
 }
@@ -24,6 +24,9 @@ var _ = Describe("JobEvaluationContext", func() {
 		jobProperties           *biproperty.Map
 		instanceGroupProperties biproperty.Map
 		deploymentProperties    biproperty.Map
+		az                      string
+		networks                map[string]NetworkContext
+		links                   map[string]map[string]LinkContext
 	)
 	BeforeEach(func() {
 		generatedContext = RootContext{}
@@ -45,6 +48,10 @@ var _ = Describe("JobEvaluationContext", func() {
 		instanceGroupProperties = biproperty.Map{}
 
 		jobProperties = nil
+
+		az = ""
+		networks = nil
+		links = nil
 	})
 
 	JustBeforeEach(func() {
@@ -56,7 +63,7 @@ var _ = Describe("JobEvaluationContext", func() {
 			instanceGroupProperties,
 			deploymentProperties,
 			"fake-deployment-name",
-			"1.2.3.4",
+			InstanceContext{Address: "1.2.3.4", AZ: az, Networks: networks, Links: links},
 			logger,
 		)
 
@@ -71,6 +78,57 @@ var _ = Describe("JobEvaluationContext", func() {
 		Expect(generatedContext.NetworkContexts["default"].IP).To(Equal(""))
 	})
 
+	Context("when the instance has networks", func() {
+		BeforeEach(func() {
+			networks = map[string]NetworkContext{
+				"fake-ipv4-network": {
+					IP:      "10.0.0.6",
+					Netmask: "255.255.255.0",
+					Gateway: "10.0.0.1",
+					Default: []string{"dns", "gateway"},
+				},
+				"fake-ipv6-network": {
+					IP:      "2001:db8::6",
+					Netmask: "ffff:ffff:ffff:ffff::",
+					Gateway: "2001:db8::1",
+					Default: []string{"gateway"},
+				},
+			}
+		})
+
+		It("it has a network context section for each network", func() {
+			Expect(generatedContext.NetworkContexts["fake-ipv4-network"]).To(Equal(networks["fake-ipv4-network"]))
+			Expect(generatedContext.NetworkContexts["fake-ipv6-network"]).To(Equal(networks["fake-ipv6-network"]))
+			Expect(generatedContext.NetworkContexts["default"].IP).To(Equal(""))
+		})
+	})
+
+	Context("when the job consumes links", func() {
+		BeforeEach(func() {
+			links = map[string]map[string]LinkContext{
+				"fake-job-name": {
+					"fake-link-name": {
+						Address: "1.2.3.4",
+						Instances: []LinkInstanceContext{
+							{Name: "fake-instance-group", Index: 0, AZ: "fake-az", Address: "1.2.3.4", Bootstrap: true},
+						},
+						Properties: biproperty.Map{"fake-link-property": "fake-link-property-value"},
+					},
+				},
+				"fake-other-job-name": {
+					"fake-other-link-name": {Address: "1.2.3.4"},
+				},
+			}
+		})
+
+		It("it has the links of the job available in the spec", func() {
+			Expect(generatedContext.Links).To(HaveLen(1))
+			Expect(generatedContext.Links["fake-link-name"].Address).To(Equal("1.2.3.4"))
+			Expect(generatedContext.Links["fake-link-name"].Instances).To(Equal(links["fake-job-name"]["fake-link-name"].Instances))
+			Expect(generatedContext.Links["fake-link-name"].Properties).To(HaveKeyWithValue("fake-link-property", "fake-link-property-value"))
+		})
+	})
+
 	It("it has address available in the spec", func() {
 		Expect(generatedContext.Address).To(Equal("1.2.3.4"))
 	})
@@ -83,6 +141,16 @@ var _ = Describe("JobEvaluationContext", func() {
 		Expect(generatedContext.AZ).To(Equal("unknown"))
 	})
 
+	Context("when the instance is in an az", func() {
+		BeforeEach(func() {
+			az = "fake-az"
+		})
+
+		It("it has the az available in the spec", func() {
+			Expect(generatedContext.AZ).To(Equal("fake-az"))
+		})
+	})
+
 	It("it has bootstrap available in the spec", func() {
 		Expect(generatedContext.Bootstrap).To(Equal(true))
 	})
@@ -114,7 +182,7 @@ var _ = Describe("JobEvaluationContext", func() {
 			instanceGroupProperties,
 			deploymentProperties,
 			"fake-deployment-name",
-			"1.2.3.4",
+			InstanceContext{Address: "1.2.3.4", AZ: az, Networks: networks, Links: links},
 			logger,
 		)
 
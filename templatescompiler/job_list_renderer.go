@@ -14,7 +14,7 @@ type JobListRenderer interface {
 		jobProperties biproperty.Map,
 		globalProperties biproperty.Map,
 		deploymentName string,
-		address string,
+		instance InstanceContext,
 	) (RenderedJobList, error)
 }
 
@@ -41,14 +41,14 @@ func (r *jobListRenderer) Render(
 	jobProperties biproperty.Map,
 	globalProperties biproperty.Map,
 	deploymentName string,
-	address string,
+	instance InstanceContext,
 ) (RenderedJobList, error) {
 	r.logger.Debug(r.logTag, "Rendering job list: deploymentName='%s' jobProperties=%#v globalProperties=%#v", deploymentName, jobProperties, globalProperties)
 	renderedJobList := NewRenderedJobList()
 
 	// render all the jobs' templates
 	for _, releaseJob := range releaseJobs {
-		renderedJob, err := r.jobRenderer.Render(releaseJob, releaseJobProperties[releaseJob.Name], jobProperties, globalProperties, deploymentName, address)
+		renderedJob, err := r.jobRenderer.Render(releaseJob, releaseJobProperties[releaseJob.Name], jobProperties, globalProperties, deploymentName, instance)
 		if err != nil {
 			defer renderedJobList.DeleteSilently()
 			return renderedJobList, bosherr.WrapErrorf(err, "Rendering templates for job '%s/%s'", releaseJob.Name, releaseJob.Fingerprint)
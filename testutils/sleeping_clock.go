@@ -0,0 +1,20 @@
+package testutils
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+// SleepingClock is a fake clock that advances when slept on, instead of waiting for the test to advance it
+type SleepingClock struct {
+	*fakeclock.FakeClock
+}
+
+func NewSleepingClock(now time.Time) SleepingClock {
+	return SleepingClock{fakeclock.NewFakeClock(now)}
+}
+
+func (c SleepingClock) Sleep(d time.Duration) {
+	c.Increment(d)
+}
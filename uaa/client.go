@@ -0,0 +1,71 @@
+package uaa
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	gourl "net/url"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// Client talks to the UAA at endpoint, which includes the client credentials as user info
+type Client struct {
+	endpoint   string
+	httpClient boshhttp.HTTPClient
+	logTag     string
+	logger     boshlog.Logger
+}
+
+func NewClient(endpoint string, httpClient boshhttp.HTTPClient, logger boshlog.Logger) Client {
+	return Client{
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		logTag:     "uaa.Client",
+		logger:     logger,
+	}
+}
+
+type tokenResp struct {
+	Type        string `json:"token_type"`
+	AccessToken string `json:"access_token"`
+}
+
+func (c Client) ClientCredentialsGrant() (Token, error) {
+	query := gourl.Values{}
+	query.Add("grant_type", "client_credentials")
+
+	response, err := c.httpClient.PostCustomized(c.endpoint+"/oauth/token", []byte(query.Encode()), func(req *http.Request) {
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	})
+	if err != nil {
+		return Token{}, bosherr.WrapError(err, "Requesting token via client credentials grant")
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return Token{}, bosherr.WrapError(err, "Reading token response")
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return Token{}, bosherr.Errorf("UAA responded with non-successful status code '%d' response '%s'", response.StatusCode, body)
+	}
+
+	var resp tokenResp
+
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return Token{}, bosherr.WrapError(err, "Unmarshaling token response")
+	}
+
+	if len(resp.AccessToken) == 0 {
+		return Token{}, bosherr.Error("Expected UAA to respond with an access token")
+	}
+
+	return Token{Type: resp.Type, Value: resp.AccessToken}, nil
+}
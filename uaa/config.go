@@ -0,0 +1,91 @@
+package uaa
+
+import (
+	"crypto/x509"
+	gourl "net/url"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// DefaultPort is the port of the UAA colocated with the director
+const DefaultPort = 443
+
+type Config struct {
+	Host string
+	Port int
+
+	// Client and ClientSecret are used for the client credentials grant
+	Client       string
+	ClientSecret string
+
+	// CACert is a PEM bundle trusted instead of the system CAs
+	CACert string
+}
+
+// NewConfigFromURL parses 'host', 'host:port' or 'https://host:port', defaulting to DefaultPort
+func NewConfigFromURL(url string) (Config, error) {
+	if strings.TrimSpace(url) == "" {
+		return Config{}, bosherr.Error("Expected non-empty UAA URL")
+	}
+
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+
+	parsedURL, err := gourl.Parse(url)
+	if err != nil {
+		return Config{}, bosherr.WrapErrorf(err, "Parsing UAA URL '%s'", url)
+	}
+
+	if parsedURL.Scheme != "https" {
+		return Config{}, bosherr.Errorf("Expected UAA URL '%s' to use https", url)
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return Config{}, bosherr.Errorf("Expected to extract host from URL '%s'", url)
+	}
+
+	port := DefaultPort
+	if parsedURL.Port() != "" {
+		port, err = strconv.Atoi(parsedURL.Port())
+		if err != nil {
+			return Config{}, bosherr.WrapErrorf(err, "Extracting port from URL '%s'", url)
+		}
+	}
+
+	return Config{Host: host, Port: port}, nil
+}
+
+func (c Config) Validate() error {
+	if len(c.Host) == 0 {
+		return bosherr.Error("Missing 'Host'")
+	}
+
+	if c.Port == 0 {
+		return bosherr.Error("Missing 'Port'")
+	}
+
+	if len(c.Client) == 0 {
+		return bosherr.Error("Missing 'Client'")
+	}
+
+	_, err := c.CACertPool()
+	return err
+}
+
+// CACertPool returns nil when no CA certificate is configured, so that the system CAs are used
+func (c Config) CACertPool() (*x509.CertPool, error) {
+	if len(c.CACert) == 0 {
+		return nil, nil
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM([]byte(c.CACert)) {
+		return nil, bosherr.Error("Parsing CA certificate: no PEM certificates found")
+	}
+
+	return certPool, nil
+}
@@ -0,0 +1,42 @@
+package uaa_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/uaa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	Describe("NewConfigFromURL", func() {
+		It("defaults the scheme and port", func() {
+			config, err := NewConfigFromURL("fake-host")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(Config{Host: "fake-host", Port: 443}))
+		})
+
+		It("reads the host and port", func() {
+			config, err := NewConfigFromURL("https://fake-host:8443")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(Config{Host: "fake-host", Port: 8443}))
+		})
+
+		It("returns an error when the URL does not use https", func() {
+			_, err := NewConfigFromURL("http://fake-host")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected UAA URL 'http://fake-host' to use https"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("requires the host, port and client", func() {
+			Expect(Config{Port: 1, Client: "fake-client"}.Validate()).To(MatchError("Missing 'Host'"))
+			Expect(Config{Host: "fake-host", Client: "fake-client"}.Validate()).To(MatchError("Missing 'Port'"))
+			Expect(Config{Host: "fake-host", Port: 1}.Validate()).To(MatchError("Missing 'Client'"))
+		})
+
+		It("requires the CA certificate to be PEM encoded", func() {
+			config := Config{Host: "fake-host", Port: 1, Client: "fake-client", CACert: "fake-cert"}
+			Expect(config.Validate()).To(MatchError(ContainSubstring("no PEM certificates found")))
+		})
+	})
+})
@@ -0,0 +1,92 @@
+package uaa_test
+
+import (
+	"encoding/pem"
+	"net/http"
+
+	. "github.com/cloudfoundry/bosh-init/uaa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("Factory", func() {
+	var (
+		server *ghttp.Server
+		config Config
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewTLSServer()
+
+		var err error
+		config, err = NewConfigFromURL(server.URL())
+		Expect(err).ToNot(HaveOccurred())
+
+		config.Client = "fake-client"
+		config.ClientSecret = "fake-client-secret"
+		config.CACert = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: server.HTTPTestServer.Certificate().Raw,
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns an error when the config is invalid", func() {
+		_, err := NewFactory(boshlog.NewLogger(boshlog.LevelNone)).New(Config{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Validating UAA connection config"))
+	})
+
+	Describe("ClientCredentialsGrant", func() {
+		It("grants a token with the client credentials", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/oauth/token"),
+					ghttp.VerifyBasicAuth("fake-client", "fake-client-secret"),
+					ghttp.VerifyContentType("application/x-www-form-urlencoded"),
+					func(w http.ResponseWriter, req *http.Request) {
+						Expect(req.ParseForm()).To(Succeed())
+						Expect(req.PostForm.Get("grant_type")).To(Equal("client_credentials"))
+					},
+					ghttp.RespondWith(http.StatusOK, `{"token_type": "bearer", "access_token": "fake-access-token"}`),
+				),
+			)
+
+			uaa, err := NewFactory(boshlog.NewLogger(boshlog.LevelNone)).New(config)
+			Expect(err).ToNot(HaveOccurred())
+
+			token, err := uaa.ClientCredentialsGrant()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal(Token{Type: "bearer", Value: "fake-access-token"}))
+			Expect(token.AuthHeader()).To(Equal("bearer fake-access-token"))
+		})
+
+		It("returns an error when the credentials are rejected", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, "fake-body"))
+
+			uaa, err := NewFactory(boshlog.NewLogger(boshlog.LevelNone)).New(config)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = uaa.ClientCredentialsGrant()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("non-successful status code '401' response 'fake-body'"))
+		})
+
+		It("returns an error when no access token is granted", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{}`))
+
+			uaa, err := NewFactory(boshlog.NewLogger(boshlog.LevelNone)).New(config)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = uaa.ClientCredentialsGrant()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected UAA to respond with an access token"))
+		})
+	})
+})
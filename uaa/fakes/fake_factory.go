@@ -0,0 +1,25 @@
+package fakes
+
+import (
+	biuaa "github.com/cloudfoundry/bosh-init/uaa"
+)
+
+type FakeFactory struct {
+	NewConfigs []biuaa.Config
+	NewUAA     *FakeUAA
+	NewErr     error
+}
+
+func NewFakeFactory() *FakeFactory {
+	return &FakeFactory{NewUAA: NewFakeUAA()}
+}
+
+func (f *FakeFactory) New(config biuaa.Config) (biuaa.UAA, error) {
+	f.NewConfigs = append(f.NewConfigs, config)
+
+	if f.NewErr != nil {
+		return nil, f.NewErr
+	}
+
+	return f.NewUAA, nil
+}
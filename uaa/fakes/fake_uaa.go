@@ -0,0 +1,20 @@
+package fakes
+
+import (
+	biuaa "github.com/cloudfoundry/bosh-init/uaa"
+)
+
+type FakeUAA struct {
+	ClientCredentialsGrantToken       biuaa.Token
+	ClientCredentialsGrantErr         error
+	ClientCredentialsGrantCalledCount int
+}
+
+func NewFakeUAA() *FakeUAA {
+	return &FakeUAA{}
+}
+
+func (u *FakeUAA) ClientCredentialsGrant() (biuaa.Token, error) {
+	u.ClientCredentialsGrantCalledCount++
+	return u.ClientCredentialsGrantToken, u.ClientCredentialsGrantErr
+}
@@ -0,0 +1,24 @@
+package uaa
+
+type UAA interface {
+	ClientCredentialsGrant() (Token, error)
+}
+
+type Token struct {
+	// Type is usually 'bearer'
+	Type  string
+	Value string
+}
+
+// AuthHeader returns the value of the Authorization header authenticating with the token
+func (t Token) AuthHeader() string {
+	return t.Type + " " + t.Value
+}
+
+type UAAImpl struct {
+	client Client
+}
+
+func (u UAAImpl) ClientCredentialsGrant() (Token, error) {
+	return u.client.ClientCredentialsGrant()
+}
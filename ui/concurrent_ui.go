@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+)
+
+// concurrentUI prints whole lines under a lock shared with the other stages performed concurrently,
+// so that their lines don't interleave. Continuations of a line, e.g. progress reports, are not printed,
+// as a line can not be continued once another stage printed after it.
+type concurrentUI struct {
+	parent UI
+	lock   *sync.Mutex
+	line   string
+}
+
+func newConcurrentUI(parent UI, lock *sync.Mutex) UI {
+	return &concurrentUI{
+		parent: parent,
+		lock:   lock,
+	}
+}
+
+func (ui *concurrentUI) ErrorLinef(pattern string, args ...interface{}) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+	ui.parent.ErrorLinef("%s", fmt.Sprintf(pattern, args...))
+}
+
+func (ui *concurrentUI) PrintLinef(pattern string, args ...interface{}) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+	ui.parent.PrintLinef("%s", fmt.Sprintf(pattern, args...))
+}
+
+func (ui *concurrentUI) BeginLinef(pattern string, args ...interface{}) {
+	ui.line = fmt.Sprintf(pattern, args...)
+	ui.PrintLinef("%s", ui.line)
+}
+
+func (ui *concurrentUI) ContinueLinef(pattern string, args ...interface{}) {}
+
+func (ui *concurrentUI) EndLinef(pattern string, args ...interface{}) {
+	ui.PrintLinef("%s%s", ui.line, fmt.Sprintf(pattern, args...))
+	ui.line = ""
+}
@@ -5,8 +5,9 @@ import (
 )
 
 type FakeStage struct {
-	PerformCalls []*PerformCall
-	SubStages    []*FakeStage
+	PerformCalls      []*PerformCall
+	SubStages         []*FakeStage
+	ConcurrencyLimits []int
 }
 
 type PerformCall struct {
@@ -14,6 +15,7 @@ type PerformCall struct {
 	Error     error
 	SkipError error
 	Stage     *FakeStage
+	Progress  []string
 }
 
 func NewFakeStage() *FakeStage {
@@ -21,6 +23,10 @@ func NewFakeStage() *FakeStage {
 }
 
 func (s *FakeStage) Perform(name string, closure func() error) error {
+	return s.PerformWithProgress(name, func(biui.Progress) error { return closure() })
+}
+
+func (s *FakeStage) PerformWithProgress(name string, closure func(biui.Progress) error) error {
 
 	call := &PerformCall{Name: name}
 
@@ -30,7 +36,7 @@ func (s *FakeStage) Perform(name string, closure func() error) error {
 	}
 	s.PerformCalls = append(s.PerformCalls, call) //We want to record the calls in the same order as the real implementation would print them
 
-	err := closure()
+	err := closure(fakeProgress{call: call})
 
 	call.Error = err
 	if err != nil {
@@ -43,6 +49,14 @@ func (s *FakeStage) Perform(name string, closure func() error) error {
 	return err
 }
 
+type fakeProgress struct {
+	call *PerformCall
+}
+
+func (p fakeProgress) Report(message string) {
+	p.call.Progress = append(p.call.Progress, message)
+}
+
 func (s *FakeStage) PerformComplex(name string, closure func(biui.Stage) error) error {
 	subStage := NewFakeStage()
 
@@ -71,3 +85,17 @@ func (s *FakeStage) PerformComplex(name string, closure func(biui.Stage) error)
 
 	return err
 }
+
+// PerformConcurrently performs the closures one after the other on this stage, so that their calls are recorded in order
+func (s *FakeStage) PerformConcurrently(limit int, closures []func(biui.Stage) error) error {
+	s.ConcurrencyLimits = append(s.ConcurrencyLimits, limit)
+
+	for _, closure := range closures {
+		err := closure(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -21,6 +21,10 @@ func (ui *FakeUI) BeginLinef(pattern string, args ...interface{}) {
 	ui.Said = append(ui.Said, fmt.Sprintf(pattern, args...))
 }
 
+func (ui *FakeUI) ContinueLinef(pattern string, args ...interface{}) {
+	ui.Said = append(ui.Said, fmt.Sprintf(pattern, args...))
+}
+
 func (ui *FakeUI) EndLinef(pattern string, args ...interface{}) {
 	ui.Said = append(ui.Said, fmt.Sprintf(pattern, args...))
 }
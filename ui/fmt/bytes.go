@@ -0,0 +1,22 @@
+package fmt
+
+import (
+	"fmt"
+)
+
+// Bytes formats a byte count with the largest binary unit that keeps it above 1, e.g. 1.5 GB
+func Bytes(bytes int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", bytes, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
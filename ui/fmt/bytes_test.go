@@ -0,0 +1,20 @@
+package fmt_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/ui/fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bytes", func() {
+	It("returns bytes below one kilobyte as is", func() {
+		Expect(Bytes(0)).To(Equal("0 B"))
+		Expect(Bytes(1023)).To(Equal("1023 B"))
+	})
+
+	It("returns larger sizes in the largest unit with one decimal", func() {
+		Expect(Bytes(1536)).To(Equal("1.5 KB"))
+		Expect(Bytes(10 * 1024 * 1024)).To(Equal("10.0 MB"))
+		Expect(Bytes(3*1024*1024*1024 + 512*1024*1024)).To(Equal("3.5 GB"))
+	})
+})
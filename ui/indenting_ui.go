@@ -26,6 +26,10 @@ func (ui *indentingUI) BeginLinef(pattern string, args ...interface{}) {
 	ui.parent.BeginLinef(fmt.Sprintf("  %s", fmt.Sprintf(pattern, args...)))
 }
 
+func (ui *indentingUI) ContinueLinef(pattern string, args ...interface{}) {
+	ui.parent.ContinueLinef(pattern, args...)
+}
+
 func (ui *indentingUI) EndLinef(pattern string, args ...interface{}) {
 	ui.parent.EndLinef(fmt.Sprintf(pattern, args...))
 }
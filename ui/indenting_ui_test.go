@@ -48,6 +48,15 @@ var _ = Describe("IndentingUI", func() {
 		})
 	})
 
+	Describe("ContinueLinef", func() {
+		It("delegates to the parent UI.ContinueLinef without an indent", func() {
+			ui.BeginLinef("fake-start")
+			ui.ContinueLinef(" fake-continue")
+			Expect(uiOut.String()).To(Equal("  fake-start fake-continue"))
+			Expect(uiErr.String()).To(BeEmpty())
+		})
+	})
+
 	Describe("EndLinef", func() {
 		It("delegates to the UI.EndLinef", func() {
 			ui.EndLinef("fake-end")
@@ -0,0 +1,25 @@
+package ui
+
+type quietUI struct {
+	parent UI
+}
+
+// NewQuietUI only passes error lines to the parent UI,
+// so that machine readable output is not interleaved with progress lines.
+func NewQuietUI(parent UI) UI {
+	return &quietUI{
+		parent: parent,
+	}
+}
+
+func (ui *quietUI) ErrorLinef(pattern string, args ...interface{}) {
+	ui.parent.ErrorLinef(pattern, args...)
+}
+
+func (ui *quietUI) PrintLinef(pattern string, args ...interface{}) {}
+
+func (ui *quietUI) BeginLinef(pattern string, args ...interface{}) {}
+
+func (ui *quietUI) ContinueLinef(pattern string, args ...interface{}) {}
+
+func (ui *quietUI) EndLinef(pattern string, args ...interface{}) {}
@@ -0,0 +1,45 @@
+package ui_test
+
+import (
+	. "github.com/cloudfoundry/bosh-init/ui"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("QuietUI", func() {
+	var (
+		uiOut, uiErr *bytes.Buffer
+		ui           UI
+	)
+
+	BeforeEach(func() {
+		uiOut = bytes.NewBufferString("")
+		uiErr = bytes.NewBufferString("")
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		ui = NewQuietUI(NewWriterUI(uiOut, uiErr, logger))
+	})
+
+	Describe("ErrorLinef", func() {
+		It("delegates to the parent UI.ErrorLinef", func() {
+			ui.ErrorLinef("fake-error-line")
+			Expect(uiErr.String()).To(Equal("fake-error-line\n"))
+			Expect(uiOut.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("PrintLinef, BeginLinef, ContinueLinef and EndLinef", func() {
+		It("discards the output", func() {
+			ui.PrintLinef("fake-line")
+			ui.BeginLinef("fake-start")
+			ui.ContinueLinef("fake-continue")
+			ui.EndLinef("fake-end")
+			Expect(uiOut.String()).To(BeEmpty())
+			Expect(uiErr.String()).To(BeEmpty())
+		})
+	})
+})
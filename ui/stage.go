@@ -1,16 +1,27 @@
 package ui
 
 import (
+	"sync"
 	"time"
 
 	biuifmt "github.com/cloudfoundry/bosh-init/ui/fmt"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/pivotal-golang/clock"
 )
 
 type Stage interface {
 	Perform(name string, closure func() error) error
+	PerformWithProgress(name string, closure func(Progress) error) error
 	PerformComplex(name string, closure func(Stage) error) error
+	// PerformConcurrently performs at most limit closures at a time, each with a stage printing whole lines.
+	// Closures not yet started when one fails are skipped.
+	PerformConcurrently(limit int, closures []func(Stage) error) error
+}
+
+// Progress appends messages to the line of a single-line stage while it is performed
+type Progress interface {
+	Report(message string)
 }
 
 type stage struct {
@@ -33,6 +44,10 @@ func NewStage(ui UI, timeService clock.Clock, logger boshlog.Logger) Stage {
 }
 
 func (s *stage) Perform(name string, closure func() error) error {
+	return s.PerformWithProgress(name, func(Progress) error { return closure() })
+}
+
+func (s *stage) PerformWithProgress(name string, closure func(Progress) error) error {
 	if !s.simpleMode {
 		// enter simple mode (only line break if exiting complex mode)
 		s.ui.PrintLinef("")
@@ -41,7 +56,7 @@ func (s *stage) Perform(name string, closure func() error) error {
 
 	s.ui.BeginLinef("%s...", name)
 	startTime := s.timeService.Now()
-	err := closure()
+	err := closure(lineProgress{ui: s.ui})
 	if err != nil {
 		if skipErr, ok := err.(SkipStageError); ok {
 			s.ui.EndLinef(" Skipped [%s] (%s)", skipErr.SkipMessage(), s.elapsedSince(startTime))
@@ -71,6 +86,71 @@ func (s *stage) PerformComplex(name string, closure func(Stage) error) error {
 	return nil
 }
 
+func (s *stage) PerformConcurrently(limit int, closures []func(Stage) error) error {
+	if !s.simpleMode {
+		s.ui.PrintLinef("")
+		s.simpleMode = true
+	}
+
+	if limit < 1 {
+		limit = 1
+	}
+
+	var (
+		uiLock    sync.Mutex
+		errsLock  sync.Mutex
+		waitGroup sync.WaitGroup
+	)
+	semaphore := make(chan struct{}, limit)
+	errs := []error{}
+
+	for _, closure := range closures {
+		semaphore <- struct{}{}
+
+		errsLock.Lock()
+		failed := len(errs) > 0
+		errsLock.Unlock()
+		if failed {
+			<-semaphore
+			break
+		}
+
+		waitGroup.Add(1)
+		go func(closure func(Stage) error) {
+			defer func() {
+				<-semaphore
+				waitGroup.Done()
+			}()
+
+			err := closure(NewStage(newConcurrentUI(s.ui, &uiLock), s.timeService, s.logger))
+			if err != nil {
+				errsLock.Lock()
+				errs = append(errs, err)
+				errsLock.Unlock()
+			}
+		}(closure)
+	}
+
+	waitGroup.Wait()
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return bosherr.NewMultiError(errs...)
+	}
+}
+
+type lineProgress struct {
+	ui UI
+}
+
+func (p lineProgress) Report(message string) {
+	p.ui.ContinueLinef(" %s", message)
+}
+
 func (s *stage) elapsedSince(startTime time.Time) string {
 	stopTime := s.timeService.Now()
 	duration := stopTime.Sub(startTime)
@@ -6,6 +6,9 @@ import (
 	. "github.com/onsi/gomega"
 
 	"bytes"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -92,6 +95,34 @@ var _ = Describe("Stage", func() {
 		})
 	})
 
+	Describe("PerformWithProgress", func() {
+		It("prints the reported progress on the line of the stage", func() {
+			err := stage.PerformWithProgress("Simple stage 1", func(progress Progress) error {
+				progress.Report("50%")
+				progress.Report("100%")
+				fakeTimeService.Increment(time.Minute)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedOutput := "Simple stage 1... 50% 100% Finished (00:01:00)\n"
+			Expect(uiOut.String()).To(Equal(expectedOutput))
+		})
+
+		It("fails on error after the reported progress", func() {
+			stageError := bosherr.Error("fake-stage-1-error")
+
+			err := stage.PerformWithProgress("Simple stage 1", func(progress Progress) error {
+				progress.Report("10%")
+				return stageError
+			})
+			Expect(err).To(Equal(stageError))
+
+			expectedOutput := "Simple stage 1... 10% Failed (00:00:00)\n"
+			Expect(uiOut.String()).To(Equal(expectedOutput))
+		})
+	})
+
 	Describe("PerformComplex", func() {
 		It("prints a multi-line stage (depth: 1)", func() {
 			actionsPerformed := []string{}
@@ -203,4 +234,120 @@ Failed Complex stage 1 (00:01:00)
 			Expect(actionsPerformed).To(Equal([]string{"1"}))
 		})
 	})
+
+	Describe("PerformConcurrently", func() {
+		It("prints each line of the concurrent stages whole, without the progress reports", func() {
+			err := stage.PerformConcurrently(1, []func(Stage) error{
+				func(stage Stage) error {
+					return stage.PerformWithProgress("Simple stage A", func(progress Progress) error {
+						progress.Report("50%")
+						fakeTimeService.Increment(time.Minute)
+						return nil
+					})
+				},
+				func(stage Stage) error {
+					return stage.Perform("Simple stage B", func() error { return nil })
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedOutput := `Simple stage A...
+Simple stage A... Finished (00:01:00)
+Simple stage B...
+Simple stage B... Finished (00:00:00)
+`
+			Expect(uiOut.String()).To(Equal(expectedOutput))
+		})
+
+		It("prints only the begin and end lines of stages reporting progress while running concurrently", func() {
+			download := func(name string) func(Stage) error {
+				return func(stage Stage) error {
+					return stage.PerformWithProgress(fmt.Sprintf("Downloading %s", name), func(progress Progress) error {
+						for percent := 10; percent <= 100; percent += 10 {
+							progress.Report(fmt.Sprintf("%d%%", percent))
+						}
+						return nil
+					})
+				}
+			}
+
+			err := stage.PerformConcurrently(2, []func(Stage) error{download("A"), download("B")})
+			Expect(err).ToNot(HaveOccurred())
+
+			lines := strings.Split(strings.TrimSuffix(uiOut.String(), "\n"), "\n")
+			Expect(lines).To(ConsistOf(
+				"Downloading A...",
+				"Downloading A... Finished (00:00:00)",
+				"Downloading B...",
+				"Downloading B... Finished (00:00:00)",
+			))
+		})
+
+		It("performs at most limit closures at a time", func() {
+			var (
+				lock       sync.Mutex
+				running    int
+				maxRunning int
+			)
+
+			closure := func(Stage) error {
+				lock.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				lock.Unlock()
+
+				time.Sleep(50 * time.Millisecond)
+
+				lock.Lock()
+				running--
+				lock.Unlock()
+				return nil
+			}
+
+			err := stage.PerformConcurrently(2, []func(Stage) error{closure, closure, closure, closure})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(maxRunning).To(Equal(2))
+		})
+
+		It("skips the closures not yet started after an error", func() {
+			actionsPerformed := []string{}
+			stageError := bosherr.Error("fake-stage-1-error")
+
+			err := stage.PerformConcurrently(1, []func(Stage) error{
+				func(Stage) error {
+					actionsPerformed = append(actionsPerformed, "1")
+					return stageError
+				},
+				func(Stage) error {
+					actionsPerformed = append(actionsPerformed, "2")
+					return nil
+				},
+			})
+			Expect(err).To(Equal(stageError))
+			Expect(actionsPerformed).To(Equal([]string{"1"}))
+		})
+
+		It("returns the errors of all the closures that failed", func() {
+			var started sync.WaitGroup
+			started.Add(2)
+
+			failAfterBothStarted := func(message string) func(Stage) error {
+				return func(Stage) error {
+					started.Done()
+					started.Wait()
+					return bosherr.Error(message)
+				}
+			}
+
+			err := stage.PerformConcurrently(2, []func(Stage) error{
+				failAfterBothStarted("fake-error-1"),
+				failAfterBothStarted("fake-error-2"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-error-1"))
+			Expect(err.Error()).To(ContainSubstring("fake-error-2"))
+		})
+	})
 })
@@ -12,6 +12,7 @@ type UI interface {
 	ErrorLinef(pattern string, args ...interface{})
 	PrintLinef(pattern string, args ...interface{})
 	BeginLinef(pattern string, args ...interface{})
+	ContinueLinef(pattern string, args ...interface{})
 	EndLinef(pattern string, args ...interface{})
 }
 
@@ -62,6 +63,15 @@ func (ui *ui) BeginLinef(pattern string, args ...interface{}) {
 	}
 }
 
+// ContinueLinef appends text to a line started with BeginLinef
+func (ui *ui) ContinueLinef(pattern string, args ...interface{}) {
+	message := fmt.Sprintf(pattern, args...)
+	_, err := fmt.Fprint(ui.outWriter, message)
+	if err != nil {
+		ui.logger.Error(ui.logTag, "UI.ContinueLinef failed (message='%s'): %s", message, err)
+	}
+}
+
 // PrintEndf ends a text line
 func (ui *ui) EndLinef(pattern string, args ...interface{}) {
 	message := fmt.Sprintf(pattern, args...)
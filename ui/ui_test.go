@@ -108,6 +108,29 @@ var _ = Describe("UI", func() {
 		})
 	})
 
+	Describe("ContinueLinef", func() {
+		It("prints to outWriter", func() {
+			ui.BeginLinef("fake-start")
+			ui.ContinueLinef(" fake-continue")
+			Expect(uiOutBuffer.String()).To(Equal("fake-start fake-continue"))
+		})
+
+		Context("when writing errors", func() {
+			BeforeEach(func() {
+				reader, writer := io.Pipe()
+				uiOut = writer
+				reader.Close()
+			})
+
+			It("logs an error", func() {
+				ui.ContinueLinef("fake-continue")
+
+				Expect(uiOutBuffer.String()).To(Equal(""))
+				Expect(logErrBuffer.String()).To(ContainSubstring("UI.ContinueLinef failed (message='fake-continue')"))
+			})
+		})
+	})
+
 	Describe("EndLinef", func() {
 		It("prints to outWriter with a trailing newline", func() {
 			ui.EndLinef("fake-end")